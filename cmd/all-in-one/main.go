@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/generator"
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/notification"
+	"notification-delivery-system/internal/producer"
+	"notification-delivery-system/internal/soak"
+	"notification-delivery-system/internal/startup"
+)
+
+// all-in-one runs the whole pipeline in a single process:
+// event generators → in-memory bus → consumer → in-memory repository →
+// task picker → SSE server. No Kafka or PostgreSQL required.
+func main() {
+	var (
+		port           = flag.Int("port", 8080, "HTTP/SSE port")
+		numUsers       = flag.Int("users", 1000, "Number of simulated users (user_1..user_N)")
+		usersFile      = flag.String("users-file", "", "Load simulated user IDs (e.g. UUIDs) from this file, one per line, instead of user_1..user_N")
+		numTenants     = flag.Int("tenants", 1, "Number of simulated tenants (user_i belongs to tenant_<i mod N>)")
+		eventRate      = flag.Int("rate", 30, "Total events per second across all generators (0 disables generation)")
+		scenarioFile   = flag.String("scenario", "", "YAML scenario shaping generated traffic: event weights, zipf users, bursts, diurnal curve (its rate overrides -rate)")
+		maxConns       = flag.Int("max-connections", 10000, "Max concurrent SSE connections")
+		heartbeat      = flag.Duration("heartbeat-interval", 30*time.Second, "Interval between SSE heartbeat frames (sse-bench -expected-heartbeat should match)")
+		compression    = flag.String("sse-compression", "", "SSE stream encodings offered, preferred first (e.g. br,gzip; empty disables)")
+		flushInterval  = flag.Duration("flush-interval", 0, "Coalesce SSE notification frames per connection for up to this long (0 flushes every frame)")
+		flushFrames    = flag.Int("flush-frames", 16, "Buffered SSE frames that force a flush before -flush-interval")
+		bandwidth      = flag.Int("bandwidth-limit", 0, "Bytes per second per SSE connection before LOW notifications are deferred (0 = unlimited)")
+		regions        = flag.String("regions", "", "Simulated client regions, name:fraction:latency[:jitter[:loss]],... (e.g. eu-west:0.3:80ms:20ms:0.01)")
+		staleTimeout   = flag.Duration("stale-timeout", 5*time.Minute, "Close SSE connections idle longer than this (swept every stale-timeout/5)")
+		liveness       = flag.Duration("liveness-timeout", 0, "Treat SSE connections as offline when their client hasn't echoed a heartbeat for this long (0 = server writes keep them alive)")
+		encoding       = flag.String("message-encoding", "json", "Encoding of generated events on the in-memory bus (json, protobuf)")
+		latePolicy     = flag.String("late-policy", "mark", "What to do with events more than -late-threshold off processing time (deliver, mark, drop)")
+		consumeTypes   = flag.String("consume-types", "", "Event types or families (job.*) the consumer persists, comma-separated (empty = all)")
+		consumePrio    = flag.String("consume-priorities", "", "Priorities the consumer persists, comma-separated (empty = all)")
+		expressPrio    = flag.String("express-priorities", "", "Priorities persisted on arrival instead of batched, e.g. HIGH")
+		consumeWork    = flag.Int("consumer-workers", 4, "Parallel consumer workers; a user's events always share one")
+		ingestKey      = flag.String("ingest-quota-key", "source", "What ingest quotas are counted per: source (source service) or tenant")
+		ingestRate     = flag.Float64("ingest-quota-rate", 0, "Events per second accepted per -ingest-quota-key (0 = unlimited unless overridden)")
+		ingestOverride = flag.String("ingest-quota-overrides", "", "Per-key ingest rates, e.g. job-service=50,followers-service=20")
+		ingestOverflow = flag.String("ingest-overflow", "degrade", "Events over their ingest quota: degrade (persist as LOW) or drop")
+		lateThreshold  = flag.Duration("late-threshold", 5*time.Minute, "Event-time distance beyond which an event is late")
+		idStrategy     = flag.String("id-strategy", "uuidv7", "Notification ID strategy (uuidv4, uuidv7, ulid, snowflake, sequential)")
+		slaInterval    = flag.Duration("sla-interval", 15*time.Second, "How often rolling SLA compliance is recomputed")
+		canaryInterval = flag.Duration("canary-interval", 5*time.Second, "Interval between built-in canary probes (0 disables the canary)")
+		deliveryRate   = flag.Int("delivery-rate", 0, "Max notifications delivered per second (0 = unlimited)")
+		userLimit      = flag.Int("user-limit", 0, "Max notifications delivered per user per -user-window (0 = unlimited)")
+		userWindow     = flag.Duration("user-window", time.Minute, "Window for -user-limit")
+		http2Mode      = flag.String("http2", "h2c", "HTTP/2 support: h2c (cleartext prior knowledge), tls (never on plain HTTP) or off")
+		maxStreams     = flag.Int("max-concurrent-streams", 1000, "HTTP/2 streams (SSE connections) per TCP connection")
+		expiryInterval = flag.Duration("expiry-interval", time.Minute, "How often pending notifications past their deadline or -max-age are expired")
+		maxAge         = flag.Duration("max-age", 0, "Expire pending notifications older than this (0 = deadline only)")
+		reprioRules    = flag.String("reprioritize", "", "Move pending notifications between priorities by age, from:after:to,... (e.g. HIGH:10m:MEDIUM,LOW:2m:MEDIUM; empty disables)")
+		reprioInterval = flag.Duration("reprioritize-interval", 30*time.Second, "How often the -reprioritize rules are applied")
+		summaryFile    = flag.String("summary-file", "", "Write the end-of-run summary to this JSON file on shutdown (it is always logged)")
+		snapshotFile   = flag.String("snapshot-file", "", "Snapshot notifications and run counters to this file on shutdown and restore them on startup")
+		soakInterval   = flag.Duration("soak-interval", 0, "Sample goroutines, heap and FDs this often and flag steady growth (0 = SOAK_INTERVAL, unset disables)")
+		drainTimeout   = flag.Duration("drain-timeout", 3*time.Second, "On SIGTERM, how long to keep delivering claimed notifications before telling clients to reconnect")
+		reconnectAfter = flag.Duration("reconnect-after", 2*time.Second, "Retry hint of SSE reconnect events (drain, load shedding) and of Retry-After while draining")
+		reconnSpread   = flag.Duration("reconnect-spread", 5*time.Second, "Spread reconnect hints over [-reconnect-after, -reconnect-after + this] so clients don't return at once")
+		publishBatch   = flag.Int("publish-max-batch", notification.DefaultMaxPublishBatch, "Max notifications per POST /notifications/batch")
+		sseRetry       = flag.Duration("sse-retry", 3*time.Second, "SSE retry: hint sent when a stream opens (0 omits it)")
+		instanceID     = flag.String("instance-id", "all-in-one", "Instance name in metrics labels, delivered notifications and logs (tell several all-in-ones apart)")
+		claimWeights   = flag.String("claim-weights", "", "Split each claim HIGH/MEDIUM/LOW by weight, e.g. 70/20/10, so LOW still moves under a HIGH flood (empty = strict priority order)")
+		connectedOnly  = flag.Bool("claim-connected-only", false, "Only claim notifications of connected users, leaving offline users' work pending instead of claiming and parking it")
+		retryBackoff   = flag.Duration("retry-backoff", time.Second, "Failed deliveries aren't claimed again for this long, doubled per retry (capped at 5m)")
+		chaosOn        = flag.Bool("chaos", false, "Chaos: wire fault injection and PUT /admin/chaos with no fault set yet (implied by any -chaos-* rate)")
+		chaosDBLatency = flag.Duration("chaos-db-latency", 0, "Chaos: latency added to repository inserts, claims and status updates picked by -chaos-db-latency-rate")
+		chaosDBRate    = flag.Float64("chaos-db-latency-rate", 0, "Chaos: fraction of repository calls delayed by -chaos-db-latency")
+		chaosWriteFail = flag.Float64("chaos-sse-write-fail-rate", 0, "Chaos: fraction of SSE stream flushes that fail, dropping the connection")
+		chaosPause     = flag.Duration("chaos-consumer-pause", 0, "Chaos: how long the consumer stalls before a message picked by -chaos-consumer-pause-rate")
+		chaosPauseRate = flag.Float64("chaos-consumer-pause-rate", 0, "Chaos: fraction of consumed messages preceded by a -chaos-consumer-pause stall")
+		chaosDelivery  = flag.Float64("chaos-delivery-error-rate", 0, "Chaos: fraction of delivery attempts failed before the SSE send")
+		stageStamps    = flag.Bool("stage-timestamps", false, "Send each notification's pipeline stage timestamps to clients (sse-bench reports latency by stage)")
+		templates      = flag.String("templates", "", "YAML file of title/message templates per event type, over the built-in ones")
+		localesDir     = flag.String("locales-dir", "", "Directory of per-locale template files (es.yaml, pt-BR.yaml), over -templates")
+		prodDropRate   = flag.Float64("producer-drop-rate", 0, "Producer faults: fraction of generated events lost before they are published")
+		prodDupRate    = flag.Float64("producer-duplicate-rate", 0, "Producer faults: fraction of generated events published twice with the same event ID")
+		prodOutEvery   = flag.Duration("producer-outage-every", 0, "Producer faults: pause publishing once per this period (with -producer-outage-duration)")
+		prodOutFor     = flag.Duration("producer-outage-duration", 0, "Producer faults: how long each outage pauses publishing before the generators catch up")
+		announceRate   = flag.Float64("announce-rate", 0, "Announcements per second broadcast to every connected user (segment all), on top of -rate")
+		topicRate      = flag.Float64("topic-rate", 0, "Job alerts per second published to job-alerts:<skill> topics, reaching the users subscribed to them (e.g. with ?topics= on the stream)")
+		maxRecipients  = flag.Int("max-broadcast-recipients", notification.DefaultMaxBroadcastRecipients, "Notifications one broadcast may expand into; larger audiences are poisoned")
+	)
+	flag.Parse()
+
+	// Atomic level so /admin/log-level can change verbosity at runtime
+	logConfig := zap.NewProductionConfig()
+	logger, _ := logConfig.Build()
+	defer logger.Sync()
+	logger = logger.With(zap.String("instance_id", *instanceID))
+
+	if *staleTimeout <= *heartbeat {
+		logger.Fatal("stale timeout must exceed the heartbeat interval",
+			zap.Duration("stale_timeout", *staleTimeout),
+			zap.Duration("heartbeat_interval", *heartbeat))
+	}
+	if *liveness != 0 && (*liveness <= *heartbeat || *liveness >= *staleTimeout) {
+		logger.Fatal("liveness timeout must exceed the heartbeat interval and be below the stale timeout",
+			zap.Duration("liveness_timeout", *liveness),
+			zap.Duration("heartbeat_interval", *heartbeat))
+	}
+
+	h2Mode, err := notification.ParseHTTP2Mode(*http2Mode)
+	if err != nil {
+		logger.Fatal("invalid -http2", zap.Error(err))
+	}
+
+	logger.Info("starting all-in-one notification system",
+		zap.Int("port", *port),
+		zap.Int("users", *numUsers),
+		zap.Int("event_rate", *eventRate))
+
+	startedAt := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := notification.NewMemoryRepository(logger)
+
+	// Fault injection, only wired with -chaos or a -chaos-* rate; PUT
+	// /admin/chaos then changes it at runtime
+	chaosConfig := notification.ChaosConfig{
+		DBLatency:         *chaosDBLatency,
+		DBLatencyRate:     *chaosDBRate,
+		SSEWriteFailRate:  *chaosWriteFail,
+		ConsumerPause:     *chaosPause,
+		ConsumerPauseRate: *chaosPauseRate,
+		DeliveryErrorRate: *chaosDelivery,
+	}
+	if err := chaosConfig.Validate(); err != nil {
+		logger.Fatal("invalid chaos flags", zap.Error(err))
+	}
+	var chaos *notification.Chaos
+	if *chaosOn || chaosConfig.Enabled() {
+		chaos = notification.NewChaos(chaosConfig)
+		logger.Warn("chaos injection enabled", zap.Any("chaos", chaos.Stats()))
+	}
+	store := notification.NewChaosRepository(repo, chaos)
+	bus := producer.NewMemoryBus("notifications", 10000, logger)
+	defer bus.Close()
+	messageEncoding, err := models.ParseMessageEncoding(*encoding)
+	if err != nil {
+		logger.Fatal("invalid message encoding", zap.Error(err))
+	}
+	bus.SetEncoding(messageEncoding)
+
+	sseCompression, err := notification.ParseSSECompression(*compression)
+	if err != nil {
+		logger.Fatal("invalid sse compression", zap.Error(err))
+	}
+	sseRegions, err := notification.ParseRegions(*regions)
+	if err != nil {
+		logger.Fatal("invalid -regions", zap.Error(err))
+	}
+	handlers, err := notification.LoadHandlers(*templates, *localesDir)
+	if err != nil {
+		logger.Fatal("invalid notification templates", zap.Error(err))
+	}
+	sseManager := notification.NewSSEManager(notification.SSEManagerConfig{
+		MaxConnections:    *maxConns,
+		HeartbeatInterval: *heartbeat,
+		StaleTimeout:      *staleTimeout,
+		LivenessTimeout:   *liveness,
+		CleanupInterval:   *staleTimeout / 5,
+		Compression:       sseCompression,
+		FlushInterval:     *flushInterval,
+		FlushMaxFrames:    *flushFrames,
+		BandwidthLimit:    *bandwidth,
+		Regions:           sseRegions,
+		Retry:             *sseRetry,
+		ReconnectAfter:    *reconnectAfter,
+		ReconnectSpread:   *reconnSpread,
+		InstanceID:        *instanceID,
+		StageTimestamps:   *stageStamps,
+		Handlers:          handlers,
+		Chaos:             chaos,
+	}, logger)
+	defer sseManager.Stop()
+	sseManager.SetPreferences(notification.NewPreferenceCache(store, 0, logger))
+
+	// Event types registered through /admin/event-types; this is the only
+	// instance, so its writes apply at once and there is nothing to reload
+	eventTypes := notification.NewEventTypeRegistry(store, handlers, logger)
+
+	// Consumer: in-memory bus → repository
+	idGen, err := idgen.New(*idStrategy, 0)
+	if err != nil {
+		logger.Fatal("failed to initialize id generator", zap.Error(err))
+	}
+	idgen.SetDefault(idGen)
+	consumer := notification.NewConsumerWithReader(bus, store, idGen, logger)
+	consumer.SetEventTypes(eventTypes)
+	consumer.SetAudience(notification.NewAudience(store, sseManager, *maxRecipients))
+	policy, err := notification.ParseLatePolicy(*latePolicy)
+	if err != nil {
+		logger.Fatal("invalid late policy", zap.Error(err))
+	}
+	consumer.SetLateEventPolicy(notification.LateEventConfig{Threshold: *lateThreshold, Policy: policy})
+	routing, err := notification.ParseHeaderRouting(*consumeTypes, *consumePrio, *expressPrio)
+	if err != nil {
+		logger.Fatal("invalid consumer routing", zap.Error(err))
+	}
+	consumer.SetHeaderRouting(routing)
+	consumer.SetChaos(chaos)
+	consumer.SetWorkers(*consumeWork)
+	ingestOverrides, err := notification.ParseIngestOverrides(*ingestOverride)
+	if err != nil {
+		logger.Fatal("invalid ingest quota overrides", zap.Error(err))
+	}
+	ingestQuotas, err := notification.NewIngestQuotas(notification.IngestQuotaConfig{
+		Key:       *ingestKey,
+		Rate:      *ingestRate,
+		Overrides: ingestOverrides,
+		Overflow:  notification.IngestOverflow(*ingestOverflow),
+	}, nil, logger)
+	if err != nil {
+		logger.Fatal("invalid ingest quota", zap.Error(err))
+	}
+	consumer.SetIngestQuotas(ingestQuotas)
+	go func() {
+		if err := consumer.Consume(ctx); err != nil {
+			logger.Error("consumer error", zap.Error(err))
+		}
+	}()
+
+	weights, err := notification.ParseClaimWeights(*claimWeights)
+	if err != nil {
+		logger.Fatal("invalid -claim-weights", zap.Error(err))
+	}
+
+	// Task picker: repository → SSE
+	taskPicker := notification.NewTaskPicker(notification.TaskPickerConfig{
+		InstanceID:         *instanceID,
+		NumPickerWorkers:   2,
+		NumDeliveryWorkers: 8,
+		BatchSize:          100,
+		PollInterval:       100 * time.Millisecond,
+		LeaseDuration:      30 * time.Second,
+		ChannelBufferSize:  1000,
+		MaxInflight:        5000,
+		ClaimPolicy:        notification.ClaimPolicyPriority,
+		ClaimWeights:       weights,
+		ClaimConnectedOnly: *connectedOnly,
+		MaxGroupSize:       20,
+		RetryBackoff:       *retryBackoff,
+		Quotas: notification.NewDeliveryQuotas(notification.QuotaConfig{
+			DeliveryRate: *deliveryRate,
+			UserLimit:    *userLimit,
+			UserWindow:   *userWindow,
+		}, nil, logger),
+		Chaos: chaos,
+	}, store, sseManager, logger)
+
+	// Restore before the picker starts claiming
+	var snapshotStore notification.SnapshotStore
+	if *snapshotFile != "" {
+		snapshotStore = notification.FileSnapshotStore{Path: *snapshotFile}
+	}
+	startedAt = notification.RestoreRunState(context.Background(), snapshotStore, *instanceID, taskPicker, sseManager, repo, logger)
+	taskPicker.Start()
+	defer taskPicker.Stop()
+
+	// Event generators, rate split across profiles by their scenario weight
+	scenario, err := generator.NewScenario(*scenarioFile)
+	if err != nil {
+		logger.Fatal("failed to load scenario", zap.Error(err))
+	}
+	if scenario.Rate > 0 {
+		*eventRate = scenario.Rate
+	}
+	// Producer faults, off unless a -producer-* flag is set; the generator
+	// summary on shutdown counts what they dropped and duplicated
+	faultConfig := generator.FaultConfig{
+		DropRate:       *prodDropRate,
+		DuplicateRate:  *prodDupRate,
+		OutageEvery:    *prodOutEvery,
+		OutageDuration: *prodOutFor,
+	}
+	if err := faultConfig.Validate(); err != nil {
+		logger.Fatal("invalid producer fault flags", zap.Error(err))
+	}
+	var faults *generator.Faults
+	if faultConfig.Enabled() {
+		faults = generator.NewFaults(faultConfig)
+		logger.Warn("producer fault injection enabled",
+			zap.Float64("drop_rate", faultConfig.DropRate),
+			zap.Float64("duplicate_rate", faultConfig.DuplicateRate),
+			zap.Duration("outage_every", faultConfig.OutageEvery),
+			zap.Duration("outage_duration", faultConfig.OutageDuration))
+	}
+	genStats := generator.NewStats()
+	if *eventRate > 0 {
+		users, err := generator.NewPopulation(*usersFile, startup.ProducerUserPrefix, *numUsers)
+		if err != nil {
+			logger.Fatal("failed to load user population", zap.Error(err))
+		}
+		totalWeight := 0.0
+		for _, profile := range generator.Profiles {
+			totalWeight += scenario.Weight(profile)
+		}
+		for _, profile := range generator.Profiles {
+			rate := float64(*eventRate) * scenario.Weight(profile) / totalWeight
+			if gen := scenario.NewGenerator(profile, rate, users, *numTenants); gen != nil {
+				gen.SetStats(genStats)
+				gen.SetFaults(faults)
+				go gen.Run(ctx, bus, logger)
+			}
+		}
+		go scenario.RunSchedule(ctx, float64(*eventRate), logger)
+	}
+	if *announceRate > 0 {
+		users, err := generator.NewPopulation(*usersFile, startup.ProducerUserPrefix, *numUsers)
+		if err != nil {
+			logger.Fatal("failed to load user population", zap.Error(err))
+		}
+		if gen := scenario.NewGenerator(generator.AnnouncementsProfile, *announceRate, users, *numTenants); gen != nil {
+			gen.SetStats(genStats)
+			go gen.Run(ctx, bus, logger)
+		} else {
+			logger.Warn("scenario gives announcements no events to produce")
+		}
+	}
+	if *topicRate > 0 {
+		users, err := generator.NewPopulation(*usersFile, startup.ProducerUserPrefix, *numUsers)
+		if err != nil {
+			logger.Fatal("failed to load user population", zap.Error(err))
+		}
+		if gen := scenario.NewGenerator(generator.JobAlertsProfile, *topicRate, users, *numTenants); gen != nil {
+			gen.SetStats(genStats)
+			go gen.Run(ctx, bus, logger)
+		} else {
+			logger.Warn("scenario gives job alerts no events to produce")
+		}
+	}
+
+	admission := notification.NewAdmissionController(notification.AdmissionConfig{
+		AcceptRate:   500,
+		AcceptBurst:  1000,
+		MaxQueueWait: 2 * time.Second,
+		MaxQueued:    1000,
+	}, logger)
+
+	// Built-in canary probes the bus → repository → SSE path
+	var canary *notification.Canary
+	if *canaryInterval > 0 {
+		canary = notification.NewCanary(notification.CanaryConfig{
+			Interval:         *canaryInterval,
+			Users:            2,
+			Timeout:          30 * time.Second,
+			LatencyThreshold: 2 * time.Second,
+		}, bus, sseManager, logger)
+		canary.Start(ctx)
+	}
+
+	sloTargets := notification.SLOTargets{
+		High:   1 * time.Second,
+		Medium: 5 * time.Second,
+		Low:    30 * time.Second,
+	}
+	slaMonitor := notification.NewSLAMonitor(repo, sloTargets, notification.SLAObjectives{
+		High:   99,
+		Medium: 95,
+		Low:    90,
+	}, 5*time.Minute, *slaInterval, logger)
+	slaMonitor.Start(ctx)
+
+	// No Kafka here, so sweep summaries are only exposed on /admin/expiry
+	expirySweeper := notification.NewExpirySweeper(repo, nil, *instanceID, notification.ExpiryConfig{
+		Interval: *expiryInterval,
+		MaxAge:   *maxAge,
+	}, logger)
+	expirySweeper.Start(ctx)
+
+	reprioritizeRules, err := notification.ParseReprioritizeRules(*reprioRules)
+	if err != nil {
+		logger.Fatal("invalid -reprioritize", zap.Error(err))
+	}
+	reprioritizer := notification.NewReprioritizer(repo, notification.ReprioritizeConfig{
+		Interval: *reprioInterval,
+		Rules:    reprioritizeRules,
+	}, logger)
+	reprioritizer.Start(ctx)
+
+	leakWatchdog := notification.NewLeakWatchdog(sseManager, taskPicker, 30*time.Second, logger)
+	leakWatchdog.Start(ctx)
+
+	soakConfig, err := soak.ConfigFromEnv()
+	if err != nil {
+		logger.Fatal("invalid soak config", zap.Error(err))
+	}
+	if *soakInterval > 0 {
+		soakConfig.Interval = *soakInterval
+	}
+	soak.New("all-in-one", soakConfig, logger).Start(ctx)
+
+	admin := notification.NewAdminHandler(store, sseManager, taskPicker, consumer, canary, slaMonitor, expirySweeper, reprioritizer, leakWatchdog, admission, chaos, eventTypes, sloTargets, logConfig.Level, logger)
+
+	// No external dependencies to probe: /health covers worker liveness only
+	health := notification.NewHealthChecker(2*time.Second,
+		notification.ConsumerHealthCheck(consumer, 30*time.Second),
+		notification.TaskPickerHealthCheck(taskPicker, 30*time.Second),
+		notification.ChannelSaturationCheck(taskPicker, 0.9))
+
+	publish := notification.NewPublishHandler(store, taskPicker, idGen, logger)
+	publish.SetMaxBatch(*publishBatch)
+	publish.SetEventTypes(eventTypes)
+
+	srv := notification.NewHTTPServer(fmt.Sprintf(":%d", *port), notification.NewRouter(notification.RouterDeps{
+		SSEManager: sseManager,
+		Admission:  admission,
+		Admin:      admin,
+		Publish:    publish,
+		Repository: store,
+		Health:     health,
+		InstanceID: *instanceID,
+		Logger:     logger,
+	}), notification.HTTPServerConfig{
+		ReadHeaderTimeout:    10 * time.Second,
+		IdleTimeout:          2 * time.Minute,
+		HTTP2:                h2Mode,
+		MaxConcurrentStreams: *maxStreams,
+	})
+
+	go func() {
+		logger.Info("starting HTTP server", zap.Int("port", *port), zap.String("http2", string(h2Mode)))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("failed to start server", zap.Error(err))
+		}
+	}()
+
+	health.SetReady(true)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("shutting down all-in-one...")
+	notification.Drain(context.Background(), notification.DrainConfig{Timeout: *drainTimeout}, health, sseManager, taskPicker, logger)
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server forced to shutdown", zap.Error(err))
+	}
+
+	taskPicker.Stop()
+	genStats.Log(logger, "event generator summary")
+	notification.EmitRunSummary(shutdownCtx, repo, taskPicker, sseManager, sloTargets, startedAt, *summaryFile, logger)
+	notification.SaveRunState(shutdownCtx, snapshotStore, *instanceID, startedAt, taskPicker, sseManager, repo, logger)
+
+	logger.Info("all-in-one exited")
+}
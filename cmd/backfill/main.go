@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/config"
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/notification"
+)
+
+// backfill re-reads a window of the notifications topic and re-inserts every
+// event whose event_id is missing from the notifications table: the recovery
+// path when a consumer bug or database outage lost persistence for part of a
+// run. Re-inserted notifications are pending, so a running service delivers
+// them; they are flagged late so they stay out of SLO/SLA statistics.
+func main() {
+	cfg, err := config.Load("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var (
+		brokers    = flag.String("brokers", strings.Join(cfg.Kafka.Brokers, ","), "Comma-separated Kafka brokers")
+		topic      = flag.String("topic", cfg.Kafka.Topic, "Topic to re-read")
+		partition  = flag.Int("partition", -1, "Partition to re-read (-1 = all)")
+		fromOffset = flag.Int64("from-offset", -1, "First offset to read (-1 = earliest retained)")
+		toOffset   = flag.Int64("to-offset", -1, "Last offset to read, inclusive (-1 = latest at start)")
+		fromTime   = flag.String("from-time", "", "Start at the first message at or after this RFC3339 time (overrides -from-offset)")
+		toTime     = flag.String("to-time", "", "Stop at the first message after this RFC3339 time")
+		batchSize  = flag.Int("batch", 500, "Events checked against the database per query")
+		dryRun     = flag.Bool("dry-run", false, "Report gaps without inserting anything")
+		markLate   = flag.Bool("mark-late", true, "Flag re-inserted notifications late (excluded from SLO/SLA stats)")
+	)
+	flag.Parse()
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	w := window{fromOffset: *fromOffset, toOffset: *toOffset}
+	if w.fromTime, err = parseTime(*fromTime); err != nil {
+		logger.Fatal("invalid -from-time", zap.Error(err))
+	}
+	if w.toTime, err = parseTime(*toTime); err != nil {
+		logger.Fatal("invalid -to-time", zap.Error(err))
+	}
+	if *batchSize <= 0 {
+		logger.Fatal("-batch must be positive", zap.Int("batch", *batchSize))
+	}
+
+	repo, err := notification.NewPostgresRepository(
+		cfg.PostgreSQL.Host,
+		cfg.PostgreSQL.Port,
+		cfg.PostgreSQL.Database,
+		cfg.PostgreSQL.User,
+		cfg.PostgreSQL.Password,
+		notification.PostgresPoolConfig{
+			MaxConns:        cfg.PostgreSQL.MaxConns,
+			MinConns:        cfg.PostgreSQL.MinConns,
+			ConnMaxLifetime: cfg.PostgreSQL.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.PostgreSQL.ConnMaxIdleTime,
+			QueryTimeout:    cfg.PostgreSQL.QueryTimeout,
+			SlowQuery:       cfg.PostgreSQL.SlowQuery,
+		},
+		logger,
+	)
+	if err != nil {
+		logger.Fatal("failed to initialize postgres repository", zap.Error(err))
+	}
+	defer repo.Close(context.Background())
+
+	idGen, err := idgen.New(cfg.IDGeneration.Strategy, cfg.IDGeneration.NodeID)
+	if err != nil {
+		logger.Fatal("failed to initialize id generator", zap.Error(err))
+	}
+	idgen.SetDefault(idGen)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	brokerList := strings.Split(*brokers, ",")
+	partitions, err := topicPartitions(ctx, brokerList[0], *topic, *partition)
+	if err != nil {
+		logger.Fatal("failed to read topic partitions", zap.Error(err))
+	}
+
+	b := &backfiller{
+		repo:      repo,
+		idGen:     idGen,
+		brokers:   brokerList,
+		topic:     *topic,
+		batchSize: *batchSize,
+		dryRun:    *dryRun,
+		markLate:  *markLate,
+		logger:    logger,
+	}
+
+	var reports []*partitionReport
+	for _, p := range partitions {
+		report, err := b.run(ctx, p, w)
+		if report != nil {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			printReports(reports, *dryRun)
+			logger.Fatal("backfill failed", zap.Int("partition", p), zap.Error(err))
+		}
+	}
+
+	printReports(reports, *dryRun)
+}
+
+// window bounds the messages re-read from each partition
+type window struct {
+	fromOffset, toOffset int64
+	fromTime, toTime     time.Time
+}
+
+// gap is a run of consecutive offsets whose events were all missing
+type gap struct {
+	first, last     int64
+	firstAt, lastAt time.Time
+	missing         int
+}
+
+type partitionReport struct {
+	partition   int
+	startOffset int64
+	endOffset   int64 // Exclusive
+	scanned     int
+	missing     int
+	inserted    int
+	duplicates  int // Same event_id seen more than once in the window
+	noEventID   int // Can't be checked, never re-inserted
+	parseErrors int
+	gaps        []gap
+}
+
+type backfiller struct {
+	repo      notification.Repository
+	idGen     idgen.Generator
+	brokers   []string
+	topic     string
+	batchSize int
+	dryRun    bool
+	markLate  bool
+	logger    *zap.Logger
+}
+
+// pendingEvent is a parsed message waiting for its batch's existence check
+type pendingEvent struct {
+	offset int64
+	at     time.Time
+	msg    models.KafkaMessage
+}
+
+// run re-reads one partition's window and re-inserts missing events
+func (b *backfiller) run(ctx context.Context, partition int, w window) (*partitionReport, error) {
+	start, end, err := b.offsetRange(ctx, partition, w)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &partitionReport{partition: partition, startOffset: start, endOffset: end}
+	if start >= end {
+		b.logger.Info("partition window is empty", zap.Int("partition", partition))
+		return report, nil
+	}
+
+	b.logger.Info("re-reading partition",
+		zap.Int("partition", partition),
+		zap.Int64("from_offset", start),
+		zap.Int64("to_offset", end-1))
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   b.brokers,
+		Topic:     b.topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(start); err != nil {
+		return report, fmt.Errorf("failed to seek to offset %d: %w", start, err)
+	}
+
+	seen := make(map[string]bool)
+	var batch []pendingEvent
+	for {
+		m, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return report, fmt.Errorf("failed to read message: %w", err)
+		}
+		if !w.toTime.IsZero() && m.Time.After(w.toTime) {
+			break
+		}
+
+		report.scanned++
+		msg, err := models.DecodeKafkaMessage(m.Value, header(m, models.HeaderContentType), header(m, models.HeaderSchemaVersion))
+		switch {
+		case err != nil:
+			report.parseErrors++
+		case msg.EventID == "":
+			report.noEventID++
+		case seen[msg.EventID]:
+			report.duplicates++
+		default:
+			seen[msg.EventID] = true
+			batch = append(batch, pendingEvent{offset: m.Offset, at: m.Time, msg: *msg})
+		}
+
+		if len(batch) >= b.batchSize {
+			if err := b.flush(ctx, batch, report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+		if m.Offset >= end-1 {
+			break
+		}
+	}
+
+	if err := b.flush(ctx, batch, report); err != nil {
+		return report, err
+	}
+
+	b.logger.Info("partition done",
+		zap.Int("partition", partition),
+		zap.Int("scanned", report.scanned),
+		zap.Int("missing", report.missing),
+		zap.Int("inserted", report.inserted))
+	return report, nil
+}
+
+// flush checks a batch against the database, records gaps and re-inserts
+// the missing events
+func (b *backfiller) flush(ctx context.Context, batch []pendingEvent, report *partitionReport) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(batch))
+	for i, ev := range batch {
+		ids[i] = ev.msg.EventID
+	}
+	existing, err := b.repo.ExistingEventIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	var missing []*models.Notification
+	for i := range batch {
+		ev := &batch[i]
+		if existing[ev.msg.EventID] {
+			continue
+		}
+		report.missing++
+		report.recordGap(ev.offset, ev.at)
+
+		notif := notification.NotificationFromMessage(b.idGen.NewID(), &ev.msg)
+		notif.IsLate = b.markLate
+		missing = append(missing, notif)
+	}
+
+	if b.dryRun || len(missing) == 0 {
+		return nil
+	}
+	if err := b.repo.BatchInsert(ctx, missing); err != nil {
+		return err
+	}
+	report.inserted += len(missing)
+	return nil
+}
+
+// recordGap extends the last gap when offset follows it, else opens a new one
+func (r *partitionReport) recordGap(offset int64, at time.Time) {
+	if n := len(r.gaps); n > 0 && r.gaps[n-1].last == offset-1 {
+		g := &r.gaps[n-1]
+		g.last, g.lastAt = offset, at
+		g.missing++
+		return
+	}
+	r.gaps = append(r.gaps, gap{first: offset, last: offset, firstAt: at, lastAt: at, missing: 1})
+}
+
+// offsetRange resolves the window to [start, end) offsets on the partition,
+// clamped to what the broker still retains
+func (b *backfiller) offsetRange(ctx context.Context, partition int, w window) (int64, int64, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", b.brokers[0], b.topic, partition)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to dial partition leader: %w", err)
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read partition offsets: %w", err)
+	}
+
+	start := first
+	switch {
+	case !w.fromTime.IsZero():
+		if start, err = conn.ReadOffset(w.fromTime); err != nil {
+			return 0, 0, fmt.Errorf("failed to look up offset for %s: %w", w.fromTime.Format(time.RFC3339), err)
+		}
+	case w.fromOffset >= 0:
+		start = w.fromOffset
+	}
+	if start < first {
+		b.logger.Warn("window starts before the oldest retained offset, older events are gone",
+			zap.Int("partition", partition),
+			zap.Int64("requested", start),
+			zap.Int64("oldest", first))
+		start = first
+	}
+
+	end := last // ReadOffsets returns the next offset to be written
+	if w.toOffset >= 0 && w.toOffset+1 < end {
+		end = w.toOffset + 1
+	}
+	return start, end, nil
+}
+
+// topicPartitions lists the topic's partitions, or just the one requested
+func topicPartitions(ctx context.Context, broker, topic string, only int) ([]int, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach broker %s: %w", broker, err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("topic %q not found: %w", topic, err)
+	}
+
+	var ids []int
+	for _, p := range partitions {
+		if only < 0 || p.ID == only {
+			ids = append(ids, p.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("topic %q has no partition %d", topic, only)
+	}
+	return ids, nil
+}
+
+// header returns the message's first header with key, or ""
+func header(m kafka.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// maxGapsShown caps the gap ranges printed per partition
+const maxGapsShown = 20
+
+func printReports(reports []*partitionReport, dryRun bool) {
+	action := "INSERTED"
+	if dryRun {
+		action = "INSERTED(dry)"
+	}
+
+	fmt.Println()
+	fmt.Printf("%-10s %22s %10s %10s %14s %8s %10s %8s\n",
+		"PARTITION", "OFFSETS", "SCANNED", "MISSING", action, "DUPES", "NO_EVENT", "GAPS")
+
+	var scanned, missing, inserted int
+	for _, r := range reports {
+		fmt.Printf("%-10d %22s %10d %10d %14d %8d %10d %8d\n",
+			r.partition,
+			fmt.Sprintf("%d-%d", r.startOffset, r.endOffset-1),
+			r.scanned, r.missing, r.inserted, r.duplicates, r.noEventID+r.parseErrors, len(r.gaps))
+		scanned += r.scanned
+		missing += r.missing
+		inserted += r.inserted
+	}
+	fmt.Printf("%-10s %22s %10d %10d %14d\n", "TOTAL", "", scanned, missing, inserted)
+
+	for _, r := range reports {
+		if len(r.gaps) == 0 {
+			continue
+		}
+		fmt.Printf("\nPartition %d gaps:\n", r.partition)
+		for i, g := range r.gaps {
+			if i == maxGapsShown {
+				fmt.Printf("  ... %d more\n", len(r.gaps)-maxGapsShown)
+				break
+			}
+			fmt.Printf("  offsets %d-%d  %d events  %s .. %s\n",
+				g.first, g.last, g.missing,
+				g.firstAt.Format(time.RFC3339), g.lastAt.Format(time.RFC3339))
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/auth"
+	"notification-delivery-system/internal/generator"
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/kafkaadmin"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/producer"
+	"notification-delivery-system/internal/soak"
+	"notification-delivery-system/internal/startup"
+	"notification-delivery-system/internal/tracing"
+)
+
+// defaultProfiles are the rates of the job, connections and followers
+// services this binary replaces
+const defaultProfiles = "job=100,connections=50,followers=75"
+
+func main() {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	logger.Info("starting event generator")
+
+	// Get config from environment
+	brokersEnv := os.Getenv("KAFKA_BROKERS")
+	if brokersEnv == "" {
+		brokersEnv = "localhost:9092"
+	}
+	brokers := strings.Split(brokersEnv, ",")
+
+	topic := os.Getenv("KAFKA_TOPIC")
+	if topic == "" {
+		topic = "notifications" // Must match the notification-service consumer topic
+	}
+
+	// PROFILES picks the event profiles and their rates; without it EVENT_RATE
+	// (if set) applies to every profile, as it did to each producer service
+	profilesEnv := os.Getenv("PROFILES")
+	if profilesEnv == "" {
+		profilesEnv = defaultProfiles
+		if rate := os.Getenv("EVENT_RATE"); rate != "" {
+			profilesEnv = fmt.Sprintf("job=%s,connections=%s,followers=%s", rate, rate, rate)
+		}
+	}
+	profiles, err := generator.ParseProfileRates(profilesEnv)
+	if err != nil {
+		logger.Fatal("invalid profiles", zap.Error(err))
+	}
+
+	// Each profile fans out over several goroutines; the producer batches
+	// their writes together
+	publishers := 4
+	if publishersStr := os.Getenv("PUBLISHERS"); publishersStr != "" {
+		if n, err := strconv.Atoi(publishersStr); err == nil && n > 0 {
+			publishers = n
+		}
+	}
+
+	statsInterval := 10 * time.Second
+	if intervalStr := os.Getenv("STATS_INTERVAL"); intervalStr != "" {
+		if d, err := time.ParseDuration(intervalStr); err == nil {
+			statsInterval = d
+		}
+	}
+
+	numUsersStr := os.Getenv("NUM_USERS")
+	numUsers := 10000
+	if numUsersStr != "" {
+		if users, err := strconv.Atoi(numUsersStr); err == nil {
+			numUsers = users
+		}
+	}
+
+	// Users are spread over tenant_0..tenant_<N-1> (see generator.TenantFor)
+	numTenants := 1
+	if tenantsStr := os.Getenv("NUM_TENANTS"); tenantsStr != "" {
+		if tenants, err := strconv.Atoi(tenantsStr); err == nil {
+			numTenants = tenants
+		}
+	}
+
+	// USERS_FILE replaces user_1..user_<NUM_USERS> with IDs loaded from a
+	// file (e.g. UUIDs), one per line
+	users, err := generator.NewPopulation(os.Getenv("USERS_FILE"), startup.ProducerUserPrefix, numUsers)
+	if err != nil {
+		logger.Fatal("failed to load user population", zap.Error(err))
+	}
+
+	// ID_STRATEGY (and ID_NODE_ID) apply to generated event and trace IDs
+	// too, e.g. sequential for reproducible runs
+	var nodeID int64
+	if nodeIDStr := os.Getenv("ID_NODE_ID"); nodeIDStr != "" {
+		if nodeID, err = strconv.ParseInt(nodeIDStr, 10, 64); err != nil {
+			logger.Fatal("invalid ID_NODE_ID", zap.Error(err))
+		}
+	}
+	idGen, err := idgen.New(os.Getenv("ID_STRATEGY"), nodeID)
+	if err != nil {
+		logger.Fatal("failed to initialize id generator", zap.Error(err))
+	}
+	idgen.SetDefault(idGen)
+
+	// Initialize tracing (enabled when OTEL_EXPORTER_OTLP_ENDPOINT is set)
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.ConfigFromEnv("event-generator"), logger)
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	// KAFKA_PARTITIONS provisions the topic first (and grows it), so the
+	// writer does not auto-create it with a single partition
+	if partitionsStr := os.Getenv("KAFKA_PARTITIONS"); partitionsStr != "" {
+		spec := kafkaadmin.TopicSpec{Name: topic, ReplicationFactor: 1}
+		spec.Partitions, _ = strconv.Atoi(partitionsStr)
+		if replication := os.Getenv("KAFKA_REPLICATION_FACTOR"); replication != "" {
+			spec.ReplicationFactor, _ = strconv.Atoi(replication)
+		}
+		provisionCtx, cancelProvision := context.WithTimeout(context.Background(), time.Minute)
+		err := kafkaadmin.New(brokers, logger).Ensure(provisionCtx, spec)
+		cancelProvision()
+		if err != nil {
+			logger.Fatal("failed to provision kafka topic", zap.Error(err))
+		}
+	}
+
+	stats := generator.NewStats()
+
+	// INGEST_URL posts events to notification-service's publish API
+	// (POST /notifications/batch) instead of Kafka, to compare protocols
+	var pub producer.Publisher
+	if ingestURL := os.Getenv("INGEST_URL"); ingestURL != "" {
+		for _, pr := range profiles {
+			if pr.Profile.Broadcast() {
+				logger.Fatal("broadcast profiles are expanded by the consumer and need Kafka, not INGEST_URL", zap.String("profile", pr.Profile.Name))
+			}
+		}
+		httpProd := producer.NewHTTPProducer(ingestURL, logger)
+		defer httpProd.Close()
+		// INGEST_TOKEN is a publish-scoped token; AUTH_SIGNING_KEY mints one
+		// valid for a day instead
+		token := os.Getenv("INGEST_TOKEN")
+		if signingKey := os.Getenv("AUTH_SIGNING_KEY"); token == "" && signingKey != "" {
+			minted, err := auth.SignService(auth.ScopePublish, "event-generator", 24*time.Hour, []byte(signingKey))
+			if err != nil {
+				logger.Fatal("failed to mint ingest token", zap.Error(err))
+			}
+			token = minted
+		}
+		httpProd.SetToken(token)
+		pub = httpProd
+	} else {
+		prod, err := producer.NewProducer(brokers, topic, logger)
+		if err != nil {
+			logger.Fatal("failed to create producer", zap.Error(err))
+		}
+		defer prod.Close()
+
+		// MESSAGE_ENCODING=protobuf needs consumers on schema 2 or later
+		encoding, err := models.ParseMessageEncoding(os.Getenv("MESSAGE_ENCODING"))
+		if err != nil {
+			logger.Fatal("invalid message encoding", zap.Error(err))
+		}
+		prod.SetEncoding(encoding)
+
+		// PRODUCER_ASYNC=true stops generators waiting for acks; failures then
+		// show up as undelivered in the summary
+		if async, _ := strconv.ParseBool(os.Getenv("PRODUCER_ASYNC")); async {
+			prod.SetAsync(func(msg *models.KafkaMessage, err error) {
+				if err != nil {
+					stats.RecordUndelivered()
+				}
+			})
+		}
+		pub = prod
+	}
+
+	// METRICS_ADDR serves the producer metrics for Prometheus, e.g. :9091
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go func() {
+			if err := http.ListenAndServe(addr, promhttp.Handler()); err != nil {
+				logger.Error("metrics server failed", zap.Error(err))
+			}
+		}()
+	}
+
+	// SCENARIO_FILE shapes the traffic: event weights, zipf users, bursts
+	// and a diurnal curve (see configs/scenario.example.yaml). Its rate
+	// replaces every profile's.
+	scenario, err := generator.NewScenario(os.Getenv("SCENARIO_FILE"))
+	if err != nil {
+		logger.Fatal("failed to load scenario", zap.Error(err))
+	}
+
+	// PRODUCER_DROP_RATE, PRODUCER_DUPLICATE_RATE and PRODUCER_OUTAGE_EVERY
+	// with PRODUCER_OUTAGE_DURATION simulate an imperfect upstream; the
+	// summary then counts what was dropped and duplicated for loss accounting
+	faultConfig, err := generator.FaultConfigFromEnv()
+	if err != nil {
+		logger.Fatal("invalid producer fault config", zap.Error(err))
+	}
+	var faults *generator.Faults
+	if faultConfig.Enabled() {
+		faults = generator.NewFaults(faultConfig)
+		logger.Warn("producer fault injection enabled",
+			zap.Float64("drop_rate", faultConfig.DropRate),
+			zap.Float64("duplicate_rate", faultConfig.DuplicateRate),
+			zap.Duration("outage_every", faultConfig.OutageEvery),
+			zap.Duration("outage_duration", faultConfig.OutageDuration))
+	}
+
+	var gens []*generator.Generator
+	baseRate := 0.0 // Events per second across profiles before the scenario's curve
+	for _, pr := range profiles {
+		rate := pr.Rate
+		if scenario.Rate > 0 {
+			rate = float64(scenario.Rate)
+		}
+		if rate == 0 {
+			continue
+		}
+		for i := 0; i < publishers; i++ {
+			gen := scenario.NewGenerator(pr.Profile, rate/float64(publishers), users, numTenants)
+			if gen == nil {
+				logger.Warn("scenario gives profile no events to produce", zap.String("profile", pr.Profile.Name))
+				break
+			}
+			gen.SetStats(stats)
+			gen.SetFaults(faults)
+			gens = append(gens, gen)
+			baseRate += rate / float64(publishers)
+		}
+	}
+	if len(gens) == 0 {
+		logger.Fatal("no profile has events to produce")
+	}
+
+	logger.Info("event generator started",
+		zap.String("profiles", profilesEnv),
+		zap.Int("publishers_per_profile", publishers),
+		zap.Int("num_users", users.Len()),
+		zap.Int("num_tenants", numTenants))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Wait for interrupt
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		logger.Info("shutting down event generator")
+		cancel()
+	}()
+
+	go scenario.RunSchedule(ctx, baseRate, logger)
+
+	// SOAK_INTERVAL enables the soak-test leak detector (goroutines, heap, FDs)
+	soakConfig, err := soak.ConfigFromEnv()
+	if err != nil {
+		logger.Fatal("invalid soak config", zap.Error(err))
+	}
+	soak.New("event-generator", soakConfig, logger).Start(ctx)
+
+	if statsInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(statsInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					stats.Log(logger, "produced events")
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for _, gen := range gens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gen.Run(ctx, pub, logger)
+		}()
+	}
+	wg.Wait()
+
+	stats.Log(logger, "event generator summary")
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/generator"
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/notification"
+	"notification-delivery-system/internal/startup"
+)
+
+// id-bench compares notification ID strategies on the real repository code
+// paths: concurrent BatchInsert, then ClaimBatch + BatchUpdateStatus until the
+// table is drained. With -flush-batch it then times BatchUpdateStatus flushes
+// of that many rows against the statement-per-row flush it replaced. The
+// notifications table is TRUNCATED before each run, so point it at a scratch
+// database with scripts/postgres-schema.sql applied.
+func main() {
+	var (
+		host       = flag.String("host", envOr("POSTGRES_HOST", "localhost"), "PostgreSQL host")
+		port       = flag.Int("port", 5432, "PostgreSQL port")
+		database   = flag.String("database", "notifications_idbench", "Scratch database (its notifications table is truncated)")
+		user       = flag.String("user", envOr("POSTGRES_USER", "postgres"), "PostgreSQL user")
+		password   = flag.String("password", envOr("POSTGRES_PASSWORD", "postgres"), "PostgreSQL password")
+		strategies = flag.String("strategies", "uuidv4,uuidv7,ulid,snowflake", "Comma-separated ID strategies to compare")
+		rows       = flag.Int("rows", 200000, "Rows inserted per strategy")
+		batchSize  = flag.Int("batch-size", 500, "Rows per BatchInsert / ClaimBatch call")
+		workers    = flag.Int("workers", 8, "Concurrent insert and claim workers")
+		numUsers   = flag.Int("users", 10000, "Distinct user IDs")
+		usersFile  = flag.String("users-file", "", "Draw user IDs (e.g. UUIDs) from this file, one per line, instead of user_1..user_N")
+		flushBatch = flag.Int("flush-batch", 0, "Also time status flushes of this many rows, single statement vs per row (0 skips)")
+		maxConns   = flag.Int("max-conns", 50, "Repository connection pool size")
+	)
+	flag.Parse()
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	if *database == "notifications" {
+		logger.Fatal("refusing to truncate the main notifications database, use a scratch database")
+	}
+
+	users, err := generator.NewPopulation(*usersFile, startup.ProducerUserPrefix, *numUsers)
+	if err != nil {
+		logger.Fatal("failed to load user population", zap.Error(err))
+	}
+
+	repo, err := notification.NewPostgresRepository(*host, *port, *database, *user, *password,
+		notification.PostgresPoolConfig{MaxConns: *maxConns}, logger)
+	if err != nil {
+		logger.Fatal("failed to initialize postgres repository", zap.Error(err))
+	}
+	defer repo.Close(context.Background())
+
+	// Separate handle for TRUNCATE, index size queries and the per-row flush
+	// baseline, none of which the repository exposes
+	db, err := pgxpool.New(context.Background(), fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable",
+		*host, *port, *database, *user, *password))
+	if err != nil {
+		logger.Fatal("failed to open postgres connection", zap.Error(err))
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var results []result
+
+	for _, strategy := range strings.Split(*strategies, ",") {
+		gen, err := idgen.New(strings.TrimSpace(strategy), 1)
+		if err != nil {
+			logger.Fatal("invalid strategy", zap.Error(err))
+		}
+
+		if _, err := db.Exec(ctx, "TRUNCATE notifications"); err != nil {
+			logger.Fatal("failed to truncate notifications", zap.Error(err))
+		}
+
+		logger.Info("benchmarking id strategy", zap.String("strategy", gen.Name()), zap.Int("rows", *rows))
+
+		r := result{strategy: gen.Name()}
+		r.insertDuration, err = runInserts(ctx, repo, gen, *rows, *batchSize, *workers, users)
+		if err != nil {
+			logger.Fatal("insert phase failed", zap.String("strategy", gen.Name()), zap.Error(err))
+		}
+
+		if err := db.QueryRow(ctx, "SELECT pg_relation_size('notifications_pkey')").Scan(&r.pkeyBytes); err != nil {
+			logger.Fatal("failed to read primary key index size", zap.Error(err))
+		}
+
+		r.claimDuration, r.claimed, err = runClaims(ctx, repo, *batchSize, *workers)
+		if err != nil {
+			logger.Fatal("claim phase failed", zap.String("strategy", gen.Name()), zap.Error(err))
+		}
+		r.rows = *rows
+
+		results = append(results, r)
+	}
+
+	printResults(results)
+
+	if *flushBatch <= 0 {
+		return
+	}
+	var flushes []flushResult
+	for _, perRow := range []bool{false, true} {
+		if _, err := db.Exec(ctx, "TRUNCATE notifications, delivery_attempts"); err != nil {
+			logger.Fatal("failed to truncate notifications", zap.Error(err))
+		}
+		gen, _ := idgen.New(idgen.StrategyUUIDv7, 1)
+		if _, err := runInserts(ctx, repo, gen, *rows, *batchSize, *workers, users); err != nil {
+			logger.Fatal("insert phase failed", zap.Error(err))
+		}
+
+		logger.Info("benchmarking status flushes", zap.Int("flush_batch", *flushBatch), zap.Bool("per_row", perRow))
+		f, err := runFlushes(ctx, repo, db, *flushBatch, perRow)
+		if err != nil {
+			logger.Fatal("flush phase failed", zap.Bool("per_row", perRow), zap.Error(err))
+		}
+		flushes = append(flushes, f)
+	}
+	printFlushResults(flushes, *flushBatch)
+}
+
+type result struct {
+	strategy       string
+	rows           int
+	claimed        int
+	insertDuration time.Duration
+	claimDuration  time.Duration
+	pkeyBytes      int64
+}
+
+// runInserts splits rows across workers, each calling BatchInsert
+func runInserts(ctx context.Context, repo *notification.PostgresRepository, gen idgen.Generator, rows, batchSize, workers int, users *generator.Population) (time.Duration, error) {
+	batches := make(chan int)
+	errCh := make(chan error, workers)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range batches {
+				if err := repo.BatchInsert(ctx, newNotifications(gen, n, users)); err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}()
+	}
+
+	for remaining := rows; remaining > 0; remaining -= batchSize {
+		n := batchSize
+		if remaining < n {
+			n = remaining
+		}
+		select {
+		case batches <- n:
+		case err := <-errCh:
+			close(batches)
+			wg.Wait()
+			return 0, err
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	select {
+	case err := <-errCh:
+		return 0, err
+	default:
+	}
+	return time.Since(start), nil
+}
+
+// runClaims drains the table through ClaimBatch and BatchUpdateStatus
+func runClaims(ctx context.Context, repo *notification.PostgresRepository, batchSize, workers int) (time.Duration, int, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		claimed  int
+		firstErr error
+	)
+
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			instanceID := fmt.Sprintf("id-bench-%d", worker)
+			for {
+				batch, err := repo.ClaimBatch(ctx, instanceID, batchSize, time.Minute, notification.ClaimPolicyPriority)
+				if err == nil && len(batch) > 0 {
+					updates := make([]*notification.StatusUpdate, len(batch))
+					for j, nb := range batch {
+						updates[j] = &notification.StatusUpdate{NotificationID: nb.NotificationID, Status: "pushed"}
+					}
+					err = repo.BatchUpdateStatus(ctx, updates)
+				}
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				claimed += len(batch)
+				mu.Unlock()
+
+				if err != nil || len(batch) == 0 {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return time.Since(start), claimed, firstErr
+}
+
+type flushResult struct {
+	mode      string
+	rows      int
+	durations []time.Duration
+}
+
+// runFlushes claims the table flushBatch rows at a time and times each
+// BatchUpdateStatus (or, with perRow, updateStatusPerRow on db) call on its
+// own. Updates carry attempt details, and one in five fails with a retry
+// time, as the picker's flushes do.
+func runFlushes(ctx context.Context, repo *notification.PostgresRepository, db *pgxpool.Pool, flushBatch int, perRow bool) (flushResult, error) {
+	r := flushResult{mode: "single"}
+	flush := repo.BatchUpdateStatus
+	if perRow {
+		r.mode = "per-row"
+		flush = func(ctx context.Context, updates []*notification.StatusUpdate) error {
+			return updateStatusPerRow(ctx, db, updates)
+		}
+	}
+
+	for {
+		batch, err := repo.ClaimBatch(ctx, "id-bench-flush", flushBatch, time.Minute, notification.ClaimPolicyPriority)
+		if err != nil || len(batch) == 0 {
+			return r, err
+		}
+
+		now := time.Now()
+		updates := make([]*notification.StatusUpdate, len(batch))
+		for i, nb := range batch {
+			update := &notification.StatusUpdate{
+				NotificationID: nb.NotificationID,
+				Status:         "pushed",
+				TenantID:       nb.TenantID,
+				UserID:         nb.UserID,
+				InstanceID:     "id-bench-flush",
+				Channel:        "sse",
+				GroupSize:      1,
+				Latency:        time.Millisecond,
+				AttemptedAt:    now,
+			}
+			if i%5 == 0 {
+				update.Status, update.ErrorMsg, update.NextAttemptAt = "failed", "id-bench: simulated failure", now.Add(time.Minute)
+			}
+			updates[i] = update
+		}
+
+		start := time.Now()
+		if err := flush(ctx, updates); err != nil {
+			return r, err
+		}
+		r.durations = append(r.durations, time.Since(start))
+		r.rows += len(batch)
+	}
+}
+
+// updateStatusPerRow is the statement-per-notification flush
+// BatchUpdateStatus replaced: two statements per update in one transaction,
+// each its own round trip (the connection's statement cache prepares them
+// once)
+func updateStatusPerRow(ctx context.Context, db *pgxpool.Pool, updates []*notification.StatusUpdate) error {
+	txn, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer txn.Rollback(ctx)
+
+	for _, update := range updates {
+		var nextAttemptAt *time.Time
+		if !update.NextAttemptAt.IsZero() {
+			nextAttemptAt = &update.NextAttemptAt
+		}
+		if _, err := txn.Exec(ctx, `
+			UPDATE notifications
+			SET status = $1,
+			    delivered_at = CASE WHEN $1 = 'pushed' THEN NOW() ELSE delivered_at END,
+			    error_message = $2,
+			    instance_id = NULL,
+			    lease_timeout = NULL,
+			    next_attempt_at = $4
+			WHERE notification_id = $3
+		`, update.Status, update.ErrorMsg, update.NotificationID, nextAttemptAt); err != nil {
+			return fmt.Errorf("failed to update %s: %w", update.NotificationID, err)
+		}
+
+		if update.AttemptedAt.IsZero() {
+			continue
+		}
+		if _, err := txn.Exec(ctx, `
+			INSERT INTO delivery_attempts (
+				notification_id, tenant_id, user_id, attempted_at, instance_id,
+				worker_id, channel, outcome, error_message, latency_ms, group_size
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
+		`,
+			update.NotificationID,
+			models.TenantOrDefault(update.TenantID),
+			update.UserID,
+			update.AttemptedAt,
+			update.InstanceID,
+			update.WorkerID,
+			update.Channel,
+			update.Status,
+			update.ErrorMsg,
+			float64(update.Latency.Microseconds())/1000,
+			update.GroupSize,
+		); err != nil {
+			return fmt.Errorf("failed to record attempt for %s: %w", update.NotificationID, err)
+		}
+	}
+
+	return txn.Commit(ctx)
+}
+
+func newNotifications(gen idgen.Generator, n int, users *generator.Population) []*models.Notification {
+	now := time.Now()
+	priorities := []models.Priority{models.PriorityHigh, models.PriorityMedium, models.PriorityLow}
+
+	notifications := make([]*models.Notification, n)
+	for i := range notifications {
+		_, userID := users.Random()
+		notifications[i] = &models.Notification{
+			NotificationID:                gen.NewID(),
+			UserID:                        userID,
+			EventType:                     models.EventJobNew,
+			Priority:                      priorities[rand.Intn(len(priorities))],
+			Payload:                       map[string]string{"job_title": "Backend Engineer"},
+			Status:                        "not_pushed",
+			EventTimestamp:                now,
+			NotificationReceivedTimestamp: now,
+			CreatedAt:                     now,
+		}
+	}
+	return notifications
+}
+
+func printResults(results []result) {
+	fmt.Println()
+	fmt.Printf("%-10s %12s %14s %12s %14s %14s\n", "STRATEGY", "ROWS", "INSERT/s", "CLAIMED", "CLAIM/s", "PKEY SIZE")
+	for _, r := range results {
+		fmt.Printf("%-10s %12d %14.0f %12d %14.0f %12.1fMB\n",
+			r.strategy,
+			r.rows,
+			float64(r.rows)/r.insertDuration.Seconds(),
+			r.claimed,
+			float64(r.claimed)/r.claimDuration.Seconds(),
+			float64(r.pkeyBytes)/(1024*1024))
+	}
+}
+
+func printFlushResults(results []flushResult, flushBatch int) {
+	fmt.Println()
+	fmt.Printf("%-10s %10s %10s %12s %12s %12s %14s\n", "FLUSH", "BATCH", "FLUSHES", "MEAN", "P95", "MAX", "ROWS/s")
+	for _, r := range results {
+		if len(r.durations) == 0 {
+			continue
+		}
+		var total time.Duration
+		for _, d := range r.durations {
+			total += d
+		}
+		sorted := slices.Clone(r.durations)
+		slices.Sort(sorted)
+		fmt.Printf("%-10s %10d %10d %12s %12s %12s %14.0f\n",
+			r.mode,
+			flushBatch,
+			len(r.durations),
+			(total / time.Duration(len(r.durations))).Round(time.Microsecond),
+			sorted[len(sorted)*95/100].Round(time.Microsecond),
+			sorted[len(sorted)-1].Round(time.Microsecond),
+			float64(r.rows)/total.Seconds())
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
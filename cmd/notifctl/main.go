@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"notification-delivery-system/internal/auth"
+)
+
+const usage = `notifctl - operate a running notification-service
+
+Usage:
+  notifctl [-server URL] <command> [flags]
+
+Commands:
+  stats [-exact]             Show repository, picker, SSE and consumer stats
+  breakdown [-window W] [-group-by COLS]
+                             Counts and delivery delay by event type, priority
+                             and status (COLS: a comma-separated subset)
+  connections [-limit N]     List open SSE connections (oldest first)
+  export [-window W]         Full benchmark export (W = run or a duration like 15m)
+  export-notifications [-o FILE] [filter flags]
+                             Stream matching notifications as NDJSON (see
+                             notifctl export-notifications -h)
+  delivery                   Show whether delivery is paused
+  pause                      Stop claiming new notifications
+  resume                     Resume claiming
+  paused-users               List users whose delivery is paused
+  pause-user [-tenant T] [-reason R] USER
+                             Park a user's notifications until resumed
+  resume-user [-tenant T] USER
+                             Resume a user and deliver their held notifications
+  requeue [-limit N]         Move failed notifications back to pending
+  reclaim                    Reset expired leases now
+  log-level [LEVEL]          Show or set the log level (debug, info, warn, error)
+  tail [-json]               Stream delivery audit events until interrupted
+  canary                     Show built-in canary latency and loss
+  sla                        Show rolling per-priority SLA compliance
+  attempts ID                Show the delivery attempt history of a notification
+  flaky [-window W] [-limit N]
+                             Users with the most failed delivery attempts
+  chaos [-off | fault flags] Show fault injection, or replace it (omitted faults
+                             are turned off; see notifctl chaos -h)
+  event-types [TYPE]         List registered event types, or show how TYPE is
+                             delivered
+  register-event-type [-priority P] [-title T] [-message M] [-digestible=false]
+                      [-channels C] [-ttl D] [-payload-schema FILE] TYPE
+                             Register an event type or family (job.*),
+                             replacing its earlier registration
+  unregister-event-type TYPE Revert an event type to its built-in behavior
+  segments [NAME]            List the segments broadcasts can address, or
+                             show NAME's members
+  put-segment [-users-file FILE] NAME [USER...]
+                             Register a segment, replacing its members
+  delete-segment NAME        Remove a segment
+  subscriptions [-tenant T] USER
+                             List the topics a user is subscribed to
+  subscribe [-tenant T] USER TOPIC...
+                             Subscribe a user to topics (job-alerts:golang)
+  unsubscribe [-tenant T] USER TOPIC
+                             Unsubscribe a user from a topic
+
+The server defaults to $NOTIFCTL_SERVER or http://localhost:8080. When the
+service has auth enabled, pass an admin token with -token ($NOTIFCTL_TOKEN), or
+the signing key with -auth-key ($NOTIFCTL_AUTH_KEY) to mint one.
+`
+
+func main() {
+	defaultServer := os.Getenv("NOTIFCTL_SERVER")
+	if defaultServer == "" {
+		defaultServer = "http://localhost:8080"
+	}
+
+	server := flag.String("server", defaultServer, "Notification service base URL")
+	timeout := flag.Duration("timeout", 10*time.Second, "Request timeout (not applied to tail)")
+	token := flag.String("token", os.Getenv("NOTIFCTL_TOKEN"), "Admin-scoped bearer token")
+	authKey := flag.String("auth-key", os.Getenv("NOTIFCTL_AUTH_KEY"), "HS256 signing key to mint an admin token with (instead of -token)")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &client{
+		baseURL: strings.TrimRight(*server, "/"),
+		token:   *token,
+		http:    &http.Client{Timeout: *timeout},
+	}
+	if *authKey != "" && c.token == "" {
+		minted, err := auth.SignService(auth.ScopeAdmin, "notifctl", time.Hour, []byte(*authKey))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "notifctl: %v\n", err)
+			os.Exit(1)
+		}
+		c.token = minted
+	}
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	var err error
+	switch cmd {
+	case "stats":
+		fs := flag.NewFlagSet("stats", flag.ExitOnError)
+		exact := fs.Bool("exact", false, "Count the whole table instead of reading the status counters")
+		fs.Parse(args)
+		err = c.printJSON(http.MethodGet, fmt.Sprintf("/admin/stats?exact=%t", *exact), nil)
+	case "breakdown":
+		fs := flag.NewFlagSet("breakdown", flag.ExitOnError)
+		window := fs.String("window", "run", "Window: run or a duration")
+		groupBy := fs.String("group-by", "event_type,priority,status", "Columns to group by")
+		fs.Parse(args)
+		err = c.printJSON(http.MethodGet, fmt.Sprintf("/admin/stats/breakdown?window=%s&group_by=%s", url.QueryEscape(*window), url.QueryEscape(*groupBy)), nil)
+	case "connections":
+		fs := flag.NewFlagSet("connections", flag.ExitOnError)
+		limit := fs.Int("limit", 100, "Max connections to list (0 = all)")
+		fs.Parse(args)
+		err = c.printJSON(http.MethodGet, fmt.Sprintf("/admin/connections?limit=%d", *limit), nil)
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		window := fs.String("window", "run", "Export window: run or a duration")
+		fs.Parse(args)
+		err = c.printJSON(http.MethodGet, "/admin/export?window="+url.QueryEscape(*window), nil)
+	case "export-notifications":
+		fs := flag.NewFlagSet("export-notifications", flag.ExitOnError)
+		out := fs.String("o", "", "Write to this file instead of stdout")
+		query := url.Values{}
+		for flagName, param := range map[string]string{
+			"tenant": "tenant_id", "user": "user_id", "status": "status", "event-type": "event_type", "priority": "priority",
+		} {
+			fs.Func(flagName, "Only notifications with this "+param, func(v string) error {
+				query.Set(param, v)
+				return nil
+			})
+		}
+		since := fs.String("since", "", "Created at or after: RFC 3339 time, or a duration ago (e.g. 1h)")
+		until := fs.String("until", "", "Created before: RFC 3339 time, or a duration ago")
+		limit := fs.Int("limit", 0, "Max notifications (0 = all)")
+		pageSize := fs.Int("page-size", 1000, "Rows the server reads per query")
+		fs.Parse(args)
+		if *since != "" {
+			query.Set("since", *since)
+		}
+		if *until != "" {
+			query.Set("until", *until)
+		}
+		query.Set("limit", fmt.Sprint(*limit))
+		query.Set("page_size", fmt.Sprint(*pageSize))
+		err = c.exportNotifications(query, *out)
+	case "delivery":
+		err = c.printJSON(http.MethodGet, "/admin/delivery", nil)
+	case "pause":
+		err = c.printJSON(http.MethodPost, "/admin/delivery/pause", nil)
+	case "resume":
+		err = c.printJSON(http.MethodPost, "/admin/delivery/resume", nil)
+	case "paused-users":
+		err = c.printJSON(http.MethodGet, "/admin/users/paused", nil)
+	case "pause-user", "resume-user":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		tenant := fs.String("tenant", "", "Tenant of the user (default tenant when empty)")
+		reason := fs.String("reason", "", "Why delivery is paused (pause-user only)")
+		fs.Parse(args)
+		if fs.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "usage: notifctl %s [-tenant T] USER\n", cmd)
+			os.Exit(2)
+		}
+		path := "/admin/users/" + url.PathEscape(fs.Arg(0)) + "/" + strings.TrimSuffix(cmd, "-user")
+		if *reason != "" && cmd == "pause-user" {
+			path += "?reason=" + url.QueryEscape(*reason)
+		}
+		c.tenant = *tenant
+		err = c.printJSON(http.MethodPost, path, nil)
+	case "requeue":
+		fs := flag.NewFlagSet("requeue", flag.ExitOnError)
+		limit := fs.Int("limit", 1000, "Max failed notifications to requeue")
+		fs.Parse(args)
+		err = c.printJSON(http.MethodPost, fmt.Sprintf("/admin/failures/requeue?limit=%d", *limit), nil)
+	case "reclaim":
+		err = c.printJSON(http.MethodPost, "/admin/reclaim", nil)
+	case "log-level":
+		if len(args) == 0 {
+			err = c.printJSON(http.MethodGet, "/admin/log-level", nil)
+		} else {
+			body, _ := json.Marshal(map[string]string{"level": args[0]})
+			err = c.printJSON(http.MethodPut, "/admin/log-level", body)
+		}
+	case "canary":
+		err = c.printJSON(http.MethodGet, "/admin/canary", nil)
+	case "sla":
+		err = c.printJSON(http.MethodGet, "/admin/sla", nil)
+	case "attempts":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: notifctl attempts NOTIFICATION_ID")
+			os.Exit(2)
+		}
+		err = c.printJSON(http.MethodGet, "/admin/attempts/"+url.PathEscape(args[0]), nil)
+	case "flaky":
+		fs := flag.NewFlagSet("flaky", flag.ExitOnError)
+		window := fs.String("window", "run", "Window: run or a duration")
+		limit := fs.Int("limit", 20, "Max users to list")
+		fs.Parse(args)
+		err = c.printJSON(http.MethodGet, fmt.Sprintf("/admin/attempts?window=%s&limit=%d", url.QueryEscape(*window), *limit), nil)
+	case "chaos":
+		fs := flag.NewFlagSet("chaos", flag.ExitOnError)
+		off := fs.Bool("off", false, "Turn all fault injection off")
+		dbLatency := fs.Duration("db-latency", 0, "Latency added to repository calls")
+		dbRate := fs.Float64("db-latency-rate", 0, "Fraction of repository calls delayed")
+		writeFail := fs.Float64("sse-write-fail-rate", 0, "Fraction of stream flushes that fail")
+		pause := fs.Duration("consumer-pause", 0, "Consumer stall length")
+		pauseRate := fs.Float64("consumer-pause-rate", 0, "Fraction of consumed messages preceded by a stall")
+		deliveryRate := fs.Float64("delivery-error-rate", 0, "Fraction of delivery attempts failed")
+		fs.Parse(args)
+		if fs.NFlag() == 0 {
+			err = c.printJSON(http.MethodGet, "/admin/chaos", nil)
+			break
+		}
+		settings := map[string]interface{}{}
+		if !*off {
+			settings = map[string]interface{}{
+				"db_latency":          dbLatency.String(),
+				"db_latency_rate":     *dbRate,
+				"sse_write_fail_rate": *writeFail,
+				"consumer_pause":      pause.String(),
+				"consumer_pause_rate": *pauseRate,
+				"delivery_error_rate": *deliveryRate,
+			}
+		}
+		body, _ := json.Marshal(settings)
+		err = c.printJSON(http.MethodPut, "/admin/chaos", body)
+	case "event-types":
+		if len(args) == 0 {
+			err = c.printJSON(http.MethodGet, "/admin/event-types", nil)
+		} else {
+			err = c.printJSON(http.MethodGet, "/admin/event-types/"+url.PathEscape(args[0]), nil)
+		}
+	case "register-event-type":
+		fs := flag.NewFlagSet("register-event-type", flag.ExitOnError)
+		priority := fs.String("priority", "", "Priority of events produced without one (HIGH, MEDIUM, LOW; empty keeps the built-in)")
+		title := fs.String("title", "", "Title template (empty keeps the templates file's)")
+		message := fs.String("message", "", "Message template (empty keeps the templates file's)")
+		digestible := fs.Bool("digestible", true, "May share a grouped frame with the user's other notifications")
+		channels := fs.String("channels", "sse", "Comma-separated delivery channels")
+		ttl := fs.String("ttl", "", "Expire events older than this undelivered, e.g. 2h (empty keeps the templates file's)")
+		schemaFile := fs.String("payload-schema", "", "JSON file with the schema payloads must satisfy")
+		fs.Parse(args)
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "usage: notifctl register-event-type [flags] TYPE")
+			os.Exit(2)
+		}
+		registration := map[string]interface{}{
+			"priority":   *priority,
+			"title":      *title,
+			"message":    *message,
+			"digestible": *digestible,
+			"channels":   strings.Split(*channels, ","),
+			"ttl":        *ttl,
+		}
+		if *schemaFile != "" {
+			schema, readErr := os.ReadFile(*schemaFile)
+			if readErr == nil && !json.Valid(schema) {
+				readErr = fmt.Errorf("%s is not valid JSON", *schemaFile)
+			}
+			if readErr != nil {
+				fmt.Fprintf(os.Stderr, "notifctl register-event-type: %v\n", readErr)
+				os.Exit(1)
+			}
+			registration["payload_schema"] = json.RawMessage(schema)
+		}
+		body, _ := json.Marshal(registration)
+		err = c.printJSON(http.MethodPut, "/admin/event-types/"+url.PathEscape(fs.Arg(0)), body)
+	case "unregister-event-type":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: notifctl unregister-event-type TYPE")
+			os.Exit(2)
+		}
+		err = c.printJSON(http.MethodDelete, "/admin/event-types/"+url.PathEscape(args[0]), nil)
+	case "segments":
+		if len(args) == 0 {
+			err = c.printJSON(http.MethodGet, "/admin/segments", nil)
+		} else {
+			err = c.printJSON(http.MethodGet, "/admin/segments/"+url.PathEscape(args[0]), nil)
+		}
+	case "put-segment":
+		fs := flag.NewFlagSet("put-segment", flag.ExitOnError)
+		usersFile := fs.String("users-file", "", "File of member user IDs, one per line, added to those given as arguments")
+		fs.Parse(args)
+		if fs.NArg() < 1 {
+			fmt.Fprintln(os.Stderr, "usage: notifctl put-segment [-users-file FILE] NAME [USER...]")
+			os.Exit(2)
+		}
+		members := fs.Args()[1:]
+		if *usersFile != "" {
+			data, readErr := os.ReadFile(*usersFile)
+			if readErr != nil {
+				fmt.Fprintf(os.Stderr, "notifctl put-segment: %v\n", readErr)
+				os.Exit(1)
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					members = append(members, line)
+				}
+			}
+		}
+		body, _ := json.Marshal(map[string][]string{"user_ids": members})
+		err = c.printJSON(http.MethodPut, "/admin/segments/"+url.PathEscape(fs.Arg(0)), body)
+	case "delete-segment":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: notifctl delete-segment NAME")
+			os.Exit(2)
+		}
+		err = c.printJSON(http.MethodDelete, "/admin/segments/"+url.PathEscape(args[0]), nil)
+	case "subscriptions", "subscribe", "unsubscribe":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		tenant := fs.String("tenant", "", "Tenant of the user (default tenant when empty)")
+		fs.Parse(args)
+		wantArgs := map[string]string{"subscriptions": "USER", "subscribe": "USER TOPIC...", "unsubscribe": "USER TOPIC"}[cmd]
+		if (cmd == "subscriptions" && fs.NArg() != 1) || (cmd == "subscribe" && fs.NArg() < 2) || (cmd == "unsubscribe" && fs.NArg() != 2) {
+			fmt.Fprintf(os.Stderr, "usage: notifctl %s [-tenant T] %s\n", cmd, wantArgs)
+			os.Exit(2)
+		}
+		c.tenant = *tenant
+		path := "/notifications/" + url.PathEscape(fs.Arg(0)) + "/subscriptions"
+		switch cmd {
+		case "subscriptions":
+			err = c.printJSON(http.MethodGet, path, nil)
+		case "subscribe":
+			for _, topic := range fs.Args()[1:] {
+				if err = c.printJSON(http.MethodPut, path+"/"+url.PathEscape(topic), nil); err != nil {
+					break
+				}
+			}
+		case "unsubscribe":
+			err = c.printJSON(http.MethodDelete, path+"/"+url.PathEscape(fs.Arg(1)), nil)
+		}
+	case "tail":
+		fs := flag.NewFlagSet("tail", flag.ExitOnError)
+		raw := fs.Bool("json", false, "Print raw JSON events")
+		fs.Parse(args)
+		err = c.tail(*raw)
+	case "help", "-h", "--help":
+		flag.Usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notifctl %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+type client struct {
+	baseURL string
+	tenant  string // Sent as X-Tenant-ID when set
+	token   string // Sent as a Bearer token when set
+	http    *http.Client
+}
+
+// newRequest builds a request to path carrying the client's tenant and token
+func (c *client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.tenant)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// printJSON performs the request and pretty-prints the JSON response
+func (c *client) printJSON(method, path string, body []byte) error {
+	req, err := c.newRequest(context.Background(), method, path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		out.Reset()
+		out.Write(data)
+	}
+	fmt.Println(strings.TrimSpace(out.String()))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// exportNotifications streams /admin/export/notifications to out (stdout
+// when empty) until the export ends or is interrupted
+func (c *client) exportNotifications(query url.Values, out string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/admin/export/notifications?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Streaming: no client timeout
+	resp, err := (&http.Client{Transport: c.http.Transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		fmt.Fprintln(os.Stderr, strings.TrimSpace(string(data)))
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	buf := bufio.NewWriterSize(w, 256*1024)
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		buf.Flush()
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted, export is incomplete")
+		}
+		return fmt.Errorf("read stream: %w", err)
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	// Trailers are only set once the body is read
+	if exportErr := resp.Trailer.Get("X-Export-Error"); exportErr != "" {
+		return fmt.Errorf("export is incomplete after %s rows: %s", resp.Trailer.Get("X-Export-Rows"), exportErr)
+	}
+	if out != "" {
+		fmt.Fprintf(os.Stderr, "exported %s notifications to %s\n", resp.Trailer.Get("X-Export-Rows"), out)
+	}
+	return nil
+}
+
+type auditEvent struct {
+	NotificationID string    `json:"notification_id"`
+	UserID         string    `json:"user_id"`
+	EventType      string    `json:"event_type"`
+	Priority       string    `json:"priority"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error"`
+	GroupSize      int       `json:"group_size"`
+	LatencyMs      float64   `json:"latency_ms"`
+	InstanceID     string    `json:"instance_id"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// tail streams /admin/audit/stream until interrupted
+func (c *client) tail(raw bool) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/admin/audit/stream", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// Streaming: no client timeout
+	resp, err := (&http.Client{Transport: c.http.Transport}).Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+
+		if raw {
+			fmt.Println(data)
+			continue
+		}
+
+		var event auditEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		fmt.Printf("%s %-12s %-7s %-6s %-28s %-12s %8.2fms group=%d %s %s\n",
+			event.Timestamp.Format("15:04:05.000"),
+			event.InstanceID,
+			event.Status,
+			event.Priority,
+			event.EventType,
+			event.UserID,
+			event.LatencyMs,
+			event.GroupSize,
+			event.NotificationID,
+			event.Error)
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("read stream: %w", err)
+	}
+	return nil
+}
@@ -10,51 +10,210 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"notification-delivery-system/internal/cache"
 	"notification-delivery-system/internal/config"
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/kafkaadmin"
+	"notification-delivery-system/internal/models"
 	"notification-delivery-system/internal/notification"
+	"notification-delivery-system/internal/producer"
+	"notification-delivery-system/internal/soak"
+	"notification-delivery-system/internal/startup"
+	"notification-delivery-system/internal/tracing"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
+	// Atomic level so /admin/log-level can change verbosity at runtime
+	logConfig := zap.NewProductionConfig()
+	logger, _ := logConfig.Build()
 	defer logger.Sync()
 
 	logger.Info("starting notification service - PostgreSQL optimized")
+	startedAt := time.Now()
 
 	cfg, err := config.Load("")
 	if err != nil {
 		logger.Fatal("failed to load config", zap.Error(err))
 	}
+	// Every log line names the instance, so a deployment's logs can be merged
+	logger = logger.With(zap.String("instance_id", cfg.TaskPicker.InstanceID))
+
+	// Initialize tracing (produce → consume → persist → claim → deliver)
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Enabled:      cfg.Tracing.Enabled,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		Insecure:     cfg.Tracing.Insecure,
+		ServiceName:  cfg.Tracing.ServiceName,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	}, logger)
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
 
 	// Initialize PostgreSQL repository
-	repo, err := notification.NewPostgresRepository(
+	pgRepo, err := notification.NewPostgresRepository(
 		cfg.PostgreSQL.Host,
 		cfg.PostgreSQL.Port,
 		cfg.PostgreSQL.Database,
 		cfg.PostgreSQL.User,
 		cfg.PostgreSQL.Password,
+		notification.PostgresPoolConfig{
+			MaxConns:        cfg.PostgreSQL.MaxConns,
+			MinConns:        cfg.PostgreSQL.MinConns,
+			ConnMaxLifetime: cfg.PostgreSQL.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.PostgreSQL.ConnMaxIdleTime,
+			QueryTimeout:    cfg.PostgreSQL.QueryTimeout,
+			SlowQuery:       cfg.PostgreSQL.SlowQuery,
+			ReadReplicas:    cfg.PostgreSQL.ReadReplicas,
+		},
 		logger,
 	)
 	if err != nil {
 		logger.Fatal("failed to initialize postgres repository", zap.Error(err))
 	}
-	defer repo.Close(context.Background())
+	defer pgRepo.Close(context.Background())
+
+	// Fault injection, only wired when CHAOS_ENABLED or a CHAOS_* fault is
+	// set; PUT /admin/chaos then changes it at runtime. A nil *Chaos injects
+	// nothing and leaves the PUT unregistered.
+	var chaos *notification.Chaos
+	if cfg.Chaos.Enabled {
+		chaos = notification.NewChaos(notification.ChaosConfig{
+			DBLatency:         cfg.Chaos.DBLatency,
+			DBLatencyRate:     cfg.Chaos.DBLatencyRate,
+			SSEWriteFailRate:  cfg.Chaos.SSEWriteFailRate,
+			ConsumerPause:     cfg.Chaos.ConsumerPause,
+			ConsumerPauseRate: cfg.Chaos.ConsumerPauseRate,
+			DeliveryErrorRate: cfg.Chaos.DeliveryErrorRate,
+		})
+		logger.Warn("chaos injection enabled", zap.Any("chaos", chaos.Stats()))
+	}
 
 	// Initialize SSE Manager
-	sseManager := notification.NewSSEManager(cfg.NotificationService.MaxSSEConnections, logger)
+	sseCompression, err := notification.ParseSSECompression(cfg.NotificationService.SSECompression)
+	if err != nil {
+		logger.Fatal("invalid sse compression", zap.Error(err))
+	}
+	sseRegions, err := notification.ParseRegions(cfg.NotificationService.SSERegions)
+	if err != nil {
+		logger.Fatal("invalid sse regions", zap.Error(err))
+	}
+	handlers, err := notification.LoadHandlers(cfg.NotificationService.TemplatesFile, cfg.NotificationService.LocalesDir)
+	if err != nil {
+		logger.Fatal("invalid notification templates", zap.Error(err))
+	}
+	sseManager := notification.NewSSEManager(notification.SSEManagerConfig{
+		MaxConnections:    cfg.NotificationService.MaxSSEConnections,
+		HeartbeatInterval: cfg.NotificationService.SSEHeartbeatInterval,
+		StaleTimeout:      cfg.NotificationService.SSEStaleTimeout,
+		LivenessTimeout:   cfg.NotificationService.SSELivenessTimeout,
+		CleanupInterval:   cfg.NotificationService.SSECleanupInterval,
+		Compression:       sseCompression,
+		FlushInterval:     cfg.NotificationService.SSEFlushInterval,
+		FlushMaxFrames:    cfg.NotificationService.SSEFlushMaxFrames,
+		BandwidthLimit:    cfg.NotificationService.SSEBandwidthLimit,
+		Regions:           sseRegions,
+		Retry:             cfg.NotificationService.SSERetry,
+		ReconnectAfter:    cfg.NotificationService.ReconnectRetryAfter,
+		ReconnectSpread:   cfg.NotificationService.SSEReconnectSpread,
+		InstanceID:        cfg.TaskPicker.InstanceID,
+		StageTimestamps:   cfg.NotificationService.SSEStageTimestamps,
+		Handlers:          handlers,
+		Chaos:             chaos,
+	}, logger)
+	defer sseManager.Stop()
+
+	// Kafka settings (topic cross-checked against KAFKA_TOPIC in config.Load)
+	kafkaBrokers := cfg.Kafka.Brokers
+	kafkaTopic := cfg.Kafka.Topic
+	kafkaGroup := cfg.Kafka.ConsumerGroup
+
+	// KAFKA_PROVISION_TOPICS: create the topics with real partition counts
+	// before anything auto-creates them with one
+	if cfg.Kafka.ProvisionTopics {
+		topics := []kafkaadmin.TopicSpec{
+			{Name: kafkaTopic, Partitions: cfg.Kafka.Partitions, ReplicationFactor: cfg.Kafka.ReplicationFactor},
+			{Name: cfg.Expiry.MetricsTopic, Partitions: cfg.Kafka.Partitions, ReplicationFactor: cfg.Kafka.ReplicationFactor},
+		}
+		topics = append(topics, kafkaadmin.TopicSpec{Name: cfg.Consumer.PoisonTopic, Partitions: cfg.Kafka.Partitions, ReplicationFactor: cfg.Kafka.ReplicationFactor})
+		if cfg.Consumer.IngestOverflow == string(notification.IngestOverflowDLQ) {
+			topics = append(topics, kafkaadmin.TopicSpec{Name: cfg.Consumer.DLQTopic, Partitions: cfg.Kafka.Partitions, ReplicationFactor: cfg.Kafka.ReplicationFactor})
+		}
+		if cfg.Kafka.ReceiptsTopic != "" {
+			topics = append(topics, kafkaadmin.TopicSpec{Name: cfg.Kafka.ReceiptsTopic, Partitions: cfg.Kafka.Partitions, ReplicationFactor: cfg.Kafka.ReplicationFactor})
+		}
+		provisionCtx, cancelProvision := context.WithTimeout(context.Background(), time.Minute)
+		err := kafkaadmin.New(kafkaBrokers, logger).Ensure(provisionCtx, topics...)
+		cancelProvision()
+		if err != nil {
+			logger.Fatal("failed to provision kafka topics", zap.Error(err))
+		}
+	}
+
+	// Fail fast instead of starting up and silently delivering nothing
+	if !cfg.NotificationService.SkipStartupChecks {
+		if err := startup.Run(context.Background(), logger,
+			startup.RepositorySchema(pgRepo),
+			startup.KafkaTopicExists(kafkaBrokers, kafkaTopic),
+			startup.UserPopulation(cfg.NotificationService.ExpectedUserPrefix, cfg.NotificationService.ExpectedUsers),
+			startup.PortAvailable("http_port", fmt.Sprintf(":%d", cfg.NotificationService.Port)),
+		); err != nil {
+			logger.Fatal("startup checks failed (set SKIP_STARTUP_CHECKS=true to bypass)", zap.Error(err))
+		}
+	}
 
-	// Get Kafka config from environment or use defaults
-	kafkaBrokers := []string{os.Getenv("KAFKA_BROKERS")}
-	if kafkaBrokers[0] == "" {
-		kafkaBrokers = []string{"localhost:9092"}
+	// Calls fail fast while Postgres is down or slow, so pickers back off and
+	// the consumer buffers instead of hammering it. Injected DB latency sits
+	// inside the breaker so chaos runs can trip it.
+	breaker := notification.NewCircuitBreaker(notification.CircuitBreakerConfig{
+		FailureThreshold: cfg.PostgreSQL.BreakerThreshold,
+		OpenTimeout:      cfg.PostgreSQL.BreakerOpenTimeout,
+		SlowCall:         cfg.PostgreSQL.BreakerSlowCall,
+	}, logger)
+	store := notification.NewBreakerRepository(notification.NewChaosRepository(pgRepo, chaos), breaker)
+
+	// Optional Redis: read-model cache for user queries and cluster-wide
+	// quota counters
+	var repo notification.Repository = store
+	var quotaCounter notification.QuotaCounter
+	var snapshotStore notification.SnapshotStore
+	if cfg.NotificationService.SnapshotFile != "" {
+		snapshotStore = notification.FileSnapshotStore{Path: cfg.NotificationService.SnapshotFile}
 	}
-	kafkaTopic := os.Getenv("KAFKA_TOPIC")
-	if kafkaTopic == "" {
-		kafkaTopic = "notifications"
+	if cfg.Redis.Addr != "" {
+		redisCache, err := cache.NewRedisCache(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.TTL, logger)
+		if err != nil {
+			logger.Fatal("failed to initialize redis cache", zap.Error(err))
+		}
+		defer redisCache.Close()
+		repo = notification.NewCachedRepository(store, redisCache, logger)
+		quotaCounter = redisCache
+		if cfg.NotificationService.SnapshotRedis {
+			snapshotStore = redisCache
+		}
 	}
-	kafkaGroup := "notification-consumer"
+
+	// Users' locales, read when rendering deliveries
+	sseManager.SetPreferences(notification.NewPreferenceCache(repo, cfg.NotificationService.PreferencesCacheTTL, logger))
+
+	quotas := notification.NewDeliveryQuotas(notification.QuotaConfig{
+		DeliveryRate: cfg.Quota.DeliveryRate,
+		UserLimit:    cfg.Quota.UserLimit,
+		UserWindow:   cfg.Quota.UserWindow,
+	}, quotaCounter, logger)
+	if quotas.Enabled() && quotaCounter == nil {
+		logger.Warn("delivery quotas are per instance without REDIS_ADDR and multiply with the instance count")
+	}
+
+	// Notification ID strategy (time-ordered IDs keep the primary key index append-mostly)
+	idGen, err := idgen.New(cfg.IDGeneration.Strategy, cfg.IDGeneration.NodeID)
+	if err != nil {
+		logger.Fatal("failed to initialize id generator", zap.Error(err))
+	}
+	idgen.SetDefault(idGen)
 
 	// Initialize Kafka Consumer (Phase 1: Kafka → ClickHouse persistence)
 	consumer, err := notification.NewConsumer(
@@ -62,16 +221,82 @@ func main() {
 		kafkaGroup,
 		kafkaTopic,
 		repo,
+		idGen,
 		logger,
 	)
 	if err != nil {
 		logger.Fatal("failed to initialize consumer", zap.Error(err))
 	}
 	defer consumer.Close()
+	consumer.SetLateEventPolicy(notification.LateEventConfig{
+		Threshold: cfg.Consumer.LateThreshold,
+		Policy:    notification.LatePolicy(cfg.Consumer.LatePolicy),
+	})
+	routing, err := notification.ParseHeaderRouting(cfg.Consumer.EventTypes, cfg.Consumer.Priorities, cfg.Consumer.ExpressPriorities)
+	if err != nil {
+		logger.Fatal("invalid consumer routing", zap.Error(err))
+	}
+	consumer.SetHeaderRouting(routing)
+	consumer.SetChaos(chaos)
+	consumer.SetWorkers(cfg.Consumer.Workers)
+	consumer.SetBufferLimit(cfg.Consumer.BufferLimit)
+	if cfg.Consumer.SpillDir != "" {
+		spill, err := notification.NewSpillBuffer(cfg.Consumer.SpillDir, cfg.Consumer.SpillMaxBytes, logger)
+		if err != nil {
+			logger.Fatal("failed to open consumer spill buffer", zap.Error(err))
+		}
+		consumer.SetSpill(spill)
+	}
+
+	// Per-producer ingest quotas; the excess is degraded, dead-lettered or dropped
+	ingestOverrides, err := notification.ParseIngestOverrides(cfg.Consumer.IngestQuotaOverrides)
+	if err != nil {
+		logger.Fatal("invalid ingest quota overrides", zap.Error(err))
+	}
+	var deadLetters notification.DeadLetterWriter
+	if cfg.Consumer.IngestOverflow == string(notification.IngestOverflowDLQ) {
+		dlqWriter := producer.NewDeadLetterWriter(kafkaBrokers, cfg.Consumer.DLQTopic)
+		defer dlqWriter.Close()
+		deadLetters = dlqWriter
+	}
+	ingestQuotas, err := notification.NewIngestQuotas(notification.IngestQuotaConfig{
+		Key:       cfg.Consumer.IngestQuotaKey,
+		Rate:      cfg.Consumer.IngestQuotaRate,
+		Burst:     cfg.Consumer.IngestQuotaBurst,
+		Overrides: ingestOverrides,
+		Overflow:  notification.IngestOverflow(cfg.Consumer.IngestOverflow),
+	}, deadLetters, logger)
+	if err != nil {
+		logger.Fatal("invalid ingest quota", zap.Error(err))
+	}
+	consumer.SetIngestQuotas(ingestQuotas)
+
+	// Invalid events (undecodable, or failing their event type's payload
+	// schema) are forwarded raw to the poison topic instead of persisted
+	poisonWriter := producer.NewDeadLetterWriter(kafkaBrokers, cfg.Consumer.PoisonTopic)
+	defer poisonWriter.Close()
+	consumer.SetPoisonWriter(poisonWriter)
+
+	// Broadcasts (user_ids or a segment) are expanded into a notification
+	// per recipient
+	consumer.SetAudience(notification.NewAudience(repo, sseManager, cfg.Consumer.MaxBroadcastRecipients))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Event types registered through /admin/event-types: priorities for events
+	// produced without one, and templates, digestibility and channels
+	eventTypes := notification.NewEventTypeRegistry(repo, handlers, logger)
+	if err := eventTypes.Reload(ctx); err != nil {
+		logger.Warn("failed to load event types, starting with the built-in ones", zap.Error(err))
+	}
+	eventTypes.Start(ctx, cfg.NotificationService.EventTypesRefresh)
+	consumer.SetEventTypes(eventTypes)
+
+	// Consumer group lag straight from Kafka (high-water marks vs commits)
+	lagMonitor := notification.NewLagMonitor(kafkaBrokers, kafkaGroup, kafkaTopic, cfg.Kafka.LagInterval, logger)
+	lagMonitor.Start(ctx)
+
 	// Start Kafka Consumer (writes to DB with status='not_pushed')
 	go func() {
 		logger.Info("starting kafka consumer - persistence layer")
@@ -80,6 +305,21 @@ func main() {
 		}
 	}()
 
+	// RECEIPTS_TOPIC: publish a receipt for every delivered notification
+	var receipts *notification.ReceiptPublisher
+	if cfg.Kafka.ReceiptsTopic != "" {
+		receiptWriter := producer.NewReceiptWriter(kafkaBrokers, cfg.Kafka.ReceiptsTopic)
+		defer receiptWriter.Close()
+		receipts = notification.NewReceiptPublisher(receiptWriter, cfg.Kafka.ReceiptsTopic, 0, logger)
+		receipts.Start()
+		defer receipts.Stop()
+	}
+
+	claimWeights, err := notification.ParseClaimWeights(cfg.TaskPicker.ClaimWeights)
+	if err != nil {
+		logger.Fatal("invalid claim weights", zap.Error(err))
+	}
+
 	// Initialize Task Picker (Phase 2: DB → SSE delivery with dual worker pools)
 	taskPickerCfg := notification.TaskPickerConfig{
 		InstanceID:         cfg.TaskPicker.InstanceID,
@@ -89,10 +329,24 @@ func main() {
 		PollInterval:       cfg.TaskPicker.PollInterval,
 		LeaseDuration:      cfg.TaskPicker.LeaseDuration,
 		ChannelBufferSize:  cfg.TaskPicker.ChannelBufferSize,
+		MaxInflight:        cfg.TaskPicker.MaxInflight,
+		ClaimPolicy:        notification.ClaimPolicy(cfg.TaskPicker.ClaimPolicy),
+		ClaimWeights:       claimWeights,
+		ClaimConnectedOnly: cfg.TaskPicker.ClaimConnectedOnly,
+		MaxGroupSize:       cfg.TaskPicker.MaxGroupSize,
+		RetryBackoff:       cfg.TaskPicker.RetryBackoff,
+		RetryBackoffMax:    cfg.TaskPicker.RetryBackoffMax,
+		Quotas:             quotas,
+		ConsumerLag:        lagMonitor,
+		Receipts:           receipts,
+		Chaos:              chaos,
 	}
 
 	taskPicker := notification.NewTaskPicker(taskPickerCfg, repo, sseManager, logger)
 
+	// Carry the run's counters over from before a restart
+	startedAt = notification.RestoreRunState(context.Background(), snapshotStore, cfg.TaskPicker.InstanceID, taskPicker, sseManager, nil, logger)
+
 	// Start Task Picker (claims from DB, delivers via SSE, batch status updates)
 	logger.Info("starting task picker - delivery layer with dual worker pools")
 	taskPicker.Start()
@@ -107,26 +361,162 @@ func main() {
 		}
 	}()
 
+	// Initialize admission control for new SSE connections
+	admission := notification.NewAdmissionController(notification.AdmissionConfig{
+		AcceptRate:   cfg.NotificationService.ConnectionAcceptRate,
+		AcceptBurst:  cfg.NotificationService.ConnectionAcceptBurst,
+		MaxQueueWait: cfg.NotificationService.ConnectionQueueTimeout,
+		MaxQueued:    cfg.NotificationService.MaxQueuedConnections,
+	}, logger)
+
+	messageEncoding, err := models.ParseMessageEncoding(cfg.Kafka.MessageEncoding)
+	if err != nil {
+		logger.Fatal("invalid message encoding", zap.Error(err))
+	}
+
+	// Built-in canary: probes the full Kafka → DB → SSE path from inside the service
+	var canary *notification.Canary
+	if cfg.Canary.Enabled {
+		canaryProducer, err := producer.NewProducer(kafkaBrokers, kafkaTopic, logger)
+		if err != nil {
+			logger.Fatal("failed to initialize canary producer", zap.Error(err))
+		}
+		defer canaryProducer.Close()
+		canaryProducer.SetEncoding(messageEncoding)
+
+		canary = notification.NewCanary(notification.CanaryConfig{
+			Interval:         cfg.Canary.Interval,
+			Users:            cfg.Canary.Users,
+			Timeout:          cfg.Canary.Timeout,
+			LatencyThreshold: cfg.Canary.LatencyThreshold,
+		}, canaryProducer, sseManager, logger)
+		canary.Start(ctx)
+	}
+
+	// Rolling SLA compliance (e.g. HIGH p99 ≤ SLO.High) over the configured window
+	sloTargets := notification.SLOTargets{
+		High:   cfg.SLO.High,
+		Medium: cfg.SLO.Medium,
+		Low:    cfg.SLO.Low,
+	}
+	slaMonitor := notification.NewSLAMonitor(repo, sloTargets, notification.SLAObjectives{
+		High:   cfg.SLA.HighPercentile,
+		Medium: cfg.SLA.MediumPercentile,
+		Low:    cfg.SLA.LowPercentile,
+	}, cfg.SLA.Window, cfg.SLA.Interval, logger)
+	slaMonitor.Start(ctx)
+
+	// Expire pending notifications past their deadline or max age and
+	// publish a summary of each sweep to the metrics topic
+	metricsProducer, err := producer.NewProducer(kafkaBrokers, cfg.Expiry.MetricsTopic, logger)
+	if err != nil {
+		logger.Fatal("failed to initialize metrics producer", zap.Error(err))
+	}
+	defer metricsProducer.Close()
+
+	expirySweeper := notification.NewExpirySweeper(repo, metricsProducer, cfg.TaskPicker.InstanceID, notification.ExpiryConfig{
+		Interval:  cfg.Expiry.Interval,
+		MaxAge:    cfg.Expiry.MaxAge,
+		BatchSize: cfg.Expiry.BatchSize,
+	}, logger)
+	expirySweeper.Start(ctx)
+
+	// Move old pending notifications between priorities (REPRIORITIZE_RULES,
+	// e.g. HIGH:10m:MEDIUM); off without rules
+	reprioritizeRules, err := notification.ParseReprioritizeRules(cfg.Reprioritize.Rules)
+	if err != nil {
+		logger.Fatal("invalid reprioritize rules", zap.Error(err))
+	}
+	reprioritizer := notification.NewReprioritizer(repo, notification.ReprioritizeConfig{
+		Interval:  cfg.Reprioritize.Interval,
+		Rules:     reprioritizeRules,
+		BatchSize: cfg.Reprioritize.BatchSize,
+	}, logger)
+	reprioritizer.Start(ctx)
+
+	// Flag SSE/picker goroutine pools growing beyond connections/workers
+	leakWatchdog := notification.NewLeakWatchdog(sseManager, taskPicker, cfg.NotificationService.LeakCheckInterval, logger)
+	leakWatchdog.Start(ctx)
+
+	// SOAK_INTERVAL enables the soak-test leak detector (goroutines, heap, FDs)
+	soakConfig, err := soak.ConfigFromEnv()
+	if err != nil {
+		logger.Fatal("invalid soak config", zap.Error(err))
+	}
+	soak.New("notification-service", soakConfig, logger).Start(ctx)
+
+	// Initialize admin endpoints
+	admin := notification.NewAdminHandler(repo, sseManager, taskPicker, consumer, canary, slaMonitor, expirySweeper, reprioritizer, leakWatchdog, admission, chaos, eventTypes, sloTargets, logConfig.Level, logger)
+
 	// Setup HTTP router
-	router := setupRouter(sseManager, repo, logger)
+	var authKey []byte
+	if cfg.NotificationService.AuthEnabled {
+		authKey = []byte(cfg.NotificationService.AuthSigningKey)
+	}
+
+	// /health probes dependencies and worker liveness; /ready gates traffic
+	health := notification.NewHealthChecker(2*time.Second,
+		notification.PostgresHealthCheck(pgRepo),
+		notification.CircuitBreakerHealthCheck(breaker),
+		notification.KafkaHealthCheck(kafkaBrokers),
+		notification.ConsumerHealthCheck(consumer, cfg.NotificationService.HealthStallTimeout),
+		notification.TaskPickerHealthCheck(taskPicker, cfg.NotificationService.HealthStallTimeout),
+		notification.ChannelSaturationCheck(taskPicker, cfg.NotificationService.HealthChannelSaturation))
+
+	publish := notification.NewPublishHandler(repo, taskPicker, idGen, logger)
+	publish.SetMaxBatch(cfg.NotificationService.PublishMaxBatch)
+	publish.SetEventTypes(eventTypes)
+
+	router := notification.NewRouter(notification.RouterDeps{
+		SSEManager: sseManager,
+		Admission:  admission,
+		Admin:      admin,
+		Publish:    publish,
+		Repository: repo,
+		Lag:        lagMonitor,
+		Health:     health,
+		AuthKey:    authKey,
+		InstanceID: cfg.TaskPicker.InstanceID,
+		Logger:     logger,
+	})
+
+	srv := notification.NewHTTPServer(fmt.Sprintf(":%d", cfg.NotificationService.Port), router, notification.HTTPServerConfig{
+		ReadHeaderTimeout:    cfg.NotificationService.HTTPReadHeaderTimeout,
+		IdleTimeout:          cfg.NotificationService.HTTPIdleTimeout,
+		MaxHeaderBytes:       cfg.NotificationService.HTTPMaxHeaderBytes,
+		HTTP2:                notification.HTTP2Mode(cfg.NotificationService.HTTP2Mode),
+		MaxConcurrentStreams: cfg.NotificationService.HTTP2MaxConcurrentStreams,
+		TCPKeepAlive:         cfg.NotificationService.TCPKeepAlive,
+	})
 
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.NotificationService.Port),
-		Handler: router,
+	tlsCfg := notification.TLSConfig{
+		CertFile:         cfg.NotificationService.TLSCertFile,
+		KeyFile:          cfg.NotificationService.TLSKeyFile,
+		AutocertDomains:  cfg.NotificationService.TLSAutocertDomains,
+		AutocertCacheDir: cfg.NotificationService.TLSAutocertCacheDir,
 	}
 
 	go func() {
-		logger.Info("starting HTTP server", zap.Int("port", cfg.NotificationService.Port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("starting HTTP server",
+			zap.Int("port", cfg.NotificationService.Port),
+			zap.Bool("tls", tlsCfg.Enabled()),
+			zap.String("http2", cfg.NotificationService.HTTP2Mode))
+		if err := notification.ListenAndServe(srv, tlsCfg, logger); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("failed to start server", zap.Error(err))
 		}
 	}()
 
+	health.SetReady(true)
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("shutting down server...")
+	notification.Drain(context.Background(), notification.DrainConfig{
+		PreStopDelay: cfg.NotificationService.PreStopDelay,
+		Timeout:      cfg.NotificationService.DrainTimeout,
+	}, health, sseManager, taskPicker, logger)
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.NotificationService.GracefulShutdownTimeout)
 	defer shutdownCancel()
@@ -135,51 +525,10 @@ func main() {
 		logger.Fatal("server forced to shutdown", zap.Error(err))
 	}
 
-	logger.Info("server exited")
-}
-
-func setupRouter(sseManager *notification.SSEManager, repo *notification.PostgresRepository, logger *zap.Logger) *gin.Engine {
-	gin.SetMode(gin.ReleaseMode)
-	router := gin.New()
-	router.Use(gin.Recovery())
-
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":             "ok",
-			"active_connections": sseManager.GetActiveConnections(),
-			"timestamp":          time.Now().Format(time.RFC3339),
-		})
-	})
-
-	router.GET("/notifications/stream", func(c *gin.Context) {
-		userID := c.Query("user_id")
-		if userID == "" {
-			c.JSON(400, gin.H{"error": "user_id is required"})
-			return
-		}
+	// Stop delivering so the summary includes the final status updates
+	taskPicker.Stop()
+	notification.EmitRunSummary(shutdownCtx, repo, taskPicker, sseManager, sloTargets, startedAt, cfg.NotificationService.RunSummaryFile, logger)
+	notification.SaveRunState(shutdownCtx, snapshotStore, cfg.TaskPicker.InstanceID, startedAt, taskPicker, sseManager, nil, logger)
 
-		logger.Info("SSE connection request", zap.String("user_id", userID))
-
-		// Use the built-in StreamToClient method that handles everything
-		sseManager.StreamToClient(c, userID)
-	})
-
-	router.GET("/notifications/:user_id", func(c *gin.Context) {
-		userID := c.Param("user_id")
-
-		notifications, err := repo.GetUserNotifications(c.Request.Context(), userID, 100)
-		if err != nil {
-			logger.Error("failed to query notifications", zap.Error(err))
-			c.JSON(500, gin.H{"error": "failed to fetch notifications"})
-			return
-		}
-
-		c.JSON(200, gin.H{
-			"user_id":       userID,
-			"notifications": notifications,
-			"count":         len(notifications),
-		})
-	})
-
-	return router
+	logger.Info("server exited")
 }
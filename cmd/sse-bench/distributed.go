@@ -0,0 +1,708 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// Distributed runs: one host can't open 100k streams, so `sse-bench agent`
+// serves a small gRPC service that runs the benchmark in-process with flags
+// sent by `sse-bench coordinator`, which splits the user range over the
+// agents, polls their stats and prints a merged report. Messages are JSON
+// (the "json" content subtype), so the service needs no generated code.
+
+const agentServiceName = "ssebench.Agent"
+
+// RPC deadlines; Start covers population loading and token minting
+const (
+	agentStartTimeout = time.Minute
+	agentCallTimeout  = 10 * time.Second
+	agentStopTimeout  = 2 * time.Minute
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec carries agent messages as JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+// startRequest starts a run with the given sse-bench flags
+type startRequest struct {
+	Args []string `json:"args"`
+}
+
+type startResponse struct {
+	Host string `json:"host"`
+}
+
+type statsRequest struct{}
+
+// agentStats is one agent's totals for its current (or last) run
+type agentStats struct {
+	Running           bool             `json:"running"`
+	Error             string           `json:"error,omitempty"` // Why the run ended early
+	ElapsedSec        float64          `json:"elapsed_sec"`
+	ActiveConnections int64            `json:"active_connections"`
+	TotalConnections  int64            `json:"total_connections"`
+	FailedConnections int64            `json:"failed_connections"`
+	Reconnections     int64            `json:"reconnections"`
+	ServerReconnects  int64            `json:"server_reconnects"`
+	Notifications     int64            `json:"notifications_received"`
+	WireBytes         int64            `json:"wire_bytes"`
+	Violations        map[string]int64 `json:"violations,omitempty"`
+	Rejections        map[string]int64 `json:"rejections,omitempty"` // Streams refused with a retry hint, by reason
+	Instances         map[string]int64 `json:"instances,omitempty"`  // Notifications by delivering server instance
+	MaxLatency        time.Duration    `json:"max_latency"`
+	Latency           latencyHistogram `json:"latency"`
+
+	// Stage latencies, when the servers send stage timestamps
+	Stages map[string]latencyHistogram `json:"stages,omitempty"`
+
+	// Latencies by notification priority, for coordinator -sla checks
+	Priorities map[string]latencyHistogram `json:"priorities,omitempty"`
+}
+
+// Snapshot returns the run's totals for a coordinator
+func (m *BenchmarkMetrics) Snapshot() agentStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := agentStats{
+		ElapsedSec:        time.Since(m.startTime).Seconds(),
+		ActiveConnections: atomic.LoadInt64(&m.activeConnections),
+		TotalConnections:  atomic.LoadInt64(&m.totalConnections),
+		FailedConnections: atomic.LoadInt64(&m.failedConnections),
+		Reconnections:     atomic.LoadInt64(&m.reconnections),
+		ServerReconnects:  atomic.LoadInt64(&m.serverReconnects),
+		Notifications:     atomic.LoadInt64(&m.notificationsReceived),
+		WireBytes:         atomic.LoadInt64(&m.wireBytes),
+		Violations:        make(map[string]int64, len(m.violationsByType)),
+		Rejections:        make(map[string]int64, len(m.rejectionsByReason)),
+		Instances:         make(map[string]int64, len(m.instances)),
+		MaxLatency:        m.maxLatency,
+		Latency:           make(latencyHistogram, len(m.latencyHistogram)),
+	}
+	for kind, count := range m.violationsByType {
+		stats.Violations[kind] = count
+	}
+	for reason, record := range m.rejectionsByReason {
+		stats.Rejections[reason] = record.count
+	}
+	for instanceID, record := range m.instances {
+		stats.Instances[instanceID] = record.notifications
+	}
+	stats.Latency.merge(m.latencyHistogram)
+	stats.Stages = histogramsOf(m.latenciesByStage)
+	stats.Priorities = histogramsOf(m.latenciesByPriority)
+	return stats
+}
+
+// Latency histogram buckets grow 5% from 100µs, so merged percentiles are
+// within 5% of exact without shipping every sample
+const (
+	histogramBase   = 100 * time.Microsecond
+	histogramGrowth = 1.05
+)
+
+// latencyHistogram counts latencies per bucket (see latencyBucket)
+type latencyHistogram map[int]int64
+
+// latencyBucket returns the bucket whose upper bound is the first at or above d
+func latencyBucket(d time.Duration) int {
+	if d <= histogramBase {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(d)/float64(histogramBase)) / math.Log(histogramGrowth)))
+}
+
+// bucketUpper is the largest latency counted in bucket i
+func bucketUpper(i int) time.Duration {
+	return time.Duration(float64(histogramBase) * math.Pow(histogramGrowth, float64(i)))
+}
+
+// histogramsOf buckets each key's latencies, nil when there are none
+func histogramsOf(latencies map[string][]time.Duration) map[string]latencyHistogram {
+	if len(latencies) == 0 {
+		return nil
+	}
+	histograms := make(map[string]latencyHistogram, len(latencies))
+	for key, samples := range latencies {
+		h := make(latencyHistogram)
+		for _, d := range samples {
+			h[latencyBucket(d)]++
+		}
+		histograms[key] = h
+	}
+	return histograms
+}
+
+func (h latencyHistogram) merge(other latencyHistogram) {
+	for bucket, count := range other {
+		h[bucket] += count
+	}
+}
+
+func (h latencyHistogram) count() int64 {
+	var total int64
+	for _, count := range h {
+		total += count
+	}
+	return total
+}
+
+// quantile returns the upper bound of the bucket holding quantile q (0-1)
+func (h latencyHistogram) quantile(q float64) time.Duration {
+	total := h.count()
+	if total == 0 {
+		return 0
+	}
+	buckets := make([]int, 0, len(h))
+	for bucket := range h {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	// Same rank as latencyStatsOf: the sample at index total*q
+	rank := int64(float64(total) * q)
+	var seen int64
+	for _, bucket := range buckets {
+		seen += h[bucket]
+		if seen > rank {
+			return bucketUpper(bucket)
+		}
+	}
+	return bucketUpper(buckets[len(buckets)-1])
+}
+
+// agentService is the gRPC service an agent implements
+type agentService interface {
+	Start(ctx context.Context, req *startRequest) (*startResponse, error)
+	Stats(ctx context.Context, req *statsRequest) (*agentStats, error)
+	Stop(ctx context.Context, req *statsRequest) (*agentStats, error)
+}
+
+var agentServiceDesc = grpc.ServiceDesc{
+	ServiceName: agentServiceName,
+	HandlerType: (*agentService)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Start",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := new(startRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(agentService).Start(ctx, req)
+			},
+		},
+		{
+			MethodName: "Stats",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := new(statsRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(agentService).Stats(ctx, req)
+			},
+		},
+		{
+			MethodName: "Stop",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := new(statsRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(agentService).Stop(ctx, req)
+			},
+		},
+	},
+	Metadata: "sse-bench/distributed.go",
+}
+
+// agentRun is one benchmark run on an agent
+type agentRun struct {
+	cancel  context.CancelFunc
+	metrics atomic.Pointer[BenchmarkMetrics]
+	done    chan struct{}
+	err     error // Set before done is closed
+}
+
+// finished reports whether the run has ended
+func (r *agentRun) finished() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// agent runs one benchmark at a time for a coordinator
+type agent struct {
+	logger *zap.Logger
+
+	mu  sync.Mutex
+	run *agentRun
+}
+
+// Start begins a run and returns once its clients are about to connect, or
+// with the error that prevented it (bad flags, unreadable users file)
+func (a *agent) Start(ctx context.Context, req *startRequest) (*startResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.run != nil && !a.run.finished() {
+		return nil, status.Error(codes.FailedPrecondition, "a run is already in progress")
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	run := &agentRun{cancel: cancel, done: make(chan struct{})}
+	started := make(chan struct{})
+	go func() {
+		defer close(run.done)
+		defer cancel()
+		run.err = runBench(runCtx, req.Args, func(m *BenchmarkMetrics) {
+			run.metrics.Store(m)
+			close(started)
+		})
+		if run.err != nil && !errors.Is(run.err, errViolations) && !errors.Is(run.err, errSLA) {
+			a.logger.Error("run failed", zap.Error(run.err))
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-run.done:
+		return nil, status.Error(codes.InvalidArgument, run.err.Error())
+	}
+	a.run = run
+
+	host, _ := os.Hostname()
+	a.logger.Info("run started", zap.Strings("args", req.Args))
+	return &startResponse{Host: host}, nil
+}
+
+// Stats returns the current run's totals
+func (a *agent) Stats(ctx context.Context, req *statsRequest) (*agentStats, error) {
+	a.mu.Lock()
+	run := a.run
+	a.mu.Unlock()
+	return run.stats(), nil
+}
+
+// Stop ends the current run and returns its final totals
+func (a *agent) Stop(ctx context.Context, req *statsRequest) (*agentStats, error) {
+	a.mu.Lock()
+	run := a.run
+	a.mu.Unlock()
+	if run == nil {
+		return &agentStats{}, nil
+	}
+
+	run.cancel()
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	a.logger.Info("run stopped")
+	return run.stats(), nil
+}
+
+func (r *agentRun) stats() *agentStats {
+	if r == nil {
+		return &agentStats{}
+	}
+	stats := &agentStats{}
+	if m := r.metrics.Load(); m != nil {
+		*stats = m.Snapshot()
+	}
+	stats.Running = !r.finished()
+	if !stats.Running && r.err != nil && !errors.Is(r.err, errViolations) && !errors.Is(r.err, errSLA) {
+		stats.Error = r.err.Error()
+	}
+	return stats
+}
+
+// runAgent serves the agent service until interrupted
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("sse-bench agent", flag.ExitOnError)
+	listen := fs.String("listen", ":9000", "Address to serve the coordinator on")
+	fs.Parse(args)
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logger.Fatal("failed to listen", zap.String("addr", *listen), zap.Error(err))
+	}
+
+	a := &agent{logger: logger}
+	srv := grpc.NewServer()
+	srv.RegisterService(&agentServiceDesc, a)
+
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+		logger.Info("agent shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), agentStopTimeout)
+		defer cancel()
+		a.Stop(ctx, &statsRequest{})
+		srv.Stop()
+	}()
+
+	logger.Info("agent listening", zap.String("addr", lis.Addr().String()))
+	if err := srv.Serve(lis); err != nil {
+		logger.Fatal("agent server error", zap.Error(err))
+	}
+}
+
+// agentClient calls one agent
+type agentClient struct {
+	addr  string
+	conn  *grpc.ClientConn
+	stats *agentStats // Last stats received
+}
+
+func (c *agentClient) call(ctx context.Context, method string, req, resp any, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return c.conn.Invoke(ctx, "/"+agentServiceName+"/"+method, req, resp)
+}
+
+// splitUsers gives agent i of n its share of total users and the offset of
+// its range; the first total%n agents take one extra
+func splitUsers(total, n, i int) (users, offset int) {
+	base, extra := total/n, total%n
+	users = base
+	if i < extra {
+		users++
+	}
+	return users, i*base + min(i, extra)
+}
+
+// runCoordinator splits a run over agents and prints merged reports
+func runCoordinator(args []string) {
+	fs := flag.NewFlagSet("sse-bench coordinator", flag.ExitOnError)
+	agentList := fs.String("agents", "", "Comma-separated agent addresses (host:port)")
+	numUsers := fs.Int("users", 1000, "Users across all agents, split into one contiguous range per agent")
+	userOffset := fs.Int("user-offset", 0, "Skip the first N users of the population")
+	reportInterval := fs.Duration("report", 10*time.Second, "Merged report interval")
+	failOnViolation := fs.Bool("fail-on-violations", false, "Exit non-zero if any agent saw a protocol violation")
+	slaSpec := fs.String("sla", "", "Exit non-zero unless each priority meets its objective across all agents, e.g. HIGH=1s@p99,MEDIUM=5s,LOW=30s")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "Usage: sse-bench coordinator -agents HOST:PORT,... [flags] [-- sse-bench flags for the agents]\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	benchArgs := fs.Args()
+
+	var addrs []string
+	for _, addr := range strings.Split(*agentList, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 || *numUsers < len(addrs) {
+		fmt.Fprintln(os.Stderr, "-agents is required and -users must be at least the number of agents")
+		os.Exit(2)
+	}
+	slaTargets, err := parseSLA(*slaSpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	agents := make([]*agentClient, len(addrs))
+	for i, addr := range addrs {
+		conn, err := grpc.NewClient(addr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())))
+		if err != nil {
+			logger.Fatal("invalid agent address", zap.String("agent", addr), zap.Error(err))
+		}
+		defer conn.Close()
+		agents[i] = &agentClient{addr: addr, conn: conn}
+	}
+
+	ctx := context.Background()
+	stopAll := func() {
+		var wg sync.WaitGroup
+		for _, a := range agents {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stats := &agentStats{}
+				if err := a.call(ctx, "Stop", &statsRequest{}, stats, agentStopTimeout); err != nil {
+					logger.Error("failed to stop agent", zap.String("agent", a.addr), zap.Error(err))
+					return
+				}
+				a.stats = stats
+			}()
+		}
+		wg.Wait()
+	}
+
+	// Later flags win, so each agent's range overrides any in benchArgs
+	for i, a := range agents {
+		users, offset := splitUsers(*numUsers, len(agents), i)
+		agentArgs := append(append([]string{}, benchArgs...),
+			"-users", strconv.Itoa(users),
+			"-user-offset", strconv.Itoa(*userOffset+offset))
+
+		resp := &startResponse{}
+		if err := a.call(ctx, "Start", &startRequest{Args: agentArgs}, resp, agentStartTimeout); err != nil {
+			logger.Error("failed to start agent", zap.String("agent", a.addr), zap.Error(err))
+			stopAll()
+			os.Exit(1)
+		}
+		logger.Info("agent started",
+			zap.String("agent", a.addr),
+			zap.String("host", resp.Host),
+			zap.Int("users", users),
+			zap.Int("user_offset", *userOffset+offset))
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*reportInterval)
+	defer ticker.Stop()
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
+
+	for running := true; running; {
+		select {
+		case <-poll.C:
+			running = false
+			for _, a := range agents {
+				stats := &agentStats{}
+				if err := a.call(ctx, "Stats", &statsRequest{}, stats, agentCallTimeout); err != nil {
+					logger.Warn("failed to poll agent", zap.String("agent", a.addr), zap.Error(err))
+					running = true
+					continue
+				}
+				a.stats = stats
+				running = running || stats.Running
+			}
+
+		case <-ticker.C:
+			printMergedReport(logger, agents)
+
+		case sig := <-sigChan:
+			logger.Info("received signal, stopping agents...", zap.String("signal", sig.String()))
+			stopAll()
+			running = false
+		}
+	}
+
+	logger.Info("=== FINAL MERGED REPORT ===")
+	merged := printMergedReport(logger, agents)
+
+	failed := false
+	for _, a := range agents {
+		if a.stats != nil && a.stats.Error != "" {
+			failed = true
+		}
+	}
+	if violations := sumViolations(merged.Violations); violations > 0 && *failOnViolation {
+		logger.Error("benchmark failed: protocol violations detected", zap.Int64("violations", violations))
+		failed = true
+	}
+	slaResults := make([]slaResult, len(slaTargets))
+	for i, target := range slaTargets {
+		slaResults[i] = target.checkHistogram(merged.Priorities[target.Priority])
+	}
+	if !reportSLA(logger, "=== Merged SLA (Event → Client, ±5%) ===", slaResults) {
+		logger.Error("benchmark failed: sla missed")
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+	logger.Info("distributed benchmark completed")
+}
+
+func sumViolations(violations map[string]int64) int64 {
+	var total int64
+	for _, count := range violations {
+		total += count
+	}
+	return total
+}
+
+// mergeHistograms adds each of from's histograms to into's
+func mergeHistograms(into, from map[string]latencyHistogram) {
+	for key, h := range from {
+		if into[key] == nil {
+			into[key] = make(latencyHistogram)
+		}
+		into[key].merge(h)
+	}
+}
+
+// printMergedReport logs each agent's last stats and their sum
+func printMergedReport(logger *zap.Logger, agents []*agentClient) agentStats {
+	merged := agentStats{
+		Violations: make(map[string]int64),
+		Rejections: make(map[string]int64),
+		Instances:  make(map[string]int64),
+		Latency:    make(latencyHistogram),
+		Stages:     make(map[string]latencyHistogram),
+		Priorities: make(map[string]latencyHistogram),
+	}
+	reporting := 0
+
+	logger.Info("=== Agents ===")
+	for _, a := range agents {
+		s := a.stats
+		if s == nil {
+			logger.Warn("agent", zap.String("agent", a.addr), zap.String("status", "no stats yet"))
+			continue
+		}
+		reporting++
+		fields := []zap.Field{
+			zap.String("agent", a.addr),
+			zap.Bool("running", s.Running),
+			zap.Int64("active_connections", s.ActiveConnections),
+			zap.Int64("total_connections", s.TotalConnections),
+			zap.Int64("failed_connections", s.FailedConnections),
+			zap.Int64("notifications_received", s.Notifications),
+			zap.Int64("protocol_violations", sumViolations(s.Violations)),
+			zap.Duration("p99", s.Latency.quantile(0.99)),
+		}
+		if s.Error != "" {
+			fields = append(fields, zap.String("error", s.Error))
+		}
+		logger.Info("agent", fields...)
+
+		merged.ElapsedSec = max(merged.ElapsedSec, s.ElapsedSec)
+		merged.ActiveConnections += s.ActiveConnections
+		merged.TotalConnections += s.TotalConnections
+		merged.FailedConnections += s.FailedConnections
+		merged.Reconnections += s.Reconnections
+		merged.ServerReconnects += s.ServerReconnects
+		merged.Notifications += s.Notifications
+		merged.WireBytes += s.WireBytes
+		merged.MaxLatency = max(merged.MaxLatency, s.MaxLatency)
+		for kind, count := range s.Violations {
+			merged.Violations[kind] += count
+		}
+		for reason, count := range s.Rejections {
+			merged.Rejections[reason] += count
+		}
+		for instanceID, count := range s.Instances {
+			merged.Instances[instanceID] += count
+		}
+		merged.Latency.merge(s.Latency)
+		mergeHistograms(merged.Stages, s.Stages)
+		mergeHistograms(merged.Priorities, s.Priorities)
+	}
+
+	var throughput float64
+	if merged.ElapsedSec > 0 {
+		throughput = float64(merged.Notifications) / merged.ElapsedSec
+	}
+	logger.Info("=== Merged Report ===",
+		zap.Int("agents", len(agents)),
+		zap.Int("agents_reporting", reporting),
+		zap.Duration("elapsed", time.Duration(merged.ElapsedSec*float64(time.Second))),
+		zap.Int64("active_connections", merged.ActiveConnections),
+		zap.Int64("total_connections", merged.TotalConnections),
+		zap.Int64("failed_connections", merged.FailedConnections),
+		zap.Int64("reconnections", merged.Reconnections),
+		zap.Int64("server_reconnects", merged.ServerReconnects),
+		zap.Int64("notifications_received", merged.Notifications),
+		zap.Int64("protocol_violations", sumViolations(merged.Violations)),
+		zap.Int64("wire_bytes", merged.WireBytes),
+		zap.Float64("throughput_per_sec", throughput))
+
+	if count := merged.Latency.count(); count > 0 {
+		logger.Info("=== Merged Latency (Event → Client, ±5%) ===",
+			zap.Int64("count", count),
+			zap.Duration("p50", merged.Latency.quantile(0.50)),
+			zap.Duration("p95", merged.Latency.quantile(0.95)),
+			zap.Duration("p99", merged.Latency.quantile(0.99)),
+			zap.Duration("max", merged.MaxLatency))
+	}
+	printMergedStages(logger, merged.Stages)
+
+	if len(merged.Violations) > 0 {
+		kinds := make([]string, 0, len(merged.Violations))
+		for kind := range merged.Violations {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			logger.Warn("violation", zap.String("type", kind), zap.Int64("count", merged.Violations[kind]))
+		}
+	}
+
+	if len(merged.Instances) > 0 {
+		logger.Info("=== Merged By Instance ===")
+		ids := make([]string, 0, len(merged.Instances))
+		for instanceID := range merged.Instances {
+			ids = append(ids, instanceID)
+		}
+		sort.Strings(ids)
+		for _, instanceID := range ids {
+			var share float64
+			if merged.Notifications > 0 {
+				share = float64(merged.Instances[instanceID]) / float64(merged.Notifications) * 100
+			}
+			logger.Info("instance",
+				zap.String("instance_id", instanceID),
+				zap.Int64("notifications", merged.Instances[instanceID]),
+				zap.Float64("notification_share_pct", share))
+		}
+	}
+
+	if len(merged.Rejections) > 0 {
+		logger.Info("=== Merged Rejections ===")
+		reasons := make([]string, 0, len(merged.Rejections))
+		for reason := range merged.Rejections {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			logger.Info("rejection", zap.String("reason", reason), zap.Int64("count", merged.Rejections[reason]))
+		}
+	}
+	return merged
+}
@@ -1,33 +1,37 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
-)
 
-type NotificationEvent struct {
-	NotificationID string    `json:"notification_id"`
-	UserID         string    `json:"user_id"`
-	Priority       string    `json:"priority"`
-	Message        string    `json:"message"`
-	EventTimestamp time.Time `json:"event_timestamp"`
-	ReceivedAt     time.Time `json:"received_at"`
-}
+	"notification-delivery-system/internal/auth"
+	"notification-delivery-system/internal/generator"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/soak"
+	"notification-delivery-system/internal/startup"
+	"notification-delivery-system/pkg/client"
+)
 
 type LatencyStats struct {
 	Min   time.Duration
@@ -46,59 +50,249 @@ type BenchmarkMetrics struct {
 	totalConnections      int64
 	failedConnections     int64
 	reconnections         int64
+	serverReconnects      int64 // Reconnections the server asked for with a reconnect event
 	notificationsReceived int64
 	latencies             []time.Duration
+	latenciesByRegion     map[string][]time.Duration // Servers simulating regions name one per stream
+	latenciesByStage      map[string][]time.Duration // Servers with SSE_STAGE_TIMESTAMPS send stage stamps (see stages.go)
+	latenciesByPriority   map[string][]time.Duration // Checked against -sla
 	connectionDurations   []time.Duration
 	startTime             time.Time
 	lastReportTime        time.Time
 	notificationsByUser   map[string]int64
+	notificationsByTenant map[string]int64
 	errorsByType          map[string]int64
+	violationsByType      map[string]int64
+	rejectionsByReason    map[string]*rejectionRecord // Streams refused with a retry hint
 	connectionStartTimes  map[string]time.Time
+
+	// Bandwidth: bytes read off the wire vs after decompression
+	wireBytes         int64
+	decodedBytes      int64
+	streamsByEncoding map[string]int64
+
+	// Transport: negotiated protocol per stream and TCP connections opened,
+	// to compare HTTP/1.1 (one connection per stream) with HTTP/2 fan-in
+	streamsByProto map[string]int64
+	tcpConnections int64
+
+	// Mid-stream filter replacements with -filter-churn (atomic)
+	filterUpdates      int64
+	filterUpdateErrors int64
+
+	// Fan-out tracking, only populated with -connections-per-user > 1
+	connectionsPerUser int
+	fanout             map[string]*fanoutRecord
+	fanoutSpreads      []time.Duration
+
+	// Results per -server target, only populated with several targets
+	targets map[string]*targetMetrics
+
+	// Results per server instance, as named by the instance_id of connected
+	// frames and notifications (servers that name none leave it empty)
+	instances map[string]*instanceMetrics
+
+	// Bucketed latencies for coordinators to merge (see distributed.go)
+	latencyHistogram latencyHistogram
+	maxLatency       time.Duration
+
+	// Latency for -metrics-port scrapes (see prometheus.go)
+	promLatency *prometheus.HistogramVec
+	promStages  *prometheus.HistogramVec
+}
+
+// targetMetrics breaks results down by -server target
+type targetMetrics struct {
+	weight            int
+	streams           int
+	activeConnections int64
+	totalConnections  int64
+	failedConnections int64
+	notifications     int64
+	latencies         []time.Duration
+}
+
+// rejectionRecord totals the stream rejections for one reason
+type rejectionRecord struct {
+	count      int64
+	retryAfter time.Duration // Sum of the server's hints
+}
+
+// instanceMetrics breaks results down by the server instance that served
+// the stream or delivered the notification
+type instanceMetrics struct {
+	activeStreams int64
+	totalStreams  int64
+	notifications int64
+	latencies     []time.Duration
+}
+
+// instance returns the instance's record, creating it; m.mu must be held
+func (m *BenchmarkMetrics) instance(instanceID string) *instanceMetrics {
+	record := m.instances[instanceID]
+	if record == nil {
+		record = &instanceMetrics{}
+		m.instances[instanceID] = record
+	}
+	return record
 }
 
-func NewBenchmarkMetrics() *BenchmarkMetrics {
+// fanoutRecord tracks copies of one notification across a user's connections
+type fanoutRecord struct {
+	firstAt time.Time
+	copies  int
+}
+
+func NewBenchmarkMetrics(connectionsPerUser int) *BenchmarkMetrics {
 	return &BenchmarkMetrics{
-		notificationsByUser:  make(map[string]int64),
-		errorsByType:         make(map[string]int64),
-		connectionStartTimes: make(map[string]time.Time),
-		startTime:            time.Now(),
-		lastReportTime:       time.Now(),
+		connectionsPerUser:    connectionsPerUser,
+		fanout:                make(map[string]*fanoutRecord),
+		notificationsByUser:   make(map[string]int64),
+		notificationsByTenant: make(map[string]int64),
+		latenciesByRegion:     make(map[string][]time.Duration),
+		latenciesByStage:      make(map[string][]time.Duration),
+		latenciesByPriority:   make(map[string][]time.Duration),
+		errorsByType:          make(map[string]int64),
+		violationsByType:      make(map[string]int64),
+		rejectionsByReason:    make(map[string]*rejectionRecord),
+		connectionStartTimes:  make(map[string]time.Time),
+		streamsByEncoding:     make(map[string]int64),
+		streamsByProto:        make(map[string]int64),
+		targets:               make(map[string]*targetMetrics),
+		instances:             make(map[string]*instanceMetrics),
+		latencyHistogram:      make(latencyHistogram),
+		promLatency:           newLatencyHistogram(),
+		promStages:            newStageHistogram(),
+		startTime:             time.Now(),
+		lastReportTime:        time.Now(),
 	}
 }
 
-func (m *BenchmarkMetrics) RecordConnection(userID string) {
+// AddTarget registers a -server target whose results are reported
+// separately; call it before any client connects
+func (m *BenchmarkMetrics) AddTarget(serverURL string, weight, streams int) {
+	m.targets[serverURL] = &targetMetrics{weight: weight, streams: streams}
+}
+
+func (m *BenchmarkMetrics) RecordConnection(connID, serverURL string) {
 	atomic.AddInt64(&m.activeConnections, 1)
 	atomic.AddInt64(&m.totalConnections, 1)
 	m.mu.Lock()
-	m.connectionStartTimes[userID] = time.Now()
+	m.connectionStartTimes[connID] = time.Now()
+	if t := m.targets[serverURL]; t != nil {
+		t.activeConnections++
+		t.totalConnections++
+	}
 	m.mu.Unlock()
 }
 
-func (m *BenchmarkMetrics) RecordDisconnection(userID string) {
+func (m *BenchmarkMetrics) RecordDisconnection(connID, serverURL string) {
 	atomic.AddInt64(&m.activeConnections, -1)
 	m.mu.Lock()
-	if startTime, exists := m.connectionStartTimes[userID]; exists {
+	if t := m.targets[serverURL]; t != nil {
+		t.activeConnections--
+	}
+	if startTime, exists := m.connectionStartTimes[connID]; exists {
 		duration := time.Since(startTime)
 		m.connectionDurations = append(m.connectionDurations, duration)
-		delete(m.connectionStartTimes, userID)
+		delete(m.connectionStartTimes, connID)
 	}
 	m.mu.Unlock()
 }
 
+// RecordEncoding counts a stream by the content encoding the server chose
+func (m *BenchmarkMetrics) RecordEncoding(encoding string) {
+	if encoding == "" {
+		encoding = "identity"
+	}
+	m.mu.Lock()
+	m.streamsByEncoding[encoding]++
+	m.mu.Unlock()
+}
+
+// RecordProtocol counts a stream by its negotiated HTTP version
+func (m *BenchmarkMetrics) RecordProtocol(proto string) {
+	m.mu.Lock()
+	m.streamsByProto[proto]++
+	m.mu.Unlock()
+}
+
 func (m *BenchmarkMetrics) RecordReconnection() {
 	atomic.AddInt64(&m.reconnections, 1)
 }
 
-func (m *BenchmarkMetrics) RecordFailedConnection() {
+// RecordServerReconnect counts a reconnect the server asked for
+func (m *BenchmarkMetrics) RecordServerReconnect() {
+	atomic.AddInt64(&m.reconnections, 1)
+	atomic.AddInt64(&m.serverReconnects, 1)
+}
+
+func (m *BenchmarkMetrics) RecordFailedConnection(serverURL string) {
 	atomic.AddInt64(&m.failedConnections, 1)
+	if t := m.targets[serverURL]; t != nil {
+		m.mu.Lock()
+		t.failedConnections++
+		m.mu.Unlock()
+	}
 }
 
-func (m *BenchmarkMetrics) RecordNotification(userID string, latency time.Duration) {
+// RecordInstanceStream counts a stream opening (+1) or closing (-1) on a
+// server instance
+func (m *BenchmarkMetrics) RecordInstanceStream(instanceID string, delta int64) {
+	m.mu.Lock()
+	record := m.instance(instanceID)
+	record.activeStreams += delta
+	if delta > 0 {
+		record.totalStreams += delta
+	}
+	m.mu.Unlock()
+}
+
+func (m *BenchmarkMetrics) RecordNotification(tenantID, userID, priority, region, serverURL, instanceID string, latency time.Duration) {
 	atomic.AddInt64(&m.notificationsReceived, 1)
 	m.mu.Lock()
 	m.latencies = append(m.latencies, latency)
+	if priority != "" {
+		m.latenciesByPriority[priority] = append(m.latenciesByPriority[priority], latency)
+	}
+	m.latencyHistogram[latencyBucket(latency)]++
+	m.maxLatency = max(m.maxLatency, latency)
+	if region != "" {
+		m.latenciesByRegion[region] = append(m.latenciesByRegion[region], latency)
+	}
+	if t := m.targets[serverURL]; t != nil {
+		t.notifications++
+		t.latencies = append(t.latencies, latency)
+	}
+	if instanceID != "" {
+		record := m.instance(instanceID)
+		record.notifications++
+		record.latencies = append(record.latencies, latency)
+	}
 	m.notificationsByUser[userID]++
+	m.notificationsByTenant[tenantID]++
 	m.mu.Unlock()
+	m.promLatency.WithLabelValues(serverURL, instanceID).Observe(latency.Seconds())
+}
+
+// RecordFanout counts one copy of a notification arriving on one of the
+// user's connections; the spread is the delay between the first and each
+// later copy, i.e. the cost of fanning out to the user's other devices
+func (m *BenchmarkMetrics) RecordFanout(notificationID string, receivedAt time.Time) {
+	if m.connectionsPerUser <= 1 || notificationID == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, exists := m.fanout[notificationID]
+	if !exists {
+		m.fanout[notificationID] = &fanoutRecord{firstAt: receivedAt, copies: 1}
+		return
+	}
+	record.copies++
+	m.fanoutSpreads = append(m.fanoutSpreads, receivedAt.Sub(record.firstAt))
 }
 
 func (m *BenchmarkMetrics) RecordError(errorType string) {
@@ -107,17 +301,67 @@ func (m *BenchmarkMetrics) RecordError(errorType string) {
 	m.mu.Unlock()
 }
 
+// RecordViolation counts a protocol violation: the stream still works, but
+// the server broke the SSE contract (see the violation* constants)
+func (m *BenchmarkMetrics) RecordViolation(kind string) {
+	m.mu.Lock()
+	m.violationsByType[kind]++
+	m.mu.Unlock()
+}
+
+// RecordRejection counts a stream the server refused with a retry hint
+func (m *BenchmarkMetrics) RecordRejection(reason string, retryAfter time.Duration) {
+	m.mu.Lock()
+	record := m.rejectionsByReason[reason]
+	if record == nil {
+		record = &rejectionRecord{}
+		m.rejectionsByReason[reason] = record
+	}
+	record.count++
+	record.retryAfter += retryAfter
+	m.mu.Unlock()
+}
+
+// TotalViolations returns the number of protocol violations seen so far
+func (m *BenchmarkMetrics) TotalViolations() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total int64
+	for _, count := range m.violationsByType {
+		total += count
+	}
+	return total
+}
+
+// CheckSLA judges the run's latencies by priority against the targets
+func (m *BenchmarkMetrics) CheckSLA(targets []slaTarget) []slaResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make([]slaResult, len(targets))
+	for i, target := range targets {
+		results[i] = target.check(m.latenciesByPriority[target.Priority])
+	}
+	return results
+}
+
 func (m *BenchmarkMetrics) GetLatencyStats() LatencyStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if len(m.latencies) == 0 {
+	return latencyStatsOf(m.latencies)
+}
+
+// latencyStatsOf summarizes latencies without modifying them
+func latencyStatsOf(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
 		return LatencyStats{}
 	}
 
 	// Sort latencies for percentile calculation
-	sortedLatencies := make([]time.Duration, len(m.latencies))
-	copy(sortedLatencies, m.latencies)
+	sortedLatencies := make([]time.Duration, len(latencies))
+	copy(sortedLatencies, latencies)
 	sort.Slice(sortedLatencies, func(i, j int) bool {
 		return sortedLatencies[i] < sortedLatencies[j]
 	})
@@ -150,6 +394,11 @@ func (m *BenchmarkMetrics) PrintReport(logger *zap.Logger, detailed bool) {
 	m.lastReportTime = time.Now()
 
 	latencyStats := m.GetLatencyStats()
+
+	var violations int64
+	for _, count := range m.violationsByType {
+		violations += count
+	}
 	throughput := float64(m.notificationsReceived) / elapsed.Seconds()
 	recentThroughput := float64(m.notificationsReceived) / sinceLast.Seconds()
 
@@ -159,7 +408,9 @@ func (m *BenchmarkMetrics) PrintReport(logger *zap.Logger, detailed bool) {
 		zap.Int64("total_connections", atomic.LoadInt64(&m.totalConnections)),
 		zap.Int64("failed_connections", atomic.LoadInt64(&m.failedConnections)),
 		zap.Int64("reconnections", atomic.LoadInt64(&m.reconnections)),
+		zap.Int64("server_reconnects", atomic.LoadInt64(&m.serverReconnects)),
 		zap.Int64("notifications_received", atomic.LoadInt64(&m.notificationsReceived)),
+		zap.Int64("protocol_violations", violations),
 		zap.Float64("throughput_per_sec", throughput),
 		zap.Float64("recent_throughput_per_sec", recentThroughput),
 	)
@@ -175,6 +426,212 @@ func (m *BenchmarkMetrics) PrintReport(logger *zap.Logger, detailed bool) {
 		)
 	}
 
+	if len(m.latenciesByRegion) > 0 {
+		logger.Info("=== Latency by Region (Event → Client) ===")
+		regions := make([]string, 0, len(m.latenciesByRegion))
+		for region := range m.latenciesByRegion {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+		for _, region := range regions {
+			stats := latencyStatsOf(m.latenciesByRegion[region])
+			logger.Info("region",
+				zap.String("region", region),
+				zap.Int64("count", stats.Count),
+				zap.Duration("p50", stats.P50),
+				zap.Duration("p95", stats.P95),
+				zap.Duration("p99", stats.P99),
+				zap.Duration("max", stats.Max))
+		}
+	}
+
+	if len(m.latenciesByPriority) > 0 {
+		logger.Info("=== Latency by Priority (Event → Client) ===")
+		priorities := make([]string, 0, len(m.latenciesByPriority))
+		for priority := range m.latenciesByPriority {
+			priorities = append(priorities, priority)
+		}
+		sort.Slice(priorities, func(i, j int) bool {
+			return priorityRank(priorities[i]) < priorityRank(priorities[j])
+		})
+		for _, priority := range priorities {
+			stats := latencyStatsOf(m.latenciesByPriority[priority])
+			logger.Info("priority",
+				zap.String("priority", priority),
+				zap.Int64("count", stats.Count),
+				zap.Duration("p50", stats.P50),
+				zap.Duration("p95", stats.P95),
+				zap.Duration("p99", stats.P99),
+				zap.Duration("max", stats.Max))
+		}
+	}
+
+	m.printStageReport(logger)
+
+	if len(m.targets) > 1 {
+		logger.Info("=== By Target ===")
+		urls := make([]string, 0, len(m.targets))
+		for serverURL := range m.targets {
+			urls = append(urls, serverURL)
+		}
+		sort.Strings(urls)
+		for _, serverURL := range urls {
+			t := m.targets[serverURL]
+			stats := latencyStatsOf(t.latencies)
+			var share float64
+			if m.notificationsReceived > 0 {
+				share = float64(t.notifications) / float64(m.notificationsReceived) * 100
+			}
+			logger.Info("target",
+				zap.String("server", serverURL),
+				zap.Int("weight", t.weight),
+				zap.Int("streams", t.streams),
+				zap.Int64("active_connections", t.activeConnections),
+				zap.Int64("total_connections", t.totalConnections),
+				zap.Int64("failed_connections", t.failedConnections),
+				zap.Int64("notifications", t.notifications),
+				zap.Float64("notification_share_pct", share),
+				zap.Duration("p50", stats.P50),
+				zap.Duration("p95", stats.P95),
+				zap.Duration("p99", stats.P99))
+		}
+	}
+
+	if len(m.instances) > 0 {
+		logger.Info("=== By Instance ===")
+		ids := make([]string, 0, len(m.instances))
+		for instanceID := range m.instances {
+			ids = append(ids, instanceID)
+		}
+		sort.Strings(ids)
+		for _, instanceID := range ids {
+			record := m.instances[instanceID]
+			stats := latencyStatsOf(record.latencies)
+			var share float64
+			if m.notificationsReceived > 0 {
+				share = float64(record.notifications) / float64(m.notificationsReceived) * 100
+			}
+			logger.Info("instance",
+				zap.String("instance_id", instanceID),
+				zap.Int64("active_streams", record.activeStreams),
+				zap.Int64("total_streams", record.totalStreams),
+				zap.Int64("notifications", record.notifications),
+				zap.Float64("notification_share_pct", share),
+				zap.Duration("p50", stats.P50),
+				zap.Duration("p95", stats.P95),
+				zap.Duration("p99", stats.P99))
+		}
+	}
+
+	if len(m.notificationsByTenant) > 1 {
+		logger.Info("=== Notifications by Tenant ===")
+		tenants := make([]string, 0, len(m.notificationsByTenant))
+		for tenantID := range m.notificationsByTenant {
+			tenants = append(tenants, tenantID)
+		}
+		sort.Strings(tenants)
+		for _, tenantID := range tenants {
+			logger.Info("tenant",
+				zap.String("tenant_id", tenantID),
+				zap.Int64("count", m.notificationsByTenant[tenantID]))
+		}
+	}
+
+	if m.connectionsPerUser > 1 && len(m.fanout) > 0 {
+		var complete, partial, extra, copies int
+		for _, record := range m.fanout {
+			copies += record.copies
+			switch {
+			case record.copies == m.connectionsPerUser:
+				complete++
+			case record.copies < m.connectionsPerUser:
+				partial++
+			default:
+				extra++
+			}
+		}
+
+		fields := []zap.Field{
+			zap.Int("connections_per_user", m.connectionsPerUser),
+			zap.Int("unique_notifications", len(m.fanout)),
+			zap.Int("copies_received", copies),
+			zap.Int("complete", complete),
+			zap.Int("partial", partial), // Includes notifications still in flight
+			zap.Int("duplicated", extra),
+			zap.Float64("complete_pct", float64(complete)/float64(len(m.fanout))*100),
+		}
+		if len(m.fanoutSpreads) > 0 {
+			spreads := make([]time.Duration, len(m.fanoutSpreads))
+			copy(spreads, m.fanoutSpreads)
+			sort.Slice(spreads, func(i, j int) bool { return spreads[i] < spreads[j] })
+			fields = append(fields,
+				zap.Duration("spread_p50", spreads[len(spreads)*50/100]),
+				zap.Duration("spread_p95", spreads[len(spreads)*95/100]),
+				zap.Duration("spread_max", spreads[len(spreads)-1]))
+		}
+		logger.Info("=== Fan-out (copies per notification across a user's connections) ===", fields...)
+	}
+
+	if wire := atomic.LoadInt64(&m.wireBytes); wire > 0 {
+		decoded := atomic.LoadInt64(&m.decodedBytes)
+		fields := []zap.Field{
+			zap.Int64("wire_bytes", wire),
+			zap.Int64("decoded_bytes", decoded),
+			zap.Float64("compression_ratio", float64(decoded)/float64(wire)),
+			zap.Float64("wire_bytes_per_sec", float64(wire)/elapsed.Seconds()),
+		}
+		if m.notificationsReceived > 0 {
+			fields = append(fields, zap.Float64("wire_bytes_per_notification", float64(wire)/float64(m.notificationsReceived)))
+		}
+		for encoding, count := range m.streamsByEncoding {
+			fields = append(fields, zap.Int64("streams_"+encoding, count))
+		}
+		logger.Info("=== Bandwidth ===", fields...)
+	}
+
+	if len(m.streamsByProto) > 0 {
+		fields := []zap.Field{zap.Int64("tcp_connections", atomic.LoadInt64(&m.tcpConnections))}
+		for proto, count := range m.streamsByProto {
+			fields = append(fields, zap.Int64("streams_"+proto, count))
+		}
+		logger.Info("=== Transport ===", fields...)
+	}
+
+	if updates, failed := atomic.LoadInt64(&m.filterUpdates), atomic.LoadInt64(&m.filterUpdateErrors); updates+failed > 0 {
+		logger.Info("=== Filter Churn ===",
+			zap.Int64("updates", updates),
+			zap.Int64("failed", failed),
+			zap.Float64("updates_per_sec", float64(updates)/elapsed.Seconds()))
+	}
+
+	if len(m.violationsByType) > 0 {
+		logger.Warn("=== Protocol Violations ===")
+		kinds := make([]string, 0, len(m.violationsByType))
+		for kind := range m.violationsByType {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			logger.Warn("violation", zap.String("type", kind), zap.Int64("count", m.violationsByType[kind]))
+		}
+	}
+
+	if len(m.rejectionsByReason) > 0 {
+		logger.Info("=== Rejections ===")
+		reasons := make([]string, 0, len(m.rejectionsByReason))
+		for reason := range m.rejectionsByReason {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			record := m.rejectionsByReason[reason]
+			logger.Info("rejection",
+				zap.String("reason", reason),
+				zap.Int64("count", record.count),
+				zap.Duration("avg_retry_after", record.retryAfter/time.Duration(record.count)))
+		}
+	}
+
 	if detailed && len(m.errorsByType) > 0 {
 		logger.Info("=== Errors by Type ===")
 		for errType, count := range m.errorsByType {
@@ -206,230 +663,592 @@ func (m *BenchmarkMetrics) PrintReport(logger *zap.Logger, detailed bool) {
 	}
 }
 
+// SSEClient is one benchmark stream: a pkg/client stream whose callbacks
+// feed BenchmarkMetrics
 type SSEClient struct {
 	userID      string
+	connID      string // userID#n, distinguishes a user's parallel streams
 	serverURL   string
+	tenantID    string // Empty for the default tenant
 	metrics     *BenchmarkMetrics
 	logger      *zap.Logger
-	stopChan    chan struct{}
-	wg          *sync.WaitGroup
-	maxRetries  int
-	retryDelay  time.Duration
-	reconnect   bool
-	pingTimeout time.Duration
+	filterChurn time.Duration // Replace the stream's filter this often (0 = never)
+
+	// Set by callbacks on the stream's goroutine
+	region     string // Simulated region from the connected frame, if the server names one
+	instanceID string // Server instance of the current stream (X-Instance-ID), if the server names one
+
+	stream *client.Client
 }
 
-func NewSSEClient(userID, serverURL string, metrics *BenchmarkMetrics, logger *zap.Logger, reconnect bool) *SSEClient {
-	return &SSEClient{
-		userID:      userID,
-		serverURL:   serverURL,
-		metrics:     metrics,
-		logger:      logger,
-		stopChan:    make(chan struct{}),
-		wg:          &sync.WaitGroup{},
-		maxRetries:  10,
-		retryDelay:  time.Second,
-		reconnect:   reconnect,
-		pingTimeout: 35 * time.Second, // Slightly longer than server's 30s ping interval
+// NewSSEClient creates a benchmark stream for cfg's user and server.
+// cfg.HTTPClient is shared by every stream so they pool connections in one
+// Transport; its handlers, logger and byte counters are set here.
+func NewSSEClient(cfg client.Config, connID string, metrics *BenchmarkMetrics, logger *zap.Logger) *SSEClient {
+	c := &SSEClient{
+		userID:    cfg.UserID,
+		connID:    connID,
+		serverURL: cfg.ServerURL,
+		tenantID:  cfg.TenantID,
+		metrics:   metrics,
+		logger:    logger,
+	}
+
+	cfg.Logger = logger
+	cfg.WireBytes = &metrics.wireBytes
+	cfg.DecodedBytes = &metrics.decodedBytes
+	cfg.Handlers = client.Handlers{
+		OnNewConn:      func() { atomic.AddInt64(&metrics.tcpConnections, 1) },
+		OnConnect:      c.onConnect,
+		OnConnected:    c.onConnected,
+		OnNotification: c.onNotification,
+		OnDisconnect:   c.onDisconnect,
+		OnReconnect: func(reason string, _ time.Duration) {
+			if reason == client.ReconnectRequested {
+				metrics.RecordServerReconnect()
+				return
+			}
+			metrics.RecordReconnection()
+		},
+		OnRejected: func(rejected *client.RejectedError) {
+			metrics.RecordRejection(rejected.Reason, rejected.After)
+		},
+		OnError: func(err error, _ int) {
+			metrics.RecordError(fmt.Sprintf("stream_error: %s", err.Error()))
+		},
+		OnGiveUp:    func(error) { metrics.RecordFailedConnection(c.serverURL) },
+		OnViolation: metrics.RecordViolation,
 	}
+	c.stream = client.New(cfg)
+	return c
 }
 
+// Connect opens the stream in the background
 func (c *SSEClient) Connect(ctx context.Context) {
-	c.wg.Add(1)
-	go c.connectLoop(ctx)
+	c.stream.Start(ctx)
 }
 
-func (c *SSEClient) connectLoop(ctx context.Context) {
-	defer c.wg.Done()
+// Drop ends the current stream as if the network dropped it; the client
+// reconnects right away (see -herd-test). False means no stream was open.
+func (c *SSEClient) Drop() bool {
+	return c.stream.Drop()
+}
 
-	retryCount := 0
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-c.stopChan:
-			return
-		default:
-		}
+func (c *SSEClient) Stop() {
+	c.stream.Stop()
+}
 
-		// Establish connection
-		if err := c.stream(ctx); err != nil {
-			if ctx.Err() != nil {
-				return
-			}
+func (c *SSEClient) onConnect(info client.StreamInfo) {
+	c.metrics.RecordConnection(c.connID, c.serverURL)
+	c.instanceID = info.InstanceID
+	if c.instanceID != "" {
+		c.metrics.RecordInstanceStream(c.instanceID, 1)
+	}
+	c.metrics.RecordProtocol(info.Proto)
+	c.metrics.RecordEncoding(info.Encoding)
+}
 
-			c.metrics.RecordError(fmt.Sprintf("stream_error: %s", err.Error()))
-			c.logger.Warn("stream error",
-				zap.String("user_id", c.userID),
-				zap.Error(err),
-				zap.Int("retry_count", retryCount),
-			)
+func (c *SSEClient) onDisconnect(info client.StreamInfo) {
+	if info.InstanceID != "" {
+		c.metrics.RecordInstanceStream(info.InstanceID, -1)
+	}
+	c.metrics.RecordDisconnection(c.connID, c.serverURL)
+}
 
-			if !c.reconnect {
-				c.metrics.RecordFailedConnection()
-				return
-			}
+func (c *SSEClient) onConnected(ctx context.Context, connected client.Connected) {
+	c.region = connected.Region
+	if c.filterChurn > 0 && connected.ConnectionID != "" {
+		go c.churnFilter(ctx, connected.ConnectionID)
+	}
+}
 
-			retryCount++
-			if retryCount > c.maxRetries {
-				c.logger.Error("max retries exceeded",
-					zap.String("user_id", c.userID),
-					zap.Int("retries", retryCount),
-				)
-				c.metrics.RecordFailedConnection()
-				return
-			}
+func (c *SSEClient) onNotification(event *models.NotificationEvent, receivedAt time.Time) {
+	c.metrics.RecordNotification(c.tenantID, c.userID, event.Priority, c.region, c.serverURL, c.deliveredBy(event), receivedAt.Sub(event.EventTimestamp))
+	c.metrics.RecordStages(event.Stages, receivedAt)
+	c.metrics.RecordFanout(event.NotificationID, receivedAt)
+}
 
-			c.metrics.RecordReconnection()
-			// Exponential backoff
-			backoff := c.retryDelay * time.Duration(1<<uint(retryCount))
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second
-			}
+// deliveredBy names the instance that delivered a notification: the one
+// in its payload, else the stream's
+func (c *SSEClient) deliveredBy(event *models.NotificationEvent) string {
+	if event.InstanceID != "" {
+		return event.InstanceID
+	}
+	return c.instanceID
+}
 
-			select {
-			case <-time.After(backoff):
-			case <-ctx.Done():
-				return
-			case <-c.stopChan:
-				return
-			}
-		} else {
-			// Clean disconnection
+// churnEventTypes are the types -filter-churn subscribes streams to
+var churnEventTypes = []models.EventType{
+	models.EventJobNew, models.EventJobUpdate, models.EventJobApplicationViewed, models.EventJobApplicationStatus,
+	models.EventConnectionRequest, models.EventConnectionAccepted, models.EventConnectionEndorsed,
+	models.EventFollowerNew, models.EventFollowerContentLiked, models.EventFollowerContentComment,
+}
+
+// churnFilter replaces the stream's filter with a random one every
+// filterChurn, starting at a random offset so streams don't update in step,
+// until the stream ends
+func (c *SSEClient) churnFilter(ctx context.Context, connectionID string) {
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(c.filterChurn))) + 1)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-timer.C:
 		}
+		c.updateFilter(ctx, connectionID, randomFilter())
+		timer.Reset(c.filterChurn)
 	}
 }
 
-func (c *SSEClient) stream(ctx context.Context) error {
-	url := fmt.Sprintf("%s/notifications/stream?user_id=%s", c.serverURL, c.userID)
+// randomFilter subscribes to a few event types and, half the time, a
+// priority; one update in four clears the filter
+func randomFilter() client.FilterUpdate {
+	if rand.Intn(4) == 0 {
+		return client.FilterUpdate{}
+	}
+	types := make([]string, 1+rand.Intn(3))
+	for i := range types {
+		types[i] = string(churnEventTypes[rand.Intn(len(churnEventTypes))])
+	}
+	filter := client.FilterUpdate{Types: types}
+	if rand.Intn(2) == 0 {
+		priorities := []models.Priority{models.PriorityHigh, models.PriorityMedium, models.PriorityLow}
+		filter.Priorities = []string{string(priorities[rand.Intn(len(priorities))])}
+	}
+	return filter
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+// updateFilter replaces the stream's filter on the server
+func (c *SSEClient) updateFilter(ctx context.Context, connectionID string, filter client.FilterUpdate) {
+	if err := c.stream.UpdateFilter(ctx, connectionID, filter); err != nil {
+		// A 404 for a stream that just ended is expected
+		if ctx.Err() == nil {
+			atomic.AddInt64(&c.metrics.filterUpdateErrors, 1)
+			c.logger.Debug("filter update failed", zap.String("connection_id", c.connID), zap.Error(err))
+		}
+		return
 	}
+	atomic.AddInt64(&c.metrics.filterUpdates, 1)
+}
 
-	req.Header.Set("Accept", "text/event-stream")
-	req.Header.Set("Cache-Control", "no-cache")
-	req.Header.Set("Connection", "keep-alive")
+// transportOptions tunes the Transport every client shares. Go's defaults
+// (100 idle connections, 2 per host) make a 10k-stream run churn
+// connections and measure the bench rather than the server.
+type transportOptions struct {
+	insecureSkipVerify    bool
+	caFile                string
+	httpVersion           string
+	maxIdleConns          int // 0 = unlimited
+	maxIdleConnsPerHost   int
+	maxConnsPerHost       int // 0 = unlimited
+	dialTimeout           time.Duration
+	keepAlive             time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration // 0 waits as long as the server takes
+	disableCompression    bool          // Streams set Accept-Encoding themselves (-compression)
+}
 
-	client := &http.Client{
-		Timeout: 0, // No timeout for streaming
-	}
+// newHTTPClient builds the shared streaming client (no timeout) with optional
+// TLS settings for benchmarking HTTPS endpoints. httpVersion "1.1" forces
+// HTTP/1.1, "2" forces HTTP/2 (h2c prior knowledge on http:// URLs), and ""
+// keeps Go's default (HTTP/2 over TLS when offered, else HTTP/1.1).
+func newHTTPClient(opts transportOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.insecureSkipVerify}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("connect: %w", err)
+	if opts.caFile != "" {
+		pem, err := os.ReadFile(opts.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.caFile)
+		}
+		tlsConfig.RootCAs = pool
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	dialer := &net.Dialer{Timeout: opts.dialTimeout, KeepAlive: opts.keepAlive}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConns = opts.maxIdleConns
+	transport.MaxIdleConnsPerHost = opts.maxIdleConnsPerHost
+	transport.MaxConnsPerHost = opts.maxConnsPerHost
+	transport.TLSHandshakeTimeout = opts.tlsHandshakeTimeout
+	transport.ResponseHeaderTimeout = opts.responseHeaderTimeout
+	transport.DisableCompression = opts.disableCompression
+
+	switch opts.httpVersion {
+	case "":
+	case "1.1":
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	case "2":
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("invalid -http %q (want 1.1 or 2)", opts.httpVersion)
 	}
 
-	c.metrics.RecordConnection(c.userID)
-	c.logger.Debug("connected", zap.String("user_id", c.userID))
+	return &http.Client{
+		Transport: transport,
+		Timeout:   0, // No timeout for streaming
+	}, nil
+}
 
-	defer func() {
-		c.metrics.RecordDisconnection(c.userID)
-		c.logger.Debug("disconnected", zap.String("user_id", c.userID))
-	}()
+// herdSettle is how long the herd test keeps counting after the last
+// stream is back, for notifications delivered during the outage to arrive
+const herdSettle = 5 * time.Second
+
+// runHerdTest waits delay, drops every stream at once and reports how long
+// the clients took to all reconnect and how many notifications the server
+// pushed that no client received. Missed is server delivered_total (from
+// /admin/stats) minus notifications received over the same window, so it
+// assumes the bench's users are the only ones connected.
+func runHerdTest(ctx context.Context, clients []*SSEClient, metrics *BenchmarkMetrics, httpClient *http.Client, serverURLs []string, adminToken string, delay time.Duration, logger *zap.Logger) {
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return
+	}
 
-	reader := bufio.NewReader(resp.Body)
-	lastActivity := time.Now()
+	serverBefore, serverErr := serverDelivered(ctx, httpClient, serverURLs, adminToken)
+	receivedBefore := atomic.LoadInt64(&metrics.notificationsReceived)
+	connectedBefore := atomic.LoadInt64(&metrics.totalConnections)
 
-	// Ping timeout checker
-	pingTimeoutChan := time.After(c.pingTimeout)
+	logger.Info("herd test: dropping every stream", zap.Int("clients", len(clients)))
+	start := time.Now()
+	dropped := 0
+	for _, client := range clients {
+		if client.Drop() {
+			dropped++
+		}
+	}
 
-	for {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	var firstBack, allBack time.Duration
+	for allBack == 0 {
 		select {
+		case <-ticker.C:
 		case <-ctx.Done():
-			return nil
-		case <-c.stopChan:
-			return nil
-		case <-pingTimeoutChan:
-			if time.Since(lastActivity) > c.pingTimeout {
-				return fmt.Errorf("ping timeout: no activity for %v", c.pingTimeout)
-			}
-			pingTimeoutChan = time.After(c.pingTimeout)
-		default:
+			logger.Warn("herd test: run ended before every stream reconnected",
+				zap.Int("dropped", dropped),
+				zap.Int64("reconnected", atomic.LoadInt64(&metrics.totalConnections)-connectedBefore))
+			return
 		}
+		back := atomic.LoadInt64(&metrics.totalConnections) - connectedBefore
+		if back > 0 && firstBack == 0 {
+			firstBack = time.Since(start)
+		}
+		if back >= int64(dropped) {
+			allBack = time.Since(start)
+		}
+	}
+
+	select {
+	case <-time.After(herdSettle):
+	case <-ctx.Done():
+		return
+	}
 
-		line, err := reader.ReadString('\n')
+	serverAfter, err := serverDelivered(ctx, httpClient, serverURLs, adminToken)
+	received := atomic.LoadInt64(&metrics.notificationsReceived) - receivedBefore
+	fields := []zap.Field{
+		zap.Int("dropped", dropped),
+		zap.Duration("time_to_first_reconnect", firstBack),
+		zap.Duration("time_to_full_reconnect", allBack),
+		zap.Int64("received", received),
+	}
+	if serverErr == nil && err == nil {
+		delivered := serverAfter - serverBefore
+		fields = append(fields,
+			zap.Int64("server_delivered", delivered),
+			zap.Int64("missed_notifications", max(delivered-received, 0)))
+	} else {
+		fields = append(fields, zap.NamedError("server_stats_error", errors.Join(serverErr, err)))
+	}
+	logger.Info("=== Herd Test ===", fields...)
+}
+
+// serverDelivered sums the task picker's delivered_total from /admin/stats
+// over the -server targets (each an instance of the cluster)
+func serverDelivered(ctx context.Context, httpClient *http.Client, serverURLs []string, adminToken string) (int64, error) {
+	var total int64
+	for _, serverURL := range serverURLs {
+		delivered, err := instanceDelivered(ctx, httpClient, serverURL, adminToken)
 		if err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return fmt.Errorf("read: %w", err)
+			return 0, fmt.Errorf("%s: %w", serverURL, err)
 		}
+		total += delivered
+	}
+	return total, nil
+}
 
-		lastActivity = time.Now()
-		line = strings.TrimSpace(line)
+// instanceDelivered reads one instance's delivered_total, sending adminToken
+// when set
+func instanceDelivered(ctx context.Context, httpClient *http.Client, serverURL, adminToken string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-		if line == "" {
-			continue
-		}
+	req, err := http.NewRequestWithContext(ctx, "GET", serverURL+"/admin/stats", nil)
+	if err != nil {
+		return 0, err
+	}
+	if adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("admin stats: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var stats struct {
+		Picker struct {
+			Delivered int64 `json:"delivered_total"`
+		} `json:"picker"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return 0, fmt.Errorf("admin stats: %w", err)
+	}
+	return stats.Picker.Delivered, nil
+}
 
-		// Handle SSE event
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimPrefix(line, "data:")
-			data = strings.TrimSpace(data)
+// serverTarget is one -server endpoint and its relative share of streams
+type serverTarget struct {
+	url    string
+	weight int
+}
 
-			// Skip ping messages
-			if data == "ping" {
+// parseTargets reads the targets from -servers-file (one URL [WEIGHT] per
+// line, # comments) when set, else from -server (URL[=WEIGHT],...).
+// Weights default to 1.
+func parseTargets(list, file string) ([]serverTarget, error) {
+	var entries [][2]string // {url, weight}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read servers file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Fields(line)
+			switch len(fields) {
+			case 0:
+			case 1:
+				entries = append(entries, [2]string{fields[0], ""})
+			case 2:
+				entries = append(entries, [2]string{fields[0], fields[1]})
+			default:
+				return nil, fmt.Errorf("invalid servers file line %q (want URL [WEIGHT])", strings.TrimSpace(line))
+			}
+		}
+	} else {
+		for _, item := range strings.Split(list, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
 				continue
 			}
+			entry := [2]string{item, ""}
+			if i := strings.LastIndex(item, "="); i > 0 {
+				entry = [2]string{item[:i], item[i+1:]}
+			}
+			entries = append(entries, entry)
+		}
+	}
 
-			// Parse notification
-			var event NotificationEvent
-			if err := json.Unmarshal([]byte(data), &event); err != nil {
-				c.logger.Warn("failed to parse notification",
-					zap.String("user_id", c.userID),
-					zap.String("data", data),
-					zap.Error(err),
-				)
-				c.metrics.RecordError("parse_error")
-				continue
+	targets := make([]serverTarget, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		target := serverTarget{url: strings.TrimRight(entry[0], "/"), weight: 1}
+		if entry[1] != "" {
+			weight, err := strconv.Atoi(entry[1])
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight %q for %s (want a positive integer)", entry[1], target.url)
 			}
+			target.weight = weight
+		}
+		if u, err := neturl.Parse(target.url); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid server URL %q", target.url)
+		}
+		if seen[target.url] {
+			return nil, fmt.Errorf("duplicate server URL %q", target.url)
+		}
+		seen[target.url] = true
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no server URL given")
+	}
+	return targets, nil
+}
 
-			// Calculate end-to-end latency (event creation to client receipt)
-			event.ReceivedAt = time.Now()
-			latency := event.ReceivedAt.Sub(event.EventTimestamp)
+// weightedRoundRobin hands out targets in proportion to their weights,
+// interleaved (smooth weighted round-robin) so every target ramps up at once
+type weightedRoundRobin struct {
+	targets []serverTarget
+	current []int
+	total   int
+}
 
-			c.metrics.RecordNotification(c.userID, latency)
+func newWeightedRoundRobin(targets []serverTarget) *weightedRoundRobin {
+	w := &weightedRoundRobin{targets: targets, current: make([]int, len(targets))}
+	for _, target := range targets {
+		w.total += target.weight
+	}
+	return w
+}
 
-			c.logger.Debug("notification received",
-				zap.String("user_id", c.userID),
-				zap.String("notification_id", event.NotificationID),
-				zap.Duration("latency", latency),
-			)
+// Next returns the index of the next target
+func (w *weightedRoundRobin) Next() int {
+	best := 0
+	for i, target := range w.targets {
+		w.current[i] += target.weight
+		if w.current[i] > w.current[best] {
+			best = i
 		}
 	}
+	w.current[best] -= w.total
+	return best
 }
 
-func (c *SSEClient) Stop() {
-	close(c.stopChan)
-	c.wg.Wait()
-}
+const usage = `sse-bench - open SSE streams against notification-service and measure delivery
+
+Usage:
+  sse-bench [flags]                              Run the benchmark from this host
+  sse-bench agent [-listen ADDR]                 Run benchmarks on behalf of a coordinator
+  sse-bench coordinator -agents HOST:PORT,... [-users N] [-report D] [-- flags]
+                                                 Split the users over agents and merge their results
+
+Flags:
+`
+
+// usageError is an invalid flag value: exit status 2, as for flag errors
+type usageError struct{ error }
+
+// errViolations fails a -fail-on-violations run
+var errViolations = errors.New("protocol violations detected")
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "coordinator":
+			runCoordinator(os.Args[2:])
+			return
+		}
+	}
+
+	err := runBench(context.Background(), os.Args[1:], nil)
+	var invalid usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.As(err, &invalid):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	case errors.Is(err, errViolations), errors.Is(err, errSLA):
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "sse-bench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runBench runs one benchmark with the given flags until -duration, a
+// signal or ctx ends it. onStart, when set, receives the run's metrics
+// before the first client connects.
+func runBench(parent context.Context, args []string, onStart func(*BenchmarkMetrics)) error {
+	fs := flag.NewFlagSet("sse-bench", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
 	var (
-		serverURL       = flag.String("server", "http://localhost:8080", "Notification service URL")
-		numUsers        = flag.Int("users", 1000, "Number of concurrent users")
-		userPrefix      = flag.String("prefix", "user_", "User ID prefix")
-		duration        = flag.Duration("duration", 5*time.Minute, "Benchmark duration (0 for infinite)")
-		reportInterval  = flag.Duration("report", 10*time.Second, "Report interval")
-		reconnect       = flag.Bool("reconnect", true, "Auto-reconnect on disconnect")
-		detailedReports = flag.Bool("detailed", false, "Show detailed reports")
-		rampUp          = flag.Duration("ramp-up", 10*time.Second, "Ramp-up duration for connections")
-		logLevel        = flag.String("log", "info", "Log level (debug, info, warn, error)")
+		serverList      = fs.String("server", "http://localhost:8080", "Notification service URL, or several as URL[=WEIGHT],... to spread streams over a cluster by weight")
+		serversFile     = fs.String("servers-file", "", "Read the -server targets from this file instead, one URL [WEIGHT] per line")
+		numUsers        = fs.Int("users", 1000, "Number of concurrent users")
+		userPrefix      = fs.String("prefix", "user_", "User ID prefix")
+		usersFile       = fs.String("users-file", "", "Connect as user IDs (e.g. UUIDs) loaded from this file, one per line, instead of <prefix>1..<prefix>N; -users caps how many")
+		userOffset      = fs.Int("user-offset", 0, "Skip the first N users of the population, so agents of a distributed run each take their own range")
+		duration        = fs.Duration("duration", 5*time.Minute, "Benchmark duration (0 for infinite)")
+		reportInterval  = fs.Duration("report", 10*time.Second, "Report interval")
+		reconnect       = fs.Bool("reconnect", true, "Auto-reconnect on disconnect")
+		detailedReports = fs.Bool("detailed", false, "Show detailed reports")
+		rampUp          = fs.Duration("ramp-up", 10*time.Second, "Ramp-up duration for connections")
+		logLevel        = fs.String("log", "info", "Log level (debug, info, warn, error)")
+		authKey         = fs.String("auth-key", "", "HS256 signing key to mint per-user test tokens (empty disables auth)")
+		insecureSkip    = fs.Bool("insecure-skip-verify", false, "Skip TLS certificate verification (self-signed test certs)")
+		caFile          = fs.String("ca", "", "PEM CA bundle used to verify the server certificate")
+		numTenants      = fs.Int("tenants", 1, "Spread users over N tenants (user i → tenant_<i mod N>, matching producers' NUM_TENANTS)")
+		failOnViolation = fs.Bool("fail-on-violations", false, "Exit non-zero if any protocol violation was seen")
+		connsPerUser    = fs.Int("connections-per-user", 1, "Simultaneous streams per user (e.g. 2 = phone + laptop) to exercise fan-out")
+		compression     = fs.String("compression", "", "Request a compressed stream via Accept-Encoding (gzip, br); the server must enable it with SSE_COMPRESSION")
+		httpVersion     = fs.String("http", "", "Force the HTTP version: 1.1, or 2 (h2c on http://, needs server HTTP2_MODE=h2c); default negotiates")
+		heartbeat       = fs.Duration("expected-heartbeat", 30*time.Second, "Server heartbeat interval (SSE_HEARTBEAT_INTERVAL); streams silent for longer are flagged and reconnected")
+		metricsPort     = fs.Int("metrics-port", 0, "Serve client-side metrics (latency histogram, reconnects, errors) for Prometheus on :PORT/metrics (0 disables)")
+		soakInterval    = fs.Duration("soak-interval", 0, "Sample the bench's goroutines, heap and FDs this often and flag steady growth (0 = SOAK_INTERVAL, unset disables)")
+		silentClients   = fs.Float64("silent-clients", 0, "Fraction of streams that never echo heartbeats, so a server with SSE_LIVENESS_TIMEOUT treats them as offline")
+		reconnectJitter = fs.Duration("reconnect-jitter", 0, "Random extra wait, up to this, before every reconnect")
+		ignoreHints     = fs.Bool("ignore-retry-after", false, "Back off exponentially from rejected streams instead of waiting the server's Retry-After hint (to compare herd pacing)")
+		maxReconnects   = fs.Int("max-concurrent-reconnects", 0, "Reconnects allowed in flight (dialing until established) across all clients (0 = unlimited)")
+		herdTest        = fs.Duration("herd-test", 0, "After ramp-up plus this long, drop every stream at once and measure time to full reconnect and missed notifications (0 disables)")
+		maxIdleConns    = fs.Int("max-idle-conns", 0, "Idle connections kept across all hosts (0 = unlimited)")
+		maxIdlePerHost  = fs.Int("max-idle-conns-per-host", 1000, "Idle connections kept per host, reused by reconnects and heartbeat echoes")
+		maxConnsPerHost = fs.Int("max-conns-per-host", 0, "Connections per host, dialing + active + idle (0 = unlimited); on HTTP/1.1 each stream holds one")
+		dialTimeout     = fs.Duration("dial-timeout", 30*time.Second, "TCP connect timeout")
+		keepAlive       = fs.Duration("tcp-keepalive", 30*time.Second, "TCP keep-alive probe interval (negative disables)")
+		tlsTimeout      = fs.Duration("tls-handshake-timeout", 10*time.Second, "TLS handshake timeout")
+		headerTimeout   = fs.Duration("response-header-timeout", 0, "Max wait for response headers once a request is sent (0 = no limit)")
+		noCompression   = fs.Bool("disable-compression", false, "Stop the transport requesting gzip on non-stream requests (streams follow -compression)")
+		filterChurn     = fs.Duration("filter-churn", 0, "Replace each stream's event-type/priority filter with a random one this often, without reconnecting, to load test subscription churn (0 disables)")
+		topicList       = fs.String("topics", "", "Subscribe every stream to these comma-separated topics (?topics=), e.g. job-alerts:golang,job-alerts:python, to benchmark topic fan-out")
+		slaSpec         = fs.String("sla", "", "Exit non-zero unless each priority meets its latency objective, e.g. HIGH=1s@p99,MEDIUM=5s@p95,LOW=30s (percentile defaults to the service's)")
 	)
 
-	flag.Parse()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err}
+	}
+
+	if *connsPerUser < 1 {
+		return usageError{errors.New("-connections-per-user must be at least 1")}
+	}
+	if *userOffset < 0 {
+		return usageError{errors.New("-user-offset must not be negative")}
+	}
+	targets, err := parseTargets(*serverList, *serversFile)
+	if err != nil {
+		return usageError{err}
+	}
+	slaTargets, err := parseSLA(*slaSpec)
+	if err != nil {
+		return usageError{err}
+	}
+	var topics []string
+	for _, topic := range strings.Split(*topicList, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	serverURLs := make([]string, len(targets))
+	for i, target := range targets {
+		serverURLs[i] = target.url
+	}
 
 	// Setup logger
 	var logger *zap.Logger
-	var err error
 	switch *logLevel {
 	case "debug":
 		logger, err = zap.NewDevelopment()
@@ -437,36 +1256,156 @@ func main() {
 		logger, err = zap.NewProduction()
 	}
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create logger: %w", err)
 	}
 	defer logger.Sync()
 
 	logger.Info("starting SSE benchmark",
-		zap.String("server", *serverURL),
+		zap.Strings("servers", serverURLs),
 		zap.Int("users", *numUsers),
+		zap.Int("user_offset", *userOffset),
+		zap.Int("connections_per_user", *connsPerUser),
 		zap.Duration("duration", *duration),
 		zap.Duration("ramp_up", *rampUp),
 		zap.Bool("reconnect", *reconnect),
 	)
 
-	metrics := NewBenchmarkMetrics()
+	transport := transportOptions{
+		insecureSkipVerify:    *insecureSkip,
+		caFile:                *caFile,
+		httpVersion:           *httpVersion,
+		maxIdleConns:          *maxIdleConns,
+		maxIdleConnsPerHost:   *maxIdlePerHost,
+		maxConnsPerHost:       *maxConnsPerHost,
+		dialTimeout:           *dialTimeout,
+		keepAlive:             *keepAlive,
+		tlsHandshakeTimeout:   *tlsTimeout,
+		responseHeaderTimeout: *headerTimeout,
+		disableCompression:    *noCompression,
+	}
+	httpClient, err := newHTTPClient(transport)
+	if err != nil {
+		return fmt.Errorf("failed to configure HTTP client: %w", err)
+	}
+	logger.Info("http transport",
+		zap.Int("max_idle_conns", transport.maxIdleConns),
+		zap.Int("max_idle_conns_per_host", transport.maxIdleConnsPerHost),
+		zap.Int("max_conns_per_host", transport.maxConnsPerHost),
+		zap.Duration("dial_timeout", transport.dialTimeout),
+		zap.Duration("tls_handshake_timeout", transport.tlsHandshakeTimeout),
+		zap.Duration("response_header_timeout", transport.responseHeaderTimeout),
+		zap.Bool("disable_compression", transport.disableCompression))
+
+	users, err := generator.NewPopulation(*usersFile, *userPrefix, *userOffset+*numUsers)
+	if err != nil {
+		return fmt.Errorf("failed to load user population: %w", err)
+	}
+	users = users.Truncate(*userOffset + *numUsers)
+
+	if users.Sequential() && *userPrefix != startup.ProducerUserPrefix {
+		logger.Warn("user prefix does not match what producers emit; clients may never receive notifications",
+			zap.String("prefix", *userPrefix),
+			zap.String("producer_prefix", startup.ProducerUserPrefix))
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	metrics := NewBenchmarkMetrics(*connsPerUser)
+
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
+	soakConfig, err := soak.ConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid soak config: %w", err)
+	}
+	if *soakInterval > 0 {
+		soakConfig.Interval = *soakInterval
+	}
+	soak.New("sse-bench", soakConfig, logger).Start(ctx)
+
+	if *metricsPort > 0 {
+		serveMetrics(ctx, *metricsPort, metrics, logger)
+	}
+
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	var reconnectSlots chan struct{}
+	if *maxReconnects > 0 {
+		reconnectSlots = make(chan struct{}, *maxReconnects)
+	}
+
+	// Create clients, one per stream; a user's streams share tenant and
+	// token, each stream picks its own target
+	clients := make([]*SSEClient, 0, max(users.Len()-*userOffset, 0)**connsPerUser)
+	picker := newWeightedRoundRobin(targets)
+	streamsPerTarget := make([]int, len(targets))
+	for k := *userOffset + 1; k <= users.Len(); k++ {
+		userID := users.UserID(k)
+		tenantID := generator.TenantFor(k, *numTenants)
+
+		var token string
+		if *authKey != "" {
+			// Valid for the whole run plus ramp-up and reconnect slack
+			ttl := *duration + *rampUp + time.Hour
+			if *duration == 0 {
+				ttl = 24 * time.Hour
+			}
+			token, err = auth.SignForTenant(tenantID, userID, ttl, []byte(*authKey))
+			if err != nil {
+				return fmt.Errorf("failed to mint token for %s: %w", userID, err)
+			}
+		}
+
+		for n := 0; n < *connsPerUser; n++ {
+			target := picker.Next()
+			streamsPerTarget[target]++
+			connID := userID
+			if *connsPerUser > 1 {
+				connID = fmt.Sprintf("%s#%d", userID, n)
+			}
+			// Spread silent streams evenly over the population
+			i := float64(len(clients))
+			stream := NewSSEClient(client.Config{
+				ServerURL:        targets[target].url,
+				UserID:           userID,
+				TenantID:         tenantID,
+				Token:            token,
+				HTTPClient:       httpClient,
+				Compression:      *compression,
+				Reconnect:        *reconnect,
+				Jitter:           *reconnectJitter,
+				IgnoreRetryAfter: *ignoreHints,
+				PingTimeout:      client.PingTimeoutFor(*heartbeat),
+				Silent:           int((i+1)**silentClients) > int(i**silentClients),
+				ReconnectSlots:   reconnectSlots,
+				Topics:           topics,
+			}, connID, metrics, logger)
+			stream.filterChurn = *filterChurn
+			clients = append(clients, stream)
+		}
+	}
 
-	// Create clients
-	clients := make([]*SSEClient, *numUsers)
-	for i := 0; i < *numUsers; i++ {
-		userID := fmt.Sprintf("%s%d", *userPrefix, i)
-		clients[i] = NewSSEClient(userID, *serverURL, metrics, logger, *reconnect)
+	if len(targets) > 1 {
+		for i, target := range targets {
+			metrics.AddTarget(target.url, target.weight, streamsPerTarget[i])
+			logger.Info("target",
+				zap.String("server", target.url),
+				zap.Int("weight", target.weight),
+				zap.Int("streams", streamsPerTarget[i]))
+		}
+	}
+
+	if len(clients) == 0 {
+		return usageError{fmt.Errorf("no users after -user-offset %d (population has %d)", *userOffset, users.Len())}
+	}
+	if onStart != nil {
+		onStart(metrics)
 	}
 
 	// Start clients with ramp-up
-	rampUpDelay := *rampUp / time.Duration(*numUsers)
+	rampUpDelay := *rampUp / time.Duration(len(clients))
 	logger.Info("ramping up connections",
 		zap.Duration("delay_per_connection", rampUpDelay),
 		zap.Duration("total_ramp_up", *rampUp),
@@ -474,12 +1413,31 @@ func main() {
 
 	for i, client := range clients {
 		client.Connect(ctx)
-		if i < *numUsers-1 {
-			time.Sleep(rampUpDelay)
+		if i < len(clients)-1 {
+			select {
+			case <-time.After(rampUpDelay):
+			case <-ctx.Done():
+			}
 		}
 	}
 
-	logger.Info("all connections initiated", zap.Int("count", *numUsers))
+	logger.Info("all connections initiated", zap.Int("count", len(clients)))
+
+	herdDone := make(chan struct{})
+	if *herdTest > 0 {
+		// /admin/stats needs an admin token when the server has auth on
+		var adminToken string
+		if *authKey != "" {
+			adminToken, err = auth.SignService(auth.ScopeAdmin, "sse-bench", *herdTest+time.Hour, []byte(*authKey))
+			if err != nil {
+				return fmt.Errorf("failed to mint admin token: %w", err)
+			}
+		}
+		go func() {
+			defer close(herdDone)
+			runHerdTest(ctx, clients, metrics, httpClient, serverURLs, adminToken, *herdTest, logger)
+		}()
+	}
 
 	// Periodic reporting
 	reportTicker := time.NewTicker(*reportInterval)
@@ -505,6 +1463,10 @@ func main() {
 			logger.Info("received signal, shutting down...", zap.String("signal", sig.String()))
 			cancel()
 			goto cleanup
+
+		case <-ctx.Done():
+			logger.Info("run stopped, shutting down...")
+			goto cleanup
 		}
 	}
 
@@ -514,10 +1476,24 @@ cleanup:
 	for _, client := range clients {
 		client.Stop()
 	}
+	if *herdTest > 0 {
+		<-herdDone
+	}
 
 	// Final report
 	logger.Info("=== FINAL REPORT ===")
 	metrics.PrintReport(logger, true)
+	slaMet := reportSLA(logger, "=== SLA (Event → Client) ===", metrics.CheckSLA(slaTargets))
+
+	if violations := metrics.TotalViolations(); violations > 0 && *failOnViolation {
+		logger.Error("benchmark failed: protocol violations detected", zap.Int64("violations", violations))
+		return errViolations
+	}
+	if !slaMet {
+		logger.Error("benchmark failed: sla missed")
+		return errSLA
+	}
 
 	logger.Info("benchmark completed")
+	return nil
 }
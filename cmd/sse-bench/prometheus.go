@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// Client-side series are named sse_bench_* so they sit next to the server's
+// notification_* series on one dashboard. The registry is the bench's own:
+// the server metrics the bench links in never show up as zeros.
+
+// newLatencyHistogram is observed per notification; the buckets match the
+// server's canary latency so both sides can be compared bucket by bucket
+func newLatencyHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "sse_bench",
+		Name:      "notification_latency_seconds",
+		Help:      "Event timestamp to client receipt, by -server target and delivering instance",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"server", "instance_id"})
+}
+
+// newStageHistogram is observed per pipeline stage of notifications that
+// carried stage timestamps
+func newStageHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "sse_bench",
+		Name:      "stage_latency_seconds",
+		Help:      "Notification latency by pipeline stage, from the server's stage timestamps",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"stage"})
+}
+
+// benchCollector reads the run's counters at scrape time
+type benchCollector struct {
+	m *BenchmarkMetrics
+
+	activeConnections *prometheus.Desc
+	connections       *prometheus.Desc
+	failedConnections *prometheus.Desc
+	reconnections     *prometheus.Desc
+	serverReconnects  *prometheus.Desc
+	notifications     *prometheus.Desc
+	errors            *prometheus.Desc
+	violations        *prometheus.Desc
+	rejections        *prometheus.Desc
+	wireBytes         *prometheus.Desc
+	tcpConnections    *prometheus.Desc
+	filterUpdates     *prometheus.Desc
+}
+
+func newBenchCollector(m *BenchmarkMetrics) *benchCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc("sse_bench_"+name, help, labels, nil)
+	}
+	return &benchCollector{
+		m:                 m,
+		activeConnections: desc("active_connections", "Streams currently open"),
+		connections:       desc("connections_total", "Streams established"),
+		failedConnections: desc("failed_connections_total", "Streams given up on (retries exhausted, or -reconnect=false)"),
+		reconnections:     desc("reconnections_total", "Reconnect attempts, for any reason"),
+		serverReconnects:  desc("server_reconnects_total", "Reconnects the server asked for with a reconnect event"),
+		notifications:     desc("notifications_received_total", "Notifications received"),
+		errors:            desc("stream_errors_total", "Streams that ended in an error"),
+		violations:        desc("protocol_violations_total", "SSE contract violations, by type", "type"),
+		rejections:        desc("rejections_total", "Streams refused with a retry hint, by reason", "reason"),
+		wireBytes:         desc("wire_bytes_total", "Stream bytes read off the wire"),
+		tcpConnections:    desc("tcp_connections_total", "TCP connections opened"),
+		filterUpdates:     desc("filter_updates_total", "Mid-stream filter updates sent with -filter-churn, by result", "result"),
+	}
+}
+
+func (c *benchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.activeConnections
+	ch <- c.connections
+	ch <- c.failedConnections
+	ch <- c.reconnections
+	ch <- c.serverReconnects
+	ch <- c.notifications
+	ch <- c.errors
+	ch <- c.violations
+	ch <- c.rejections
+	ch <- c.wireBytes
+	ch <- c.tcpConnections
+	ch <- c.filterUpdates
+	c.m.promLatency.Describe(ch)
+	c.m.promStages.Describe(ch)
+}
+
+func (c *benchCollector) Collect(ch chan<- prometheus.Metric) {
+	m := c.m
+	counter := func(desc *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.activeConnections, prometheus.GaugeValue, float64(atomic.LoadInt64(&m.activeConnections)))
+	counter(c.connections, atomic.LoadInt64(&m.totalConnections))
+	counter(c.failedConnections, atomic.LoadInt64(&m.failedConnections))
+	counter(c.reconnections, atomic.LoadInt64(&m.reconnections))
+	counter(c.serverReconnects, atomic.LoadInt64(&m.serverReconnects))
+	counter(c.notifications, atomic.LoadInt64(&m.notificationsReceived))
+	counter(c.wireBytes, atomic.LoadInt64(&m.wireBytes))
+	counter(c.tcpConnections, atomic.LoadInt64(&m.tcpConnections))
+	counter(c.filterUpdates, atomic.LoadInt64(&m.filterUpdates), "ok")
+	counter(c.filterUpdates, atomic.LoadInt64(&m.filterUpdateErrors), "failed")
+
+	// Error types embed the error text, so they are summed rather than labeled
+	m.mu.RLock()
+	var streamErrors int64
+	for _, count := range m.errorsByType {
+		streamErrors += count
+	}
+	counter(c.errors, streamErrors)
+	for kind, count := range m.violationsByType {
+		counter(c.violations, count, kind)
+	}
+	for reason, record := range m.rejectionsByReason {
+		counter(c.rejections, record.count, reason)
+	}
+	m.mu.RUnlock()
+
+	m.promLatency.Collect(ch)
+	m.promStages.Collect(ch)
+}
+
+// serveMetrics serves the run's metrics on /metrics at port until ctx ends
+func serveMetrics(ctx context.Context, port int, m *BenchmarkMetrics, logger *zap.Logger) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		newBenchCollector(m),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		logger.Info("serving client metrics", zap.String("addr", server.Addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server failed", zap.Error(err))
+		}
+	}()
+}
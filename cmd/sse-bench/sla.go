@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errSLA fails a run that missed an -sla objective
+var errSLA = errors.New("sla missed")
+
+// defaultSLAPercentiles match the service's sla.*percentile defaults
+var defaultSLAPercentiles = map[string]float64{"HIGH": 99, "MEDIUM": 95, "LOW": 90}
+
+// slaTarget is one priority's objective: Percentile% of its notifications
+// delivered within Target, event → client
+type slaTarget struct {
+	Priority   string
+	Target     time.Duration
+	Percentile float64
+}
+
+// slaResult is a target checked against a run's latencies
+type slaResult struct {
+	slaTarget
+	Count        int64
+	WithinTarget int64
+	Measured     time.Duration // Latency at Percentile
+}
+
+// Met is true when enough notifications arrived within target; a priority
+// that saw no notifications is not judged
+func (r slaResult) Met() bool {
+	return r.Count == 0 || float64(r.WithinTarget)*100 >= r.Percentile*float64(r.Count)
+}
+
+// parseSLA parses -sla: PRIORITY=TARGET[@pPERCENTILE],... e.g.
+// "HIGH=1s@p99,MEDIUM=5s,LOW=30s"; an omitted percentile takes the service's
+// default for the priority
+func parseSLA(spec string) ([]slaTarget, error) {
+	var targets []slaTarget
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		priority, rest, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid -sla entry %q (want PRIORITY=TARGET[@pN])", item)
+		}
+		priority = strings.ToUpper(strings.TrimSpace(priority))
+		percentile, known := defaultSLAPercentiles[priority]
+		if !known {
+			return nil, fmt.Errorf("invalid -sla priority %q (want HIGH, MEDIUM or LOW)", priority)
+		}
+
+		targetStr, percentileStr, hasPercentile := strings.Cut(rest, "@")
+		target, err := time.ParseDuration(strings.TrimSpace(targetStr))
+		if err != nil || target <= 0 {
+			return nil, fmt.Errorf("invalid -sla target %q for %s", targetStr, priority)
+		}
+		if hasPercentile {
+			percentile, err = strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(percentileStr), "p"), 64)
+			if err != nil || percentile <= 0 || percentile > 100 {
+				return nil, fmt.Errorf("invalid -sla percentile %q for %s", percentileStr, priority)
+			}
+		}
+		targets = append(targets, slaTarget{Priority: priority, Target: target, Percentile: percentile})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return priorityRank(targets[i].Priority) < priorityRank(targets[j].Priority)
+	})
+	return targets, nil
+}
+
+func priorityRank(priority string) int {
+	switch priority {
+	case "HIGH":
+		return 0
+	case "MEDIUM":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// check judges the target against exact latencies
+func (t slaTarget) check(latencies []time.Duration) slaResult {
+	result := slaResult{slaTarget: t, Count: int64(len(latencies))}
+	if len(latencies) == 0 {
+		return result
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	result.WithinTarget = int64(sort.Search(len(sorted), func(i int) bool { return sorted[i] > t.Target }))
+	result.Measured = sorted[min(int(float64(len(sorted))*t.Percentile/100), len(sorted)-1)]
+	return result
+}
+
+// checkHistogram judges the target against merged buckets, within their 5%
+func (t slaTarget) checkHistogram(h latencyHistogram) slaResult {
+	result := slaResult{slaTarget: t, Count: h.count()}
+	if result.Count == 0 {
+		return result
+	}
+	for bucket, count := range h {
+		if bucketUpper(bucket) <= t.Target {
+			result.WithinTarget += count
+		}
+	}
+	result.Measured = h.quantile(min(t.Percentile/100, 0.9999))
+	return result
+}
+
+// reportSLA logs each result and returns whether all were met
+func reportSLA(logger *zap.Logger, title string, results []slaResult) bool {
+	if len(results) == 0 {
+		return true
+	}
+
+	met := true
+	logger.Info(title)
+	for _, r := range results {
+		fields := []zap.Field{
+			zap.String("priority", r.Priority),
+			zap.Duration("target", r.Target),
+			zap.Float64("objective_percent", r.Percentile),
+			zap.Int64("notifications", r.Count),
+		}
+		if r.Count == 0 {
+			logger.Info("sla not evaluated: no notifications", fields...)
+			continue
+		}
+		fields = append(fields,
+			zap.Float64("within_target_percent", float64(r.WithinTarget)/float64(r.Count)*100),
+			zap.Duration(fmt.Sprintf("p%g", r.Percentile), r.Measured),
+			zap.Bool("met", r.Met()))
+		if r.Met() {
+			logger.Info("sla met", fields...)
+		} else {
+			met = false
+			logger.Error("sla missed", fields...)
+		}
+	}
+	return met
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// pipelineStage is one hop of a notification's path, between two of the
+// stage timestamps servers with SSE_STAGE_TIMESTAMPS send
+type pipelineStage struct {
+	name string
+	span string
+}
+
+// pipelineStages in pipeline order
+var pipelineStages = []pipelineStage{
+	{"produce", "producer → consumer"},
+	{"persist", "consumer → row written"},
+	{"pending", "row written → claimed"},
+	{"deliver", "claimed → written to the stream"},
+	{"network", "written → received"},
+}
+
+// stageDurations splits a notification's latency by stage. Stages missing a
+// boundary are left out; stamps come from different clocks, so slightly
+// negative stages are clamped to zero.
+func stageDurations(stages *models.StageTimestamps, receivedAt time.Time) map[string]time.Duration {
+	bounds := []time.Time{stages.ProducedAt, stages.ConsumedAt, stages.PersistedAt, stages.ClaimedAt, stages.DeliveredAt, receivedAt}
+	durations := make(map[string]time.Duration, len(pipelineStages))
+	for i, stage := range pipelineStages {
+		from, to := bounds[i], bounds[i+1]
+		if from.IsZero() || to.IsZero() {
+			continue
+		}
+		durations[stage.name] = max(to.Sub(from), 0)
+	}
+	return durations
+}
+
+// RecordStages records the stage latencies of a notification that carried
+// stage timestamps
+func (m *BenchmarkMetrics) RecordStages(stages *models.StageTimestamps, receivedAt time.Time) {
+	if stages == nil {
+		return
+	}
+	durations := stageDurations(stages, receivedAt)
+
+	m.mu.Lock()
+	for stage, d := range durations {
+		m.latenciesByStage[stage] = append(m.latenciesByStage[stage], d)
+	}
+	m.mu.Unlock()
+
+	for stage, d := range durations {
+		m.promStages.WithLabelValues(stage).Observe(d.Seconds())
+	}
+}
+
+// printStageReport logs stage percentiles in pipeline order. Caller holds m.mu.
+func (m *BenchmarkMetrics) printStageReport(logger *zap.Logger) {
+	if len(m.latenciesByStage) == 0 {
+		return
+	}
+
+	logger.Info("=== Latency by Stage ===")
+	for _, stage := range pipelineStages {
+		latencies := m.latenciesByStage[stage.name]
+		if len(latencies) == 0 {
+			continue
+		}
+		stats := latencyStatsOf(latencies)
+		logger.Info("stage",
+			zap.String("stage", stage.name),
+			zap.String("span", stage.span),
+			zap.Int64("count", stats.Count),
+			zap.Duration("p50", stats.P50),
+			zap.Duration("p95", stats.P95),
+			zap.Duration("p99", stats.P99),
+			zap.Duration("max", stats.Max))
+	}
+}
+
+// printMergedStages logs merged stage percentiles in pipeline order
+func printMergedStages(logger *zap.Logger, stages map[string]latencyHistogram) {
+	if len(stages) == 0 {
+		return
+	}
+
+	logger.Info("=== Merged Latency by Stage (±5%) ===")
+	for _, stage := range pipelineStages {
+		h := stages[stage.name]
+		if h.count() == 0 {
+			continue
+		}
+		logger.Info("stage",
+			zap.String("stage", stage.name),
+			zap.String("span", stage.span),
+			zap.Int64("count", h.count()),
+			zap.Duration("p50", h.quantile(0.50)),
+			zap.Duration("p95", h.quantile(0.95)),
+			zap.Duration("p99", h.quantile(0.99)))
+	}
+}
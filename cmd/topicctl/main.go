@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/kafkaadmin"
+)
+
+const usage = `topicctl - create and scale the Kafka topics of the notification system
+
+Usage:
+  topicctl [-brokers LIST] <command> [flags]
+
+Commands:
+  list [TOPIC...]            Show partition counts and replication (all topics by default)
+  ensure [-partitions N] [-replication N] TOPIC[:PARTITIONS[:REPLICATION]]...
+                             Create missing topics and add partitions to smaller ones
+
+Brokers default to $KAFKA_BROKERS or localhost:9092. Adding partitions
+changes which partition a user's events hash to, so scale before a run.
+`
+
+func main() {
+	defaultBrokers := os.Getenv("KAFKA_BROKERS")
+	if defaultBrokers == "" {
+		defaultBrokers = "localhost:9092"
+	}
+
+	brokers := flag.String("brokers", defaultBrokers, "Comma-separated Kafka brokers")
+	timeout := flag.Duration("timeout", time.Minute, "Overall timeout")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger, _ := zap.NewDevelopment()
+	defer logger.Sync()
+
+	admin := kafkaadmin.New(strings.Split(*brokers, ","), logger)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	var err error
+	switch cmd {
+	case "list":
+		var topics []kafkaadmin.TopicInfo
+		if topics, err = admin.Describe(ctx, args...); err == nil {
+			err = printJSON(topics)
+		}
+	case "ensure":
+		fs := flag.NewFlagSet("ensure", flag.ExitOnError)
+		partitions := fs.Int("partitions", 10, "Partitions for topics without an explicit count")
+		replication := fs.Int("replication", 1, "Replication factor for topics without an explicit one")
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "usage: topicctl ensure [-partitions N] [-replication N] TOPIC[:PARTITIONS[:REPLICATION]]...")
+			os.Exit(2)
+		}
+
+		var specs []kafkaadmin.TopicSpec
+		if specs, err = kafkaadmin.ParseTopicSpecs(strings.Join(fs.Args(), ","), *partitions, *replication); err != nil {
+			break
+		}
+		if err = admin.Ensure(ctx, specs...); err != nil {
+			break
+		}
+		names := make([]string, len(specs))
+		for i, spec := range specs {
+			names[i] = spec.Name
+		}
+		var topics []kafkaadmin.TopicInfo
+		if topics, err = admin.Describe(ctx, names...); err == nil {
+			err = printJSON(topics)
+		}
+	case "help", "-h", "--help":
+		flag.Usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "topicctl %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+// printJSON pretty-prints v to stdout
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// UserIDKey is the gin context key holding the authenticated user ID
+const UserIDKey = "auth_user_id"
+
+// TenantIDKey is the gin context key holding the request's resolved tenant,
+// set by the tenant middleware before authentication runs
+const TenantIDKey = "tenant_id"
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported token algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrMissingSubject   = errors.New("token has no subject")
+)
+
+// Scopes of service tokens, held by operators and producers rather than
+// users. User tokens carry none.
+const (
+	ScopeAdmin   = "admin"   // /admin endpoints
+	ScopePublish = "publish" // POST /notifications(/batch), for any tenant
+)
+
+// Claims are the JWT claims understood by the service
+type Claims struct {
+	Subject   string `json:"sub"`             // user_id
+	TenantID  string `json:"tid,omitempty"`   // Empty means the default tenant
+	Scope     string `json:"scope,omitempty"` // Service tokens only, e.g. ScopeAdmin
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Sign mints an HS256 JWT for the given default-tenant user valid for ttl
+func Sign(userID string, ttl time.Duration, key []byte) (string, error) {
+	return SignForTenant("", userID, ttl, key)
+}
+
+// SignForTenant mints an HS256 JWT bound to a tenant's user
+func SignForTenant(tenantID, userID string, ttl time.Duration, key []byte) (string, error) {
+	return sign(Claims{Subject: userID, TenantID: tenantID}, ttl, key)
+}
+
+// sign stamps the claims with their issue and expiry times and signs them
+func sign(claims Claims, ttl time.Duration, key []byte) (string, error) {
+	now := time.Now()
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return signingInput + "." + signature(signingInput, key), nil
+}
+
+// SignService mints an HS256 service token with the given scope for
+// subject (the operator or producer holding it), valid for ttl
+func SignService(scope, subject string, ttl time.Duration, key []byte) (string, error) {
+	return sign(Claims{Subject: subject, Scope: scope}, ttl, key)
+}
+
+// Verify checks an HS256 JWT and returns its claims
+func Verify(token string, key []byte) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if header.Alg != "HS256" {
+		return nil, ErrUnsupportedAlg
+	}
+
+	expected := signature(parts[0]+"."+parts[1], key)
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidSignature
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if claims.ExpiresAt != 0 && time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	if claims.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+
+	return &claims, nil
+}
+
+func signature(signingInput string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Middleware authenticates requests with a Bearer token (or ?token= for
+// EventSource clients, which can't set headers) and stores the token's
+// subject under UserIDKey. A user_id in the query or path must match it,
+// and the token's tenant the request's (a token without one is only valid
+// for the default tenant).
+func Middleware(key []byte, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := verifyRequest(c, key, logger)
+		if !ok {
+			return
+		}
+		if claims.Scope != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "service token is not valid for user endpoints"})
+			return
+		}
+
+		for _, requested := range []string{c.Query("user_id"), c.Param("user_id")} {
+			if requested != "" && requested != claims.Subject {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token does not match user_id"})
+				return
+			}
+		}
+
+		if models.TenantOrDefault(claims.TenantID) != models.TenantOrDefault(c.GetString(TenantIDKey)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token does not match tenant"})
+			return
+		}
+
+		c.Set(UserIDKey, claims.Subject)
+		c.Next()
+	}
+}
+
+// RequireScope authenticates requests with a service token of the given
+// scope (see SignService) and stores its subject under UserIDKey. User
+// tokens are refused.
+func RequireScope(key []byte, scope string, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := verifyRequest(c, key, logger)
+		if !ok {
+			return
+		}
+		if claims.Scope != scope {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token lacks the " + scope + " scope"})
+			return
+		}
+
+		c.Set(UserIDKey, claims.Subject)
+		c.Next()
+	}
+}
+
+// verifyRequest reads the request's Bearer token (or ?token=) and verifies
+// it, answering 401 and returning false when it is missing or invalid
+func verifyRequest(c *gin.Context, key []byte, logger *zap.Logger) (*Claims, bool) {
+	token := c.Query("token")
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+		return nil, false
+	}
+
+	claims, err := Verify(token, key)
+	if err != nil {
+		logger.Debug("rejected token", zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMiddlewareTenant(t *testing.T) {
+	key := []byte("test-key")
+	for _, tc := range []struct {
+		name        string
+		tokenTenant string // tid claim; empty mints a token without one
+		tenant      string // Tenant the request resolved to
+		want        int
+	}{
+		{"same tenant", "acme", "acme", http.StatusOK},
+		{"other tenant", "acme", "globex", http.StatusForbidden},
+		{"bound token on default tenant", "acme", "default", http.StatusForbidden},
+		{"unbound token on default tenant", "", "default", http.StatusOK},
+		{"unbound token on other tenant", "", "acme", http.StatusForbidden},
+		{"default tenant token", "default", "default", http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/notifications/:user_id", func(c *gin.Context) {
+				c.Set(TenantIDKey, tc.tenant)
+			}, Middleware(key, zap.NewNop()), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			token, err := SignForTenant(tc.tokenTenant, "u1", time.Minute, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/notifications/u1", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	key := []byte("test-key")
+	adminToken, err := SignService(ScopeAdmin, "notifctl", time.Minute, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishToken, err := SignService(ScopePublish, "event-generator", time.Minute, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userToken, err := SignForTenant("", "u1", time.Minute, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/export", RequireScope(key, ScopeAdmin, zap.NewNop()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/notifications/:user_id", Middleware(key, zap.NewNop()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"admin token", "/admin/export", adminToken, http.StatusOK},
+		{"user token on admin endpoint", "/admin/export", userToken, http.StatusForbidden},
+		{"publish token on admin endpoint", "/admin/export", publishToken, http.StatusForbidden},
+		{"no token", "/admin/export", "", http.StatusUnauthorized},
+		{"admin token on user endpoint", "/notifications/u1", adminToken, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
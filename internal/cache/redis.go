@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// keyPrefix namespaces every key this cache writes
+const keyPrefix = "notif:user:"
+
+// RedisCache caches per-user read models in Redis. Each user has one hash
+// (one field per query shape, e.g. list limit) so a single DEL invalidates
+// everything cached for them.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewRedisCache connects to Redis and verifies it is reachable
+func NewRedisCache(addr, password string, db int, ttl time.Duration, logger *zap.Logger) (*RedisCache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	logger.Info("connected to redis",
+		zap.String("addr", addr),
+		zap.Int("db", db),
+		zap.Duration("ttl", ttl))
+
+	return &RedisCache{
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+	}, nil
+}
+
+func userKey(userKey string) string {
+	return keyPrefix + userKey
+}
+
+// GetNotifications returns the cached notification list for a user and
+// limit; found is false on a miss
+func (c *RedisCache) GetNotifications(ctx context.Context, key string, limit int) (notifications []map[string]interface{}, found bool, err error) {
+	data, err := c.client.HGet(ctx, userKey(key), "list:"+strconv.Itoa(limit)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cached notifications: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &notifications); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached notifications: %w", err)
+	}
+	return notifications, true, nil
+}
+
+// SetNotifications caches a user's notification list for the given limit
+func (c *RedisCache) SetNotifications(ctx context.Context, key string, limit int, notifications []map[string]interface{}) error {
+	data, err := json.Marshal(notifications)
+	if err != nil {
+		return fmt.Errorf("failed to encode notifications: %w", err)
+	}
+
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, userKey(key), "list:"+strconv.Itoa(limit), data)
+	pipe.Expire(ctx, userKey(key), c.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cache notifications: %w", err)
+	}
+	return nil
+}
+
+// Invalidate drops everything cached for the given users
+func (c *RedisCache) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = userKey(key)
+	}
+
+	if err := c.client.Del(ctx, redisKeys...).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %w", err)
+	}
+	return nil
+}
+
+// quotaKeyPrefix namespaces delivery quota counters
+const quotaKeyPrefix = "notif:quota:"
+
+// takeQuotaScript adds ARGV[1] to a window counter (expiring it after ARGV[3]
+// ms on first use) and hands back whatever exceeds the limit ARGV[2], so the
+// counter only ever holds what was granted. Returns the amount granted.
+var takeQuotaScript = redis.NewScript(`
+local n = tonumber(ARGV[1])
+local count = redis.call('INCRBY', KEYS[1], n)
+if count == n then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+local over = count - tonumber(ARGV[2])
+if over <= 0 then
+	return n
+end
+if over > n then
+	over = n
+end
+redis.call('DECRBY', KEYS[1], over)
+return n - over
+`)
+
+// TakeQuota atomically takes up to n units from the counter at key, which
+// allows limit units before it expires after window. Every instance sharing
+// this Redis shares the counter. Returns how many units were granted.
+func (c *RedisCache) TakeQuota(ctx context.Context, key string, n, limit int, window time.Duration) (int, error) {
+	granted, err := takeQuotaScript.Run(ctx, c.client, []string{quotaKeyPrefix + key}, n, limit, window.Milliseconds()).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take quota: %w", err)
+	}
+	return granted, nil
+}
+
+// snapshotKeyPrefix namespaces per-instance delivery snapshots
+const snapshotKeyPrefix = "notif:snapshot:"
+
+// snapshotTTL bounds how long a snapshot outlives its instance
+const snapshotTTL = 24 * time.Hour
+
+// SaveSnapshot stores an instance's delivery snapshot, replacing any earlier one
+func (c *RedisCache) SaveSnapshot(ctx context.Context, instanceID string, data []byte) error {
+	if err := c.client.Set(ctx, snapshotKeyPrefix+instanceID, data, snapshotTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot returns an instance's delivery snapshot, or nil if it has none
+func (c *RedisCache) LoadSnapshot(ctx context.Context, instanceID string) ([]byte, error) {
+	data, err := c.client.Get(ctx, snapshotKeyPrefix+instanceID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Close closes the Redis client
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,6 +15,17 @@ type Config struct {
 	Kafka               KafkaConfig
 	PostgreSQL          PostgreSQLConfig
 	PriorityDelays      PriorityDelaysConfig
+	Tracing             TracingConfig
+	SLO                 SLOConfig
+	SLA                 SLAConfig
+	IDGeneration        IDGenerationConfig
+	Canary              CanaryConfig
+	Redis               RedisConfig
+	Consumer            ConsumerConfig
+	Quota               QuotaConfig
+	Expiry              ExpiryConfig
+	Reprioritize        ReprioritizeConfig
+	Chaos               ChaosConfig
 }
 
 type NotificationServiceConfig struct {
@@ -21,7 +33,78 @@ type NotificationServiceConfig struct {
 	PprofPort               int
 	MaxSSEConnections       int
 	SSEHeartbeatInterval    time.Duration
+	SSEStaleTimeout         time.Duration // Idle connections are closed after this
+	SSELivenessTimeout      time.Duration // Connections whose client hasn't echoed a heartbeat for this long count as offline (0 disables echoes)
+	SSECleanupInterval      time.Duration // How often stale connections are swept
+	SSECompression          string        // Stream encodings offered, preferred first (e.g. "br,gzip"); empty disables
+	SSEFlushInterval        time.Duration // Coalesce notification frames for up to this long per connection (0 flushes every frame)
+	SSEFlushMaxFrames       int           // Buffered frames that force an early flush (default 16)
+	SSEBandwidthLimit       int           // Bytes per second per connection before LOW notifications are deferred (0 = unlimited)
+	SSERegions              string        // Simulated client regions, name:fraction:latency[:jitter[:loss]],... (empty disables)
+	SSERetry                time.Duration // SSE retry: hint sent when a stream opens (default 3s)
+	SSEReconnectSpread      time.Duration // Reconnect hints are spread over [hint, hint+spread] so clients don't return at once
+	SSEStageTimestamps      bool          // Send each notification's pipeline stage timestamps to clients
+	TemplatesFile           string        // YAML title/message templates per event type, over the built-in ones (empty uses only those)
+	LocalesDir              string        // Directory of per-locale template files (es.yaml, pt-BR.yaml), over the templates file
+	PreferencesCacheTTL     time.Duration // How long a user's preferences (locale) are cached for delivery (default 1m)
+	EventTypesRefresh       time.Duration // How often event type registrations are reloaded from the store (default 30s)
 	GracefulShutdownTimeout time.Duration
+
+	// Admission control for new SSE connections
+	ConnectionAcceptRate   float64
+	ConnectionAcceptBurst  int
+	ConnectionQueueTimeout time.Duration
+	MaxQueuedConnections   int
+
+	// JWT authentication for user-scoped endpoints
+	AuthEnabled    bool
+	AuthSigningKey string
+
+	// Fail-fast startup checks (topic exists, schema present, user population)
+	SkipStartupChecks  bool
+	ExpectedUserPrefix string // User ID prefix the bench will connect with (BENCH_USER_PREFIX)
+	ExpectedUsers      int    // Producer NUM_USERS, when known
+
+	// TLS for the HTTP/SSE server: static cert/key or autocert domains
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocertDomains  []string
+	TLSAutocertCacheDir string
+
+	// HTTP server connection handling. There are no read/write timeouts:
+	// SSE responses stay open indefinitely.
+	HTTPReadHeaderTimeout     time.Duration
+	HTTPIdleTimeout           time.Duration
+	HTTPMaxHeaderBytes        int           // 0 = Go default (1MB)
+	HTTP2Mode                 string        // "tls" (HTTP/2 negotiated over TLS only), "h2c" (also cleartext) or "off"
+	HTTP2MaxConcurrentStreams int           // HTTP/2 streams (SSE connections) per TCP connection
+	TCPKeepAlive              time.Duration // 0 = Go default (15s), negative disables
+
+	// Goroutine leak watchdog (see /admin/goroutines)
+	LeakCheckInterval time.Duration
+
+	// /health fails when a consumer or picker worker is stuck this long, or
+	// a worker channel is fuller than HealthChannelSaturation (0-1)
+	HealthStallTimeout      time.Duration
+	HealthChannelSaturation float64
+
+	// Shutdown drain (SIGTERM): fail readiness, wait PreStopDelay for load
+	// balancers to notice, refuse new streams, deliver claimed work for up
+	// to DrainTimeout, then tell clients to reconnect after ReconnectRetryAfter
+	PreStopDelay        time.Duration
+	DrainTimeout        time.Duration
+	ReconnectRetryAfter time.Duration
+
+	// Notifications accepted per POST /notifications/batch
+	PublishMaxBatch int
+
+	// JSON file the end-of-run summary is written to on shutdown (empty only logs it)
+	RunSummaryFile string
+
+	// Delivery state snapshotted on shutdown and restored on startup, so
+	// restarts mid-run don't reset the run summary: a JSON file, or Redis
+	SnapshotFile  string
+	SnapshotRedis bool
 }
 
 type TaskPickerConfig struct {
@@ -32,12 +115,81 @@ type TaskPickerConfig struct {
 	PollInterval       time.Duration
 	LeaseDuration      time.Duration
 	ChannelBufferSize  int
+	MaxInflight        int
+	ClaimPolicy        string // "priority" or "deadline"
+	ClaimWeights       string // HIGH/MEDIUM/LOW split of each claim, e.g. "70/20/10" (empty = strict policy order)
+	ClaimConnectedOnly bool   // Only claim notifications of users connected to the instance
+	MaxGroupSize       int
+	RetryBackoff       time.Duration // Failed deliveries aren't claimed again for this long, doubled per retry
+	RetryBackoffMax    time.Duration
+}
+
+// ConsumerConfig controls how the consumer treats events by event time
+type ConsumerConfig struct {
+	LateThreshold time.Duration // Events further than this from processing time are late
+	LatePolicy    string        // "deliver", "mark" (excluded from SLO stats) or "drop"
+
+	// Routing on Kafka headers, before the body is decoded (comma-separated)
+	EventTypes        string // Event types or families ("job.*") to persist; empty keeps all
+	Priorities        string // Priorities to persist; empty keeps all
+	ExpressPriorities string // Priorities persisted on arrival instead of with the next full batch
+
+	Workers     int // Parallel decode/persist workers; a user's events always share one
+	BufferLimit int // Notifications a worker holds while Postgres is unavailable before it spills or stops reading
+
+	// Spill-to-disk buffer for outages longer than memory allows (empty SpillDir disables it)
+	SpillDir      string
+	SpillMaxBytes int64
+
+	// Per-producer ingest quotas
+	IngestQuotaKey       string  // "source" (metadata.source_service) or "tenant"
+	IngestQuotaRate      float64 // Events per second per key (0 = unlimited unless overridden)
+	IngestQuotaBurst     int     // Events a key may send at once (0 = one second's worth)
+	IngestQuotaOverrides string  // Per-key rates, e.g. "job-service=50,followers-service=20"
+	IngestOverflow       string  // "degrade" (persist as LOW), "dlq" or "drop"
+	DLQTopic             string  // Kafka topic for dead-lettered events
+	PoisonTopic          string  // Kafka topic for events refused as invalid (default notifications-poison)
+
+	MaxBroadcastRecipients int // Notifications one broadcast may expand into; larger audiences are poisoned (default 10000)
+}
+
+// QuotaConfig limits delivery across every instance draining the backlog.
+// Counters live in Redis when Redis.Addr is set, else they are per instance.
+type QuotaConfig struct {
+	DeliveryRate int // Notifications delivered per second, cluster-wide (0 = unlimited)
+	UserLimit    int // Notifications delivered per user per UserWindow (0 = unlimited)
+	UserWindow   time.Duration
+}
+
+// ExpiryConfig controls the sweeper that expires notifications which can no
+// longer be delivered usefully
+type ExpiryConfig struct {
+	Interval     time.Duration // Time between sweeps
+	MaxAge       time.Duration // Pending/parked notifications older than this expire (0 = deadline only)
+	BatchSize    int
+	MetricsTopic string // Kafka topic for sweep summary events
+}
+
+// ReprioritizeConfig controls the job that moves old pending notifications
+// between priorities
+type ReprioritizeConfig struct {
+	Interval  time.Duration // Time between passes
+	Rules     string        // from:after:to,... e.g. HIGH:10m:MEDIUM (empty disables)
+	BatchSize int
 }
 
 type KafkaConfig struct {
-	Brokers       []string
-	ConsumerGroup string
-	Topic         string
+	Brokers         []string
+	ConsumerGroup   string
+	Topic           string
+	MessageEncoding string        // Encoding of events this service produces: json (default) or protobuf
+	LagInterval     time.Duration // Time between consumer group lag checks
+	ReceiptsTopic   string        // Topic for delivery receipts, e.g. notification-deliveries (empty disables)
+
+	// Topic provisioning at startup (replaces 1-partition auto-created topics)
+	ProvisionTopics   bool // Create missing topics and add partitions to smaller ones
+	Partitions        int  // Partitions per provisioned topic
+	ReplicationFactor int
 }
 
 type PostgreSQLConfig struct {
@@ -46,6 +198,83 @@ type PostgreSQLConfig struct {
 	Database string
 	User     string
 	Password string
+
+	// Connection pool, tunable per load test without recompiling
+	MaxConns        int           // Pool size
+	MinConns        int           // Connections kept open while idle
+	ConnMaxLifetime time.Duration // Connections are replaced after this long
+	ConnMaxIdleTime time.Duration // Idle connections above MinConns close after this long
+	QueryTimeout    time.Duration // How long a statement may run once it has a connection, 0 = no limit
+	SlowQuery       time.Duration // Statements slower than this are logged, 0 = off
+
+	// Read replica DSNs for the API's reads (user notifications, stats,
+	// export); empty reads from the primary
+	ReadReplicas []string
+
+	// Circuit breaker: after BreakerThreshold consecutive unavailable (or
+	// slower than BreakerSlowCall) calls, calls fail fast for BreakerOpenTimeout
+	BreakerThreshold   int // Negative disables the breaker
+	BreakerOpenTimeout time.Duration
+	BreakerSlowCall    time.Duration // 0 counts only errors
+}
+
+type IDGenerationConfig struct {
+	Strategy string // uuidv4, uuidv7, ulid, snowflake or sequential
+	NodeID   int64  // Snowflake (0-1023) or sequential node ID, unique per instance
+}
+
+type TracingConfig struct {
+	Enabled      bool
+	OTLPEndpoint string
+	Insecure     bool
+	ServiceName  string
+	SampleRatio  float64
+}
+
+// RedisConfig enables the read-model cache for user queries (empty Addr disables it)
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	TTL      time.Duration // Upper bound on staleness for writes that don't invalidate
+}
+
+// ChaosConfig injects faults for resilience benchmarks; rates are
+// probabilities from 0 to 1 (all zero injects nothing)
+type ChaosConfig struct {
+	Enabled           bool          // Wires fault injection and PUT /admin/chaos; implied by any fault below
+	DBLatency         time.Duration // Added to inserts, claims and status updates picked by DBLatencyRate
+	DBLatencyRate     float64
+	SSEWriteFailRate  float64       // Stream flushes that fail, dropping the connection
+	ConsumerPause     time.Duration // How long the consumer stalls before a picked message
+	ConsumerPauseRate float64
+	DeliveryErrorRate float64 // Delivery attempts failed before the SSE send
+}
+
+// CanaryConfig controls the built-in end-to-end probe
+type CanaryConfig struct {
+	Enabled          bool
+	Interval         time.Duration // Time between probes
+	Users            int           // Dedicated canary users (canary tenant)
+	Timeout          time.Duration // A probe not received within this is lost
+	LatencyThreshold time.Duration // p95 above this marks the canary degraded
+}
+
+// SLOConfig holds the maximum acceptable event → delivery delay per priority
+type SLOConfig struct {
+	High   time.Duration
+	Medium time.Duration
+	Low    time.Duration
+}
+
+// SLAConfig turns the SLO latency targets into percentile objectives, e.g.
+// HighPercentile 99 with SLO.High 1s means "HIGH p99 ≤ 1s"
+type SLAConfig struct {
+	Window           time.Duration // Rolling window compliance is computed over
+	Interval         time.Duration // How often compliance is recomputed
+	HighPercentile   float64
+	MediumPercentile float64
+	LowPercentile    float64
 }
 
 type PriorityDelaysConfig struct {
@@ -100,17 +329,342 @@ func Load(configPath string) (*Config, error) {
 	if pgPass := os.Getenv("POSTGRES_PASSWORD"); pgPass != "" {
 		v.Set("postgresql.password", pgPass)
 	}
+	if maxConns := os.Getenv("POSTGRES_MAX_CONNS"); maxConns != "" {
+		v.Set("postgresql.maxconns", maxConns)
+	}
+	if minConns := os.Getenv("POSTGRES_MIN_CONNS"); minConns != "" {
+		v.Set("postgresql.minconns", minConns)
+	}
+	if lifetime := os.Getenv("POSTGRES_CONN_MAX_LIFETIME"); lifetime != "" {
+		v.Set("postgresql.connmaxlifetime", lifetime)
+	}
+	if idleTime := os.Getenv("POSTGRES_CONN_MAX_IDLE_TIME"); idleTime != "" {
+		v.Set("postgresql.connmaxidletime", idleTime)
+	}
+	if queryTimeout := os.Getenv("POSTGRES_QUERY_TIMEOUT"); queryTimeout != "" {
+		v.Set("postgresql.querytimeout", queryTimeout)
+	}
+	if slowQuery := os.Getenv("POSTGRES_SLOW_QUERY"); slowQuery != "" {
+		v.Set("postgresql.slowquery", slowQuery)
+	}
+	if replicas := os.Getenv("POSTGRES_READ_REPLICAS"); replicas != "" {
+		v.Set("postgresql.readreplicas", strings.Split(replicas, ","))
+	}
+	if threshold := os.Getenv("POSTGRES_BREAKER_THRESHOLD"); threshold != "" {
+		v.Set("postgresql.breakerthreshold", threshold)
+	}
+	if openTimeout := os.Getenv("POSTGRES_BREAKER_OPEN_TIMEOUT"); openTimeout != "" {
+		v.Set("postgresql.breakeropentimeout", openTimeout)
+	}
+	if slowCall := os.Getenv("POSTGRES_BREAKER_SLOW_CALL"); slowCall != "" {
+		v.Set("postgresql.breakerslowcall", slowCall)
+	}
+
+	// Auth environment variables
+	if signingKey := os.Getenv("AUTH_SIGNING_KEY"); signingKey != "" {
+		v.Set("notificationservice.authsigningkey", signingKey)
+	}
+
+	// SSE connection lifecycle environment variables
+	if heartbeat := os.Getenv("SSE_HEARTBEAT_INTERVAL"); heartbeat != "" {
+		v.Set("notificationservice.sseheartbeatinterval", heartbeat)
+	}
+	if staleTimeout := os.Getenv("SSE_STALE_TIMEOUT"); staleTimeout != "" {
+		v.Set("notificationservice.ssestaletimeout", staleTimeout)
+	}
+	if cleanupInterval := os.Getenv("SSE_CLEANUP_INTERVAL"); cleanupInterval != "" {
+		v.Set("notificationservice.ssecleanupinterval", cleanupInterval)
+	}
+	if compression := os.Getenv("SSE_COMPRESSION"); compression != "" {
+		v.Set("notificationservice.ssecompression", compression)
+	}
+	if flushInterval := os.Getenv("SSE_FLUSH_INTERVAL"); flushInterval != "" {
+		v.Set("notificationservice.sseflushinterval", flushInterval)
+	}
+	if bandwidthLimit := os.Getenv("SSE_BANDWIDTH_LIMIT"); bandwidthLimit != "" {
+		v.Set("notificationservice.ssebandwidthlimit", bandwidthLimit)
+	}
+	if livenessTimeout := os.Getenv("SSE_LIVENESS_TIMEOUT"); livenessTimeout != "" {
+		v.Set("notificationservice.sselivenesstimeout", livenessTimeout)
+	}
+	if regions := os.Getenv("SSE_REGIONS"); regions != "" {
+		v.Set("notificationservice.sseregions", regions)
+	}
+	if retry := os.Getenv("SSE_RETRY"); retry != "" {
+		v.Set("notificationservice.sseretry", retry)
+	}
+	if spread := os.Getenv("SSE_RECONNECT_SPREAD"); spread != "" {
+		v.Set("notificationservice.ssereconnectspread", spread)
+	}
+	if stages := os.Getenv("SSE_STAGE_TIMESTAMPS"); stages != "" {
+		v.Set("notificationservice.ssestagetimestamps", stages)
+	}
+	if templates := os.Getenv("NOTIFICATION_TEMPLATES"); templates != "" {
+		v.Set("notificationservice.templatesfile", templates)
+	}
+	if localesDir := os.Getenv("NOTIFICATION_LOCALES_DIR"); localesDir != "" {
+		v.Set("notificationservice.localesdir", localesDir)
+	}
+	if prefsTTL := os.Getenv("PREFERENCES_CACHE_TTL"); prefsTTL != "" {
+		v.Set("notificationservice.preferencescachettl", prefsTTL)
+	}
+	if eventTypesRefresh := os.Getenv("EVENT_TYPES_REFRESH"); eventTypesRefresh != "" {
+		v.Set("notificationservice.eventtypesrefresh", eventTypesRefresh)
+	}
+	if maxBatch := os.Getenv("PUBLISH_MAX_BATCH"); maxBatch != "" {
+		v.Set("notificationservice.publishmaxbatch", maxBatch)
+	}
+	if summaryFile := os.Getenv("RUN_SUMMARY_FILE"); summaryFile != "" {
+		v.Set("notificationservice.runsummaryfile", summaryFile)
+	}
+	if snapshotFile := os.Getenv("SNAPSHOT_FILE"); snapshotFile != "" {
+		v.Set("notificationservice.snapshotfile", snapshotFile)
+	}
+	if snapshotRedis := os.Getenv("SNAPSHOT_REDIS"); snapshotRedis != "" {
+		v.Set("notificationservice.snapshotredis", snapshotRedis)
+	}
+	// A stable instance ID lets a restarted instance find its Redis snapshot
+	if instanceID := os.Getenv("INSTANCE_ID"); instanceID != "" {
+		v.Set("taskpicker.instanceid", instanceID)
+	}
+	if backoff := os.Getenv("RETRY_BACKOFF"); backoff != "" {
+		v.Set("taskpicker.retrybackoff", backoff)
+	}
+	if backoffMax := os.Getenv("RETRY_BACKOFF_MAX"); backoffMax != "" {
+		v.Set("taskpicker.retrybackoffmax", backoffMax)
+	}
+	if weights := os.Getenv("CLAIM_WEIGHTS"); weights != "" {
+		v.Set("taskpicker.claimweights", weights)
+	}
+	if connectedOnly := os.Getenv("CLAIM_CONNECTED_ONLY"); connectedOnly != "" {
+		v.Set("taskpicker.claimconnectedonly", connectedOnly)
+	}
+
+	// HTTP server environment variables
+	if http2Mode := os.Getenv("HTTP2_MODE"); http2Mode != "" {
+		v.Set("notificationservice.http2mode", http2Mode)
+	}
+	if maxStreams := os.Getenv("HTTP2_MAX_CONCURRENT_STREAMS"); maxStreams != "" {
+		v.Set("notificationservice.http2maxconcurrentstreams", maxStreams)
+	}
+
+	// TLS environment variables
+	if certFile := os.Getenv("TLS_CERT_FILE"); certFile != "" {
+		v.Set("notificationservice.tlscertfile", certFile)
+	}
+	if keyFile := os.Getenv("TLS_KEY_FILE"); keyFile != "" {
+		v.Set("notificationservice.tlskeyfile", keyFile)
+	}
+
+	// ID generation environment variables
+	if idStrategy := os.Getenv("ID_STRATEGY"); idStrategy != "" {
+		v.Set("idgeneration.strategy", idStrategy)
+	}
+	if nodeID := os.Getenv("ID_NODE_ID"); nodeID != "" {
+		v.Set("idgeneration.nodeid", nodeID)
+	}
+
+	// Tracing environment variables; like tracing.ConfigFromEnv, an OTLP
+	// endpoint turns tracing on
+	if otlpEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); otlpEndpoint != "" {
+		v.Set("tracing.otlpendpoint", otlpEndpoint)
+		v.Set("tracing.enabled", true)
+	}
 
 	// Kafka environment variables
 	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
 		v.Set("kafka.brokers", []string{brokers})
 	}
+	if encoding := os.Getenv("MESSAGE_ENCODING"); encoding != "" {
+		v.Set("kafka.messageencoding", encoding)
+	}
+	if lagInterval := os.Getenv("KAFKA_LAG_INTERVAL"); lagInterval != "" {
+		v.Set("kafka.laginterval", lagInterval)
+	}
+	if receiptsTopic := os.Getenv("RECEIPTS_TOPIC"); receiptsTopic != "" {
+		v.Set("kafka.receiptstopic", receiptsTopic)
+	}
+	if provision := os.Getenv("KAFKA_PROVISION_TOPICS"); provision != "" {
+		v.Set("kafka.provisiontopics", provision)
+	}
+	if partitions := os.Getenv("KAFKA_PARTITIONS"); partitions != "" {
+		v.Set("kafka.partitions", partitions)
+	}
+	if replication := os.Getenv("KAFKA_REPLICATION_FACTOR"); replication != "" {
+		v.Set("kafka.replicationfactor", replication)
+	}
+
+	// Redis environment variables
+	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
+		v.Set("redis.addr", redisAddr)
+	}
+	if redisPass := os.Getenv("REDIS_PASSWORD"); redisPass != "" {
+		v.Set("redis.password", redisPass)
+	}
+
+	// Consumer environment variables
+	if latePolicy := os.Getenv("LATE_EVENT_POLICY"); latePolicy != "" {
+		v.Set("consumer.latepolicy", latePolicy)
+	}
+	if eventTypes := os.Getenv("CONSUMER_EVENT_TYPES"); eventTypes != "" {
+		v.Set("consumer.eventtypes", eventTypes)
+	}
+	if priorities := os.Getenv("CONSUMER_PRIORITIES"); priorities != "" {
+		v.Set("consumer.priorities", priorities)
+	}
+	if express := os.Getenv("CONSUMER_EXPRESS_PRIORITIES"); express != "" {
+		v.Set("consumer.expresspriorities", express)
+	}
+	if workers := os.Getenv("CONSUMER_WORKERS"); workers != "" {
+		v.Set("consumer.workers", workers)
+	}
+	if bufferLimit := os.Getenv("CONSUMER_BUFFER_LIMIT"); bufferLimit != "" {
+		v.Set("consumer.bufferlimit", bufferLimit)
+	}
+	if spillDir := os.Getenv("CONSUMER_SPILL_DIR"); spillDir != "" {
+		v.Set("consumer.spilldir", spillDir)
+	}
+	if spillMax := os.Getenv("CONSUMER_SPILL_MAX_BYTES"); spillMax != "" {
+		v.Set("consumer.spillmaxbytes", spillMax)
+	}
+	if quotaKey := os.Getenv("INGEST_QUOTA_KEY"); quotaKey != "" {
+		v.Set("consumer.ingestquotakey", quotaKey)
+	}
+	if quotaRate := os.Getenv("INGEST_QUOTA_RATE"); quotaRate != "" {
+		v.Set("consumer.ingestquotarate", quotaRate)
+	}
+	if quotaBurst := os.Getenv("INGEST_QUOTA_BURST"); quotaBurst != "" {
+		v.Set("consumer.ingestquotaburst", quotaBurst)
+	}
+	if overrides := os.Getenv("INGEST_QUOTA_OVERRIDES"); overrides != "" {
+		v.Set("consumer.ingestquotaoverrides", overrides)
+	}
+	if overflow := os.Getenv("INGEST_OVERFLOW"); overflow != "" {
+		v.Set("consumer.ingestoverflow", overflow)
+	}
+	if dlqTopic := os.Getenv("DLQ_TOPIC"); dlqTopic != "" {
+		v.Set("consumer.dlqtopic", dlqTopic)
+	}
+	if poisonTopic := os.Getenv("POISON_TOPIC"); poisonTopic != "" {
+		v.Set("consumer.poisontopic", poisonTopic)
+	}
+	if maxRecipients := os.Getenv("MAX_BROADCAST_RECIPIENTS"); maxRecipients != "" {
+		v.Set("consumer.maxbroadcastrecipients", maxRecipients)
+	}
+
+	// Quota environment variables
+	if deliveryRate := os.Getenv("DELIVERY_RATE_LIMIT"); deliveryRate != "" {
+		v.Set("quota.deliveryrate", deliveryRate)
+	}
+	if userLimit := os.Getenv("USER_DELIVERY_LIMIT"); userLimit != "" {
+		v.Set("quota.userlimit", userLimit)
+	}
+
+	// Expiry environment variables
+	if maxAge := os.Getenv("EXPIRY_MAX_AGE"); maxAge != "" {
+		v.Set("expiry.maxage", maxAge)
+	}
+	if metricsTopic := os.Getenv("METRICS_TOPIC"); metricsTopic != "" {
+		v.Set("expiry.metricstopic", metricsTopic)
+	}
+
+	// Reprioritize environment variables
+	if rules := os.Getenv("REPRIORITIZE_RULES"); rules != "" {
+		v.Set("reprioritize.rules", rules)
+	}
+	if interval := os.Getenv("REPRIORITIZE_INTERVAL"); interval != "" {
+		v.Set("reprioritize.interval", interval)
+	}
+
+	// Chaos environment variables
+	if enabled := os.Getenv("CHAOS_ENABLED"); enabled != "" {
+		v.Set("chaos.enabled", enabled)
+	}
+	if latency := os.Getenv("CHAOS_DB_LATENCY"); latency != "" {
+		v.Set("chaos.dblatency", latency)
+	}
+	if rate := os.Getenv("CHAOS_DB_LATENCY_RATE"); rate != "" {
+		v.Set("chaos.dblatencyrate", rate)
+	}
+	if rate := os.Getenv("CHAOS_SSE_WRITE_FAIL_RATE"); rate != "" {
+		v.Set("chaos.ssewritefailrate", rate)
+	}
+	if pause := os.Getenv("CHAOS_CONSUMER_PAUSE"); pause != "" {
+		v.Set("chaos.consumerpause", pause)
+	}
+	if rate := os.Getenv("CHAOS_CONSUMER_PAUSE_RATE"); rate != "" {
+		v.Set("chaos.consumerpauserate", rate)
+	}
+	if rate := os.Getenv("CHAOS_DELIVERY_ERROR_RATE"); rate != "" {
+		v.Set("chaos.deliveryerrorrate", rate)
+	}
+
+	// Canary environment variables
+	if canary := os.Getenv("CANARY_ENABLED"); canary != "" {
+		v.Set("canary.enabled", canary)
+	}
+
+	// Startup check environment variables
+	if prefix := os.Getenv("BENCH_USER_PREFIX"); prefix != "" {
+		v.Set("notificationservice.expecteduserprefix", prefix)
+	}
+	if numUsers := os.Getenv("NUM_USERS"); numUsers != "" {
+		v.Set("notificationservice.expectedusers", numUsers)
+	}
+	if skip := os.Getenv("SKIP_STARTUP_CHECKS"); skip != "" {
+		v.Set("notificationservice.skipstartupchecks", skip)
+	}
+	if stall := os.Getenv("HEALTH_STALL_TIMEOUT"); stall != "" {
+		v.Set("notificationservice.healthstalltimeout", stall)
+	}
+	if saturation := os.Getenv("HEALTH_CHANNEL_SATURATION"); saturation != "" {
+		v.Set("notificationservice.healthchannelsaturation", saturation)
+	}
+	if delay := os.Getenv("PRE_STOP_DELAY"); delay != "" {
+		v.Set("notificationservice.prestopdelay", delay)
+	}
+	if drain := os.Getenv("DRAIN_TIMEOUT"); drain != "" {
+		v.Set("notificationservice.draintimeout", drain)
+	}
+	if retry := os.Getenv("RECONNECT_RETRY_AFTER"); retry != "" {
+		v.Set("notificationservice.reconnectretryafter", retry)
+	}
 
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Kafka topic: config file and KAFKA_TOPIC must agree when both are set,
+	// otherwise the consumer silently reads a topic nobody produces to
+	envTopic := os.Getenv("KAFKA_TOPIC")
+	switch {
+	case envTopic != "" && config.Kafka.Topic != "" && envTopic != config.Kafka.Topic:
+		return nil, fmt.Errorf("kafka topic mismatch: config file has %q but KAFKA_TOPIC=%q; set one or make them agree", config.Kafka.Topic, envTopic)
+	case envTopic != "":
+		config.Kafka.Topic = envTopic
+	case config.Kafka.Topic == "":
+		config.Kafka.Topic = "notifications"
+	}
+	if len(config.Kafka.Brokers) == 0 || config.Kafka.Brokers[0] == "" {
+		config.Kafka.Brokers = []string{"localhost:9092"}
+	}
+	if config.Kafka.ConsumerGroup == "" {
+		config.Kafka.ConsumerGroup = "notification-consumer"
+	}
+	if config.Kafka.LagInterval == 0 {
+		config.Kafka.LagInterval = 15 * time.Second
+	}
+	if config.Kafka.Partitions == 0 {
+		config.Kafka.Partitions = 10
+	}
+	if config.Kafka.ReplicationFactor == 0 {
+		config.Kafka.ReplicationFactor = 1
+	}
+	if config.Kafka.Partitions < 0 || config.Kafka.ReplicationFactor < 0 {
+		return nil, fmt.Errorf("invalid kafka topic layout: partitions %d and replication factor %d must be positive", config.Kafka.Partitions, config.Kafka.ReplicationFactor)
+	}
+
 	// PostgreSQL defaults
 	if config.PostgreSQL.Host == "" {
 		config.PostgreSQL.Host = "localhost"
@@ -127,6 +681,39 @@ func Load(configPath string) (*Config, error) {
 	if config.PostgreSQL.Password == "" {
 		config.PostgreSQL.Password = "admin123"
 	}
+	if config.PostgreSQL.MaxConns == 0 {
+		config.PostgreSQL.MaxConns = 50
+	}
+	if config.PostgreSQL.ConnMaxLifetime == 0 {
+		config.PostgreSQL.ConnMaxLifetime = 5 * time.Minute
+	}
+	if config.PostgreSQL.ConnMaxIdleTime == 0 {
+		config.PostgreSQL.ConnMaxIdleTime = 1 * time.Minute
+	}
+	if config.PostgreSQL.MaxConns < 0 || config.PostgreSQL.MinConns < 0 || config.PostgreSQL.MinConns > config.PostgreSQL.MaxConns {
+		return nil, fmt.Errorf("invalid postgres pool: min conns %d and max conns %d must satisfy 0 <= min <= max", config.PostgreSQL.MinConns, config.PostgreSQL.MaxConns)
+	}
+	if config.PostgreSQL.ConnMaxLifetime < 0 || config.PostgreSQL.ConnMaxIdleTime < 0 ||
+		config.PostgreSQL.QueryTimeout < 0 || config.PostgreSQL.SlowQuery < 0 {
+		return nil, fmt.Errorf("invalid postgres pool durations: lifetime %s, idle time %s, query timeout %s and slow query %s must not be negative",
+			config.PostgreSQL.ConnMaxLifetime, config.PostgreSQL.ConnMaxIdleTime, config.PostgreSQL.QueryTimeout, config.PostgreSQL.SlowQuery)
+	}
+	replicas := config.PostgreSQL.ReadReplicas[:0]
+	for _, dsn := range config.PostgreSQL.ReadReplicas {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			replicas = append(replicas, dsn)
+		}
+	}
+	config.PostgreSQL.ReadReplicas = replicas
+	if config.PostgreSQL.BreakerThreshold == 0 {
+		config.PostgreSQL.BreakerThreshold = 5
+	}
+	if config.PostgreSQL.BreakerOpenTimeout == 0 {
+		config.PostgreSQL.BreakerOpenTimeout = 5 * time.Second
+	}
+	if config.PostgreSQL.BreakerOpenTimeout < 0 || config.PostgreSQL.BreakerSlowCall < 0 {
+		return nil, fmt.Errorf("invalid postgres breaker open timeout (%s) or slow call (%s)", config.PostgreSQL.BreakerOpenTimeout, config.PostgreSQL.BreakerSlowCall)
+	}
 
 	// Service defaults
 	if config.NotificationService.Port == 0 {
@@ -141,10 +728,121 @@ func Load(configPath string) (*Config, error) {
 	if config.NotificationService.SSEHeartbeatInterval == 0 {
 		config.NotificationService.SSEHeartbeatInterval = 30 * time.Second
 	}
+	if config.NotificationService.SSEStaleTimeout == 0 {
+		config.NotificationService.SSEStaleTimeout = 5 * time.Minute
+	}
+	if config.NotificationService.SSECleanupInterval == 0 {
+		config.NotificationService.SSECleanupInterval = 1 * time.Minute
+	}
+	if config.NotificationService.SSEFlushMaxFrames == 0 {
+		config.NotificationService.SSEFlushMaxFrames = 16
+	}
+	if config.NotificationService.SSEFlushInterval < 0 || config.NotificationService.SSEFlushInterval >= config.NotificationService.SSEHeartbeatInterval {
+		return nil, fmt.Errorf("invalid sse flush interval: %s (must be non-negative and below the heartbeat interval)", config.NotificationService.SSEFlushInterval)
+	}
+	if config.NotificationService.SSEBandwidthLimit < 0 {
+		return nil, fmt.Errorf("invalid sse bandwidth limit: %d", config.NotificationService.SSEBandwidthLimit)
+	}
+	if config.NotificationService.PreferencesCacheTTL == 0 {
+		config.NotificationService.PreferencesCacheTTL = time.Minute
+	}
+	if config.NotificationService.PreferencesCacheTTL < 0 {
+		return nil, fmt.Errorf("invalid preferences cache ttl: %s", config.NotificationService.PreferencesCacheTTL)
+	}
+	if config.NotificationService.EventTypesRefresh == 0 {
+		config.NotificationService.EventTypesRefresh = 30 * time.Second
+	}
+	if config.NotificationService.EventTypesRefresh < 0 {
+		return nil, fmt.Errorf("invalid event types refresh interval: %s", config.NotificationService.EventTypesRefresh)
+	}
+	if config.NotificationService.SSERetry == 0 {
+		config.NotificationService.SSERetry = 3 * time.Second
+	}
+	if config.NotificationService.SSEReconnectSpread == 0 {
+		config.NotificationService.SSEReconnectSpread = 5 * time.Second
+	}
+	if config.NotificationService.SSERetry < 0 || config.NotificationService.SSEReconnectSpread < 0 {
+		return nil, fmt.Errorf("invalid sse retry (%s) or reconnect spread (%s)", config.NotificationService.SSERetry, config.NotificationService.SSEReconnectSpread)
+	}
+	if config.NotificationService.SSEStaleTimeout <= config.NotificationService.SSEHeartbeatInterval {
+		return nil, fmt.Errorf("sse stale timeout (%s) must exceed the heartbeat interval (%s)",
+			config.NotificationService.SSEStaleTimeout, config.NotificationService.SSEHeartbeatInterval)
+	}
+	if liveness := config.NotificationService.SSELivenessTimeout; liveness != 0 &&
+		(liveness <= config.NotificationService.SSEHeartbeatInterval || liveness >= config.NotificationService.SSEStaleTimeout) {
+		return nil, fmt.Errorf("invalid sse liveness timeout: %s (must exceed the heartbeat interval and be below the stale timeout)", liveness)
+	}
 	if config.NotificationService.GracefulShutdownTimeout == 0 {
 		config.NotificationService.GracefulShutdownTimeout = 30 * time.Second
 	}
-	
+	if config.NotificationService.ConnectionAcceptRate == 0 {
+		config.NotificationService.ConnectionAcceptRate = 500 // New connections per second
+	}
+	if config.NotificationService.ConnectionAcceptBurst == 0 {
+		config.NotificationService.ConnectionAcceptBurst = 1000
+	}
+	if config.NotificationService.ConnectionQueueTimeout == 0 {
+		config.NotificationService.ConnectionQueueTimeout = 2 * time.Second
+	}
+	if config.NotificationService.LeakCheckInterval == 0 {
+		config.NotificationService.LeakCheckInterval = 30 * time.Second
+	}
+	if config.NotificationService.HealthStallTimeout == 0 {
+		config.NotificationService.HealthStallTimeout = 30 * time.Second
+	}
+	if config.NotificationService.HealthChannelSaturation == 0 {
+		config.NotificationService.HealthChannelSaturation = 0.9
+	}
+	if config.NotificationService.HealthChannelSaturation < 0 || config.NotificationService.HealthChannelSaturation > 1 {
+		return nil, fmt.Errorf("invalid health channel saturation: %g (want 0-1)", config.NotificationService.HealthChannelSaturation)
+	}
+	if config.NotificationService.PublishMaxBatch == 0 {
+		config.NotificationService.PublishMaxBatch = 1000
+	}
+	if config.NotificationService.PublishMaxBatch < 0 {
+		return nil, fmt.Errorf("invalid publish max batch: %d", config.NotificationService.PublishMaxBatch)
+	}
+	if config.NotificationService.DrainTimeout == 0 {
+		config.NotificationService.DrainTimeout = 20 * time.Second
+	}
+	if config.NotificationService.ReconnectRetryAfter == 0 {
+		config.NotificationService.ReconnectRetryAfter = 2 * time.Second
+	}
+	if config.NotificationService.PreStopDelay < 0 {
+		return nil, fmt.Errorf("invalid pre-stop delay: %s", config.NotificationService.PreStopDelay)
+	}
+	if config.NotificationService.MaxQueuedConnections == 0 {
+		config.NotificationService.MaxQueuedConnections = 1000
+	}
+	if config.NotificationService.AuthEnabled && config.NotificationService.AuthSigningKey == "" {
+		return nil, fmt.Errorf("auth is enabled but no signing key is configured (set AUTH_SIGNING_KEY)")
+	}
+	if (config.NotificationService.TLSCertFile == "") != (config.NotificationService.TLSKeyFile == "") {
+		return nil, fmt.Errorf("tls cert file and key file must be set together")
+	}
+	if config.NotificationService.TLSCertFile != "" && len(config.NotificationService.TLSAutocertDomains) > 0 {
+		return nil, fmt.Errorf("tls cert file and autocert domains are mutually exclusive")
+	}
+	if config.NotificationService.TLSAutocertCacheDir == "" {
+		config.NotificationService.TLSAutocertCacheDir = "./autocert-cache"
+	}
+	if config.NotificationService.HTTPReadHeaderTimeout == 0 {
+		config.NotificationService.HTTPReadHeaderTimeout = 10 * time.Second
+	}
+	if config.NotificationService.HTTPIdleTimeout == 0 {
+		config.NotificationService.HTTPIdleTimeout = 2 * time.Minute
+	}
+	if config.NotificationService.HTTP2MaxConcurrentStreams == 0 {
+		config.NotificationService.HTTP2MaxConcurrentStreams = 1000
+	}
+	switch config.NotificationService.HTTP2Mode {
+	case "":
+		config.NotificationService.HTTP2Mode = "tls"
+	case "tls", "h2c", "off":
+	default:
+		return nil, fmt.Errorf("invalid http2 mode: %q", config.NotificationService.HTTP2Mode)
+	}
+
 	// Task Picker defaults - Optimized for high throughput
 	if config.TaskPicker.InstanceID == "" {
 		config.TaskPicker.InstanceID = fmt.Sprintf("notif-service-%d", time.Now().Unix())
@@ -167,6 +865,188 @@ func Load(configPath string) (*Config, error) {
 	if config.TaskPicker.ChannelBufferSize == 0 {
 		config.TaskPicker.ChannelBufferSize = 5000 // Increased from 2000 for higher throughput
 	}
+	if config.TaskPicker.MaxInflight == 0 {
+		config.TaskPicker.MaxInflight = 10000 // Claimed-but-undelivered cap per instance
+	}
+	if config.TaskPicker.MaxGroupSize == 0 {
+		config.TaskPicker.MaxGroupSize = 20 // Notifications per user per SSE frame
+	}
+	if config.TaskPicker.RetryBackoff == 0 {
+		config.TaskPicker.RetryBackoff = time.Second
+	}
+	if config.TaskPicker.RetryBackoffMax == 0 {
+		config.TaskPicker.RetryBackoffMax = 5 * time.Minute
+	}
+	if config.TaskPicker.RetryBackoff < 0 || config.TaskPicker.RetryBackoffMax < config.TaskPicker.RetryBackoff {
+		return nil, fmt.Errorf("invalid retry backoff (%s) or backoff max (%s)", config.TaskPicker.RetryBackoff, config.TaskPicker.RetryBackoffMax)
+	}
+	switch config.TaskPicker.ClaimPolicy {
+	case "":
+		config.TaskPicker.ClaimPolicy = "priority"
+	case "priority", "deadline":
+	default:
+		return nil, fmt.Errorf("invalid task picker claim policy: %q", config.TaskPicker.ClaimPolicy)
+	}
+
+	// Consumer defaults
+	if config.Consumer.LateThreshold == 0 {
+		config.Consumer.LateThreshold = 5 * time.Minute
+	}
+	switch config.Consumer.LatePolicy {
+	case "":
+		config.Consumer.LatePolicy = "mark"
+	case "deliver", "mark", "drop":
+	default:
+		return nil, fmt.Errorf("invalid consumer late policy: %q", config.Consumer.LatePolicy)
+	}
+	if config.Consumer.Workers == 0 {
+		config.Consumer.Workers = 4
+	}
+	if config.Consumer.Workers < 0 {
+		return nil, fmt.Errorf("invalid consumer workers: %d", config.Consumer.Workers)
+	}
+	if config.Consumer.BufferLimit == 0 {
+		config.Consumer.BufferLimit = 1000
+	}
+	if config.Consumer.BufferLimit < 0 {
+		return nil, fmt.Errorf("invalid consumer buffer limit: %d", config.Consumer.BufferLimit)
+	}
+	if config.Consumer.SpillMaxBytes == 0 {
+		config.Consumer.SpillMaxBytes = 1 << 30
+	}
+	if config.Consumer.SpillMaxBytes < 0 {
+		return nil, fmt.Errorf("invalid consumer spill max bytes: %d", config.Consumer.SpillMaxBytes)
+	}
+	if config.Consumer.IngestQuotaKey == "" {
+		config.Consumer.IngestQuotaKey = "source"
+	}
+	if config.Consumer.IngestOverflow == "" {
+		config.Consumer.IngestOverflow = "degrade"
+	}
+	if config.Consumer.DLQTopic == "" {
+		config.Consumer.DLQTopic = "notifications-dlq"
+	}
+	if config.Consumer.PoisonTopic == "" {
+		config.Consumer.PoisonTopic = "notifications-poison"
+	}
+	if config.Consumer.MaxBroadcastRecipients < 0 {
+		return nil, fmt.Errorf("invalid max broadcast recipients %d: must not be negative", config.Consumer.MaxBroadcastRecipients)
+	}
+	if config.Consumer.MaxBroadcastRecipients == 0 {
+		config.Consumer.MaxBroadcastRecipients = 10000
+	}
+	if config.Consumer.IngestQuotaRate < 0 || config.Consumer.IngestQuotaBurst < 0 {
+		return nil, fmt.Errorf("invalid ingest quota: rate %g and burst %d must not be negative", config.Consumer.IngestQuotaRate, config.Consumer.IngestQuotaBurst)
+	}
+
+	// Quota defaults
+	if config.Quota.UserWindow == 0 {
+		config.Quota.UserWindow = time.Minute
+	}
+	if config.Quota.DeliveryRate < 0 || config.Quota.UserLimit < 0 {
+		return nil, fmt.Errorf("invalid quota: delivery rate %d and user limit %d must not be negative", config.Quota.DeliveryRate, config.Quota.UserLimit)
+	}
+
+	// Expiry defaults
+	if config.Expiry.Interval == 0 {
+		config.Expiry.Interval = time.Minute
+	}
+	if config.Expiry.BatchSize == 0 {
+		config.Expiry.BatchSize = 5000
+	}
+	if config.Expiry.MetricsTopic == "" {
+		config.Expiry.MetricsTopic = "notification-metrics"
+	}
+	if config.Expiry.MaxAge < 0 {
+		return nil, fmt.Errorf("invalid expiry max age: %s", config.Expiry.MaxAge)
+	}
+
+	// Reprioritize defaults
+	if config.Reprioritize.Interval == 0 {
+		config.Reprioritize.Interval = 30 * time.Second
+	}
+	if config.Reprioritize.BatchSize == 0 {
+		config.Reprioritize.BatchSize = 5000
+	}
+
+	// ID generation defaults
+	if config.IDGeneration.Strategy == "" {
+		config.IDGeneration.Strategy = "uuidv4"
+	}
+
+	// SLO defaults
+	if config.SLO.High == 0 {
+		config.SLO.High = 1 * time.Second
+	}
+	if config.SLO.Medium == 0 {
+		config.SLO.Medium = 5 * time.Second
+	}
+	if config.SLO.Low == 0 {
+		config.SLO.Low = 30 * time.Second
+	}
+
+	// SLA defaults
+	if config.SLA.Window == 0 {
+		config.SLA.Window = 5 * time.Minute
+	}
+	if config.SLA.Interval == 0 {
+		config.SLA.Interval = 15 * time.Second
+	}
+	if config.SLA.HighPercentile == 0 {
+		config.SLA.HighPercentile = 99
+	}
+	if config.SLA.MediumPercentile == 0 {
+		config.SLA.MediumPercentile = 95
+	}
+	if config.SLA.LowPercentile == 0 {
+		config.SLA.LowPercentile = 90
+	}
+
+	// Redis defaults
+	if config.Redis.TTL == 0 {
+		config.Redis.TTL = 30 * time.Second
+	}
+	if config.NotificationService.SnapshotRedis && (config.Redis.Addr == "" || config.NotificationService.SnapshotFile != "") {
+		return nil, fmt.Errorf("invalid snapshot store: SNAPSHOT_REDIS needs REDIS_ADDR and excludes SNAPSHOT_FILE")
+	}
+
+	// Canary defaults
+	if config.Canary.Interval == 0 {
+		config.Canary.Interval = 5 * time.Second
+	}
+	if config.Canary.Users == 0 {
+		config.Canary.Users = 2
+	}
+	if config.Canary.Timeout == 0 {
+		config.Canary.Timeout = 30 * time.Second
+	}
+	if config.Canary.LatencyThreshold == 0 {
+		config.Canary.LatencyThreshold = 2 * time.Second
+	}
+
+	// Chaos rates are probabilities
+	for _, rate := range []float64{config.Chaos.DBLatencyRate, config.Chaos.SSEWriteFailRate, config.Chaos.ConsumerPauseRate, config.Chaos.DeliveryErrorRate} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid chaos rate %v (want 0-1)", rate)
+		}
+	}
+	if config.Chaos.DBLatency < 0 || config.Chaos.ConsumerPause < 0 {
+		return nil, fmt.Errorf("invalid chaos db latency (%s) or consumer pause (%s)", config.Chaos.DBLatency, config.Chaos.ConsumerPause)
+	}
+	if config.Chaos.DBLatencyRate > 0 || config.Chaos.SSEWriteFailRate > 0 || config.Chaos.ConsumerPauseRate > 0 || config.Chaos.DeliveryErrorRate > 0 {
+		config.Chaos.Enabled = true
+	}
+
+	// Tracing defaults
+	if config.Tracing.OTLPEndpoint == "" {
+		config.Tracing.OTLPEndpoint = "localhost:4318"
+	}
+	if config.Tracing.ServiceName == "" {
+		config.Tracing.ServiceName = "notification-service"
+	}
+	if config.Tracing.SampleRatio == 0 {
+		config.Tracing.SampleRatio = 1.0
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// Producer faults, named in metrics and stats
+const (
+	FaultDropped    = "dropped"
+	FaultDuplicated = "duplicated"
+	FaultOutage     = "outage"
+)
+
+// FaultConfig simulates an imperfect upstream; rates are probabilities from
+// 0 to 1 and the zero value injects nothing
+type FaultConfig struct {
+	DropRate       float64       // Events lost before they reach the producer
+	DuplicateRate  float64       // Events published twice with the same event ID, as by a retrying upstream
+	OutageEvery    time.Duration // Publishing pauses once per period...
+	OutageDuration time.Duration // ...for its last OutageDuration, then catches up
+}
+
+// Enabled reports whether any fault can be injected
+func (c FaultConfig) Enabled() bool {
+	return c.DropRate > 0 || c.DuplicateRate > 0 || (c.OutageEvery > 0 && c.OutageDuration > 0)
+}
+
+// Validate checks the rates are probabilities and an outage fits its period
+func (c FaultConfig) Validate() error {
+	if c.DropRate < 0 || c.DropRate > 1 {
+		return fmt.Errorf("invalid producer drop rate %v (want 0-1)", c.DropRate)
+	}
+	if c.DuplicateRate < 0 || c.DuplicateRate > 1 {
+		return fmt.Errorf("invalid producer duplicate rate %v (want 0-1)", c.DuplicateRate)
+	}
+	if c.OutageEvery < 0 || c.OutageDuration < 0 {
+		return fmt.Errorf("invalid producer outage every (%s) or duration (%s)", c.OutageEvery, c.OutageDuration)
+	}
+	if c.OutageDuration > 0 && c.OutageDuration >= c.OutageEvery {
+		return fmt.Errorf("producer outage duration %s must be shorter than its period %s", c.OutageDuration, c.OutageEvery)
+	}
+	return nil
+}
+
+// FaultConfigFromEnv reads PRODUCER_DROP_RATE, PRODUCER_DUPLICATE_RATE,
+// PRODUCER_OUTAGE_EVERY and PRODUCER_OUTAGE_DURATION
+func FaultConfigFromEnv() (FaultConfig, error) {
+	var cfg FaultConfig
+	var err error
+	for env, field := range map[string]*float64{
+		"PRODUCER_DROP_RATE":      &cfg.DropRate,
+		"PRODUCER_DUPLICATE_RATE": &cfg.DuplicateRate,
+	} {
+		if v := os.Getenv(env); v != "" {
+			if *field, err = strconv.ParseFloat(v, 64); err != nil {
+				return FaultConfig{}, fmt.Errorf("invalid %s: %w", env, err)
+			}
+		}
+	}
+	for env, field := range map[string]*time.Duration{
+		"PRODUCER_OUTAGE_EVERY":    &cfg.OutageEvery,
+		"PRODUCER_OUTAGE_DURATION": &cfg.OutageDuration,
+	} {
+		if v := os.Getenv(env); v != "" {
+			if *field, err = time.ParseDuration(v); err != nil {
+				return FaultConfig{}, fmt.Errorf("invalid %s: %w", env, err)
+			}
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// Faults injects producer faults into the generators sharing it. Outages
+// follow one clock so every generator pauses together, as behind a shared
+// upstream. A nil *Faults injects nothing.
+type Faults struct {
+	config    FaultConfig
+	startedAt time.Time
+
+	mu         sync.Mutex
+	lastOutage int64 // Period of the last outage counted
+}
+
+// NewFaults starts the outage clock for config
+func NewFaults(config FaultConfig) *Faults {
+	return &Faults{config: config, startedAt: time.Now(), lastOutage: -1}
+}
+
+// Config returns the faults' config
+func (f *Faults) Config() FaultConfig {
+	if f == nil {
+		return FaultConfig{}
+	}
+	return f.config
+}
+
+// apply drops and duplicates msgs at the configured rates, counting both
+// into stats, and returns what is left to publish
+func (f *Faults) apply(msgs []*models.KafkaMessage, rng *rand.Rand, stats *Stats) []*models.KafkaMessage {
+	if f == nil || (f.config.DropRate <= 0 && f.config.DuplicateRate <= 0) {
+		return msgs
+	}
+
+	out := make([]*models.KafkaMessage, 0, len(msgs))
+	var dropped, duplicated int64
+	for _, msg := range msgs {
+		if f.config.DropRate > 0 && rng.Float64() < f.config.DropRate {
+			dropped++
+			continue
+		}
+		out = append(out, msg)
+		if f.config.DuplicateRate > 0 && rng.Float64() < f.config.DuplicateRate {
+			dup := *msg
+			out = append(out, &dup)
+			duplicated++
+		}
+	}
+
+	metrics.GeneratorFaults.WithLabelValues(FaultDropped).Add(float64(dropped))
+	metrics.GeneratorFaults.WithLabelValues(FaultDuplicated).Add(float64(duplicated))
+	if stats != nil {
+		stats.RecordFaults(dropped, duplicated)
+	}
+	return out
+}
+
+// waitOutage blocks while an outage is on, returning false if ctx ends
+// first. Events owed meanwhile are published in a burst once it is over.
+func (f *Faults) waitOutage(ctx context.Context, stats *Stats) bool {
+	if f == nil || f.config.OutageEvery <= 0 || f.config.OutageDuration <= 0 {
+		return true
+	}
+
+	elapsed := time.Since(f.startedAt)
+	period := int64(elapsed / f.config.OutageEvery)
+	into := elapsed % f.config.OutageEvery
+	if into < f.config.OutageEvery-f.config.OutageDuration {
+		return true
+	}
+
+	f.mu.Lock()
+	first := period > f.lastOutage
+	if first {
+		f.lastOutage = period
+	}
+	f.mu.Unlock()
+	if first {
+		metrics.GeneratorFaults.WithLabelValues(FaultOutage).Inc()
+		metrics.GeneratorOutage.Set(1)
+		if stats != nil {
+			stats.RecordOutage(f.config.OutageDuration)
+		}
+	}
+
+	timer := time.NewTimer(f.config.OutageEvery - into)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		metrics.GeneratorOutage.Set(0)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,245 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Profile describes the events produced by one upstream service
+type Profile struct {
+	Name          string // Selects the profile in event-generator's PROFILES
+	SourceService string
+	EventTypes    []models.EventType
+	Payload       func(eventType models.EventType) map[string]string
+	Segment       string   // Broadcast each event to this segment instead of one user
+	Topics        []string // Publish each event to one of these topics instead of one user
+}
+
+// Broadcast reports whether the profile's events are expanded into many
+// notifications by the consumer rather than addressed to one user
+func (p Profile) Broadcast() bool {
+	return p.Segment != "" || len(p.Topics) > 0
+}
+
+var jobTitles = []string{
+	"Senior Backend Engineer", "Data Scientist", "Product Manager", "Frontend Developer",
+	"DevOps Engineer", "ML Engineer", "QA Engineer", "Technical Lead",
+}
+
+var companies = []string{
+	"TechCorp", "DataCo", "StartupXYZ", "BigTech Inc", "InnovateAI", "CloudSystems",
+}
+
+var names = []string{
+	"John Doe", "Jane Smith", "Michael Brown", "Emily Davis",
+	"David Wilson", "Sarah Johnson", "Robert Lee", "Lisa Anderson",
+}
+
+var skills = []string{
+	"Go", "Python", "JavaScript", "React", "Docker", "Kubernetes",
+	"System Design", "Machine Learning", "Cloud Architecture",
+}
+
+var headlines = []string{
+	"Scheduled maintenance tonight from 02:00 to 03:00 UTC",
+	"New: save searches and get alerted to matching jobs",
+	"We've updated our privacy policy",
+	"Your year in review is ready",
+}
+
+var followerNames = []string{
+	"Alice Williams", "Bob Martin", "Carol White", "Daniel Harris",
+	"Eva Thompson", "Frank Garcia", "Grace Martinez", "Henry Robinson",
+}
+
+// JobProfile is the event mix of the job service
+var JobProfile = Profile{
+	Name:          "job",
+	SourceService: "job-service",
+	EventTypes: []models.EventType{
+		models.EventJobNew,
+		models.EventJobUpdate,
+		models.EventJobApplicationViewed,
+		models.EventJobApplicationStatus,
+	},
+	Payload: func(eventType models.EventType) map[string]string {
+		payload := make(map[string]string)
+		switch eventType {
+		case models.EventJobNew:
+			payload["job_title"] = jobTitles[rand.Intn(len(jobTitles))]
+			payload["company_name"] = companies[rand.Intn(len(companies))]
+			payload["location"] = "Remote"
+		case models.EventJobUpdate:
+			payload["job_title"] = jobTitles[rand.Intn(len(jobTitles))]
+			payload["company_name"] = companies[rand.Intn(len(companies))]
+			payload["update_type"] = "salary_updated"
+		case models.EventJobApplicationViewed:
+			payload["company_name"] = companies[rand.Intn(len(companies))]
+			payload["job_title"] = jobTitles[rand.Intn(len(jobTitles))]
+			payload["recruiter_name"] = "John Doe"
+		case models.EventJobApplicationStatus:
+			payload["company_name"] = companies[rand.Intn(len(companies))]
+			payload["status"] = "interview_scheduled"
+		}
+		return payload
+	},
+}
+
+// ConnectionsProfile is the event mix of the connections service
+var ConnectionsProfile = Profile{
+	Name:          "connections",
+	SourceService: "connections-service",
+	EventTypes: []models.EventType{
+		models.EventConnectionRequest,
+		models.EventConnectionAccepted,
+		models.EventConnectionEndorsed,
+	},
+	Payload: func(eventType models.EventType) map[string]string {
+		payload := make(map[string]string)
+		switch eventType {
+		case models.EventConnectionRequest:
+			payload["from"] = names[rand.Intn(len(names))]
+			payload["headline"] = "Software Engineer at TechCorp"
+		case models.EventConnectionAccepted:
+			payload["from"] = names[rand.Intn(len(names))]
+		case models.EventConnectionEndorsed:
+			payload["from"] = names[rand.Intn(len(names))]
+			payload["skill"] = skills[rand.Intn(len(skills))]
+		}
+		return payload
+	},
+}
+
+// FollowersProfile is the event mix of the followers service
+var FollowersProfile = Profile{
+	Name:          "followers",
+	SourceService: "followers-service",
+	EventTypes: []models.EventType{
+		models.EventFollowerNew,
+		models.EventFollowerContentLiked,
+		models.EventFollowerContentComment,
+	},
+	Payload: func(eventType models.EventType) map[string]string {
+		payload := make(map[string]string)
+		switch eventType {
+		case models.EventFollowerNew:
+			payload["follower_id"] = fmt.Sprintf("user_%d", rand.Intn(10000)+1)
+			payload["follower_name"] = followerNames[rand.Intn(len(followerNames))]
+			payload["follower_headline"] = "Product Manager at StartupXYZ"
+		case models.EventFollowerContentLiked:
+			payload["liker_name"] = followerNames[rand.Intn(len(followerNames))]
+			payload["content_title"] = "My thoughts on distributed systems"
+		case models.EventFollowerContentComment:
+			payload["commenter_name"] = followerNames[rand.Intn(len(followerNames))]
+			payload["content_title"] = "Building scalable services with Go"
+			payload["comment_preview"] = "Great insights!"
+		}
+		return payload
+	},
+}
+
+// AnnouncementsProfile broadcasts product announcements to every
+// connected user
+var AnnouncementsProfile = Profile{
+	Name:          "announcements",
+	SourceService: "announcements-service",
+	EventTypes:    []models.EventType{models.EventSystemAnnouncement},
+	Payload: func(eventType models.EventType) map[string]string {
+		return map[string]string{"headline": headlines[rand.Intn(len(headlines))]}
+	},
+	Segment: models.SegmentAll,
+}
+
+// alertTopics are the topics JobAlertsProfile publishes to
+var alertTopics = []string{
+	"job-alerts:golang", "job-alerts:python", "job-alerts:react",
+	"job-alerts:kubernetes", "job-alerts:machine-learning",
+}
+
+// JobAlertsProfile publishes new jobs to job-alerts:<skill> topics, for
+// the users subscribed to them
+var JobAlertsProfile = Profile{
+	Name:          "job-alerts",
+	SourceService: "job-alerts-service",
+	EventTypes:    []models.EventType{models.EventJobNew},
+	Payload: func(eventType models.EventType) map[string]string {
+		return map[string]string{
+			"job_title":    jobTitles[rand.Intn(len(jobTitles))],
+			"company_name": companies[rand.Intn(len(companies))],
+			"location":     "Remote",
+		}
+	},
+	Topics: alertTopics,
+}
+
+// Profiles lists all built-in profiles
+var Profiles = []Profile{JobProfile, ConnectionsProfile, FollowersProfile}
+
+// BroadcastProfiles are only produced when asked for by name: each of their
+// events fans out to many users, so they'd dwarf the per-user traffic
+var BroadcastProfiles = []Profile{AnnouncementsProfile, JobAlertsProfile}
+
+// ProfileRate is a profile and its base events per second
+type ProfileRate struct {
+	Profile Profile
+	Rate    float64
+}
+
+// ParseProfileRates parses comma-separated name=rate entries, e.g.
+// "job=100,connections=50,followers=75". Profiles left out are not produced.
+func ParseProfileRates(raw string) ([]ProfileRate, error) {
+	var rates []ProfileRate
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rateStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid profile %q (want name=rate)", entry)
+		}
+		profile, ok := ProfileByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("profile %q listed twice", name)
+		}
+		seen[name] = true
+
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid profile %q: rate must be a non-negative number", entry)
+		}
+		rates = append(rates, ProfileRate{Profile: profile, Rate: rate})
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no profiles given")
+	}
+	return rates, nil
+}
+
+// ProfileByName looks up a built-in or broadcast profile
+func ProfileByName(name string) (Profile, bool) {
+	for _, p := range append(Profiles, BroadcastProfiles...) {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// TenantFor maps a simulated user index to its tenant (tenant_<index mod
+// numTenants>) so producers and sse-bench agree on the population. With
+// numTenants <= 1 everything belongs to the default tenant.
+func TenantFor(userIndex, numTenants int) string {
+	if numTenants <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("tenant_%d", userIndex%numTenants)
+}
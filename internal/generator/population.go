@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Population is the set of simulated user IDs producers target and sse-bench
+// connects as. Members are numbered 1..Len(); member k belongs to
+// TenantFor(k, numTenants) so every process agrees on the tenant split.
+type Population struct {
+	prefix string
+	size   int
+	ids    []string // Loaded IDs; nil for a sequential population
+}
+
+// SequentialPopulation is prefix1..prefixN (user_1..user_N for the built-in
+// producers)
+func SequentialPopulation(prefix string, size int) *Population {
+	return &Population{prefix: prefix, size: size}
+}
+
+// LoadPopulation reads one user ID per line (UUIDs, emails, anything
+// ValidateUserID accepts). Blank lines and lines starting with # are skipped.
+func LoadPopulation(path string) (*Population, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user population: %w", err)
+	}
+	defer f.Close()
+
+	var ids []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+		if err := models.ValidateUserID(id); err != nil {
+			return nil, fmt.Errorf("invalid user id on line %d of %s: %w", line, path, err)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate user id %q on line %d of %s", id, line, path)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user population: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("user population %s is empty", path)
+	}
+	return &Population{ids: ids, size: len(ids)}, nil
+}
+
+// NewPopulation loads path when set, otherwise returns the sequential
+// prefix1..prefixN population
+func NewPopulation(path, prefix string, size int) (*Population, error) {
+	if path != "" {
+		return LoadPopulation(path)
+	}
+	if size < 1 {
+		return nil, fmt.Errorf("invalid user count %d", size)
+	}
+	return SequentialPopulation(prefix, size), nil
+}
+
+// Len returns the number of members
+func (p *Population) Len() int {
+	return p.size
+}
+
+// Sequential reports whether IDs are generated from a prefix rather than loaded
+func (p *Population) Sequential() bool {
+	return p.ids == nil
+}
+
+// UserID returns member k (1-based)
+func (p *Population) UserID(k int) string {
+	if p.ids != nil {
+		return p.ids[k-1]
+	}
+	return p.prefix + strconv.Itoa(k)
+}
+
+// Random picks a member uniformly and returns its number and ID
+func (p *Population) Random() (int, string) {
+	k := rand.Intn(p.size) + 1
+	return k, p.UserID(k)
+}
+
+// Truncate keeps the first n members (all of them when n covers the population)
+func (p *Population) Truncate(n int) *Population {
+	if n <= 0 || n >= p.size {
+		return p
+	}
+	truncated := *p
+	truncated.size = n
+	if p.ids != nil {
+		truncated.ids = p.ids[:n]
+	}
+	return &truncated
+}
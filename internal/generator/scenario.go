@@ -0,0 +1,513 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/producer"
+)
+
+// Scenario shapes generated traffic: the event mix, which users receive it
+// and how the rate moves over time. The zero value is a flat rate with every
+// profile event type equally likely, sent to uniformly random users.
+type Scenario struct {
+	Rate    int                `yaml:"rate"`   // Base events per second (0 = the producer's own rate)
+	Events  map[string]float64 `yaml:"events"` // Event type → relative weight; unlisted types are not produced
+	Users   UserDistribution   `yaml:"users"`
+	Bursts  []Burst            `yaml:"bursts"`
+	Diurnal *DiurnalCurve      `yaml:"diurnal"`
+	Ramp    *Ramp              `yaml:"ramp"`
+
+	// Pad every payload with a prose "body" field to about this many bytes,
+	// for large-payload (e.g. compression) runs; 0 leaves payloads as is
+	PayloadBytes int `yaml:"payload_bytes"`
+}
+
+// UserDistribution picks the recipient of each event
+type UserDistribution struct {
+	Distribution string  `yaml:"distribution"` // uniform (default) or zipf
+	Exponent     float64 `yaml:"exponent"`     // Zipf s, > 1 (default 1.1); higher concentrates traffic on fewer users
+}
+
+// User distributions
+const (
+	DistributionUniform = "uniform"
+	DistributionZipf    = "zipf"
+)
+
+// Burst multiplies the rate for Duration at the start of every Every, or
+// once when Every is 0
+type Burst struct {
+	Every      time.Duration `yaml:"every"`
+	Duration   time.Duration `yaml:"duration"`
+	Multiplier float64       `yaml:"multiplier"`
+	Offset     time.Duration `yaml:"offset"` // Delay before the first burst
+}
+
+// active reports whether the burst is on elapsed into the run
+func (b Burst) active(elapsed time.Duration) bool {
+	if elapsed < b.Offset {
+		return false
+	}
+	if b.Every == 0 {
+		return elapsed-b.Offset < b.Duration
+	}
+	return (elapsed-b.Offset)%b.Every < b.Duration
+}
+
+// Ramp moves the rate multiplier linearly from From to To over Duration,
+// starting Offset into the run, and holds it at To afterwards. With rate 100,
+// to 50 ramps from 100 to 5000 events per second.
+type Ramp struct {
+	From     float64       `yaml:"from"` // Default 1
+	To       float64       `yaml:"to"`
+	Duration time.Duration `yaml:"duration"`
+	Offset   time.Duration `yaml:"offset"`
+}
+
+// at is the ramp's multiplier elapsed into the run
+func (r *Ramp) at(elapsed time.Duration) float64 {
+	progress := float64(elapsed-r.Offset) / float64(r.Duration)
+	progress = min(max(progress, 0), 1)
+	return r.From + (r.To-r.From)*progress
+}
+
+// DiurnalCurve scales the rate along a cosine between Trough and Peak.
+// Shorten Period to compress a day into a benchmark run.
+type DiurnalCurve struct {
+	Period time.Duration `yaml:"period"`  // Default 24h
+	PeakAt time.Duration `yaml:"peak_at"` // Time from the start of the run to the first peak
+	Peak   float64       `yaml:"peak"`    // Rate multiplier at the peak (default 1)
+	Trough float64       `yaml:"trough"`  // Rate multiplier at the trough
+}
+
+// LoadScenario reads a YAML scenario; unknown keys are rejected so typos
+// don't silently fall back to defaults
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var s Scenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// NewScenario loads path, or returns the default scenario when path is empty
+func NewScenario(path string) (*Scenario, error) {
+	if path == "" {
+		return &Scenario{}, nil
+	}
+	return LoadScenario(path)
+}
+
+func (s *Scenario) validate() error {
+	if s.Rate < 0 {
+		return fmt.Errorf("rate %d must not be negative", s.Rate)
+	}
+	if s.PayloadBytes < 0 {
+		return fmt.Errorf("payload_bytes %d must not be negative", s.PayloadBytes)
+	}
+
+	known := make(map[string]bool)
+	total := 0.0
+	for _, p := range Profiles {
+		for _, eventType := range p.EventTypes {
+			known[string(eventType)] = true
+		}
+		total += s.Weight(p)
+	}
+	if total == 0 {
+		return fmt.Errorf("events: at least one weight must be positive")
+	}
+	for eventType, weight := range s.Events {
+		if !known[eventType] {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+		if weight < 0 {
+			return fmt.Errorf("event %q has negative weight %g", eventType, weight)
+		}
+	}
+
+	switch s.Users.Distribution {
+	case "", DistributionUniform:
+	case DistributionZipf:
+		if s.Users.Exponent == 0 {
+			s.Users.Exponent = 1.1
+		}
+		if s.Users.Exponent <= 1 {
+			return fmt.Errorf("zipf exponent %g must be greater than 1", s.Users.Exponent)
+		}
+	default:
+		return fmt.Errorf("unknown user distribution %q (want uniform or zipf)", s.Users.Distribution)
+	}
+
+	for i, b := range s.Bursts {
+		if b.Every < 0 || b.Duration <= 0 || (b.Every > 0 && b.Duration > b.Every) {
+			return fmt.Errorf("burst %d: want 0 < duration <= every (or every 0 for a single burst)", i)
+		}
+		if b.Multiplier < 0 || b.Offset < 0 {
+			return fmt.Errorf("burst %d: multiplier and offset must not be negative", i)
+		}
+	}
+
+	if d := s.Diurnal; d != nil {
+		if d.Period == 0 {
+			d.Period = 24 * time.Hour
+		}
+		if d.Peak == 0 {
+			d.Peak = 1
+		}
+		if d.Period < 0 || d.Trough < 0 || d.Trough > d.Peak {
+			return fmt.Errorf("diurnal: want a positive period and 0 <= trough <= peak")
+		}
+	}
+
+	if r := s.Ramp; r != nil {
+		if r.From == 0 {
+			r.From = 1
+		}
+		if r.Duration <= 0 || r.From < 0 || r.To < 0 || r.Offset < 0 {
+			return fmt.Errorf("ramp: want a positive duration and non-negative from, to and offset")
+		}
+	}
+	return nil
+}
+
+// weights returns p's event types the scenario produces and their weights
+func (s *Scenario) weights(p Profile) ([]models.EventType, []float64) {
+	var types []models.EventType
+	var weights []float64
+	for _, eventType := range p.EventTypes {
+		weight := 1.0
+		if len(s.Events) > 0 {
+			weight = s.Events[string(eventType)]
+		}
+		if weight > 0 {
+			types = append(types, eventType)
+			weights = append(weights, weight)
+		}
+	}
+	return types, weights
+}
+
+// Weight is the total weight of p's event types, for splitting one rate
+// across profiles
+func (s *Scenario) Weight(p Profile) float64 {
+	_, weights := s.weights(p)
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	return total
+}
+
+// Multiplier is the rate multiplier elapsed into the run: the diurnal curve
+// times the ramp times every active burst
+func (s *Scenario) Multiplier(elapsed time.Duration) float64 {
+	m := 1.0
+	if d := s.Diurnal; d != nil {
+		phase := 2 * math.Pi * float64(elapsed-d.PeakAt) / float64(d.Period)
+		m = d.Trough + (d.Peak-d.Trough)*(1+math.Cos(phase))/2
+	}
+	if s.Ramp != nil {
+		m *= s.Ramp.at(elapsed)
+	}
+	for _, b := range s.Bursts {
+		if b.active(elapsed) {
+			m *= b.Multiplier
+		}
+	}
+	return m
+}
+
+// activeBursts counts the bursts on elapsed into the run
+func (s *Scenario) activeBursts(elapsed time.Duration) int {
+	n := 0
+	for _, b := range s.Bursts {
+		if b.active(elapsed) {
+			n++
+		}
+	}
+	return n
+}
+
+const (
+	// schedulePoll is how often RunSchedule samples the rate curve
+	schedulePoll = 100 * time.Millisecond
+
+	// scheduleLogChange is the relative rate change that RunSchedule logs
+	scheduleLogChange = 0.05
+)
+
+// RunSchedule publishes the target rate (baseRate times the multiplier) as
+// the notification_generator_target_rate gauge and logs it whenever a burst
+// starts or ends or it has moved 5% since last logged, so latency in reports
+// can be lined up with the load that caused it. Runs until ctx is done.
+func (s *Scenario) RunSchedule(ctx context.Context, baseRate float64, logger *zap.Logger) {
+	ticker := time.NewTicker(schedulePoll)
+	defer ticker.Stop()
+
+	start := time.Now()
+	loggedRate, loggedBursts := -1.0, -1
+	for {
+		elapsed := time.Since(start)
+		multiplier := s.Multiplier(elapsed)
+		rate := baseRate * multiplier
+		bursts := s.activeBursts(elapsed)
+		metrics.GeneratorTargetRate.Set(rate)
+		metrics.GeneratorActiveBursts.Set(float64(bursts))
+
+		if bursts != loggedBursts || math.Abs(rate-loggedRate) > scheduleLogChange*loggedRate {
+			logger.Info("rate schedule",
+				zap.Duration("elapsed", elapsed.Round(time.Millisecond)),
+				zap.Float64("target_rate", rate),
+				zap.Float64("multiplier", multiplier),
+				zap.Int("active_bursts", bursts))
+			loggedRate, loggedBursts = rate, bursts
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Generator produces one profile's events under a scenario
+type Generator struct {
+	profile    Profile
+	scenario   *Scenario
+	rate       float64 // Base events per second
+	types      []models.EventType
+	cumulative []float64 // Running sum of the types' weights
+	users      *Population
+	numTenants int
+	rng        *rand.Rand
+	zipf       *rand.Zipf // nil for uniform users
+	stats      *Stats     // Optional, shared with other generators
+	faults     *Faults    // Optional, shared with other generators
+}
+
+// NewGenerator builds a generator for p at rate base events per second, or
+// returns nil when the scenario gives p no events
+func (s *Scenario) NewGenerator(p Profile, rate float64, users *Population, numTenants int) *Generator {
+	types, weights := s.weights(p)
+	if len(types) == 0 {
+		return nil
+	}
+
+	g := &Generator{
+		profile:    p,
+		scenario:   s,
+		rate:       rate,
+		types:      types,
+		cumulative: make([]float64, len(weights)),
+		users:      users,
+		numTenants: numTenants,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	total := 0.0
+	for i, w := range weights {
+		total += w
+		g.cumulative[i] = total
+	}
+	if s.Users.Distribution == DistributionZipf && users.Len() > 1 {
+		g.zipf = rand.NewZipf(g.rng, s.Users.Exponent, 1, uint64(users.Len()-1))
+	}
+	return g
+}
+
+// NewMessage builds the next event: a weighted event type for a user drawn
+// from the scenario's distribution (with zipf, member 1 is the busiest), or
+// for a broadcast profile its segment or one of its topics, in that user's
+// tenant
+func (g *Generator) NewMessage() *models.KafkaMessage {
+	r := g.rng.Float64() * g.cumulative[len(g.cumulative)-1]
+	eventType := g.types[len(g.types)-1]
+	for i, c := range g.cumulative {
+		if r < c {
+			eventType = g.types[i]
+			break
+		}
+	}
+
+	userIndex, userID := g.users.Random()
+	if g.zipf != nil {
+		userIndex = int(g.zipf.Uint64()) + 1
+		userID = g.users.UserID(userIndex)
+	}
+
+	payload := g.profile.Payload(eventType)
+	if g.scenario.PayloadBytes > 0 {
+		g.pad(payload, g.scenario.PayloadBytes)
+	}
+
+	msg := &models.KafkaMessage{
+		EventID:        idgen.NewID().String(),
+		TenantID:       TenantFor(userIndex, g.numTenants),
+		EventType:      string(eventType),
+		Priority:       string(models.GetPriorityForEventType(eventType)),
+		UserID:         userID,
+		EventTimestamp: time.Now(),
+		Payload:        payload,
+		Metadata: models.Metadata{
+			SourceService: g.profile.SourceService,
+			TraceID:       idgen.NewID().String(),
+		},
+	}
+	switch {
+	case g.profile.Segment != "":
+		msg.UserID, msg.Segment = "", g.profile.Segment
+	case len(g.profile.Topics) > 0:
+		msg.UserID, msg.Topic = "", g.profile.Topics[g.rng.Intn(len(g.profile.Topics))]
+	}
+	return msg
+}
+
+// padWords make up padded payload bodies: random picks compress like text
+// rather than like either noise or one repeated string
+var padWords = strings.Fields(`the team is hiring engineers to build reliable
+systems for millions of members who share updates about their work and careers
+your profile was viewed by recruiters from companies in technology finance and
+healthcare looking for people with experience in distributed systems data
+platforms and product design congratulations on the new role many of your
+connections have endorsed your skills and commented on your latest post`)
+
+// pad adds a "body" field of random words so the payload's values total
+// about size bytes
+func (g *Generator) pad(payload map[string]string, size int) {
+	for key, value := range payload {
+		size -= len(key) + len(value)
+	}
+	var body strings.Builder
+	for body.Len() < size {
+		if body.Len() > 0 {
+			body.WriteByte(' ')
+		}
+		body.WriteString(padWords[g.rng.Intn(len(padWords))])
+	}
+	if body.Len() > 0 {
+		payload["body"] = body.String()
+	}
+}
+
+// SetStats records every publish into stats
+func (g *Generator) SetStats(stats *Stats) {
+	g.stats = stats
+}
+
+// SetFaults injects producer faults into every publish
+func (g *Generator) SetFaults(faults *Faults) {
+	g.faults = faults
+}
+
+const (
+	// generatorTick is how often Run tops up the events owed at the current rate
+	generatorTick = 10 * time.Millisecond
+
+	// maxGeneratorBatch caps the events Run hands a BatchPublisher per call
+	maxGeneratorBatch = 500
+)
+
+// Run publishes events until ctx is done, following the scenario's rate
+// curve. Events owed in one tick go out as a batch when pub supports it.
+func (g *Generator) Run(ctx context.Context, pub producer.Publisher, logger *zap.Logger) {
+	batcher, _ := pub.(producer.BatchPublisher)
+
+	ticker := time.NewTicker(generatorTick)
+	defer ticker.Stop()
+
+	logger.Info("event generator started",
+		zap.String("source_service", g.profile.SourceService),
+		zap.Float64("event_rate", g.rate),
+		zap.Int("event_types", len(g.types)),
+		zap.String("user_distribution", g.scenario.Users.Distribution),
+		zap.Int("bursts", len(g.scenario.Bursts)),
+		zap.Bool("diurnal", g.scenario.Diurnal != nil),
+		zap.Bool("ramp", g.scenario.Ramp != nil),
+		zap.Int("num_users", g.users.Len()),
+		zap.Int("num_tenants", g.numTenants))
+
+	start := time.Now()
+	last := start
+	owed := 0.0
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("event generator stopped", zap.String("source_service", g.profile.SourceService))
+			return
+		case now := <-ticker.C:
+			owed += g.rate * g.scenario.Multiplier(now.Sub(start)) * now.Sub(last).Seconds()
+			last = now
+			for owed >= 1 && ctx.Err() == nil {
+				n := 1
+				if batcher != nil {
+					n = min(int(owed), maxGeneratorBatch)
+				}
+				g.publish(ctx, pub, batcher, n, logger)
+				owed -= float64(n)
+			}
+		}
+	}
+}
+
+// publish sends n new events, through batcher when it is set. Injected
+// faults apply first: an outage holds the publish back, and dropped events
+// never reach pub.
+func (g *Generator) publish(ctx context.Context, pub producer.Publisher, batcher producer.BatchPublisher, n int, logger *zap.Logger) {
+	if !g.faults.waitOutage(ctx, g.stats) {
+		return
+	}
+	msgs := make([]*models.KafkaMessage, n)
+	for i := range msgs {
+		msgs[i] = g.NewMessage()
+	}
+	msgs = g.faults.apply(msgs, g.rng, g.stats)
+	if len(msgs) == 0 {
+		return
+	}
+
+	var err error
+	if batcher != nil {
+		err = batcher.PublishBatch(ctx, msgs)
+	} else {
+		for _, msg := range msgs {
+			if err = pub.PublishNotification(ctx, msg); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		logger.Error("failed to publish events", zap.Int("count", len(msgs)), zap.Error(err))
+	}
+	if g.stats != nil {
+		for _, msg := range msgs {
+			g.stats.Record(g.profile.Name, msg, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Stats counts the events generators publish, shared by all of a process's
+// generators
+type Stats struct {
+	mu          sync.Mutex
+	startedAt   time.Time
+	published   int64
+	failed      int64
+	undelivered int64 // Queued by an async producer, then failed
+	dropped     int64 // Lost to an injected fault before reaching the producer
+	duplicated  int64 // Extra copies published by an injected fault, counted in published too
+	outages     int64
+	outageTime  time.Duration
+	byProfile   map[string]int64
+	byEventType map[string]int64
+	byPriority  map[string]int64
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		startedAt:   time.Now(),
+		byProfile:   make(map[string]int64),
+		byEventType: make(map[string]int64),
+		byPriority:  make(map[string]int64),
+	}
+}
+
+// Record counts one publish attempt
+func (s *Stats) Record(profile string, msg *models.KafkaMessage, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.failed++
+		return
+	}
+	s.published++
+	s.byProfile[profile]++
+	s.byEventType[msg.EventType]++
+	s.byPriority[msg.Priority]++
+}
+
+// RecordUndelivered counts an event an async producer accepted but failed
+// to deliver; it stays counted as published
+func (s *Stats) RecordUndelivered() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.undelivered++
+}
+
+// RecordFaults counts events dropped and duplicated by injected faults
+func (s *Stats) RecordFaults(dropped, duplicated int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped += dropped
+	s.duplicated += duplicated
+}
+
+// RecordOutage counts a simulated producer outage of length d
+func (s *Stats) RecordOutage(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outages++
+	s.outageTime += d
+}
+
+// StatsSummary is a point-in-time copy of Stats
+type StatsSummary struct {
+	Elapsed     time.Duration
+	Published   int64
+	Failed      int64
+	Undelivered int64
+	Dropped     int64
+	Duplicated  int64
+	Outages     int64
+	OutageTime  time.Duration
+	Rate        float64 // Published events per second since start
+	ByProfile   map[string]int64
+	ByEventType map[string]int64
+	ByPriority  map[string]int64
+}
+
+// Summary copies the counters
+func (s *Stats) Summary() StatsSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.startedAt)
+	sum := StatsSummary{
+		Elapsed:     elapsed,
+		Published:   s.published,
+		Failed:      s.failed,
+		Undelivered: s.undelivered,
+		Dropped:     s.dropped,
+		Duplicated:  s.duplicated,
+		Outages:     s.outages,
+		OutageTime:  s.outageTime,
+		ByProfile:   make(map[string]int64, len(s.byProfile)),
+		ByEventType: make(map[string]int64, len(s.byEventType)),
+		ByPriority:  make(map[string]int64, len(s.byPriority)),
+	}
+	if elapsed > 0 {
+		sum.Rate = float64(s.published) / elapsed.Seconds()
+	}
+	for k, v := range s.byProfile {
+		sum.ByProfile[k] = v
+	}
+	for k, v := range s.byEventType {
+		sum.ByEventType[k] = v
+	}
+	for k, v := range s.byPriority {
+		sum.ByPriority[k] = v
+	}
+	return sum
+}
+
+// Unique is the number of distinct event IDs published: injected duplicates
+// share their original's. The consumer doesn't deduplicate by event ID, so
+// the pipeline persists Published notifications, not Unique.
+func (sum StatsSummary) Unique() int64 {
+	return sum.Published - sum.Duplicated
+}
+
+// Log writes the summary as one structured line
+func (s *Stats) Log(logger *zap.Logger, msg string) {
+	sum := s.Summary()
+	fields := []zap.Field{
+		zap.Duration("elapsed", sum.Elapsed.Round(time.Second)),
+		zap.Int64("published", sum.Published),
+		zap.Int64("failed", sum.Failed),
+		zap.Int64("undelivered", sum.Undelivered),
+		zap.Float64("events_per_sec", sum.Rate),
+		zap.Any("by_profile", sum.ByProfile),
+		zap.Any("by_event_type", sum.ByEventType),
+		zap.Any("by_priority", sum.ByPriority),
+	}
+	if sum.Dropped > 0 || sum.Duplicated > 0 || sum.Outages > 0 {
+		fields = append(fields,
+			zap.Int64("unique_published", sum.Unique()),
+			zap.Int64("fault_dropped", sum.Dropped),
+			zap.Int64("fault_duplicated", sum.Duplicated),
+			zap.Int64("fault_outages", sum.Outages),
+			zap.Duration("fault_outage_time", sum.OutageTime))
+	}
+	logger.Info(msg, fields...)
+}
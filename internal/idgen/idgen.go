@@ -0,0 +1,201 @@
+package idgen
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Strategy names accepted by New
+const (
+	StrategyUUIDv4     = "uuidv4"
+	StrategyUUIDv7     = "uuidv7"
+	StrategyULID       = "ulid"
+	StrategySnowflake  = "snowflake"
+	StrategySequential = "sequential"
+)
+
+// Generator produces notification IDs. Every strategy yields a 16-byte
+// value stored in the UUID primary key column; the time-ordered ones
+// (uuidv7, ulid, snowflake) keep B-tree inserts append-mostly.
+type Generator interface {
+	NewID() uuid.UUID
+	Name() string
+}
+
+// New returns the generator for the given strategy. nodeID identifies the
+// instance for snowflake (0-1023) and sequential IDs and is ignored by other
+// strategies.
+func New(strategy string, nodeID int64) (Generator, error) {
+	switch strategy {
+	case "", StrategyUUIDv4:
+		return uuidV4{}, nil
+	case StrategyUUIDv7:
+		return uuidV7{}, nil
+	case StrategyULID:
+		return &ulid{}, nil
+	case StrategySnowflake:
+		if nodeID < 0 || nodeID > maxNodeID {
+			return nil, fmt.Errorf("snowflake node id must be in [0, %d], got %d", maxNodeID, nodeID)
+		}
+		return &snowflake{nodeID: nodeID}, nil
+	case StrategySequential:
+		return NewSequential(nodeID), nil
+	default:
+		return nil, fmt.Errorf("unknown id strategy: %q", strategy)
+	}
+}
+
+// defaultGen backs NewID; see SetDefault
+var defaultGen atomic.Pointer[Generator]
+
+// SetDefault makes g the process-wide generator behind NewID. Binaries set
+// it once from the configured strategy so every ID they mint (connections,
+// probes, generated events) follows it.
+func SetDefault(g Generator) {
+	defaultGen.Store(&g)
+}
+
+// Default returns the process-wide generator, uuidv4 until SetDefault
+func Default() Generator {
+	if g := defaultGen.Load(); g != nil {
+		return *g
+	}
+	return uuidV4{}
+}
+
+// NewID returns an ID from the process-wide generator
+func NewID() uuid.UUID {
+	return Default().NewID()
+}
+
+// uuidV4 generates random UUIDs (the historical default)
+type uuidV4 struct{}
+
+func (uuidV4) NewID() uuid.UUID { return uuid.New() }
+func (uuidV4) Name() string     { return StrategyUUIDv4 }
+
+// uuidV7 generates RFC 9562 time-ordered UUIDs
+type uuidV7 struct{}
+
+func (uuidV7) NewID() uuid.UUID { return uuid.Must(uuid.NewV7()) }
+func (uuidV7) Name() string     { return StrategyUUIDv7 }
+
+// ulid generates ULIDs (48-bit ms timestamp + 80 random bits) in binary form.
+// IDs within the same millisecond increment the random part so they stay
+// monotonic.
+type ulid struct {
+	mu       sync.Mutex
+	lastMS   uint64
+	lastRand [10]byte
+}
+
+func (g *ulid) Name() string { return StrategyULID }
+
+func (g *ulid) NewID() uuid.UUID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMS {
+		ms = g.lastMS
+		incrementBytes(g.lastRand[:])
+	} else {
+		g.lastMS = ms
+		_, _ = crand.Read(g.lastRand[:])
+	}
+
+	var id uuid.UUID
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	copy(id[6:], g.lastRand[:])
+	return id
+}
+
+func incrementBytes(b []byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+const (
+	snowflakeEpoch = 1704067200000 // 2024-01-01T00:00:00Z in ms
+	nodeBits       = 10
+	sequenceBits   = 12
+	maxNodeID      = 1<<nodeBits - 1
+	maxSequence    = 1<<sequenceBits - 1
+)
+
+// snowflake generates 64-bit IDs (41-bit ms timestamp, 10-bit node,
+// 12-bit sequence) stored big-endian in the first 8 bytes of the UUID
+type snowflake struct {
+	mu       sync.Mutex
+	nodeID   int64
+	lastMS   int64
+	sequence int64
+}
+
+func (g *snowflake) Name() string { return StrategySnowflake }
+
+func (g *snowflake) NewID() uuid.UUID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Now().UnixMilli() - snowflakeEpoch
+	if ms < g.lastMS {
+		ms = g.lastMS // Clock moved backwards; stay on the last tick
+	}
+
+	if ms == g.lastMS {
+		g.sequence = (g.sequence + 1) & maxSequence
+		if g.sequence == 0 {
+			// Sequence exhausted for this millisecond, wait for the next one
+			for ms <= g.lastMS {
+				time.Sleep(100 * time.Microsecond)
+				ms = time.Now().UnixMilli() - snowflakeEpoch
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMS = ms
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], uint64(ms<<(nodeBits+sequenceBits)|g.nodeID<<sequenceBits|g.sequence))
+	return id
+}
+
+// sequential generates deterministic IDs: nodeID in the first 8 bytes and a
+// counter starting at 1 in the last 8, so the same run mints the same IDs.
+// For tests and reproducible runs only: a restart reuses IDs.
+type sequential struct {
+	nodeID int64
+	next   atomic.Uint64
+}
+
+// NewSequential returns a deterministic generator whose IDs are
+// 00000000-0000-<nodeID>-<counter>
+func NewSequential(nodeID int64) Generator {
+	return &sequential{nodeID: nodeID}
+}
+
+func (g *sequential) Name() string { return StrategySequential }
+
+func (g *sequential) NewID() uuid.UUID {
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], uint64(g.nodeID))
+	binary.BigEndian.PutUint64(id[8:], g.next.Add(1))
+	return id
+}
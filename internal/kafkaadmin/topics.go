@@ -0,0 +1,211 @@
+// Package kafkaadmin creates and scales the Kafka topics the system uses.
+// Topics auto-created by producers get the broker default of one partition,
+// which caps every consumer group at a single active reader.
+package kafkaadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// TopicSpec is the layout a topic should have
+type TopicSpec struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+}
+
+// ParseTopicSpecs parses comma-separated name[:partitions[:replication]]
+// entries, e.g. "notifications:24:3,notification-metrics". Missing fields
+// take the given defaults.
+func ParseTopicSpecs(raw string, partitions, replication int) ([]TopicSpec, error) {
+	var specs []TopicSpec
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid topic %q (want name[:partitions[:replication]])", entry)
+		}
+
+		spec := TopicSpec{Name: parts[0], Partitions: partitions, ReplicationFactor: replication}
+		var err error
+		if len(parts) > 1 {
+			if spec.Partitions, err = strconv.Atoi(parts[1]); err != nil {
+				return nil, fmt.Errorf("invalid topic %q: bad partition count", entry)
+			}
+		}
+		if len(parts) > 2 {
+			if spec.ReplicationFactor, err = strconv.Atoi(parts[2]); err != nil {
+				return nil, fmt.Errorf("invalid topic %q: bad replication factor", entry)
+			}
+		}
+		if err := spec.validate(); err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (s TopicSpec) validate() error {
+	if s.Partitions < 1 || s.ReplicationFactor < 1 {
+		return fmt.Errorf("invalid topic %q: partitions (%d) and replication factor (%d) must be positive", s.Name, s.Partitions, s.ReplicationFactor)
+	}
+	return nil
+}
+
+// TopicInfo describes an existing topic
+type TopicInfo struct {
+	Name              string `json:"name"`
+	Partitions        int    `json:"partitions"`
+	ReplicationFactor int    `json:"replication_factor"`
+}
+
+// Admin creates, describes and scales topics through any reachable broker
+type Admin struct {
+	client *kafka.Client
+	logger *zap.Logger
+}
+
+// New creates an admin client for the given brokers
+func New(brokers []string, logger *zap.Logger) *Admin {
+	return &Admin{
+		client: &kafka.Client{
+			Addr:    kafka.TCP(brokers...),
+			Timeout: 30 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+// Describe returns the layout of the named topics; missing topics are left
+// out
+func (a *Admin) Describe(ctx context.Context, names ...string) ([]TopicInfo, error) {
+	meta, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: names})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+
+	var topics []TopicInfo
+	for _, t := range meta.Topics {
+		if errors.Is(t.Error, kafka.UnknownTopicOrPartition) {
+			continue
+		}
+		if t.Error != nil {
+			return nil, fmt.Errorf("failed to describe topic %q: %w", t.Name, t.Error)
+		}
+		if strings.HasPrefix(t.Name, "__") && len(names) == 0 {
+			continue // Internal topics (offsets, transactions)
+		}
+
+		info := TopicInfo{Name: t.Name, Partitions: len(t.Partitions)}
+		if len(t.Partitions) > 0 {
+			info.ReplicationFactor = len(t.Partitions[0].Replicas)
+		}
+		topics = append(topics, info)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	return topics, nil
+}
+
+// Ensure creates missing topics and adds partitions to topics with fewer
+// than wanted. Kafka cannot remove partitions or change the replication
+// factor of an existing topic, so a larger existing topic is left alone and
+// a replication mismatch is only logged.
+func (a *Admin) Ensure(ctx context.Context, specs ...TopicSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		if err := spec.validate(); err != nil {
+			return err
+		}
+		names[i] = spec.Name
+	}
+
+	existing, err := a.Describe(ctx, names...)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]TopicInfo, len(existing))
+	for _, info := range existing {
+		current[info.Name] = info
+	}
+
+	var create []kafka.TopicConfig
+	var grow []kafka.TopicPartitionsConfig
+	for _, spec := range specs {
+		info, ok := current[spec.Name]
+		switch {
+		case !ok:
+			create = append(create, kafka.TopicConfig{
+				Topic:             spec.Name,
+				NumPartitions:     spec.Partitions,
+				ReplicationFactor: spec.ReplicationFactor,
+			})
+		case info.Partitions < spec.Partitions:
+			grow = append(grow, kafka.TopicPartitionsConfig{Name: spec.Name, Count: int32(spec.Partitions)})
+		default:
+			a.logger.Info("kafka topic up to date",
+				zap.String("topic", spec.Name),
+				zap.Int("partitions", info.Partitions),
+				zap.Int("wanted_partitions", spec.Partitions))
+		}
+		if ok && info.ReplicationFactor != spec.ReplicationFactor {
+			a.logger.Warn("kafka topic replication factor differs and cannot be changed here",
+				zap.String("topic", spec.Name),
+				zap.Int("replication_factor", info.ReplicationFactor),
+				zap.Int("wanted_replication_factor", spec.ReplicationFactor))
+		}
+	}
+
+	var errs []error
+	if len(create) > 0 {
+		resp, err := a.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: create})
+		if err != nil {
+			return fmt.Errorf("failed to create topics: %w", err)
+		}
+		for _, topic := range create {
+			// Another instance may have created it since Describe
+			if err := resp.Errors[topic.Topic]; err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+				errs = append(errs, fmt.Errorf("failed to create topic %q: %w", topic.Topic, err))
+				continue
+			}
+			a.logger.Info("created kafka topic",
+				zap.String("topic", topic.Topic),
+				zap.Int("partitions", topic.NumPartitions),
+				zap.Int("replication_factor", topic.ReplicationFactor))
+		}
+	}
+
+	if len(grow) > 0 {
+		resp, err := a.client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{Topics: grow})
+		if err != nil {
+			return fmt.Errorf("failed to add partitions: %w", err)
+		}
+		for _, topic := range grow {
+			if err := resp.Errors[topic.Name]; err != nil {
+				errs = append(errs, fmt.Errorf("failed to add partitions to topic %q: %w", topic.Name, err))
+				continue
+			}
+			// Keyed events of a user may now hash to a different partition
+			a.logger.Warn("added partitions to kafka topic; key-to-partition mapping changed",
+				zap.String("topic", topic.Name),
+				zap.Int("from", current[topic.Name].Partitions),
+				zap.Int32("to", topic.Count))
+		}
+	}
+	return errors.Join(errs...)
+}
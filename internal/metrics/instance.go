@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// InstanceLabel names the instance that exposed a series
+const InstanceLabel = "instance_id"
+
+// Handler serves the default registry for Prometheus with every series
+// labeled instance_id, so series of several instances stay apart however
+// they are scraped and aggregated. An empty instanceID leaves them as they are.
+func Handler(instanceID string) http.Handler {
+	if instanceID == "" {
+		return promhttp.Handler()
+	}
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(instanceGatherer{prometheus.DefaultGatherer, instanceID}, promhttp.HandlerOpts{}))
+}
+
+// instanceGatherer adds the instance label to everything it gathers
+type instanceGatherer struct {
+	gatherer   prometheus.Gatherer
+	instanceID string
+}
+
+func (g instanceGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	name, value := InstanceLabel, g.instanceID
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			if hasLabel(metric, name) {
+				continue
+			}
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+			sort.Slice(metric.Label, func(i, j int) bool {
+				return metric.Label[i].GetName() < metric.Label[j].GetName()
+			})
+		}
+	}
+	return families, err
+}
+
+func hasLabel(metric *dto.Metric, name string) bool {
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,773 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "notification"
+
+// SSE admission control
+var (
+	AdmissionAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse_admission",
+		Name:      "accepted_total",
+		Help:      "SSE connection attempts admitted (immediately or after queueing)",
+	})
+
+	AdmissionQueued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse_admission",
+		Name:      "queued_total",
+		Help:      "SSE connection attempts that had to wait for an admission token",
+	})
+
+	AdmissionRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse_admission",
+		Name:      "rejected_total",
+		Help:      "SSE connection attempts rejected with Retry-After",
+	})
+
+	AdmissionQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sse_admission",
+		Name:      "queue_depth",
+		Help:      "SSE connection attempts currently waiting for admission",
+	})
+
+	AdmissionWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sse_admission",
+		Name:      "wait_seconds",
+		Help:      "Time queued connection attempts waited before admission",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	})
+)
+
+// SSE connection rejections
+var (
+	SSERejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "rejections_total",
+		Help:      "SSE connections refused with a retry hint, by reason (capacity, draining, rate_limited)",
+	}, []string{"reason"})
+
+	SSERetryAfterSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "retry_after_seconds",
+		Help:      "Retry hints given to refused SSE connections, by reason",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"reason"})
+)
+
+// SSE stream lifecycle
+var (
+	SSECleanupRuns = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "cleanup_runs_total",
+		Help:      "Stale SSE connection sweeps run",
+	})
+
+	SSEStaleRemoved = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "stale_removed_total",
+		Help:      "SSE connections closed for exceeding the stale timeout",
+	})
+
+	SSECleanupSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "cleanup_seconds",
+		Help:      "Time each stale SSE connection sweep held the connection lock",
+		Buckets:   []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5},
+	})
+
+	SSECompressedStreams = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "streams_total",
+		Help:      "SSE streams opened, by negotiated content encoding (identity when uncompressed)",
+	}, []string{"encoding"})
+
+	// SSECompressionBytes counts compressed streams' bytes by encoding and
+	// side: "in" is the SSE text handed to the compressor, "out" what it
+	// wrote; 1 - out/in is the bandwidth saved
+	SSECompressionBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "compression_bytes_total",
+		Help:      "Bytes through SSE stream compressors, by encoding and side (in = uncompressed, out = written)",
+	}, []string{"encoding", "side"})
+
+	SSEFramesPerFlush = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "frames_per_flush",
+		Help:      "SSE frames written per response flush (above 1 when write coalescing batches frames)",
+		Buckets:   []float64{1, 2, 4, 8, 16, 32, 64},
+	})
+
+	SSERenderSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "render_seconds",
+		Help:      "Time to render a notification's frame data, by whether it had delivery-time template variables to resolve",
+		Buckets:   []float64{0.000001, 0.0000025, 0.000005, 0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001},
+	}, []string{"templated"})
+
+	SSETemplateErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "template_errors_total",
+		Help:      "Notifications whose title or message template failed to execute and fell back to the generic text, by event type",
+	}, []string{"event_type"})
+
+	SSEBytesWritten = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "bytes_written_total",
+		Help:      "Bytes written to SSE clients, after compression",
+	})
+
+	SSEReconnectsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "reconnects_sent_total",
+		Help:      "Reconnect events sent to SSE clients, by reason (draining, shed)",
+	}, []string{"reason"})
+
+	SSEBandwidthDeferred = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "bandwidth_deferred_total",
+		Help:      "LOW priority notifications held back from a connection over its bandwidth cap",
+	})
+
+	SSERegionDelay = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "region_delay_seconds",
+		Help:      "Simulated WAN delay added to frames for users in each region",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .2, .4, .8, 1.6},
+	}, []string{"region"})
+
+	SSERegionFramesLost = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "region_frames_lost_total",
+		Help:      "Frames dropped by the simulated WAN link of each region",
+	}, []string{"region"})
+
+	SSEClientEchoes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "client_echoes_total",
+		Help:      "Heartbeat echoes received from SSE clients",
+	})
+
+	SSEFilterUpdates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "filter_updates_total",
+		Help:      "Stream filters replaced mid-connection by clients",
+	})
+
+	SSESilentSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "silent_skipped_total",
+		Help:      "Deliveries that skipped an open connection whose client stopped echoing heartbeats",
+	})
+)
+
+// Offline-user parking
+var (
+	NotificationsParked = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parking",
+		Name:      "parked_total",
+		Help:      "Notifications parked because their user had no open connection",
+	})
+
+	NotificationsUnparked = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parking",
+		Name:      "unparked_total",
+		Help:      "Parked notifications claimed for delivery after their user reconnected",
+	})
+
+	NotificationsHeld = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parking",
+		Name:      "held_total",
+		Help:      "Notifications parked because an operator paused delivery to their user",
+	})
+
+	PausedUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "parking",
+		Name:      "paused_users",
+		Help:      "Users whose delivery is paused on this instance",
+	})
+
+	UnparkSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "parking",
+		Name:      "unpark_seconds",
+		Help:      "Time from a user reconnecting to their parked notifications being queued for delivery",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	})
+)
+
+// Consumer event-time watermark
+var (
+	ConsumerEventLagSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "event_lag_seconds",
+		Help:      "Processing time minus event timestamp of consumed events (future-stamped events count as 0)",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 30, 60, 300, 1800, 3600},
+	})
+
+	ConsumerWatermarkLagSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "watermark_lag_seconds",
+		Help:      "Processing time minus the highest event timestamp seen so far",
+	})
+
+	ConsumerLateEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "late_events_total",
+		Help:      "Events beyond the late threshold, by lateness (late, future) and action taken (deliver, mark, drop)",
+	}, []string{"lateness", "action"})
+
+	// ConsumerMessages is labeled from Kafka headers when present, so
+	// filtered events are counted without decoding them
+	ConsumerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "messages_total",
+		Help:      "Consumed events by event type, priority and result (accepted, filtered, over_quota, parse_error, unsupported_schema, invalid_payload, invalid_audience, unresolved_audience)",
+	}, []string{"event_type", "priority", "result"})
+
+	ConsumerMessageFormats = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "message_formats_total",
+		Help:      "Decoded events by encoding (json, protobuf) and schema version, to follow a rolling producer upgrade",
+	}, []string{"encoding", "schema_version"})
+)
+
+// Consumer worker pool
+var (
+	ConsumerInflight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "inflight_messages",
+		Help:      "Messages fetched but not yet committable (being processed, or behind an unfinished message of their partition)",
+	})
+
+	ConsumerCommits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "commits_total",
+		Help:      "Offset commits by result (success, error)",
+	}, []string{"result"})
+)
+
+// Consumer poison messages
+var (
+	ConsumerPoisonMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "poison_messages_total",
+		Help:      "Events refused as invalid by reason (parse_error, unsupported_schema, invalid_user_id, invalid_payload, invalid_audience, unresolved_audience) and outcome (written to the poison topic, dropped without one, error)",
+	}, []string{"reason", "outcome"})
+)
+
+// Consumer broadcasts
+var (
+	ConsumerBroadcastRecipients = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "broadcast_recipients",
+		Help:      "Notifications each accepted broadcast event expanded into",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 9), // 1 to 65536
+	})
+)
+
+// Consumer ingest quotas
+var (
+	ConsumerIngestOverQuota = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "ingest_over_quota_total",
+		Help:      "Events beyond their producer's ingest quota, by quota key kind (source, tenant) and overflow action (degrade, dlq, drop)",
+	}, []string{"key", "action"})
+)
+
+// Consumer group lag, as seen by Kafka
+var (
+	ConsumerPartitionLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "partition_lag",
+		Help:      "Partition high-water mark minus the consumer group's committed offset",
+	}, []string{"partition"})
+
+	ConsumerTotalLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "total_lag",
+		Help:      "Consumer group lag summed over the topic's partitions",
+	})
+
+	ConsumerLagChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "lag_checks_total",
+		Help:      "Consumer lag checks against Kafka by result (success, error)",
+	}, []string{"result"})
+)
+
+// Rolling per-priority SLA compliance
+var (
+	SLACompliancePercent = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sla",
+		Name:      "compliance_percent",
+		Help:      "Share of notifications delivered within the SLO target over the rolling SLA window",
+	}, []string{"priority"})
+
+	SLAObjectivePercent = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sla",
+		Name:      "objective_percent",
+		Help:      "Configured SLA objective (the percentile that must be within the SLO target)",
+	}, []string{"priority"})
+
+	SLAMet = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sla",
+		Name:      "met",
+		Help:      "1 while compliance meets the SLA objective over the rolling window",
+	}, []string{"priority"})
+
+	SLABreaches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sla",
+		Name:      "breaches_total",
+		Help:      "Transitions from meeting to missing the SLA objective",
+	}, []string{"priority"})
+)
+
+// Redis read-model cache
+var (
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "User read-model cache lookups by result (hit, miss, error)",
+	}, []string{"result"})
+
+	CacheInvalidationErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "invalidation_errors_total",
+		Help:      "Cache invalidations that failed (entries stay until their TTL)",
+	})
+)
+
+// User preferences read on the delivery path
+var (
+	PreferenceLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "preferences",
+		Name:      "lookups_total",
+		Help:      "User preference lookups when rendering deliveries, by result (hit, miss, error)",
+	}, []string{"result"})
+)
+
+// Goroutine leak watchdog
+var (
+	Goroutines = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "goroutines",
+		Name:      "count",
+		Help:      "Live goroutines per labeled pool (unlabeled = everything else)",
+	}, []string{"pool"})
+
+	GoroutineLeakSuspected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "goroutines",
+		Name:      "leak_suspected",
+		Help:      "1 while a pool exceeds its expected bound or unlabeled goroutines grow steadily",
+	}, []string{"pool"})
+)
+
+// Built-in canary probe
+var (
+	CanaryLatencySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "canary",
+		Name:      "latency_seconds",
+		Help:      "End-to-end latency of canary probes from publish to SSE receipt",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	})
+
+	CanaryProbesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "canary",
+		Name:      "probes_sent_total",
+		Help:      "Canary probes published",
+	})
+
+	CanaryProbesLost = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "canary",
+		Name:      "probes_lost_total",
+		Help:      "Canary probes not received within the timeout",
+	})
+
+	CanaryDegraded = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "canary",
+		Name:      "degraded",
+		Help:      "1 while canary latency is above threshold or probes are being lost",
+	})
+)
+
+// Task picker worker pools
+var (
+	// QueueWaitSeconds measures enqueue → dequeue time on the channels between
+	// worker pools, labeled by queue ("notification" or "status_update")
+	QueueWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "task_picker",
+		Name:      "queue_wait_seconds",
+		Help:      "Time items spend waiting in task picker channels before a worker picks them up",
+		Buckets:   []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"queue"})
+
+	PickerStoreUnavailable = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "task_picker",
+		Name:      "store_unavailable_total",
+		Help:      "Repository calls by the task picker that failed because the store was unavailable (claims back off, status updates are retried)",
+	})
+)
+
+// Cluster-wide delivery quotas
+var (
+	QuotaThrottleSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "quota",
+		Name:      "throttle_seconds",
+		Help:      "Time delivery workers waited for the cluster-wide delivery rate",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+
+	QuotaDeferred = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "quota",
+		Name:      "deferred_total",
+		Help:      "Notifications returned to pending because their user reached the delivery cap",
+	})
+
+	QuotaErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "quota",
+		Name:      "errors_total",
+		Help:      "Quota counter errors (delivery proceeds unthrottled)",
+	})
+)
+
+// Expiry sweeper
+var (
+	NotificationsExpired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "expiry",
+		Name:      "expired_total",
+		Help:      "Notifications expired by the sweeper, by event type, priority and reason (deadline or age)",
+	}, []string{"event_type", "priority", "reason"})
+
+	PickerExpired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "expiry",
+		Name:      "claimed_expired_total",
+		Help:      "Claimed notifications the task picker expired instead of delivering, by event type, priority and reason (deadline or ttl)",
+	}, []string{"event_type", "priority", "reason"})
+
+	ExpirySweepSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "expiry",
+		Name:      "sweep_seconds",
+		Help:      "Duration of expiry sweeps",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+	})
+)
+
+// Reprioritizer
+var (
+	NotificationsReprioritized = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reprioritize",
+		Name:      "moved_total",
+		Help:      "Pending notifications moved between priorities by age, by from and to priority",
+	}, []string{"from", "to"})
+)
+
+// Delivery receipts topic
+var (
+	DeliveryReceipts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "receipts",
+		Name:      "total",
+		Help:      "Delivery receipts by result (published, dropped when the queue is full, failed)",
+	}, []string{"result"})
+)
+
+// Pipeline stage latency of delivered notifications
+var (
+	// PipelineStageSeconds is labeled by stage: produce (producer → consumer),
+	// persist (consumer → stored), pending (stored → claimed), queue (claimed
+	// → delivery worker), deliver (SSE write) and total (produced → delivered)
+	PipelineStageSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "pipeline",
+		Name:      "stage_seconds",
+		Help:      "Time delivered notifications spent in each pipeline stage",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"stage"})
+)
+
+// Chaos injection
+var (
+	ChaosInjected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "chaos",
+		Name:      "injected_total",
+		Help:      "Faults injected, by fault (db_latency, sse_write, consumer_pause, delivery)",
+	}, []string{"fault"})
+)
+
+// Postgres circuit breaker
+var (
+	CircuitBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "circuit_breaker",
+		Name:      "state",
+		Help:      "Postgres circuit breaker state: 0 closed, 1 half-open, 2 open",
+	})
+
+	CircuitBreakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "circuit_breaker",
+		Name:      "transitions_total",
+		Help:      "Postgres circuit breaker state changes, by the state entered",
+	}, []string{"state"})
+
+	CircuitBreakerRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "circuit_breaker",
+		Name:      "rejected_total",
+		Help:      "Repository calls failed fast while the Postgres circuit breaker was open",
+	})
+
+	ConsumerBuffered = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "buffered_notifications",
+		Help:      "Notifications held in memory by consumer workers while the store is unavailable",
+	})
+)
+
+// Postgres connection pools (sampled every few seconds; pool is primary or
+// replica_N), slow statements and replica reads
+var (
+	PostgresPoolConns = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "pool_connections",
+		Help:      "Postgres pool connections by pool and state (acquired, idle, constructing)",
+	}, []string{"pool", "state"})
+
+	PostgresPoolMaxConns = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "pool_max_connections",
+		Help:      "Maximum size of each Postgres pool",
+	}, []string{"pool"})
+
+	PostgresPoolAcquires = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "pool_acquires_total",
+		Help:      "Connections acquired from each Postgres pool",
+	}, []string{"pool"})
+
+	PostgresPoolEmptyAcquires = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "pool_empty_acquires_total",
+		Help:      "Acquires that had to wait for a connection because none was idle",
+	}, []string{"pool"})
+
+	PostgresPoolAcquireWait = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "pool_acquire_wait_seconds_total",
+		Help:      "Time spent waiting to acquire Postgres pool connections",
+	}, []string{"pool"})
+
+	PostgresSlowQueries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "slow_queries_total",
+		Help:      "Statements slower than the slow-query threshold",
+	})
+
+	PostgresReplicaReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "replica_reads_total",
+		Help:      "Reads routed to read replicas by outcome (ok, error, fallback: the replica was unavailable and the primary answered)",
+	}, []string{"outcome"})
+)
+
+// Consumer spill-to-disk buffer
+var (
+	ConsumerSpill = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "spill_notifications_total",
+		Help:      "Notifications through the spill buffer, by op (spilled, replayed, dropped)",
+	}, []string{"op"})
+
+	ConsumerSpillDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "spill_depth",
+		Help:      "Notifications on disk waiting to be replayed into the store",
+	})
+
+	ConsumerSpillBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "spill_bytes",
+		Help:      "Size of the spill buffer's segment files",
+	})
+)
+
+// Event producers (Kafka, or the publish API with INGEST_URL)
+var (
+	ProducerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "producer",
+		Name:      "messages_total",
+		Help:      "Messages published by result (delivered or failed)",
+	}, []string{"result"})
+
+	ProducerBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "producer",
+		Name:      "batch_size",
+		Help:      "Messages per producer write call or publish request",
+		Buckets:   []float64{1, 5, 10, 50, 100, 250, 500, 1000, 5000},
+	})
+
+	ProducerInflight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "producer",
+		Name:      "inflight_messages",
+		Help:      "Messages queued by an async producer and not yet acknowledged",
+	})
+)
+
+// Direct publish API (POST /notifications, POST /notifications/batch)
+var (
+	PublishedNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "publish",
+		Name:      "notifications_total",
+		Help:      "Notifications received over the publish API, by endpoint (single, batch) and result (persisted, rejected, failed)",
+	}, []string{"endpoint", "result"})
+
+	PublishBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "publish",
+		Name:      "batch_size",
+		Help:      "Notifications per accepted POST /notifications/batch",
+		Buckets:   []float64{1, 5, 10, 50, 100, 250, 500, 1000, 5000},
+	})
+)
+
+// Event generator rate schedule
+var (
+	GeneratorTargetRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "generator",
+		Name:      "target_rate",
+		Help:      "Events per second the generators aim for under the scenario's ramp, bursts and diurnal curve",
+	})
+
+	GeneratorActiveBursts = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "generator",
+		Name:      "active_bursts",
+		Help:      "Scenario bursts currently multiplying the event rate",
+	})
+
+	GeneratorFaults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "generator",
+		Name:      "faults_total",
+		Help:      "Producer faults the generators injected, by fault (dropped, duplicated, outage)",
+	}, []string{"fault"})
+
+	GeneratorOutage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "generator",
+		Name:      "outage",
+		Help:      "1 while a simulated producer outage has publishing paused",
+	})
+)
+
+// Soak-test leak detector
+var (
+	SoakGoroutines = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "soak",
+		Name:      "goroutines",
+		Help:      "Goroutines at the last leak detector sample",
+	})
+
+	SoakHeapLiveBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "soak",
+		Name:      "heap_live_bytes",
+		Help:      "Heap marked live by the last GC at the last leak detector sample",
+	})
+
+	SoakOpenFDs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "soak",
+		Name:      "open_fds",
+		Help:      "Open file descriptors at the last leak detector sample (Linux only)",
+	})
+
+	SoakLeakSuspected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "soak",
+		Name:      "leak_suspected",
+		Help:      "1 while a resource (goroutines, heap, fds) has grown steadily across the detector window",
+	}, []string{"resource"})
+)
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Kafka message encodings, named in the content_type header
+const (
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+)
+
+// Kafka headers describing how a notification event is encoded
+const (
+	HeaderContentType   = "content_type"
+	HeaderSchemaVersion = "schema_version"
+)
+
+// KafkaSchemaVersion is the newest KafkaMessage schema this build reads and
+// the one it writes. Consumers read every version up to their own, so
+// upgrade consumers before producers.
+//
+//	1: the original JSON body, unversioned and without headers
+//	2: schema_version in the headers and the JSON body; protobuf encoding
+//	   (kafka_message.proto)
+const KafkaSchemaVersion = 2
+
+// ErrUnsupportedSchema is returned for events written by a newer producer
+var ErrUnsupportedSchema = errors.New("unsupported kafka message schema")
+
+// ParseMessageEncoding validates an encoding name; empty means JSON
+func ParseMessageEncoding(raw string) (string, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(raw)); encoding {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingProtobuf:
+		return EncodingProtobuf, nil
+	default:
+		return "", fmt.Errorf("invalid message encoding %q (want json or protobuf)", raw)
+	}
+}
+
+// EncodeKafkaMessage stamps msg with KafkaSchemaVersion and encodes it
+func EncodeKafkaMessage(msg *KafkaMessage, encoding string) ([]byte, error) {
+	msg.SchemaVersion = KafkaSchemaVersion
+	switch encoding {
+	case "", EncodingJSON:
+		return json.Marshal(msg)
+	case EncodingProtobuf:
+		return marshalKafkaProto(msg), nil
+	default:
+		return nil, fmt.Errorf("invalid message encoding %q", encoding)
+	}
+}
+
+// DecodeKafkaMessage decodes an event from its content_type and
+// schema_version headers. Without headers (schema 1 producers) the body is
+// JSON and the version comes from the body.
+func DecodeKafkaMessage(data []byte, contentType, schemaVersion string) (*KafkaMessage, error) {
+	version := 0
+	if schemaVersion != "" {
+		v, err := strconv.Atoi(schemaVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema version header %q", schemaVersion)
+		}
+		version = v
+	}
+	if version > KafkaSchemaVersion {
+		return nil, fmt.Errorf("%w: version %d (newest known %d)", ErrUnsupportedSchema, version, KafkaSchemaVersion)
+	}
+
+	var msg KafkaMessage
+	switch contentType {
+	case "", EncodingJSON:
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+	case EncodingProtobuf:
+		if err := unmarshalKafkaProto(data, &msg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: content type %q", ErrUnsupportedSchema, contentType)
+	}
+
+	if msg.SchemaVersion == 0 {
+		msg.SchemaVersion = 1
+	}
+	if msg.SchemaVersion > KafkaSchemaVersion {
+		return nil, fmt.Errorf("%w: version %d (newest known %d)", ErrUnsupportedSchema, msg.SchemaVersion, KafkaSchemaVersion)
+	}
+	return &msg, nil
+}
+
+// Field numbers from kafka_message.proto
+const (
+	protoSchemaVersion  protowire.Number = 1
+	protoEventID        protowire.Number = 2
+	protoTenantID       protowire.Number = 3
+	protoEventType      protowire.Number = 4
+	protoPriority       protowire.Number = 5
+	protoUserID         protowire.Number = 6
+	protoEventTimestamp protowire.Number = 7
+	protoPayload        protowire.Number = 8
+	protoMetadata       protowire.Number = 9
+	protoExpiresAt      protowire.Number = 10
+	protoProducedAt     protowire.Number = 11
+	protoUserIDs        protowire.Number = 12
+	protoSegment        protowire.Number = 13
+	protoTopic          protowire.Number = 14
+
+	protoMapKey   protowire.Number = 1
+	protoMapValue protowire.Number = 2
+
+	protoSourceService protowire.Number = 1
+	protoTraceID       protowire.Number = 2
+)
+
+func marshalKafkaProto(msg *KafkaMessage) []byte {
+	var b []byte
+	b = appendProtoVarint(b, protoSchemaVersion, uint64(msg.SchemaVersion))
+	b = appendProtoString(b, protoEventID, msg.EventID)
+	b = appendProtoString(b, protoTenantID, msg.TenantID)
+	b = appendProtoString(b, protoEventType, msg.EventType)
+	b = appendProtoString(b, protoPriority, msg.Priority)
+	b = appendProtoString(b, protoUserID, msg.UserID)
+	if !msg.EventTimestamp.IsZero() {
+		b = appendProtoVarint(b, protoEventTimestamp, uint64(msg.EventTimestamp.UnixNano()))
+	}
+	for key, value := range msg.Payload {
+		var entry []byte
+		entry = appendProtoString(entry, protoMapKey, key)
+		entry = appendProtoString(entry, protoMapValue, value)
+		b = protowire.AppendTag(b, protoPayload, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	var metadata []byte
+	metadata = appendProtoString(metadata, protoSourceService, msg.Metadata.SourceService)
+	metadata = appendProtoString(metadata, protoTraceID, msg.Metadata.TraceID)
+	if len(metadata) > 0 {
+		b = protowire.AppendTag(b, protoMetadata, protowire.BytesType)
+		b = protowire.AppendBytes(b, metadata)
+	}
+	if msg.ExpiresAt != nil {
+		b = appendProtoVarint(b, protoExpiresAt, uint64(msg.ExpiresAt.UnixNano()))
+	}
+	if msg.ProducedAt != nil {
+		b = appendProtoVarint(b, protoProducedAt, uint64(msg.ProducedAt.UnixNano()))
+	}
+	for _, userID := range msg.UserIDs {
+		b = protowire.AppendTag(b, protoUserIDs, protowire.BytesType)
+		b = protowire.AppendString(b, userID)
+	}
+	b = appendProtoString(b, protoSegment, msg.Segment)
+	b = appendProtoString(b, protoTopic, msg.Topic)
+	return b
+}
+
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendProtoVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// unmarshalKafkaProto decodes the protobuf encoding; unknown fields (from a
+// newer schema) are skipped
+func unmarshalKafkaProto(b []byte, msg *KafkaMessage) error {
+	return walkProto(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch {
+		case num == protoSchemaVersion && typ == protowire.VarintType:
+			msg.SchemaVersion = int(v)
+		case num == protoEventID && typ == protowire.BytesType:
+			msg.EventID = string(data)
+		case num == protoTenantID && typ == protowire.BytesType:
+			msg.TenantID = string(data)
+		case num == protoEventType && typ == protowire.BytesType:
+			msg.EventType = string(data)
+		case num == protoPriority && typ == protowire.BytesType:
+			msg.Priority = string(data)
+		case num == protoUserID && typ == protowire.BytesType:
+			msg.UserID = string(data)
+		case num == protoEventTimestamp && typ == protowire.VarintType:
+			msg.EventTimestamp = time.Unix(0, int64(v)).UTC()
+		case num == protoPayload && typ == protowire.BytesType:
+			var key, value string
+			err := walkProto(data, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+				if typ == protowire.BytesType && num == protoMapKey {
+					key = string(data)
+				} else if typ == protowire.BytesType && num == protoMapValue {
+					value = string(data)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if msg.Payload == nil {
+				msg.Payload = make(map[string]string)
+			}
+			msg.Payload[key] = value
+		case num == protoMetadata && typ == protowire.BytesType:
+			return walkProto(data, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+				if typ == protowire.BytesType && num == protoSourceService {
+					msg.Metadata.SourceService = string(data)
+				} else if typ == protowire.BytesType && num == protoTraceID {
+					msg.Metadata.TraceID = string(data)
+				}
+				return nil
+			})
+		case num == protoExpiresAt && typ == protowire.VarintType:
+			expiresAt := time.Unix(0, int64(v)).UTC()
+			msg.ExpiresAt = &expiresAt
+		case num == protoProducedAt && typ == protowire.VarintType:
+			producedAt := time.Unix(0, int64(v)).UTC()
+			msg.ProducedAt = &producedAt
+		case num == protoUserIDs && typ == protowire.BytesType:
+			msg.UserIDs = append(msg.UserIDs, string(data))
+		case num == protoSegment && typ == protowire.BytesType:
+			msg.Segment = string(data)
+		case num == protoTopic && typ == protowire.BytesType:
+			msg.Topic = string(data)
+		}
+		return nil
+	})
+}
+
+// walkProto calls fn for each field; varints arrive in v, length-delimited
+// fields in data, and other wire types are skipped
+func walkProto(b []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var v uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
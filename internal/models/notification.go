@@ -2,7 +2,12 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -16,6 +21,40 @@ const (
 	PriorityLow    Priority = "LOW"
 )
 
+// DefaultTenantID is used when a message or request carries no tenant
+const DefaultTenantID = "default"
+
+// TenantOrDefault returns tenantID, or DefaultTenantID when empty
+func TenantOrDefault(tenantID string) string {
+	if tenantID == "" {
+		return DefaultTenantID
+	}
+	return tenantID
+}
+
+// MaxUserIDLength matches the user_id VARCHAR(255) columns
+const MaxUserIDLength = 255
+
+// ValidateUserID accepts any opaque user ID (user_42, a UUID, an email...)
+// that fits the column and contains no whitespace or control characters
+func ValidateUserID(userID string) error {
+	if userID == "" {
+		return errors.New("user_id is required")
+	}
+	if len(userID) > MaxUserIDLength {
+		return fmt.Errorf("user_id exceeds %d bytes", MaxUserIDLength)
+	}
+	if !utf8.ValidString(userID) {
+		return errors.New("user_id is not valid UTF-8")
+	}
+	for _, r := range userID {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("user_id contains invalid character %q", r)
+		}
+	}
+	return nil
+}
+
 // EventType represents the type of notification event
 type EventType string
 
@@ -35,15 +74,48 @@ const (
 	EventFollowerNew            EventType = "follower.new"
 	EventFollowerContentLiked   EventType = "follower.content_liked"
 	EventFollowerContentComment EventType = "follower.content_commented"
+
+	// Announcements, broadcast to segments
+	EventSystemAnnouncement EventType = "system.announcement"
+
+	// Synthetic end-to-end probes from the built-in canary
+	EventCanaryProbe EventType = "canary.probe"
 )
 
+// Event categories badge counts are split by
+const (
+	CategoryJobs        = "jobs"
+	CategoryConnections = "connections"
+	CategoryFollowers   = "followers"
+	CategoryOther       = "other"
+)
+
+// Category is the event category of the event type, by its prefix
+// (job.new is jobs); unknown prefixes are other
+func (e EventType) Category() string {
+	prefix, _, _ := strings.Cut(string(e), ".")
+	switch prefix {
+	case "job":
+		return CategoryJobs
+	case "connection":
+		return CategoryConnections
+	case "follower":
+		return CategoryFollowers
+	default:
+		return CategoryOther
+	}
+}
+
 // Notification represents a notification in the system
 type Notification struct {
 	NotificationID                 uuid.UUID         `json:"notification_id"`
+	EventID                        string            `json:"event_id,omitempty"` // Producer's event ID, used to find events lost before persistence
+	TenantID                       string            `json:"tenant_id"`
 	UserID                         string            `json:"user_id"`
+	Topic                          string            `json:"topic,omitempty"` // Topic it was published to, empty when addressed to the user
 	EventType                      EventType         `json:"event_type"`
 	Priority                       Priority          `json:"priority"`
-	Status                         string            `json:"status"` // not_pushed, processing, pushed, delivered, failed
+	Status                         string            `json:"status"` // not_pushed, processing, pushed, delivered, failed, parked, expired, archived
 	EventTimestamp                 time.Time         `json:"event_timestamp"`
 	NotificationReceivedTimestamp  time.Time         `json:"notification_received_timestamp"`
 	NotificationDeliveredTimestamp time.Time         `json:"notification_delivered_timestamp"`
@@ -52,17 +124,39 @@ type Notification struct {
 	IsRead                         bool              `json:"is_read"`
 	RetryCount                     int               `json:"retry_count"`
 	CreatedAt                      time.Time         `json:"created_at"`
+	ExpiresAt                      *time.Time        `json:"expires_at,omitempty"` // Delivery deadline, nil if none
+	TraceID                        string            `json:"trace_id,omitempty"`
+	IsLate                         bool              `json:"is_late,omitempty"`     // Event time beyond the late threshold; excluded from SLO stats
+	ProducedAt                     *time.Time        `json:"produced_at,omitempty"` // When the producer sent the event, nil if it didn't say
 }
 
 // KafkaMessage represents the message format in Kafka
 type KafkaMessage struct {
+	SchemaVersion  int               `json:"schema_version,omitempty"` // See KafkaSchemaVersion; absent in schema 1
 	EventID        string            `json:"event_id"`
+	TenantID       string            `json:"tenant_id,omitempty"` // Empty means DefaultTenantID
 	EventType      string            `json:"event_type"`
 	Priority       string            `json:"priority"`
 	UserID         string            `json:"user_id"`
+	UserIDs        []string          `json:"user_ids,omitempty"` // Broadcast to these users instead of UserID
+	Segment        string            `json:"segment,omitempty"`  // Broadcast to a registered segment or SegmentAll instead of UserID
+	Topic          string            `json:"topic,omitempty"`    // Publish to the topic's subscribers instead of UserID
 	EventTimestamp time.Time         `json:"event_timestamp"`
 	Payload        map[string]string `json:"payload"`
 	Metadata       Metadata          `json:"metadata"`
+	ExpiresAt      *time.Time        `json:"expires_at,omitempty"`
+	ProducedAt     *time.Time        `json:"produced_at,omitempty"` // Set by the producer when it sends the event
+}
+
+// SegmentAll addresses a broadcast to every user connected to the instance
+// that consumes it
+const SegmentAll = "all"
+
+// IsBroadcast reports whether the event addresses a list of users, a
+// segment or a topic rather than one user; the consumer expands it into a
+// notification per recipient
+func (m *KafkaMessage) IsBroadcast() bool {
+	return len(m.UserIDs) > 0 || m.Segment != "" || m.Topic != ""
 }
 
 // Metadata contains additional event metadata
@@ -117,13 +211,3 @@ func GetPriorityForEventType(eventType EventType) Priority {
 		return PriorityMedium
 	}
 }
-
-// SSEMessage represents a message sent over SSE
-type SSEMessage struct {
-	NotificationID uuid.UUID `json:"notification_id"`
-	Type           string    `json:"type"`
-	Priority       string    `json:"priority"`
-	Title          string    `json:"title"`
-	Message        string    `json:"message"`
-	Timestamp      time.Time `json:"timestamp"`
-}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// WireVersion is the version of the SSE notification wire format, sent as
+// "v" on every notification. Additive changes keep the version; anything
+// that changes or removes a field bumps it.
+const WireVersion = 1
+
+// NotificationEvent is the data of a "notification" SSE frame, of each item
+// in a "notifications" frame, and of typed frames named after the event type
+type NotificationEvent struct {
+	Version        int               `json:"v"`
+	NotificationID string            `json:"notification_id"`
+	EventType      string            `json:"event_type"`
+	Priority       string            `json:"priority"`
+	Topic          string            `json:"topic,omitempty"` // Set when it came from one of the user's topic subscriptions
+	EventTimestamp time.Time         `json:"event_timestamp"`
+	Payload        map[string]string `json:"payload"`
+	Title          string            `json:"title,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	InstanceID     string            `json:"instance_id,omitempty"` // Instance that delivered it
+	Stages         *StageTimestamps  `json:"stages,omitempty"`      // Sent when the server has SSE_STAGE_TIMESTAMPS on
+}
+
+// StageTimestamps are the times a notification passed each pipeline stage,
+// so clients can split its latency. Unknown stages are left out; stamps come
+// from different hosts (producer, database, service), so stage durations
+// are only as good as their clock sync.
+type StageTimestamps struct {
+	ProducedAt  time.Time `json:"produced_at,omitzero"`  // Producer sent the event
+	ConsumedAt  time.Time `json:"consumed_at,omitzero"`  // Consumer decoded it
+	PersistedAt time.Time `json:"persisted_at,omitzero"` // Row written (database clock)
+	ClaimedAt   time.Time `json:"claimed_at,omitzero"`   // Claimed by a task picker (database clock)
+	DeliveredAt time.Time `json:"delivered_at,omitzero"` // Written to the stream
+}
+
+// NotificationGroup is the data of a "notifications" SSE frame: several
+// notifications for one user
+type NotificationGroup struct {
+	Version       int                  `json:"v"`
+	UserID        string               `json:"user_id"`
+	Count         int                  `json:"count"`
+	Notifications []*NotificationEvent `json:"notifications"`
+}
+
+// SupportedWireVersion reports whether a client built against WireVersion
+// can read a frame of version v. Frames from before versioning carry no v.
+func SupportedWireVersion(v int) bool {
+	return v >= 0 && v <= WireVersion
+}
@@ -0,0 +1,772 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// SLOTargets holds the maximum acceptable event → delivery delay per priority
+type SLOTargets struct {
+	High   time.Duration
+	Medium time.Duration
+	Low    time.Duration
+}
+
+// For returns the target for the given priority
+func (t SLOTargets) For(priority string) time.Duration {
+	switch priority {
+	case "HIGH":
+		return t.High
+	case "LOW":
+		return t.Low
+	default:
+		return t.Medium
+	}
+}
+
+// AdminHandler serves operational endpoints under /admin
+type AdminHandler struct {
+	repository Repository
+	sseManager *SSEManager
+	taskPicker *TaskPicker
+	consumer   *Consumer
+	canary     *Canary // Optional; nil when the canary is disabled
+	sla        *SLAMonitor
+	expiry     *ExpirySweeper
+	reprio     *Reprioritizer
+	leaks      *LeakWatchdog
+	admission  *AdmissionController
+	chaos      *Chaos
+	eventTypes *EventTypeRegistry
+	sloTargets SLOTargets
+	logLevel   zap.AtomicLevel
+	startTime  time.Time
+	logger     *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(repo Repository, sseManager *SSEManager, taskPicker *TaskPicker, consumer *Consumer, canary *Canary, sla *SLAMonitor, expiry *ExpirySweeper, reprio *Reprioritizer, leaks *LeakWatchdog, admission *AdmissionController, chaos *Chaos, eventTypes *EventTypeRegistry, sloTargets SLOTargets, logLevel zap.AtomicLevel, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		repository: repo,
+		sseManager: sseManager,
+		taskPicker: taskPicker,
+		consumer:   consumer,
+		canary:     canary,
+		sla:        sla,
+		expiry:     expiry,
+		reprio:     reprio,
+		leaks:      leaks,
+		admission:  admission,
+		chaos:      chaos,
+		eventTypes: eventTypes,
+		sloTargets: sloTargets,
+		logLevel:   logLevel,
+		startTime:  time.Now(),
+		logger:     logger,
+	}
+}
+
+// RegisterRoutes mounts the admin endpoints on the router, all behind
+// adminAuth: they pause delivery, requeue and export across every tenant.
+func (h *AdminHandler) RegisterRoutes(router gin.IRouter, adminAuth gin.HandlerFunc) {
+	admin := router.Group("/admin", adminAuth)
+	admin.GET("/export", h.Export)
+	admin.GET("/export/notifications", h.ExportNotifications)
+	admin.GET("/stats", h.Stats)
+	admin.GET("/stats/breakdown", h.StatsBreakdown)
+	admin.GET("/tenants", h.Tenants)
+	admin.GET("/connections", h.Connections)
+	admin.POST("/connections/reconnect", h.ReconnectConnections)
+	admin.GET("/delivery", h.DeliveryState)
+	admin.POST("/delivery/pause", h.PauseDelivery)
+	admin.POST("/delivery/resume", h.ResumeDelivery)
+	admin.GET("/users/paused", h.PausedUsers)
+	admin.POST("/users/:user_id/pause", h.PauseUser)
+	admin.POST("/users/:user_id/resume", h.ResumeUser)
+	admin.POST("/failures/requeue", h.RequeueFailures)
+	admin.POST("/reclaim", h.Reclaim)
+	admin.GET("/audit/stream", h.AuditStream)
+	admin.GET("/canary", h.Canary)
+	admin.GET("/sla", h.SLA)
+	admin.GET("/expiry", h.Expiry)
+	admin.GET("/reprioritize", h.Reprioritize)
+	admin.GET("/goroutines", h.Goroutines)
+	admin.GET("/attempts", h.FlakyUsers)
+	admin.GET("/attempts/:notification_id", h.DeliveryAttempts)
+	admin.GET("/chaos", h.Chaos)
+	if h.chaos != nil {
+		// Only when the service was started with chaos enabled
+		admin.PUT("/chaos", h.SetChaos)
+	}
+	admin.GET("/event-types", h.EventTypes)
+	admin.GET("/event-types/:event_type", h.EventType)
+	admin.PUT("/event-types/:event_type", h.PutEventType)
+	admin.DELETE("/event-types/:event_type", h.DeleteEventType)
+	admin.GET("/segments", h.Segments)
+	admin.GET("/segments/:segment", h.Segment)
+	admin.PUT("/segments/:segment", h.PutSegment)
+	admin.DELETE("/segments/:segment", h.DeleteSegment)
+	// zap.AtomicLevel serves GET (current level) and PUT {"level":"debug"}
+	admin.GET("/log-level", gin.WrapH(h.logLevel))
+	admin.PUT("/log-level", gin.WrapH(h.logLevel))
+}
+
+// Stats returns current repository, picker, SSE and consumer stats. The
+// repository counts come from its counters unless ?exact=true, which counts
+// the whole table instead (slow on a large one).
+func (h *AdminHandler) Stats(c *gin.Context) {
+	getStats := h.repository.GetStats
+	if exact, _ := strconv.ParseBool(c.Query("exact")); exact {
+		getStats = h.repository.GetExactStats
+	}
+
+	stats, err := getStats(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to get stats", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats":        stats,
+		"picker":       h.taskPicker.Metrics(),
+		"sse":          h.sseManager.Stats(),
+		"consumer":     h.consumer.Stats(),
+		"expiry":       h.expiry.Stats(),
+		"reprioritize": h.reprio.Stats(),
+	})
+}
+
+// StatsBreakdown counts the notifications created in the window, with
+// their average and maximum event → delivery delay, grouped by
+// ?group_by= (a comma-separated subset of event_type, priority and status;
+// default all three), so a benchmark can tell which event classes lag.
+// ?window=run (since service start, default) or a duration such as 15m.
+func (h *AdminHandler) StatsBreakdown(c *gin.Context) {
+	window := c.DefaultQuery("window", "run")
+	since, ok := h.windowStart(c, window)
+	if !ok {
+		return
+	}
+
+	var group BreakdownGroup
+	groupBy := strings.Split(c.DefaultQuery("group_by", "event_type,priority,status"), ",")
+	for _, column := range groupBy {
+		switch strings.TrimSpace(column) {
+		case "event_type":
+			group.EventType = true
+		case "priority":
+			group.Priority = true
+		case "status":
+			group.Status = true
+		case "":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must list event_type, priority or status"})
+			return
+		}
+	}
+
+	breakdown, err := h.repository.GetStatsBreakdown(c.Request.Context(), since, group)
+	if err != nil {
+		h.logger.Error("failed to get stats breakdown", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch stats breakdown")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":       window,
+		"window_start": since,
+		"group_by":     groupBy,
+		"groups":       breakdown,
+	})
+}
+
+// Tenants returns notification status counts and open connections per tenant
+func (h *AdminHandler) Tenants(c *gin.Context) {
+	stats, err := h.repository.GetTenantStats(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to get tenant stats", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch tenant stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tenants":     stats,
+		"connections": h.sseManager.TenantConnections(),
+	})
+}
+
+// Connections lists open SSE connections, oldest first (?limit=100 default, 0 = all)
+func (h *AdminHandler) Connections(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+		return
+	}
+
+	connections := h.sseManager.Connections(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"total":       h.sseManager.GetActiveConnections(),
+		"count":       len(connections),
+		"connections": connections,
+	})
+}
+
+// ReconnectConnections sends a share of open streams a reconnect event with
+// a jittered retry hint (?fraction=1 default, optional ?user_id=); the
+// tenant comes from X-Tenant-ID when set
+func (h *AdminHandler) ReconnectConnections(c *gin.Context) {
+	fraction, err := strconv.ParseFloat(c.DefaultQuery("fraction", "1"), 64)
+	if err != nil || fraction <= 0 || fraction > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fraction must be in (0, 1]"})
+		return
+	}
+
+	requested := h.sseManager.RequestReconnect(ReconnectRequest{
+		TenantID: c.GetHeader(TenantHeader),
+		UserID:   c.Query("user_id"),
+		Fraction: fraction,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"requested": requested,
+		"total":     h.sseManager.GetActiveConnections(),
+	})
+}
+
+// DeliveryState reports whether claiming is paused
+func (h *AdminHandler) DeliveryState(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"paused": h.taskPicker.Paused()})
+}
+
+// PauseDelivery stops this instance from claiming new notifications
+func (h *AdminHandler) PauseDelivery(c *gin.Context) {
+	h.taskPicker.Pause()
+	c.JSON(http.StatusOK, gin.H{"paused": true})
+}
+
+// ResumeDelivery resumes claiming
+func (h *AdminHandler) ResumeDelivery(c *gin.Context) {
+	h.taskPicker.Resume()
+	c.JSON(http.StatusOK, gin.H{"paused": false})
+}
+
+// PausedUsers lists users whose delivery is paused on this instance
+func (h *AdminHandler) PausedUsers(c *gin.Context) {
+	users := h.taskPicker.PausedUsers()
+	c.JSON(http.StatusOK, gin.H{"count": len(users), "users": users})
+}
+
+// PauseUser parks a user's notifications until resumed (optional ?reason=);
+// the tenant comes from X-Tenant-ID
+func (h *AdminHandler) PauseUser(c *gin.Context) {
+	tenantID, userID, ok := h.pauseTarget(c)
+	if !ok {
+		return
+	}
+	changed := h.taskPicker.PauseUser(tenantID, userID, c.Query("reason"))
+	c.JSON(http.StatusOK, gin.H{"tenant_id": tenantID, "user_id": userID, "paused": true, "changed": changed})
+}
+
+// ResumeUser lifts a user's pause and delivers their held notifications
+func (h *AdminHandler) ResumeUser(c *gin.Context) {
+	tenantID, userID, ok := h.pauseTarget(c)
+	if !ok {
+		return
+	}
+	changed := h.taskPicker.ResumeUser(tenantID, userID)
+	c.JSON(http.StatusOK, gin.H{"tenant_id": tenantID, "user_id": userID, "paused": false, "changed": changed})
+}
+
+// pauseTarget resolves the tenant and user of a pause/resume request,
+// answering 400 when either is malformed
+func (h *AdminHandler) pauseTarget(c *gin.Context) (string, string, bool) {
+	tenantID := models.TenantOrDefault(c.GetHeader(TenantHeader))
+	if !tenantIDPattern.MatchString(tenantID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant id"})
+		return "", "", false
+	}
+	userID := c.Param("user_id")
+	if !validUserID(c, userID) {
+		return "", "", false
+	}
+	return tenantID, userID, true
+}
+
+// RequeueFailures moves failed notifications back to pending (?limit=1000 default)
+func (h *AdminHandler) RequeueFailures(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	count, err := h.repository.RequeueFailed(c.Request.Context(), limit)
+	if err != nil {
+		h.logger.Error("failed to requeue failures", zap.Error(err))
+		respondRepoError(c, err, "failed to requeue failures")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requeued": count})
+}
+
+// Reclaim resets expired leases immediately
+func (h *AdminHandler) Reclaim(c *gin.Context) {
+	count, err := h.taskPicker.ReclaimNow(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to reclaim stale tasks", zap.Error(err))
+		respondRepoError(c, err, "failed to reclaim stale tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reclaimed": count})
+}
+
+// Canary reports built-in probe latency and loss
+func (h *AdminHandler) Canary(c *gin.Context) {
+	if h.canary == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "canary is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, h.canary.Stats())
+}
+
+// Expiry returns lifetime expiry sweep totals and the last sweep's summary
+func (h *AdminHandler) Expiry(c *gin.Context) {
+	c.JSON(http.StatusOK, h.expiry.Stats())
+}
+
+// Reprioritize returns the reprioritizer's rules and lifetime totals
+func (h *AdminHandler) Reprioritize(c *gin.Context) {
+	c.JSON(http.StatusOK, h.reprio.Stats())
+}
+
+// SLA returns the latest rolling per-priority SLA compliance
+func (h *AdminHandler) SLA(c *gin.Context) {
+	c.JSON(http.StatusOK, h.sla.Report())
+}
+
+// Chaos returns the fault injection config and the faults injected so far
+func (h *AdminHandler) Chaos(c *gin.Context) {
+	c.JSON(http.StatusOK, h.chaos.Stats())
+}
+
+// SetChaos replaces the fault injection config; faults the body omits are
+// turned off
+func (h *AdminHandler) SetChaos(c *gin.Context) {
+	if h.chaos == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chaos injection is not available"})
+		return
+	}
+	var req chaosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg, err := req.config()
+	if err == nil {
+		err = h.chaos.SetConfig(cfg)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logger.Warn("chaos config changed",
+		zap.Duration("db_latency", cfg.DBLatency),
+		zap.Float64("db_latency_rate", cfg.DBLatencyRate),
+		zap.Float64("sse_write_fail_rate", cfg.SSEWriteFailRate),
+		zap.Duration("consumer_pause", cfg.ConsumerPause),
+		zap.Float64("consumer_pause_rate", cfg.ConsumerPauseRate),
+		zap.Float64("delivery_error_rate", cfg.DeliveryErrorRate))
+	c.JSON(http.StatusOK, h.chaos.Stats())
+}
+
+// EventTypes lists the event types registered at runtime
+func (h *AdminHandler) EventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"event_types": h.eventTypes.List()})
+}
+
+// EventType shows how an event type is delivered: the registration in
+// effect, if any, and the priority, digestibility, channel and TTL it gets
+func (h *AdminHandler) EventType(c *gin.Context) {
+	c.JSON(http.StatusOK, h.eventTypes.Describe(c.Param("event_type")))
+}
+
+// PutEventType registers an event type or family ("job.*"), replacing its
+// earlier registration. Fields the body omits take their defaults: the
+// built-in priority and templates, digestible, and the sse channel.
+func (h *AdminHandler) PutEventType(c *gin.Context) {
+	cfg := EventTypeConfig{Digestible: true}
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg.EventType = c.Param("event_type")
+	if err := h.eventTypes.Validate(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stored, err := h.eventTypes.Put(c.Request.Context(), cfg)
+	if err != nil {
+		h.logger.Error("failed to register event type", zap.String("event_type", cfg.EventType), zap.Error(err))
+		respondRepoError(c, err, "failed to register event type")
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteEventType removes an event type's registration, reverting it to
+// the built-in behavior
+func (h *AdminHandler) DeleteEventType(c *gin.Context) {
+	if err := h.eventTypes.Delete(c.Request.Context(), c.Param("event_type")); err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			h.logger.Error("failed to unregister event type", zap.String("event_type", c.Param("event_type")), zap.Error(err))
+		}
+		respondRepoError(c, err, "failed to unregister event type")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Segments lists the segments broadcasts can address, with their sizes
+func (h *AdminHandler) Segments(c *gin.Context) {
+	segments, err := h.repository.ListSegments(c.Request.Context())
+	if err != nil {
+		respondRepoError(c, err, "failed to list segments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"segments": segments})
+}
+
+// Segment shows a segment's members
+func (h *AdminHandler) Segment(c *gin.Context) {
+	segment, err := h.repository.GetSegment(c.Request.Context(), c.Param("segment"))
+	if err != nil {
+		respondRepoError(c, err, "failed to get segment")
+		return
+	}
+	c.JSON(http.StatusOK, segment)
+}
+
+// PutSegment registers a segment ({"user_ids": [...]}), replacing its
+// earlier members; broadcasts consumed afterwards reach the new ones
+func (h *AdminHandler) PutSegment(c *gin.Context) {
+	var segment Segment
+	if err := c.ShouldBindJSON(&segment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	segment.Segment = c.Param("segment")
+	if err := ValidateSegment(&segment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stored, err := h.repository.PutSegment(c.Request.Context(), segment)
+	if err != nil {
+		h.logger.Error("failed to register segment", zap.String("segment", segment.Segment), zap.Error(err))
+		respondRepoError(c, err, "failed to register segment")
+		return
+	}
+	h.logger.Info("segment registered", zap.String("segment", stored.Segment), zap.Int("size", stored.Size))
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteSegment removes a segment; broadcasts to it are then poisoned
+func (h *AdminHandler) DeleteSegment(c *gin.Context) {
+	if err := h.repository.DeleteSegment(c.Request.Context(), c.Param("segment")); err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			h.logger.Error("failed to delete segment", zap.String("segment", c.Param("segment")), zap.Error(err))
+		}
+		respondRepoError(c, err, "failed to delete segment")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Goroutines breaks live goroutines down by labeled pool against their
+// expected bounds (?refresh=true checks now instead of returning the last check)
+func (h *AdminHandler) Goroutines(c *gin.Context) {
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		c.JSON(http.StatusOK, h.leaks.Check())
+		return
+	}
+	c.JSON(http.StatusOK, h.leaks.Report())
+}
+
+// DeliveryAttempts returns the attempt history of one notification
+func (h *AdminHandler) DeliveryAttempts(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("notification_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notification_id must be a UUID"})
+		return
+	}
+
+	attempts, err := h.repository.GetDeliveryAttempts(c.Request.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get delivery attempts", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch delivery attempts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notification_id": id.String(),
+		"count":           len(attempts),
+		"attempts":        attempts,
+	})
+}
+
+// FlakyUsers lists users with the most failed attempts
+// (?window=run or a duration, default run; ?limit=20 default)
+func (h *AdminHandler) FlakyUsers(c *gin.Context) {
+	window := c.DefaultQuery("window", "run")
+	since, ok := h.windowStart(c, window)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	users, err := h.repository.GetFlakyUsers(c.Request.Context(), since, limit)
+	if err != nil {
+		h.logger.Error("failed to get flaky users", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch flaky users")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":       window,
+		"window_start": since,
+		"users":        users,
+	})
+}
+
+// AuditStream streams delivery audit events as SSE ("delivery" events)
+// until the client disconnects
+func (h *AdminHandler) AuditStream(c *gin.Context) {
+	runInPool(c.Request.Context(), PoolAuditStream, func() { h.auditStream(c) })
+}
+
+func (h *AdminHandler) auditStream(c *gin.Context) {
+	events, unsubscribe := h.taskPicker.Audit().Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: delivery\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// Export returns a single JSON document with everything a benchmark report
+// needs for the requested window: ?window=run (since service start, default)
+// or a duration such as ?window=15m
+func (h *AdminHandler) Export(c *gin.Context) {
+	window := c.DefaultQuery("window", "run")
+	since, ok := h.windowStart(c, window)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	stats, err := h.repository.GetStats(ctx)
+	if err != nil {
+		h.logger.Error("failed to get stats for export", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch stats")
+		return
+	}
+
+	slo, err := h.repository.GetSLOAttainment(ctx, since, h.sloTargets)
+	if err != nil {
+		h.logger.Error("failed to get slo attainment for export", zap.Error(err))
+		respondRepoError(c, err, "failed to fetch slo attainment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":          window,
+		"window_start":    since,
+		"window_end":      time.Now(),
+		"instance_uptime": time.Since(h.startTime).Seconds(),
+		"stats":           stats,
+		"stats_history":   h.taskPicker.StatsHistory(since),
+		"picker":          h.taskPicker.Metrics(),
+		"sse": gin.H{
+			"connections":     h.sseManager.Stats(),
+			"admission_queue": h.admission.QueueDepth(),
+		},
+		"consumer":       h.consumer.Stats(),
+		"slo_attainment": slo,
+		"sla":            h.sla.Report(),
+		"expiry":         h.expiry.Stats(),
+		"reprioritize":   h.reprio.Stats(),
+	})
+}
+
+// Rows ExportNotifications reads per query: by default, and at most
+// (?page_size=)
+const (
+	exportPageSize    = 1000
+	maxExportPageSize = 10000
+)
+
+// ExportNotifications streams the notifications matching the query as
+// NDJSON, one object per line in notification_id order, for offline
+// analysis of a benchmark dataset. Filters: ?tenant_id=, ?user_id=,
+// ?status=, ?event_type=, ?priority=, and ?since= / ?until= on created_at
+// (RFC 3339, or a duration meaning that long ago); ?limit= caps the rows
+// (default all). Rows are read a page at a time by keyset, so the export
+// never holds more than a page in memory. A failure after the first row
+// can't change the status any more: the stream stops and the
+// X-Export-Error trailer says why; X-Export-Rows counts what was written.
+func (h *AdminHandler) ExportNotifications(c *gin.Context) {
+	now := time.Now()
+	filter := ExportFilter{
+		TenantID:  c.Query("tenant_id"),
+		UserID:    c.Query("user_id"),
+		Status:    c.Query("status"),
+		EventType: c.Query("event_type"),
+		Priority:  c.Query("priority"),
+	}
+	var err error
+	if filter.Since, err = parseExportTime(c.Query("since"), now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time or a positive duration"})
+		return
+	}
+	if filter.Until, err = parseExportTime(c.Query("until"), now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC 3339 time or a positive duration"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer (0 for all)"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(exportPageSize)))
+	if err != nil || pageSize <= 0 || pageSize > maxExportPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", maxExportPageSize)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	next := func(after uuid.UUID, written int) ([]*ExportedNotification, error) {
+		size := pageSize
+		if limit > 0 {
+			size = min(size, limit-written)
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		return h.repository.ExportNotifications(ctx, filter, after, size)
+	}
+
+	// The first page is read before the response starts, so a bad store
+	// still gets a proper error status
+	page, err := next(uuid.Nil, 0)
+	if err != nil {
+		h.logger.Error("failed to export notifications", zap.Error(err))
+		respondRepoError(c, err, "failed to export notifications")
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Trailer", "X-Export-Rows, X-Export-Error")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	written := 0
+	for len(page) > 0 {
+		for _, n := range page {
+			if err := enc.Encode(n); err != nil {
+				return // Client went away
+			}
+		}
+		written += len(page)
+		c.Writer.Flush()
+
+		if len(page) < pageSize {
+			break
+		}
+		if page, err = next(page[len(page)-1].NotificationID, written); err != nil {
+			h.logger.Error("notification export failed mid-stream", zap.Int("rows", written), zap.Error(err))
+			c.Writer.Header().Set("X-Export-Error", err.Error())
+			break
+		}
+	}
+	c.Writer.Header().Set("X-Export-Rows", strconv.Itoa(written))
+
+	h.logger.Info("exported notifications",
+		zap.Int("rows", written),
+		zap.Any("filter", filter),
+		zap.Duration("duration", time.Since(now)))
+}
+
+// parseExportTime reads an export bound: empty for none, an RFC 3339 time,
+// or a duration meaning that long before now
+func parseExportTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, errors.New("duration must be positive")
+		}
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// windowStart reads a ?window= value: run (since service start) or a
+// positive duration back from now. On a bad value it answers 400 and
+// returns false.
+func (h *AdminHandler) windowStart(c *gin.Context, window string) (time.Time, bool) {
+	if window == "run" {
+		return h.startTime, true
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be 'run' or a positive duration"})
+		return time.Time{}, false
+	}
+	return time.Now().Add(-d), true
+}
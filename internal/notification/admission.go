@@ -0,0 +1,130 @@
+package notification
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// AdmissionConfig holds configuration for SSE connection admission control
+type AdmissionConfig struct {
+	AcceptRate   float64       // New connections admitted per second
+	AcceptBurst  int           // Connections that may be admitted at once
+	MaxQueueWait time.Duration // Longest a connection may wait for a token
+	MaxQueued    int           // Connections allowed to wait concurrently
+}
+
+// AdmissionController rate-limits new SSE connections so that reconnect
+// storms (e.g. after a restart) don't all hit AddConnection at once.
+// Connections beyond the rate wait in a bounded queue, or are rejected
+// with a Retry-After hint when the queue is full or the wait too long.
+type AdmissionController struct {
+	limiter      *rate.Limiter
+	maxQueueWait time.Duration
+	maxQueued    int64
+	queued       int64
+	rejections   rejectionWindow
+	logger       *zap.Logger
+}
+
+// errRateLimited is the rejection error of connections over the accept rate
+var errRateLimited = errors.New("connection rate limit exceeded")
+
+// NewAdmissionController creates a new admission controller
+func NewAdmissionController(cfg AdmissionConfig, logger *zap.Logger) *AdmissionController {
+	return &AdmissionController{
+		limiter:      rate.NewLimiter(rate.Limit(cfg.AcceptRate), cfg.AcceptBurst),
+		maxQueueWait: cfg.MaxQueueWait,
+		maxQueued:    int64(cfg.MaxQueued),
+		logger:       logger,
+	}
+}
+
+// Middleware returns a gin handler that admits or rejects the request
+func (a *AdmissionController) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admitted, retryAfter := a.admit(c)
+		if !admitted {
+			writeRejection(c, &RejectionError{
+				Reason:     RejectRateLimited,
+				RetryAfter: a.retryAfter(retryAfter),
+				Err:        errRateLimited,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// admit blocks until the connection may proceed, or reports how long
+// the client should back off before retrying
+func (a *AdmissionController) admit(c *gin.Context) (bool, time.Duration) {
+	now := time.Now()
+	reservation := a.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		metrics.AdmissionRejected.Inc()
+		return false, time.Second
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		metrics.AdmissionAccepted.Inc()
+		return true, 0
+	}
+
+	if delay > a.maxQueueWait {
+		reservation.CancelAt(now)
+		metrics.AdmissionRejected.Inc()
+		return false, delay
+	}
+
+	if atomic.AddInt64(&a.queued, 1) > a.maxQueued {
+		atomic.AddInt64(&a.queued, -1)
+		reservation.CancelAt(now)
+		metrics.AdmissionRejected.Inc()
+		return false, delay
+	}
+	defer atomic.AddInt64(&a.queued, -1)
+
+	metrics.AdmissionQueued.Inc()
+	metrics.AdmissionQueueDepth.Inc()
+	defer metrics.AdmissionQueueDepth.Dec()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		metrics.AdmissionAccepted.Inc()
+		metrics.AdmissionWaitSeconds.Observe(delay.Seconds())
+		return true, 0
+	case <-c.Request.Context().Done():
+		// Client gave up while queued
+		reservation.Cancel()
+		a.logger.Debug("client left admission queue", zap.Duration("remaining_wait", delay))
+		return false, delay
+	}
+}
+
+// retryAfter is the hint for a rejected connection: at least the wait for
+// its token, and long enough for the queue and the connections refused in
+// the last second, which will all be back, to be admitted at the accept rate
+func (a *AdmissionController) retryAfter(tokenWait time.Duration) time.Duration {
+	backlog := float64(atomic.LoadInt64(&a.queued)) + a.rejections.add(time.Now())
+	d := tokenWait
+	if limit := float64(a.limiter.Limit()); limit > 0 {
+		d = max(d, time.Duration(backlog/limit*float64(time.Second)))
+	}
+	return spreadRetryAfter(d)
+}
+
+// QueueDepth returns the number of connections currently waiting
+func (a *AdmissionController) QueueDepth() int64 {
+	return atomic.LoadInt64(&a.queued)
+}
@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"notification-delivery-system/internal/models"
+)
+
+// MaxSegmentLength matches the segments.segment VARCHAR(64) column
+const MaxSegmentLength = 64
+
+// DefaultMaxBroadcastRecipients caps the notifications one broadcast
+// expands into
+const DefaultMaxBroadcastRecipients = 10000
+
+// segmentPattern matches a segment name ("beta-testers", "plan.pro")
+var segmentPattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// ErrInvalidAudience marks a broadcast addressed in a way no retry can fix
+var ErrInvalidAudience = errors.New("invalid broadcast audience")
+
+// Audience resolves who a broadcast event is for: its user_ids, a
+// registered segment's members, a topic's subscribers in its tenant or, for
+// models.SegmentAll, every user of its tenant connected to this instance.
+// Each event is consumed by one instance, so with several instances "all"
+// only reaches that instance's users; address a registered segment to reach
+// everyone.
+type Audience struct {
+	repo          Repository
+	sseManager    *SSEManager
+	maxRecipients int
+}
+
+// NewAudience creates a resolver that reads segments from repo and
+// connected users from sseManager (maxRecipients defaults to
+// DefaultMaxBroadcastRecipients)
+func NewAudience(repo Repository, sseManager *SSEManager, maxRecipients int) *Audience {
+	if maxRecipients <= 0 {
+		maxRecipients = DefaultMaxBroadcastRecipients
+	}
+	return &Audience{
+		repo:          repo,
+		sseManager:    sseManager,
+		maxRecipients: maxRecipients,
+	}
+}
+
+// ValidateSegment checks a segment's name and members, dropping duplicate
+// members
+func ValidateSegment(segment *Segment) error {
+	if len(segment.Segment) > MaxSegmentLength {
+		return fmt.Errorf("segment exceeds %d bytes", MaxSegmentLength)
+	}
+	if !segmentPattern.MatchString(segment.Segment) {
+		return fmt.Errorf("invalid segment %q: expected lower-case names such as beta-testers or plan.pro", segment.Segment)
+	}
+	if segment.Segment == models.SegmentAll {
+		return fmt.Errorf("segment %q is reserved for every connected user", models.SegmentAll)
+	}
+	members, err := distinctUsers(segment.UserIDs)
+	if err != nil {
+		return err
+	}
+	segment.UserIDs = members
+	segment.Size = len(members)
+	return nil
+}
+
+// Resolve returns the distinct recipients of a broadcast in its tenant.
+// Errors wrapping ErrInvalidAudience mean the event is malformed; others
+// (an unknown segment, an unavailable store) that it could not be resolved
+// now.
+func (a *Audience) Resolve(ctx context.Context, msg *models.KafkaMessage) ([]string, error) {
+	if a == nil {
+		return nil, errors.New("broadcasts are not enabled on this consumer")
+	}
+	addressed := 0
+	for _, set := range []bool{msg.UserID != "", len(msg.UserIDs) > 0, msg.Segment != "", msg.Topic != ""} {
+		if set {
+			addressed++
+		}
+	}
+	if addressed > 1 {
+		return nil, fmt.Errorf("%w: user_id, user_ids, segment and topic are mutually exclusive", ErrInvalidAudience)
+	}
+
+	var users []string
+	switch {
+	case len(msg.UserIDs) > 0:
+		users = msg.UserIDs
+	case msg.Topic != "":
+		if err := ValidateTopic(msg.Topic); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidAudience, err)
+		}
+		subscribers, err := a.repo.TopicSubscribers(ctx, msg.TenantID, msg.Topic)
+		if err != nil {
+			return nil, err
+		}
+		users = subscribers
+	case msg.Segment == models.SegmentAll:
+		tenantID := models.TenantOrDefault(msg.TenantID)
+		for _, user := range a.sseManager.ConnectedUsers() {
+			if models.TenantOrDefault(user.TenantID) == tenantID {
+				users = append(users, user.UserID)
+			}
+		}
+	default:
+		segment, err := a.repo.GetSegment(ctx, msg.Segment)
+		if err != nil {
+			return nil, err
+		}
+		users = segment.UserIDs
+	}
+
+	recipients, err := distinctUsers(users)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAudience, err)
+	}
+	if len(recipients) > a.maxRecipients {
+		return nil, fmt.Errorf("%w: %d recipients exceed the limit of %d", ErrInvalidAudience, len(recipients), a.maxRecipients)
+	}
+	return recipients, nil
+}
+
+// distinctUsers validates user IDs and drops repeats, keeping the order
+func distinctUsers(userIDs []string) ([]string, error) {
+	seen := make(map[string]bool, len(userIDs))
+	distinct := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if err := models.ValidateUserID(userID); err != nil {
+			return nil, fmt.Errorf("recipient %q: %w", userID, err)
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			distinct = append(distinct, userID)
+		}
+	}
+	return distinct, nil
+}
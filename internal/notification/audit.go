@@ -0,0 +1,94 @@
+package notification
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AuditEvent records the outcome of a single delivery attempt
+type AuditEvent struct {
+	NotificationID string    `json:"notification_id"`
+	TenantID       string    `json:"tenant_id"`
+	UserID         string    `json:"user_id"`
+	EventType      string    `json:"event_type"`
+	Priority       string    `json:"priority"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	GroupSize      int       `json:"group_size"`
+	LatencyMs      float64   `json:"latency_ms"`
+	InstanceID     string    `json:"instance_id"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// auditSubscriberBuffer is the per-subscriber backlog before events are dropped
+const auditSubscriberBuffer = 1000
+
+// AuditLog fans delivery audit events out to live subscribers (e.g. notifctl tail).
+// Publishing never blocks delivery: slow subscribers drop events.
+type AuditLog struct {
+	mu          sync.RWMutex
+	subscribers map[chan AuditEvent]struct{}
+	dropped     int64
+}
+
+// NewAuditLog creates an empty audit log
+func NewAuditLog() *AuditLog {
+	return &AuditLog{
+		subscribers: make(map[chan AuditEvent]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber. The returned func unsubscribes and
+// closes the channel.
+func (a *AuditLog) Subscribe() (<-chan AuditEvent, func()) {
+	ch := make(chan AuditEvent, auditSubscriberBuffer)
+
+	a.mu.Lock()
+	a.subscribers[ch] = struct{}{}
+	a.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			a.mu.Lock()
+			delete(a.subscribers, ch)
+			a.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event to every subscriber without blocking
+func (a *AuditLog) Publish(event AuditEvent) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for ch := range a.subscribers {
+		select {
+		case ch <- event:
+		default:
+			atomic.AddInt64(&a.dropped, 1)
+		}
+	}
+}
+
+// HasSubscribers reports whether anyone is listening, so callers can skip
+// building events nobody will read
+func (a *AuditLog) HasSubscribers() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.subscribers) > 0
+}
+
+// Subscribers returns the number of live subscribers
+func (a *AuditLog) Subscribers() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.subscribers)
+}
+
+// Dropped returns the number of events dropped for slow subscribers
+func (a *AuditLog) Dropped() int64 {
+	return atomic.LoadInt64(&a.dropped)
+}
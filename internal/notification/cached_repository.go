@@ -0,0 +1,217 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// UserCache caches per-user read models. Keys are connectionKey(tenant, user).
+// *cache.RedisCache is the production implementation.
+type UserCache interface {
+	GetNotifications(ctx context.Context, key string, limit int) ([]map[string]interface{}, bool, error)
+	SetNotifications(ctx context.Context, key string, limit int, notifications []map[string]interface{}) error
+	Invalidate(ctx context.Context, keys ...string) error
+}
+
+// CachedRepository serves user queries from a UserCache so heavy REST read
+// traffic doesn't contend with the queue workload, and invalidates a user's
+// entries on every write that changes what they would see (insert, claim,
+// delivery, marking read). Bulk maintenance (reclaim, requeue) relies on the cache TTL.
+// Cache errors are logged and fall through to the wrapped repository.
+type CachedRepository struct {
+	Repository
+	cache  UserCache
+	logger *zap.Logger
+}
+
+// NewCachedRepository wraps repo with a read-model cache
+func NewCachedRepository(repo Repository, cache UserCache, logger *zap.Logger) *CachedRepository {
+	return &CachedRepository{
+		Repository: repo,
+		cache:      cache,
+		logger:     logger,
+	}
+}
+
+// Insert adds a notification and invalidates its user's cache
+func (r *CachedRepository) Insert(ctx context.Context, notification *models.Notification) error {
+	return r.BatchInsert(ctx, []*models.Notification{notification})
+}
+
+// BatchInsert inserts notifications and invalidates their users' caches
+func (r *CachedRepository) BatchInsert(ctx context.Context, notifications []*models.Notification) error {
+	if err := r.Repository.BatchInsert(ctx, notifications); err != nil {
+		return err
+	}
+
+	keys := make([]string, len(notifications))
+	for i, n := range notifications {
+		keys[i] = connectionKey(n.TenantID, n.UserID)
+	}
+	r.invalidate(ctx, keys)
+	return nil
+}
+
+// ClaimBatch claims notifications and invalidates their users' caches
+func (r *CachedRepository) ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	batch, err := r.Repository.ClaimBatch(ctx, instanceID, batchSize, leaseDuration, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidateClaimed(ctx, batch)
+	return batch, nil
+}
+
+// ClaimForUsers claims connected users' notifications and invalidates their
+// caches
+func (r *CachedRepository) ClaimForUsers(ctx context.Context, instanceID string, users []UserRef, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	batch, err := r.Repository.ClaimForUsers(ctx, instanceID, users, batchSize, leaseDuration, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidateClaimed(ctx, batch)
+	return batch, nil
+}
+
+// ClaimWeighted claims notifications by priority and invalidates their
+// users' caches
+func (r *CachedRepository) ClaimWeighted(ctx context.Context, instanceID string, quotas map[models.Priority]int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	batch, err := r.Repository.ClaimWeighted(ctx, instanceID, quotas, leaseDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidateClaimed(ctx, batch)
+	return batch, nil
+}
+
+// invalidateClaimed drops the cached notifications of a claim's users
+func (r *CachedRepository) invalidateClaimed(ctx context.Context, batch []*NotificationBatch) {
+	keys := make([]string, len(batch))
+	for i, n := range batch {
+		keys[i] = connectionKey(n.TenantID, n.UserID)
+	}
+	r.invalidate(ctx, keys)
+}
+
+// ClaimParked un-parks a user's notifications and invalidates their cache
+func (r *CachedRepository) ClaimParked(ctx context.Context, tenantID, userID, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	batch, err := r.Repository.ClaimParked(ctx, tenantID, userID, instanceID, batchSize, leaseDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(batch) > 0 {
+		r.invalidate(ctx, []string{connectionKey(tenantID, userID)})
+	}
+	return batch, nil
+}
+
+// BatchUpdateStatus records delivery outcomes and invalidates the users' caches
+func (r *CachedRepository) BatchUpdateStatus(ctx context.Context, updates []*StatusUpdate) error {
+	if err := r.Repository.BatchUpdateStatus(ctx, updates); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(updates))
+	for _, u := range updates {
+		if u.UserID != "" {
+			keys = append(keys, connectionKey(u.TenantID, u.UserID))
+		}
+	}
+	r.invalidate(ctx, keys)
+	return nil
+}
+
+// MarkRead marks a user's notifications read and invalidates their cache
+func (r *CachedRepository) MarkRead(ctx context.Context, tenantID, userID string, notificationIDs []uuid.UUID) (int, error) {
+	marked, err := r.Repository.MarkRead(ctx, tenantID, userID, notificationIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	if marked > 0 {
+		r.invalidate(ctx, []string{connectionKey(tenantID, userID)})
+	}
+	return marked, nil
+}
+
+// ArchiveNotification archives a user's notification and invalidates their cache
+func (r *CachedRepository) ArchiveNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	if err := r.Repository.ArchiveNotification(ctx, tenantID, userID, notificationID); err != nil {
+		return err
+	}
+	r.invalidate(ctx, []string{connectionKey(tenantID, userID)})
+	return nil
+}
+
+// DeleteNotification deletes a user's notification and invalidates their cache
+func (r *CachedRepository) DeleteNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	if err := r.Repository.DeleteNotification(ctx, tenantID, userID, notificationID); err != nil {
+		return err
+	}
+	r.invalidate(ctx, []string{connectionKey(tenantID, userID)})
+	return nil
+}
+
+// GetUserNotifications serves from the cache, loading and caching on a miss.
+// Only the default listing is cached; one including archived notifications
+// always reads the repository.
+func (r *CachedRepository) GetUserNotifications(ctx context.Context, tenantID, userID string, limit int, includeArchived bool) ([]map[string]interface{}, error) {
+	if includeArchived {
+		return r.Repository.GetUserNotifications(ctx, tenantID, userID, limit, true)
+	}
+
+	key := connectionKey(tenantID, userID)
+
+	cached, found, err := r.cache.GetNotifications(ctx, key, limit)
+	switch {
+	case err != nil:
+		metrics.CacheRequests.WithLabelValues("error").Inc()
+		r.logger.Warn("cache read failed, falling back to repository", zap.String("key", key), zap.Error(err))
+	case found:
+		metrics.CacheRequests.WithLabelValues("hit").Inc()
+		return cached, nil
+	default:
+		metrics.CacheRequests.WithLabelValues("miss").Inc()
+	}
+
+	notifications, err := r.Repository.GetUserNotifications(ctx, tenantID, userID, limit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.cache.SetNotifications(ctx, key, limit, notifications); err != nil {
+		r.logger.Warn("failed to populate cache", zap.String("key", key), zap.Error(err))
+	}
+	return notifications, nil
+}
+
+// invalidate drops cached entries for the distinct keys, logging failures
+func (r *CachedRepository) invalidate(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	unique := keys[:0]
+	for _, key := range keys {
+		if _, dup := seen[key]; !dup {
+			seen[key] = struct{}{}
+			unique = append(unique, key)
+		}
+	}
+
+	if err := r.cache.Invalidate(ctx, unique...); err != nil {
+		metrics.CacheInvalidationErrors.Inc()
+		r.logger.Warn("failed to invalidate cache", zap.Int("keys", len(unique)), zap.Error(err))
+	}
+}
@@ -0,0 +1,403 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/producer"
+)
+
+const (
+	// CanaryTenantID isolates probe traffic from real tenants
+	CanaryTenantID = "canary"
+	// canaryWindow is how many recent probe outcomes feed the degraded check
+	canaryWindow = 100
+)
+
+// CanaryConfig controls the built-in end-to-end probe
+type CanaryConfig struct {
+	Interval         time.Duration
+	Users            int
+	Timeout          time.Duration
+	LatencyThreshold time.Duration
+}
+
+// CanaryStats summarises probe outcomes since start and over the recent window
+type CanaryStats struct {
+	Running         bool      `json:"running"`
+	Users           int       `json:"users"`
+	Sent            int64     `json:"sent"`
+	Received        int64     `json:"received"`
+	Lost            int64     `json:"lost"`
+	Pending         int       `json:"pending"`
+	OldestPendingMs float64   `json:"oldest_pending_ms"`
+	WindowSize      int       `json:"window_size"`
+	WindowLost      int       `json:"window_lost"`
+	P50Ms           float64   `json:"p50_ms"`
+	P95Ms           float64   `json:"p95_ms"`
+	MaxMs           float64   `json:"max_ms"`
+	ThresholdMs     float64   `json:"threshold_ms"`
+	Degraded        bool      `json:"degraded"`
+	LastProbeAt     time.Time `json:"last_probe_at"`
+	LastReceivedAt  time.Time `json:"last_received_at"`
+	DegradedSince   time.Time `json:"degraded_since"`
+}
+
+// canaryOutcome is one entry in the recent window: a latency or a loss
+type canaryOutcome struct {
+	latency time.Duration
+	lost    bool
+}
+
+// Canary continuously publishes low-rate probe notifications for dedicated
+// users held open through internal SSE connections, measuring true
+// publish → Kafka → DB → picker → SSE latency from inside the service.
+// It runs independently of any external benchmark.
+type Canary struct {
+	config     CanaryConfig
+	publisher  producer.Publisher
+	sseManager *SSEManager
+	logger     *zap.Logger
+
+	mu             sync.Mutex
+	running        bool
+	pending        map[string]time.Time // probe_id → sent at
+	recent         []canaryOutcome
+	sent           int64
+	received       int64
+	lost           int64
+	lastProbeAt    time.Time
+	lastReceivedAt time.Time
+	degraded       bool
+	degradedSince  time.Time
+}
+
+// NewCanary creates a canary that publishes probes through publisher
+func NewCanary(config CanaryConfig, publisher producer.Publisher, sseManager *SSEManager, logger *zap.Logger) *Canary {
+	if config.Users <= 0 {
+		config.Users = 1
+	}
+	return &Canary{
+		config:     config,
+		publisher:  publisher,
+		sseManager: sseManager,
+		logger:     logger,
+		pending:    make(map[string]time.Time),
+	}
+}
+
+// Start opens the canary connections and begins probing until ctx is done
+func (c *Canary) Start(ctx context.Context) {
+	c.mu.Lock()
+	c.running = true
+	c.mu.Unlock()
+
+	for i := 0; i < c.config.Users; i++ {
+		go c.listen(ctx, fmt.Sprintf("canary_%d", i))
+	}
+	go c.probeLoop(ctx)
+
+	c.logger.Info("canary started",
+		zap.Int("users", c.config.Users),
+		zap.Duration("interval", c.config.Interval),
+		zap.Duration("latency_threshold", c.config.LatencyThreshold))
+}
+
+// listen holds an internal SSE connection open for userID, reconnecting if
+// it is dropped (e.g. by stale cleanup or a full connection table)
+func (c *Canary) listen(ctx context.Context, userID string) {
+	for ctx.Err() == nil {
+		conn, err := c.sseManager.AddConnection(CanaryTenantID, userID)
+		if err != nil {
+			c.logger.Warn("canary connection rejected", zap.String("user_id", userID), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.config.Interval):
+			}
+			continue
+		}
+
+		c.consume(ctx, conn)
+		if ctx.Err() != nil {
+			c.sseManager.RemoveConnection(conn)
+			return
+		}
+	}
+}
+
+// consume reads frames until ctx is done or the channel is closed
+func (c *Canary) consume(ctx context.Context, conn *SSEConnection) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-conn.ClientChan:
+			if !ok {
+				return
+			}
+			conn.touch(time.Now())
+			c.handleFrame(frame)
+		}
+	}
+}
+
+// handleFrame extracts probe IDs from a "notification" or grouped
+// "notifications" SSE frame
+func (c *Canary) handleFrame(frame []byte) {
+	receivedAt := time.Now()
+
+	var data []byte
+	for _, line := range bytes.Split(frame, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("data: ")) {
+			data = bytes.TrimPrefix(line, []byte("data: "))
+			break
+		}
+	}
+	if data == nil {
+		return
+	}
+
+	var envelope struct {
+		models.NotificationEvent
+		Notifications []models.NotificationEvent `json:"notifications"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return
+	}
+
+	payloads := []map[string]string{envelope.Payload}
+	for _, n := range envelope.Notifications {
+		payloads = append(payloads, n.Payload)
+	}
+
+	for _, probe := range payloads {
+		if id := probe["probe_id"]; id != "" {
+			c.observe(id, receivedAt)
+		}
+	}
+}
+
+// observe records a received probe
+func (c *Canary) observe(probeID string, receivedAt time.Time) {
+	c.mu.Lock()
+	sentAt, ok := c.pending[probeID]
+	if !ok {
+		// Already timed out, or a redelivery
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, probeID)
+
+	latency := receivedAt.Sub(sentAt)
+	c.received++
+	c.lastReceivedAt = receivedAt
+	c.record(canaryOutcome{latency: latency})
+	c.mu.Unlock()
+
+	metrics.CanaryLatencySeconds.Observe(latency.Seconds())
+}
+
+// probeLoop publishes one probe per interval, round-robin across canary users
+func (c *Canary) probeLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.running = false
+			c.mu.Unlock()
+			return
+		case <-ticker.C:
+			c.expire()
+			c.publish(ctx, fmt.Sprintf("canary_%d", i%c.config.Users))
+			c.evaluate()
+		}
+	}
+}
+
+// publish sends a single HIGH priority probe
+func (c *Canary) publish(ctx context.Context, userID string) {
+	probeID := idgen.NewID().String()
+	now := time.Now()
+
+	msg := &models.KafkaMessage{
+		EventID:        probeID,
+		TenantID:       CanaryTenantID,
+		EventType:      string(models.EventCanaryProbe),
+		Priority:       string(models.PriorityHigh),
+		UserID:         userID,
+		EventTimestamp: now,
+		Payload: map[string]string{
+			"probe_id": probeID,
+			"sent_at":  now.Format(time.RFC3339Nano),
+		},
+		Metadata: models.Metadata{
+			SourceService: "canary",
+		},
+	}
+
+	// Register before publishing so a fast delivery isn't missed
+	c.mu.Lock()
+	c.pending[probeID] = now
+	c.lastProbeAt = now
+	c.mu.Unlock()
+
+	if err := c.publisher.PublishNotification(ctx, msg); err != nil {
+		c.mu.Lock()
+		delete(c.pending, probeID)
+		c.mu.Unlock()
+		if ctx.Err() == nil {
+			c.logger.Warn("failed to publish canary probe", zap.String("user_id", userID), zap.Error(err))
+		}
+		return
+	}
+
+	c.mu.Lock()
+	c.sent++
+	c.mu.Unlock()
+	metrics.CanaryProbesSent.Inc()
+}
+
+// expire marks probes older than the timeout as lost
+func (c *Canary) expire() {
+	cutoff := time.Now().Add(-c.config.Timeout)
+
+	c.mu.Lock()
+	expired := 0
+	for id, sentAt := range c.pending {
+		if sentAt.Before(cutoff) {
+			delete(c.pending, id)
+			c.lost++
+			c.record(canaryOutcome{lost: true})
+			expired++
+		}
+	}
+	c.mu.Unlock()
+
+	if expired > 0 {
+		metrics.CanaryProbesLost.Add(float64(expired))
+	}
+}
+
+// record appends an outcome to the recent window. Caller holds c.mu.
+func (c *Canary) record(outcome canaryOutcome) {
+	c.recent = append(c.recent, outcome)
+	if len(c.recent) > canaryWindow {
+		c.recent = c.recent[len(c.recent)-canaryWindow:]
+	}
+}
+
+// evaluate flips the degraded state and alerts on transitions
+func (c *Canary) evaluate() {
+	c.mu.Lock()
+	p50, p95, maxLatency, windowLost := c.windowStats()
+	oldestPending := c.oldestPending()
+	// A stalled pipeline shows up as aging pending probes long before they time out
+	degraded := windowLost > 0 || p95 > c.config.LatencyThreshold || oldestPending > c.config.LatencyThreshold
+	changed := degraded != c.degraded
+	c.degraded = degraded
+	if changed && degraded {
+		c.degradedSince = time.Now()
+	}
+	degradedFor := time.Since(c.degradedSince)
+	c.mu.Unlock()
+
+	if degraded {
+		metrics.CanaryDegraded.Set(1)
+	} else {
+		metrics.CanaryDegraded.Set(0)
+	}
+
+	if !changed {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.Duration("p50", p50),
+		zap.Duration("p95", p95),
+		zap.Duration("max", maxLatency),
+		zap.Int("window_lost", windowLost),
+		zap.Duration("oldest_pending", oldestPending),
+		zap.Duration("threshold", c.config.LatencyThreshold),
+	}
+	if degraded {
+		c.logger.Warn("canary degraded: end-to-end delivery is slow or losing probes", fields...)
+	} else {
+		c.logger.Info("canary recovered", append(fields, zap.Duration("degraded_for", degradedFor))...)
+	}
+}
+
+// windowStats computes latency percentiles and losses over the recent
+// window. Caller holds c.mu.
+func (c *Canary) windowStats() (p50, p95, maxLatency time.Duration, lost int) {
+	latencies := make([]time.Duration, 0, len(c.recent))
+	for _, o := range c.recent {
+		if o.lost {
+			lost++
+			continue
+		}
+		latencies = append(latencies, o.latency)
+	}
+	if len(latencies) == 0 {
+		return 0, 0, 0, lost
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	at := func(p float64) time.Duration {
+		return latencies[int(float64(len(latencies)-1)*p)]
+	}
+	return at(0.50), at(0.95), latencies[len(latencies)-1], lost
+}
+
+// oldestPending returns the age of the oldest unanswered probe. Caller holds c.mu.
+func (c *Canary) oldestPending() time.Duration {
+	var oldest time.Duration
+	now := time.Now()
+	for _, sentAt := range c.pending {
+		oldest = max(oldest, now.Sub(sentAt))
+	}
+	return oldest
+}
+
+// Stats returns a snapshot of probe outcomes
+func (c *Canary) Stats() CanaryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p50, p95, maxLatency, windowLost := c.windowStats()
+	stats := CanaryStats{
+		Running:         c.running,
+		Users:           c.config.Users,
+		Sent:            c.sent,
+		Received:        c.received,
+		Lost:            c.lost,
+		Pending:         len(c.pending),
+		OldestPendingMs: float64(c.oldestPending().Microseconds()) / 1000,
+		WindowSize:      len(c.recent),
+		WindowLost:      windowLost,
+		P50Ms:           float64(p50.Microseconds()) / 1000,
+		P95Ms:           float64(p95.Microseconds()) / 1000,
+		MaxMs:           float64(maxLatency.Microseconds()) / 1000,
+		ThresholdMs:     float64(c.config.LatencyThreshold.Microseconds()) / 1000,
+		Degraded:        c.degraded,
+		LastProbeAt:     c.lastProbeAt,
+		LastReceivedAt:  c.lastReceivedAt,
+	}
+	if c.degraded {
+		stats.DegradedSince = c.degradedSince
+	}
+	return stats
+}
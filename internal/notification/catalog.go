@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FallbackLocale is the locale everything falls back to; the built-in
+// templates and messages are written in it
+const FallbackLocale = "en"
+
+// Message catalog keys used by the server itself. Templates may look up
+// any key with {{t "key"}}.
+const (
+	MsgNotificationTitle   = "notification.title"   // Title when an event type's template has none or fails
+	MsgNotificationMessage = "notification.message" // Message likewise
+	MsgTimeAgoJustNow      = "time_ago.just_now"
+	MsgTimeAgoSeconds      = "time_ago.seconds" // fmt format with a single %d
+	MsgTimeAgoMinute       = "time_ago.minute"
+	MsgTimeAgoMinutes      = "time_ago.minutes" // %d
+	MsgTimeAgoHour         = "time_ago.hour"
+	MsgTimeAgoHours        = "time_ago.hours" // %d
+	MsgTimeAgoDay          = "time_ago.day"
+	MsgTimeAgoDays         = "time_ago.days" // %d
+)
+
+// MessageCatalog holds translated strings by locale and key. Lookups try
+// the locale, its base language, then FallbackLocale; a key missing from
+// all of them renders as itself so the gap is visible.
+type MessageCatalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// NewMessageCatalog creates an empty catalog
+func NewMessageCatalog() *MessageCatalog {
+	return &MessageCatalog{messages: make(map[string]map[string]string)}
+}
+
+// Add sets messages of a locale, replacing earlier ones with the same key
+func (c *MessageCatalog) Add(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, ok := c.messages[locale]
+	if !ok {
+		existing = make(map[string]string, len(messages))
+		c.messages[locale] = existing
+	}
+	for key, text := range messages {
+		existing[key] = text
+	}
+}
+
+// Lookup returns the message for key in locale
+func (c *MessageCatalog) Lookup(locale, key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, candidate := range localeChain(locale) {
+		if text, ok := c.messages[candidate][key]; ok {
+			return text
+		}
+	}
+	return key
+}
+
+// Locales returns the locales with messages
+func (c *MessageCatalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// TimeAgo describes an age the way a notification feed does, in locale
+func (c *MessageCatalog) TimeAgo(locale string, d time.Duration) string {
+	count := func(n int, one, many string) string {
+		if n == 1 {
+			return c.Lookup(locale, one)
+		}
+		return fmt.Sprintf(c.Lookup(locale, many), n)
+	}
+	switch {
+	case d < 10*time.Second:
+		return c.Lookup(locale, MsgTimeAgoJustNow)
+	case d < time.Minute:
+		return fmt.Sprintf(c.Lookup(locale, MsgTimeAgoSeconds), int(d.Seconds()))
+	case d < time.Hour:
+		return count(int(d.Minutes()), MsgTimeAgoMinute, MsgTimeAgoMinutes)
+	case d < 24*time.Hour:
+		return count(int(d.Hours()), MsgTimeAgoHour, MsgTimeAgoHours)
+	default:
+		return count(int(d.Hours()/24), MsgTimeAgoDay, MsgTimeAgoDays)
+	}
+}
+
+// localeChain is the lookup order for a locale: itself, its base language
+// ("pt" for "pt-br"), then FallbackLocale
+func localeChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	chain := make([]string, 0, 3)
+	if locale != "" {
+		chain = append(chain, locale)
+		if dash := strings.IndexByte(locale, '-'); dash > 0 {
+			chain = append(chain, locale[:dash])
+		}
+	}
+	return append(chain, FallbackLocale)
+}
+
+// normalizeLocale lower-cases a locale tag and uses dashes ("pt_BR" →
+// "pt-br")
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ValidateLocale checks a locale tag: a 2-3 letter language optionally
+// followed by dash- or underscore-separated subtags ("en", "pt-BR",
+// "zh-Hant-TW")
+func ValidateLocale(locale string) error {
+	if len(locale) > 35 {
+		return fmt.Errorf("locale %q is too long", locale)
+	}
+	parts := strings.Split(strings.ReplaceAll(locale, "_", "-"), "-")
+	if n := len(parts[0]); n < 2 || n > 3 || !isLetters(parts[0]) {
+		return fmt.Errorf("invalid locale %q: expected a language such as en or pt-BR", locale)
+	}
+	for _, sub := range parts[1:] {
+		if sub == "" || len(sub) > 8 || !isAlphanumeric(sub) {
+			return fmt.Errorf("invalid locale %q: expected a language such as en or pt-BR", locale)
+		}
+	}
+	return nil
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
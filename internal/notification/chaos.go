@@ -0,0 +1,289 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// Faults Chaos injects, named in metrics and stats
+const (
+	FaultDBLatency     = "db_latency"
+	FaultSSEWrite      = "sse_write"
+	FaultConsumerPause = "consumer_pause"
+	FaultDelivery      = "delivery"
+)
+
+var chaosFaults = []string{FaultDBLatency, FaultSSEWrite, FaultConsumerPause, FaultDelivery}
+
+// Injected failures
+var (
+	errChaosWrite    = errors.New("chaos: injected stream write failure")
+	errChaosDelivery = errors.New("chaos: injected delivery error")
+)
+
+// ChaosConfig sets how often each fault is injected; rates are
+// probabilities from 0 to 1 and the zero value injects nothing
+type ChaosConfig struct {
+	DBLatency         time.Duration // Added to repository calls picked by DBLatencyRate
+	DBLatencyRate     float64
+	SSEWriteFailRate  float64       // Stream flushes that fail, dropping the connection
+	ConsumerPause     time.Duration // How long the consumer stalls before a picked message
+	ConsumerPauseRate float64
+	DeliveryErrorRate float64 // Delivery attempts failed before the SSE send
+}
+
+// Enabled reports whether any fault can be injected
+func (c ChaosConfig) Enabled() bool {
+	return (c.DBLatency > 0 && c.DBLatencyRate > 0) || c.SSEWriteFailRate > 0 ||
+		(c.ConsumerPause > 0 && c.ConsumerPauseRate > 0) || c.DeliveryErrorRate > 0
+}
+
+// Validate checks the rates are probabilities and the durations positive
+func (c ChaosConfig) Validate() error {
+	for name, rate := range map[string]float64{
+		"db latency rate":     c.DBLatencyRate,
+		"sse write fail rate": c.SSEWriteFailRate,
+		"consumer pause rate": c.ConsumerPauseRate,
+		"delivery error rate": c.DeliveryErrorRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("invalid chaos %s %v (want 0-1)", name, rate)
+		}
+	}
+	if c.DBLatency < 0 || c.ConsumerPause < 0 {
+		return fmt.Errorf("invalid chaos db latency (%s) or consumer pause (%s)", c.DBLatency, c.ConsumerPause)
+	}
+	return nil
+}
+
+// Chaos injects faults into the repository, SSE streams, consumer and
+// delivery workers so retries, leases and reconnects can be validated under
+// load. The config can be changed at runtime (PUT /admin/chaos). A nil
+// *Chaos injects nothing.
+type Chaos struct {
+	config   atomic.Pointer[ChaosConfig]
+	injected map[string]*int64 // By fault (atomic)
+}
+
+// NewChaos creates a fault injector with the given config
+func NewChaos(config ChaosConfig) *Chaos {
+	c := &Chaos{injected: make(map[string]*int64, len(chaosFaults))}
+	for _, fault := range chaosFaults {
+		c.injected[fault] = new(int64)
+	}
+	c.config.Store(&config)
+	return c
+}
+
+// Config returns the current config
+func (c *Chaos) Config() ChaosConfig {
+	if c == nil {
+		return ChaosConfig{}
+	}
+	return *c.config.Load()
+}
+
+// SetConfig replaces the config
+func (c *Chaos) SetConfig(config ChaosConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	c.config.Store(&config)
+	return nil
+}
+
+// roll decides whether to inject fault, counting it when it does
+func (c *Chaos) roll(fault string, rate float64) bool {
+	if rate <= 0 || rand.Float64() >= rate {
+		return false
+	}
+	atomic.AddInt64(c.injected[fault], 1)
+	metrics.ChaosInjected.WithLabelValues(fault).Inc()
+	return true
+}
+
+// DelayDB sleeps for the DB latency when the fault is picked
+func (c *Chaos) DelayDB(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	cfg := c.config.Load()
+	if cfg.DBLatency > 0 && c.roll(FaultDBLatency, cfg.DBLatencyRate) {
+		sleepCtx(ctx, cfg.DBLatency)
+	}
+}
+
+// PauseConsumer stalls the consumer when the fault is picked
+func (c *Chaos) PauseConsumer(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	cfg := c.config.Load()
+	if cfg.ConsumerPause > 0 && c.roll(FaultConsumerPause, cfg.ConsumerPauseRate) {
+		sleepCtx(ctx, cfg.ConsumerPause)
+	}
+}
+
+// FailWrite returns an error for stream writes picked to fail
+func (c *Chaos) FailWrite() error {
+	if c == nil || !c.roll(FaultSSEWrite, c.config.Load().SSEWriteFailRate) {
+		return nil
+	}
+	return errChaosWrite
+}
+
+// FailDelivery returns an error for delivery attempts picked to fail
+func (c *Chaos) FailDelivery() error {
+	if c == nil || !c.roll(FaultDelivery, c.config.Load().DeliveryErrorRate) {
+		return nil
+	}
+	return errChaosDelivery
+}
+
+// abortStream closes the connection under a response without ending it, as
+// a failed write would. HTTP/2 streams cannot be hijacked and end cleanly.
+func abortStream(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+	if conn, _, err := hijacker.Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+// ChaosStats is the config in effect and the faults injected so far
+type ChaosStats struct {
+	Enabled           bool             `json:"enabled"`
+	DBLatency         string           `json:"db_latency"`
+	DBLatencyRate     float64          `json:"db_latency_rate"`
+	SSEWriteFailRate  float64          `json:"sse_write_fail_rate"`
+	ConsumerPause     string           `json:"consumer_pause"`
+	ConsumerPauseRate float64          `json:"consumer_pause_rate"`
+	DeliveryErrorRate float64          `json:"delivery_error_rate"`
+	Injected          map[string]int64 `json:"injected"`
+}
+
+// Stats returns the config and injected fault counts
+func (c *Chaos) Stats() ChaosStats {
+	cfg := c.Config()
+	stats := ChaosStats{
+		Enabled:           cfg.Enabled(),
+		DBLatency:         cfg.DBLatency.String(),
+		DBLatencyRate:     cfg.DBLatencyRate,
+		SSEWriteFailRate:  cfg.SSEWriteFailRate,
+		ConsumerPause:     cfg.ConsumerPause.String(),
+		ConsumerPauseRate: cfg.ConsumerPauseRate,
+		DeliveryErrorRate: cfg.DeliveryErrorRate,
+		Injected:          make(map[string]int64, len(chaosFaults)),
+	}
+	if c != nil {
+		for fault, count := range c.injected {
+			stats.Injected[fault] = atomic.LoadInt64(count)
+		}
+	}
+	return stats
+}
+
+// chaosRequest is the body of PUT /admin/chaos; omitted fields are off
+type chaosRequest struct {
+	DBLatency         string  `json:"db_latency"`
+	DBLatencyRate     float64 `json:"db_latency_rate"`
+	SSEWriteFailRate  float64 `json:"sse_write_fail_rate"`
+	ConsumerPause     string  `json:"consumer_pause"`
+	ConsumerPauseRate float64 `json:"consumer_pause_rate"`
+	DeliveryErrorRate float64 `json:"delivery_error_rate"`
+}
+
+func (r chaosRequest) config() (ChaosConfig, error) {
+	cfg := ChaosConfig{
+		DBLatencyRate:     r.DBLatencyRate,
+		SSEWriteFailRate:  r.SSEWriteFailRate,
+		ConsumerPauseRate: r.ConsumerPauseRate,
+		DeliveryErrorRate: r.DeliveryErrorRate,
+	}
+	var err error
+	if r.DBLatency != "" {
+		if cfg.DBLatency, err = time.ParseDuration(r.DBLatency); err != nil {
+			return cfg, fmt.Errorf("invalid db_latency: %w", err)
+		}
+	}
+	if r.ConsumerPause != "" {
+		if cfg.ConsumerPause, err = time.ParseDuration(r.ConsumerPause); err != nil {
+			return cfg, fmt.Errorf("invalid consumer_pause: %w", err)
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// sleepCtx sleeps for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// ChaosRepository delays the calls of the delivery pipeline's hot path
+// (insert, claim, status updates) by the chaos DB latency
+type ChaosRepository struct {
+	Repository
+	chaos *Chaos
+}
+
+// NewChaosRepository wraps repo with injected DB latency
+func NewChaosRepository(repo Repository, chaos *Chaos) *ChaosRepository {
+	return &ChaosRepository{Repository: repo, chaos: chaos}
+}
+
+// Insert adds a notification after the injected latency
+func (r *ChaosRepository) Insert(ctx context.Context, notification *models.Notification) error {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.Insert(ctx, notification)
+}
+
+// BatchInsert inserts notifications after the injected latency
+func (r *ChaosRepository) BatchInsert(ctx context.Context, notifications []*models.Notification) error {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.BatchInsert(ctx, notifications)
+}
+
+// ClaimBatch claims notifications after the injected latency
+func (r *ChaosRepository) ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.ClaimBatch(ctx, instanceID, batchSize, leaseDuration, policy)
+}
+
+// ClaimForUsers claims connected users' notifications after the injected latency
+func (r *ChaosRepository) ClaimForUsers(ctx context.Context, instanceID string, users []UserRef, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.ClaimForUsers(ctx, instanceID, users, batchSize, leaseDuration, policy)
+}
+
+// ClaimWeighted claims notifications by priority after the injected latency
+func (r *ChaosRepository) ClaimWeighted(ctx context.Context, instanceID string, quotas map[models.Priority]int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.ClaimWeighted(ctx, instanceID, quotas, leaseDuration)
+}
+
+// ClaimParked un-parks a user's notifications after the injected latency
+func (r *ChaosRepository) ClaimParked(ctx context.Context, tenantID, userID, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.ClaimParked(ctx, tenantID, userID, instanceID, batchSize, leaseDuration)
+}
+
+// BatchUpdateStatus updates statuses after the injected latency
+func (r *ChaosRepository) BatchUpdateStatus(ctx context.Context, updates []*StatusUpdate) error {
+	r.chaos.DelayDB(ctx)
+	return r.Repository.BatchUpdateStatus(ctx, updates)
+}
@@ -0,0 +1,477 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // Calls go through
+	BreakerHalfOpen = "half_open" // One probe call goes through; the rest fail fast
+	BreakerOpen     = "open"      // Calls fail fast until the open timeout passes
+)
+
+// ErrCircuitOpen is returned without calling the store while the breaker is
+// open. It is an ErrUnavailable, so callers back off and retry as they do
+// when the store itself is down.
+var ErrCircuitOpen error = &kindError{kind: ErrUnavailable, err: errors.New("postgres circuit breaker open")}
+
+// CircuitBreakerConfig controls when the breaker opens and for how long
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the breaker (0 disables it)
+	OpenTimeout      time.Duration // How long it stays open before a probe call
+	SlowCall         time.Duration // Calls slower than this count as failures (0 = only errors)
+}
+
+// CircuitBreaker stops calls to a failing store. Calls that fail with
+// ErrUnavailable (or run slower than SlowCall) are failures; after
+// FailureThreshold in a row the breaker opens and calls fail fast with
+// ErrCircuitOpen. After OpenTimeout one probe is let through: its success
+// closes the breaker, its failure reopens it. A nil *CircuitBreaker lets
+// every call through.
+type CircuitBreaker struct {
+	cfg    CircuitBreakerConfig
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	state    string
+	failures int // Consecutive
+	openedAt time.Time
+	probing  bool // A half-open probe is in flight
+	lastErr  error
+	opened   int64 // Lifetime
+	rejected int64 // Lifetime
+}
+
+// NewCircuitBreaker creates a closed breaker; nil when the threshold is 0
+func NewCircuitBreaker(cfg CircuitBreakerConfig, logger *zap.Logger) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		return nil
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	metrics.CircuitBreakerState.Set(0)
+	return &CircuitBreaker{cfg: cfg, logger: logger, state: BreakerClosed}
+}
+
+// allow returns ErrCircuitOpen when the call must fail fast
+func (b *CircuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cfg.OpenTimeout {
+			break
+		}
+		b.transition(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probing {
+			break
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+	b.rejected++
+	metrics.CircuitBreakerRejected.Inc()
+	return ErrCircuitOpen
+}
+
+// record counts the outcome of a call allow let through
+func (b *CircuitBreaker) record(err error, elapsed time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up (shutdown): says nothing about the store
+		return
+	}
+
+	if !errors.Is(err, ErrUnavailable) && (b.cfg.SlowCall <= 0 || elapsed <= b.cfg.SlowCall) {
+		b.failures = 0
+		if b.state == BreakerHalfOpen {
+			b.transition(BreakerClosed)
+			b.logger.Info("postgres circuit breaker closed")
+		}
+		return
+	}
+
+	b.failures++
+	if err == nil {
+		err = fmt.Errorf("call took %s (slow call threshold %s)", elapsed.Round(time.Millisecond), b.cfg.SlowCall)
+	}
+	b.lastErr = err
+	// Calls already in flight when it opened don't reopen it
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.cfg.FailureThreshold) {
+		b.openedAt = time.Now()
+		b.opened++
+		b.transition(BreakerOpen)
+		b.logger.Warn("postgres circuit breaker open, failing calls fast",
+			zap.Int("consecutive_failures", b.failures),
+			zap.Duration("open_timeout", b.cfg.OpenTimeout),
+			zap.Error(err))
+	}
+}
+
+// transition enters state. Caller holds b.mu.
+func (b *CircuitBreaker) transition(state string) {
+	b.state = state
+	metrics.CircuitBreakerTransitions.WithLabelValues(state).Inc()
+	switch state {
+	case BreakerClosed:
+		metrics.CircuitBreakerState.Set(0)
+	case BreakerHalfOpen:
+		metrics.CircuitBreakerState.Set(1)
+	case BreakerOpen:
+		metrics.CircuitBreakerState.Set(2)
+	}
+}
+
+// CircuitBreakerStats is a point-in-time view of the breaker
+type CircuitBreakerStats struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at,omitzero"`
+	LastError           string    `json:"last_error,omitempty"`
+	OpenedTotal         int64     `json:"opened_total"`
+	RejectedTotal       int64     `json:"rejected_total"`
+}
+
+// Stats returns the breaker state; a nil breaker is always closed
+func (b *CircuitBreaker) Stats() CircuitBreakerStats {
+	if b == nil {
+		return CircuitBreakerStats{State: BreakerClosed}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := CircuitBreakerStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		OpenedTotal:         b.opened,
+		RejectedTotal:       b.rejected,
+	}
+	if b.state != BreakerClosed {
+		stats.OpenedAt = b.openedAt
+		if b.lastErr != nil {
+			stats.LastError = b.lastErr.Error()
+		}
+	}
+	return stats
+}
+
+// CircuitBreakerHealthCheck fails while the breaker is open or half-open
+func CircuitBreakerHealthCheck(b *CircuitBreaker) HealthCheck {
+	return HealthCheck{
+		Name: "postgres_breaker",
+		Check: func(ctx context.Context) error {
+			stats := b.Stats()
+			if stats.State == BreakerClosed {
+				return nil
+			}
+			return fmt.Errorf("circuit breaker %s since %s after %d consecutive failures: %s",
+				stats.State, stats.OpenedAt.Format(time.RFC3339), stats.ConsecutiveFailures, stats.LastError)
+		},
+	}
+}
+
+// guard runs fn through the breaker
+func guard(b *CircuitBreaker, fn func() error) error {
+	_, err := guardValue(b, func() (struct{}, error) { return struct{}{}, fn() })
+	return err
+}
+
+// guardValue runs fn through the breaker, returning its result
+func guardValue[T any](b *CircuitBreaker, fn func() (T, error)) (T, error) {
+	if b == nil {
+		return fn()
+	}
+	start := time.Now()
+	if err := b.allow(start); err != nil {
+		var zero T
+		return zero, err
+	}
+	v, err := fn()
+	b.record(err, time.Since(start))
+	return v, err
+}
+
+// BreakerRepository runs the store's calls through a circuit breaker so a
+// down or slow Postgres is backed off from instead of hammered. Close and
+// Flush always go through.
+type BreakerRepository struct {
+	Repository
+	breaker *CircuitBreaker
+}
+
+// NewBreakerRepository wraps repo with breaker
+func NewBreakerRepository(repo Repository, breaker *CircuitBreaker) *BreakerRepository {
+	return &BreakerRepository{Repository: repo, breaker: breaker}
+}
+
+// Insert adds a notification through the breaker
+func (r *BreakerRepository) Insert(ctx context.Context, notification *models.Notification) error {
+	return guard(r.breaker, func() error { return r.Repository.Insert(ctx, notification) })
+}
+
+// BatchInsert inserts notifications through the breaker
+func (r *BreakerRepository) BatchInsert(ctx context.Context, notifications []*models.Notification) error {
+	return guard(r.breaker, func() error { return r.Repository.BatchInsert(ctx, notifications) })
+}
+
+// ClaimBatch claims notifications through the breaker
+func (r *BreakerRepository) ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	return guardValue(r.breaker, func() ([]*NotificationBatch, error) {
+		return r.Repository.ClaimBatch(ctx, instanceID, batchSize, leaseDuration, policy)
+	})
+}
+
+// ClaimForUsers claims connected users' notifications through the breaker
+func (r *BreakerRepository) ClaimForUsers(ctx context.Context, instanceID string, users []UserRef, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	return guardValue(r.breaker, func() ([]*NotificationBatch, error) {
+		return r.Repository.ClaimForUsers(ctx, instanceID, users, batchSize, leaseDuration, policy)
+	})
+}
+
+// ClaimWeighted claims notifications by priority through the breaker
+func (r *BreakerRepository) ClaimWeighted(ctx context.Context, instanceID string, quotas map[models.Priority]int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	return guardValue(r.breaker, func() ([]*NotificationBatch, error) {
+		return r.Repository.ClaimWeighted(ctx, instanceID, quotas, leaseDuration)
+	})
+}
+
+// ClaimParked un-parks a user's notifications through the breaker
+func (r *BreakerRepository) ClaimParked(ctx context.Context, tenantID, userID, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	return guardValue(r.breaker, func() ([]*NotificationBatch, error) {
+		return r.Repository.ClaimParked(ctx, tenantID, userID, instanceID, batchSize, leaseDuration)
+	})
+}
+
+// BatchUpdateStatus updates statuses through the breaker
+func (r *BreakerRepository) BatchUpdateStatus(ctx context.Context, updates []*StatusUpdate) error {
+	return guard(r.breaker, func() error { return r.Repository.BatchUpdateStatus(ctx, updates) })
+}
+
+// ReclaimStaleTasks reclaims expired leases through the breaker
+func (r *BreakerRepository) ReclaimStaleTasks(ctx context.Context) (int, error) {
+	return guardValue(r.breaker, func() (int, error) { return r.Repository.ReclaimStaleTasks(ctx) })
+}
+
+// RequeueFailed requeues failed notifications through the breaker
+func (r *BreakerRepository) RequeueFailed(ctx context.Context, limit int) (int, error) {
+	return guardValue(r.breaker, func() (int, error) { return r.Repository.RequeueFailed(ctx, limit) })
+}
+
+// ExpireStale expires notifications through the breaker
+func (r *BreakerRepository) ExpireStale(ctx context.Context, now time.Time, maxAge time.Duration, limit int) ([]ExpiredCount, error) {
+	return guardValue(r.breaker, func() ([]ExpiredCount, error) {
+		return r.Repository.ExpireStale(ctx, now, maxAge, limit)
+	})
+}
+
+// Reprioritize moves old pending notifications through the breaker
+func (r *BreakerRepository) Reprioritize(ctx context.Context, from, to models.Priority, createdBefore time.Time, limit int) (int, error) {
+	return guardValue(r.breaker, func() (int, error) {
+		return r.Repository.Reprioritize(ctx, from, to, createdBefore, limit)
+	})
+}
+
+// GetUserNotifications reads a user's notifications through the breaker
+func (r *BreakerRepository) GetUserNotifications(ctx context.Context, tenantID, userID string, limit int, includeArchived bool) ([]map[string]interface{}, error) {
+	return guardValue(r.breaker, func() ([]map[string]interface{}, error) {
+		return r.Repository.GetUserNotifications(ctx, tenantID, userID, limit, includeArchived)
+	})
+}
+
+// GetUnreadCounts reads a user's unread counts through the breaker
+func (r *BreakerRepository) GetUnreadCounts(ctx context.Context, tenantID, userID string) (map[string]int64, error) {
+	return guardValue(r.breaker, func() (map[string]int64, error) {
+		return r.Repository.GetUnreadCounts(ctx, tenantID, userID)
+	})
+}
+
+// MarkRead marks a user's notifications read through the breaker
+func (r *BreakerRepository) MarkRead(ctx context.Context, tenantID, userID string, notificationIDs []uuid.UUID) (int, error) {
+	return guardValue(r.breaker, func() (int, error) {
+		return r.Repository.MarkRead(ctx, tenantID, userID, notificationIDs)
+	})
+}
+
+// ArchiveNotification archives a user's notification through the breaker
+func (r *BreakerRepository) ArchiveNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	return guard(r.breaker, func() error {
+		return r.Repository.ArchiveNotification(ctx, tenantID, userID, notificationID)
+	})
+}
+
+// DeleteNotification deletes a user's notification through the breaker
+func (r *BreakerRepository) DeleteNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	return guard(r.breaker, func() error {
+		return r.Repository.DeleteNotification(ctx, tenantID, userID, notificationID)
+	})
+}
+
+// GetUserPreferences reads a user's preferences through the breaker
+func (r *BreakerRepository) GetUserPreferences(ctx context.Context, tenantID, userID string) (UserPreferences, error) {
+	return guardValue(r.breaker, func() (UserPreferences, error) {
+		return r.Repository.GetUserPreferences(ctx, tenantID, userID)
+	})
+}
+
+// SetUserPreferences writes a user's preferences through the breaker
+func (r *BreakerRepository) SetUserPreferences(ctx context.Context, tenantID, userID string, prefs UserPreferences) (UserPreferences, error) {
+	return guardValue(r.breaker, func() (UserPreferences, error) {
+		return r.Repository.SetUserPreferences(ctx, tenantID, userID, prefs)
+	})
+}
+
+// ListEventTypes reads the registered event types through the breaker
+func (r *BreakerRepository) ListEventTypes(ctx context.Context) ([]EventTypeConfig, error) {
+	return guardValue(r.breaker, func() ([]EventTypeConfig, error) {
+		return r.Repository.ListEventTypes(ctx)
+	})
+}
+
+// PutEventType registers an event type through the breaker
+func (r *BreakerRepository) PutEventType(ctx context.Context, cfg EventTypeConfig) (EventTypeConfig, error) {
+	return guardValue(r.breaker, func() (EventTypeConfig, error) {
+		return r.Repository.PutEventType(ctx, cfg)
+	})
+}
+
+// DeleteEventType removes an event type's registration through the breaker
+func (r *BreakerRepository) DeleteEventType(ctx context.Context, eventType string) error {
+	return guard(r.breaker, func() error {
+		return r.Repository.DeleteEventType(ctx, eventType)
+	})
+}
+
+// ListSegments reads the registered segments through the breaker
+func (r *BreakerRepository) ListSegments(ctx context.Context) ([]Segment, error) {
+	return guardValue(r.breaker, func() ([]Segment, error) {
+		return r.Repository.ListSegments(ctx)
+	})
+}
+
+// GetSegment reads a segment's members through the breaker
+func (r *BreakerRepository) GetSegment(ctx context.Context, name string) (Segment, error) {
+	return guardValue(r.breaker, func() (Segment, error) {
+		return r.Repository.GetSegment(ctx, name)
+	})
+}
+
+// PutSegment registers a segment through the breaker
+func (r *BreakerRepository) PutSegment(ctx context.Context, segment Segment) (Segment, error) {
+	return guardValue(r.breaker, func() (Segment, error) {
+		return r.Repository.PutSegment(ctx, segment)
+	})
+}
+
+// DeleteSegment removes a segment through the breaker
+func (r *BreakerRepository) DeleteSegment(ctx context.Context, name string) error {
+	return guard(r.breaker, func() error {
+		return r.Repository.DeleteSegment(ctx, name)
+	})
+}
+
+// ListSubscriptions reads a user's topics through the breaker
+func (r *BreakerRepository) ListSubscriptions(ctx context.Context, tenantID, userID string) ([]Subscription, error) {
+	return guardValue(r.breaker, func() ([]Subscription, error) {
+		return r.Repository.ListSubscriptions(ctx, tenantID, userID)
+	})
+}
+
+// Subscribe adds a user's topics through the breaker
+func (r *BreakerRepository) Subscribe(ctx context.Context, tenantID, userID string, topics []string) error {
+	return guard(r.breaker, func() error {
+		return r.Repository.Subscribe(ctx, tenantID, userID, topics)
+	})
+}
+
+// Unsubscribe removes one of a user's topics through the breaker
+func (r *BreakerRepository) Unsubscribe(ctx context.Context, tenantID, userID, topic string) error {
+	return guard(r.breaker, func() error {
+		return r.Repository.Unsubscribe(ctx, tenantID, userID, topic)
+	})
+}
+
+// TopicSubscribers reads a topic's subscribers through the breaker
+func (r *BreakerRepository) TopicSubscribers(ctx context.Context, tenantID, topic string) ([]string, error) {
+	return guardValue(r.breaker, func() ([]string, error) {
+		return r.Repository.TopicSubscribers(ctx, tenantID, topic)
+	})
+}
+
+// GetStats reads delivery stats through the breaker
+func (r *BreakerRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	return guardValue(r.breaker, func() (map[string]interface{}, error) { return r.Repository.GetStats(ctx) })
+}
+
+// GetExactStats counts notifications by status through the breaker
+func (r *BreakerRepository) GetExactStats(ctx context.Context) (map[string]interface{}, error) {
+	return guardValue(r.breaker, func() (map[string]interface{}, error) { return r.Repository.GetExactStats(ctx) })
+}
+
+// GetStatsBreakdown reads grouped stats through the breaker
+func (r *BreakerRepository) GetStatsBreakdown(ctx context.Context, since time.Time, group BreakdownGroup) ([]BreakdownRow, error) {
+	return guardValue(r.breaker, func() ([]BreakdownRow, error) {
+		return r.Repository.GetStatsBreakdown(ctx, since, group)
+	})
+}
+
+// GetTenantStats reads per-tenant stats through the breaker
+func (r *BreakerRepository) GetTenantStats(ctx context.Context) (map[string]interface{}, error) {
+	return guardValue(r.breaker, func() (map[string]interface{}, error) { return r.Repository.GetTenantStats(ctx) })
+}
+
+// GetSLOAttainment reads SLO attainment through the breaker
+func (r *BreakerRepository) GetSLOAttainment(ctx context.Context, since time.Time, targets SLOTargets) (map[string]interface{}, error) {
+	return guardValue(r.breaker, func() (map[string]interface{}, error) {
+		return r.Repository.GetSLOAttainment(ctx, since, targets)
+	})
+}
+
+// GetDeliveryAttempts reads a notification's attempts through the breaker
+func (r *BreakerRepository) GetDeliveryAttempts(ctx context.Context, notificationID uuid.UUID) ([]map[string]interface{}, error) {
+	return guardValue(r.breaker, func() ([]map[string]interface{}, error) {
+		return r.Repository.GetDeliveryAttempts(ctx, notificationID)
+	})
+}
+
+// GetFlakyUsers reads users with failing deliveries through the breaker
+func (r *BreakerRepository) GetFlakyUsers(ctx context.Context, since time.Time, limit int) ([]map[string]interface{}, error) {
+	return guardValue(r.breaker, func() ([]map[string]interface{}, error) {
+		return r.Repository.GetFlakyUsers(ctx, since, limit)
+	})
+}
+
+// ExistingEventIDs looks up already persisted events through the breaker
+func (r *BreakerRepository) ExistingEventIDs(ctx context.Context, eventIDs []string) (map[string]bool, error) {
+	return guardValue(r.breaker, func() (map[string]bool, error) {
+		return r.Repository.ExistingEventIDs(ctx, eventIDs)
+	})
+}
+
+// ExportNotifications reads one export page through the breaker
+func (r *BreakerRepository) ExportNotifications(ctx context.Context, filter ExportFilter, after uuid.UUID, limit int) ([]*ExportedNotification, error) {
+	return guardValue(r.breaker, func() ([]*ExportedNotification, error) {
+		return r.Repository.ExportNotifications(ctx, filter, after, limit)
+	})
+}
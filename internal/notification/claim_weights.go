@@ -0,0 +1,107 @@
+package notification
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"notification-delivery-system/internal/models"
+)
+
+// claimPriorities are the priorities weighted claims split between, in
+// priorityRank order
+var claimPriorities = []models.Priority{models.PriorityHigh, models.PriorityMedium, models.PriorityLow}
+
+// ClaimWeights splits every claim between priorities so a sustained flood of
+// HIGH notifications can't starve MEDIUM and LOW: with 70/20/10 a claim of
+// 500 takes up to 350 HIGH, 100 MEDIUM and 50 LOW, and whatever share a
+// priority can't use goes to the others in claim policy order. The zero
+// value claims in strict policy order.
+type ClaimWeights struct {
+	High, Medium, Low int
+}
+
+// ParseClaimWeights reads HIGH/MEDIUM/LOW weights such as "70/20/10"; empty
+// disables weighting
+func ParseClaimWeights(s string) (ClaimWeights, error) {
+	if s == "" {
+		return ClaimWeights{}, nil
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != len(claimPriorities) {
+		return ClaimWeights{}, fmt.Errorf("invalid claim weights %q (want HIGH/MEDIUM/LOW, e.g. 70/20/10)", s)
+	}
+	var weights [3]int
+	for i, part := range parts {
+		w, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || w < 0 {
+			return ClaimWeights{}, fmt.Errorf("invalid claim weight %q for %s", part, claimPriorities[i])
+		}
+		weights[i] = w
+	}
+	w := ClaimWeights{High: weights[0], Medium: weights[1], Low: weights[2]}
+	if !w.Enabled() {
+		return ClaimWeights{}, fmt.Errorf("invalid claim weights %q (all zero)", s)
+	}
+	return w, nil
+}
+
+// Enabled reports whether claims are split by weight
+func (w ClaimWeights) Enabled() bool {
+	return w.High+w.Medium+w.Low > 0
+}
+
+// String formats the weights the way ParseClaimWeights reads them
+func (w ClaimWeights) String() string {
+	if !w.Enabled() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d/%d", w.High, w.Medium, w.Low)
+}
+
+func (w ClaimWeights) weight(p models.Priority) int {
+	switch p {
+	case models.PriorityHigh:
+		return w.High
+	case models.PriorityMedium:
+		return w.Medium
+	default:
+		return w.Low
+	}
+}
+
+// Quotas splits a claim of n between the priorities by weight. Slots left
+// over by rounding are drawn at random, weighted by what each priority was
+// rounded down by, so shares hold on average even for claims of a few
+// notifications (near the inflight cap) and LOW still moves.
+func (w ClaimWeights) Quotas(n int) map[models.Priority]int {
+	quotas := make(map[models.Priority]int, len(claimPriorities))
+	total := w.High + w.Medium + w.Low
+	if total == 0 {
+		return quotas
+	}
+
+	left := n
+	remainders := make(map[models.Priority]int, len(claimPriorities))
+	var remainder int
+	for _, p := range claimPriorities {
+		quotas[p] = n * w.weight(p) / total
+		remainders[p] = n * w.weight(p) % total
+		remainder += remainders[p]
+		left -= quotas[p]
+	}
+	for ; left > 0 && remainder > 0; left-- {
+		draw := rand.Intn(remainder)
+		for _, p := range claimPriorities {
+			if draw < remainders[p] {
+				quotas[p]++
+				remainder -= remainders[p]
+				remainders[p] = 0
+				break
+			}
+			draw -= remainders[p]
+		}
+	}
+	return quotas
+}
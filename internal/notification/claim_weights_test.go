@@ -0,0 +1,203 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+func TestParseClaimWeights(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    ClaimWeights
+		wantErr bool
+	}{
+		{in: "", want: ClaimWeights{}},
+		{in: "70/20/10", want: ClaimWeights{High: 70, Medium: 20, Low: 10}},
+		{in: " 70 / 20 / 10 ", want: ClaimWeights{High: 70, Medium: 20, Low: 10}},
+		// Weights are relative: they need not sum to 100
+		{in: "7/2/1", want: ClaimWeights{High: 7, Medium: 2, Low: 1}},
+		{in: "3/0/1", want: ClaimWeights{High: 3, Low: 1}},
+		{in: "0/0/0", wantErr: true},
+		{in: "70/20", wantErr: true},
+		{in: "70/20/10/5", wantErr: true},
+		{in: "70/-20/10", wantErr: true},
+		{in: "70/x/10", wantErr: true},
+		{in: "70,20,10", wantErr: true},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseClaimWeights(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseClaimWeights(%q) = %v, want error", tc.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseClaimWeights(%q): %v", tc.in, err)
+			}
+			if got != tc.want {
+				t.Fatalf("ParseClaimWeights(%q) = %+v, want %+v", tc.in, got, tc.want)
+			}
+			if got.String() != "" {
+				if again, err := ParseClaimWeights(got.String()); err != nil || again != got {
+					t.Fatalf("round trip of %q = %+v, %v", got.String(), again, err)
+				}
+			}
+		})
+	}
+}
+
+func TestClaimWeightsQuotas(t *testing.T) {
+	for _, tc := range []struct {
+		weights ClaimWeights
+		n       int
+		want    map[models.Priority]int
+	}{
+		{ClaimWeights{70, 20, 10}, 500, map[models.Priority]int{models.PriorityHigh: 350, models.PriorityMedium: 100, models.PriorityLow: 50}},
+		{ClaimWeights{7, 2, 1}, 500, map[models.Priority]int{models.PriorityHigh: 350, models.PriorityMedium: 100, models.PriorityLow: 50}},
+		{ClaimWeights{3, 0, 1}, 8, map[models.Priority]int{models.PriorityHigh: 6, models.PriorityMedium: 0, models.PriorityLow: 2}},
+		{ClaimWeights{70, 20, 10}, 0, map[models.Priority]int{models.PriorityHigh: 0, models.PriorityMedium: 0, models.PriorityLow: 0}},
+		{ClaimWeights{}, 500, map[models.Priority]int{}},
+	} {
+		name := tc.weights.String()
+		if name == "" {
+			name = "disabled"
+		}
+		t.Run(fmt.Sprintf("%s/%d", name, tc.n), func(t *testing.T) {
+			got := tc.weights.Quotas(tc.n)
+			if len(got) != len(tc.want) {
+				t.Fatalf("Quotas(%d) = %v, want %v", tc.n, got, tc.want)
+			}
+			for p, want := range tc.want {
+				if got[p] != want {
+					t.Fatalf("Quotas(%d) = %v, want %v", tc.n, got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+// TestClaimWeightsQuotasRemainder checks that rounding leftovers always fill
+// the claim and are shared by weight, so small claims still move LOW
+func TestClaimWeightsQuotasRemainder(t *testing.T) {
+	w := ClaimWeights{High: 70, Medium: 20, Low: 10}
+	for n := 1; n <= 50; n++ {
+		quotas := w.Quotas(n)
+		sum := 0
+		for _, p := range claimPriorities {
+			if quotas[p] < n*w.weight(p)/100 {
+				t.Fatalf("Quotas(%d) = %v, %s below its rounded-down share", n, quotas, p)
+			}
+			sum += quotas[p]
+		}
+		if sum != n {
+			t.Fatalf("Quotas(%d) = %v, sums to %d", n, quotas, sum)
+		}
+	}
+
+	// Claims of one are all remainder: LOW should get about 10% of them
+	const draws = 10000
+	var low int
+	for i := 0; i < draws; i++ {
+		low += w.Quotas(1)[models.PriorityLow]
+	}
+	if low < draws*7/100 || low > draws*13/100 {
+		t.Fatalf("LOW drew %d of %d single-slot claims, want about 10%%", low, draws)
+	}
+}
+
+// TestWeightedClaimMovesLowDuringHighFlood seeds a HIGH flood with a few LOW
+// rows behind it and checks that weighted claims, topped up to the claim
+// size, reach every LOW row within a bounded number of claims while strict
+// priority order would claim none of them.
+func TestWeightedClaimMovesLowDuringHighFlood(t *testing.T) {
+	const (
+		highRows  = 1000
+		lowRows   = 5
+		claimSize = 10
+	)
+
+	for _, tc := range []struct {
+		name      string
+		weights   ClaimWeights
+		maxClaims int // Claims within which every LOW row must be claimed; 0 if none should be
+	}{
+		{"strict", ClaimWeights{}, 0},
+		{"weighted", ClaimWeights{High: 70, Medium: 20, Low: 10}, lowRows},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewMemoryRepository(zap.NewNop())
+			seedPriority(t, repo, models.PriorityHigh, highRows)
+			seedPriority(t, repo, models.PriorityLow, lowRows)
+
+			tp := NewTaskPicker(TaskPickerConfig{
+				InstanceID:        "claim-test",
+				BatchSize:         claimSize,
+				LeaseDuration:     time.Minute,
+				ChannelBufferSize: 1,
+				ClaimPolicy:       ClaimPolicyPriority,
+				ClaimWeights:      tc.weights,
+			}, repo, nil, zap.NewNop())
+			defer tp.Stop()
+
+			claims := tc.maxClaims
+			if claims == 0 {
+				claims = 20
+			}
+			var low int
+			for i := 0; i < claims; i++ {
+				batch, err := tp.claim(claimSize)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Top-up keeps claims full while HIGH is backlogged
+				if len(batch) != claimSize {
+					t.Fatalf("claim %d returned %d notifications, want %d", i, len(batch), claimSize)
+				}
+				for _, n := range batch {
+					if n.Priority == string(models.PriorityLow) {
+						low++
+					}
+				}
+			}
+
+			want := lowRows
+			if tc.maxClaims == 0 {
+				want = 0
+			}
+			if low != want {
+				t.Fatalf("claimed %d LOW rows in %d claims, want %d", low, claims, want)
+			}
+		})
+	}
+}
+
+// seedPriority inserts count pending notifications of one priority, each
+// for its own user
+func seedPriority(t *testing.T, repo Repository, priority models.Priority, count int) {
+	t.Helper()
+	now := time.Now()
+	for i := 0; i < count; i++ {
+		if err := repo.Insert(context.Background(), &models.Notification{
+			NotificationID:                uuid.New(),
+			TenantID:                      models.DefaultTenantID,
+			UserID:                        fmt.Sprintf("%s_user_%d", priority, i),
+			EventType:                     models.EventJobNew,
+			Priority:                      priority,
+			Status:                        "not_pushed",
+			EventTimestamp:                now,
+			NotificationReceivedTimestamp: now,
+			CreatedAt:                     now,
+			Payload:                       map[string]string{"job_title": "SRE"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -2,134 +2,663 @@ package notification
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/metrics"
 	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/tracing"
 )
 
+// pendingInsert is a notification waiting for the next batch flush,
+// along with the consume span it should be persisted under
+type pendingInsert struct {
+	notif   *models.Notification
+	spanCtx trace.SpanContext
+}
+
+// MessageReader is the message source the consumer reads from.
+// *kafka.Reader satisfies it; the all-in-one binary uses an in-memory bus.
+type MessageReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
 type Consumer struct {
-	reader     *kafka.Reader
-	repository *PostgresRepository
+	reader     MessageReader
+	repository Repository
+	idGen      idgen.Generator
 	logger     *zap.Logger
-	
+
 	// Batch processing configuration
 	batchSize    int
 	batchTimeout time.Duration
+
+	// Event-time tracking and late event handling
+	watermark *Watermark
+	late      LateEventConfig
+
+	// Header-based filtering and express flushing
+	routing HeaderRouting
+
+	// Per-producer ingest limits (nil = unlimited)
+	quotas *IngestQuotas
+
+	// Injected consumer pauses (nil = none)
+	chaos *Chaos
+
+	// Priorities of events produced without one and payload schemas (nil =
+	// the built-in mapping and no schemas)
+	eventTypes *EventTypeRegistry
+
+	// Where invalid events are forwarded (nil = dropped)
+	poisonWriter DeadLetterWriter
+
+	// Resolves broadcasts' recipients (nil = broadcasts are refused)
+	audience *Audience
+
+	// Notifications a worker holds in memory while the store is unavailable
+	// before it stops taking messages
+	bufferLimit int
+	buffered    int64        // Held across workers (atomic)
+	spill       *SpillBuffer // Takes full buffers to disk (nil = workers stop reading instead)
+
+	// Parallel processing and ordered commits
+	workers   int
+	committer MessageCommitter // nil when the reader commits on read
+	offsets   *offsetTracker
+	running   int32   // Set while Consume runs (atomic)
+	beats     []int64 // Per worker: UnixNano of its last loop (atomic)
+
+	// Lifetime counters
+	messagesConsumed int64
+	parseErrors      int64
+	insertErrors     int64
+	lateEvents       int64
+	droppedLate      int64
+	headerRouted     int64 // Routed on headers, before decoding the body
+	filtered         int64
+	expressFlushes   int64
+	unsupported      int64 // Events from a newer schema than this build reads
+	invalidPayloads  int64 // Events failing their event type's payload schema
+	poisoned         int64 // Written to the poison topic
+	poisonErrors     int64
+	broadcasts       int64 // Broadcast events expanded
+	recipients       int64 // Notifications broadcasts expanded into
+	commits          int64
+}
+
+// NotificationFromMessage builds a pending (not_pushed) notification from an event
+func NotificationFromMessage(id uuid.UUID, msg *models.KafkaMessage) *models.Notification {
+	now := time.Now()
+	return &models.Notification{
+		NotificationID:                id,
+		EventID:                       msg.EventID,
+		TenantID:                      models.TenantOrDefault(msg.TenantID),
+		UserID:                        msg.UserID,
+		Topic:                         msg.Topic,
+		EventType:                     models.EventType(msg.EventType),
+		Priority:                      models.Priority(msg.Priority),
+		EventTimestamp:                msg.EventTimestamp,
+		NotificationReceivedTimestamp: now,
+		Status:                        "not_pushed", // Key: Just write, don't deliver
+		Payload:                       msg.Payload,
+		IsRead:                        false,
+		RetryCount:                    0,
+		CreatedAt:                     now,
+		ExpiresAt:                     msg.ExpiresAt,
+		TraceID:                       msg.Metadata.TraceID,
+		ProducedAt:                    msg.ProducedAt,
+	}
+}
+
+// ConsumerStats is a point-in-time view of consumer progress
+type ConsumerStats struct {
+	Topic            string `json:"topic"`
+	Lag              int64  `json:"lag"`
+	Offset           int64  `json:"offset"`
+	MessagesConsumed int64  `json:"messages_consumed"`
+	ParseErrors      int64  `json:"parse_errors"`
+	InsertErrors     int64  `json:"insert_errors"`
+
+	// Event time
+	Watermark           time.Time `json:"watermark"`
+	WatermarkLagSeconds float64   `json:"watermark_lag_seconds"`
+	LateThreshold       string    `json:"late_threshold"`
+	LatePolicy          string    `json:"late_policy"`
+	LateEvents          int64     `json:"late_events"`
+	DroppedLate         int64     `json:"dropped_late"`
+
+	// Header routing
+	HeaderRouted   int64 `json:"header_routed"`
+	Filtered       int64 `json:"filtered"`
+	ExpressFlushes int64 `json:"express_flushes"`
+
+	// Message format
+	SchemaVersion     int   `json:"schema_version"` // Newest schema this consumer reads
+	UnsupportedSchema int64 `json:"unsupported_schema"`
+
+	// Invalid events
+	InvalidPayloads int64 `json:"invalid_payloads"`
+	Poisoned        int64 `json:"poisoned"`
+	PoisonErrors    int64 `json:"poison_errors"`
+
+	// Broadcasts
+	Broadcasts          int64 `json:"broadcasts"`
+	BroadcastRecipients int64 `json:"broadcast_recipients"` // Notifications they expanded into
+
+	// Worker pool
+	Workers      int   `json:"workers"`
+	ManualCommit bool  `json:"manual_commit"`
+	InFlight     int64 `json:"in_flight"` // Fetched but not yet committable
+	Commits      int64 `json:"commits"`
+	Buffered     int64 `json:"buffered"` // Held in memory while the store is unavailable
+
+	Spill       *SpillStats       `json:"spill,omitempty"`
+	IngestQuota *IngestQuotaStats `json:"ingest_quota,omitempty"`
 }
 
-func NewConsumer(brokers []string, groupID, topic string, repository *PostgresRepository, logger *zap.Logger) (*Consumer, error) {
+func NewConsumer(brokers []string, groupID, topic string, repository Repository, idGen idgen.Generator, logger *zap.Logger) (*Consumer, error) {
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:        brokers,
 		GroupID:        groupID,
 		Topic:          topic,
 		MinBytes:       10e3,        // 10KB
 		MaxBytes:       10e6,        // 10MB
-		CommitInterval: time.Second, // Auto-commit every second
+		CommitInterval: time.Second, // Flush commits every second
 		StartOffset:    kafka.FirstOffset,
 		MaxWait:        1 * time.Second,
 	})
 
-	logger.Info("kafka consumer created", 
-		zap.Strings("brokers", brokers), 
-		zap.String("group_id", groupID), 
+	logger.Info("kafka consumer created",
+		zap.Strings("brokers", brokers),
+		zap.String("group_id", groupID),
 		zap.String("topic", topic))
 
+	return NewConsumerWithReader(reader, repository, idGen, logger), nil
+}
+
+// NewConsumerWithReader creates a consumer over an arbitrary message source
+func NewConsumerWithReader(reader MessageReader, repository Repository, idGen idgen.Generator, logger *zap.Logger) *Consumer {
+	committer, _ := reader.(MessageCommitter)
 	return &Consumer{
 		reader:       reader,
 		repository:   repository,
+		idGen:        idGen,
 		logger:       logger,
-		batchSize:    100,  // Batch 100 notifications
+		batchSize:    100,                   // Batch 100 notifications
 		batchTimeout: 50 * time.Millisecond, // Or 50ms timeout
-	}, nil
+		watermark:    &Watermark{},
+		late: LateEventConfig{
+			Threshold: 5 * time.Minute,
+			Policy:    LatePolicyMark,
+		},
+		workers:     1,
+		bufferLimit: 1000,
+		committer:   committer,
+		offsets:     newOffsetTracker(),
+	}
+}
+
+// SetIngestQuotas limits events per source service or tenant. Call before
+// Consume.
+func (c *Consumer) SetIngestQuotas(quotas *IngestQuotas) {
+	c.quotas = quotas
+}
+
+// SetChaos stalls the consumer at the chaos consumer pause rate. Call
+// before Consume.
+func (c *Consumer) SetChaos(chaos *Chaos) {
+	c.chaos = chaos
+}
+
+// SetWorkers sets how many workers decode and persist messages in parallel
+// (default 1). Call before Consume.
+func (c *Consumer) SetWorkers(n int) {
+	if n > 0 {
+		c.workers = n
+	}
+}
+
+// SetBufferLimit sets how many notifications each worker holds while the
+// store is unavailable (default 1000). A full worker stops taking messages,
+// so the backlog stays in Kafka. Call before Consume.
+func (c *Consumer) SetBufferLimit(n int) {
+	if n > 0 {
+		c.bufferLimit = n
+	}
+}
+
+// SetSpill moves a worker's buffer to disk when it fills during an outage,
+// so the worker keeps reading; spilled notifications are replayed once the
+// store is back. Call before Consume.
+func (c *Consumer) SetSpill(spill *SpillBuffer) {
+	c.spill = spill
+}
+
+// SetLateEventPolicy replaces the default late event handling (mark events
+// more than 5m off). Call before Consume.
+func (c *Consumer) SetLateEventPolicy(cfg LateEventConfig) {
+	c.late = cfg
+}
+
+// SetHeaderRouting filters events and picks express priorities from their
+// Kafka headers (by default everything is kept and batched). Call before
+// Consume.
+func (c *Consumer) SetHeaderRouting(routing HeaderRouting) {
+	c.routing = routing
+}
+
+// SetEventTypes sets where the priority of events produced without one
+// and the payload schemas come from. Call before Consume.
+func (c *Consumer) SetEventTypes(eventTypes *EventTypeRegistry) {
+	c.eventTypes = eventTypes
+}
+
+// SetAudience sets how broadcast events' recipients are resolved; without
+// it broadcasts are poisoned. Call before Consume.
+func (c *Consumer) SetAudience(audience *Audience) {
+	c.audience = audience
+}
+
+// checkLateness records the event against the watermark and applies the
+// late policy; it returns false when the event should be dropped
+func (c *Consumer) checkLateness(notif *models.Notification, span trace.Span) bool {
+	now := notif.NotificationReceivedTimestamp
+	lag := c.watermark.Observe(notif.EventTimestamp, now)
+	metrics.ConsumerEventLagSeconds.Observe(max(lag, 0).Seconds())
+	metrics.ConsumerWatermarkLagSeconds.Set(c.watermark.Lag(now).Seconds())
+
+	lateness := c.late.Lateness(lag)
+	if lateness == "" {
+		return true
+	}
+
+	atomic.AddInt64(&c.lateEvents, 1)
+	metrics.ConsumerLateEvents.WithLabelValues(lateness, string(c.late.Policy)).Inc()
+	span.SetAttributes(
+		attribute.String("lateness", lateness),
+		attribute.String("late_policy", string(c.late.Policy)))
+
+	switch c.late.Policy {
+	case LatePolicyDrop:
+		atomic.AddInt64(&c.droppedLate, 1)
+		c.logger.Warn("dropped late event",
+			zap.String("lateness", lateness),
+			zap.String("tenant_id", notif.TenantID),
+			zap.String("user_id", notif.UserID),
+			zap.Time("event_timestamp", notif.EventTimestamp),
+			zap.Duration("lag", lag))
+		return false
+	case LatePolicyMark:
+		notif.IsLate = true
+	}
+	return true
 }
 
 // Consume reads from Kafka and writes to ClickHouse with status='not_pushed'
-// Uses batch processing for 5-10x throughput improvement
+// Uses batch processing for 5-10x throughput improvement. Messages are
+// spread over the workers by key, so each user's events stay in order, and
+// offsets are committed in order once messages are persisted.
 func (c *Consumer) Consume(ctx context.Context) error {
 	c.logger.Info("starting consumer with batch processing",
+		zap.String("id_strategy", c.idGen.Name()),
+		zap.Int("workers", c.workers),
+		zap.Bool("manual_commit", c.committer != nil),
 		zap.Int("batch_size", c.batchSize),
 		zap.Duration("batch_timeout", c.batchTimeout))
 
-	batch := make([]*models.Notification, 0, c.batchSize)
-	ticker := time.NewTicker(c.batchTimeout)
-	defer ticker.Stop()
-
-	flushBatch := func() {
-		if len(batch) == 0 {
-			return
+	queues := make([]chan kafka.Message, c.workers)
+	c.beats = make([]int64, c.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, c.batchSize)
+		c.beats[i] = time.Now().UnixNano()
+		wg.Add(1)
+		go func(queue <-chan kafka.Message, beat *int64) {
+			defer wg.Done()
+			c.runWorker(ctx, queue, beat)
+		}(queues[i], &c.beats[i])
+	}
+	if c.spill != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.spill.Replay(ctx, c.repository)
+		}()
+	}
+	atomic.StoreInt32(&c.running, 1)
+	defer func() {
+		atomic.StoreInt32(&c.running, 0)
+		for _, queue := range queues {
+			close(queue)
 		}
+		wg.Wait()
+		c.logger.Info("consumer stopped")
+	}()
 
-		// Bulk insert to ClickHouse
-		for _, notif := range batch {
-			if err := c.repository.Insert(ctx, notif); err != nil {
-				c.logger.Error("failed to insert notification",
-					zap.Error(err),
-					zap.String("notification_id", notif.NotificationID.String()))
+	for {
+		msg, err := c.fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
 			}
+			c.logger.Error("failed to read message", zap.Error(err))
+			time.Sleep(100 * time.Millisecond)
+			continue
 		}
+		atomic.AddInt64(&c.messagesConsumed, 1)
+		c.offsets.Fetched(msg)
+		c.chaos.PauseConsumer(ctx)
 
-		c.logger.Debug("batch persisted",
-			zap.Int("batch_size", len(batch)))
-
-		// Clear batch
-		batch = batch[:0]
-	}
-
-	for {
 		select {
+		case queues[workerFor(msg.Key, c.workers)] <- msg:
 		case <-ctx.Done():
-			flushBatch() // Flush remaining
-			c.logger.Info("consumer stopped")
 			return nil
+		}
+	}
+}
+
+// process decodes, routes and checks one message. It returns the
+// notifications to batch (one per recipient of a broadcast) and the
+// priority it was routed on, or false when the message is not persisted.
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) ([]pendingInsert, string, bool) {
+	// Fast path: filter on headers so unwanted events are never decoded
+	eventType, priority, routed := routingHeaders(msg.Headers)
+	if routed {
+		atomic.AddInt64(&c.headerRouted, 1)
+		if !c.routing.Accepts(eventType, priority) {
+			c.filter(eventType, priority)
+			return nil, "", false
+		}
+	}
 
-		case <-ticker.C:
-			// Timeout: flush partial batch
-			flushBatch()
+	// Continue the producer's trace from the message headers
+	_, span := tracing.Tracer().Start(tracing.ExtractHeaders(ctx, msg.Headers), "kafka.consume",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset)))
+	defer span.End()
 
-		default:
-			msg, err := c.reader.ReadMessage(ctx)
-			if err != nil {
-				c.logger.Error("failed to read message", zap.Error(err))
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
+	// Parse Kafka message in whichever encoding and schema it was written
+	contentType := headerValue(msg.Headers, models.HeaderContentType)
+	kafkaMsg, err := models.DecodeKafkaMessage(msg.Value, contentType, headerValue(msg.Headers, models.HeaderSchemaVersion))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unmarshal failed")
+		atomic.AddInt64(&c.parseErrors, 1)
+		result := PoisonParseError
+		if errors.Is(err, models.ErrUnsupportedSchema) {
+			result = PoisonUnsupportedSchema
+			atomic.AddInt64(&c.unsupported, 1)
+		}
+		c.logger.Error("failed to unmarshal message", zap.Error(err), zap.ByteString("raw", msg.Value))
+		metrics.ConsumerMessages.WithLabelValues(eventType, priority, result).Inc()
+		c.poison(ctx, msg, result, err)
+		return nil, "", false
+	}
+	if contentType == "" {
+		contentType = models.EncodingJSON
+	}
+	metrics.ConsumerMessageFormats.WithLabelValues(contentType, strconv.Itoa(kafkaMsg.SchemaVersion)).Inc()
+
+	// Broadcasts name their recipients later, once they pass the checks
+	broadcast := kafkaMsg.IsBroadcast()
+	if err := models.ValidateUserID(kafkaMsg.UserID); err != nil && !broadcast {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid user id")
+		atomic.AddInt64(&c.parseErrors, 1)
+		c.logger.Error("rejected message with invalid user id", zap.Error(err), zap.String("event_id", kafkaMsg.EventID))
+		metrics.ConsumerMessages.WithLabelValues(eventType, priority, "parse_error").Inc()
+		c.poison(ctx, msg, PoisonInvalidUserID, err)
+		return nil, "", false
+	}
+
+	// Events produced without a priority get their event type's
+	if kafkaMsg.Priority == "" {
+		kafkaMsg.Priority = string(c.eventTypes.Priority(kafkaMsg.EventType))
+	}
+
+	// Events produced without a deadline get their event type's TTL as one,
+	// so the sweeper expires a stale backlog before anyone claims it
+	if kafkaMsg.ExpiresAt == nil {
+		kafkaMsg.ExpiresAt = c.eventTypes.ExpiresAt(kafkaMsg.EventType, kafkaMsg.EventTimestamp)
+	}
+
+	// No routing headers: route on the decoded body
+	if !routed {
+		eventType, priority = kafkaMsg.EventType, kafkaMsg.Priority
+		if !c.routing.Accepts(eventType, priority) {
+			c.filter(eventType, priority)
+			return nil, "", false
+		}
+	}
+
+	// Payloads are checked against their event type's schema, if it has one
+	if schema := c.eventTypes.PayloadSchema(kafkaMsg.EventType); schema != nil {
+		if err := schema.Validate(kafkaMsg.Payload); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "invalid payload")
+			atomic.AddInt64(&c.invalidPayloads, 1)
+			c.logger.Warn("rejected message with invalid payload",
+				zap.Error(err),
+				zap.String("event_id", kafkaMsg.EventID),
+				zap.String("event_type", kafkaMsg.EventType))
+			metrics.ConsumerMessages.WithLabelValues(eventType, priority, PoisonInvalidPayload).Inc()
+			c.poison(ctx, msg, PoisonInvalidPayload, err)
+			return nil, "", false
+		}
+	}
 
-			// Parse Kafka message
-			var kafkaMsg models.KafkaMessage
-			if err := json.Unmarshal(msg.Value, &kafkaMsg); err != nil {
-				c.logger.Error("failed to unmarshal message", zap.Error(err), zap.ByteString("raw", msg.Value))
-				continue
+	// Per-producer ingest quota: degrade, dead-letter or drop the excess
+	if c.quotas.Enabled() {
+		if key := c.quotas.KeyOf(kafkaMsg); !c.quotas.Allow(key) {
+			span.SetAttributes(attribute.String("ingest_quota_key", key))
+			if !c.quotas.Overflow(ctx, key, msg, kafkaMsg) {
+				metrics.ConsumerMessages.WithLabelValues(eventType, priority, "over_quota").Inc()
+				return nil, "", false
 			}
+			priority = kafkaMsg.Priority
+		}
+	}
 
-			// Create notification with status='not_pushed'
-			notif := &models.Notification{
-				NotificationID:                uuid.New(),
-				UserID:                        kafkaMsg.UserID,
-				EventType:                     models.EventType(kafkaMsg.EventType),
-				Priority:                      models.Priority(kafkaMsg.Priority),
-				EventTimestamp:                kafkaMsg.EventTimestamp,
-				NotificationReceivedTimestamp: time.Now(),
-				Status:                        "not_pushed", // Key: Just write, don't deliver
-				Payload:                       kafkaMsg.Payload,
-				IsRead:                        false,
-				RetryCount:                    0,
-				CreatedAt:                     time.Now(),
+	var recipients []string
+	if broadcast {
+		recipients, err = c.audience.Resolve(ctx, kafkaMsg)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "unresolved audience")
+			reason := PoisonUnresolvedAudience
+			if errors.Is(err, ErrInvalidAudience) {
+				reason = PoisonInvalidAudience
 			}
+			c.logger.Warn("rejected broadcast",
+				zap.Error(err),
+				zap.String("event_id", kafkaMsg.EventID),
+				zap.String("segment", kafkaMsg.Segment),
+				zap.String("topic", kafkaMsg.Topic),
+				zap.Int("user_ids", len(kafkaMsg.UserIDs)))
+			metrics.ConsumerMessages.WithLabelValues(eventType, priority, reason).Inc()
+			c.poison(ctx, msg, reason, err)
+			return nil, "", false
+		}
+	}
+
+	// Create notification with status='not_pushed'
+	notif := NotificationFromMessage(c.idGen.NewID(), kafkaMsg)
+	span.SetAttributes(attribute.String("notification_id", notif.NotificationID.String()))
+	if !c.checkLateness(notif, span) {
+		return nil, "", false
+	}
+
+	metrics.ConsumerMessages.WithLabelValues(eventType, priority, "accepted").Inc()
+	if broadcast {
+		return c.fanOut(notif, recipients, span), priority, true
+	}
+	return []pendingInsert{{notif: notif, spanCtx: span.SpanContext()}}, priority, true
+}
+
+// fanOut expands a broadcast into a notification per recipient, each with
+// its own ID; an empty audience persists nothing
+func (c *Consumer) fanOut(notif *models.Notification, recipients []string, span trace.Span) []pendingInsert {
+	atomic.AddInt64(&c.broadcasts, 1)
+	atomic.AddInt64(&c.recipients, int64(len(recipients)))
+	metrics.ConsumerBroadcastRecipients.Observe(float64(len(recipients)))
+	span.SetAttributes(attribute.Int("broadcast.recipients", len(recipients)))
+
+	pending := make([]pendingInsert, len(recipients))
+	for i, userID := range recipients {
+		copied := *notif
+		if i > 0 {
+			copied.NotificationID = c.idGen.NewID()
+		}
+		copied.UserID = userID
+		pending[i] = pendingInsert{notif: &copied, spanCtx: span.SpanContext()}
+	}
+	return pending
+}
+
+// persist writes a batch to the repository with one BatchInsert. When the
+// store is unavailable it returns the unpersisted rows, to be retried.
+func (c *Consumer) persist(ctx context.Context, batch []pendingInsert) []pendingInsert {
+	if len(batch) == 0 {
+		return nil
+	}
+	if len(batch) == 1 {
+		return c.persistEach(ctx, batch)
+	}
+
+	// One BatchInsert per worker batch: one transaction and one round trip
+	notifs := make([]*models.Notification, len(batch))
+	spans := make([]trace.Span, len(batch))
+	for i, pending := range batch {
+		notifs[i] = pending.notif
+		_, spans[i] = tracing.Tracer().Start(trace.ContextWithSpanContext(ctx, pending.spanCtx), "notification.persist",
+			trace.WithAttributes(
+				attribute.String("notification_id", pending.notif.NotificationID.String()),
+				attribute.Int("batch_size", len(batch))))
+	}
+	err := c.repository.BatchInsert(ctx, notifs)
+	for _, span := range spans {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "batch insert failed")
+		}
+		span.End()
+	}
+
+	switch {
+	case err == nil:
+		c.logger.Debug("batch persisted",
+			zap.Int("batch_size", len(batch)))
+		return nil
+	case errors.Is(err, ErrUnavailable) && ctx.Err() == nil:
+		// Nothing was written; the whole batch is retried
+		return batch
+	}
 
-			// Add to batch
-			batch = append(batch, notif)
+	// A bad row fails the whole transaction: insert row by row so only the
+	// bad rows are lost
+	c.logger.Warn("batch insert failed, inserting row by row",
+		zap.Error(err),
+		zap.Int("batch_size", len(batch)))
+	return c.persistEach(ctx, batch)
+}
 
-			// Flush if batch is full
-			if len(batch) >= c.batchSize {
-				flushBatch()
+// persistEach inserts a batch one row at a time, so a bad row doesn't take
+// the others with it. When the store is unavailable it stops at the failed
+// insert and returns the rest.
+func (c *Consumer) persistEach(ctx context.Context, batch []pendingInsert) []pendingInsert {
+	for i, pending := range batch {
+		notif := pending.notif
+		insertCtx, span := tracing.Tracer().Start(trace.ContextWithSpanContext(ctx, pending.spanCtx), "notification.persist",
+			trace.WithAttributes(attribute.String("notification_id", notif.NotificationID.String())))
+		if err := c.repository.Insert(insertCtx, notif); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "insert failed")
+			if errors.Is(err, ErrUnavailable) && ctx.Err() == nil {
+				span.End()
+				return batch[i:]
 			}
+			atomic.AddInt64(&c.insertErrors, 1)
+			c.logger.Error("failed to insert notification",
+				zap.Error(err),
+				zap.String("notification_id", notif.NotificationID.String()))
 		}
+		span.End()
+	}
+
+	c.logger.Debug("batch persisted",
+		zap.Int("batch_size", len(batch)))
+	return nil
+}
+
+// Stats returns consumer lag and lifetime counters
+func (c *Consumer) Stats() ConsumerStats {
+	readerStats := c.reader.Stats()
+	stats := ConsumerStats{
+		Topic:            readerStats.Topic,
+		Lag:              readerStats.Lag,
+		Offset:           readerStats.Offset,
+		MessagesConsumed: atomic.LoadInt64(&c.messagesConsumed),
+		ParseErrors:      atomic.LoadInt64(&c.parseErrors),
+		InsertErrors:     atomic.LoadInt64(&c.insertErrors),
+
+		Watermark:           c.watermark.Time(),
+		WatermarkLagSeconds: c.watermark.Lag(time.Now()).Seconds(),
+		LateThreshold:       c.late.Threshold.String(),
+		LatePolicy:          string(c.late.Policy),
+		LateEvents:          atomic.LoadInt64(&c.lateEvents),
+		DroppedLate:         atomic.LoadInt64(&c.droppedLate),
+
+		HeaderRouted:   atomic.LoadInt64(&c.headerRouted),
+		Filtered:       atomic.LoadInt64(&c.filtered),
+		ExpressFlushes: atomic.LoadInt64(&c.expressFlushes),
+
+		SchemaVersion:     models.KafkaSchemaVersion,
+		UnsupportedSchema: atomic.LoadInt64(&c.unsupported),
+		InvalidPayloads:   atomic.LoadInt64(&c.invalidPayloads),
+		Poisoned:          atomic.LoadInt64(&c.poisoned),
+		PoisonErrors:      atomic.LoadInt64(&c.poisonErrors),
+
+		Broadcasts:          atomic.LoadInt64(&c.broadcasts),
+		BroadcastRecipients: atomic.LoadInt64(&c.recipients),
+
+		Workers:      c.workers,
+		ManualCommit: c.committer != nil,
+		InFlight:     c.offsets.InFlight(),
+		Commits:      atomic.LoadInt64(&c.commits),
+		Buffered:     atomic.LoadInt64(&c.buffered),
 	}
+	if c.spill != nil {
+		spillStats := c.spill.Stats()
+		stats.Spill = &spillStats
+	}
+	if c.quotas.Enabled() {
+		quotaStats := c.quotas.Stats()
+		stats.IngestQuota = &quotaStats
+	}
+	return stats
+}
+
+// filter counts an event dropped by the header routing
+func (c *Consumer) filter(eventType, priority string) {
+	atomic.AddInt64(&c.filtered, 1)
+	metrics.ConsumerMessages.WithLabelValues(eventType, priority, "filtered").Inc()
 }
 
 func (c *Consumer) Close() {
@@ -0,0 +1,287 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// MessageCommitter is a reader whose offsets the consumer commits itself,
+// once messages are persisted. *kafka.Reader in a consumer group satisfies
+// it; other readers are read with ReadMessage.
+type MessageCommitter interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// workerFor picks the worker for a message key, so every event of a user
+// lands on the same worker and stays in order
+func workerFor(key []byte, workers int) int {
+	if workers <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(workers))
+}
+
+// topicPartition identifies a partition for offset tracking
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// partitionOffsets tracks one partition's messages between fetch and commit
+type partitionOffsets struct {
+	pending []int64        // Fetched offsets not yet committed, in fetch order
+	done    map[int64]bool // Pending offsets whose messages are settled
+}
+
+// offsetTracker turns out-of-order completions from the workers into
+// in-order commits: a partition's offset only advances past a message once
+// every earlier message of that partition is settled too
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+	inFlight   int64 // atomic
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[topicPartition]*partitionOffsets)}
+}
+
+// Fetched records a message handed to a worker
+func (t *offsetTracker) Fetched(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[tp]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[tp] = p
+	}
+	p.pending = append(p.pending, msg.Offset)
+	metrics.ConsumerInflight.Set(float64(atomic.AddInt64(&t.inFlight, 1)))
+}
+
+// Settle marks messages as persisted (or deliberately skipped) and passes
+// the new commit point of every partition that advanced to commit. commit
+// runs under the tracker lock so commit points never go backwards.
+func (t *offsetTracker) Settle(msgs []kafka.Message, commit func(msgs []kafka.Message)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	touched := make(map[topicPartition]bool)
+	for _, msg := range msgs {
+		tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+		if p, ok := t.partitions[tp]; ok {
+			p.done[msg.Offset] = true
+			touched[tp] = true
+		}
+	}
+
+	var ready []kafka.Message
+	settled := 0
+	for tp := range touched {
+		p := t.partitions[tp]
+		last := int64(-1)
+		for len(p.pending) > 0 && p.done[p.pending[0]] {
+			last = p.pending[0]
+			delete(p.done, last)
+			p.pending = p.pending[1:]
+			settled++
+		}
+		if last >= 0 {
+			ready = append(ready, kafka.Message{Topic: tp.topic, Partition: tp.partition, Offset: last})
+		}
+	}
+	metrics.ConsumerInflight.Set(float64(atomic.AddInt64(&t.inFlight, -int64(settled))))
+
+	if len(ready) > 0 {
+		commit(ready)
+	}
+}
+
+// InFlight reports messages fetched but not yet committable
+func (t *offsetTracker) InFlight() int64 {
+	return atomic.LoadInt64(&t.inFlight)
+}
+
+// fetch reads the next message, leaving the commit to the consumer when the
+// reader supports it
+func (c *Consumer) fetch(ctx context.Context) (kafka.Message, error) {
+	if c.committer != nil {
+		return c.committer.FetchMessage(ctx)
+	}
+	return c.reader.ReadMessage(ctx)
+}
+
+// settle commits offsets for messages whose outcome is final
+func (c *Consumer) settle(ctx context.Context, msgs []kafka.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	c.offsets.Settle(msgs, func(ready []kafka.Message) {
+		if c.committer == nil {
+			return
+		}
+		// Still commit what was persisted while shutting down
+		commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := c.committer.CommitMessages(commitCtx, ready...); err != nil {
+			metrics.ConsumerCommits.WithLabelValues("error").Inc()
+			c.logger.Error("failed to commit offsets", zap.Error(err))
+			return
+		}
+		atomic.AddInt64(&c.commits, 1)
+		metrics.ConsumerCommits.WithLabelValues("success").Inc()
+	})
+}
+
+// Liveness reports whether Consume is running and how long ago its least
+// recently active worker came round its loop
+func (c *Consumer) Liveness(now time.Time) (bool, time.Duration) {
+	if atomic.LoadInt32(&c.running) == 0 {
+		return false, 0
+	}
+	var stalest time.Duration
+	for i := range c.beats {
+		stalest = max(stalest, now.Sub(time.Unix(0, atomic.LoadInt64(&c.beats[i]))))
+	}
+	return true, stalest
+}
+
+// spillBatch moves a full buffer to the spill buffer, reporting whether it did
+func (c *Consumer) spillBatch(batch []pendingInsert) bool {
+	if c.spill == nil {
+		return false
+	}
+	notifications := make([]*models.Notification, len(batch))
+	for i, pending := range batch {
+		notifications[i] = pending.notif
+	}
+	if err := c.spill.Append(notifications); err != nil {
+		if !errors.Is(err, ErrSpillFull) {
+			c.logger.Error("failed to spill notifications", zap.Error(err))
+		}
+		return false
+	}
+	c.logger.Warn("store unavailable, spilled buffered notifications to disk",
+		zap.Int("spilled", len(notifications)),
+		zap.Int64("spill_depth", c.spill.Stats().Notifications))
+	return true
+}
+
+// runWorker decodes and persists the messages routed to one worker, in
+// batches of its own. beat is refreshed every time round the loop. While the
+// store is unavailable the unpersisted batch is held, unsettled, and retried
+// with backoff; once it reaches the buffer limit it is spilled to disk, or
+// without a spill buffer (or with a full one) the worker stops taking
+// messages.
+func (c *Consumer) runWorker(ctx context.Context, queue <-chan kafka.Message, beat *int64) {
+	batch := make([]pendingInsert, 0, c.batchSize)
+	var settled []kafka.Message // Messages that are final once the batch is persisted
+	ticker := time.NewTicker(c.batchTimeout)
+	defer ticker.Stop()
+
+	var held int // Notifications held after a failed flush
+	var backoff time.Duration
+	var retryAt time.Time
+	hold := func(n int) {
+		atomic.AddInt64(&c.buffered, int64(n-held))
+		metrics.ConsumerBuffered.Add(float64(n - held))
+		held = n
+	}
+	defer hold(0)
+
+	flush := func() {
+		if time.Now().Before(retryAt) {
+			return
+		}
+		if left := c.persist(ctx, batch); len(left) > 0 {
+			batch = batch[:copy(batch, left)]
+			backoff = min(max(2*backoff, minStoreBackoff), maxStoreBackoff)
+			retryAt = time.Now().Add(backoff)
+			if len(batch) >= c.bufferLimit && c.spillBatch(batch) {
+				// On disk: settle it and keep reading
+				batch = batch[:0]
+				hold(0)
+				c.settle(ctx, settled)
+				settled = settled[:0]
+				return
+			}
+			hold(len(batch))
+			c.logger.Warn("store unavailable, buffering notifications",
+				zap.Int("buffered", len(batch)),
+				zap.Int("buffer_limit", c.bufferLimit),
+				zap.Duration("backoff", backoff))
+			return
+		}
+		hold(0)
+		backoff, retryAt = 0, time.Time{}
+		batch = batch[:0]
+		c.settle(ctx, settled)
+		settled = settled[:0]
+	}
+
+	// Held notifications are never settled when the worker stops, so a
+	// consumer group reads them again after a restart
+	stop := func() {
+		retryAt = time.Time{}
+		if held > 0 {
+			c.logger.Warn("consumer stopping with buffered notifications unpersisted",
+				zap.Int("buffered", len(batch)))
+			return
+		}
+		flush() // Flush remaining
+	}
+
+	for {
+		atomic.StoreInt64(beat, time.Now().UnixNano())
+		in := queue
+		if len(batch) >= c.bufferLimit {
+			in = nil // Full: leave the backlog in Kafka until the store is back
+		}
+
+		select {
+		case <-ctx.Done():
+			stop()
+			return
+
+		case <-ticker.C:
+			// Timeout: flush partial batch
+			flush()
+
+		case msg, ok := <-in:
+			if !ok {
+				stop()
+				return
+			}
+			settled = append(settled, msg)
+
+			pending, priority, keep := c.process(ctx, msg)
+			if !keep {
+				continue
+			}
+			batch = append(batch, pending...)
+
+			// Flush if batch is full, or right away for express priorities
+			if len(batch) >= c.batchSize {
+				flush()
+			} else if c.routing.IsExpress(priority) {
+				atomic.AddInt64(&c.expressFlushes, 1)
+				flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DrainConfig controls the pre-stop drain on SIGTERM
+type DrainConfig struct {
+	PreStopDelay time.Duration // Time between failing readiness and refusing streams, for load balancers to catch up
+	Timeout      time.Duration // Longest wait for claimed notifications to be delivered
+}
+
+// Drain takes the instance out of rotation without dropping notifications:
+// it fails readiness, refuses new SSE streams, delivers what the picker has
+// already claimed to the streams still open, then tells those clients to
+// reconnect (to another instance). Call it before shutting the HTTP server
+// down, which then waits for the streams to end. Failures are logged;
+// shutdown carries on.
+func Drain(ctx context.Context, cfg DrainConfig, health *HealthChecker, sseManager *SSEManager, taskPicker *TaskPicker, logger *zap.Logger) {
+	start := time.Now()
+	health.SetReady(false)
+
+	if cfg.PreStopDelay > 0 {
+		logger.Info("waiting for load balancers to stop routing", zap.Duration("pre_stop_delay", cfg.PreStopDelay))
+		select {
+		case <-time.After(cfg.PreStopDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	sseManager.StopAccepting()
+
+	drainCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	if err := taskPicker.Drain(drainCtx); err != nil {
+		logger.Warn("task picker drain incomplete; remaining claims will be reclaimed", zap.Error(err))
+	}
+
+	reconnects := sseManager.Drain()
+	logger.Info("drained for shutdown",
+		zap.Int("reconnects_sent", reconnects),
+		zap.Duration("took", time.Since(start)))
+}
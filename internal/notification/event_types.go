@@ -0,0 +1,422 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// MaxEventTypeLength matches the event_type VARCHAR(50) columns
+const MaxEventTypeLength = 50
+
+// eventTypePattern matches an event type ("job.new") or family ("job.*")
+var eventTypePattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*(\.\*)?$`)
+
+// deliveryChannels are the channels a registration may name
+var deliveryChannels = []string{ChannelSSE}
+
+// EventTypeRegistry holds the event types registered at runtime, so a new
+// producer's events get a priority, templates, digestibility, channels and
+// a payload schema and TTL without redeploying the service. Registrations live in the repository
+// and are written through /admin/event-types; an instance applies its own
+// writes at once and everyone's on the next reload. Lookups try the exact
+// type, then its family. Anything not registered keeps its built-in
+// priority (models.GetPriorityForEventType) and the templates file's
+// handler.
+type EventTypeRegistry struct {
+	repo     Repository
+	handlers *HandlerRegistry
+	logger   *zap.Logger
+
+	writeMu sync.Mutex // Serializes reloads and writes so an older list can't replace a newer one
+	mu      sync.RWMutex
+	types   map[string]EventTypeConfig
+	schemas map[string]*PayloadSchema // Compiled payload schemas, by event type or family
+}
+
+// EventTypeInfo is how an event type is delivered right now
+type EventTypeInfo struct {
+	EventType    string           `json:"event_type"`
+	Registration *EventTypeConfig `json:"registration,omitempty"` // The exact or family registration in effect, if any
+	Priority     models.Priority  `json:"priority"`               // Assigned when the producer sets none
+	Digestible   bool             `json:"digestible"`
+	Channel      string           `json:"channel"`
+	TTL          string           `json:"ttl,omitempty"`
+}
+
+// NewEventTypeRegistry creates a registry that overrides handlers' event
+// types. Call Reload to load the stored registrations.
+func NewEventTypeRegistry(repo Repository, handlers *HandlerRegistry, logger *zap.Logger) *EventTypeRegistry {
+	return &EventTypeRegistry{
+		repo:     repo,
+		handlers: handlers,
+		logger:   logger,
+		types:    make(map[string]EventTypeConfig),
+		schemas:  make(map[string]*PayloadSchema),
+	}
+}
+
+// Start reloads the registrations every interval until ctx is done, so
+// writes made through other instances take effect here
+func (r *EventTypeRegistry) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Reload(ctx); err != nil && ctx.Err() == nil {
+					r.logger.Warn("failed to reload event types, keeping the current ones", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	r.logger.Info("event type registry started", zap.Duration("refresh", interval))
+}
+
+// Reload replaces the registrations with the stored ones
+func (r *EventTypeRegistry) Reload(ctx context.Context) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	configs, err := r.repo.ListEventTypes(ctx)
+	if err != nil {
+		return err
+	}
+	types := make(map[string]EventTypeConfig, len(configs))
+	for _, cfg := range configs {
+		types[cfg.EventType] = cfg
+	}
+	r.apply(types)
+	return nil
+}
+
+// Validate normalizes a registration and checks it: the event type or
+// family name, priority, channels (default ["sse"]), locales, TTL, and that
+// its templates and payload schema compile
+func (r *EventTypeRegistry) Validate(cfg *EventTypeConfig) error {
+	if len(cfg.EventType) > MaxEventTypeLength {
+		return fmt.Errorf("event_type exceeds %d bytes", MaxEventTypeLength)
+	}
+	if !eventTypePattern.MatchString(cfg.EventType) {
+		return fmt.Errorf("invalid event_type %q: expected lower-case dotted names such as job.new or a family such as job.*", cfg.EventType)
+	}
+
+	cfg.Priority = models.Priority(strings.ToUpper(string(cfg.Priority)))
+	switch cfg.Priority {
+	case "", models.PriorityHigh, models.PriorityMedium, models.PriorityLow:
+	default:
+		return fmt.Errorf("invalid priority %q: expected HIGH, MEDIUM or LOW", cfg.Priority)
+	}
+
+	if len(cfg.Channels) == 0 {
+		cfg.Channels = []string{ChannelSSE}
+	}
+	for _, channel := range cfg.Channels {
+		if !slices.Contains(deliveryChannels, channel) {
+			return fmt.Errorf("unsupported channel %q (supported: %s)", channel, strings.Join(deliveryChannels, ", "))
+		}
+	}
+
+	for locale := range cfg.Locales {
+		if err := ValidateLocale(locale); err != nil {
+			return err
+		}
+	}
+	if _, err := cfg.ttl(); err != nil {
+		return err
+	}
+	if cfg.hasTemplates() {
+		if _, err := NewTemplateHandler(cfg.EventType, FallbackLocale, cfg.templateSpec(0), r.handlers.Catalog()); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	if len(cfg.PayloadSchema) > 0 {
+		if _, err := ParsePayloadSchema(cfg.PayloadSchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put validates and stores a registration, replacing any earlier one for
+// the event type, and applies it to this instance
+func (r *EventTypeRegistry) Put(ctx context.Context, cfg EventTypeConfig) (EventTypeConfig, error) {
+	if err := r.Validate(&cfg); err != nil {
+		return EventTypeConfig{}, err
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	stored, err := r.repo.PutEventType(ctx, cfg)
+	if err != nil {
+		return EventTypeConfig{}, err
+	}
+	types := r.snapshot()
+	types[stored.EventType] = stored
+	r.apply(types)
+
+	r.logger.Info("event type registered",
+		zap.String("event_type", stored.EventType),
+		zap.String("priority", string(stored.Priority)),
+		zap.Bool("digestible", stored.Digestible),
+		zap.Strings("channels", stored.Channels))
+	return stored, nil
+}
+
+// Delete removes an event type's registration, returning ErrNotFound when
+// it has none; the event type reverts to its built-in behavior
+func (r *EventTypeRegistry) Delete(ctx context.Context, eventType string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.repo.DeleteEventType(ctx, eventType); err != nil {
+		return err
+	}
+	types := r.snapshot()
+	delete(types, eventType)
+	r.apply(types)
+
+	r.logger.Info("event type unregistered", zap.String("event_type", eventType))
+	return nil
+}
+
+// List returns the registrations sorted by event type
+func (r *EventTypeRegistry) List() []EventTypeConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := make([]EventTypeConfig, 0, len(r.types))
+	for _, cfg := range r.types {
+		configs = append(configs, cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].EventType < configs[j].EventType })
+	return configs
+}
+
+// Priority returns the priority for an event whose producer set none: the
+// registered one, else the built-in mapping. A nil registry uses the
+// built-in mapping.
+func (r *EventTypeRegistry) Priority(eventType string) models.Priority {
+	if r != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		for _, pattern := range eventTypePatterns(eventType) {
+			if cfg, ok := r.types[pattern]; ok && cfg.Priority != "" {
+				return cfg.Priority
+			}
+		}
+	}
+	return models.GetPriorityForEventType(models.EventType(eventType))
+}
+
+// ExpiresAt returns when an event that happened at eventTimestamp stops
+// being worth delivering under its event type's TTL, registered or from
+// the templates file. It is nil when the event type has no TTL, or on a nil
+// registry.
+func (r *EventTypeRegistry) ExpiresAt(eventType string, eventTimestamp time.Time) *time.Time {
+	if r == nil || eventTimestamp.IsZero() {
+		return nil
+	}
+	ttl := r.handlers.Lookup(eventType).TTL()
+	if ttl <= 0 {
+		return nil
+	}
+	expiresAt := eventTimestamp.Add(ttl)
+	return &expiresAt
+}
+
+// PayloadSchema returns the schema an event type's payload must satisfy:
+// its registration's, else its family's. It is nil when neither has one,
+// or on a nil registry.
+func (r *EventTypeRegistry) PayloadSchema(eventType string) *PayloadSchema {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, pattern := range eventTypePatterns(eventType) {
+		if schema, ok := r.schemas[pattern]; ok {
+			return schema
+		}
+	}
+	return nil
+}
+
+// Describe returns how an event type is delivered right now
+func (r *EventTypeRegistry) Describe(eventType string) EventTypeInfo {
+	h := r.handlers.Lookup(eventType)
+	info := EventTypeInfo{
+		EventType:  eventType,
+		Priority:   r.Priority(eventType),
+		Digestible: h.Coalesce(),
+		Channel:    h.Channel(),
+	}
+	if ttl := h.TTL(); ttl > 0 {
+		info.TTL = ttl.String()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, pattern := range eventTypePatterns(eventType) {
+		if cfg, ok := r.types[pattern]; ok {
+			info.Registration = &cfg
+			break
+		}
+	}
+	return info
+}
+
+// snapshot copies the registrations; the caller holds r.writeMu
+func (r *EventTypeRegistry) snapshot() map[string]EventTypeConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make(map[string]EventTypeConfig, len(r.types)+1)
+	for pattern, cfg := range r.types {
+		types[pattern] = cfg
+	}
+	return types
+}
+
+// apply builds a handler override per registration and swaps them and the
+// registrations in. Families are built first so an exact type registered
+// without templates renders with its family's. A stored registration that
+// doesn't compile (one written by a newer version, say) is skipped. The
+// caller holds r.writeMu.
+func (r *EventTypeRegistry) apply(types map[string]EventTypeConfig) {
+	patterns := make([]string, 0, len(types))
+	for pattern := range types {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		iFamily, jFamily := strings.HasSuffix(patterns[i], ".*"), strings.HasSuffix(patterns[j], ".*")
+		if iFamily != jFamily {
+			return iFamily
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	overrides := make(map[string]DeliveryHandler, len(types))
+	schemas := make(map[string]*PayloadSchema)
+	for _, pattern := range patterns {
+		cfg := types[pattern]
+		h, err := r.handler(cfg, overrides)
+		var schema *PayloadSchema
+		if err == nil && len(cfg.PayloadSchema) > 0 {
+			schema, err = ParsePayloadSchema(cfg.PayloadSchema)
+		}
+		if err != nil {
+			r.logger.Warn("skipping invalid event type registration",
+				zap.String("event_type", pattern),
+				zap.Error(err))
+			delete(types, pattern)
+			continue
+		}
+		overrides[pattern] = h
+		if schema != nil {
+			schemas[pattern] = schema
+		}
+	}
+
+	r.mu.Lock()
+	r.types = types
+	r.schemas = schemas
+	r.mu.Unlock()
+	r.handlers.SetOverrides(overrides)
+}
+
+// handler builds a registration's delivery handler over the one it
+// replaces, which keeps rendering when the registration has no templates
+func (r *EventTypeRegistry) handler(cfg EventTypeConfig, overrides map[string]DeliveryHandler) (DeliveryHandler, error) {
+	base := r.handlers.resolve(cfg.EventType, overrides)
+	ttl, err := cfg.ttl()
+	if err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = base.TTL()
+	}
+	if cfg.hasTemplates() {
+		th, err := NewTemplateHandler(cfg.EventType, FallbackLocale, cfg.templateSpec(ttl), r.handlers.Catalog())
+		if err != nil {
+			return nil, err
+		}
+		base = th
+	}
+	channel := ChannelSSE
+	if len(cfg.Channels) > 0 {
+		channel = cfg.Channels[0]
+	}
+	return registeredHandler{DeliveryHandler: base, channel: channel, digestible: cfg.Digestible, ttl: ttl}, nil
+}
+
+// hasTemplates reports whether the registration brings its own text
+func (cfg EventTypeConfig) hasTemplates() bool {
+	return cfg.Title != "" || cfg.Message != "" || len(cfg.Locales) > 0
+}
+
+// ttl parses the registration's TTL, 0 when it has none
+func (cfg EventTypeConfig) ttl() (time.Duration, error) {
+	if cfg.TTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl %q: expected a positive duration such as 30m or 2h", cfg.TTL)
+	}
+	return ttl, nil
+}
+
+// templateSpec is the registration's text as a template spec
+func (cfg EventTypeConfig) templateSpec(maxAge time.Duration) TemplateSpec {
+	return TemplateSpec{
+		Title:   cfg.Title,
+		Message: cfg.Message,
+		Locales: cfg.Locales,
+		MaxAge:  maxAge,
+		NoGroup: !cfg.Digestible,
+	}
+}
+
+// registeredHandler delivers a registered event type: it renders with the
+// registration's templates or the handler it replaced, on the registered
+// channel and with the registered digestibility and TTL
+type registeredHandler struct {
+	DeliveryHandler
+	channel    string
+	digestible bool
+	ttl        time.Duration
+}
+
+// Channel returns the registration's first channel
+func (h registeredHandler) Channel() string { return h.channel }
+
+// Coalesce returns the registration's digestibility
+func (h registeredHandler) Coalesce() bool { return h.digestible }
+
+// TTL returns the registration's TTL, else the replaced handler's
+func (h registeredHandler) TTL() time.Duration { return h.ttl }
+
+// eventTypePatterns are the registry keys an event type matches, most
+// specific first
+func eventTypePatterns(eventType string) []string {
+	if dot := strings.LastIndex(eventType, "."); dot > 0 && !strings.HasSuffix(eventType, ".*") {
+		return []string{eventType, eventType[:dot] + ".*"}
+	}
+	return []string{eventType}
+}
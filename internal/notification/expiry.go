@@ -0,0 +1,208 @@
+package notification
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// SummaryPublisher publishes summary events to the metrics topic.
+// *producer.Producer implements it.
+type SummaryPublisher interface {
+	PublishJSON(ctx context.Context, key string, v interface{}) error
+}
+
+// ExpiryConfig controls the expiry sweeper
+type ExpiryConfig struct {
+	Interval  time.Duration // Time between sweeps
+	MaxAge    time.Duration // Pending/parked notifications older than this age out (0 = deadline only)
+	BatchSize int           // Notifications expired per statement; a sweep repeats until a batch comes back short
+}
+
+// ExpirySummary is the event published after every sweep
+type ExpirySummary struct {
+	Event       string           `json:"event"` // Always "expiry_sweep"
+	InstanceID  string           `json:"instance_id"`
+	SweptAt     time.Time        `json:"swept_at"`
+	DurationMs  float64          `json:"duration_ms"`
+	Expired     int64            `json:"expired"`
+	ByReason    map[string]int64 `json:"by_reason"`
+	ByEventType map[string]int64 `json:"by_event_type"`
+	ByPriority  map[string]int64 `json:"by_priority"`
+}
+
+// ExpiryStats is the sweeper's lifetime view for the admin API
+type ExpiryStats struct {
+	Interval    string           `json:"interval"`
+	MaxAge      string           `json:"max_age"`
+	Sweeps      int64            `json:"sweeps"`
+	Errors      int64            `json:"errors"`
+	Expired     int64            `json:"expired_total"`
+	ByReason    map[string]int64 `json:"by_reason"`
+	ByEventType map[string]int64 `json:"by_event_type"`
+	ByPriority  map[string]int64 `json:"by_priority"`
+	LastSweep   *ExpirySummary   `json:"last_sweep,omitempty"`
+}
+
+// ExpirySweeper periodically expires pending and parked notifications that
+// are past their deadline or have aged out, so the backlog over a long soak
+// only holds work that can still be delivered. Each sweep's counts go to
+// Prometheus, the metrics topic and the admin API.
+type ExpirySweeper struct {
+	repo       Repository
+	publisher  SummaryPublisher // nil disables summary events
+	instanceID string
+	cfg        ExpiryConfig
+	logger     *zap.Logger
+
+	mu    sync.RWMutex
+	stats ExpiryStats
+}
+
+// NewExpirySweeper creates a sweeper. publisher may be nil.
+func NewExpirySweeper(repo Repository, publisher SummaryPublisher, instanceID string, cfg ExpiryConfig, logger *zap.Logger) *ExpirySweeper {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 5000
+	}
+
+	return &ExpirySweeper{
+		repo:       repo,
+		publisher:  publisher,
+		instanceID: instanceID,
+		cfg:        cfg,
+		logger:     logger,
+		stats: ExpiryStats{
+			Interval:    cfg.Interval.String(),
+			MaxAge:      cfg.MaxAge.String(),
+			ByReason:    make(map[string]int64),
+			ByEventType: make(map[string]int64),
+			ByPriority:  make(map[string]int64),
+		},
+	}
+}
+
+// Start sweeps every interval until ctx is done
+func (s *ExpirySweeper) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep(ctx)
+			}
+		}
+	}()
+
+	s.logger.Info("expiry sweeper started",
+		zap.Duration("interval", s.cfg.Interval),
+		zap.Duration("max_age", s.cfg.MaxAge),
+		zap.Bool("publish_summaries", s.publisher != nil))
+}
+
+// Sweep expires everything currently stale and returns the summary
+func (s *ExpirySweeper) Sweep(ctx context.Context) *ExpirySummary {
+	start := time.Now()
+	summary := &ExpirySummary{
+		Event:       "expiry_sweep",
+		InstanceID:  s.instanceID,
+		SweptAt:     start,
+		ByReason:    make(map[string]int64),
+		ByEventType: make(map[string]int64),
+		ByPriority:  make(map[string]int64),
+	}
+
+	var sweepErr error
+	for {
+		counts, err := s.repo.ExpireStale(ctx, start, s.cfg.MaxAge, s.cfg.BatchSize)
+		if err != nil {
+			sweepErr = err
+			break
+		}
+
+		var batch int64
+		for _, c := range counts {
+			batch += c.Count
+			summary.ByReason[c.Reason] += c.Count
+			summary.ByEventType[c.EventType] += c.Count
+			summary.ByPriority[c.Priority] += c.Count
+			metrics.NotificationsExpired.WithLabelValues(c.EventType, c.Priority, c.Reason).Add(float64(c.Count))
+		}
+		summary.Expired += batch
+
+		if batch < int64(s.cfg.BatchSize) {
+			break
+		}
+	}
+
+	elapsed := time.Since(start)
+	summary.DurationMs = float64(elapsed.Microseconds()) / 1000
+	metrics.ExpirySweepSeconds.Observe(elapsed.Seconds())
+
+	s.mu.Lock()
+	s.stats.Sweeps++
+	if sweepErr != nil {
+		s.stats.Errors++
+	}
+	s.stats.Expired += summary.Expired
+	for k, v := range summary.ByReason {
+		s.stats.ByReason[k] += v
+	}
+	for k, v := range summary.ByEventType {
+		s.stats.ByEventType[k] += v
+	}
+	for k, v := range summary.ByPriority {
+		s.stats.ByPriority[k] += v
+	}
+	s.stats.LastSweep = summary
+	s.mu.Unlock()
+
+	if sweepErr != nil {
+		s.logger.Error("expiry sweep failed", zap.Int64("expired", summary.Expired), zap.Error(sweepErr))
+	} else if summary.Expired > 0 {
+		s.logger.Info("expired stale notifications",
+			zap.Int64("count", summary.Expired),
+			zap.Any("by_reason", summary.ByReason),
+			zap.Duration("duration", elapsed))
+	}
+
+	// Published every sweep, even empty ones, so consumers of the metrics
+	// topic can tell a clean backlog from a stalled sweeper
+	if s.publisher != nil {
+		if err := s.publisher.PublishJSON(ctx, s.instanceID, summary); err != nil {
+			s.logger.Warn("failed to publish expiry summary", zap.Error(err))
+		}
+	}
+
+	return summary
+}
+
+// Stats returns lifetime sweep totals
+func (s *ExpirySweeper) Stats() ExpiryStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := s.stats
+	stats.ByReason = copyCounts(s.stats.ByReason)
+	stats.ByEventType = copyCounts(s.stats.ByEventType)
+	stats.ByPriority = copyCounts(s.stats.ByPriority)
+	return stats
+}
+
+func copyCounts(m map[string]int64) map[string]int64 {
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+package notification
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// Goroutine pools tracked for leak detection. Each is also a pprof label
+// (pool=<name>), so `go tool pprof -tagfocus pool=sse_stream` isolates it.
+const (
+	PoolSSEStream       = "sse_stream"
+	PoolSSECleanup      = "sse_cleanup"
+	PoolAuditStream     = "audit_stream"
+	PoolPickerWorker    = "picker_worker"
+	PoolDeliveryWorker  = "delivery_worker"
+	PoolStatusUpdater   = "status_updater"
+	PoolLeaseCleanup    = "lease_cleanup"
+	PoolMetricsReporter = "metrics_reporter"
+	PoolUnparker        = "unparker"
+)
+
+// poolCounts maps pool name → *int64 live goroutine count
+var poolCounts sync.Map
+
+func poolCounter(pool string) *int64 {
+	counter, _ := poolCounts.LoadOrStore(pool, new(int64))
+	return counter.(*int64)
+}
+
+// runInPool runs fn on the calling goroutine, counted and labeled as part of pool
+func runInPool(ctx context.Context, pool string, fn func()) {
+	counter := poolCounter(pool)
+	atomic.AddInt64(counter, 1)
+	defer atomic.AddInt64(counter, -1)
+
+	pprof.Do(ctx, pprof.Labels("pool", pool), func(context.Context) { fn() })
+}
+
+// PoolCounts returns the live goroutine count of every pool seen so far
+func PoolCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	poolCounts.Range(func(key, value interface{}) bool {
+		counts[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return counts
+}
+
+// leakSlack absorbs goroutines that are legitimately between states
+// (e.g. a stream handler that has not yet registered its connection)
+const leakSlack = 10
+
+// unlabeledHistory is how many consecutive samples of steady growth in
+// unlabeled goroutines it takes to suspect a leak
+const unlabeledHistory = 10
+
+// PoolReport is one pool's current size against its expected bound
+type PoolReport struct {
+	Count     int64 `json:"count"`
+	Max       int64 `json:"max"`
+	Suspected bool  `json:"suspected"`
+}
+
+// GoroutineReport is the /admin/goroutines breakdown
+type GoroutineReport struct {
+	Total             int                   `json:"total"`
+	Pools             map[string]PoolReport `json:"pools"`
+	Unlabeled         int64                 `json:"unlabeled"`
+	UnlabeledHistory  []int64               `json:"unlabeled_history"`
+	UnlabeledSuspect  bool                  `json:"unlabeled_suspected"`
+	Suspected         []string              `json:"suspected"`
+	ActiveConnections int                   `json:"active_connections"`
+	CheckedAt         time.Time             `json:"checked_at"`
+}
+
+// LeakWatchdog periodically compares goroutine pools against what the
+// managers should be running (one stream per connection, a fixed number of
+// workers) and flags pools, or the unlabeled remainder, that grow without bound
+type LeakWatchdog struct {
+	sseManager *SSEManager
+	taskPicker *TaskPicker
+	interval   time.Duration
+	logger     *zap.Logger
+
+	mu        sync.Mutex
+	history   []int64
+	suspected map[string]bool
+	report    GoroutineReport
+}
+
+// NewLeakWatchdog creates a watchdog for the given managers
+func NewLeakWatchdog(sseManager *SSEManager, taskPicker *TaskPicker, interval time.Duration, logger *zap.Logger) *LeakWatchdog {
+	return &LeakWatchdog{
+		sseManager: sseManager,
+		taskPicker: taskPicker,
+		interval:   interval,
+		logger:     logger,
+		suspected:  make(map[string]bool),
+	}
+}
+
+// Start checks every interval until ctx is done
+func (w *LeakWatchdog) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.Check()
+			}
+		}
+	}()
+}
+
+// bounds returns the expected maximum size of every pool
+func (w *LeakWatchdog) bounds() map[string]int64 {
+	connections := int64(w.sseManager.GetActiveConnections())
+	return map[string]int64{
+		PoolSSEStream:       connections + leakSlack,
+		PoolSSECleanup:      1,
+		PoolAuditStream:     int64(w.taskPicker.Audit().Subscribers()) + leakSlack,
+		PoolPickerWorker:    int64(w.taskPicker.numPickerWorkers),
+		PoolDeliveryWorker:  int64(w.taskPicker.numDeliveryWorkers),
+		PoolStatusUpdater:   1,
+		PoolLeaseCleanup:    1,
+		PoolMetricsReporter: 1,
+		PoolUnparker:        1,
+	}
+}
+
+// Check samples goroutine counts, updates metrics and logs newly suspected
+// leaks. It returns the resulting report.
+func (w *LeakWatchdog) Check() GoroutineReport {
+	total := runtime.NumGoroutine()
+	counts := PoolCounts()
+	bounds := w.bounds()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	report := GoroutineReport{
+		Total:             total,
+		Pools:             make(map[string]PoolReport, len(bounds)),
+		Suspected:         []string{},
+		ActiveConnections: w.sseManager.GetActiveConnections(),
+		CheckedAt:         time.Now(),
+	}
+
+	var labeled int64
+	for pool, max := range bounds {
+		count := counts[pool]
+		labeled += count
+		suspected := count > max
+		report.Pools[pool] = PoolReport{Count: count, Max: max, Suspected: suspected}
+		w.transition(pool, suspected, zap.Int64("count", count), zap.Int64("max", max))
+		metrics.Goroutines.WithLabelValues(pool).Set(float64(count))
+	}
+
+	// Goroutines outside the tracked pools (HTTP, Kafka, runtime): flag steady growth
+	report.Unlabeled = int64(total) - labeled
+	w.history = append(w.history, report.Unlabeled)
+	if len(w.history) > unlabeledHistory {
+		w.history = w.history[len(w.history)-unlabeledHistory:]
+	}
+	report.UnlabeledHistory = append([]int64(nil), w.history...)
+	report.UnlabeledSuspect = steadilyGrowing(w.history)
+	w.transition("unlabeled", report.UnlabeledSuspect, zap.Int64s("history", w.history))
+	metrics.Goroutines.WithLabelValues("unlabeled").Set(float64(report.Unlabeled))
+
+	for pool, suspected := range w.suspected {
+		if suspected {
+			report.Suspected = append(report.Suspected, pool)
+		}
+	}
+	sort.Strings(report.Suspected)
+
+	w.report = report
+	return report
+}
+
+// transition logs and records a pool entering or leaving the suspected state.
+// Caller holds w.mu.
+func (w *LeakWatchdog) transition(pool string, suspected bool, fields ...zap.Field) {
+	if suspected == w.suspected[pool] {
+		return
+	}
+	w.suspected[pool] = suspected
+
+	fields = append(fields, zap.String("pool", pool))
+	if suspected {
+		metrics.GoroutineLeakSuspected.WithLabelValues(pool).Set(1)
+		w.logger.Warn("goroutine leak suspected", fields...)
+	} else {
+		metrics.GoroutineLeakSuspected.WithLabelValues(pool).Set(0)
+		w.logger.Info("goroutine pool back within bounds", fields...)
+	}
+}
+
+// Report returns the latest check, running one if none has happened yet
+func (w *LeakWatchdog) Report() GoroutineReport {
+	w.mu.Lock()
+	report := w.report
+	w.mu.Unlock()
+
+	if report.CheckedAt.IsZero() {
+		return w.Check()
+	}
+	return report
+}
+
+// steadilyGrowing reports whether a full history never shrinks and grew by
+// more than the slack overall
+func steadilyGrowing(history []int64) bool {
+	if len(history) < unlabeledHistory {
+		return false
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i] < history[i-1] {
+			return false
+		}
+	}
+	return history[len(history)-1]-history[0] > leakSlack
+}
@@ -0,0 +1,218 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"notification-delivery-system/internal/models"
+)
+
+// NewNotificationEvent builds the standard wire event for a notification.
+// Payload is the stored JSONB decoded into fields, so clients get an object
+// rather than an escaped JSON string.
+func NewNotificationEvent(notif *NotificationBatch) *models.NotificationEvent {
+	return &models.NotificationEvent{
+		Version:        models.WireVersion,
+		NotificationID: notif.NotificationID.String(),
+		EventType:      notif.EventType,
+		Priority:       notif.Priority,
+		EventTimestamp: notif.EventTimestamp,
+		Payload:        notif.Fields(),
+	}
+}
+
+// Fields decodes the stored payload. Anything but a JSON object of strings
+// (which is all producers and the publish API write) decodes as empty.
+func (n *NotificationBatch) Fields() map[string]string {
+	fields := make(map[string]string)
+	if n.Payload != "" {
+		if err := json.Unmarshal([]byte(n.Payload), &fields); err != nil {
+			return make(map[string]string)
+		}
+	}
+	return fields
+}
+
+// DeliveryHandler customizes how one event type (or family) is delivered
+type DeliveryHandler interface {
+	// Render builds the data of the notification's SSE frame
+	Render(n *NotificationBatch) *models.NotificationEvent
+	// Describe returns a human-readable title and message
+	Describe(n *NotificationBatch) (title, message string)
+	// Channel is the delivery channel recorded for attempts
+	Channel() string
+	// Coalesce reports whether the notification may share a grouped frame
+	// with the user's other notifications
+	Coalesce() bool
+	// TTL is how long after its event time the notification is still worth
+	// delivering; older ones fail as expired. 0 means no limit.
+	TTL() time.Duration
+}
+
+// DefaultHandler renders the standard payload, coalesces and never expires.
+// Embed it to override only what an event family needs.
+type DefaultHandler struct{}
+
+// Render returns the standard wire event
+func (DefaultHandler) Render(n *NotificationBatch) *models.NotificationEvent {
+	return NewNotificationEvent(n)
+}
+
+// Describe returns a generic title and message
+func (DefaultHandler) Describe(*NotificationBatch) (string, string) {
+	return "New Notification", "You have a new notification"
+}
+
+// Channel returns ChannelSSE
+func (DefaultHandler) Channel() string { return ChannelSSE }
+
+// Coalesce returns true
+func (DefaultHandler) Coalesce() bool { return true }
+
+// TTL returns 0 (no limit)
+func (DefaultHandler) TTL() time.Duration { return 0 }
+
+// TextHandler adds a fixed title and a message formatted from one payload
+// field to the standard payload
+type TextHandler struct {
+	DefaultHandler
+	Title   string
+	Format  string // fmt format with a single %s, e.g. "%s viewed your application"
+	Field   string // Payload field substituted into Format
+	MaxAge  time.Duration
+	NoGroup bool // Always deliver in its own frame
+}
+
+// Render returns the standard wire event plus title and message
+func (h TextHandler) Render(n *NotificationBatch) *models.NotificationEvent {
+	msg := NewNotificationEvent(n)
+	msg.Title, msg.Message = h.describe(msg.Payload)
+	return msg
+}
+
+// Describe returns the handler's title and formatted message
+func (h TextHandler) Describe(n *NotificationBatch) (string, string) {
+	return h.describe(n.Fields())
+}
+
+// describe formats the message from decoded fields; missing fields render empty
+func (h TextHandler) describe(fields map[string]string) (string, string) {
+	return h.Title, fmt.Sprintf(h.Format, fields[h.Field])
+}
+
+// Coalesce returns false when NoGroup is set
+func (h TextHandler) Coalesce() bool { return !h.NoGroup }
+
+// TTL returns MaxAge
+func (h TextHandler) TTL() time.Duration { return h.MaxAge }
+
+// HandlerRegistry maps event types to delivery handlers. Handlers are
+// registered for an exact type ("job.new") or a family ("job.*"); lookups
+// try the exact type, then its family, then the fallback. Overrides (the
+// runtime event type registrations) take precedence over handlers at each
+// step. The registry also carries the message catalog its templates
+// translate with.
+type HandlerRegistry struct {
+	mu        sync.RWMutex
+	handlers  map[string]DeliveryHandler
+	overrides map[string]DeliveryHandler
+	fallback  DeliveryHandler
+	catalog   *MessageCatalog
+}
+
+// NewHandlerRegistry creates an empty registry that falls back to DefaultHandler
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[string]DeliveryHandler),
+		fallback: DefaultHandler{},
+		catalog:  NewMessageCatalog(),
+	}
+}
+
+// DefaultHandlers returns a registry with the built-in event handlers and
+// messages, from default_templates.yaml
+func DefaultHandlers() *HandlerRegistry {
+	r := NewHandlerRegistry()
+	if err := r.RegisterTemplates(builtinTemplates()); err != nil {
+		panic("invalid built-in templates: " + err.Error())
+	}
+	return r
+}
+
+// Catalog returns the registry's message catalog
+func (r *HandlerRegistry) Catalog() *MessageCatalog {
+	return r.catalog
+}
+
+// Register sets the handler for an event type or family ("job.*"),
+// replacing any previous one
+func (r *HandlerRegistry) Register(pattern string, h DeliveryHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[pattern] = h
+}
+
+// SetFallback replaces the handler used for unregistered event types
+func (r *HandlerRegistry) SetFallback(h DeliveryHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = h
+}
+
+// SetOverrides replaces the overrides in one step
+func (r *HandlerRegistry) SetOverrides(overrides map[string]DeliveryHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.overrides = overrides
+}
+
+// Lookup returns the handler for an event type
+func (r *HandlerRegistry) Lookup(eventType string) DeliveryHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookup(eventType, r.overrides)
+}
+
+// resolve looks an event type up with the given overrides in place of the
+// current ones
+func (r *HandlerRegistry) resolve(eventType string, overrides map[string]DeliveryHandler) DeliveryHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookup(eventType, overrides)
+}
+
+// lookup tries the exact type, then its family, each in overrides before
+// handlers. The caller holds r.mu.
+func (r *HandlerRegistry) lookup(eventType string, overrides map[string]DeliveryHandler) DeliveryHandler {
+	if h, ok := overrides[eventType]; ok {
+		return h
+	}
+	if h, ok := r.handlers[eventType]; ok {
+		return h
+	}
+	if dot := strings.LastIndex(eventType, "."); dot > 0 {
+		family := eventType[:dot] + ".*"
+		if h, ok := overrides[family]; ok {
+			return h
+		}
+		if h, ok := r.handlers[family]; ok {
+			return h
+		}
+	}
+	return r.fallback
+}
+
+// Patterns returns the registered event types and families
+func (r *HandlerRegistry) Patterns() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	patterns := make([]string, 0, len(r.handlers))
+	for pattern := range r.handlers {
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
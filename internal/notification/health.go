@@ -0,0 +1,199 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// HealthCheck probes one component; a nil error means it is healthy
+type HealthCheck struct {
+	Name      string
+	Readiness bool // Also gates /ready (the instance can take traffic)
+	Liveness  bool // Also gates /livez (the process should be restarted when it fails)
+	Check     func(ctx context.Context) error
+}
+
+// ComponentHealth is the outcome of one check
+type ComponentHealth struct {
+	Status    string  `json:"status"` // "ok" or "fail"
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// HealthReport is the outcome of every check
+type HealthReport struct {
+	Healthy    bool                       `json:"-"`
+	Status     string                     `json:"status"` // "ok" or "unhealthy"
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// HealthChecker runs dependency and liveness checks for /health, /livez and /ready
+type HealthChecker struct {
+	checks  []HealthCheck
+	timeout time.Duration
+	ready   int32 // Set once startup completes (atomic)
+}
+
+// NewHealthChecker creates a checker that gives each check up to timeout
+func NewHealthChecker(timeout time.Duration, checks ...HealthCheck) *HealthChecker {
+	return &HealthChecker{checks: checks, timeout: timeout}
+}
+
+// SetReady marks startup complete (or the instance draining)
+func (h *HealthChecker) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&h.ready, v)
+}
+
+// Health runs every check concurrently
+func (h *HealthChecker) Health(ctx context.Context) HealthReport {
+	return h.run(ctx, h.checks)
+}
+
+// Ready reports whether startup completed and every readiness check passes
+func (h *HealthChecker) Ready(ctx context.Context) HealthReport {
+	report := h.run(ctx, h.filter(func(check HealthCheck) bool { return check.Readiness }))
+	if atomic.LoadInt32(&h.ready) == 0 {
+		report.Healthy = false
+		report.Status = "unhealthy"
+		report.Components["startup"] = ComponentHealth{Status: "fail", Error: "not started or shutting down"}
+	}
+	return report
+}
+
+// Live reports whether every liveness check passes. Draining doesn't fail
+// it: the instance is finishing its work, not stuck.
+func (h *HealthChecker) Live(ctx context.Context) HealthReport {
+	return h.run(ctx, h.filter(func(check HealthCheck) bool { return check.Liveness }))
+}
+
+func (h *HealthChecker) filter(keep func(HealthCheck) bool) []HealthCheck {
+	var checks []HealthCheck
+	for _, check := range h.checks {
+		if keep(check) {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+func (h *HealthChecker) run(ctx context.Context, checks []HealthCheck) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := HealthReport{Healthy: true, Status: "ok", Components: make(map[string]ComponentHealth, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(ctx)
+			component := ComponentHealth{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				component.Status = "fail"
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.Name] = component
+			if err != nil {
+				report.Healthy = false
+				report.Status = "unhealthy"
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// Pinger is a store that can check its connection
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PostgresHealthCheck pings the database
+func PostgresHealthCheck(db Pinger) HealthCheck {
+	return HealthCheck{Name: "postgres", Readiness: true, Check: db.Ping}
+}
+
+// KafkaHealthCheck passes when any broker accepts a connection
+func KafkaHealthCheck(brokers []string) HealthCheck {
+	return HealthCheck{
+		Name: "kafka",
+		Check: func(ctx context.Context) error {
+			var lastErr error
+			for _, broker := range brokers {
+				conn, err := kafka.DialContext(ctx, "tcp", broker)
+				if err == nil {
+					conn.Close()
+					return nil
+				}
+				lastErr = err
+			}
+			return fmt.Errorf("no broker reachable: %w", lastErr)
+		},
+	}
+}
+
+// ConsumerHealthCheck fails when the consumer is not running or one of its
+// workers has not come round its loop within stall (stuck on an insert)
+func ConsumerHealthCheck(c *Consumer, stall time.Duration) HealthCheck {
+	return HealthCheck{
+		Name:     "consumer",
+		Liveness: true,
+		Check: func(ctx context.Context) error {
+			running, stalest := c.Liveness(time.Now())
+			if !running {
+				return fmt.Errorf("consumer is not running")
+			}
+			if stalest > stall {
+				return fmt.Errorf("a consumer worker has been stuck for %s", stalest.Round(time.Millisecond))
+			}
+			return nil
+		},
+	}
+}
+
+// TaskPickerHealthCheck fails when a picker worker has not polled within
+// stall (stuck on a claim or on a full delivery channel)
+func TaskPickerHealthCheck(tp *TaskPicker, stall time.Duration) HealthCheck {
+	return HealthCheck{
+		Name:     "task_picker",
+		Liveness: true,
+		Check: func(ctx context.Context) error {
+			if stalest := tp.Liveness(time.Now()); stalest > stall {
+				return fmt.Errorf("a picker worker has been stuck for %s", stalest.Round(time.Millisecond))
+			}
+			return nil
+		},
+	}
+}
+
+// ChannelSaturationCheck fails when the picker → delivery or delivery →
+// status update channel is fuller than threshold (0-1)
+func ChannelSaturationCheck(tp *TaskPicker, threshold float64) HealthCheck {
+	return HealthCheck{
+		Name: "channels",
+		Check: func(ctx context.Context) error {
+			notifications, statusUpdates := tp.ChannelSaturation()
+			if notifications >= threshold {
+				return fmt.Errorf("notification channel %.0f%% full", notifications*100)
+			}
+			if statusUpdates >= threshold {
+				return fmt.Errorf("status update channel %.0f%% full", statusUpdates*100)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTP2Mode selects which HTTP/2 variants the server speaks
+type HTTP2Mode string
+
+const (
+	// HTTP2TLS negotiates HTTP/2 over TLS via ALPN; cleartext is HTTP/1.1 only
+	HTTP2TLS HTTP2Mode = "tls"
+	// HTTP2Cleartext also accepts prior-knowledge h2c on plain HTTP
+	HTTP2Cleartext HTTP2Mode = "h2c"
+	// HTTP2Off serves HTTP/1.1 only, even over TLS
+	HTTP2Off HTTP2Mode = "off"
+)
+
+// ParseHTTP2Mode validates an HTTP/2 mode name
+func ParseHTTP2Mode(raw string) (HTTP2Mode, error) {
+	switch mode := HTTP2Mode(raw); mode {
+	case HTTP2TLS, HTTP2Cleartext, HTTP2Off:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid http2 mode %q (want tls, h2c or off)", raw)
+	}
+}
+
+// HTTPServerConfig holds server-level connection knobs. There are no
+// read/write timeouts: SSE responses stay open indefinitely.
+type HTTPServerConfig struct {
+	ReadHeaderTimeout    time.Duration
+	IdleTimeout          time.Duration
+	MaxHeaderBytes       int // 0 = Go default (1MB)
+	HTTP2                HTTP2Mode
+	MaxConcurrentStreams int           // HTTP/2 streams per connection, 0 = Go default (250)
+	TCPKeepAlive         time.Duration // 0 = Go default (15s), negative disables
+}
+
+// NewHTTPServer creates the HTTP/SSE server. With HTTP/2 every SSE stream
+// from a client multiplexes over one TCP connection, so MaxConcurrentStreams
+// bounds how many connections one client (or proxy) can hold per socket.
+func NewHTTPServer(addr string, handler http.Handler, cfg HTTPServerConfig) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		HTTP2:             &http.HTTP2Config{MaxConcurrentStreams: cfg.MaxConcurrentStreams},
+	}
+
+	switch cfg.HTTP2 {
+	case HTTP2Cleartext:
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	case HTTP2Off:
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+	}
+
+	if cfg.TCPKeepAlive != 0 {
+		srv.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				setKeepAlive(conn, cfg.TCPKeepAlive)
+			}
+		}
+	}
+	return srv
+}
+
+// setKeepAlive overrides the keepalive the listener set on an accepted
+// connection
+func setKeepAlive(conn net.Conn, period time.Duration) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+
+	if period < 0 {
+		tcpConn.SetKeepAlive(false)
+		return
+	}
+	tcpConn.SetKeepAlive(true)
+	tcpConn.SetKeepAlivePeriod(period)
+}
+
+// http2Disabled reports whether srv was configured without HTTP/2
+func http2Disabled(srv *http.Server) bool {
+	return srv.Protocols != nil && !srv.Protocols.HTTP2()
+}
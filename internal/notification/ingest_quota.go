@@ -0,0 +1,242 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// IngestOverflow is what the consumer does with events beyond a producer's quota
+type IngestOverflow string
+
+const (
+	IngestOverflowDegrade IngestOverflow = "degrade" // Persisted as LOW priority
+	IngestOverflowDLQ     IngestOverflow = "dlq"     // Forwarded to the dead-letter topic, not persisted
+	IngestOverflowDrop    IngestOverflow = "drop"    // Not persisted
+)
+
+// Ingest quota keys
+const (
+	IngestKeySource = "source" // metadata.source_service
+	IngestKeyTenant = "tenant"
+)
+
+// Headers stamped on dead-lettered events
+const (
+	HeaderDLQReason   = "dlq_reason"
+	HeaderDLQQuotaKey = "dlq_quota_key"
+)
+
+// DeadLetterWriter receives events rejected at ingest. *kafka.Writer
+// satisfies it (see producer.NewDeadLetterWriter).
+type DeadLetterWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// IngestQuotaConfig limits the events the consumer accepts per producer
+type IngestQuotaConfig struct {
+	Key       string             // IngestKeySource or IngestKeyTenant
+	Rate      float64            // Events per second per key (0 = unlimited unless overridden)
+	Burst     int                // Events a key may send at once (default: one second's worth)
+	Overrides map[string]float64 // Per-key rates replacing Rate
+	Overflow  IngestOverflow
+}
+
+// ParseIngestOverrides parses comma-separated key=rate entries, e.g.
+// "job-service=50,followers-service=20"
+func ParseIngestOverrides(raw string) (map[string]float64, error) {
+	var overrides map[string]float64
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, rateStr, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid ingest quota override %q (want key=rate)", entry)
+		}
+		r, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || r < 0 {
+			return nil, fmt.Errorf("invalid ingest quota override %q: rate must be a non-negative number", entry)
+		}
+		if overrides == nil {
+			overrides = make(map[string]float64)
+		}
+		overrides[key] = r
+	}
+	return overrides, nil
+}
+
+// ParseIngestOverflow validates an overflow policy name
+func ParseIngestOverflow(raw string) (IngestOverflow, error) {
+	switch overflow := IngestOverflow(raw); overflow {
+	case IngestOverflowDegrade, IngestOverflowDLQ, IngestOverflowDrop:
+		return overflow, nil
+	default:
+		return "", fmt.Errorf("invalid ingest overflow policy %q (want degrade, dlq or drop)", raw)
+	}
+}
+
+// IngestQuotaStats is a point-in-time view of ingest quota enforcement
+type IngestQuotaStats struct {
+	Key          string             `json:"key"`
+	Rate         float64            `json:"rate"`
+	Overrides    map[string]float64 `json:"overrides,omitempty"`
+	Overflow     string             `json:"overflow"`
+	Degraded     int64              `json:"degraded"`
+	DeadLettered int64              `json:"dead_lettered"`
+	Dropped      int64              `json:"dropped"`
+	DLQErrors    int64              `json:"dlq_errors"`
+	OverQuota    map[string]int64   `json:"over_quota"` // By key
+}
+
+// IngestQuotas rate-limits events per source service or tenant so a noisy
+// producer cannot crowd out the rest. Limits are per consumer instance.
+type IngestQuotas struct {
+	cfg    IngestQuotaConfig
+	dlq    DeadLetterWriter
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	limiters  map[string]*rate.Limiter
+	overQuota map[string]int64
+
+	degraded     int64
+	deadLettered int64
+	dropped      int64
+	dlqErrors    int64
+}
+
+// NewIngestQuotas creates ingest quotas. dlq is required with the dlq
+// overflow policy and ignored otherwise.
+func NewIngestQuotas(cfg IngestQuotaConfig, dlq DeadLetterWriter, logger *zap.Logger) (*IngestQuotas, error) {
+	switch cfg.Key {
+	case IngestKeySource, IngestKeyTenant:
+	default:
+		return nil, fmt.Errorf("invalid ingest quota key %q (want %s or %s)", cfg.Key, IngestKeySource, IngestKeyTenant)
+	}
+	if _, err := ParseIngestOverflow(string(cfg.Overflow)); err != nil {
+		return nil, err
+	}
+	if cfg.Overflow == IngestOverflowDLQ && dlq == nil {
+		return nil, fmt.Errorf("ingest overflow policy dlq needs a dead-letter topic")
+	}
+
+	return &IngestQuotas{
+		cfg:       cfg,
+		dlq:       dlq,
+		logger:    logger,
+		limiters:  make(map[string]*rate.Limiter),
+		overQuota: make(map[string]int64),
+	}, nil
+}
+
+// Enabled reports whether any key is limited
+func (q *IngestQuotas) Enabled() bool {
+	return q != nil && (q.cfg.Rate > 0 || len(q.cfg.Overrides) > 0)
+}
+
+// KeyOf returns the quota key of an event
+func (q *IngestQuotas) KeyOf(msg *models.KafkaMessage) string {
+	if q.cfg.Key == IngestKeyTenant {
+		return models.TenantOrDefault(msg.TenantID)
+	}
+	return msg.Metadata.SourceService
+}
+
+// Allow takes one event from key's quota
+func (q *IngestQuotas) Allow(key string) bool {
+	limit := q.cfg.Rate
+	if override, ok := q.cfg.Overrides[key]; ok {
+		limit = override
+	}
+	if limit <= 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	limiter, ok := q.limiters[key]
+	if !ok {
+		burst := q.cfg.Burst
+		if burst <= 0 {
+			burst = max(int(limit), 1)
+		}
+		limiter = rate.NewLimiter(rate.Limit(limit), burst)
+		q.limiters[key] = limiter
+	}
+	if limiter.Allow() {
+		return true
+	}
+	q.overQuota[key]++
+	return false
+}
+
+// Overflow applies the overflow policy to an event over key's quota and
+// reports whether it should still be persisted (degraded to LOW)
+func (q *IngestQuotas) Overflow(ctx context.Context, key string, msg kafka.Message, kafkaMsg *models.KafkaMessage) bool {
+	metrics.ConsumerIngestOverQuota.WithLabelValues(q.cfg.Key, string(q.cfg.Overflow)).Inc()
+
+	switch q.cfg.Overflow {
+	case IngestOverflowDegrade:
+		atomic.AddInt64(&q.degraded, 1)
+		kafkaMsg.Priority = string(models.PriorityLow)
+		return true
+
+	case IngestOverflowDLQ:
+		dead := kafka.Message{
+			Key:   msg.Key,
+			Value: msg.Value,
+			Headers: append(append([]kafka.Header(nil), msg.Headers...),
+				kafka.Header{Key: HeaderDLQReason, Value: []byte("ingest_quota")},
+				kafka.Header{Key: HeaderDLQQuotaKey, Value: []byte(key)}),
+		}
+		if err := q.dlq.WriteMessages(ctx, dead); err != nil {
+			atomic.AddInt64(&q.dlqErrors, 1)
+			q.logger.Error("failed to dead-letter event over ingest quota",
+				zap.Error(err),
+				zap.String("quota_key", key),
+				zap.String("event_id", kafkaMsg.EventID))
+			return false
+		}
+		atomic.AddInt64(&q.deadLettered, 1)
+		return false
+
+	default:
+		atomic.AddInt64(&q.dropped, 1)
+		return false
+	}
+}
+
+// Stats returns the quota configuration and overflow counters
+func (q *IngestQuotas) Stats() IngestQuotaStats {
+	q.mu.Lock()
+	overQuota := make(map[string]int64, len(q.overQuota))
+	for key, n := range q.overQuota {
+		overQuota[key] = n
+	}
+	q.mu.Unlock()
+
+	return IngestQuotaStats{
+		Key:          q.cfg.Key,
+		Rate:         q.cfg.Rate,
+		Overrides:    q.cfg.Overrides,
+		Overflow:     string(q.cfg.Overflow),
+		Degraded:     atomic.LoadInt64(&q.degraded),
+		DeadLettered: atomic.LoadInt64(&q.deadLettered),
+		Dropped:      atomic.LoadInt64(&q.dropped),
+		DLQErrors:    atomic.LoadInt64(&q.dlqErrors),
+		OverQuota:    overQuota,
+	}
+}
@@ -0,0 +1,197 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// PartitionLag is how far the consumer group trails one partition
+type PartitionLag struct {
+	Partition     int   `json:"partition"`
+	HighWatermark int64 `json:"high_watermark"`
+	Committed     int64 `json:"committed"` // -1 before the group's first commit
+	Lag           int64 `json:"lag"`
+}
+
+// LagReport is the latest consumer group lag across the topic's partitions
+type LagReport struct {
+	Topic      string         `json:"topic"`
+	GroupID    string         `json:"group_id"`
+	CheckedAt  time.Time      `json:"checked_at"`
+	TotalLag   int64          `json:"total_lag"`
+	MaxLag     int64          `json:"max_lag"`
+	Partitions []PartitionLag `json:"partitions"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// LagMonitor periodically asks Kafka for each partition's high-water mark
+// and the consumer group's committed offset, so ingest falling behind shows
+// up even while the consumer itself is stuck
+type LagMonitor struct {
+	client   *kafka.Client
+	groupID  string
+	topic    string
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu     sync.RWMutex
+	report LagReport
+}
+
+// NewLagMonitor creates a lag monitor for a consumer group on a topic
+func NewLagMonitor(brokers []string, groupID, topic string, interval time.Duration, logger *zap.Logger) *LagMonitor {
+	return &LagMonitor{
+		client: &kafka.Client{
+			Addr:    kafka.TCP(brokers...),
+			Timeout: 10 * time.Second,
+		},
+		groupID:  groupID,
+		topic:    topic,
+		interval: interval,
+		logger:   logger,
+		report:   LagReport{Topic: topic, GroupID: groupID},
+	}
+}
+
+// Start checks lag every interval until ctx is done
+func (m *LagMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.evaluate(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	m.logger.Info("consumer lag monitor started",
+		zap.String("topic", m.topic),
+		zap.String("group_id", m.groupID),
+		zap.Duration("interval", m.interval))
+}
+
+// Report returns the latest lag snapshot
+func (m *LagMonitor) Report() LagReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.report
+}
+
+// TotalLag returns the latest lag summed over partitions
+func (m *LagMonitor) TotalLag() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.report.TotalLag
+}
+
+// evaluate refreshes the report and the lag gauges
+func (m *LagMonitor) evaluate(ctx context.Context) {
+	partitions, err := m.check(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			metrics.ConsumerLagChecks.WithLabelValues("error").Inc()
+			m.logger.Warn("failed to check consumer lag", zap.Error(err))
+			m.mu.Lock()
+			m.report.Error = err.Error()
+			m.mu.Unlock()
+		}
+		return
+	}
+	metrics.ConsumerLagChecks.WithLabelValues("success").Inc()
+
+	report := LagReport{
+		Topic:      m.topic,
+		GroupID:    m.groupID,
+		CheckedAt:  time.Now(),
+		Partitions: partitions,
+	}
+	for _, p := range partitions {
+		report.TotalLag += p.Lag
+		report.MaxLag = max(report.MaxLag, p.Lag)
+		metrics.ConsumerPartitionLag.WithLabelValues(strconv.Itoa(p.Partition)).Set(float64(p.Lag))
+	}
+	metrics.ConsumerTotalLag.Set(float64(report.TotalLag))
+
+	m.mu.Lock()
+	m.report = report
+	m.mu.Unlock()
+}
+
+// check fetches the topic's partitions, their offsets and the group's
+// commits. A partition the group never committed on lags by everything
+// still retained.
+func (m *LagMonitor) check(ctx context.Context) ([]PartitionLag, error) {
+	meta, err := m.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{m.topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	if len(meta.Topics) != 1 {
+		return nil, fmt.Errorf("topic %q not found", m.topic)
+	}
+	if meta.Topics[0].Error != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", meta.Topics[0].Error)
+	}
+
+	ids := make([]int, 0, len(meta.Topics[0].Partitions))
+	requests := make([]kafka.OffsetRequest, 0, 2*len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		ids = append(ids, p.ID)
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+
+	offsets, err := m.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{m.topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	committed, err := m.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: m.groupID,
+		Topics:  map[string][]int{m.topic: ids},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", committed.Error)
+	}
+	commits := make(map[int]int64)
+	for _, p := range committed.Topics[m.topic] {
+		if p.Error == nil {
+			commits[p.Partition] = p.CommittedOffset
+		}
+	}
+
+	var partitions []PartitionLag
+	for _, p := range offsets.Topics[m.topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets of partition %d: %w", p.Partition, p.Error)
+		}
+
+		lag := PartitionLag{Partition: p.Partition, HighWatermark: p.LastOffset, Committed: -1}
+		if offset, ok := commits[p.Partition]; ok && offset >= 0 {
+			lag.Committed = offset
+			lag.Lag = max(p.LastOffset-offset, 0)
+		} else {
+			lag.Lag = max(p.LastOffset-p.FirstOffset, 0)
+		}
+		partitions = append(partitions, lag)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Partition < partitions[j].Partition })
+	return partitions, nil
+}
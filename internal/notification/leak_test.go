@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// leakTestPicker is a small picker polling fast enough for tests
+func leakTestPicker(repo Repository, sseManager *SSEManager) *TaskPicker {
+	return NewTaskPicker(TaskPickerConfig{
+		InstanceID:         "leak-test",
+		NumPickerWorkers:   2,
+		NumDeliveryWorkers: 2,
+		BatchSize:          10,
+		PollInterval:       10 * time.Millisecond,
+		LeaseDuration:      30 * time.Second,
+		ChannelBufferSize:  10,
+		MaxInflight:        100,
+		MaxGroupSize:       1,
+	}, repo, sseManager, zap.NewNop())
+}
+
+// assertPoolsEmpty fails if a labeled pool still counts a live goroutine
+func assertPoolsEmpty(t *testing.T) {
+	t.Helper()
+	for pool, count := range PoolCounts() {
+		if count != 0 {
+			t.Errorf("pool %s has %d goroutines after stop", pool, count)
+		}
+	}
+}
+
+func TestSSEManagerStopLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	m := NewSSEManager(SSEManagerConfig{MaxConnections: 10, CleanupInterval: 10 * time.Millisecond}, zap.NewNop())
+	time.Sleep(30 * time.Millisecond) // Let the cleanup loop run a few sweeps
+	m.Stop()
+
+	assertPoolsEmpty(t)
+}
+
+func TestTaskPickerStopLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	m := NewSSEManager(SSEManagerConfig{MaxConnections: 10}, zap.NewNop())
+	tp := leakTestPicker(NewMemoryRepository(zap.NewNop()), m)
+	tp.Start()
+	time.Sleep(30 * time.Millisecond)
+	tp.Stop()
+	tp.Stop() // Second stop is a no-op
+	m.Stop()
+
+	assertPoolsEmpty(t)
+}
+
+// TestDrainLeavesNoGoroutines delivers over a real stream, then drains and
+// stops everything the way a rolling deploy does: picker drain, stream
+// drain, picker and manager stop, server shutdown.
+func TestDrainLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	repo := NewMemoryRepository(zap.NewNop())
+	m := NewSSEManager(SSEManagerConfig{MaxConnections: 10}, zap.NewNop())
+	tp := leakTestPicker(repo, m)
+
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		m.StreamToClient(c, "", c.Query("user_id"))
+	})
+	server := httptest.NewServer(router)
+	client := server.Client()
+
+	resp, err := client.Get(server.URL + "/stream?user_id=user_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := make(chan string, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events <- name
+			}
+		}
+	}()
+	waitForEvent(t, events, "connected")
+
+	tp.Start()
+	now := time.Now()
+	if err := repo.Insert(context.Background(), &models.Notification{
+		NotificationID:                uuid.New(),
+		TenantID:                      models.DefaultTenantID,
+		UserID:                        "user_1",
+		EventType:                     models.EventJobNew,
+		Priority:                      models.PriorityHigh,
+		Status:                        "not_pushed",
+		EventTimestamp:                now,
+		NotificationReceivedTimestamp: now,
+		CreatedAt:                     now,
+		Payload:                       map[string]string{"job_title": "SRE"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, events, "notification")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tp.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if open := m.Drain(); open != 1 {
+		t.Errorf("drain asked %d streams to reconnect, want 1", open)
+	}
+	waitForEvent(t, events, "reconnect")
+	for range events {
+		// The stream ends after the reconnect event
+	}
+
+	tp.Stop()
+	m.Stop()
+	server.Close()
+	client.CloseIdleConnections()
+
+	assertPoolsEmpty(t)
+}
+
+// waitForEvent reads SSE event names until want arrives
+func waitForEvent(t *testing.T, events <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case name, ok := <-events:
+			if !ok {
+				t.Fatalf("stream ended before %q", want)
+			}
+			if name == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %q event within 5s", want)
+		}
+	}
+}
@@ -0,0 +1,1119 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// memoryRecord is a notification row held by MemoryRepository
+type memoryRecord struct {
+	notif        models.Notification
+	payload      string
+	status       string
+	deliveredAt  time.Time
+	errorMessage string
+	instanceID   string
+	leaseTimeout time.Time
+	persistedAt  time.Time
+	claimedAt    time.Time
+	nextAttempt  time.Time       // Failed deliveries aren't claimed again before this
+	attempts     []*StatusUpdate // Delivery attempt history, oldest first
+}
+
+// MemoryRepository is an in-process Repository for demos and local runs.
+// It mirrors the PostgreSQL semantics (claim leases, status transitions)
+// but keeps everything in memory, so nothing survives a restart unless it is
+// carried over in a DeliverySnapshot.
+type MemoryRepository struct {
+	mu          sync.Mutex
+	records     map[uuid.UUID]*memoryRecord
+	preferences map[string]UserPreferences // By connectionKey
+	eventTypes  map[string]EventTypeConfig
+	segments    map[string]Segment
+	// Topic subscriptions, by connectionKey then topic, and the subscribers
+	// of each topic by connectionKey(tenant, topic)
+	subscriptions map[string]map[string]time.Time
+	subscribers   map[string]map[string]bool
+	logger        *zap.Logger
+}
+
+// NewMemoryRepository creates a new in-memory repository
+func NewMemoryRepository(logger *zap.Logger) *MemoryRepository {
+	logger.Info("memory repository initialized")
+
+	return &MemoryRepository{
+		records:     make(map[uuid.UUID]*memoryRecord),
+		preferences: make(map[string]UserPreferences),
+		eventTypes:  make(map[string]EventTypeConfig),
+		segments:    make(map[string]Segment),
+		logger:      logger,
+
+		subscriptions: make(map[string]map[string]time.Time),
+		subscribers:   make(map[string]map[string]bool),
+	}
+}
+
+// Insert adds a notification
+func (r *MemoryRepository) Insert(ctx context.Context, notification *models.Notification) error {
+	return r.BatchInsert(ctx, []*models.Notification{notification})
+}
+
+// BatchInsert adds multiple notifications
+func (r *MemoryRepository) BatchInsert(ctx context.Context, notifications []*models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// All or nothing, like the Postgres transaction
+	for _, notif := range notifications {
+		if _, exists := r.records[notif.NotificationID]; exists {
+			return fmt.Errorf("notification %s already exists: %w", notif.NotificationID, ErrConflict)
+		}
+	}
+
+	now := time.Now()
+	for _, notif := range notifications {
+		payloadJSON, err := json.Marshal(notif.Payload)
+		if err != nil {
+			payloadJSON = []byte("{}")
+		}
+
+		status := notif.Status
+		if status == "" {
+			status = "not_pushed"
+		}
+
+		rec := &memoryRecord{
+			notif:       *notif,
+			payload:     string(payloadJSON),
+			status:      status,
+			persistedAt: now,
+		}
+		rec.notif.TenantID = models.TenantOrDefault(notif.TenantID)
+		r.records[notif.NotificationID] = rec
+	}
+
+	return nil
+}
+
+// ClaimBatch claims up to batchSize pending notifications in policy order
+func (r *MemoryRepository) ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var pending []*memoryRecord
+	for _, rec := range r.records {
+		if rec.status == "not_pushed" && !rec.nextAttempt.After(now) {
+			pending = append(pending, rec)
+		}
+	}
+
+	return r.claimRecords(pending, instanceID, batchSize, leaseDuration, policy), nil
+}
+
+// ClaimForUsers claims up to batchSize pending notifications of the given
+// users in policy order
+func (r *MemoryRepository) ClaimForUsers(ctx context.Context, instanceID string, users []UserRef, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	wanted := make(map[string]bool, len(users))
+	for _, u := range users {
+		wanted[connectionKey(u.TenantID, u.UserID)] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var pending []*memoryRecord
+	for _, rec := range r.records {
+		if rec.status == "not_pushed" && !rec.nextAttempt.After(now) && wanted[connectionKey(rec.notif.TenantID, rec.notif.UserID)] {
+			pending = append(pending, rec)
+		}
+	}
+
+	return r.claimRecords(pending, instanceID, batchSize, leaseDuration, policy), nil
+}
+
+// ClaimWeighted claims up to quotas[p] pending notifications of each
+// priority, earliest deadline first within a priority
+func (r *MemoryRepository) ClaimWeighted(ctx context.Context, instanceID string, quotas map[models.Priority]int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	pending := make(map[int][]*memoryRecord, len(claimPriorities))
+	for _, rec := range r.records {
+		if rec.status == "not_pushed" && !rec.nextAttempt.After(now) {
+			rank := priorityRank(rec.notif.Priority)
+			pending[rank] = append(pending[rank], rec)
+		}
+	}
+
+	var batch []*NotificationBatch
+	for rank, p := range claimPriorities {
+		if quotas[p] > 0 {
+			batch = append(batch, r.claimRecords(pending[rank], instanceID, quotas[p], leaseDuration, ClaimPolicyPriority)...)
+		}
+	}
+	return batch, nil
+}
+
+// ClaimParked un-parks up to batchSize of a user's parked notifications by
+// claiming them for instanceID, highest priority first
+func (r *MemoryRepository) ClaimParked(ctx context.Context, tenantID, userID, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenantID = models.TenantOrDefault(tenantID)
+	var parked []*memoryRecord
+	for _, rec := range r.records {
+		if rec.status == "parked" && rec.notif.TenantID == tenantID && rec.notif.UserID == userID {
+			parked = append(parked, rec)
+		}
+	}
+
+	return r.claimRecords(parked, instanceID, batchSize, leaseDuration, ClaimPolicyPriority), nil
+}
+
+// claimRecords claims up to batchSize of the given records in policy order.
+// Caller holds r.mu.
+func (r *MemoryRepository) claimRecords(records []*memoryRecord, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) []*NotificationBatch {
+	sort.Slice(records, func(i, j int) bool {
+		return claimLess(&records[i].notif, &records[j].notif, policy)
+	})
+
+	if len(records) > batchSize {
+		records = records[:batchSize]
+	}
+
+	now := time.Now()
+	leaseTimeout := now.Add(leaseDuration)
+	batch := make([]*NotificationBatch, 0, len(records))
+	for _, rec := range records {
+		rec.status = "claimed"
+		rec.instanceID = instanceID
+		rec.leaseTimeout = leaseTimeout
+		rec.claimedAt = now
+
+		batch = append(batch, &NotificationBatch{
+			NotificationID:                rec.notif.NotificationID,
+			TenantID:                      rec.notif.TenantID,
+			UserID:                        rec.notif.UserID,
+			Topic:                         rec.notif.Topic,
+			EventType:                     string(rec.notif.EventType),
+			Priority:                      string(rec.notif.Priority),
+			EventTimestamp:                rec.notif.EventTimestamp,
+			NotificationReceivedTimestamp: rec.notif.NotificationReceivedTimestamp,
+			Payload:                       rec.payload,
+			TraceID:                       rec.notif.TraceID,
+			RetryCount:                    rec.notif.RetryCount,
+			PersistedAt:                   rec.persistedAt,
+			ClaimedAt:                     now,
+		})
+		if rec.notif.ProducedAt != nil {
+			batch[len(batch)-1].ProducedAt = *rec.notif.ProducedAt
+		}
+		if rec.notif.ExpiresAt != nil {
+			batch[len(batch)-1].ExpiresAt = *rec.notif.ExpiresAt
+		}
+	}
+
+	return batch
+}
+
+// claimLess orders notifications the same way claimOrderBy does in SQL
+func claimLess(a, b *models.Notification, policy ClaimPolicy) bool {
+	rankA, rankB := priorityRank(a.Priority), priorityRank(b.Priority)
+	deadlineCmp := compareDeadlines(a.ExpiresAt, b.ExpiresAt)
+
+	if policy == ClaimPolicyDeadline {
+		if deadlineCmp != 0 {
+			return deadlineCmp < 0
+		}
+		if rankA != rankB {
+			return rankA < rankB
+		}
+	} else {
+		if rankA != rankB {
+			return rankA < rankB
+		}
+		if deadlineCmp != 0 {
+			return deadlineCmp < 0
+		}
+	}
+
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// priorityRank maps priority to a sortable rank (HIGH first)
+func priorityRank(p models.Priority) int {
+	switch p {
+	case models.PriorityHigh:
+		return 0
+	case models.PriorityMedium:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// compareDeadlines compares expiry times with NULLS LAST semantics
+func compareDeadlines(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case a.Before(*b):
+		return -1
+	case b.Before(*a):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// BatchUpdateStatus updates the status of multiple notifications
+func (r *MemoryRepository) BatchUpdateStatus(ctx context.Context, updates []*StatusUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, update := range updates {
+		rec, ok := r.records[update.NotificationID]
+		if !ok {
+			continue
+		}
+
+		rec.status = update.Status
+		rec.errorMessage = update.ErrorMsg
+		rec.nextAttempt = update.NextAttemptAt
+		if !update.AttemptedAt.IsZero() {
+			rec.attempts = append(rec.attempts, update)
+		}
+		rec.instanceID = ""
+		rec.leaseTimeout = time.Time{}
+		if update.Status == "pushed" {
+			rec.deliveredAt = now
+		}
+	}
+
+	return nil
+}
+
+// ReclaimStaleTasks reclaims notifications with expired leases
+func (r *MemoryRepository) ReclaimStaleTasks(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, rec := range r.records {
+		if rec.status == "claimed" && rec.leaseTimeout.Before(now) {
+			rec.status = "not_pushed"
+			rec.instanceID = ""
+			rec.leaseTimeout = time.Time{}
+			rec.notif.RetryCount++
+			count++
+		}
+	}
+
+	if count > 0 {
+		r.logger.Info("reclaimed stale tasks", zap.Int("count", count))
+	}
+
+	return count, nil
+}
+
+// RequeueFailed moves up to limit failed notifications (oldest first) back to not_pushed
+func (r *MemoryRepository) RequeueFailed(ctx context.Context, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var failed []*memoryRecord
+	for _, rec := range r.records {
+		if rec.status == "failed" {
+			failed = append(failed, rec)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].notif.CreatedAt.Before(failed[j].notif.CreatedAt)
+	})
+	if len(failed) > limit {
+		failed = failed[:limit]
+	}
+
+	for _, rec := range failed {
+		rec.status = "not_pushed"
+		rec.errorMessage = ""
+		rec.notif.RetryCount++
+	}
+
+	if len(failed) > 0 {
+		r.logger.Info("requeued failed notifications", zap.Int("count", len(failed)))
+	}
+
+	return len(failed), nil
+}
+
+// ExpireStale marks up to limit pending or parked notifications expired when
+// they are past their deadline or, with maxAge > 0, older than maxAge, and
+// returns the counts by event type, priority and reason
+func (r *MemoryRepository) ExpireStale(ctx context.Context, now time.Time, maxAge time.Duration, limit int) ([]ExpiredCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := make(map[ExpiredCount]int64)
+	expired := 0
+	for _, rec := range r.records {
+		if expired >= limit {
+			break
+		}
+		if rec.status != "not_pushed" && rec.status != "parked" {
+			continue
+		}
+
+		var reason string
+		switch {
+		case rec.notif.ExpiresAt != nil && rec.notif.ExpiresAt.Before(now):
+			reason = ExpiryReasonDeadline
+		case maxAge > 0 && now.Sub(rec.notif.CreatedAt) > maxAge:
+			reason = ExpiryReasonAge
+		default:
+			continue
+		}
+
+		rec.status = "expired"
+		rec.errorMessage = reason
+		rec.instanceID = ""
+		rec.leaseTimeout = time.Time{}
+		groups[ExpiredCount{EventType: string(rec.notif.EventType), Priority: string(rec.notif.Priority), Reason: reason}]++
+		expired++
+	}
+
+	counts := make([]ExpiredCount, 0, len(groups))
+	for group, n := range groups {
+		group.Count = n
+		counts = append(counts, group)
+	}
+	return counts, nil
+}
+
+// Reprioritize moves up to limit pending or parked notifications of
+// priority from, persisted before createdBefore, to priority to
+func (r *MemoryRepository) Reprioritize(ctx context.Context, from, to models.Priority, createdBefore time.Time, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	moved := 0
+	for _, rec := range r.records {
+		if moved >= limit {
+			break
+		}
+		if (rec.status != "not_pushed" && rec.status != "parked") || rec.notif.Priority != from || !rec.notif.CreatedAt.Before(createdBefore) {
+			continue
+		}
+		rec.notif.Priority = to
+		moved++
+	}
+	return moved, nil
+}
+
+// GetUserNotifications retrieves recent notifications for a user within a tenant
+func (r *MemoryRepository) GetUserNotifications(ctx context.Context, tenantID, userID string, limit int, includeArchived bool) ([]map[string]interface{}, error) {
+	tenantID = models.TenantOrDefault(tenantID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []*memoryRecord
+	for _, rec := range r.records {
+		if rec.notif.TenantID == tenantID && rec.notif.UserID == userID && (includeArchived || rec.status != "archived") {
+			matched = append(matched, rec)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].notif.EventTimestamp.After(matched[j].notif.EventTimestamp)
+	})
+
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	var results []map[string]interface{}
+	for _, rec := range matched {
+		result := map[string]interface{}{
+			"notification_id":                 rec.notif.NotificationID.String(),
+			"tenant_id":                       rec.notif.TenantID,
+			"user_id":                         rec.notif.UserID,
+			"event_type":                      string(rec.notif.EventType),
+			"priority":                        string(rec.notif.Priority),
+			"status":                          rec.status,
+			"event_timestamp":                 rec.notif.EventTimestamp,
+			"notification_received_timestamp": rec.notif.NotificationReceivedTimestamp,
+			"is_read":                         rec.notif.IsRead,
+		}
+
+		if !rec.deliveredAt.IsZero() {
+			result["notification_delivered_timestamp"] = rec.deliveredAt
+			result["delay_seconds"] = rec.deliveredAt.Sub(rec.notif.EventTimestamp).Seconds()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetUnreadCounts counts a user's unread notifications by event type,
+// leaving out expired and archived ones
+func (r *MemoryRepository) GetUnreadCounts(ctx context.Context, tenantID, userID string) (map[string]int64, error) {
+	tenantID = models.TenantOrDefault(tenantID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, rec := range r.records {
+		if rec.notif.TenantID == tenantID && rec.notif.UserID == userID && !rec.notif.IsRead && rec.status != "expired" && rec.status != "archived" {
+			counts[string(rec.notif.EventType)]++
+		}
+	}
+	return counts, nil
+}
+
+// MarkRead marks the given notifications of a user read, or all of them
+// when notificationIDs is empty, and returns how many were unread
+func (r *MemoryRepository) MarkRead(ctx context.Context, tenantID, userID string, notificationIDs []uuid.UUID) (int, error) {
+	tenantID = models.TenantOrDefault(tenantID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mark := func(rec *memoryRecord) int {
+		if rec.notif.TenantID != tenantID || rec.notif.UserID != userID || rec.notif.IsRead {
+			return 0
+		}
+		rec.notif.IsRead = true
+		return 1
+	}
+
+	marked := 0
+	if len(notificationIDs) == 0 {
+		for _, rec := range r.records {
+			marked += mark(rec)
+		}
+		return marked, nil
+	}
+	for _, id := range notificationIDs {
+		if rec, ok := r.records[id]; ok {
+			marked += mark(rec)
+		}
+	}
+	return marked, nil
+}
+
+// ArchiveNotification moves a user's notification to the archived status;
+// ErrNotFound when the user has no such notification, ErrConflict while
+// it is claimed
+func (r *MemoryRepository) ArchiveNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, err := r.ownedRecord(tenantID, userID, notificationID)
+	if err != nil {
+		return err
+	}
+	rec.status = "archived"
+	rec.instanceID = ""
+	rec.leaseTimeout = time.Time{}
+	rec.nextAttempt = time.Time{}
+	return nil
+}
+
+// DeleteNotification removes a user's notification and its attempts;
+// errors as ArchiveNotification
+func (r *MemoryRepository) DeleteNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.ownedRecord(tenantID, userID, notificationID); err != nil {
+		return err
+	}
+	delete(r.records, notificationID)
+	return nil
+}
+
+// GetUserPreferences returns a user's preferences, the zero value when
+// never set
+func (r *MemoryRepository) GetUserPreferences(ctx context.Context, tenantID, userID string) (UserPreferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.preferences[connectionKey(tenantID, userID)], nil
+}
+
+// SetUserPreferences replaces a user's preferences
+func (r *MemoryRepository) SetUserPreferences(ctx context.Context, tenantID, userID string, prefs UserPreferences) (UserPreferences, error) {
+	prefs.UpdatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.preferences[connectionKey(tenantID, userID)] = prefs
+	return prefs, nil
+}
+
+// ListEventTypes returns the registered event types
+func (r *MemoryRepository) ListEventTypes(ctx context.Context) ([]EventTypeConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configs := make([]EventTypeConfig, 0, len(r.eventTypes))
+	for _, cfg := range r.eventTypes {
+		configs = append(configs, cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].EventType < configs[j].EventType })
+	return configs, nil
+}
+
+// PutEventType registers an event type, replacing its earlier registration
+func (r *MemoryRepository) PutEventType(ctx context.Context, cfg EventTypeConfig) (EventTypeConfig, error) {
+	cfg.UpdatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.eventTypes[cfg.EventType] = cfg
+	return cfg, nil
+}
+
+// DeleteEventType removes an event type's registration
+func (r *MemoryRepository) DeleteEventType(ctx context.Context, eventType string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.eventTypes[eventType]; !ok {
+		return ErrNotFound
+	}
+	delete(r.eventTypes, eventType)
+	return nil
+}
+
+// ListSegments returns the registered segments, without their members
+func (r *MemoryRepository) ListSegments(ctx context.Context) ([]Segment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	segments := make([]Segment, 0, len(r.segments))
+	for _, segment := range r.segments {
+		segment.UserIDs = nil
+		segments = append(segments, segment)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Segment < segments[j].Segment })
+	return segments, nil
+}
+
+// GetSegment returns a segment with its members
+func (r *MemoryRepository) GetSegment(ctx context.Context, name string) (Segment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	segment, ok := r.segments[name]
+	if !ok {
+		return Segment{}, fmt.Errorf("segment %s: %w", name, ErrNotFound)
+	}
+	segment.UserIDs = slices.Clone(segment.UserIDs)
+	return segment, nil
+}
+
+// PutSegment registers a segment, replacing its earlier members
+func (r *MemoryRepository) PutSegment(ctx context.Context, segment Segment) (Segment, error) {
+	segment.UserIDs = slices.Clone(segment.UserIDs)
+	segment.Size = len(segment.UserIDs)
+	segment.UpdatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.segments[segment.Segment] = segment
+	return segment, nil
+}
+
+// DeleteSegment removes a segment
+func (r *MemoryRepository) DeleteSegment(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.segments[name]; !ok {
+		return ErrNotFound
+	}
+	delete(r.segments, name)
+	return nil
+}
+
+// ListSubscriptions returns a user's topics in name order
+func (r *MemoryRepository) ListSubscriptions(ctx context.Context, tenantID, userID string) ([]Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	topics := r.subscriptions[connectionKey(tenantID, userID)]
+	subscriptions := make([]Subscription, 0, len(topics))
+	for topic, createdAt := range topics {
+		subscriptions = append(subscriptions, Subscription{Topic: topic, CreatedAt: createdAt})
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].Topic < subscriptions[j].Topic })
+	return subscriptions, nil
+}
+
+// Subscribe adds topics to a user's subscriptions; existing ones are kept
+func (r *MemoryRepository) Subscribe(ctx context.Context, tenantID, userID string, topics []string) error {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := connectionKey(tenantID, userID)
+	if r.subscriptions[key] == nil {
+		r.subscriptions[key] = make(map[string]time.Time)
+	}
+	for _, topic := range topics {
+		if _, ok := r.subscriptions[key][topic]; ok {
+			continue
+		}
+		r.subscriptions[key][topic] = now
+		topicKey := connectionKey(tenantID, topic)
+		if r.subscribers[topicKey] == nil {
+			r.subscribers[topicKey] = make(map[string]bool)
+		}
+		r.subscribers[topicKey][userID] = true
+	}
+	return nil
+}
+
+// Unsubscribe removes a topic from a user's subscriptions
+func (r *MemoryRepository) Unsubscribe(ctx context.Context, tenantID, userID, topic string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := connectionKey(tenantID, userID)
+	if _, ok := r.subscriptions[key][topic]; !ok {
+		return ErrNotFound
+	}
+	delete(r.subscriptions[key], topic)
+	if len(r.subscriptions[key]) == 0 {
+		delete(r.subscriptions, key)
+	}
+	topicKey := connectionKey(tenantID, topic)
+	delete(r.subscribers[topicKey], userID)
+	if len(r.subscribers[topicKey]) == 0 {
+		delete(r.subscribers, topicKey)
+	}
+	return nil
+}
+
+// TopicSubscribers returns the users of a tenant subscribed to a topic
+func (r *MemoryRepository) TopicSubscribers(ctx context.Context, tenantID, topic string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscribers := r.subscribers[connectionKey(tenantID, topic)]
+	users := make([]string, 0, len(subscribers))
+	for userID := range subscribers {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// ownedRecord finds a user's notification that isn't claimed, for archive
+// and delete. The caller holds r.mu.
+func (r *MemoryRepository) ownedRecord(tenantID, userID string, notificationID uuid.UUID) (*memoryRecord, error) {
+	rec, ok := r.records[notificationID]
+	if !ok || rec.notif.TenantID != models.TenantOrDefault(tenantID) || rec.notif.UserID != userID {
+		return nil, fmt.Errorf("notification %s: %w", notificationID, ErrNotFound)
+	}
+	if rec.status == "claimed" {
+		return nil, fmt.Errorf("notification %s is being delivered: %w", notificationID, ErrConflict)
+	}
+	return rec, nil
+}
+
+// GetStats retrieves notification statistics
+func (r *MemoryRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, rec := range r.records {
+		counts[rec.status]++
+	}
+	return statusStats(counts), nil
+}
+
+// GetExactStats is GetStats: the memory counts are always exact
+func (r *MemoryRepository) GetExactStats(ctx context.Context) (map[string]interface{}, error) {
+	return r.GetStats(ctx)
+}
+
+// GetStatsBreakdown counts the notifications created since the given time
+// by the chosen columns, with their delivery delay, in event type,
+// priority (HIGH first) and status order
+func (r *MemoryRepository) GetStatsBreakdown(ctx context.Context, since time.Time, group BreakdownGroup) ([]BreakdownRow, error) {
+	type groupKey struct{ eventType, priority, status string }
+	type groupTotals struct {
+		row      BreakdownRow
+		delays   int64 // On-time deliveries the delay sum covers
+		delaySum float64
+	}
+
+	r.mu.Lock()
+	groups := make(map[groupKey]*groupTotals)
+	for _, rec := range r.records {
+		if rec.notif.CreatedAt.Before(since) {
+			continue
+		}
+		var key groupKey
+		if group.EventType {
+			key.eventType = string(rec.notif.EventType)
+		}
+		if group.Priority {
+			key.priority = string(rec.notif.Priority)
+		}
+		if group.Status {
+			key.status = rec.status
+		}
+
+		totals := groups[key]
+		if totals == nil {
+			totals = &groupTotals{row: BreakdownRow{EventType: key.eventType, Priority: key.priority, Status: key.status}}
+			groups[key] = totals
+		}
+		totals.row.Count++
+		if rec.deliveredAt.IsZero() {
+			continue
+		}
+		totals.row.Delivered++
+		if rec.notif.IsLate {
+			continue
+		}
+		delay := rec.deliveredAt.Sub(rec.notif.EventTimestamp).Seconds()
+		totals.delays++
+		totals.delaySum += delay
+		if totals.row.MaxDelaySeconds == nil || delay > *totals.row.MaxDelaySeconds {
+			totals.row.MaxDelaySeconds = &delay
+		}
+	}
+	r.mu.Unlock()
+
+	breakdown := make([]BreakdownRow, 0, len(groups))
+	for _, totals := range groups {
+		if totals.delays > 0 {
+			avg := totals.delaySum / float64(totals.delays)
+			totals.row.AvgDelaySeconds = &avg
+		}
+		breakdown = append(breakdown, totals.row)
+	}
+
+	sort.Slice(breakdown, func(i, j int) bool {
+		a, b := breakdown[i], breakdown[j]
+		if a.EventType != b.EventType {
+			return a.EventType < b.EventType
+		}
+		if a.Priority != b.Priority {
+			if ra, rb := priorityRank(models.Priority(a.Priority)), priorityRank(models.Priority(b.Priority)); ra != rb {
+				return ra < rb
+			}
+			return a.Priority < b.Priority
+		}
+		return a.Status < b.Status
+	})
+	return breakdown, nil
+}
+
+// GetTenantStats returns status counts per tenant
+func (r *MemoryRepository) GetTenantStats(ctx context.Context) (map[string]interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]map[string]int64)
+	for _, rec := range r.records {
+		tenant := counts[rec.notif.TenantID]
+		if tenant == nil {
+			tenant = make(map[string]int64)
+			counts[rec.notif.TenantID] = tenant
+		}
+		tenant[rec.status]++
+		tenant["total"]++
+	}
+
+	result := make(map[string]interface{}, len(counts))
+	for tenantID, tenant := range counts {
+		result[tenantID] = map[string]interface{}{
+			"pending":   tenant["not_pushed"],
+			"delivered": tenant["pushed"],
+			"claimed":   tenant["claimed"],
+			"failed":    tenant["failed"],
+			"parked":    tenant["parked"],
+			"total":     tenant["total"],
+		}
+	}
+
+	return result, nil
+}
+
+// GetSLOAttainment returns per-priority delivery latency percentiles and the
+// share of notifications delivered within their SLO target since the given time
+func (r *MemoryRepository) GetSLOAttainment(ctx context.Context, since time.Time, targets SLOTargets) (map[string]interface{}, error) {
+	r.mu.Lock()
+	delays := make(map[string][]float64)
+	for _, rec := range r.records {
+		if rec.status == "pushed" && !rec.deliveredAt.Before(since) && !rec.notif.IsLate {
+			priority := string(rec.notif.Priority)
+			delays[priority] = append(delays[priority], rec.deliveredAt.Sub(rec.notif.EventTimestamp).Seconds())
+		}
+	}
+	r.mu.Unlock()
+
+	results := make(map[string]interface{})
+	for priority, values := range delays {
+		sort.Float64s(values)
+
+		target := targets.For(priority).Seconds()
+		var within int64
+		for _, v := range values {
+			if v <= target {
+				within++
+			}
+		}
+
+		results[priority] = map[string]interface{}{
+			"target_seconds":     target,
+			"delivered":          int64(len(values)),
+			"within_target":      within,
+			"attainment_percent": float64(within) / float64(len(values)) * 100,
+			"p50_seconds":        values[len(values)*50/100],
+			"p95_seconds":        values[len(values)*95/100],
+			"p99_seconds":        values[len(values)*99/100],
+		}
+	}
+
+	return results, nil
+}
+
+// GetDeliveryAttempts returns every recorded delivery attempt for a
+// notification, oldest first
+func (r *MemoryRepository) GetDeliveryAttempts(ctx context.Context, notificationID uuid.UUID) ([]map[string]interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[notificationID]
+	if !ok {
+		return nil, fmt.Errorf("notification %s: %w", notificationID, ErrNotFound)
+	}
+
+	attempts := make([]map[string]interface{}, 0, len(rec.attempts))
+	for _, a := range rec.attempts {
+		attempts = append(attempts, map[string]interface{}{
+			"attempted_at":  a.AttemptedAt,
+			"instance_id":   a.InstanceID,
+			"worker_id":     a.WorkerID,
+			"channel":       a.Channel,
+			"outcome":       a.Status,
+			"error_message": a.ErrorMsg,
+			"latency_ms":    float64(a.Latency.Microseconds()) / 1000,
+			"group_size":    a.GroupSize,
+		})
+	}
+	return attempts, nil
+}
+
+// ExistingEventIDs reports which of the given producer event IDs have been
+// persisted
+func (r *MemoryRepository) ExistingEventIDs(ctx context.Context, eventIDs []string) (map[string]bool, error) {
+	wanted := make(map[string]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		wanted[id] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := make(map[string]bool, len(eventIDs))
+	for _, rec := range r.records {
+		if id := rec.notif.EventID; id != "" && wanted[id] {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+// ExportNotifications returns up to limit notifications matching filter
+// with IDs after the given one, in ID order (byte order, as Postgres sorts
+// UUIDs)
+func (r *MemoryRepository) ExportNotifications(ctx context.Context, filter ExportFilter, after uuid.UUID, limit int) ([]*ExportedNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []*memoryRecord
+	for id, rec := range r.records {
+		n := &rec.notif
+		switch {
+		case bytes.Compare(id[:], after[:]) <= 0,
+			filter.TenantID != "" && n.TenantID != filter.TenantID,
+			filter.UserID != "" && n.UserID != filter.UserID,
+			filter.Status != "" && rec.status != filter.Status,
+			filter.EventType != "" && string(n.EventType) != filter.EventType,
+			filter.Priority != "" && string(n.Priority) != filter.Priority,
+			!filter.Since.IsZero() && n.CreatedAt.Before(filter.Since),
+			!filter.Until.IsZero() && !n.CreatedAt.Before(filter.Until):
+			continue
+		}
+		matched = append(matched, rec)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return bytes.Compare(matched[i].notif.NotificationID[:], matched[j].notif.NotificationID[:]) < 0
+	})
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	page := make([]*ExportedNotification, 0, len(matched))
+	for _, rec := range matched {
+		n := &ExportedNotification{
+			NotificationID: rec.notif.NotificationID,
+			EventID:        rec.notif.EventID,
+			TenantID:       rec.notif.TenantID,
+			UserID:         rec.notif.UserID,
+			EventType:      string(rec.notif.EventType),
+			Priority:       string(rec.notif.Priority),
+			Status:         rec.status,
+			Payload:        json.RawMessage(rec.payload),
+			RetryCount:     rec.notif.RetryCount,
+			ErrorMessage:   rec.errorMessage,
+			TraceID:        rec.notif.TraceID,
+			IsLate:         rec.notif.IsLate,
+			EventTimestamp: rec.notif.EventTimestamp,
+			ProducedAt:     copyTime(rec.notif.ProducedAt),
+			ReceivedAt:     rec.notif.NotificationReceivedTimestamp,
+			PersistedAt:    timeOrNil(rec.persistedAt),
+			ClaimedAt:      timeOrNil(rec.claimedAt),
+			DeliveredAt:    timeOrNil(rec.deliveredAt),
+			ExpiresAt:      copyTime(rec.notif.ExpiresAt),
+			CreatedAt:      rec.notif.CreatedAt,
+		}
+		if n.DeliveredAt != nil {
+			delay := n.DeliveredAt.Sub(n.EventTimestamp).Seconds()
+			n.DelaySeconds = &delay
+		}
+		page = append(page, n)
+	}
+	return page, nil
+}
+
+// timeOrNil is nil for the zero time, like a NULL column
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// copyTime copies t so the caller can't change the record through it
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// GetFlakyUsers returns the users with the most failed delivery attempts
+// since the given time
+func (r *MemoryRepository) GetFlakyUsers(ctx context.Context, since time.Time, limit int) ([]map[string]interface{}, error) {
+	type userAttempts struct {
+		tenantID, userID                  string
+		attempts, failures, notifications int64
+		lastFailure                       time.Time
+	}
+
+	r.mu.Lock()
+	byUser := make(map[string]*userAttempts)
+	for _, rec := range r.records {
+		counted := false
+		for _, a := range rec.attempts {
+			if a.AttemptedAt.Before(since) {
+				continue
+			}
+			tenantID := models.TenantOrDefault(rec.notif.TenantID)
+			key := connectionKey(tenantID, rec.notif.UserID)
+			u, ok := byUser[key]
+			if !ok {
+				u = &userAttempts{tenantID: tenantID, userID: rec.notif.UserID}
+				byUser[key] = u
+			}
+			u.attempts++
+			if !counted {
+				u.notifications++
+				counted = true
+			}
+			if a.Status == "failed" {
+				u.failures++
+				if a.AttemptedAt.After(u.lastFailure) {
+					u.lastFailure = a.AttemptedAt
+				}
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	flaky := make([]*userAttempts, 0, len(byUser))
+	for _, u := range byUser {
+		if u.failures > 0 {
+			flaky = append(flaky, u)
+		}
+	}
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].failures != flaky[j].failures {
+			return flaky[i].failures > flaky[j].failures
+		}
+		return flaky[i].attempts > flaky[j].attempts
+	})
+	if len(flaky) > limit {
+		flaky = flaky[:limit]
+	}
+
+	users := make([]map[string]interface{}, 0, len(flaky))
+	for _, u := range flaky {
+		users = append(users, map[string]interface{}{
+			"tenant_id":       u.tenantID,
+			"user_id":         u.userID,
+			"attempts":        u.attempts,
+			"failures":        u.failures,
+			"notifications":   u.notifications,
+			"failure_percent": float64(u.failures) / float64(u.attempts) * 100,
+			"last_failure":    u.lastFailure,
+		})
+	}
+	return users, nil
+}
+
+// Close is a no-op for the in-memory repository
+func (r *MemoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+// Flush is a no-op for the in-memory repository
+func (r *MemoryRepository) Flush(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// PayloadSchema is the subset of JSON Schema that fits notification
+// payloads, which are objects of string fields:
+//
+//	{
+//	  "type": "object",
+//	  "required": ["invoice_id", "amount"],
+//	  "properties": {
+//	    "invoice_id": {"type": "string", "pattern": "^INV-[0-9]+$"},
+//	    "amount":     {"type": "number"},
+//	    "currency":   {"enum": ["EUR", "USD"]}
+//	  },
+//	  "additionalProperties": false
+//	}
+//
+// A field's type says what its string must parse as. Keywords outside the
+// subset are rejected rather than ignored, so a schema never checks less
+// than it appears to.
+type PayloadSchema struct {
+	Schema               string                  `json:"$schema,omitempty"`
+	Title                string                  `json:"title,omitempty"`
+	Description          string                  `json:"description,omitempty"`
+	Type                 string                  `json:"type,omitempty"` // "object" when set
+	Required             []string                `json:"required,omitempty"`
+	Properties           map[string]*FieldSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                   `json:"additionalProperties,omitempty"` // false rejects fields not in properties
+	MaxProperties        *int                    `json:"maxProperties,omitempty"`
+}
+
+// FieldSchema constrains one payload field
+type FieldSchema struct {
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"` // string (default), integer, number or boolean
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"` // RE2, unanchored as in JSON Schema
+	MinLength   *int     `json:"minLength,omitempty"`
+	MaxLength   *int     `json:"maxLength,omitempty"`
+	Format      string   `json:"format,omitempty"` // date-time, uri, uuid or email
+
+	pattern *regexp.Regexp
+}
+
+// ParsePayloadSchema decodes and checks a schema
+func ParsePayloadSchema(raw json.RawMessage) (*PayloadSchema, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var s PayloadSchema
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid payload schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("invalid payload schema: %w", err)
+	}
+	return &s, nil
+}
+
+// compile checks the keywords and compiles the patterns
+func (s *PayloadSchema) compile() error {
+	if s.Type != "" && s.Type != "object" {
+		return fmt.Errorf("type %q: payloads are objects", s.Type)
+	}
+	if s.MaxProperties != nil && *s.MaxProperties < 0 {
+		return fmt.Errorf("maxProperties must not be negative")
+	}
+	for name, field := range s.Properties {
+		if field == nil {
+			return fmt.Errorf("property %q has no schema", name)
+		}
+		switch field.Type {
+		case "", "string", "integer", "number", "boolean":
+		default:
+			return fmt.Errorf("property %q: unsupported type %q (string, integer, number or boolean)", name, field.Type)
+		}
+		switch field.Format {
+		case "", "date-time", "uri", "uuid", "email":
+		default:
+			return fmt.Errorf("property %q: unsupported format %q (date-time, uri, uuid or email)", name, field.Format)
+		}
+		if (field.MinLength != nil && *field.MinLength < 0) || (field.MaxLength != nil && *field.MaxLength < 0) {
+			return fmt.Errorf("property %q: lengths must not be negative", name)
+		}
+		if field.Pattern != "" {
+			re, err := regexp.Compile(field.Pattern)
+			if err != nil {
+				return fmt.Errorf("property %q: %w", name, err)
+			}
+			field.pattern = re
+		}
+	}
+	return nil
+}
+
+// Validate returns the first way payload violates the schema, checking
+// fields in name order so the reason is stable
+func (s *PayloadSchema) Validate(payload map[string]string) error {
+	for _, name := range s.Required {
+		if _, ok := payload[name]; !ok {
+			return fmt.Errorf("missing required field %q", name)
+		}
+	}
+	if s.MaxProperties != nil && len(payload) > *s.MaxProperties {
+		return fmt.Errorf("%d fields exceed maxProperties %d", len(payload), *s.MaxProperties)
+	}
+
+	names := make([]string, 0, len(payload))
+	for name := range payload {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("unexpected field %q", name)
+			}
+			continue
+		}
+		if err := field.validate(payload[name]); err != nil {
+			return fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks one field's value
+func (f *FieldSchema) validate(value string) error {
+	switch f.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+	case "boolean":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	}
+
+	if len(f.Enum) > 0 && !slices.Contains(f.Enum, value) {
+		return fmt.Errorf("%q is not one of %q", value, f.Enum)
+	}
+
+	length := utf8.RuneCountInString(value)
+	if f.MinLength != nil && length < *f.MinLength {
+		return fmt.Errorf("shorter than minLength %d", *f.MinLength)
+	}
+	if f.MaxLength != nil && length > *f.MaxLength {
+		return fmt.Errorf("longer than maxLength %d", *f.MaxLength)
+	}
+	if f.pattern != nil && !f.pattern.MatchString(value) {
+		return fmt.Errorf("%q does not match %q", value, f.Pattern)
+	}
+
+	switch f.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%q is not an RFC 3339 date-time", value)
+		}
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return fmt.Errorf("%q is not an absolute URI", value)
+		}
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			return fmt.Errorf("%q is not a UUID", value)
+		}
+	case "email":
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("%q is not an email address", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// Reasons an event is poisoned, stamped in its HeaderPoisonReason
+const (
+	PoisonParseError         = "parse_error"        // Not decodable
+	PoisonUnsupportedSchema  = "unsupported_schema" // From a newer message schema than this build reads
+	PoisonInvalidUserID      = "invalid_user_id"
+	PoisonInvalidPayload     = "invalid_payload"     // Fails its event type's payload schema
+	PoisonInvalidAudience    = "invalid_audience"    // A broadcast addressed inconsistently, to invalid users or to too many
+	PoisonUnresolvedAudience = "unresolved_audience" // A broadcast to an unknown segment, or one the store couldn't resolve
+)
+
+// Headers stamped on poisoned events, next to the original ones
+const (
+	HeaderPoisonReason = "poison_reason"
+	HeaderPoisonError  = "poison_error" // The validation error, for a human
+)
+
+// SetPoisonWriter sets where events refused as invalid are forwarded,
+// raw and with their key and headers, so they can be inspected and
+// replayed instead of vanishing (see producer.NewDeadLetterWriter). Without
+// one they are dropped. Call before Consume.
+func (c *Consumer) SetPoisonWriter(w DeadLetterWriter) {
+	c.poisonWriter = w
+}
+
+// poison forwards an invalid event to the poison topic; every outcome
+// counts in notification_consumer_poison_messages_total{reason,outcome}
+func (c *Consumer) poison(ctx context.Context, msg kafka.Message, reason string, cause error) {
+	if c.poisonWriter == nil {
+		metrics.ConsumerPoisonMessages.WithLabelValues(reason, "dropped").Inc()
+		return
+	}
+
+	poisoned := kafka.Message{
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: append(append([]kafka.Header(nil), msg.Headers...),
+			kafka.Header{Key: HeaderPoisonReason, Value: []byte(reason)},
+			kafka.Header{Key: HeaderPoisonError, Value: []byte(cause.Error())}),
+	}
+	if err := c.poisonWriter.WriteMessages(ctx, poisoned); err != nil {
+		atomic.AddInt64(&c.poisonErrors, 1)
+		metrics.ConsumerPoisonMessages.WithLabelValues(reason, "error").Inc()
+		c.logger.Error("failed to write poison message",
+			zap.Error(err),
+			zap.String("reason", reason),
+			zap.ByteString("raw", msg.Value))
+		return
+	}
+	atomic.AddInt64(&c.poisoned, 1)
+	metrics.ConsumerPoisonMessages.WithLabelValues(reason, "written").Inc()
+}
@@ -0,0 +1,107 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Status counters. Statement-level triggers on notifications (see
+// scripts/postgres-schema.sql) append one row per changed status to
+// notification_status_counts, holding how much that statement moved the
+// status's count, in the statement's own transaction. The counts are
+// therefore exact as of any snapshot, and reading them sums a few rows
+// instead of scanning the table. Writers only ever insert, so concurrent
+// claims and status updates don't queue on a shared counter row; the
+// repository folds the rows back into one per status every
+// statusCountsCompactInterval.
+
+// statusCountsCompactInterval is how often the counter rows are folded
+const statusCountsCompactInterval = 10 * time.Second
+
+// statusCountTriggers are the triggers that keep the counters, checked by
+// VerifySchema
+var statusCountTriggers = []string{
+	"notifications_count_insert",
+	"notifications_count_update",
+	"notifications_count_delete",
+	"notifications_count_truncate",
+}
+
+// counterStats reads GetStats from the status counters
+func (r *PostgresRepository) counterStats(ctx context.Context, db *pgxpool.Pool) (map[string]interface{}, error) {
+	rows, err := db.Query(ctx, `
+		SELECT status, SUM(delta)::bigint
+		FROM notification_status_counts
+		GROUP BY status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var (
+			status string
+			count  int64
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return statusStats(counts), nil
+}
+
+// compactStatusCounts folds the counter rows every interval until Close
+func (r *PostgresRepository) compactStatusCounts() {
+	ticker := time.NewTicker(statusCountsCompactInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), statusCountsCompactInterval)
+		folded, err := r.foldStatusCounts(ctx)
+		cancel()
+		if err != nil {
+			r.logger.Warn("failed to compact status counters", zap.Error(err))
+			continue
+		}
+		r.logger.Debug("compacted status counters", zap.Int64("statuses", folded))
+	}
+}
+
+// foldStatusCounts replaces the counter rows with one per status in a
+// single statement, so the sums never change. Rows appended meanwhile are
+// left for the next pass, and a concurrent fold by another instance skips
+// the rows this one deleted.
+func (r *PostgresRepository) foldStatusCounts(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		WITH folded AS (
+			DELETE FROM notification_status_counts
+			RETURNING status, delta
+		)
+		INSERT INTO notification_status_counts (status, delta)
+		SELECT status, SUM(delta)
+		FROM folded
+		GROUP BY status
+		HAVING SUM(delta) <> 0
+	`)
+	if err != nil {
+		return 0, pgError(err)
+	}
+	return tag.RowsAffected(), nil
+}
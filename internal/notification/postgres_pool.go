@@ -0,0 +1,226 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// poolStatsInterval is how often the pool's stats are sampled into metrics
+const poolStatsInterval = 5 * time.Second
+
+// PostgresPoolConfig sizes the repository's connection pool and bounds its
+// statements; zero fields take the defaults
+type PostgresPoolConfig struct {
+	MaxConns        int           // Pool size (default 50)
+	MinConns        int           // Connections kept open while idle (default 0)
+	ConnMaxLifetime time.Duration // Connections are replaced after this long (default 5m)
+	ConnMaxIdleTime time.Duration // Idle connections above MinConns close after this long (default 1m)
+	QueryTimeout    time.Duration // How long a statement (or batch) may run once it has a connection, 0 = no limit
+	SlowQuery       time.Duration // Statements slower than this are logged, 0 = off
+
+	// Read replica DSNs (URL or key=value). Each gets a pool sized like the
+	// primary's; empty reads everything from the primary.
+	ReadReplicas []string
+}
+
+func (c PostgresPoolConfig) withDefaults() PostgresPoolConfig {
+	if c.MaxConns <= 0 {
+		c.MaxConns = 50
+	}
+	if c.ConnMaxLifetime <= 0 {
+		c.ConnMaxLifetime = 5 * time.Minute
+	}
+	if c.ConnMaxIdleTime <= 0 {
+		c.ConnMaxIdleTime = 1 * time.Minute
+	}
+	return c
+}
+
+// apply sets the pool sizing and installs the statement tracer
+func (c PostgresPoolConfig) apply(poolConfig *pgxpool.Config, logger *zap.Logger) {
+	poolConfig.MaxConns = int32(c.MaxConns)
+	poolConfig.MinConns = int32(min(c.MinConns, c.MaxConns))
+	poolConfig.MaxConnLifetime = c.ConnMaxLifetime
+	poolConfig.MaxConnIdleTime = c.ConnMaxIdleTime
+	if c.QueryTimeout > 0 || c.SlowQuery > 0 {
+		poolConfig.ConnConfig.Tracer = &queryTracer{
+			timeout: c.QueryTimeout,
+			slow:    c.SlowQuery,
+			logger:  logger,
+		}
+	}
+}
+
+// queryTracer bounds every statement by the query timeout and logs those
+// slower than the slow-query threshold. pgx runs a statement under the
+// context TraceQueryStart returns and calls TraceQueryEnd once it is done
+// (for queries, when their rows are closed), which releases the timeout.
+type queryTracer struct {
+	timeout time.Duration
+	slow    time.Duration
+	logger  *zap.Logger
+}
+
+type queryTraceKey struct{}
+
+// queryTrace is what a statement's context carries from start to end
+type queryTrace struct {
+	sql     string
+	started time.Time
+	cancel  context.CancelFunc
+}
+
+func (t *queryTracer) start(ctx context.Context, sql string) context.Context {
+	trace := &queryTrace{sql: sql, started: time.Now()}
+	if t.timeout > 0 {
+		ctx, trace.cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	return context.WithValue(ctx, queryTraceKey{}, trace)
+}
+
+func (t *queryTracer) end(ctx context.Context, rows int64, err error) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+	if trace.cancel != nil {
+		trace.cancel()
+	}
+
+	elapsed := time.Since(trace.started)
+	if t.slow <= 0 || elapsed < t.slow {
+		return
+	}
+	metrics.PostgresSlowQueries.Inc()
+	fields := []zap.Field{
+		zap.String("sql", compactSQL(trace.sql)),
+		zap.Duration("duration", elapsed),
+		zap.Int64("rows", rows),
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	t.logger.Warn("slow postgres query", fields...)
+}
+
+// TraceQueryStart starts a statement's timeout and clock
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, data.SQL)
+}
+
+// TraceQueryEnd releases the timeout and logs the statement if it was slow
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.end(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart times a pipelined batch as one statement, named by its
+// first query
+func (t *queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	sql := "batch"
+	if data.Batch != nil && data.Batch.Len() > 0 {
+		sql = fmt.Sprintf("batch of %d: %s", data.Batch.Len(), data.Batch.QueuedQueries[0].SQL)
+	}
+	return t.start(ctx, sql)
+}
+
+// TraceBatchQuery is called for each query of a batch; the batch is timed whole
+func (t *queryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+// TraceBatchEnd releases the batch's timeout and logs it if it was slow
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, 0, data.Err)
+}
+
+// compactSQL folds a statement onto one line for the log, cut at 300 bytes
+func compactSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > 300 {
+		sql = sql[:300] + "..."
+	}
+	return sql
+}
+
+// samplePoolStats publishes a pool's connection counts and acquire totals
+// under its name until Close
+func (r *PostgresRepository) samplePoolStats(name string, pool *pgxpool.Pool) {
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+
+	var lastAcquires, lastEmpty int64
+	var lastWait time.Duration
+	for {
+		stat := pool.Stat()
+		metrics.PostgresPoolConns.WithLabelValues(name, "acquired").Set(float64(stat.AcquiredConns()))
+		metrics.PostgresPoolConns.WithLabelValues(name, "idle").Set(float64(stat.IdleConns()))
+		metrics.PostgresPoolConns.WithLabelValues(name, "constructing").Set(float64(stat.ConstructingConns()))
+		metrics.PostgresPoolMaxConns.WithLabelValues(name).Set(float64(stat.MaxConns()))
+
+		// The pool keeps lifetime totals; the counters take what's new
+		metrics.PostgresPoolAcquires.WithLabelValues(name).Add(float64(stat.AcquireCount() - lastAcquires))
+		metrics.PostgresPoolEmptyAcquires.WithLabelValues(name).Add(float64(stat.EmptyAcquireCount() - lastEmpty))
+		metrics.PostgresPoolAcquireWait.WithLabelValues(name).Add((stat.AcquireDuration() - lastWait).Seconds())
+		lastAcquires, lastEmpty, lastWait = stat.AcquireCount(), stat.EmptyAcquireCount(), stat.AcquireDuration()
+
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// openPool opens and pings one pool
+func openPool(dsn string, cfg PostgresPoolConfig, logger *zap.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres config: %w", err)
+	}
+	cfg.apply(poolConfig, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres pool: %w", pgError(err))
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", pgError(err))
+	}
+	return pool, nil
+}
+
+// readReplica runs a read that may lag the primary on the next replica,
+// round-robin, falling back to the primary when the replica is
+// unavailable; without replicas it reads from the primary
+func readReplica[T any](ctx context.Context, r *PostgresRepository, read func(db *pgxpool.Pool) (T, error)) (T, error) {
+	if len(r.replicas) == 0 {
+		return read(r.pool)
+	}
+
+	i := r.nextReplica.Add(1) % uint64(len(r.replicas))
+	value, err := read(r.replicas[i])
+	switch {
+	case err == nil:
+		metrics.PostgresReplicaReads.WithLabelValues("ok").Inc()
+		return value, nil
+	case !errors.Is(err, ErrUnavailable) || ctx.Err() != nil:
+		metrics.PostgresReplicaReads.WithLabelValues("error").Inc()
+		return value, err
+	}
+
+	metrics.PostgresReplicaReads.WithLabelValues("fallback").Inc()
+	r.logger.Debug("read replica unavailable, reading from the primary",
+		zap.Uint64("replica", i), zap.Error(err))
+	return read(r.pool)
+}
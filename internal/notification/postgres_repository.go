@@ -4,55 +4,165 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq" // PostgreSQL driver (imported for side effects)
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
 	"notification-delivery-system/internal/models"
 )
 
-// PostgresRepository handles PostgreSQL operations with optimized batch inserts
+// PostgresRepository handles PostgreSQL operations with optimized batch
+// inserts. It talks to Postgres through a pgx pool: every connection caches
+// the statements it has prepared, so the claim and status queries are
+// parsed and planned once per connection, and batch inserts are pipelined
+// in one round trip. With read replicas configured, the API's reads
+// (GetUserNotifications, GetStats, ExportNotifications) go to them, so they
+// don't compete with the delivery pipeline for the primary.
 type PostgresRepository struct {
-	db     *sql.DB
-	logger *zap.Logger
+	pool        *pgxpool.Pool
+	replicas    []*pgxpool.Pool // Read replicas, empty when reads use the primary
+	nextReplica atomic.Uint64
+	logger      *zap.Logger
+	stop        chan struct{} // Closed by Close to stop the pool stats samplers and counter compaction
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
-func NewPostgresRepository(host string, port int, database, user, password string, logger *zap.Logger) (*PostgresRepository, error) {
+func NewPostgresRepository(host string, port int, database, user, password string, poolCfg PostgresPoolConfig, logger *zap.Logger) (*PostgresRepository, error) {
 	connStr := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable",
 		host, port, database, user, password)
 
-	db, err := sql.Open("postgres", connStr)
+	poolCfg = poolCfg.withDefaults()
+	pool, err := openPool(connStr, poolCfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open postgres connection: %w", err)
+		return nil, err
 	}
 
-	// Connection pool settings for high throughput
-	db.SetMaxOpenConns(50)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
-	db.SetConnMaxIdleTime(1 * time.Minute)
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	r := &PostgresRepository{
+		pool:   pool,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+	for i, dsn := range poolCfg.ReadReplicas {
+		replica, err := openPool(dsn, poolCfg, logger)
+		if err != nil {
+			r.closePools()
+			return nil, fmt.Errorf("read replica %d: %w", i, err)
+		}
+		r.replicas = append(r.replicas, replica)
 	}
 
 	logger.Info("postgres repository initialized",
 		zap.String("host", host),
 		zap.Int("port", port),
-		zap.String("database", database))
+		zap.String("database", database),
+		zap.Int("max_conns", poolCfg.MaxConns),
+		zap.Int("min_conns", poolCfg.MinConns),
+		zap.Duration("query_timeout", poolCfg.QueryTimeout),
+		zap.Duration("slow_query", poolCfg.SlowQuery),
+		zap.Int("read_replicas", len(r.replicas)))
 
-	return &PostgresRepository{
-		db:     db,
-		logger: logger,
-	}, nil
+	go r.samplePoolStats("primary", r.pool)
+	go r.compactStatusCounts()
+	for i, replica := range r.replicas {
+		go r.samplePoolStats(fmt.Sprintf("replica_%d", i), replica)
+	}
+	return r, nil
+}
+
+// closePools closes the primary and replica pools
+func (r *PostgresRepository) closePools() {
+	r.pool.Close()
+	for _, replica := range r.replicas {
+		replica.Close()
+	}
+}
+
+// requiredColumns are the notifications columns this code reads or writes
+var requiredColumns = []string{
+	"notification_id", "tenant_id", "user_id", "event_type", "priority", "payload",
+	"status", "event_timestamp", "notification_received_timestamp", "created_at",
+	"delivered_at", "retry_count", "error_message", "lease_timeout", "instance_id",
+	"expires_at", "trace_id", "is_late", "event_id", "produced_at", "persisted_at", "claimed_at",
+	"next_attempt_at", "topic",
+}
+
+// Ping checks the database connection
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return pgError(r.pool.Ping(ctx))
+}
+
+// VerifySchema checks that the notifications table exists with every column
+// the service needs, so a missing migration fails at startup instead of on
+// the first insert
+func (r *PostgresRepository) VerifySchema(ctx context.Context) error {
+	rows, err := r.pool.Query(ctx, `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'notifications'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read notifications schema: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	present := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return fmt.Errorf("failed to scan column: %w", pgError(err))
+		}
+		present[column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	if len(present) == 0 {
+		return fmt.Errorf("table notifications does not exist (apply scripts/postgres-schema.sql)")
+	}
+
+	var missing []string
+	for _, column := range requiredColumns {
+		if !present[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("table notifications is missing columns %v (apply scripts/postgres-schema.sql)", missing)
+	}
+
+	for _, table := range []string{"delivery_attempts", "user_preferences", "event_types", "segments", "subscriptions"} {
+		var regclass sql.NullString
+		if err := r.pool.QueryRow(ctx, `SELECT to_regclass($1)::text`, table).Scan(&regclass); err != nil {
+			return fmt.Errorf("failed to check %s table: %w", table, pgError(err))
+		}
+		if !regclass.Valid {
+			return fmt.Errorf("table %s does not exist (apply scripts/postgres-schema.sql)", table)
+		}
+	}
+
+	var counterTriggers int
+	if err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM pg_trigger
+		WHERE tgrelid = 'notifications'::regclass AND tgname = ANY($1)
+	`, statusCountTriggers).Scan(&counterTriggers); err != nil {
+		return fmt.Errorf("failed to check status counter triggers: %w", pgError(err))
+	}
+	if counterTriggers != len(statusCountTriggers) {
+		return fmt.Errorf("notifications status counter triggers %v are missing (apply scripts/postgres-schema.sql)", statusCountTriggers)
+	}
+
+	return nil
 }
 
 // Insert adds a notification (for compatibility, but prefer BatchInsert)
@@ -60,31 +170,30 @@ func (r *PostgresRepository) Insert(ctx context.Context, notification *models.No
 	return r.BatchInsert(ctx, []*models.Notification{notification})
 }
 
-// BatchInsert inserts multiple notifications using prepared statement for high performance
+// insertNotificationSQL inserts one notification; BatchInsert pipelines it
+const insertNotificationSQL = `
+	INSERT INTO notifications (
+		notification_id, user_id, event_type, priority, payload,
+		status, event_timestamp, notification_received_timestamp,
+		is_read, retry_count, created_at, expires_at, trace_id, tenant_id,
+		is_late, event_id, produced_at, persisted_at, topic
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16, ''), $17, clock_timestamp(), NULLIF($18, ''))
+`
+
+// BatchInsert inserts multiple notifications in one transaction, sending
+// every INSERT in a single pipelined batch (one round trip)
 func (r *PostgresRepository) BatchInsert(ctx context.Context, notifications []*models.Notification) error {
 	if len(notifications) == 0 {
 		return nil
 	}
 
-	// Use transaction with prepared statement for fast batch inserts
-	txn, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer txn.Rollback()
-
-	stmt, err := txn.PrepareContext(ctx, `
-		INSERT INTO notifications (
-			notification_id, user_id, event_type, priority, payload,
-			status, event_timestamp, notification_received_timestamp,
-			is_read, retry_count, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`)
+	txn, err := r.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", pgError(err))
 	}
-	defer stmt.Close()
+	defer txn.Rollback(ctx)
 
+	batch := &pgx.Batch{}
 	for _, notif := range notifications {
 		// Convert payload to JSONB
 		payloadJSON, err := json.Marshal(notif.Payload)
@@ -100,7 +209,7 @@ func (r *PostgresRepository) BatchInsert(ctx context.Context, notifications []*m
 			status = "not_pushed"
 		}
 
-		_, err = stmt.ExecContext(ctx,
+		batch.Queue(insertNotificationSQL,
 			notif.NotificationID,
 			notif.UserID,
 			string(notif.EventType),
@@ -112,14 +221,24 @@ func (r *PostgresRepository) BatchInsert(ctx context.Context, notifications []*m
 			notif.IsRead,
 			notif.RetryCount,
 			notif.CreatedAt,
+			notif.ExpiresAt,
+			notif.TraceID,
+			models.TenantOrDefault(notif.TenantID),
+			notif.IsLate,
+			notif.EventID,
+			notif.ProducedAt,
+			notif.Topic,
 		)
-		if err != nil {
-			return fmt.Errorf("failed to insert notification: %w", err)
-		}
 	}
 
-	if err := txn.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Close returns the first failed insert's error; the rest were skipped
+	// by the aborted transaction
+	if err := txn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert notification: %w", pgError(err))
+	}
+
+	if err := txn.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", pgError(err))
 	}
 
 	r.logger.Debug("batch inserted to postgres",
@@ -128,105 +247,351 @@ func (r *PostgresRepository) BatchInsert(ctx context.Context, notifications []*m
 	return nil
 }
 
+// priorityRankSQL maps priority to a sortable rank (HIGH first).
+// Must match the expression in the idx_pending_* indexes.
+const priorityRankSQL = `(CASE priority WHEN 'HIGH' THEN 0 WHEN 'MEDIUM' THEN 1 ELSE 2 END)`
+
+// claimOrderBy returns the ORDER BY clause for the given claim policy
+func claimOrderBy(policy ClaimPolicy) string {
+	switch policy {
+	case ClaimPolicyDeadline:
+		// Earliest deadline first, priority breaks ties
+		return "expires_at ASC NULLS LAST, " + priorityRankSQL + ", created_at ASC"
+	default:
+		// Priority first, earliest deadline first within a priority
+		return priorityRankSQL + ", expires_at ASC NULLS LAST, created_at ASC"
+	}
+}
+
 // ClaimBatch claims a batch of notifications for processing
 // Uses FOR UPDATE SKIP LOCKED for high concurrency without blocking
-func (r *PostgresRepository) ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+func (r *PostgresRepository) ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	query := `
+		UPDATE notifications
+		SET status = 'claimed',
+		    instance_id = $1,
+		    lease_timeout = $2,
+		    claimed_at = clock_timestamp()
+		FROM (
+			SELECT notification_id
+			FROM notifications
+			WHERE status = 'not_pushed'
+			AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			ORDER BY ` + claimOrderBy(policy) + `
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) AS batch
+		WHERE notifications.notification_id = batch.notification_id
+		RETURNING 
+			notifications.notification_id,
+			notifications.tenant_id,
+			notifications.user_id,
+			notifications.event_type,
+			notifications.priority,
+			notifications.event_timestamp,
+			notifications.notification_received_timestamp,
+			notifications.payload::text,
+			COALESCE(notifications.trace_id, ''),
+			notifications.retry_count,
+			notifications.produced_at,
+			notifications.persisted_at,
+			notifications.claimed_at,
+			notifications.expires_at,
+			COALESCE(notifications.topic, '')
+	`
+
+	leaseTimeout := time.Now().Add(leaseDuration)
+	rows, err := r.pool.Query(ctx, query, instanceID, leaseTimeout, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim batch: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	return scanClaimed(rows)
+}
+
+// ClaimForUsers claims like ClaimBatch but only notifications of the given
+// users, so an instance leaves offline users' work pending instead of
+// claiming and parking it
+func (r *PostgresRepository) ClaimForUsers(ctx context.Context, instanceID string, users []UserRef, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+	tenantIDs := make([]string, len(users))
+	userIDs := make([]string, len(users))
+	for i, u := range users {
+		tenantIDs[i] = models.TenantOrDefault(u.TenantID)
+		userIDs[i] = u.UserID
+	}
+
 	query := `
 		UPDATE notifications
 		SET status = 'claimed',
 		    instance_id = $1,
-		    lease_timeout = $2
+		    lease_timeout = $2,
+		    claimed_at = clock_timestamp()
 		FROM (
 			SELECT notification_id
 			FROM notifications
 			WHERE status = 'not_pushed'
-			ORDER BY priority DESC, created_at ASC
+			AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			AND (tenant_id, user_id) IN (SELECT * FROM unnest($4::text[], $5::text[]))
+			ORDER BY ` + claimOrderBy(policy) + `
 			LIMIT $3
 			FOR UPDATE SKIP LOCKED
 		) AS batch
 		WHERE notifications.notification_id = batch.notification_id
 		RETURNING 
 			notifications.notification_id,
+			notifications.tenant_id,
+			notifications.user_id,
+			notifications.event_type,
+			notifications.priority,
+			notifications.event_timestamp,
+			notifications.notification_received_timestamp,
+			notifications.payload::text,
+			COALESCE(notifications.trace_id, ''),
+			notifications.retry_count,
+			notifications.produced_at,
+			notifications.persisted_at,
+			notifications.claimed_at,
+			notifications.expires_at,
+			COALESCE(notifications.topic, '')
+	`
+
+	leaseTimeout := time.Now().Add(leaseDuration)
+	rows, err := r.pool.Query(ctx, query, instanceID, leaseTimeout, batchSize, tenantIDs, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim batch for connected users: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	return scanClaimed(rows)
+}
+
+// ClaimWeighted claims up to quotas[p] pending notifications of each
+// priority in one statement, earliest deadline first within a priority. Each
+// priority is locked in its own CTE since FOR UPDATE can't take a UNION.
+func (r *PostgresRepository) ClaimWeighted(ctx context.Context, instanceID string, quotas map[models.Priority]int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	pending := func(rank, limit string) string {
+		return `
+			SELECT notification_id
+			FROM notifications
+			WHERE status = 'not_pushed'
+			AND ` + priorityRankSQL + ` = ` + rank + `
+			AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			ORDER BY ` + claimOrderBy(ClaimPolicyPriority) + `
+			LIMIT ` + limit + `
+			FOR UPDATE SKIP LOCKED`
+	}
+	query := `
+		WITH high AS (` + pending("0", "$3") + `
+		), medium AS (` + pending("1", "$4") + `
+		), low AS (` + pending("2", "$5") + `
+		)
+		UPDATE notifications
+		SET status = 'claimed',
+		    instance_id = $1,
+		    lease_timeout = $2,
+		    claimed_at = clock_timestamp()
+		FROM (
+			SELECT notification_id FROM high
+			UNION ALL SELECT notification_id FROM medium
+			UNION ALL SELECT notification_id FROM low
+		) AS batch
+		WHERE notifications.notification_id = batch.notification_id
+		RETURNING 
+			notifications.notification_id,
+			notifications.tenant_id,
+			notifications.user_id,
+			notifications.event_type,
+			notifications.priority,
+			notifications.event_timestamp,
+			notifications.notification_received_timestamp,
+			notifications.payload::text,
+			COALESCE(notifications.trace_id, ''),
+			notifications.retry_count,
+			notifications.produced_at,
+			notifications.persisted_at,
+			notifications.claimed_at,
+			notifications.expires_at,
+			COALESCE(notifications.topic, '')
+	`
+
+	leaseTimeout := time.Now().Add(leaseDuration)
+	rows, err := r.pool.Query(ctx, query, instanceID, leaseTimeout,
+		quotas[models.PriorityHigh], quotas[models.PriorityMedium], quotas[models.PriorityLow])
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim weighted batch: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	return scanClaimed(rows)
+}
+
+// ClaimParked un-parks up to batchSize of a user's parked notifications by
+// claiming them for instanceID, highest priority first
+func (r *PostgresRepository) ClaimParked(ctx context.Context, tenantID, userID, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error) {
+	query := `
+		UPDATE notifications
+		SET status = 'claimed',
+		    instance_id = $1,
+		    lease_timeout = $2,
+		    claimed_at = clock_timestamp()
+		FROM (
+			SELECT notification_id
+			FROM notifications
+			WHERE tenant_id = $3 AND user_id = $4 AND status = 'parked'
+			ORDER BY ` + claimOrderBy(ClaimPolicyPriority) + `
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		) AS batch
+		WHERE notifications.notification_id = batch.notification_id
+		RETURNING 
+			notifications.notification_id,
+			notifications.tenant_id,
 			notifications.user_id,
 			notifications.event_type,
 			notifications.priority,
 			notifications.event_timestamp,
 			notifications.notification_received_timestamp,
-			notifications.payload::text
+			notifications.payload::text,
+			COALESCE(notifications.trace_id, ''),
+			notifications.retry_count,
+			notifications.produced_at,
+			notifications.persisted_at,
+			notifications.claimed_at,
+			notifications.expires_at,
+			COALESCE(notifications.topic, '')
 	`
 
 	leaseTimeout := time.Now().Add(leaseDuration)
-	rows, err := r.db.QueryContext(ctx, query, instanceID, leaseTimeout, batchSize)
+	rows, err := r.pool.Query(ctx, query, instanceID, leaseTimeout, models.TenantOrDefault(tenantID), userID, batchSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to claim batch: %w", err)
+		return nil, fmt.Errorf("failed to claim parked notifications: %w", pgError(err))
 	}
 	defer rows.Close()
 
+	return scanClaimed(rows)
+}
+
+// scanClaimed reads the RETURNING rows of a claim query
+func scanClaimed(rows pgx.Rows) ([]*NotificationBatch, error) {
 	var batch []*NotificationBatch
 	for rows.Next() {
 		var nb NotificationBatch
 		var payloadStr string
+		var producedAt, persistedAt, claimedAt, expiresAt sql.NullTime
 
 		if err := rows.Scan(
 			&nb.NotificationID,
+			&nb.TenantID,
 			&nb.UserID,
 			&nb.EventType,
 			&nb.Priority,
 			&nb.EventTimestamp,
 			&nb.NotificationReceivedTimestamp,
 			&payloadStr,
+			&nb.TraceID,
+			&nb.RetryCount,
+			&producedAt,
+			&persistedAt,
+			&claimedAt,
+			&expiresAt,
+			&nb.Topic,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
 		}
 
 		nb.Payload = payloadStr
+		nb.ProducedAt = producedAt.Time
+		nb.PersistedAt = persistedAt.Time
+		nb.ClaimedAt = claimedAt.Time
+		nb.ExpiresAt = expiresAt.Time
 		batch = append(batch, &nb)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
 	}
 
 	return batch, nil
 }
 
-// BatchUpdateStatus updates the status of multiple notifications
+// BatchUpdateStatus updates the status of multiple notifications in one
+// UPDATE joined against the unnested updates, and records their delivery
+// attempts in one INSERT, so a flush costs three round trips (with the row
+// locks) however large it is. Statuses travel per row, so a flush mixing pushed, failed and parked
+// notifications is still one statement. When a notification appears more
+// than once the last update wins; each of its attempts is recorded.
 func (r *PostgresRepository) BatchUpdateStatus(ctx context.Context, updates []*StatusUpdate) error {
 	if len(updates) == 0 {
 		return nil
 	}
 
-	txn, err := r.db.BeginTx(ctx, nil)
+	// UPDATE ... FROM takes an arbitrary source row per target, so keep the
+	// last update per notification
+	latest := make(map[uuid.UUID]*StatusUpdate, len(updates))
+	for _, update := range updates {
+		latest[update.NotificationID] = update
+	}
+	ids := make([]uuid.UUID, 0, len(latest))
+	for id := range latest {
+		ids = append(ids, id)
+	}
+
+	var (
+		statuses      = make([]string, len(ids))
+		errorMessages = make([]string, len(ids))
+		nextAttempts  = make([]*time.Time, len(ids))
+	)
+	for i, id := range ids {
+		update := latest[id]
+		statuses[i] = update.Status
+		errorMessages[i] = update.ErrorMsg
+		if !update.NextAttemptAt.IsZero() {
+			nextAttempts[i] = &update.NextAttemptAt
+		}
+	}
+
+	txn, err := r.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", pgError(err))
 	}
-	defer txn.Rollback()
+	defer txn.Rollback(ctx)
 
-	stmt, err := txn.PrepareContext(ctx, `
-		UPDATE notifications
-		SET status = $1,
-		    delivered_at = CASE WHEN $1 = 'pushed' THEN NOW() ELSE delivered_at END,
-		    error_message = $2,
+	// The UPDATE locks rows in whatever order its join visits them, so take
+	// the locks in ID order first; concurrent flushes then can't deadlock
+	if _, err := txn.Exec(ctx, `
+		SELECT 1 FROM notifications
+		WHERE notification_id = ANY($1)
+		ORDER BY notification_id
+		FOR UPDATE
+	`, ids); err != nil {
+		return fmt.Errorf("failed to lock notifications: %w", pgError(err))
+	}
+
+	if _, err := txn.Exec(ctx, `
+		UPDATE notifications n
+		SET status = u.status,
+		    delivered_at = CASE WHEN u.status = 'pushed' THEN NOW() ELSE n.delivered_at END,
+		    error_message = u.error_message,
 		    instance_id = NULL,
-		    lease_timeout = NULL
-		WHERE notification_id = $3
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare update statement: %w", err)
+		    lease_timeout = NULL,
+		    next_attempt_at = u.next_attempt_at
+		FROM unnest($1::uuid[], $2::text[], $3::text[], $4::timestamptz[])
+		     AS u(notification_id, status, error_message, next_attempt_at)
+		WHERE n.notification_id = u.notification_id
+	`, ids, statuses, errorMessages, nextAttempts); err != nil {
+		return fmt.Errorf("failed to update statuses: %w", pgError(err))
 	}
-	defer stmt.Close()
 
-	for _, update := range updates {
-		if _, err := stmt.ExecContext(ctx, update.Status, update.ErrorMsg, update.NotificationID); err != nil {
-			r.logger.Warn("failed to update notification status",
-				zap.Error(err),
-				zap.String("notification_id", update.NotificationID.String()))
-			// Continue with other updates
-		}
+	if err := r.insertAttempts(ctx, txn, updates); err != nil {
+		return err
 	}
 
-	if err := txn.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if err := txn.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", pgError(err))
 	}
 
 	r.logger.Debug("batch updated status",
@@ -235,9 +600,61 @@ func (r *PostgresRepository) BatchUpdateStatus(ctx context.Context, updates []*S
 	return nil
 }
 
+// insertAttempts records the delivery attempts among updates in one INSERT;
+// updates without attempt details (e.g. admin transitions) aren't attempts
+func (r *PostgresRepository) insertAttempts(ctx context.Context, txn pgx.Tx, updates []*StatusUpdate) error {
+	var (
+		notificationIDs                                []uuid.UUID
+		tenantIDs, userIDs                             []string
+		instanceIDs, channels, outcomes, errorMessages []string
+		attemptedAt                                    []time.Time
+		workerIDs, groupSizes                          []int64
+		latencies                                      []float64
+	)
+	for _, update := range updates {
+		if update.AttemptedAt.IsZero() {
+			continue
+		}
+		notificationIDs = append(notificationIDs, update.NotificationID)
+		tenantIDs = append(tenantIDs, models.TenantOrDefault(update.TenantID))
+		userIDs = append(userIDs, update.UserID)
+		attemptedAt = append(attemptedAt, update.AttemptedAt)
+		instanceIDs = append(instanceIDs, update.InstanceID)
+		workerIDs = append(workerIDs, int64(update.WorkerID))
+		channels = append(channels, update.Channel)
+		outcomes = append(outcomes, update.Status)
+		errorMessages = append(errorMessages, update.ErrorMsg)
+		latencies = append(latencies, float64(update.Latency.Microseconds())/1000)
+		groupSizes = append(groupSizes, int64(update.GroupSize))
+	}
+	if len(notificationIDs) == 0 {
+		return nil
+	}
+
+	if _, err := txn.Exec(ctx, `
+		INSERT INTO delivery_attempts (
+			notification_id, tenant_id, user_id, attempted_at, instance_id,
+			worker_id, channel, outcome, error_message, latency_ms, group_size
+		)
+		SELECT notification_id, tenant_id, user_id, attempted_at, instance_id,
+		       worker_id, channel, outcome, NULLIF(error_message, ''), latency_ms, group_size
+		FROM unnest($1::uuid[], $2::text[], $3::text[], $4::timestamptz[], $5::text[],
+		            $6::int[], $7::text[], $8::text[], $9::text[], $10::float8[], $11::int[])
+		     AS a(notification_id, tenant_id, user_id, attempted_at, instance_id,
+		          worker_id, channel, outcome, error_message, latency_ms, group_size)
+	`,
+		notificationIDs, tenantIDs, userIDs, attemptedAt,
+		instanceIDs, workerIDs, channels, outcomes,
+		errorMessages, latencies, groupSizes,
+	); err != nil {
+		return fmt.Errorf("failed to record delivery attempts: %w", pgError(err))
+	}
+	return nil
+}
+
 // ReclaimStaleTasks reclaims notifications with expired leases
 func (r *PostgresRepository) ReclaimStaleTasks(ctx context.Context) (int, error) {
-	result, err := r.db.ExecContext(ctx, `
+	result, err := r.pool.Exec(ctx, `
 		UPDATE notifications
 		SET status = 'not_pushed',
 		    instance_id = NULL,
@@ -247,10 +664,10 @@ func (r *PostgresRepository) ReclaimStaleTasks(ctx context.Context) (int, error)
 		AND lease_timeout < NOW()
 	`)
 	if err != nil {
-		return 0, fmt.Errorf("failed to reclaim stale tasks: %w", err)
+		return 0, fmt.Errorf("failed to reclaim stale tasks: %w", pgError(err))
 	}
 
-	count, _ := result.RowsAffected()
+	count := result.RowsAffected()
 	if count > 0 {
 		r.logger.Info("reclaimed stale tasks", zap.Int64("count", count))
 	}
@@ -258,28 +675,144 @@ func (r *PostgresRepository) ReclaimStaleTasks(ctx context.Context) (int, error)
 	return int(count), nil
 }
 
-// GetUserNotifications retrieves recent notifications for a user
-func (r *PostgresRepository) GetUserNotifications(ctx context.Context, userID string, limit int) ([]map[string]interface{}, error) {
+// RequeueFailed moves up to limit failed notifications (oldest first) back to
+// not_pushed so the task picker retries them
+func (r *PostgresRepository) RequeueFailed(ctx context.Context, limit int) (int, error) {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE notifications
+		SET status = 'not_pushed',
+		    error_message = NULL,
+		    retry_count = retry_count + 1
+		WHERE notification_id IN (
+			SELECT notification_id
+			FROM notifications
+			WHERE status = 'failed'
+			ORDER BY created_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue failed notifications: %w", pgError(err))
+	}
+
+	count := result.RowsAffected()
+	if count > 0 {
+		r.logger.Info("requeued failed notifications", zap.Int64("count", count))
+	}
+
+	return int(count), nil
+}
+
+// ExpireStale marks up to limit pending or parked notifications expired when
+// they are past their deadline or, with maxAge > 0, older than maxAge, and
+// returns the counts by event type, priority and reason
+func (r *PostgresRepository) ExpireStale(ctx context.Context, now time.Time, maxAge time.Duration, limit int) ([]ExpiredCount, error) {
+	// A zero cutoff matches nothing when max age is disabled
+	var ageCutoff time.Time
+	if maxAge > 0 {
+		ageCutoff = now.Add(-maxAge)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		WITH expired AS (
+			UPDATE notifications
+			SET status = 'expired',
+			    error_message = CASE WHEN expires_at < $1 THEN 'deadline' ELSE 'age' END,
+			    instance_id = NULL,
+			    lease_timeout = NULL
+			WHERE notification_id IN (
+				SELECT notification_id
+				FROM notifications
+				WHERE status IN ('not_pushed', 'parked')
+				AND (expires_at < $1 OR created_at < $2)
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING event_type, priority, error_message
+		)
+		SELECT event_type, priority, error_message, COUNT(*)
+		FROM expired
+		GROUP BY event_type, priority, error_message
+	`, now, ageCutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire notifications: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var counts []ExpiredCount
+	for rows.Next() {
+		var c ExpiredCount
+		if err := rows.Scan(&c.EventType, &c.Priority, &c.Reason, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return counts, nil
+}
+
+// Reprioritize moves up to limit pending or parked notifications of
+// priority from, persisted before createdBefore, to priority to
+func (r *PostgresRepository) Reprioritize(ctx context.Context, from, to models.Priority, createdBefore time.Time, limit int) (int, error) {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE notifications
+		SET priority = $2
+		WHERE notification_id IN (
+			SELECT notification_id
+			FROM notifications
+			WHERE status IN ('not_pushed', 'parked')
+			AND priority = $1
+			AND created_at < $3
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+	`, string(from), string(to), createdBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reprioritize notifications: %w", pgError(err))
+	}
+
+	count := result.RowsAffected()
+	return int(count), nil
+}
+
+// GetUserNotifications retrieves recent notifications for a user within a
+// tenant, leaving out archived ones unless includeArchived, from a read
+// replica when there are any
+func (r *PostgresRepository) GetUserNotifications(ctx context.Context, tenantID, userID string, limit int, includeArchived bool) ([]map[string]interface{}, error) {
+	return readReplica(ctx, r, func(db *pgxpool.Pool) ([]map[string]interface{}, error) {
+		return r.userNotifications(ctx, db, tenantID, userID, limit, includeArchived)
+	})
+}
+
+func (r *PostgresRepository) userNotifications(ctx context.Context, db *pgxpool.Pool, tenantID, userID string, limit int, includeArchived bool) ([]map[string]interface{}, error) {
 	query := `
 		SELECT 
 			notification_id,
+			tenant_id,
 			user_id,
 			event_type,
 			priority,
 			status,
 			event_timestamp,
 			notification_received_timestamp,
+			is_read,
 			delivered_at,
 			EXTRACT(EPOCH FROM (delivered_at - event_timestamp)) as delay_seconds
 		FROM notifications
-		WHERE user_id = $1
+		WHERE tenant_id = $1 AND user_id = $2
+		AND ($4 OR status <> 'archived')
 		ORDER BY event_timestamp DESC
-		LIMIT $2
+		LIMIT $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := db.Query(ctx, query, models.TenantOrDefault(tenantID), userID, limit, includeArchived)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query notifications: %w", err)
+		return nil, fmt.Errorf("failed to query notifications: %w", pgError(err))
 	}
 	defer rows.Close()
 
@@ -287,38 +820,44 @@ func (r *PostgresRepository) GetUserNotifications(ctx context.Context, userID st
 	for rows.Next() {
 		var (
 			notificationID                uuid.UUID
+			tenantIDVal                   string
 			userIDVal                     string
 			eventType                     string
 			priority                      string
 			status                        string
 			eventTimestamp                time.Time
 			notificationReceivedTimestamp time.Time
+			isRead                        bool
 			deliveredAt                   sql.NullTime
 			delaySeconds                  sql.NullFloat64
 		)
 
 		if err := rows.Scan(
 			&notificationID,
+			&tenantIDVal,
 			&userIDVal,
 			&eventType,
 			&priority,
 			&status,
 			&eventTimestamp,
 			&notificationReceivedTimestamp,
+			&isRead,
 			&deliveredAt,
 			&delaySeconds,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
 		}
 
 		result := map[string]interface{}{
 			"notification_id":                 notificationID.String(),
+			"tenant_id":                       tenantIDVal,
 			"user_id":                         userIDVal,
 			"event_type":                      eventType,
 			"priority":                        priority,
 			"status":                          status,
 			"event_timestamp":                 eventTimestamp,
 			"notification_received_timestamp": notificationReceivedTimestamp,
+			"is_read":                         isRead,
 		}
 
 		if deliveredAt.Valid {
@@ -334,51 +873,897 @@ func (r *PostgresRepository) GetUserNotifications(ctx context.Context, userID st
 	return results, nil
 }
 
-// GetStats retrieves notification statistics
-func (r *PostgresRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
-	query := `
+// GetTenantStats returns status counts per tenant
+func (r *PostgresRepository) GetTenantStats(ctx context.Context) (map[string]interface{}, error) {
+	rows, err := r.pool.Query(ctx, `
 		SELECT
+			tenant_id,
 			COUNT(*) FILTER (WHERE status = 'not_pushed') as pending,
 			COUNT(*) FILTER (WHERE status = 'pushed') as delivered,
 			COUNT(*) FILTER (WHERE status = 'claimed') as claimed,
 			COUNT(*) FILTER (WHERE status = 'failed') as failed,
+			COUNT(*) FILTER (WHERE status = 'parked') as parked,
 			COUNT(*) as total
 		FROM notifications
-	`
+		GROUP BY tenant_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant stats: %w", pgError(err))
+	}
+	defer rows.Close()
 
-	var stats struct {
-		Pending   int64
-		Delivered int64
-		Claimed   int64
-		Failed    int64
-		Total     int64
+	result := make(map[string]interface{})
+	for rows.Next() {
+		var (
+			tenantID                                           string
+			pending, delivered, claimed, failed, parked, total int64
+		)
+		if err := rows.Scan(&tenantID, &pending, &delivered, &claimed, &failed, &parked, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant stats: %w", pgError(err))
+		}
+		result[tenantID] = map[string]interface{}{
+			"pending":   pending,
+			"delivered": delivered,
+			"claimed":   claimed,
+			"failed":    failed,
+			"parked":    parked,
+			"total":     total,
+		}
 	}
 
-	if err := r.db.QueryRowContext(ctx, query).Scan(
-		&stats.Pending,
-		&stats.Delivered,
-		&stats.Claimed,
-		&stats.Failed,
-		&stats.Total,
-	); err != nil {
-		return nil, fmt.Errorf("failed to get stats: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
 	}
 
-	return map[string]interface{}{
-		"pending":   stats.Pending,
-		"delivered": stats.Delivered,
-		"claimed":   stats.Claimed,
-		"failed":    stats.Failed,
-		"total":     stats.Total,
-	}, nil
+	return result, nil
 }
 
-// Close closes the database connection
-func (r *PostgresRepository) Close(ctx context.Context) error {
-	return r.db.Close()
+// GetUnreadCounts counts a user's unread notifications by event type, from
+// a read replica when there are any. Expired notifications never reached
+// the user and archived ones were put away by them, so neither is counted.
+func (r *PostgresRepository) GetUnreadCounts(ctx context.Context, tenantID, userID string) (map[string]int64, error) {
+	return readReplica(ctx, r, func(db *pgxpool.Pool) (map[string]int64, error) {
+		return r.unreadCounts(ctx, db, tenantID, userID)
+	})
 }
 
-// Flush is a no-op for PostgreSQL (kept for interface compatibility)
-func (r *PostgresRepository) Flush(ctx context.Context) error {
-	return nil
+func (r *PostgresRepository) unreadCounts(ctx context.Context, db *pgxpool.Pool, tenantID, userID string) (map[string]int64, error) {
+	// Served by idx_tenant_user_created
+	rows, err := db.Query(ctx, `
+		SELECT event_type, COUNT(*)
+		FROM notifications
+		WHERE tenant_id = $1 AND user_id = $2
+		AND NOT is_read
+		AND status NOT IN ('expired', 'archived')
+		GROUP BY event_type
+	`, models.TenantOrDefault(tenantID), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var (
+			eventType string
+			count     int64
+		)
+		if err := rows.Scan(&eventType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+		counts[eventType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return counts, nil
+}
+
+// MarkRead marks the given notifications of a user read, or all of them
+// when notificationIDs is empty, and returns how many were unread
+func (r *PostgresRepository) MarkRead(ctx context.Context, tenantID, userID string, notificationIDs []uuid.UUID) (int, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE notifications
+		SET is_read = TRUE
+		WHERE tenant_id = $1 AND user_id = $2
+		AND NOT is_read
+		AND (COALESCE(cardinality($3::uuid[]), 0) = 0 OR notification_id = ANY($3))
+	`, models.TenantOrDefault(tenantID), userID, notificationIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", pgError(err))
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ArchiveNotification moves a user's notification to the archived status,
+// which keeps it out of listings and unread counts but on record. It is
+// ErrNotFound when the user has no such notification and ErrConflict while
+// a picker holds it claimed, since the delivery outcome would overwrite the
+// status; archiving twice is not an error.
+func (r *PostgresRepository) ArchiveNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE notifications
+		SET status = 'archived',
+		    instance_id = NULL,
+		    lease_timeout = NULL,
+		    next_attempt_at = NULL
+		WHERE notification_id = $1 AND tenant_id = $2 AND user_id = $3
+		AND status <> 'claimed'
+	`, notificationID, models.TenantOrDefault(tenantID), userID)
+	if err != nil {
+		return fmt.Errorf("failed to archive notification: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return r.lifecycleMiss(ctx, tenantID, userID, notificationID)
+	}
+	return nil
+}
+
+// DeleteNotification removes a user's notification and its delivery
+// attempts for good. Like ArchiveNotification it is ErrNotFound for
+// another user's or a missing notification and ErrConflict while claimed.
+func (r *PostgresRepository) DeleteNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	txn, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", pgError(err))
+	}
+	defer txn.Rollback(ctx)
+
+	tag, err := txn.Exec(ctx, `
+		DELETE FROM notifications
+		WHERE notification_id = $1 AND tenant_id = $2 AND user_id = $3
+		AND status <> 'claimed'
+	`, notificationID, models.TenantOrDefault(tenantID), userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return r.lifecycleMiss(ctx, tenantID, userID, notificationID)
+	}
+
+	if _, err := txn.Exec(ctx, `
+		DELETE FROM delivery_attempts WHERE notification_id = $1
+	`, notificationID); err != nil {
+		return fmt.Errorf("failed to delete delivery attempts: %w", pgError(err))
+	}
+
+	if err := txn.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", pgError(err))
+	}
+	return nil
+}
+
+// GetUserPreferences returns a user's preferences; a user who never set
+// any gets the zero value
+func (r *PostgresRepository) GetUserPreferences(ctx context.Context, tenantID, userID string) (UserPreferences, error) {
+	var prefs UserPreferences
+	err := r.pool.QueryRow(ctx, `
+		SELECT locale, updated_at
+		FROM user_preferences
+		WHERE tenant_id = $1 AND user_id = $2
+	`, models.TenantOrDefault(tenantID), userID).Scan(&prefs.Locale, &prefs.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return UserPreferences{}, nil
+	}
+	if err != nil {
+		return UserPreferences{}, fmt.Errorf("failed to get user preferences: %w", pgError(err))
+	}
+	return prefs, nil
+}
+
+// SetUserPreferences replaces a user's preferences and returns them as stored
+func (r *PostgresRepository) SetUserPreferences(ctx context.Context, tenantID, userID string, prefs UserPreferences) (UserPreferences, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO user_preferences (tenant_id, user_id, locale, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET locale = EXCLUDED.locale, updated_at = EXCLUDED.updated_at
+		RETURNING locale, updated_at
+	`, models.TenantOrDefault(tenantID), userID, prefs.Locale).Scan(&prefs.Locale, &prefs.UpdatedAt)
+	if err != nil {
+		return UserPreferences{}, fmt.Errorf("failed to set user preferences: %w", pgError(err))
+	}
+	return prefs, nil
+}
+
+// ListEventTypes returns the registered event types
+func (r *PostgresRepository) ListEventTypes(ctx context.Context) ([]EventTypeConfig, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT event_type, priority, title, message, locales, digestible, channels, payload_schema, ttl, updated_at
+		FROM event_types
+		ORDER BY event_type
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event types: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var configs []EventTypeConfig
+	for rows.Next() {
+		var cfg EventTypeConfig
+		var locales, schema []byte
+		if err := rows.Scan(&cfg.EventType, &cfg.Priority, &cfg.Title, &cfg.Message, &locales, &cfg.Digestible, &cfg.Channels, &schema, &cfg.TTL, &cfg.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event type: %w", pgError(err))
+		}
+		if err := json.Unmarshal(locales, &cfg.Locales); err != nil {
+			return nil, fmt.Errorf("invalid locales of event type %s: %w", cfg.EventType, err)
+		}
+		if schema != nil {
+			cfg.PayloadSchema = schema
+		}
+		configs = append(configs, cfg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list event types: %w", pgError(err))
+	}
+	return configs, nil
+}
+
+// PutEventType registers an event type, replacing its earlier registration,
+// and returns it as stored
+func (r *PostgresRepository) PutEventType(ctx context.Context, cfg EventTypeConfig) (EventTypeConfig, error) {
+	locales, err := json.Marshal(cfg.Locales)
+	if err != nil {
+		return EventTypeConfig{}, fmt.Errorf("failed to encode locales: %w", err)
+	}
+	if cfg.Locales == nil {
+		locales = []byte("{}")
+	}
+	var schema []byte // NULL when the event type has none
+	if len(cfg.PayloadSchema) > 0 {
+		schema = cfg.PayloadSchema
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO event_types (event_type, priority, title, message, locales, digestible, channels, payload_schema, ttl, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (event_type) DO UPDATE
+		SET priority = EXCLUDED.priority, title = EXCLUDED.title, message = EXCLUDED.message,
+		    locales = EXCLUDED.locales, digestible = EXCLUDED.digestible,
+		    channels = EXCLUDED.channels, payload_schema = EXCLUDED.payload_schema,
+		    ttl = EXCLUDED.ttl, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, cfg.EventType, string(cfg.Priority), cfg.Title, cfg.Message, locales, cfg.Digestible, cfg.Channels, schema, cfg.TTL).Scan(&cfg.UpdatedAt)
+	if err != nil {
+		return EventTypeConfig{}, fmt.Errorf("failed to put event type: %w", pgError(err))
+	}
+	return cfg, nil
+}
+
+// DeleteEventType removes an event type's registration, returning
+// ErrNotFound when it has none
+func (r *PostgresRepository) DeleteEventType(ctx context.Context, eventType string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM event_types WHERE event_type = $1`, eventType)
+	if err != nil {
+		return fmt.Errorf("failed to delete event type: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListSegments returns the registered segments, without their members
+func (r *PostgresRepository) ListSegments(ctx context.Context) ([]Segment, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT segment, cardinality(user_ids), updated_at
+		FROM segments
+		ORDER BY segment
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list segments: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	segments := []Segment{}
+	for rows.Next() {
+		var segment Segment
+		if err := rows.Scan(&segment.Segment, &segment.Size, &segment.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan segment: %w", pgError(err))
+		}
+		segments = append(segments, segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list segments: %w", pgError(err))
+	}
+	return segments, nil
+}
+
+// GetSegment returns a segment with its members, ErrNotFound when it isn't
+// registered
+func (r *PostgresRepository) GetSegment(ctx context.Context, name string) (Segment, error) {
+	segment := Segment{Segment: name}
+	err := r.pool.QueryRow(ctx, `
+		SELECT user_ids, updated_at
+		FROM segments
+		WHERE segment = $1
+	`, name).Scan(&segment.UserIDs, &segment.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Segment{}, fmt.Errorf("segment %s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return Segment{}, fmt.Errorf("failed to get segment: %w", pgError(err))
+	}
+	segment.Size = len(segment.UserIDs)
+	return segment, nil
+}
+
+// PutSegment registers a segment, replacing its earlier members, and
+// returns it as stored
+func (r *PostgresRepository) PutSegment(ctx context.Context, segment Segment) (Segment, error) {
+	if segment.UserIDs == nil {
+		segment.UserIDs = []string{}
+	}
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO segments (segment, user_ids, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (segment) DO UPDATE
+		SET user_ids = EXCLUDED.user_ids, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, segment.Segment, segment.UserIDs).Scan(&segment.UpdatedAt)
+	if err != nil {
+		return Segment{}, fmt.Errorf("failed to put segment: %w", pgError(err))
+	}
+	segment.Size = len(segment.UserIDs)
+	return segment, nil
+}
+
+// DeleteSegment removes a segment, returning ErrNotFound when it isn't
+// registered
+func (r *PostgresRepository) DeleteSegment(ctx context.Context, name string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM segments WHERE segment = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete segment: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListSubscriptions returns a user's topics in name order
+func (r *PostgresRepository) ListSubscriptions(ctx context.Context, tenantID, userID string) ([]Subscription, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT topic, created_at
+		FROM subscriptions
+		WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY topic
+	`, models.TenantOrDefault(tenantID), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	subscriptions := []Subscription{}
+	for rows.Next() {
+		var subscription Subscription
+		if err := rows.Scan(&subscription.Topic, &subscription.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", pgError(err))
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", pgError(err))
+	}
+	return subscriptions, nil
+}
+
+// Subscribe adds topics to a user's subscriptions in one statement;
+// existing ones keep their created_at
+func (r *PostgresRepository) Subscribe(ctx context.Context, tenantID, userID string, topics []string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO subscriptions (tenant_id, user_id, topic, created_at)
+		SELECT $1, $2, topic, NOW()
+		FROM unnest($3::text[]) AS topic
+		ON CONFLICT (tenant_id, user_id, topic) DO NOTHING
+	`, models.TenantOrDefault(tenantID), userID, topics)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", pgError(err))
+	}
+	return nil
+}
+
+// Unsubscribe removes a topic from a user's subscriptions, returning
+// ErrNotFound when the user isn't subscribed to it
+func (r *PostgresRepository) Unsubscribe(ctx context.Context, tenantID, userID, topic string) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM subscriptions
+		WHERE tenant_id = $1 AND user_id = $2 AND topic = $3
+	`, models.TenantOrDefault(tenantID), userID, topic)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TopicSubscribers returns the users of a tenant subscribed to a topic
+// (served by idx_subscriptions_topic)
+func (r *PostgresRepository) TopicSubscribers(ctx context.Context, tenantID, topic string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT user_id
+		FROM subscriptions
+		WHERE tenant_id = $1 AND topic = $2
+		ORDER BY user_id
+	`, models.TenantOrDefault(tenantID), topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topic subscribers: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %w", pgError(err))
+		}
+		users = append(users, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get topic subscribers: %w", pgError(err))
+	}
+	return users, nil
+}
+
+// lifecycleMiss explains why an archive or delete matched no row: the
+// user has no such notification, or it is claimed
+func (r *PostgresRepository) lifecycleMiss(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error {
+	var status string
+	err := r.pool.QueryRow(ctx, `
+		SELECT status FROM notifications
+		WHERE notification_id = $1 AND tenant_id = $2 AND user_id = $3
+	`, notificationID, models.TenantOrDefault(tenantID), userID).Scan(&status)
+	if err != nil {
+		return fmt.Errorf("notification %s: %w", notificationID, pgError(err))
+	}
+	return fmt.Errorf("notification %s is being delivered: %w", notificationID, ErrConflict)
+}
+
+// GetStats retrieves notification statistics from the status counters
+// (see postgres_counts.go), from a read replica when there are any. It
+// costs the same however large the table grows.
+func (r *PostgresRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	return readReplica(ctx, r, func(db *pgxpool.Pool) (map[string]interface{}, error) {
+		return r.counterStats(ctx, db)
+	})
+}
+
+// GetExactStats counts notifications by status over the whole table, from
+// a read replica when there are any, to check the counters against
+func (r *PostgresRepository) GetExactStats(ctx context.Context) (map[string]interface{}, error) {
+	return readReplica(ctx, r, func(db *pgxpool.Pool) (map[string]interface{}, error) {
+		return r.exactStats(ctx, db)
+	})
+}
+
+func (r *PostgresRepository) exactStats(ctx context.Context, db *pgxpool.Pool) (map[string]interface{}, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'not_pushed') as pending,
+			COUNT(*) FILTER (WHERE status = 'pushed') as delivered,
+			COUNT(*) FILTER (WHERE status = 'claimed') as claimed,
+			COUNT(*) FILTER (WHERE status = 'failed') as failed,
+			COUNT(*) FILTER (WHERE status = 'parked') as parked,
+			COUNT(*) FILTER (WHERE status = 'expired') as expired,
+			COUNT(*) FILTER (WHERE status = 'archived') as archived,
+			COUNT(*) as total
+		FROM notifications
+	`
+
+	var stats struct {
+		Pending   int64
+		Delivered int64
+		Claimed   int64
+		Failed    int64
+		Parked    int64
+		Expired   int64
+		Archived  int64
+		Total     int64
+	}
+
+	if err := db.QueryRow(ctx, query).Scan(
+		&stats.Pending,
+		&stats.Delivered,
+		&stats.Claimed,
+		&stats.Failed,
+		&stats.Parked,
+		&stats.Expired,
+		&stats.Archived,
+		&stats.Total,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", pgError(err))
+	}
+
+	return map[string]interface{}{
+		"pending":   stats.Pending,
+		"delivered": stats.Delivered,
+		"claimed":   stats.Claimed,
+		"failed":    stats.Failed,
+		"parked":    stats.Parked,
+		"expired":   stats.Expired,
+		"archived":  stats.Archived,
+		"total":     stats.Total,
+	}, nil
+}
+
+// GetStatsBreakdown counts the notifications created since the given time
+// by the chosen columns, with their delivery delay, from a read replica
+// when there are any. Groups come in event type, priority (HIGH first) and
+// status order.
+func (r *PostgresRepository) GetStatsBreakdown(ctx context.Context, since time.Time, group BreakdownGroup) ([]BreakdownRow, error) {
+	return readReplica(ctx, r, func(db *pgxpool.Pool) ([]BreakdownRow, error) {
+		return r.statsBreakdown(ctx, db, since, group)
+	})
+}
+
+func (r *PostgresRepository) statsBreakdown(ctx context.Context, db *pgxpool.Pool, since time.Time, group BreakdownGroup) ([]BreakdownRow, error) {
+	// Columns left out of the grouping collapse to '' so one statement
+	// serves every combination
+	query := `
+		SELECT * FROM (
+			SELECT
+				CASE WHEN $2 THEN event_type ELSE '' END AS event_type_group,
+				CASE WHEN $3 THEN priority ELSE '' END AS priority_group,
+				CASE WHEN $4 THEN status ELSE '' END AS status_group,
+				COUNT(*) AS total,
+				COUNT(delivered_at) AS delivered,
+				AVG(EXTRACT(EPOCH FROM (delivered_at - event_timestamp))) FILTER (WHERE NOT is_late)::float8 AS avg_delay,
+				MAX(EXTRACT(EPOCH FROM (delivered_at - event_timestamp))) FILTER (WHERE NOT is_late)::float8 AS max_delay
+			FROM notifications
+			WHERE created_at >= $1
+			GROUP BY 1, 2, 3
+		) groups
+		ORDER BY event_type_group,
+			(CASE priority_group WHEN 'HIGH' THEN 0 WHEN 'MEDIUM' THEN 1 WHEN 'LOW' THEN 2 ELSE 3 END),
+			status_group
+	`
+
+	rows, err := db.Query(ctx, query, since, group.EventType, group.Priority, group.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats breakdown: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var breakdown []BreakdownRow
+	for rows.Next() {
+		var row BreakdownRow
+		if err := rows.Scan(&row.EventType, &row.Priority, &row.Status, &row.Count,
+			&row.Delivered, &row.AvgDelaySeconds, &row.MaxDelaySeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+		breakdown = append(breakdown, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return breakdown, nil
+}
+
+// GetSLOAttainment returns per-priority delivery latency percentiles and the
+// share of notifications delivered within their SLO target since the given time
+func (r *PostgresRepository) GetSLOAttainment(ctx context.Context, since time.Time, targets SLOTargets) (map[string]interface{}, error) {
+	query := `
+		SELECT
+			priority,
+			COUNT(*) as delivered,
+			COUNT(*) FILTER (WHERE EXTRACT(EPOCH FROM (delivered_at - event_timestamp)) <=
+				CASE priority WHEN 'HIGH' THEN $2::float8 WHEN 'MEDIUM' THEN $3::float8 ELSE $4::float8 END) as within_target,
+			percentile_cont(0.50) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (delivered_at - event_timestamp))) as p50,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (delivered_at - event_timestamp))) as p95,
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (delivered_at - event_timestamp))) as p99
+		FROM notifications
+		WHERE status = 'pushed'
+		AND delivered_at >= $1
+		AND NOT is_late
+		GROUP BY priority
+	`
+
+	rows, err := r.pool.Query(ctx, query, since,
+		targets.High.Seconds(), targets.Medium.Seconds(), targets.Low.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slo attainment: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	results := make(map[string]interface{})
+	for rows.Next() {
+		var (
+			priority          string
+			delivered, within int64
+			p50, p95, p99     float64
+		)
+
+		if err := rows.Scan(&priority, &delivered, &within, &p50, &p95, &p99); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+
+		attainment := 0.0
+		if delivered > 0 {
+			attainment = float64(within) / float64(delivered) * 100
+		}
+
+		results[priority] = map[string]interface{}{
+			"target_seconds":     targets.For(priority).Seconds(),
+			"delivered":          delivered,
+			"within_target":      within,
+			"attainment_percent": attainment,
+			"p50_seconds":        p50,
+			"p95_seconds":        p95,
+			"p99_seconds":        p99,
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return results, nil
+}
+
+// GetDeliveryAttempts returns every recorded delivery attempt for a
+// notification, oldest first
+func (r *PostgresRepository) GetDeliveryAttempts(ctx context.Context, notificationID uuid.UUID) ([]map[string]interface{}, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT attempted_at, COALESCE(instance_id, ''), COALESCE(worker_id, 0), channel,
+		       outcome, COALESCE(error_message, ''), latency_ms, group_size
+		FROM delivery_attempts
+		WHERE notification_id = $1
+		ORDER BY attempted_at, attempt_id
+	`, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delivery attempts: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var attempts []map[string]interface{}
+	for rows.Next() {
+		var (
+			attemptedAt                            time.Time
+			instanceID, channel, outcome, errorMsg string
+			workerID, groupSize                    int
+			latencyMs                              float64
+		)
+		if err := rows.Scan(&attemptedAt, &instanceID, &workerID, &channel, &outcome, &errorMsg, &latencyMs, &groupSize); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+
+		attempts = append(attempts, map[string]interface{}{
+			"attempted_at":  attemptedAt,
+			"instance_id":   instanceID,
+			"worker_id":     workerID,
+			"channel":       channel,
+			"outcome":       outcome,
+			"error_message": errorMsg,
+			"latency_ms":    latencyMs,
+			"group_size":    groupSize,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	// No attempts yet is only an answer if the notification exists
+	if len(attempts) == 0 {
+		var exists bool
+		if err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM notifications WHERE notification_id = $1)`, notificationID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check notification: %w", pgError(err))
+		}
+		if !exists {
+			return nil, fmt.Errorf("notification %s: %w", notificationID, ErrNotFound)
+		}
+	}
+
+	return attempts, nil
+}
+
+// GetFlakyUsers returns the users with the most failed delivery attempts
+// since the given time
+func (r *PostgresRepository) GetFlakyUsers(ctx context.Context, since time.Time, limit int) ([]map[string]interface{}, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			tenant_id,
+			user_id,
+			COUNT(*) as attempts,
+			COUNT(*) FILTER (WHERE outcome = 'failed') as failures,
+			COUNT(DISTINCT notification_id) as notifications,
+			MAX(attempted_at) FILTER (WHERE outcome = 'failed') as last_failure
+		FROM delivery_attempts
+		WHERE attempted_at >= $1
+		GROUP BY tenant_id, user_id
+		HAVING COUNT(*) FILTER (WHERE outcome = 'failed') > 0
+		ORDER BY failures DESC, attempts DESC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flaky users: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var users []map[string]interface{}
+	for rows.Next() {
+		var (
+			tenantID, userID                  string
+			attempts, failures, notifications int64
+			lastFailure                       time.Time
+		)
+		if err := rows.Scan(&tenantID, &userID, &attempts, &failures, &notifications, &lastFailure); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+
+		users = append(users, map[string]interface{}{
+			"tenant_id":       tenantID,
+			"user_id":         userID,
+			"attempts":        attempts,
+			"failures":        failures,
+			"notifications":   notifications,
+			"failure_percent": float64(failures) / float64(attempts) * 100,
+			"last_failure":    lastFailure,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return users, nil
+}
+
+// ExistingEventIDs reports which of the given producer event IDs have been
+// persisted
+func (r *PostgresRepository) ExistingEventIDs(ctx context.Context, eventIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT DISTINCT event_id
+		FROM notifications
+		WHERE event_id = ANY($1)
+	`, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event ids: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID string
+		if err := rows.Scan(&eventID); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+		existing[eventID] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return existing, nil
+}
+
+// ExportNotifications returns up to limit notifications matching filter
+// with IDs after the given one, in ID order, from a read replica when there
+// are any. Paging by the primary key keeps every page an index range scan,
+// however deep the export is.
+func (r *PostgresRepository) ExportNotifications(ctx context.Context, filter ExportFilter, after uuid.UUID, limit int) ([]*ExportedNotification, error) {
+	return readReplica(ctx, r, func(db *pgxpool.Pool) ([]*ExportedNotification, error) {
+		return r.exportPage(ctx, db, filter, after, limit)
+	})
+}
+
+func (r *PostgresRepository) exportPage(ctx context.Context, db *pgxpool.Pool, filter ExportFilter, after uuid.UUID, limit int) ([]*ExportedNotification, error) {
+	var since, until *time.Time
+	if !filter.Since.IsZero() {
+		since = &filter.Since
+	}
+	if !filter.Until.IsZero() {
+		until = &filter.Until
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT notification_id, COALESCE(event_id, ''), tenant_id, user_id, event_type,
+			priority, status, payload::text, retry_count, COALESCE(error_message, ''),
+			COALESCE(trace_id, ''), is_late, event_timestamp, produced_at,
+			notification_received_timestamp, persisted_at, claimed_at, delivered_at,
+			expires_at, created_at
+		FROM notifications
+		WHERE notification_id > $1
+			AND ($2 = '' OR tenant_id = $2)
+			AND ($3 = '' OR user_id = $3)
+			AND ($4 = '' OR status = $4)
+			AND ($5 = '' OR event_type = $5)
+			AND ($6 = '' OR priority = $6)
+			AND ($7::timestamptz IS NULL OR created_at >= $7)
+			AND ($8::timestamptz IS NULL OR created_at < $8)
+		ORDER BY notification_id
+		LIMIT $9
+	`, after, filter.TenantID, filter.UserID, filter.Status, filter.EventType, filter.Priority, since, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications for export: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var page []*ExportedNotification
+	for rows.Next() {
+		var (
+			n       ExportedNotification
+			payload string
+		)
+		if err := rows.Scan(
+			&n.NotificationID, &n.EventID, &n.TenantID, &n.UserID, &n.EventType,
+			&n.Priority, &n.Status, &payload, &n.RetryCount, &n.ErrorMessage,
+			&n.TraceID, &n.IsLate, &n.EventTimestamp, &n.ProducedAt,
+			&n.ReceivedAt, &n.PersistedAt, &n.ClaimedAt, &n.DeliveredAt,
+			&n.ExpiresAt, &n.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", pgError(err))
+		}
+		n.Payload = json.RawMessage(payload)
+		if n.DeliveredAt != nil {
+			delay := n.DeliveredAt.Sub(n.EventTimestamp).Seconds()
+			n.DelaySeconds = &delay
+		}
+		page = append(page, &n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", pgError(err))
+	}
+
+	return page, nil
+}
+
+// Close stops the background loops and closes every connection
+func (r *PostgresRepository) Close(ctx context.Context) error {
+	close(r.stop)
+	r.closePools()
+	return nil
+}
+
+// Flush is a no-op for PostgreSQL (kept for interface compatibility)
+func (r *PostgresRepository) Flush(ctx context.Context) error {
+	return nil
+}
+
+// pgError tags driver errors with a repository error kind: no rows is
+// ErrNotFound, unique/exclusion violations and serialization failures are
+// ErrConflict, and connection failures, server shutdown and resource
+// exhaustion are ErrUnavailable. Other errors are returned as is.
+func pgError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return withKind(ErrNotFound, err)
+	case errors.As(err, &pgErr):
+		switch code := pgErr.Code; {
+		case code == "23505", code == "23P01", code == "40001", code == "40P01":
+			return withKind(ErrConflict, err)
+		case code[:2] == "08", code[:2] == "53",
+			code == "57P01", code == "57P02", code == "57P03":
+			return withKind(ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &netErr) ||
+		errors.As(err, &connectErr) ||
+		pgconn.SafeToRetry(err) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return withKind(ErrUnavailable, err)
+	}
+	return err
 }
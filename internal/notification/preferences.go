@@ -0,0 +1,107 @@
+package notification
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// preferenceLoadTimeout bounds a cache miss on the delivery path
+const preferenceLoadTimeout = 500 * time.Millisecond
+
+// PreferenceCache serves users' preferences to the delivery path, reading
+// each user's from the repository at most once per TTL. A failed read is
+// cached as empty preferences for the TTL too, so an unavailable store
+// costs one timeout per user rather than one per delivery. Writes through
+// this instance's API invalidate its entry; other instances see them once
+// theirs expires.
+type PreferenceCache struct {
+	repo   Repository
+	ttl    time.Duration
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	entries   map[string]preferenceEntry
+	pruneSize int // Entries at the last prune; the next one runs at twice as many
+}
+
+type preferenceEntry struct {
+	prefs    UserPreferences
+	loadedAt time.Time
+}
+
+// NewPreferenceCache creates a cache over repo (ttl defaults to 1m)
+func NewPreferenceCache(repo Repository, ttl time.Duration, logger *zap.Logger) *PreferenceCache {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &PreferenceCache{
+		repo:      repo,
+		ttl:       ttl,
+		logger:    logger,
+		entries:   make(map[string]preferenceEntry),
+		pruneSize: 1024,
+	}
+}
+
+// Get returns a user's preferences, loading them on a miss
+func (c *PreferenceCache) Get(tenantID, userID string) UserPreferences {
+	key := connectionKey(tenantID, userID)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Sub(entry.loadedAt) < c.ttl {
+		metrics.PreferenceLookups.WithLabelValues("hit").Inc()
+		return entry.prefs
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), preferenceLoadTimeout)
+	prefs, err := c.repo.GetUserPreferences(ctx, tenantID, userID)
+	cancel()
+	if err != nil {
+		metrics.PreferenceLookups.WithLabelValues("error").Inc()
+		c.logger.Warn("failed to load user preferences, using defaults",
+			zap.String("tenant_id", tenantID),
+			zap.String("user_id", userID),
+			zap.Error(err))
+		prefs = UserPreferences{}
+	} else {
+		metrics.PreferenceLookups.WithLabelValues("miss").Inc()
+	}
+
+	c.mu.Lock()
+	c.entries[key] = preferenceEntry{prefs: prefs, loadedAt: now}
+	if len(c.entries) >= 2*c.pruneSize {
+		c.prune(now)
+	}
+	c.mu.Unlock()
+	return prefs
+}
+
+// Locale returns a user's preferred locale, empty when they have none
+func (c *PreferenceCache) Locale(tenantID, userID string) string {
+	return c.Get(tenantID, userID).Locale
+}
+
+// Invalidate drops a user's entry so the next delivery reads the store
+func (c *PreferenceCache) Invalidate(tenantID, userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, connectionKey(tenantID, userID))
+}
+
+// prune drops expired entries; the caller holds c.mu
+func (c *PreferenceCache) prune(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.loadedAt) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	c.pruneSize = max(len(c.entries), 1024)
+}
@@ -0,0 +1,248 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+const (
+	defaultDeliveryWait = 5 * time.Second
+	maxDeliveryWait     = 30 * time.Second
+
+	// DefaultMaxPublishBatch caps the notifications in one POST /notifications/batch
+	DefaultMaxPublishBatch = 1000
+)
+
+// PublishHandler serves POST /notifications and POST /notifications/batch:
+// direct publishing that writes straight to the repository, bypassing
+// Kafka, for smoke tests, latency probes and HTTP-based producers
+type PublishHandler struct {
+	repository Repository
+	taskPicker *TaskPicker
+	idGen      idgen.Generator
+	maxBatch   int
+	eventTypes *EventTypeRegistry // Optional; nil uses the built-in priorities
+	logger     *zap.Logger
+}
+
+// PublishBatchRequest is the body of POST /notifications/batch
+type PublishBatchRequest struct {
+	Notifications []*models.KafkaMessage `json:"notifications"`
+}
+
+// PublishError names an invalid notification of a batch
+type PublishError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// NewPublishHandler creates a new publish handler
+func NewPublishHandler(repo Repository, taskPicker *TaskPicker, idGen idgen.Generator, logger *zap.Logger) *PublishHandler {
+	return &PublishHandler{
+		repository: repo,
+		taskPicker: taskPicker,
+		idGen:      idGen,
+		maxBatch:   DefaultMaxPublishBatch,
+		logger:     logger,
+	}
+}
+
+// SetMaxBatch caps the notifications accepted per batch request
+func (h *PublishHandler) SetMaxBatch(n int) {
+	if n > 0 {
+		h.maxBatch = n
+	}
+}
+
+// SetEventTypes sets where the priority of events published without one
+// comes from
+func (h *PublishHandler) SetEventTypes(eventTypes *EventTypeRegistry) {
+	h.eventTypes = eventTypes
+}
+
+// prepare validates a published event, including its payload against the
+// event type's schema, and fills in what the API defaults; a missing
+// priority and deadline come from eventTypes
+func prepare(msg *models.KafkaMessage, tenantID string, eventTypes *EventTypeRegistry) error {
+	if msg.IsBroadcast() {
+		return errors.New("broadcasts (user_ids or segment) are only expanded by the consumer; publish them to Kafka")
+	}
+	if msg.UserID == "" || msg.EventType == "" {
+		return errors.New("user_id and event_type are required")
+	}
+	if err := models.ValidateUserID(msg.UserID); err != nil {
+		return err
+	}
+	if schema := eventTypes.PayloadSchema(msg.EventType); schema != nil {
+		if err := schema.Validate(msg.Payload); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+	}
+	if msg.Priority == "" {
+		msg.Priority = string(eventTypes.Priority(msg.EventType))
+	}
+	if msg.EventTimestamp.IsZero() {
+		msg.EventTimestamp = time.Now()
+	}
+	if msg.ExpiresAt == nil {
+		msg.ExpiresAt = eventTypes.ExpiresAt(msg.EventType, msg.EventTimestamp)
+	}
+	msg.TenantID = tenantID
+	return nil
+}
+
+// Publish persists a notification and returns its ID once it is readable.
+// With ?wait_for_delivery=true it also waits (up to ?timeout=, default 5s,
+// max 30s) for this instance to attempt delivery and returns the outcome.
+func (h *PublishHandler) Publish(c *gin.Context) {
+	var msg models.KafkaMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if err := prepare(&msg, c.GetString(TenantIDKey), h.eventTypes); err != nil {
+		metrics.PublishedNotifications.WithLabelValues("single", "rejected").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	waitForDelivery, _ := strconv.ParseBool(c.Query("wait_for_delivery"))
+	wait := defaultDeliveryWait
+	if raw := c.Query("timeout"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a positive duration"})
+			return
+		}
+		wait = min(d, maxDeliveryWait)
+	}
+
+	notif := NotificationFromMessage(h.idGen.NewID(), &msg)
+
+	// Register before inserting so a delivery that beats us back isn't missed
+	var outcome <-chan AuditEvent
+	if waitForDelivery {
+		var release func()
+		outcome, release = h.taskPicker.WaitForDelivery(notif.NotificationID)
+		defer release()
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repository.Insert(ctx, notif); err != nil {
+		metrics.PublishedNotifications.WithLabelValues("single", "failed").Inc()
+		if errors.Is(err, ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "notification " + notif.NotificationID.String() + " already exists"})
+			return
+		}
+		h.logger.Error("failed to persist published notification",
+			zap.String("user_id", notif.UserID),
+			zap.Error(err))
+		respondRepoError(c, err, "failed to persist notification")
+		return
+	}
+	metrics.PublishedNotifications.WithLabelValues("single", "persisted").Inc()
+
+	response := gin.H{
+		"notification_id": notif.NotificationID.String(),
+		"tenant_id":       notif.TenantID,
+		"user_id":         notif.UserID,
+		"priority":        notif.Priority,
+		"status":          notif.Status,
+		"persisted_at":    notif.CreatedAt,
+	}
+
+	if !waitForDelivery {
+		c.JSON(http.StatusCreated, response)
+		return
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case event := <-outcome:
+		response["status"] = event.Status
+		response["delivery"] = event
+		response["end_to_end_ms"] = float64(event.Timestamp.Sub(notif.CreatedAt).Microseconds()) / 1000
+		c.JSON(http.StatusOK, response)
+	case <-timer.C:
+		// Still pending, or delivered by another instance
+		response["delivery"] = gin.H{"status": "timeout", "waited_ms": wait.Milliseconds()}
+		c.JSON(http.StatusAccepted, response)
+	case <-ctx.Done():
+	}
+}
+
+// PublishBatch validates up to maxBatch notifications and persists them in
+// one BatchInsert, so HTTP producers pay one round trip and one transaction
+// per batch. The batch is all or nothing: any invalid notification rejects
+// it with the index of every invalid one.
+func (h *PublishHandler) PublishBatch(c *gin.Context) {
+	var req PublishBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Notifications) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notifications must not be empty"})
+		return
+	}
+	if len(req.Notifications) > h.maxBatch {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":     "too many notifications in one batch",
+			"max_batch": h.maxBatch,
+		})
+		return
+	}
+
+	tenantID := c.GetString(TenantIDKey)
+	var invalid []PublishError
+	for i, msg := range req.Notifications {
+		if msg == nil {
+			invalid = append(invalid, PublishError{Index: i, Error: "notification is null"})
+			continue
+		}
+		if err := prepare(msg, tenantID, h.eventTypes); err != nil {
+			invalid = append(invalid, PublishError{Index: i, Error: err.Error()})
+		}
+	}
+	if len(invalid) > 0 {
+		metrics.PublishedNotifications.WithLabelValues("batch", "rejected").Add(float64(len(req.Notifications)))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notifications", "invalid": invalid})
+		return
+	}
+
+	notifs := make([]*models.Notification, len(req.Notifications))
+	ids := make([]string, len(req.Notifications))
+	for i, msg := range req.Notifications {
+		notifs[i] = NotificationFromMessage(h.idGen.NewID(), msg)
+		ids[i] = notifs[i].NotificationID.String()
+	}
+
+	metrics.PublishBatchSize.Observe(float64(len(notifs)))
+	if err := h.repository.BatchInsert(c.Request.Context(), notifs); err != nil {
+		metrics.PublishedNotifications.WithLabelValues("batch", "failed").Add(float64(len(notifs)))
+		h.logger.Error("failed to persist published batch",
+			zap.Int("count", len(notifs)),
+			zap.Error(err))
+		respondRepoError(c, err, "failed to persist notifications")
+		return
+	}
+	metrics.PublishedNotifications.WithLabelValues("batch", "persisted").Add(float64(len(notifs)))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"count":            len(notifs),
+		"tenant_id":        models.TenantOrDefault(tenantID),
+		"notification_ids": ids,
+	})
+}
@@ -0,0 +1,216 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// QuotaCounter hands out units from fixed-window counters. Instances that
+// share a counter enforce limits together; *cache.RedisCache is the
+// cluster-wide implementation.
+type QuotaCounter interface {
+	// TakeQuota takes up to n units from the counter at key, which allows
+	// limit units before it expires after window, and returns how many were
+	// granted
+	TakeQuota(ctx context.Context, key string, n, limit int, window time.Duration) (int, error)
+}
+
+// QuotaConfig holds delivery limits
+type QuotaConfig struct {
+	DeliveryRate int           // Notifications delivered per second across instances (0 = unlimited)
+	UserLimit    int           // Notifications delivered per user per UserWindow (0 = unlimited)
+	UserWindow   time.Duration // Per-user window (default 1m)
+}
+
+// QuotaStats is a point-in-time view of quota enforcement
+type QuotaStats struct {
+	Shared          bool    `json:"shared"` // Counters shared across instances
+	DeliveryRate    int     `json:"delivery_rate"`
+	UserLimit       int     `json:"user_limit"`
+	UserWindow      string  `json:"user_window"`
+	Throttled       int64   `json:"throttled_total"`
+	ThrottledWaitMs float64 `json:"throttled_wait_ms"`
+	Deferred        int64   `json:"deferred_total"`
+	Errors          int64   `json:"errors_total"`
+}
+
+// DeliveryQuotas enforces the global delivery rate and per-user caps. Both
+// are fixed windows keyed by wall-clock time, so instances sharing a counter
+// agree on window boundaries without coordinating. Counter errors fail open.
+type DeliveryQuotas struct {
+	cfg     QuotaConfig
+	counter QuotaCounter
+	shared  bool
+	logger  *zap.Logger
+
+	throttled  int64
+	throttleNs int64
+	deferred   int64
+	errors     int64
+}
+
+// NewDeliveryQuotas creates quotas backed by counter, or by per-instance
+// counters when counter is nil
+func NewDeliveryQuotas(cfg QuotaConfig, counter QuotaCounter, logger *zap.Logger) *DeliveryQuotas {
+	if cfg.UserWindow <= 0 {
+		cfg.UserWindow = time.Minute
+	}
+
+	shared := counter != nil
+	if !shared {
+		counter = NewLocalQuotaCounter()
+	}
+
+	return &DeliveryQuotas{
+		cfg:     cfg,
+		counter: counter,
+		shared:  shared,
+		logger:  logger,
+	}
+}
+
+// Enabled reports whether any limit is configured
+func (q *DeliveryQuotas) Enabled() bool {
+	return q != nil && (q.cfg.DeliveryRate > 0 || q.cfg.UserLimit > 0)
+}
+
+// AllowUser takes up to n deliveries from the user's cap for the current
+// window and returns how many may go out now
+func (q *DeliveryQuotas) AllowUser(ctx context.Context, tenantID, userID string, n int) int {
+	if q.cfg.UserLimit <= 0 {
+		return n
+	}
+
+	window := time.Now().Truncate(q.cfg.UserWindow).Unix()
+	key := fmt.Sprintf("user:%s:%d", connectionKey(tenantID, userID), window)
+	granted, err := q.counter.TakeQuota(ctx, key, n, q.cfg.UserLimit, q.cfg.UserWindow)
+	if err != nil {
+		q.failOpen(err)
+		return n
+	}
+
+	if deferred := n - granted; deferred > 0 {
+		atomic.AddInt64(&q.deferred, int64(deferred))
+		metrics.QuotaDeferred.Add(float64(deferred))
+	}
+	return granted
+}
+
+// UserWindowEnd returns when the current per-user window ends
+func (q *DeliveryQuotas) UserWindowEnd() time.Time {
+	return time.Now().Truncate(q.cfg.UserWindow).Add(q.cfg.UserWindow)
+}
+
+// WaitGlobal blocks until n deliveries fit under the global delivery rate,
+// taking what each one-second window has left. It returns early when ctx is
+// done.
+func (q *DeliveryQuotas) WaitGlobal(ctx context.Context, n int) {
+	if q.cfg.DeliveryRate <= 0 {
+		return
+	}
+
+	var waited time.Duration
+	for remaining := n; remaining > 0; {
+		now := time.Now()
+		key := fmt.Sprintf("global:%d", now.Unix())
+		granted, err := q.counter.TakeQuota(ctx, key, remaining, q.cfg.DeliveryRate, time.Second)
+		if err != nil {
+			q.failOpen(err)
+			return
+		}
+		if remaining -= granted; remaining == 0 {
+			break
+		}
+
+		// Window exhausted, wait for the next one
+		wait := now.Truncate(time.Second).Add(time.Second).Sub(now)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			waited += wait
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	if waited > 0 {
+		atomic.AddInt64(&q.throttled, 1)
+		atomic.AddInt64(&q.throttleNs, int64(waited))
+		metrics.QuotaThrottleSeconds.Observe(waited.Seconds())
+	}
+}
+
+func (q *DeliveryQuotas) failOpen(err error) {
+	atomic.AddInt64(&q.errors, 1)
+	metrics.QuotaErrors.Inc()
+	q.logger.Warn("quota counter failed, delivering unthrottled", zap.Error(err))
+}
+
+// Stats returns quota configuration and counters
+func (q *DeliveryQuotas) Stats() QuotaStats {
+	return QuotaStats{
+		Shared:          q.shared,
+		DeliveryRate:    q.cfg.DeliveryRate,
+		UserLimit:       q.cfg.UserLimit,
+		UserWindow:      q.cfg.UserWindow.String(),
+		Throttled:       atomic.LoadInt64(&q.throttled),
+		ThrottledWaitMs: float64(atomic.LoadInt64(&q.throttleNs)) / 1e6,
+		Deferred:        atomic.LoadInt64(&q.deferred),
+		Errors:          atomic.LoadInt64(&q.errors),
+	}
+}
+
+// LocalQuotaCounter is an in-process QuotaCounter: limits hold per instance
+// only, so they multiply with the number of instances
+type LocalQuotaCounter struct {
+	mu        sync.Mutex
+	counters  map[string]*localQuota
+	lastSweep time.Time
+}
+
+type localQuota struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewLocalQuotaCounter creates an empty in-process counter
+func NewLocalQuotaCounter() *LocalQuotaCounter {
+	return &LocalQuotaCounter{counters: make(map[string]*localQuota)}
+}
+
+// TakeQuota implements QuotaCounter
+func (c *LocalQuotaCounter) TakeQuota(ctx context.Context, key string, n, limit int, window time.Duration) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, quota := range c.counters {
+			if now.After(quota.expiresAt) {
+				delete(c.counters, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	quota, ok := c.counters[key]
+	if !ok || now.After(quota.expiresAt) {
+		quota = &localQuota{expiresAt: now.Add(window)}
+		c.counters[key] = quota
+	}
+
+	granted := min(n, limit-quota.count)
+	if granted < 0 {
+		granted = 0
+	}
+	quota.count += granted
+	return granted, nil
+}
@@ -0,0 +1,260 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// ReceiptWriter receives delivery receipts. *kafka.Writer satisfies it (see
+// producer.NewReceiptWriter).
+type ReceiptWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// DeliveryReceipt is the compact event published for every delivered
+// notification, so analytics can follow deliveries without database access
+type DeliveryReceipt struct {
+	NotificationID string        `json:"notification_id"`
+	TenantID       string        `json:"tenant_id"`
+	UserID         string        `json:"user_id"`
+	EventType      string        `json:"event_type"`
+	Priority       string        `json:"priority"`
+	Channel        string        `json:"channel"`
+	InstanceID     string        `json:"instance_id"`
+	GroupSize      int           `json:"group_size"`
+	EventTimestamp time.Time     `json:"event_timestamp"`
+	DeliveredAt    time.Time     `json:"delivered_at"`
+	Stages         ReceiptStages `json:"stages"`
+}
+
+// ReceiptStages splits a notification's end-to-end latency by pipeline stage
+type ReceiptStages struct {
+	IngestMs  float64 `json:"ingest_ms"`            // Event → persisted by the consumer
+	StoredMs  float64 `json:"stored_ms"`            // Persisted → claimed by a picker
+	QueuedMs  float64 `json:"queued_ms"`            // Claimed → taken by a delivery worker
+	DeliverMs float64 `json:"deliver_ms"`           // SSE write
+	TotalMs   float64 `json:"total_ms"`             // Event → delivered
+	ProduceMs float64 `json:"produce_ms,omitempty"` // Producer → consumer, when the producer stamped it
+	PersistMs float64 `json:"persist_ms,omitempty"` // Consumer → row written
+	PendingMs float64 `json:"pending_ms,omitempty"` // Row written → claimed, by the database clock
+}
+
+// ReceiptStats reports receipt publishing
+type ReceiptStats struct {
+	Topic     string `json:"topic"`
+	Published int64  `json:"published_total"`
+	Dropped   int64  `json:"dropped_total"` // Queue full: the writer couldn't keep up
+	Failed    int64  `json:"failed_total"`  // Broker writes that failed
+	Queued    int    `json:"queued"`
+}
+
+// Receipt batching
+const (
+	receiptBatchSize    = 500
+	receiptFlushTimeout = 100 * time.Millisecond
+)
+
+// ReceiptPublisher queues delivery receipts and writes them in batches from
+// a background goroutine. Delivery workers never wait on the broker: when
+// the queue is full, receipts are dropped and counted.
+type ReceiptPublisher struct {
+	writer ReceiptWriter
+	topic  string
+	queue  chan kafka.Message
+	logger *zap.Logger
+
+	published int64
+	dropped   int64
+	failed    int64
+
+	wg       sync.WaitGroup
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewReceiptPublisher creates a publisher queueing up to bufferSize receipts
+func NewReceiptPublisher(writer ReceiptWriter, topic string, bufferSize int, logger *zap.Logger) *ReceiptPublisher {
+	if bufferSize <= 0 {
+		bufferSize = 10000
+	}
+	return &ReceiptPublisher{
+		writer: writer,
+		topic:  topic,
+		queue:  make(chan kafka.Message, bufferSize),
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs the writer goroutine until Stop
+func (p *ReceiptPublisher) Start() {
+	p.wg.Add(1)
+	go p.run()
+	p.logger.Info("receipt publisher started",
+		zap.String("topic", p.topic),
+		zap.Int("buffer_size", cap(p.queue)))
+}
+
+// Stop writes the receipts still queued and stops the writer goroutine.
+// Call it after the task picker has stopped.
+func (p *ReceiptPublisher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		p.logger.Info("receipt publisher stopped",
+			zap.Int64("published", atomic.LoadInt64(&p.published)),
+			zap.Int64("dropped", atomic.LoadInt64(&p.dropped)),
+			zap.Int64("failed", atomic.LoadInt64(&p.failed)))
+	})
+}
+
+// Publish queues a receipt without blocking
+func (p *ReceiptPublisher) Publish(receipt DeliveryReceipt) {
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		p.logger.Error("failed to marshal receipt", zap.Error(err))
+		return
+	}
+
+	select {
+	case p.queue <- kafka.Message{Key: []byte(receipt.UserID), Value: data, Time: receipt.DeliveredAt}:
+	default:
+		atomic.AddInt64(&p.dropped, 1)
+		metrics.DeliveryReceipts.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Stats returns the publisher's counters
+func (p *ReceiptPublisher) Stats() ReceiptStats {
+	return ReceiptStats{
+		Topic:     p.topic,
+		Published: atomic.LoadInt64(&p.published),
+		Dropped:   atomic.LoadInt64(&p.dropped),
+		Failed:    atomic.LoadInt64(&p.failed),
+		Queued:    len(p.queue),
+	}
+}
+
+func (p *ReceiptPublisher) run() {
+	defer p.wg.Done()
+
+	batch := make([]kafka.Message, 0, receiptBatchSize)
+	ticker := time.NewTicker(receiptFlushTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-p.queue:
+			batch = append(batch, msg)
+			if len(batch) >= receiptBatchSize {
+				batch = p.flush(batch)
+			}
+
+		case <-ticker.C:
+			batch = p.flush(batch)
+
+		case <-p.done:
+			// Write what is left, then stop
+			for len(p.queue) > 0 {
+				batch = append(batch, <-p.queue)
+				if len(batch) >= receiptBatchSize {
+					batch = p.flush(batch)
+				}
+			}
+			p.flush(batch)
+			return
+		}
+	}
+}
+
+// flush writes a batch and returns it emptied
+func (p *ReceiptPublisher) flush(batch []kafka.Message) []kafka.Message {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := p.writer.WriteMessages(ctx, batch...); err != nil {
+		atomic.AddInt64(&p.failed, int64(len(batch)))
+		metrics.DeliveryReceipts.WithLabelValues("failed").Add(float64(len(batch)))
+		p.logger.Warn("failed to publish delivery receipts",
+			zap.Int("count", len(batch)),
+			zap.Error(err))
+	} else {
+		atomic.AddInt64(&p.published, int64(len(batch)))
+		metrics.DeliveryReceipts.WithLabelValues("published").Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// newDeliveryReceipt builds the receipt of a notification delivered by the
+// task picker
+func newDeliveryReceipt(notif *NotificationBatch, update *StatusUpdate, startedAt, deliveredAt time.Time) DeliveryReceipt {
+	return DeliveryReceipt{
+		NotificationID: notif.NotificationID.String(),
+		TenantID:       notif.TenantID,
+		UserID:         notif.UserID,
+		EventType:      notif.EventType,
+		Priority:       notif.Priority,
+		Channel:        update.Channel,
+		InstanceID:     update.InstanceID,
+		GroupSize:      update.GroupSize,
+		EventTimestamp: notif.EventTimestamp,
+		DeliveredAt:    deliveredAt,
+		Stages: ReceiptStages{
+			IngestMs:  stageMs(notif.EventTimestamp, notif.NotificationReceivedTimestamp),
+			StoredMs:  stageMs(notif.NotificationReceivedTimestamp, notif.enqueuedAt),
+			QueuedMs:  stageMs(notif.enqueuedAt, startedAt),
+			DeliverMs: stageMs(startedAt, deliveredAt),
+			TotalMs:   stageMs(notif.EventTimestamp, deliveredAt),
+			ProduceMs: stageMs(notif.ProducedAt, notif.NotificationReceivedTimestamp),
+			PersistMs: stageMs(notif.NotificationReceivedTimestamp, notif.PersistedAt),
+			PendingMs: stageMs(notif.PersistedAt, notif.ClaimedAt),
+		},
+	}
+}
+
+// observeStages records a delivered notification's pipeline stages. Stages
+// with an unknown boundary are skipped; stamps from different clocks can be
+// slightly out of order, which is clamped to zero.
+func observeStages(notif *NotificationBatch, startedAt, deliveredAt time.Time) {
+	claimedAt := notif.ClaimedAt
+	if claimedAt.IsZero() {
+		claimedAt = notif.enqueuedAt
+	}
+	start := notif.ProducedAt
+	if start.IsZero() {
+		start = notif.EventTimestamp
+	}
+
+	observe := func(stage string, from, to time.Time) {
+		if from.IsZero() || to.IsZero() {
+			return
+		}
+		metrics.PipelineStageSeconds.WithLabelValues(stage).Observe(max(to.Sub(from), 0).Seconds())
+	}
+	observe("produce", notif.ProducedAt, notif.NotificationReceivedTimestamp)
+	observe("persist", notif.NotificationReceivedTimestamp, notif.PersistedAt)
+	observe("pending", notif.PersistedAt, claimedAt)
+	observe("queue", claimedAt, startedAt)
+	observe("deliver", startedAt, deliveredAt)
+	observe("total", start, deliveredAt)
+}
+
+// stageMs is the time between two stage boundaries in milliseconds, 0 when
+// either is unknown
+func stageMs(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
@@ -0,0 +1,231 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Repository is the persistence layer used by the consumer, task picker
+// and HTTP handlers. PostgresRepository is the production implementation;
+// MemoryRepository backs the all-in-one demo binary.
+type Repository interface {
+	Insert(ctx context.Context, notification *models.Notification) error
+	BatchInsert(ctx context.Context, notifications []*models.Notification) error
+	ClaimBatch(ctx context.Context, instanceID string, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error)
+	ClaimWeighted(ctx context.Context, instanceID string, quotas map[models.Priority]int, leaseDuration time.Duration) ([]*NotificationBatch, error)
+	ClaimForUsers(ctx context.Context, instanceID string, users []UserRef, batchSize int, leaseDuration time.Duration, policy ClaimPolicy) ([]*NotificationBatch, error)
+	ClaimParked(ctx context.Context, tenantID, userID, instanceID string, batchSize int, leaseDuration time.Duration) ([]*NotificationBatch, error)
+	BatchUpdateStatus(ctx context.Context, updates []*StatusUpdate) error
+	ReclaimStaleTasks(ctx context.Context) (int, error)
+	RequeueFailed(ctx context.Context, limit int) (int, error)
+	ExpireStale(ctx context.Context, now time.Time, maxAge time.Duration, limit int) ([]ExpiredCount, error)
+	Reprioritize(ctx context.Context, from, to models.Priority, createdBefore time.Time, limit int) (int, error)
+	GetUserNotifications(ctx context.Context, tenantID, userID string, limit int, includeArchived bool) ([]map[string]interface{}, error)
+	GetUnreadCounts(ctx context.Context, tenantID, userID string) (map[string]int64, error)
+	MarkRead(ctx context.Context, tenantID, userID string, notificationIDs []uuid.UUID) (int, error)
+	ArchiveNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error
+	DeleteNotification(ctx context.Context, tenantID, userID string, notificationID uuid.UUID) error
+	GetUserPreferences(ctx context.Context, tenantID, userID string) (UserPreferences, error)
+	SetUserPreferences(ctx context.Context, tenantID, userID string, prefs UserPreferences) (UserPreferences, error)
+	ListEventTypes(ctx context.Context) ([]EventTypeConfig, error)
+	PutEventType(ctx context.Context, cfg EventTypeConfig) (EventTypeConfig, error)
+	DeleteEventType(ctx context.Context, eventType string) error
+	ListSegments(ctx context.Context) ([]Segment, error)
+	GetSegment(ctx context.Context, name string) (Segment, error)
+	PutSegment(ctx context.Context, segment Segment) (Segment, error)
+	DeleteSegment(ctx context.Context, name string) error
+	ListSubscriptions(ctx context.Context, tenantID, userID string) ([]Subscription, error)
+	Subscribe(ctx context.Context, tenantID, userID string, topics []string) error
+	Unsubscribe(ctx context.Context, tenantID, userID, topic string) error
+	TopicSubscribers(ctx context.Context, tenantID, topic string) ([]string, error)
+	GetStats(ctx context.Context) (map[string]interface{}, error)
+	GetExactStats(ctx context.Context) (map[string]interface{}, error)
+	GetStatsBreakdown(ctx context.Context, since time.Time, group BreakdownGroup) ([]BreakdownRow, error)
+	GetTenantStats(ctx context.Context) (map[string]interface{}, error)
+	GetSLOAttainment(ctx context.Context, since time.Time, targets SLOTargets) (map[string]interface{}, error)
+	GetDeliveryAttempts(ctx context.Context, notificationID uuid.UUID) ([]map[string]interface{}, error)
+	GetFlakyUsers(ctx context.Context, since time.Time, limit int) ([]map[string]interface{}, error)
+	ExistingEventIDs(ctx context.Context, eventIDs []string) (map[string]bool, error)
+	ExportNotifications(ctx context.Context, filter ExportFilter, after uuid.UUID, limit int) ([]*ExportedNotification, error)
+	Close(ctx context.Context) error
+	Flush(ctx context.Context) error
+}
+
+// UserRef names one of a tenant's users
+type UserRef struct {
+	TenantID string
+	UserID   string
+}
+
+// UserPreferences are a user's delivery settings
+type UserPreferences struct {
+	Locale    string    `json:"locale"`               // Language of titles and messages ("es", "pt-BR"); empty = the templates' default
+	UpdatedAt time.Time `json:"updated_at,omitempty"` // Zero when never set
+}
+
+// EventTypeConfig registers an event type or family ("job.*") at runtime,
+// overriding what is built in for it; see EventTypeRegistry
+type EventTypeConfig struct {
+	EventType  string                   `json:"event_type"`
+	Priority   models.Priority          `json:"priority,omitempty"` // Used when the producer sets none; empty keeps the built-in
+	Title      string                   `json:"title,omitempty"`    // Templates in FallbackLocale; leaving all three empty keeps the templates file's
+	Message    string                   `json:"message,omitempty"`
+	Locales    map[string]LocalizedText `json:"locales,omitempty"`
+	Digestible bool                     `json:"digestible"`    // May share a grouped frame with the user's other notifications
+	Channels   []string                 `json:"channels"`      // Delivery channels (default ["sse"])
+	TTL        string                   `json:"ttl,omitempty"` // Events older than this expire undelivered ("2h"); empty keeps the templates file's
+	// The consumer poisons events whose payload fails it; see PayloadSchema
+	PayloadSchema json.RawMessage `json:"payload_schema,omitempty"`
+	UpdatedAt     time.Time       `json:"updated_at,omitempty"`
+}
+
+// Segment is a named list of users that broadcasts can address; see
+// Audience
+type Segment struct {
+	Segment   string    `json:"segment"`
+	UserIDs   []string  `json:"user_ids,omitempty"` // Left out of listings
+	Size      int       `json:"size"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Subscription is one of a user's topics; events published to the topic
+// reach the user (see Audience)
+type Subscription struct {
+	Topic     string    `json:"topic"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Repository error kinds. Implementations wrap driver errors so that
+// errors.Is matches both the kind and the underlying error.
+var (
+	// ErrNotFound means the requested notification does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the write collided with existing data or a
+	// concurrent transaction (duplicate key, serialization failure)
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable means the store could not be reached or refused work;
+	// the operation may succeed if retried later
+	ErrUnavailable = errors.New("repository unavailable")
+)
+
+// kindError tags an underlying error with one of the repository error kinds
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// withKind tags err with kind; nil stays nil
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// Reasons a notification is expired, by ExpireStale or, once claimed, by
+// the task picker (ttl)
+const (
+	ExpiryReasonDeadline = "deadline" // Past its expires_at
+	ExpiryReasonAge      = "age"      // Undelivered for longer than the max age
+	ExpiryReasonTTL      = "ttl"      // Older than its event type's TTL when claimed
+)
+
+// ExpiredCount is how many notifications of one event type and priority
+// ExpireStale expired for one reason
+type ExpiredCount struct {
+	EventType string
+	Priority  string
+	Reason    string
+	Count     int64
+}
+
+// statusStats is the GetStats view of notification counts by status
+func statusStats(counts map[string]int64) map[string]interface{} {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return map[string]interface{}{
+		"pending":   counts["not_pushed"],
+		"delivered": counts["pushed"],
+		"claimed":   counts["claimed"],
+		"failed":    counts["failed"],
+		"parked":    counts["parked"],
+		"expired":   counts["expired"],
+		"archived":  counts["archived"],
+		"total":     total,
+	}
+}
+
+// BreakdownGroup picks the columns GetStatsBreakdown groups by; the others
+// are folded together and left empty in its rows
+type BreakdownGroup struct {
+	EventType bool
+	Priority  bool
+	Status    bool
+}
+
+// BreakdownRow is one group of notifications created in the breakdown window
+type BreakdownRow struct {
+	EventType       string   `json:"event_type,omitempty"`
+	Priority        string   `json:"priority,omitempty"`
+	Status          string   `json:"status,omitempty"`
+	Count           int64    `json:"count"`
+	Delivered       int64    `json:"delivered"`                   // Rows with a delivery time
+	AvgDelaySeconds *float64 `json:"avg_delay_seconds,omitempty"` // Event → delivery, late events excluded; nil with none delivered
+	MaxDelaySeconds *float64 `json:"max_delay_seconds,omitempty"`
+}
+
+// ExportFilter selects the notifications ExportNotifications returns; empty
+// fields match everything
+type ExportFilter struct {
+	TenantID  string
+	UserID    string
+	Status    string
+	EventType string
+	Priority  string
+	Since     time.Time // created_at at or after, zero for no bound
+	Until     time.Time // created_at before, zero for no bound
+}
+
+// ExportedNotification is one notification row as bulk export writes it
+type ExportedNotification struct {
+	NotificationID uuid.UUID       `json:"notification_id"`
+	EventID        string          `json:"event_id,omitempty"`
+	TenantID       string          `json:"tenant_id"`
+	UserID         string          `json:"user_id"`
+	EventType      string          `json:"event_type"`
+	Priority       string          `json:"priority"`
+	Status         string          `json:"status"`
+	Payload        json.RawMessage `json:"payload"`
+	RetryCount     int             `json:"retry_count"`
+	ErrorMessage   string          `json:"error_message,omitempty"`
+	TraceID        string          `json:"trace_id,omitempty"`
+	IsLate         bool            `json:"is_late"`
+	EventTimestamp time.Time       `json:"event_timestamp"`
+	ProducedAt     *time.Time      `json:"produced_at,omitempty"`
+	ReceivedAt     time.Time       `json:"notification_received_timestamp"`
+	PersistedAt    *time.Time      `json:"persisted_at,omitempty"`
+	ClaimedAt      *time.Time      `json:"claimed_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	DelaySeconds   *float64        `json:"delay_seconds,omitempty"` // Event time to delivery
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+var (
+	_ Repository = (*PostgresRepository)(nil)
+	_ Repository = (*MemoryRepository)(nil)
+	_ Repository = (*CachedRepository)(nil)
+	_ Repository = (*ChaosRepository)(nil)
+	_ Repository = (*BreakerRepository)(nil)
+)
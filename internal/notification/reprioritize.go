@@ -0,0 +1,221 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// ReprioritizeRule moves pending and parked notifications of one priority
+// to another once they are older than After: a decay (HIGH to MEDIUM) or a
+// boost (LOW to MEDIUM)
+type ReprioritizeRule struct {
+	From  models.Priority
+	After time.Duration
+	To    models.Priority
+}
+
+// String formats the rule the way ParseReprioritizeRules reads it
+func (r ReprioritizeRule) String() string {
+	return fmt.Sprintf("%s:%s:%s", r.From, r.After, r.To)
+}
+
+// transition names the rule's move in stats, e.g. HIGH_to_MEDIUM
+func (r ReprioritizeRule) transition() string {
+	return string(r.From) + "_to_" + string(r.To)
+}
+
+// ParseReprioritizeRules reads comma-separated from:after:to rules, e.g.
+// "HIGH:10m:MEDIUM,LOW:30m:MEDIUM". Ages count from when the notification
+// was persisted, so rules chain: with HIGH:10m:MEDIUM,MEDIUM:20m:LOW a HIGH
+// notification is LOW after 20 minutes. Rules that would move a
+// notification back to a priority it left are rejected.
+func ParseReprioritizeRules(raw string) ([]ReprioritizeRule, error) {
+	var rules []ReprioritizeRule
+	next := make(map[models.Priority]models.Priority)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid reprioritize rule %q (want from:after:to, e.g. HIGH:10m:MEDIUM)", entry)
+		}
+
+		from, to := models.Priority(strings.ToUpper(parts[0])), models.Priority(strings.ToUpper(parts[2]))
+		if !slices.Contains(claimPriorities, from) || !slices.Contains(claimPriorities, to) || from == to {
+			return nil, fmt.Errorf("invalid reprioritize rule %q: priorities must be two of HIGH, MEDIUM, LOW", entry)
+		}
+		after, err := time.ParseDuration(parts[1])
+		if err != nil || after <= 0 {
+			return nil, fmt.Errorf("invalid reprioritize rule %q: age must be a positive duration", entry)
+		}
+		if _, ok := next[from]; ok {
+			return nil, fmt.Errorf("invalid reprioritize rule %q: %s has a rule already", entry, from)
+		}
+		next[from] = to
+		rules = append(rules, ReprioritizeRule{From: from, After: after, To: to})
+	}
+
+	for _, rule := range rules {
+		seen := map[models.Priority]bool{rule.From: true}
+		for p, ok := next[rule.From]; ok; p, ok = next[p] {
+			if seen[p] {
+				return nil, fmt.Errorf("invalid reprioritize rules %q: %s cycles back to itself", raw, rule.From)
+			}
+			seen[p] = true
+		}
+	}
+	return rules, nil
+}
+
+// ReprioritizeConfig controls the reprioritizer
+type ReprioritizeConfig struct {
+	Interval  time.Duration // Time between passes
+	Rules     []ReprioritizeRule
+	BatchSize int // Notifications moved per statement; a rule repeats until a batch comes back short
+}
+
+// ReprioritizeStats is the reprioritizer's lifetime view for the admin API
+type ReprioritizeStats struct {
+	Interval     string           `json:"interval"`
+	Rules        []string         `json:"rules"`
+	Passes       int64            `json:"passes"`
+	Errors       int64            `json:"errors"`
+	Moved        int64            `json:"moved_total"`
+	ByTransition map[string]int64 `json:"by_transition"`
+	LastPassAt   time.Time        `json:"last_pass_at"`
+}
+
+// Reprioritizer periodically changes the priority of old pending
+// notifications by its rules, so backlog-draining strategies other than
+// strict priority order (HIGH decaying once stale, LOW boosted before it
+// starves) can be compared under the same load
+type Reprioritizer struct {
+	repo   Repository
+	cfg    ReprioritizeConfig
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	stats ReprioritizeStats
+}
+
+// NewReprioritizer creates a reprioritizer; it does nothing without rules
+func NewReprioritizer(repo Repository, cfg ReprioritizeConfig, logger *zap.Logger) *Reprioritizer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 5000
+	}
+
+	rules := make([]string, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rules[i] = rule.String()
+	}
+	return &Reprioritizer{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		stats: ReprioritizeStats{
+			Interval:     cfg.Interval.String(),
+			Rules:        rules,
+			ByTransition: make(map[string]int64),
+		},
+	}
+}
+
+// Start runs a pass every interval until ctx is done
+func (r *Reprioritizer) Start(ctx context.Context) {
+	if len(r.cfg.Rules) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Run(ctx)
+			}
+		}
+	}()
+
+	r.logger.Info("reprioritizer started",
+		zap.Duration("interval", r.cfg.Interval),
+		zap.Strings("rules", r.stats.Rules))
+}
+
+// Run applies every rule to the notifications old enough now and returns
+// how many moved by transition. Rules run in order, so a chained rule sees
+// what the rule before it just moved.
+func (r *Reprioritizer) Run(ctx context.Context) map[string]int64 {
+	now := time.Now()
+	moved := make(map[string]int64, len(r.cfg.Rules))
+
+	var runErr error
+	for _, rule := range r.cfg.Rules {
+		for {
+			n, err := r.repo.Reprioritize(ctx, rule.From, rule.To, now.Add(-rule.After), r.cfg.BatchSize)
+			if err != nil {
+				runErr = err
+				break
+			}
+			moved[rule.transition()] += int64(n)
+			metrics.NotificationsReprioritized.WithLabelValues(string(rule.From), string(rule.To)).Add(float64(n))
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+		if runErr != nil {
+			break
+		}
+	}
+
+	var total int64
+	r.mu.Lock()
+	r.stats.Passes++
+	if runErr != nil {
+		r.stats.Errors++
+	}
+	for transition, n := range moved {
+		r.stats.ByTransition[transition] += n
+		total += n
+	}
+	r.stats.Moved += total
+	r.stats.LastPassAt = now
+	r.mu.Unlock()
+
+	if runErr != nil {
+		r.logger.Error("reprioritize pass failed", zap.Int64("moved", total), zap.Error(runErr))
+	} else if total > 0 {
+		r.logger.Info("reprioritized pending notifications",
+			zap.Int64("count", total),
+			zap.Any("by_transition", moved),
+			zap.Duration("duration", time.Since(now)))
+	}
+	return moved
+}
+
+// Stats returns lifetime totals
+func (r *Reprioritizer) Stats() ReprioritizeStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := r.stats
+	stats.ByTransition = copyCounts(r.stats.ByTransition)
+	return stats
+}
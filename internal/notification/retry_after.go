@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// Connection rejection reasons, named in the rejection body so clients can
+// tell a full instance from a draining or rate-limited one
+const (
+	RejectCapacity    = "capacity"
+	RejectDraining    = "draining"
+	RejectRateLimited = "rate_limited"
+)
+
+// ErrAtCapacity is returned for new connections while the instance holds
+// its maximum
+var ErrAtCapacity = errors.New("max connections reached")
+
+// Retry hints are kept within these bounds
+const (
+	minRetryAfter = 100 * time.Millisecond
+	maxRetryAfter = time.Minute
+)
+
+// RejectionError refuses a new connection with a hint of when to retry
+type RejectionError struct {
+	Reason     string // RejectCapacity, RejectDraining or RejectRateLimited
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RejectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectionError) Unwrap() error {
+	return e.Err
+}
+
+// writeRejection answers a refused stream with 503 and the retry hint, in
+// the Retry-After header (whole seconds, for generic clients) and precisely
+// in the JSON body
+func writeRejection(c *gin.Context, rejection *RejectionError) {
+	metrics.SSERejections.WithLabelValues(rejection.Reason).Inc()
+	metrics.SSERetryAfterSeconds.WithLabelValues(rejection.Reason).Observe(rejection.RetryAfter.Seconds())
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rejection.RetryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":          rejection.Error(),
+		"reason":         rejection.Reason,
+		"retry_after_ms": rejection.RetryAfter.Milliseconds(),
+	})
+}
+
+// spreadRetryAfter jitters a hint over [d, 1.5d] so clients rejected
+// together don't all come back together, and clamps it
+func spreadRetryAfter(d time.Duration) time.Duration {
+	if d < minRetryAfter {
+		d = minRetryAfter
+	}
+	d += time.Duration(rand.Int63n(int64(d)/2 + 1))
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d
+}
+
+// rejectionWindow estimates how many connections were refused over the last
+// second: the clients about to retry, which a hint has to make room for
+type rejectionWindow struct {
+	mu       sync.Mutex
+	start    time.Time
+	current  int64
+	previous int64
+}
+
+// add counts a rejection and returns the estimate, including it
+func (w *rejectionWindow) add(now time.Time) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch elapsed := now.Sub(w.start); {
+	case elapsed >= 2*time.Second:
+		w.start, w.current, w.previous = now, 0, 0
+	case elapsed >= time.Second:
+		w.start, w.current, w.previous = w.start.Add(time.Second), 0, w.current
+	}
+	w.current++
+
+	// The previous second counts for the part still inside the window
+	overlap := 1 - now.Sub(w.start).Seconds()
+	return float64(w.current) + float64(w.previous)*overlap
+}
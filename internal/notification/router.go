@@ -0,0 +1,530 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/auth"
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/web"
+)
+
+// RouterDeps holds everything the HTTP router serves from
+type RouterDeps struct {
+	SSEManager *SSEManager
+	Admission  *AdmissionController
+	Admin      *AdminHandler
+	Publish    *PublishHandler // Direct publish API; nil disables POST /notifications(/batch)
+	Repository Repository
+	Lag        *LagMonitor    // Consumer group lag for /health; nil leaves it out
+	Health     *HealthChecker // Component checks for /health, /livez and /ready(z); nil always reports ok
+	AuthKey    []byte         // HS256 signing key; nil disables authentication
+	InstanceID string         // Labels every /metrics series (instance_id); empty leaves them unlabeled
+	Logger     *zap.Logger
+}
+
+// NewRouter builds the HTTP router shared by notification-service and all-in-one
+func NewRouter(deps RouterDeps) *gin.Engine {
+	sseManager, admission, admin, repo, logger := deps.SSEManager, deps.Admission, deps.Admin, deps.Repository, deps.Logger
+
+	// User-scoped endpoints require a token whose subject matches user_id
+	userAuth := func(c *gin.Context) { c.Next() }
+	if deps.AuthKey != nil {
+		userAuth = auth.Middleware(deps.AuthKey, logger)
+	}
+	// Admin endpoints require an admin-scoped service token
+	adminAuth := func(c *gin.Context) { c.Next() }
+	if deps.AuthKey != nil {
+		adminAuth = auth.RequireScope(deps.AuthKey, auth.ScopeAdmin, logger)
+	}
+	// Publishing requires a publish-scoped service token, held by producers
+	publishAuth := func(c *gin.Context) { c.Next() }
+	if deps.AuthKey != nil {
+		publishAuth = auth.RequireScope(deps.AuthKey, auth.ScopePublish, logger)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		health := gin.H{
+			"status":             "ok",
+			"active_connections": sseManager.GetActiveConnections(),
+			"admission_queue":    admission.QueueDepth(),
+			"timestamp":          time.Now().Format(time.RFC3339),
+		}
+		if deps.Lag != nil {
+			health["consumer_lag"] = deps.Lag.Report()
+		}
+		code := http.StatusOK
+		if deps.Health != nil {
+			report := deps.Health.Health(c.Request.Context())
+			health["status"] = report.Status
+			health["components"] = report.Components
+			if !report.Healthy {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		c.JSON(code, health)
+	})
+
+	// Liveness for orchestration: workers not stuck. Dependency outages
+	// fail readiness instead, so they don't get the instance restarted.
+	router.GET("/livez", func(c *gin.Context) {
+		if deps.Health == nil {
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			return
+		}
+		report := deps.Health.Live(c.Request.Context())
+		code := http.StatusOK
+		if !report.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, report)
+	})
+
+	// Readiness for orchestration: started, not draining, store reachable
+	ready := func(c *gin.Context) {
+		if deps.Health == nil {
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			return
+		}
+		report := deps.Health.Ready(c.Request.Context())
+		code := http.StatusOK
+		if !report.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, report)
+	}
+	router.GET("/ready", ready)
+	router.GET("/readyz", ready)
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler(deps.InstanceID)))
+	admin.RegisterRoutes(router, adminAuth)
+	web.RegisterRoutes(router)
+
+	router.GET("/notifications/stream", TenantMiddleware(), userAuth, admission.Middleware(), func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Query("user_id")
+		if authUserID := c.GetString(auth.UserIDKey); authUserID != "" {
+			userID = authUserID
+		}
+		if !validUserID(c, userID) {
+			return
+		}
+
+		// ?topics= also subscribes the user to them, so what is published
+		// to them while the user is away is waiting when they reconnect
+		topics, err := ParseTopics(splitList(c.Query("topics")))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(topics) > 0 {
+			if err := repo.Subscribe(c.Request.Context(), tenantID, userID, topics); err != nil {
+				logger.Error("failed to subscribe stream topics", zap.Error(err))
+				respondRepoError(c, err, "failed to subscribe")
+				return
+			}
+		}
+
+		logger.Info("SSE connection request",
+			zap.String("tenant_id", tenantID),
+			zap.String("user_id", userID))
+
+		// Use the built-in StreamToClient method that handles everything
+		sseManager.StreamToClient(c, tenantID, userID)
+	})
+
+	// Heartbeat echo: the client is alive (see SSEManagerConfig.LivenessTimeout)
+	router.POST("/notifications/stream/heartbeat", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Query("user_id")
+		if authUserID := c.GetString(auth.UserIDKey); authUserID != "" {
+			userID = authUserID
+		}
+		if !validUserID(c, userID) {
+			return
+		}
+
+		if err := sseManager.Echo(tenantID, userID, c.Query("connection_id")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// Mid-stream filter update: replaces what a connection receives without
+	// reconnecting (see SSEManager.UpdateFilter)
+	router.PUT("/notifications/stream/filter", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Query("user_id")
+		if authUserID := c.GetString(auth.UserIDKey); authUserID != "" {
+			userID = authUserID
+		}
+		if !validUserID(c, userID) {
+			return
+		}
+
+		var update FilterUpdate
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter, err := sseManager.UpdateFilter(tenantID, userID, c.Query("connection_id"), update)
+		switch {
+		case errors.Is(err, ErrUnknownConnection):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"filter_types":      sortedKeys(filter.Types),
+			"filter_priorities": sortedKeys(filter.Priorities),
+			"filter_topics":     sortedKeys(filter.Topics),
+			"typed_events":      filter.TypedEvents,
+		})
+	})
+
+	if deps.Publish != nil {
+		router.POST("/notifications", TenantMiddleware(), publishAuth, deps.Publish.Publish)
+		router.POST("/notifications/batch", TenantMiddleware(), publishAuth, deps.Publish.PublishBatch)
+	}
+
+	// Debug view of a user's streams on this instance: "why didn't they get it?"
+	router.GET("/connections/:user_id", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		connections := sseManager.UserConnections(tenantID, userID)
+		c.JSON(200, gin.H{
+			"tenant_id":     tenantID,
+			"user_id":       userID,
+			"connected":     len(connections) > 0,
+			"count":         len(connections),
+			"bytes_written": sseManager.UserBytesWritten(tenantID, userID),
+			"connections":   connections,
+		})
+	})
+
+	router.GET("/notifications/:user_id", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		// Archived notifications are left out unless ?include_archived=true
+		includeArchived := c.Query("include_archived") == "true"
+		notifications, err := repo.GetUserNotifications(c.Request.Context(), tenantID, userID, 100, includeArchived)
+		if err != nil {
+			logger.Error("failed to query notifications", zap.Error(err))
+			respondRepoError(c, err, "failed to fetch notifications")
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id":     tenantID,
+			"user_id":       userID,
+			"notifications": notifications,
+			"count":         len(notifications),
+		})
+	})
+
+	// Badge counts: unread notifications overall and per event category
+	router.GET("/notifications/:user_id/count", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		counts, err := repo.GetUnreadCounts(c.Request.Context(), tenantID, userID)
+		if err != nil {
+			logger.Error("failed to count unread notifications", zap.Error(err))
+			respondRepoError(c, err, "failed to count notifications")
+			return
+		}
+
+		var unread int64
+		byCategory := map[string]int64{
+			models.CategoryJobs:        0,
+			models.CategoryConnections: 0,
+			models.CategoryFollowers:   0,
+		}
+		for eventType, n := range counts {
+			unread += n
+			byCategory[models.EventType(eventType).Category()] += n
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id":     tenantID,
+			"user_id":       userID,
+			"unread":        unread,
+			"by_category":   byCategory,
+			"by_event_type": counts,
+		})
+	})
+
+	// Marks the listed notifications read ({"notification_ids": [...]}), or
+	// all of the user's without a body
+	router.POST("/notifications/:user_id/read", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		var body struct {
+			NotificationIDs []uuid.UUID `json:"notification_ids"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		marked, err := repo.MarkRead(c.Request.Context(), tenantID, userID, body.NotificationIDs)
+		if err != nil {
+			logger.Error("failed to mark notifications read", zap.Error(err))
+			respondRepoError(c, err, "failed to mark notifications read")
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id": tenantID,
+			"user_id":   userID,
+			"marked":    marked,
+		})
+	})
+
+	// The user's delivery preferences; locale picks the language titles and
+	// messages are rendered in
+	router.GET("/notifications/:user_id/preferences", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		prefs, err := repo.GetUserPreferences(c.Request.Context(), tenantID, userID)
+		if err != nil {
+			logger.Error("failed to get user preferences", zap.Error(err))
+			respondRepoError(c, err, "failed to get preferences")
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id":   tenantID,
+			"user_id":     userID,
+			"preferences": prefs,
+		})
+	})
+
+	// Replaces the user's preferences ({"locale": "es"}; an empty locale
+	// goes back to the default). This instance renders with them at once,
+	// others within PREFERENCES_CACHE_TTL.
+	router.PUT("/notifications/:user_id/preferences", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		var body UserPreferences
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		body.Locale = strings.TrimSpace(body.Locale)
+		if body.Locale != "" {
+			if err := ValidateLocale(body.Locale); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		prefs, err := repo.SetUserPreferences(c.Request.Context(), tenantID, userID, body)
+		if err != nil {
+			logger.Error("failed to set user preferences", zap.Error(err))
+			respondRepoError(c, err, "failed to set preferences")
+			return
+		}
+		if cache := sseManager.Preferences(); cache != nil {
+			cache.Invalidate(tenantID, userID)
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id":   tenantID,
+			"user_id":     userID,
+			"preferences": prefs,
+		})
+	})
+
+	// The user's topic subscriptions: events published to a topic are
+	// persisted for each of its subscribers (see Audience)
+	router.GET("/notifications/:user_id/subscriptions", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		subscriptions, err := repo.ListSubscriptions(c.Request.Context(), tenantID, userID)
+		if err != nil {
+			logger.Error("failed to list subscriptions", zap.Error(err))
+			respondRepoError(c, err, "failed to list subscriptions")
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id":     tenantID,
+			"user_id":       userID,
+			"subscriptions": subscriptions,
+		})
+	})
+
+	// Subscribes the user to a topic; subscribing again changes nothing
+	router.PUT("/notifications/:user_id/subscriptions/:topic", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		topic := c.Param("topic")
+		if !validUserID(c, userID) {
+			return
+		}
+		if err := ValidateTopic(topic); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := repo.Subscribe(c.Request.Context(), tenantID, userID, []string{topic}); err != nil {
+			logger.Error("failed to subscribe", zap.String("topic", topic), zap.Error(err))
+			respondRepoError(c, err, "failed to subscribe")
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id": tenantID,
+			"user_id":   userID,
+			"topic":     topic,
+			"status":    "subscribed",
+		})
+	})
+
+	// Unsubscribes the user from a topic
+	router.DELETE("/notifications/:user_id/subscriptions/:topic", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		topic := c.Param("topic")
+		if !validUserID(c, userID) {
+			return
+		}
+
+		if err := repo.Unsubscribe(c.Request.Context(), tenantID, userID, topic); err != nil {
+			logger.Warn("failed to unsubscribe", zap.String("topic", topic), zap.Error(err))
+			respondRepoError(c, err, "failed to unsubscribe")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	})
+
+	// Archives one of the user's notifications: it stays on record but
+	// leaves the default listing and the unread counts
+	router.POST("/notifications/:user_id/:notification_id/archive", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		notificationID, ok := userNotificationID(c, userID)
+		if !ok {
+			return
+		}
+
+		if err := repo.ArchiveNotification(c.Request.Context(), tenantID, userID, notificationID); err != nil {
+			logger.Warn("failed to archive notification", zap.String("notification_id", notificationID.String()), zap.Error(err))
+			respondRepoError(c, err, "failed to archive notification")
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"tenant_id":       tenantID,
+			"user_id":         userID,
+			"notification_id": notificationID,
+			"status":          "archived",
+		})
+	})
+
+	// Deletes one of the user's notifications and its delivery history
+	router.DELETE("/notifications/:user_id/:notification_id", TenantMiddleware(), userAuth, func(c *gin.Context) {
+		tenantID := c.GetString(TenantIDKey)
+		userID := c.Param("user_id")
+		notificationID, ok := userNotificationID(c, userID)
+		if !ok {
+			return
+		}
+
+		if err := repo.DeleteNotification(c.Request.Context(), tenantID, userID, notificationID); err != nil {
+			logger.Warn("failed to delete notification", zap.String("notification_id", notificationID.String()), zap.Error(err))
+			respondRepoError(c, err, "failed to delete notification")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	})
+
+	return router
+}
+
+// userNotificationID validates the user ID and parses the :notification_id
+// parameter, answering 400 when either is malformed
+func userNotificationID(c *gin.Context, userID string) (uuid.UUID, bool) {
+	if !validUserID(c, userID) {
+		return uuid.Nil, false
+	}
+	notificationID, err := uuid.Parse(c.Param("notification_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification_id"})
+		return uuid.Nil, false
+	}
+	return notificationID, true
+}
+
+// validUserID rejects malformed user IDs with 400 before they reach the
+// repository or the connection registry
+func validUserID(c *gin.Context, userID string) bool {
+	if err := models.ValidateUserID(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// respondRepoError writes the response for a failed repository call: 404
+// for ErrNotFound, 409 for ErrConflict, 503 with Retry-After for
+// ErrUnavailable and 500 otherwise
+func respondRepoError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": message + ": not found"})
+	case errors.Is(err, ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": message + ": conflict"})
+	case errors.Is(err, ErrUnavailable):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": message + ": storage unavailable"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
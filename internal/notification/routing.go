@@ -0,0 +1,109 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Kafka headers producers set on every event (see producer.newKafkaMessage)
+const (
+	HeaderEventType = "event_type"
+	HeaderPriority  = "priority"
+)
+
+// HeaderRouting decides from Kafka headers alone, before the JSON body is
+// decoded, whether the consumer keeps an event and how urgently it is
+// persisted. Events without both headers are decoded and routed on their
+// body fields instead.
+type HeaderRouting struct {
+	EventTypes map[string]bool // Kept event types or families ("job.*"); empty keeps all
+	Priorities map[string]bool // Kept priorities; empty keeps all
+	Express    map[string]bool // Priorities that flush the insert batch on arrival instead of waiting for it to fill
+}
+
+// ParseHeaderRouting parses comma-separated event types, priorities and
+// express priorities (e.g. "job.*,follower.new", "HIGH,MEDIUM", "HIGH")
+func ParseHeaderRouting(eventTypes, priorities, express string) (HeaderRouting, error) {
+	var routing HeaderRouting
+	var err error
+
+	routing.EventTypes = parseSet(eventTypes, strings.TrimSpace)
+	if routing.Priorities, err = parsePrioritySet(priorities); err != nil {
+		return HeaderRouting{}, err
+	}
+	if routing.Express, err = parsePrioritySet(express); err != nil {
+		return HeaderRouting{}, err
+	}
+	return routing, nil
+}
+
+func parsePrioritySet(raw string) (map[string]bool, error) {
+	set := parseSet(raw, func(s string) string { return strings.ToUpper(strings.TrimSpace(s)) })
+	for priority := range set {
+		switch models.Priority(priority) {
+		case models.PriorityHigh, models.PriorityMedium, models.PriorityLow:
+		default:
+			return nil, fmt.Errorf("invalid priority %q (want HIGH, MEDIUM or LOW)", priority)
+		}
+	}
+	return set, nil
+}
+
+func parseSet(raw string, normalize func(string) string) map[string]bool {
+	var set map[string]bool
+	for _, item := range strings.Split(raw, ",") {
+		if item = normalize(item); item == "" {
+			continue
+		}
+		if set == nil {
+			set = make(map[string]bool)
+		}
+		set[item] = true
+	}
+	return set
+}
+
+// Accepts reports whether an event of this type and priority is kept
+func (r HeaderRouting) Accepts(eventType, priority string) bool {
+	if len(r.Priorities) > 0 && !r.Priorities[priority] {
+		return false
+	}
+	if len(r.EventTypes) == 0 || r.EventTypes[eventType] {
+		return true
+	}
+	dot := strings.LastIndex(eventType, ".")
+	return dot > 0 && r.EventTypes[eventType[:dot]+".*"]
+}
+
+// IsExpress reports whether events of this priority flush the batch at once
+func (r HeaderRouting) IsExpress(priority string) bool {
+	return r.Express[priority]
+}
+
+// headerValue returns the first header with key, or ""
+func headerValue(headers []kafka.Header, key string) string {
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// routingHeaders returns the event type and priority headers; ok is false
+// unless both are present and non-empty
+func routingHeaders(headers []kafka.Header) (eventType, priority string, ok bool) {
+	for _, h := range headers {
+		switch h.Key {
+		case HeaderEventType:
+			eventType = string(h.Value)
+		case HeaderPriority:
+			priority = string(h.Value)
+		}
+	}
+	return eventType, priority, eventType != "" && priority != ""
+}
@@ -0,0 +1,199 @@
+package notification
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// slaPriorities are reported even when nothing was delivered in the window
+var slaPriorities = []string{"HIGH", "MEDIUM", "LOW"}
+
+// SLAObjectives holds, per priority, the percentage of notifications that
+// must be delivered within the SLO target (99 means "p99 ≤ target")
+type SLAObjectives struct {
+	High   float64
+	Medium float64
+	Low    float64
+}
+
+// For returns the objective for the given priority
+func (o SLAObjectives) For(priority string) float64 {
+	switch priority {
+	case "HIGH":
+		return o.High
+	case "LOW":
+		return o.Low
+	default:
+		return o.Medium
+	}
+}
+
+// SLACompliance is the rolling compliance of a single priority
+type SLACompliance struct {
+	TargetSeconds     float64   `json:"target_seconds"`
+	ObjectivePercent  float64   `json:"objective_percent"`
+	Delivered         int64     `json:"delivered"`
+	WithinTarget      int64     `json:"within_target"`
+	CompliancePercent float64   `json:"compliance_percent"`
+	P50Seconds        float64   `json:"p50_seconds"`
+	P95Seconds        float64   `json:"p95_seconds"`
+	P99Seconds        float64   `json:"p99_seconds"`
+	Met               bool      `json:"met"`
+	BreachedSince     time.Time `json:"breached_since"`
+}
+
+// SLAReport is the latest compliance snapshot across priorities
+type SLAReport struct {
+	Window     string                   `json:"window"`
+	ComputedAt time.Time                `json:"computed_at"`
+	Met        bool                     `json:"met"`
+	Priorities map[string]SLACompliance `json:"priorities"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// SLAMonitor periodically computes rolling SLA compliance from delivered_at
+// vs event_timestamp, exports it as metrics and logs breaches
+type SLAMonitor struct {
+	repository Repository
+	targets    SLOTargets
+	objectives SLAObjectives
+	window     time.Duration
+	interval   time.Duration
+	logger     *zap.Logger
+
+	mu     sync.RWMutex
+	report SLAReport
+}
+
+// NewSLAMonitor creates a new SLA monitor
+func NewSLAMonitor(repo Repository, targets SLOTargets, objectives SLAObjectives, window, interval time.Duration, logger *zap.Logger) *SLAMonitor {
+	for _, priority := range slaPriorities {
+		metrics.SLAObjectivePercent.WithLabelValues(priority).Set(objectives.For(priority))
+	}
+
+	return &SLAMonitor{
+		repository: repo,
+		targets:    targets,
+		objectives: objectives,
+		window:     window,
+		interval:   interval,
+		logger:     logger,
+		report:     SLAReport{Window: window.String(), Met: true, Priorities: map[string]SLACompliance{}},
+	}
+}
+
+// Start recomputes compliance every interval until ctx is done
+func (m *SLAMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.evaluate(ctx)
+			}
+		}
+	}()
+
+	m.logger.Info("sla monitor started",
+		zap.Duration("window", m.window),
+		zap.Float64("high_objective", m.objectives.High),
+		zap.Float64("medium_objective", m.objectives.Medium),
+		zap.Float64("low_objective", m.objectives.Low))
+}
+
+// Report returns the latest compliance snapshot
+func (m *SLAMonitor) Report() SLAReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.report
+}
+
+// evaluate computes compliance over the window and updates metrics
+func (m *SLAMonitor) evaluate(ctx context.Context) {
+	now := time.Now()
+	attainment, err := m.repository.GetSLOAttainment(ctx, now.Add(-m.window), m.targets)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Error("failed to compute sla compliance", zap.Error(err))
+			m.mu.Lock()
+			m.report.Error = err.Error()
+			m.mu.Unlock()
+		}
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.report.Priorities
+	report := SLAReport{
+		Window:     m.window.String(),
+		ComputedAt: now,
+		Met:        true,
+		Priorities: make(map[string]SLACompliance, len(slaPriorities)),
+	}
+
+	for _, priority := range slaPriorities {
+		compliance := SLACompliance{
+			TargetSeconds:     m.targets.For(priority).Seconds(),
+			ObjectivePercent:  m.objectives.For(priority),
+			CompliancePercent: 100,
+			Met:               true,
+		}
+
+		if row, ok := attainment[priority].(map[string]interface{}); ok {
+			compliance.Delivered, _ = row["delivered"].(int64)
+			compliance.WithinTarget, _ = row["within_target"].(int64)
+			compliance.P50Seconds, _ = row["p50_seconds"].(float64)
+			compliance.P95Seconds, _ = row["p95_seconds"].(float64)
+			compliance.P99Seconds, _ = row["p99_seconds"].(float64)
+		}
+
+		// An empty window is vacuously compliant
+		if compliance.Delivered > 0 {
+			compliance.CompliancePercent = float64(compliance.WithinTarget) / float64(compliance.Delivered) * 100
+			compliance.Met = compliance.CompliancePercent >= compliance.ObjectivePercent
+			metrics.SLACompliancePercent.WithLabelValues(priority).Set(compliance.CompliancePercent)
+		}
+
+		prev, seen := previous[priority]
+		switch {
+		case !compliance.Met && (!seen || prev.Met):
+			compliance.BreachedSince = now
+			metrics.SLABreaches.WithLabelValues(priority).Inc()
+			m.logger.Warn("sla breached",
+				zap.String("priority", priority),
+				zap.Float64("compliance_percent", compliance.CompliancePercent),
+				zap.Float64("objective_percent", compliance.ObjectivePercent),
+				zap.Float64("target_seconds", compliance.TargetSeconds),
+				zap.Int64("delivered", compliance.Delivered))
+		case !compliance.Met:
+			compliance.BreachedSince = prev.BreachedSince
+		case seen && !prev.Met:
+			m.logger.Info("sla recovered",
+				zap.String("priority", priority),
+				zap.Float64("compliance_percent", compliance.CompliancePercent),
+				zap.Duration("breached_for", now.Sub(prev.BreachedSince)))
+		}
+
+		if compliance.Met {
+			metrics.SLAMet.WithLabelValues(priority).Set(1)
+		} else {
+			metrics.SLAMet.WithLabelValues(priority).Set(0)
+			report.Met = false
+		}
+
+		report.Priorities[priority] = compliance
+	}
+
+	m.report = report
+}
@@ -0,0 +1,327 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// deliverySnapshotVersion is bumped whenever DeliverySnapshot changes
+// incompatibly; snapshots from another version are ignored
+const deliverySnapshotVersion = 1
+
+// SnapshotStore persists one delivery snapshot per instance.
+// *cache.RedisCache is the shared implementation.
+type SnapshotStore interface {
+	SaveSnapshot(ctx context.Context, instanceID string, data []byte) error
+	// LoadSnapshot returns nil data when the instance has no snapshot
+	LoadSnapshot(ctx context.Context, instanceID string) ([]byte, error)
+}
+
+// FileSnapshotStore keeps the snapshot in a local JSON file
+type FileSnapshotStore struct {
+	Path string
+}
+
+// SaveSnapshot replaces the file atomically, so a crash mid-write keeps the
+// previous snapshot
+func (s FileSnapshotStore) SaveSnapshot(ctx context.Context, instanceID string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads the file, if it exists
+func (s FileSnapshotStore) LoadSnapshot(ctx context.Context, instanceID string) ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	return data, nil
+}
+
+// DeliverySnapshot is the in-memory delivery state carried across a restart:
+// the counters behind the run summary and, for the in-memory repository, the
+// notifications themselves. Postgres deployments keep notifications durable
+// already, so only the counters are snapshotted there.
+type DeliverySnapshot struct {
+	Version      int       `json:"version"`
+	InstanceID   string    `json:"instance_id"`
+	TakenAt      time.Time `json:"taken_at"`
+	RunStartedAt time.Time `json:"run_started_at"` // Start of the run the snapshot belongs to
+
+	Picker        PickerCounters   `json:"picker"`
+	SSE           SSECounters      `json:"sse"`
+	Notifications []SnapshotRecord `json:"notifications,omitempty"`
+}
+
+// PickerCounters are the task picker's lifetime totals
+type PickerCounters struct {
+	Claimed       int64     `json:"claimed"`
+	Delivered     int64     `json:"delivered"`
+	Failed        int64     `json:"failed"`
+	Parked        int64     `json:"parked"`
+	Unparked      int64     `json:"unparked"`
+	PeakBacklog   int64     `json:"peak_backlog"`
+	PeakBacklogAt time.Time `json:"peak_backlog_at"`
+}
+
+// SSECounters are the SSE manager's connection and bandwidth totals
+type SSECounters struct {
+	TotalAccepted   int64            `json:"total_accepted"`
+	TotalRejected   int64            `json:"total_rejected"`
+	PeakConnections int64            `json:"peak_connections"`
+	BytesWritten    int64            `json:"bytes_written"`
+	UserBytes       map[string]int64 `json:"user_bytes,omitempty"` // connectionKey → bytes
+}
+
+// SnapshotRecord is a MemoryRepository row
+type SnapshotRecord struct {
+	Notification models.Notification `json:"notification"`
+	Payload      string              `json:"payload"`
+	Status       string              `json:"status"`
+	DeliveredAt  time.Time           `json:"delivered_at"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+	Attempts     []*StatusUpdate     `json:"attempts,omitempty"`
+}
+
+// SaveDeliverySnapshot snapshots the delivery state to store. Call it after
+// the picker has stopped so in-flight status updates are included; memRepo
+// is nil unless the in-memory repository is in use.
+func SaveDeliverySnapshot(ctx context.Context, store SnapshotStore, instanceID string, runStartedAt time.Time, taskPicker *TaskPicker, sseManager *SSEManager, memRepo *MemoryRepository) (*DeliverySnapshot, error) {
+	snapshot := &DeliverySnapshot{
+		Version:      deliverySnapshotVersion,
+		InstanceID:   instanceID,
+		TakenAt:      time.Now(),
+		RunStartedAt: runStartedAt,
+		Picker:       taskPicker.counters(),
+		SSE:          sseManager.counters(),
+	}
+	if memRepo != nil {
+		snapshot.Notifications = memRepo.Snapshot()
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal delivery snapshot: %w", err)
+	}
+	if err := store.SaveSnapshot(ctx, instanceID, data); err != nil {
+		return nil, fmt.Errorf("failed to save delivery snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// RestoreDeliverySnapshot loads the instance's snapshot from store and
+// restores it. Call it before the picker starts. Returns nil when there is
+// no usable snapshot.
+func RestoreDeliverySnapshot(ctx context.Context, store SnapshotStore, instanceID string, taskPicker *TaskPicker, sseManager *SSEManager, memRepo *MemoryRepository) (*DeliverySnapshot, error) {
+	data, err := store.LoadSnapshot(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery snapshot: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var snapshot DeliverySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery snapshot: %w", err)
+	}
+	if snapshot.Version != deliverySnapshotVersion {
+		return nil, fmt.Errorf("unsupported delivery snapshot version %d (want %d)", snapshot.Version, deliverySnapshotVersion)
+	}
+
+	taskPicker.restoreCounters(snapshot.Picker)
+	sseManager.restoreCounters(snapshot.SSE)
+	if memRepo != nil {
+		memRepo.Restore(snapshot.Notifications)
+	}
+	return &snapshot, nil
+}
+
+// RestoreRunState restores the delivery snapshot when a store is
+// configured and returns when the run started: the snapshot's run start, or
+// now for a fresh run. Failures are logged; the run starts fresh.
+func RestoreRunState(ctx context.Context, store SnapshotStore, instanceID string, taskPicker *TaskPicker, sseManager *SSEManager, memRepo *MemoryRepository, logger *zap.Logger) time.Time {
+	startedAt := time.Now()
+	if store == nil {
+		return startedAt
+	}
+
+	snapshot, err := RestoreDeliverySnapshot(ctx, store, instanceID, taskPicker, sseManager, memRepo)
+	if err != nil {
+		logger.Error("failed to restore delivery snapshot, starting fresh", zap.Error(err))
+		return startedAt
+	}
+	if snapshot == nil {
+		logger.Info("no delivery snapshot, starting fresh")
+		return startedAt
+	}
+
+	logger.Info("delivery snapshot restored",
+		zap.Time("taken_at", snapshot.TakenAt),
+		zap.Time("run_started_at", snapshot.RunStartedAt),
+		zap.Int("notifications", len(snapshot.Notifications)),
+		zap.Int64("delivered", snapshot.Picker.Delivered))
+	return snapshot.RunStartedAt
+}
+
+// SaveRunState saves the delivery snapshot when a store is configured.
+// Failures are logged; shutdown carries on.
+func SaveRunState(ctx context.Context, store SnapshotStore, instanceID string, runStartedAt time.Time, taskPicker *TaskPicker, sseManager *SSEManager, memRepo *MemoryRepository, logger *zap.Logger) {
+	if store == nil {
+		return
+	}
+
+	snapshot, err := SaveDeliverySnapshot(ctx, store, instanceID, runStartedAt, taskPicker, sseManager, memRepo)
+	if err != nil {
+		logger.Error("failed to save delivery snapshot", zap.Error(err))
+		return
+	}
+	logger.Info("delivery snapshot saved",
+		zap.Int("notifications", len(snapshot.Notifications)),
+		zap.Int("users", len(snapshot.SSE.UserBytes)))
+}
+
+// counters returns the picker's lifetime totals
+func (tp *TaskPicker) counters() PickerCounters {
+	peak, peakAt := tp.PeakBacklog()
+	return PickerCounters{
+		Claimed:       atomic.LoadInt64(&tp.claimedTotal),
+		Delivered:     atomic.LoadInt64(&tp.deliveredTotal),
+		Failed:        atomic.LoadInt64(&tp.failedTotal),
+		Parked:        atomic.LoadInt64(&tp.parkedTotal),
+		Unparked:      atomic.LoadInt64(&tp.unparkedTotal),
+		PeakBacklog:   peak,
+		PeakBacklogAt: peakAt,
+	}
+}
+
+// restoreCounters adds a previous process's totals to the picker's own
+func (tp *TaskPicker) restoreCounters(c PickerCounters) {
+	atomic.AddInt64(&tp.claimedTotal, c.Claimed)
+	atomic.AddInt64(&tp.deliveredTotal, c.Delivered)
+	atomic.AddInt64(&tp.failedTotal, c.Failed)
+	atomic.AddInt64(&tp.parkedTotal, c.Parked)
+	atomic.AddInt64(&tp.unparkedTotal, c.Unparked)
+
+	tp.historyMu.Lock()
+	defer tp.historyMu.Unlock()
+	if c.PeakBacklog > tp.peakBacklog || tp.peakBacklogAt.IsZero() {
+		tp.peakBacklog, tp.peakBacklogAt = c.PeakBacklog, c.PeakBacklogAt
+	}
+}
+
+// counters returns the connection and bandwidth totals
+func (m *SSEManager) counters() SSECounters {
+	m.mu.RLock()
+	c := SSECounters{
+		TotalAccepted:   m.totalAccepted,
+		TotalRejected:   m.totalRejected,
+		PeakConnections: m.peakConns,
+	}
+	m.mu.RUnlock()
+
+	c.BytesWritten = atomic.LoadInt64(&m.bytesWritten)
+	c.UserBytes = make(map[string]int64)
+	m.userBytes.Range(func(key, value any) bool {
+		c.UserBytes[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return c
+}
+
+// restoreCounters adds a previous process's totals to the manager's own
+func (m *SSEManager) restoreCounters(c SSECounters) {
+	m.mu.Lock()
+	m.totalAccepted += c.TotalAccepted
+	m.totalRejected += c.TotalRejected
+	m.peakConns = max(m.peakConns, c.PeakConnections)
+	m.mu.Unlock()
+
+	atomic.AddInt64(&m.bytesWritten, c.BytesWritten)
+	for key, n := range c.UserBytes {
+		counter, _ := m.userBytes.LoadOrStore(key, new(int64))
+		atomic.AddInt64(counter.(*int64), n)
+	}
+}
+
+// Snapshot returns every row for a DeliverySnapshot
+func (r *MemoryRepository) Snapshot() []SnapshotRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := make([]SnapshotRecord, 0, len(r.records))
+	for _, rec := range r.records {
+		records = append(records, SnapshotRecord{
+			Notification: rec.notif,
+			Payload:      rec.payload,
+			Status:       rec.status,
+			DeliveredAt:  rec.deliveredAt,
+			ErrorMessage: rec.errorMessage,
+			Attempts:     rec.attempts,
+		})
+	}
+	return records
+}
+
+// Restore loads rows from a DeliverySnapshot, skipping any already present.
+// Claims belonged to the previous process, so claimed rows are pending again.
+func (r *MemoryRepository) Restore(records []SnapshotRecord) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	restored := 0
+	for _, snap := range records {
+		if _, exists := r.records[snap.Notification.NotificationID]; exists {
+			continue
+		}
+
+		rec := &memoryRecord{
+			notif:        snap.Notification,
+			payload:      snap.Payload,
+			status:       snap.Status,
+			deliveredAt:  snap.DeliveredAt,
+			errorMessage: snap.ErrorMessage,
+			attempts:     snap.Attempts,
+		}
+		if rec.status == "claimed" {
+			rec.status = "not_pushed"
+			rec.notif.RetryCount++
+		}
+		r.records[rec.notif.NotificationID] = rec
+		restored++
+	}
+
+	r.logger.Info("restored notifications from snapshot", zap.Int("count", restored))
+	return restored
+}
@@ -0,0 +1,349 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// ErrSpillFull means the spill buffer is at its size limit
+var ErrSpillFull = errors.New("spill buffer full")
+
+const (
+	spillSegmentBytes    = 16 << 20 // A segment is closed for appends past this size
+	spillReplayInterval  = time.Second
+	spillReplayBatchSize = 100
+)
+
+// spillSegment is one file of the spill buffer
+type spillSegment struct {
+	path  string
+	bytes int64
+	count int64
+}
+
+// SpillBuffer holds notifications on disk while the store is unavailable so
+// the consumer can keep reading (and committing) past its memory limit.
+// Notifications are appended as JSON lines to segment files in dir and
+// fsynced before Append returns; Replay inserts them oldest segment first
+// once the store is back and deletes each segment when it is done. Segments
+// left by a previous run are replayed too.
+type SpillBuffer struct {
+	dir      string
+	maxBytes int64
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	segments []*spillSegment // Oldest first; the last takes appends while file is open
+	file     *os.File
+	nextSeq  int64
+	bytes    int64
+	count    int64
+
+	// Lifetime counters (atomic)
+	spilled  int64
+	replayed int64
+	dropped  int64 // Rejected by the store for good (not unavailable, not duplicates)
+}
+
+// NewSpillBuffer opens a spill buffer in dir, creating it if needed, that
+// holds up to maxBytes
+func NewSpillBuffer(dir string, maxBytes int64, logger *zap.Logger) (*SpillBuffer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill dir: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "spill-*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spill segments: %w", err)
+	}
+	sort.Strings(paths) // Zero-padded sequence numbers sort in order
+
+	b := &SpillBuffer{dir: dir, maxBytes: maxBytes, logger: logger}
+	for _, path := range paths {
+		segment, err := scanSpillSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		b.segments = append(b.segments, segment)
+		b.bytes += segment.bytes
+		b.count += segment.count
+
+		var seq int64
+		fmt.Sscanf(filepath.Base(path), "spill-%d.jsonl", &seq)
+		b.nextSeq = max(b.nextSeq, seq+1)
+	}
+	b.report()
+	if b.count > 0 {
+		logger.Warn("replaying notifications spilled by a previous run",
+			zap.String("dir", dir),
+			zap.Int("segments", len(b.segments)),
+			zap.Int64("notifications", b.count))
+	}
+	return b, nil
+}
+
+// scanSpillSegment sizes a segment left on disk
+func scanSpillSegment(path string) (*spillSegment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill segment: %w", err)
+	}
+	defer f.Close()
+
+	segment := &spillSegment{path: path}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, spillSegmentBytes)
+	for scanner.Scan() {
+		segment.bytes += int64(len(scanner.Bytes())) + 1
+		segment.count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spill segment %s: %w", path, err)
+	}
+	return segment, nil
+}
+
+// Append writes notifications to disk, returning once they are synced.
+// It returns ErrSpillFull if they would take the buffer past its limit.
+func (b *SpillBuffer) Append(notifications []*models.Notification) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf) // One object per line
+	for _, notif := range notifications {
+		if err := enc.Encode(notif); err != nil {
+			return fmt.Errorf("failed to encode spilled notification: %w", err)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.bytes+int64(buf.Len()) > b.maxBytes {
+		return ErrSpillFull
+	}
+	if b.file == nil || b.segments[len(b.segments)-1].bytes >= spillSegmentBytes {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := b.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spill segment: %w", err)
+	}
+	if err := b.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spill segment: %w", err)
+	}
+
+	segment := b.segments[len(b.segments)-1]
+	segment.bytes += int64(buf.Len())
+	segment.count += int64(len(notifications))
+	b.bytes += int64(buf.Len())
+	b.count += int64(len(notifications))
+	atomic.AddInt64(&b.spilled, int64(len(notifications)))
+	metrics.ConsumerSpill.WithLabelValues("spilled").Add(float64(len(notifications)))
+	b.report()
+	return nil
+}
+
+// rotate closes the segment taking appends and opens a new one. Caller
+// holds b.mu.
+func (b *SpillBuffer) rotate() error {
+	b.closeFile()
+	path := filepath.Join(b.dir, fmt.Sprintf("spill-%020d.jsonl", b.nextSeq))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spill segment: %w", err)
+	}
+	b.nextSeq++
+	b.file = f
+	b.segments = append(b.segments, &spillSegment{path: path})
+	return nil
+}
+
+// closeFile stops appends to the newest segment. Caller holds b.mu.
+func (b *SpillBuffer) closeFile() {
+	if b.file == nil {
+		return
+	}
+	if err := b.file.Close(); err != nil {
+		b.logger.Error("failed to close spill segment", zap.Error(err))
+	}
+	b.file = nil
+}
+
+// oldest returns the oldest segment, closing it for appends first
+func (b *SpillBuffer) oldest() *spillSegment {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.segments) == 0 {
+		return nil
+	}
+	if len(b.segments) == 1 {
+		b.closeFile()
+	}
+	return b.segments[0]
+}
+
+// Replay inserts spilled notifications into repo until ctx is done
+func (b *SpillBuffer) Replay(ctx context.Context, repo Repository) {
+	ticker := time.NewTicker(spillReplayInterval)
+	defer ticker.Stop()
+	defer func() {
+		b.mu.Lock()
+		b.closeFile()
+		b.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain segment after segment while the store takes them
+			for segment := b.oldest(); segment != nil; segment = b.oldest() {
+				if !b.replaySegment(ctx, repo, segment) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// replaySegment inserts a segment's notifications and deletes it. It
+// returns false, leaving the segment, when the store is unavailable;
+// notifications inserted before that are skipped as duplicates next time.
+func (b *SpillBuffer) replaySegment(ctx context.Context, repo Repository, segment *spillSegment) bool {
+	data, err := os.ReadFile(segment.path)
+	if err != nil {
+		b.logger.Error("failed to read spill segment", zap.String("path", segment.path), zap.Error(err))
+		return false
+	}
+
+	var batch []*models.Notification
+	var replayed, dropped int64
+	for line := range bytes.Lines(data) {
+		var notif models.Notification
+		if err := json.Unmarshal(line, &notif); err != nil {
+			// A torn write from a crash; its offsets were never committed
+			b.logger.Warn("skipping unreadable spilled notification", zap.String("path", segment.path), zap.Error(err))
+			continue
+		}
+		batch = append(batch, &notif)
+		if len(batch) < spillReplayBatchSize {
+			continue
+		}
+		ok, d := b.insert(ctx, repo, batch)
+		if !ok {
+			return false
+		}
+		replayed, dropped = replayed+int64(len(batch))-d, dropped+d
+		batch = batch[:0]
+	}
+	if len(batch) > 0 {
+		ok, d := b.insert(ctx, repo, batch)
+		if !ok {
+			return false
+		}
+		replayed, dropped = replayed+int64(len(batch))-d, dropped+d
+	}
+
+	if err := os.Remove(segment.path); err != nil {
+		b.logger.Error("failed to remove replayed spill segment", zap.String("path", segment.path), zap.Error(err))
+		return false
+	}
+
+	b.mu.Lock()
+	b.segments = b.segments[1:]
+	b.bytes -= segment.bytes
+	b.count -= segment.count
+	b.report()
+	b.mu.Unlock()
+
+	atomic.AddInt64(&b.replayed, replayed)
+	atomic.AddInt64(&b.dropped, dropped)
+	metrics.ConsumerSpill.WithLabelValues("replayed").Add(float64(replayed))
+	metrics.ConsumerSpill.WithLabelValues("dropped").Add(float64(dropped))
+	b.logger.Info("replayed spilled notifications",
+		zap.Int64("replayed", replayed),
+		zap.Int64("dropped", dropped),
+		zap.Int64("remaining", b.Stats().Notifications))
+	return true
+}
+
+// insert writes a replay batch, falling back to one insert per notification
+// when the batch fails, so duplicates from an earlier partial replay are
+// skipped. It returns false when the store is unavailable, and the number
+// of notifications the store rejected.
+func (b *SpillBuffer) insert(ctx context.Context, repo Repository, batch []*models.Notification) (bool, int64) {
+	err := repo.BatchInsert(ctx, batch)
+	if err == nil {
+		return true, 0
+	}
+	if errors.Is(err, ErrUnavailable) || ctx.Err() != nil {
+		return false, 0
+	}
+
+	var dropped int64
+	for _, notif := range batch {
+		err := repo.Insert(ctx, notif)
+		switch {
+		case err == nil, errors.Is(err, ErrConflict):
+		case errors.Is(err, ErrUnavailable) || ctx.Err() != nil:
+			return false, 0
+		default:
+			dropped++
+			b.logger.Error("failed to insert spilled notification",
+				zap.String("notification_id", notif.NotificationID.String()),
+				zap.Error(err))
+		}
+	}
+	return true, dropped
+}
+
+// report publishes the buffer depth. Caller holds b.mu.
+func (b *SpillBuffer) report() {
+	metrics.ConsumerSpillDepth.Set(float64(b.count))
+	metrics.ConsumerSpillBytes.Set(float64(b.bytes))
+}
+
+// SpillStats is a point-in-time view of the spill buffer
+type SpillStats struct {
+	Dir           string `json:"dir"`
+	Segments      int    `json:"segments"`
+	Notifications int64  `json:"notifications"`
+	Bytes         int64  `json:"bytes"`
+	MaxBytes      int64  `json:"max_bytes"`
+	Spilled       int64  `json:"spilled_total"`
+	Replayed      int64  `json:"replayed_total"`
+	Dropped       int64  `json:"dropped_total"`
+}
+
+// Stats returns the buffer's depth and lifetime counters
+func (b *SpillBuffer) Stats() SpillStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return SpillStats{
+		Dir:           b.dir,
+		Segments:      len(b.segments),
+		Notifications: b.count,
+		Bytes:         b.bytes,
+		MaxBytes:      b.maxBytes,
+		Spilled:       atomic.LoadInt64(&b.spilled),
+		Replayed:      atomic.LoadInt64(&b.replayed),
+		Dropped:       atomic.LoadInt64(&b.dropped),
+	}
+}
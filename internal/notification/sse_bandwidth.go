@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"notification-delivery-system/internal/models"
+)
+
+// bandwidthRetryDelay is how long notifications deferred by a bandwidth cap
+// stay parked before they are claimed again
+const bandwidthRetryDelay = time.Second
+
+// errThrottled marks a notification deferred because every connection that
+// would have taken it was over its bandwidth cap
+var errThrottled = errors.New("deferred: connection over its bandwidth cap")
+
+// bandwidthBucket is a per-connection token bucket in bytes, holding at most
+// one second's allowance. Charges may overdraw it: sends that are never
+// deferred still count against the ones that can be.
+type bandwidthBucket struct {
+	mu     sync.Mutex
+	rate   float64 // Bytes per second
+	tokens float64
+	last   time.Time
+}
+
+func newBandwidthBucket(bytesPerSecond int, now time.Time) *bandwidthBucket {
+	return &bandwidthBucket{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   now,
+	}
+}
+
+// Exhausted reports whether the allowance is used up
+func (b *bandwidthBucket) Exhausted(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens <= 0
+}
+
+// Charge deducts bytes written to the client
+func (b *bandwidthBucket) Charge(now time.Time, n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.tokens -= float64(n)
+}
+
+func (b *bandwidthBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.rate, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// deferrable reports whether a bandwidth cap may hold back a notification
+func deferrable(n *NotificationBatch) bool {
+	return n.Priority == string(models.PriorityLow)
+}
@@ -0,0 +1,181 @@
+package notification
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// Content encodings the SSE stream can be compressed with
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// ParseSSECompression parses a comma-separated list of encodings in server
+// preference order (e.g. "br,gzip"); empty disables compression
+func ParseSSECompression(raw string) ([]string, error) {
+	var encodings []string
+	for _, enc := range strings.Split(raw, ",") {
+		enc = strings.ToLower(strings.TrimSpace(enc))
+		switch enc {
+		case "":
+		case EncodingBrotli, EncodingGzip:
+			encodings = append(encodings, enc)
+		default:
+			return nil, fmt.Errorf("unsupported sse compression %q (want br or gzip)", enc)
+		}
+	}
+	return encodings, nil
+}
+
+// negotiateEncoding picks the first allowed encoding the client accepts with
+// a non-zero q value, or "" for identity
+func negotiateEncoding(acceptEncoding string, allowed []string) string {
+	if len(allowed) == 0 || acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+
+	for _, enc := range allowed {
+		if accepted[enc] {
+			return enc
+		}
+	}
+	return ""
+}
+
+// flushWriteCloser is a streaming compressor that can emit everything
+// written so far without ending the stream
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// sseWriter writes SSE frames to a client through the negotiated
+// compressor. Frames are buffered until Flush, which writes them in one
+// call and flushes the response, so several frames cost one syscall.
+type sseWriter struct {
+	w        gin.ResponseWriter
+	enc      flushWriteCloser // nil for identity
+	encoding string
+	pending  []byte
+	frames   int // Frames in pending
+
+	// Compression accounting, see metrics.SSECompressionBytes
+	bytesIn, bytesOut prometheus.Counter
+	written           int64 // Response size at the last Flush
+}
+
+// newSSEWriter negotiates compression from Accept-Encoding and sets the
+// response headers accordingly. Call before anything is written.
+func newSSEWriter(c *gin.Context, allowed []string) *sseWriter {
+	sw := &sseWriter{w: c.Writer}
+	if len(allowed) == 0 {
+		return sw
+	}
+
+	// Responses differ by Accept-Encoding whenever compression is enabled
+	c.Header("Vary", "Accept-Encoding")
+
+	switch sw.encoding = negotiateEncoding(c.GetHeader("Accept-Encoding"), allowed); sw.encoding {
+	case EncodingBrotli:
+		sw.enc = brotli.NewWriterLevel(c.Writer, brotli.DefaultCompression)
+	case EncodingGzip:
+		sw.enc = gzip.NewWriter(c.Writer)
+	}
+
+	if sw.enc != nil {
+		c.Header("Content-Encoding", sw.encoding)
+		metrics.SSECompressedStreams.WithLabelValues(sw.encoding).Inc()
+		sw.bytesIn = metrics.SSECompressionBytes.WithLabelValues(sw.encoding, "in")
+		sw.bytesOut = metrics.SSECompressionBytes.WithLabelValues(sw.encoding, "out")
+	} else {
+		metrics.SSECompressedStreams.WithLabelValues("identity").Inc()
+	}
+	return sw
+}
+
+// Buffer appends one complete SSE frame for the next Flush
+func (sw *sseWriter) Buffer(frame []byte) {
+	sw.pending = append(sw.pending, frame...)
+	sw.frames++
+}
+
+// Pending returns the number of buffered frames
+func (sw *sseWriter) Pending() int {
+	return sw.frames
+}
+
+// Flush writes the buffered frames and flushes them to the client
+func (sw *sseWriter) Flush() error {
+	if sw.frames == 0 {
+		return nil
+	}
+	defer func() {
+		sw.pending = sw.pending[:0]
+		sw.frames = 0
+	}()
+
+	if sw.enc == nil {
+		if _, err := sw.w.Write(sw.pending); err != nil {
+			return err
+		}
+		sw.w.Flush()
+		return nil
+	}
+
+	if _, err := sw.enc.Write(sw.pending); err != nil {
+		return err
+	}
+	if err := sw.enc.Flush(); err != nil {
+		return err
+	}
+	sw.w.Flush()
+
+	// Counted per flush, as the compressor may hold bytes until then
+	written := sw.Written()
+	sw.bytesIn.Add(float64(len(sw.pending)))
+	sw.bytesOut.Add(float64(written - sw.written))
+	sw.written = written
+	return nil
+}
+
+// Written returns the bytes written to the response so far, after compression
+func (sw *sseWriter) Written() int64 {
+	return int64(max(sw.w.Size(), 0))
+}
+
+// WriteFrame writes one complete SSE frame, with anything buffered before
+// it, and flushes it to the client
+func (sw *sseWriter) WriteFrame(frame []byte) error {
+	sw.Buffer(frame)
+	return sw.Flush()
+}
+
+// Close ends the compressed stream, if any
+func (sw *sseWriter) Close() error {
+	if sw.enc == nil {
+		return nil
+	}
+	return sw.enc.Close()
+}
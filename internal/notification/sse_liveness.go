@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// With a liveness timeout, LastPing follows the client instead of the
+// server's writes: clients echo heartbeats (POST
+// /notifications/stream/heartbeat) and only echoes refresh it. An open
+// connection that hasn't echoed within the timeout is silent: deliveries skip
+// it, so a user whose connections are all silent is offline and their
+// notifications are parked until an echo brings them back.
+
+// ErrUnknownConnection is returned for an echo or filter update naming no
+// open connection
+var ErrUnknownConnection = errors.New("unknown connection")
+
+// LastPing is the last sign of life: a client echo with a liveness timeout,
+// otherwise the last frame written
+func (conn *SSEConnection) LastPing() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&conn.lastPing))
+}
+
+func (conn *SSEConnection) touch(now time.Time) {
+	atomic.StoreInt64(&conn.lastPing, now.UnixNano())
+}
+
+// wrote refreshes LastPing after a server write, unless clients must echo
+func (m *SSEManager) wrote(conn *SSEConnection) {
+	if m.livenessTimeout == 0 {
+		conn.touch(time.Now())
+	}
+}
+
+// silent reports whether conn's client has missed its echoes; never true
+// without a liveness timeout
+func (m *SSEManager) silent(conn *SSEConnection, now time.Time) bool {
+	return m.livenessTimeout > 0 && now.Sub(conn.LastPing()) > m.livenessTimeout
+}
+
+// liveConnections returns a tenant's user's connections that are not
+// silent, and how many were
+func (m *SSEManager) liveConnections(tenantID, userID string) (live []*SSEConnection, silent int) {
+	m.mu.RLock()
+	connections := m.connections[connectionKey(tenantID, userID)]
+	m.mu.RUnlock()
+
+	if m.livenessTimeout == 0 {
+		return connections, 0
+	}
+	now := time.Now()
+	live = make([]*SSEConnection, 0, len(connections))
+	for _, conn := range connections {
+		if m.silent(conn, now) {
+			silent++
+			continue
+		}
+		live = append(live, conn)
+	}
+	return live, silent
+}
+
+// sendable returns the connections a delivery goes to, counting silent ones
+// it skips, or ErrNoConnection when there are none
+func (m *SSEManager) sendable(tenantID, userID string) ([]*SSEConnection, error) {
+	connections, silent := m.liveConnections(tenantID, userID)
+	if silent > 0 {
+		metrics.SSESilentSkipped.Add(float64(silent))
+	}
+	if len(connections) == 0 {
+		return nil, fmt.Errorf("%w for user: %s", ErrNoConnection, userID)
+	}
+	return connections, nil
+}
+
+// Echo records a client's heartbeat echo on one of a tenant's user's
+// connections, or all of them when connectionID is empty. A user whose
+// connections were all silent comes back online, releasing their parked
+// notifications.
+func (m *SSEManager) Echo(tenantID, userID, connectionID string) error {
+	tenantID = models.TenantOrDefault(tenantID)
+	now := time.Now()
+
+	m.mu.RLock()
+	connections := m.connections[connectionKey(tenantID, userID)]
+	wasOnline, found := false, false
+	for _, conn := range connections {
+		if !m.silent(conn, now) {
+			wasOnline = true
+		}
+	}
+	for _, conn := range connections {
+		if connectionID == "" || conn.ID == connectionID {
+			conn.touch(now)
+			found = true
+		}
+	}
+	onUserConnected := m.onUserConnected
+	m.mu.RUnlock()
+
+	if !found {
+		return fmt.Errorf("%w %q for user: %s", ErrUnknownConnection, connectionID, userID)
+	}
+	atomic.AddInt64(&m.echoes, 1)
+	metrics.SSEClientEchoes.Inc()
+
+	if !wasOnline && onUserConnected != nil {
+		onUserConnected(tenantID, userID)
+	}
+	return nil
+}
+
+// ConnectedUsers returns the users with a live (not silent) connection on
+// this instance
+func (m *SSEManager) ConnectedUsers() []UserRef {
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]UserRef, 0, len(m.connections))
+	for _, connections := range m.connections {
+		for _, conn := range connections {
+			if !m.silent(conn, now) {
+				users = append(users, UserRef{TenantID: conn.TenantID, UserID: conn.UserID})
+				break
+			}
+		}
+	}
+	return users
+}
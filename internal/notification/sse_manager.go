@@ -1,50 +1,508 @@
 package notification
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"notification-delivery-system/internal/idgen"
+	"notification-delivery-system/internal/metrics"
 	"notification-delivery-system/internal/models"
 )
 
 // SSEConnection represents a client SSE connection
 type SSEConnection struct {
-	UserID     string
-	ClientChan chan []byte
-	LastPing   time.Time
+	ID          string // Named in the connected frame; clients echo heartbeats and update filters with it
+	TenantID    string
+	UserID      string
+	ClientChan  chan []byte
+	ConnectedAt time.Time
+
+	filter   atomic.Pointer[StreamFilter] // Replaced whole by UpdateFilter, see Filter
+	lastPing int64                        // UnixNano, see LastPing (atomic)
+
+	// Delivery counters (atomic)
+	sent     int64 // Notification frames written to the client
+	dropped  int64 // Frames skipped because ClientChan was full
+	bytes    int64 // Bytes written to the client, after compression
+	deferred int64 // Notifications held back by the bandwidth cap
+
+	bandwidth *bandwidthBucket // nil when uncapped
+	region    *simRegion       // nil for local users
+	reconnect chan reconnectRequest
+}
+
+// reconnectRequest asks one stream to send a reconnect event and end
+type reconnectRequest struct {
+	reason string
+	after  time.Duration
+}
+
+// Reconnect event reasons
+const (
+	ReconnectDraining = "draining" // Instance shutting down
+	ReconnectShed     = "shed"     // Load shed via /admin/connections/reconnect
+)
+
+// ConnectionInfo describes one open SSE connection
+type ConnectionInfo struct {
+	ID               string    `json:"connection_id"`
+	TenantID         string    `json:"tenant_id"`
+	UserID           string    `json:"user_id"`
+	ConnectedAt      time.Time `json:"connected_at"`
+	AgeSeconds       float64   `json:"age_seconds"`
+	LastPing         time.Time `json:"last_ping"`
+	Silent           bool      `json:"silent,omitempty"` // Client stopped echoing heartbeats; treated as offline
+	MessagesSent     int64     `json:"messages_sent"`
+	MessagesDropped  int64     `json:"messages_dropped"` // Skipped because the buffer was full
+	BytesWritten     int64     `json:"bytes_written"`
+	BytesPerSecond   float64   `json:"bytes_per_second"` // Average since connecting
+	BandwidthLimited bool      `json:"bandwidth_limited,omitempty"`
+	BandwidthDefer   int64     `json:"bandwidth_deferred,omitempty"` // Notifications held back by the cap
+	Region           string    `json:"region,omitempty"`             // Simulated region; empty for local users
+	QueuedMsgs       int       `json:"queued_messages"`
+	BufferCapacity   int       `json:"buffer_capacity"`
+	FilterTypes      []string  `json:"filter_types,omitempty"`
+	FilterPriorities []string  `json:"filter_priorities,omitempty"`
+	FilterTopics     []string  `json:"filter_topics,omitempty"`
+	TypedEvents      bool      `json:"typed_events,omitempty"`
+}
+
+// info snapshots the connection's state
+func (conn *SSEConnection) info(now time.Time) ConnectionInfo {
+	filter := conn.Filter()
+	bytes := atomic.LoadInt64(&conn.bytes)
+	var bytesPerSecond float64
+	if age := now.Sub(conn.ConnectedAt).Seconds(); age > 0 {
+		bytesPerSecond = float64(bytes) / age
+	}
+	return ConnectionInfo{
+		ID:               conn.ID,
+		TenantID:         conn.TenantID,
+		UserID:           conn.UserID,
+		ConnectedAt:      conn.ConnectedAt,
+		AgeSeconds:       now.Sub(conn.ConnectedAt).Seconds(),
+		LastPing:         conn.LastPing(),
+		MessagesSent:     atomic.LoadInt64(&conn.sent),
+		MessagesDropped:  atomic.LoadInt64(&conn.dropped),
+		BytesWritten:     bytes,
+		BytesPerSecond:   bytesPerSecond,
+		BandwidthLimited: conn.bandwidth != nil && conn.bandwidth.Exhausted(now),
+		BandwidthDefer:   atomic.LoadInt64(&conn.deferred),
+		Region:           conn.regionLabel(),
+		QueuedMsgs:       len(conn.ClientChan),
+		BufferCapacity:   cap(conn.ClientChan),
+		FilterTypes:      sortedKeys(filter.Types),
+		FilterPriorities: sortedKeys(filter.Priorities),
+		FilterTopics:     sortedKeys(filter.Topics),
+		TypedEvents:      filter.TypedEvents,
+	}
+}
+
+// regionLabel names the connection's simulated region, or "" when regions
+// are not simulated
+func (conn *SSEConnection) regionLabel() string {
+	if conn.region == nil {
+		return ""
+	}
+	return conn.region.Name
+}
+
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
+// ErrNoConnection is returned when a user has no open SSE connection
+var ErrNoConnection = errors.New("no active connections")
+
+// ErrDraining is returned for new connections once the manager is draining
+var ErrDraining = errors.New("server is draining, reconnect to another instance")
+
 // SSEManager manages SSE connections for all users
 type SSEManager struct {
-	connections map[string][]*SSEConnection
+	connections map[string][]*SSEConnection // Keyed by connectionKey(tenant, user)
 	mu          sync.RWMutex
 	logger      *zap.Logger
 	maxConns    int
+	instanceID  string
+
+	// Pipeline stage timestamps in notification frames
+	stageTimestamps bool
+
+	chaos *Chaos
+
+	// Connection liveness
+	heartbeatInterval time.Duration
+	staleTimeout      time.Duration
+	livenessTimeout   time.Duration // Echo silence after which a connection counts as offline; 0 disables echoes
+	cleanupInterval   time.Duration
+	compression       []string // Allowed stream encodings in preference order
+	flushInterval     time.Duration
+	flushMaxFrames    int
+	bandwidthLimit    int          // Bytes per second per connection; 0 disables the cap
+	regions           []*simRegion // Simulated client regions; empty disables the WAN layer
+	handlers          *HandlerRegistry
+	preferences       *PreferenceCache
+	ctx               context.Context
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+
+	// Called (outside the lock) when a user goes from zero to one connection
+	onUserConnected func(tenantID, userID string)
+
+	// Reconnect hinting: retry is the SSE retry: sent on connect; reconnect
+	// events carry reconnectAfter plus up to reconnectSpread of jitter
+	retry           time.Duration
+	reconnectAfter  time.Duration
+	reconnectSpread time.Duration
+	reconnectsSent  int64 // atomic
+
+	// Pre-stop drain: once draining, new streams are refused; closing
+	// drainCh tells open streams to send a reconnect event and end
+	draining  int32 // atomic
+	drainCh   chan struct{}
+	drainOnce sync.Once
+
+	// Connection counters
+	totalAccepted int64
+	totalRejected int64
+	peakConns     int64
+
+	// Recent capacity rejections, which scale the Retry-After hint
+	rejections rejectionWindow
+
+	// Heartbeat echoes and mid-stream filter updates received (atomic)
+	echoes        int64
+	filterUpdates int64
+
+	// Write coalescing counters (atomic)
+	framesWritten int64
+	flushes       int64
+
+	// Bandwidth accounting (atomic); userBytes maps connectionKey to an
+	// *int64 total that outlives the user's connections
+	bytesWritten      int64
+	bandwidthDeferred int64
+	userBytes         sync.Map
+
+	// Delivered notifications per connectionKey (*int64), for {{unread_count}}
+	userUnread sync.Map
+
+	// Cleanup counters
+	cleanupRuns     int64
+	staleRemoved    int64
+	lastCleanup     time.Time
+	lastCleanupTook time.Duration
 }
 
-// NewSSEManager creates a new SSE manager
-func NewSSEManager(maxConns int, logger *zap.Logger) *SSEManager {
+// SSEManagerConfig holds SSE manager configuration
+type SSEManagerConfig struct {
+	MaxConnections    int
+	HeartbeatInterval time.Duration    // Heartbeat frame period on every stream
+	StaleTimeout      time.Duration    // Connections idle longer than this are closed
+	LivenessTimeout   time.Duration    // Connections that haven't echoed a heartbeat for this long count as offline (0 = server writes keep them alive)
+	CleanupInterval   time.Duration    // How often stale connections are swept
+	Compression       []string         // Allowed stream encodings, preferred first (nil disables)
+	FlushInterval     time.Duration    // Max time a notification frame waits to be coalesced with others (0 flushes every frame)
+	FlushMaxFrames    int              // Buffered frames that force a flush before FlushInterval
+	BandwidthLimit    int              // Bytes per second per connection before LOW notifications are deferred (0 = unlimited)
+	Regions           []Region         // Simulated client regions whose frames pass through a WAN delay/loss layer
+	Handlers          *HandlerRegistry // Per-event-type delivery handlers (nil uses DefaultHandlers)
+	Retry             time.Duration    // SSE retry: hint sent when a stream opens (0 omits it)
+	ReconnectAfter    time.Duration    // Retry hint of reconnect events (default 2s)
+	ReconnectSpread   time.Duration    // Reconnect hints are spread over [ReconnectAfter, ReconnectAfter+ReconnectSpread]
+	InstanceID        string           // Named in connected frames, notifications and the X-Instance-ID header
+	Chaos             *Chaos           // Injected stream write failures (nil = none)
+	StageTimestamps   bool             // Send pipeline stage timestamps with every notification
+}
+
+// SSEStats is a point-in-time view of SSE connection state
+type SSEStats struct {
+	ActiveConnections int   `json:"active_connections"`
+	ConnectedUsers    int   `json:"connected_users"`
+	MaxConnections    int   `json:"max_connections"`
+	PeakConnections   int64 `json:"peak_connections"`
+	TotalAccepted     int64 `json:"total_accepted"`
+	TotalRejected     int64 `json:"total_rejected"`
+
+	// Connection liveness
+	HeartbeatInterval string    `json:"heartbeat_interval"`
+	StaleTimeout      string    `json:"stale_timeout"`
+	LivenessTimeout   string    `json:"liveness_timeout,omitempty"`
+	SilentConns       int       `json:"silent_connections"`
+	ClientEchoes      int64     `json:"client_echoes"`
+	FilterUpdates     int64     `json:"filter_updates"`
+	CleanupInterval   string    `json:"cleanup_interval"`
+	CleanupRuns       int64     `json:"cleanup_runs"`
+	StaleRemoved      int64     `json:"stale_removed"`
+	LastCleanup       time.Time `json:"last_cleanup"`
+	LastCleanupTookMs float64   `json:"last_cleanup_took_ms"`
+
+	// Write coalescing
+	FlushInterval  string  `json:"flush_interval"`
+	FlushMaxFrames int     `json:"flush_max_frames"`
+	FramesWritten  int64   `json:"frames_written"`
+	Flushes        int64   `json:"flushes"`
+	FramesPerFlush float64 `json:"frames_per_flush"`
+
+	// Bandwidth
+	BytesWritten      int64 `json:"bytes_written"`
+	BandwidthLimit    int   `json:"bandwidth_limit"` // Bytes per second per connection (0 = unlimited)
+	BandwidthDeferred int64 `json:"bandwidth_deferred"`
+
+	// Simulated regions
+	Regions []RegionStats `json:"regions,omitempty"`
+
+	// Reconnect hinting and pre-stop drain
+	Retry           string `json:"retry,omitempty"`
+	ReconnectAfter  string `json:"reconnect_after"`
+	ReconnectSpread string `json:"reconnect_spread"`
+	ReconnectsSent  int64  `json:"reconnects_sent"`
+	Draining        bool   `json:"draining"`
+}
+
+// NewSSEManager creates a new SSE manager and starts its cleanup loop;
+// call Stop to end it
+func NewSSEManager(config SSEManagerConfig, logger *zap.Logger) *SSEManager {
+	// Set defaults
+	if config.HeartbeatInterval == 0 {
+		config.HeartbeatInterval = 30 * time.Second
+	}
+	if config.StaleTimeout == 0 {
+		config.StaleTimeout = 5 * time.Minute
+	}
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = 1 * time.Minute
+	}
+	if config.FlushInterval > 0 && config.FlushMaxFrames <= 0 {
+		config.FlushMaxFrames = 16
+	}
+	if config.Handlers == nil {
+		config.Handlers = DefaultHandlers()
+	}
+	if config.ReconnectAfter == 0 {
+		config.ReconnectAfter = 2 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	manager := &SSEManager{
-		connections: make(map[string][]*SSEConnection),
-		logger:      logger,
-		maxConns:    maxConns,
+		connections:       make(map[string][]*SSEConnection),
+		logger:            logger,
+		maxConns:          config.MaxConnections,
+		instanceID:        config.InstanceID,
+		chaos:             config.Chaos,
+		stageTimestamps:   config.StageTimestamps,
+		heartbeatInterval: config.HeartbeatInterval,
+		staleTimeout:      config.StaleTimeout,
+		livenessTimeout:   config.LivenessTimeout,
+		cleanupInterval:   config.CleanupInterval,
+		compression:       config.Compression,
+		flushInterval:     config.FlushInterval,
+		flushMaxFrames:    config.FlushMaxFrames,
+		bandwidthLimit:    config.BandwidthLimit,
+		handlers:          config.Handlers,
+		retry:             config.Retry,
+		reconnectAfter:    config.ReconnectAfter,
+		reconnectSpread:   config.ReconnectSpread,
+		drainCh:           make(chan struct{}),
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+	for _, r := range config.Regions {
+		manager.regions = append(manager.regions, &simRegion{Region: r})
 	}
 
 	// Start cleanup goroutine
-	go manager.cleanupStaleConnections()
+	manager.wg.Add(1)
+	go runInPool(ctx, PoolSSECleanup, manager.cleanupStaleConnections)
 
 	return manager
 }
 
-// AddConnection adds a new SSE connection for a user
-func (m *SSEManager) AddConnection(userID string) (*SSEConnection, error) {
+// Stop ends the cleanup loop and waits for it to exit. Open connections are
+// left to their stream handlers, which end with the HTTP server.
+func (m *SSEManager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+	m.logger.Info("SSE manager stopped")
+}
+
+// StopAccepting refuses new streams with 503 and a Retry-After hint while
+// open streams keep receiving notifications
+func (m *SSEManager) StopAccepting() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if atomic.CompareAndSwapInt32(&m.draining, 0, 1) {
+		m.logger.Info("SSE manager draining, refusing new connections")
+	}
+}
+
+// Drain stops accepting streams and sends every open stream a reconnect
+// event carrying the retry hint, after which the stream ends. It returns
+// the number of streams told to reconnect; their handlers return on their
+// own, so wait for the HTTP server's Shutdown afterwards.
+func (m *SSEManager) Drain() int {
+	m.StopAccepting()
+	open := m.GetActiveConnections()
+	m.drainOnce.Do(func() { close(m.drainCh) })
+	return open
+}
+
+// ReconnectRequest selects streams to send a reconnect event, shedding load
+// or rebalancing across instances
+type ReconnectRequest struct {
+	TenantID string  // Only this tenant's streams; empty for all
+	UserID   string  // Only this user's streams; empty for all
+	Fraction float64 // Share of the matching streams, picked at random (0-1)
+}
+
+// RequestReconnect sends a random share of the matching streams a
+// reconnect event with a jittered retry hint and ends them; it returns how
+// many were asked
+func (m *SSEManager) RequestReconnect(req ReconnectRequest) int {
+	m.mu.RLock()
+	var matching []*SSEConnection
+	for _, conns := range m.connections {
+		for _, conn := range conns {
+			if conn.TenantID == CanaryTenantID {
+				continue // Canary probes have no stream to reconnect
+			}
+			if (req.TenantID == "" || conn.TenantID == req.TenantID) && (req.UserID == "" || conn.UserID == req.UserID) {
+				matching = append(matching, conn)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	rand.Shuffle(len(matching), func(i, j int) { matching[i], matching[j] = matching[j], matching[i] })
+	want := int(math.Ceil(req.Fraction * float64(len(matching))))
+
+	asked := 0
+	for _, conn := range matching[:min(want, len(matching))] {
+		select {
+		case conn.reconnect <- reconnectRequest{reason: ReconnectShed, after: m.reconnectHint()}:
+			asked++
+		default: // Already asked
+		}
+	}
+	m.logger.Info("requested SSE reconnects",
+		zap.Int("matching", len(matching)),
+		zap.Int("requested", asked),
+		zap.Float64("fraction", req.Fraction))
+	return asked
+}
+
+// reconnectHint spreads reconnects over [reconnectAfter,
+// reconnectAfter+reconnectSpread] so clients don't all return at once
+func (m *SSEManager) reconnectHint() time.Duration {
+	if m.reconnectSpread <= 0 {
+		return m.reconnectAfter
+	}
+	return m.reconnectAfter + time.Duration(rand.Int63n(int64(m.reconnectSpread)+1))
+}
+
+// capacityRetryAfter scales the retry hint by demand over capacity: open
+// connections plus those refused in the last second, all of which will be
+// back. A full instance turning away a herd twice its size asks for three
+// times reconnectAfter.
+func (m *SSEManager) capacityRetryAfter(open int) time.Duration {
+	base := m.reconnectAfter
+	if base <= 0 {
+		base = time.Second
+	}
+	load := (float64(open) + m.rejections.add(time.Now())) / float64(max(m.maxConns, 1))
+	return spreadRetryAfter(time.Duration(float64(base) * load))
+}
+
+// reconnectFrame asks the client to reconnect after retryAfter, both as an
+// SSE retry field (used by EventSource) and in the event data
+func reconnectFrame(reason string, retryAfter time.Duration) []byte {
+	return fmt.Appendf(nil, "retry: %d\nevent: reconnect\ndata: {\"reason\":%q,\"retry_after_ms\":%d}\n\n",
+		retryAfter.Milliseconds(), reason, retryAfter.Milliseconds())
+}
+
+// Handlers returns the per-event-type delivery handlers
+func (m *SSEManager) Handlers() *HandlerRegistry {
+	return m.handlers
+}
+
+// connectionKey scopes a user ID to its tenant so tenants never see each
+// other's notifications even when user IDs collide
+func connectionKey(tenantID, userID string) string {
+	return models.TenantOrDefault(tenantID) + "/" + userID
+}
+
+// OnUserConnected registers fn to run when a user with no open connections
+// connects. It runs on the connecting request's goroutine after the
+// connection is registered, so sends to the user succeed.
+func (m *SSEManager) OnUserConnected(fn func(tenantID, userID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onUserConnected = fn
+}
+
+// HasConnections reports whether a tenant's user has any open connection
+// that isn't silent
+func (m *SSEManager) HasConnections(tenantID, userID string) bool {
+	live, _ := m.liveConnections(tenantID, userID)
+	return len(live) > 0
+}
+
+// AddConnection adds a new SSE connection for a tenant's user
+func (m *SSEManager) AddConnection(tenantID, userID string) (*SSEConnection, error) {
+	return m.addConnection(tenantID, userID, StreamFilter{})
+}
+
+func (m *SSEManager) addConnection(tenantID, userID string, filter StreamFilter) (*SSEConnection, error) {
+	tenantID = models.TenantOrDefault(tenantID)
+
+	conn, firstConn, err := m.registerConnection(tenantID, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	onUserConnected := m.onUserConnected
+	m.mu.RUnlock()
+	if firstConn && onUserConnected != nil {
+		onUserConnected(tenantID, userID)
+	}
+
+	return conn, nil
+}
+
+// registerConnection adds the connection under the lock; firstConn reports
+// whether it is the user's only connection
+func (m *SSEManager) registerConnection(tenantID, userID string, filter StreamFilter) (conn *SSEConnection, firstConn bool, err error) {
+	key := connectionKey(tenantID, userID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if atomic.LoadInt32(&m.draining) == 1 {
+		m.totalRejected++
+		return nil, false, &RejectionError{Reason: RejectDraining, RetryAfter: m.reconnectHint(), Err: ErrDraining}
+	}
 
 	// Check max connections
 	totalConns := 0
@@ -53,101 +511,122 @@ func (m *SSEManager) AddConnection(userID string) (*SSEConnection, error) {
 	}
 
 	if totalConns >= m.maxConns {
-		return nil, fmt.Errorf("max connections reached: %d", m.maxConns)
+		m.totalRejected++
+		return nil, false, &RejectionError{
+			Reason:     RejectCapacity,
+			RetryAfter: m.capacityRetryAfter(totalConns),
+			Err:        fmt.Errorf("%w: %d", ErrAtCapacity, m.maxConns),
+		}
 	}
 
-	conn := &SSEConnection{
-		UserID:     userID,
-		ClientChan: make(chan []byte, 100), // Buffer for 100 messages
-		LastPing:   time.Now(),
+	now := time.Now()
+	conn = &SSEConnection{
+		ID:          idgen.NewID().String(),
+		TenantID:    tenantID,
+		UserID:      userID,
+		ClientChan:  make(chan []byte, 100), // Buffer for 100 messages
+		ConnectedAt: now,
+		reconnect:   make(chan reconnectRequest, 1),
 	}
+	conn.filter.Store(&filter)
+	conn.touch(now)
+	if m.bandwidthLimit > 0 {
+		conn.bandwidth = newBandwidthBucket(m.bandwidthLimit, now)
+	}
+	conn.region = m.regionFor(tenantID, userID)
 
-	m.connections[userID] = append(m.connections[userID], conn)
+	m.connections[key] = append(m.connections[key], conn)
+	m.totalAccepted++
+	if int64(totalConns+1) > m.peakConns {
+		m.peakConns = int64(totalConns + 1)
+	}
 
 	m.logger.Info("SSE connection added",
+		zap.String("tenant_id", tenantID),
 		zap.String("user_id", userID),
-		zap.Int("user_connections", len(m.connections[userID])),
+		zap.Int("user_connections", len(m.connections[key])),
 		zap.Int("total_connections", totalConns+1))
 
-	return conn, nil
+	return conn, len(m.connections[key]) == 1, nil
 }
 
 // RemoveConnection removes an SSE connection
-func (m *SSEManager) RemoveConnection(userID string, conn *SSEConnection) {
+func (m *SSEManager) RemoveConnection(conn *SSEConnection) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	connections := m.connections[userID]
+	key := connectionKey(conn.TenantID, conn.UserID)
+	connections := m.connections[key]
 	for i, c := range connections {
 		if c == conn {
 			close(c.ClientChan)
-			m.connections[userID] = append(connections[:i], connections[i+1:]...)
+			m.connections[key] = append(connections[:i], connections[i+1:]...)
 			break
 		}
 	}
 
 	// Remove user entry if no more connections
-	if len(m.connections[userID]) == 0 {
-		delete(m.connections, userID)
+	if len(m.connections[key]) == 0 {
+		delete(m.connections, key)
 	}
 
 	m.logger.Info("SSE connection removed",
-		zap.String("user_id", userID),
-		zap.Int("remaining_connections", len(m.connections[userID])))
+		zap.String("tenant_id", conn.TenantID),
+		zap.String("user_id", conn.UserID),
+		zap.Int("remaining_connections", len(m.connections[key])))
 }
 
 // BroadcastToUser sends a notification to all connections of a user
 func (m *SSEManager) BroadcastToUser(userID string, notification *models.Notification) {
-	m.mu.RLock()
-	connections := m.connections[userID]
-	m.mu.RUnlock()
-
-	if len(connections) == 0 {
+	connections, err := m.sendable(notification.TenantID, userID)
+	if err != nil {
 		m.logger.Debug("no active connections for user", zap.String("user_id", userID))
 		return
 	}
 
-	// Create SSE message
-	sseMsg := models.SSEMessage{
+	// Same wire event the task picker delivers
+	m.addUnread(notification.TenantID, userID, 1)
+	payload, _ := json.Marshal(notification.Payload)
+	frame, err := sseFrame("notification", m.render(&NotificationBatch{
 		NotificationID: notification.NotificationID,
-		Type:           string(notification.EventType),
+		TenantID:       notification.TenantID,
+		UserID:         userID,
+		EventType:      string(notification.EventType),
 		Priority:       string(notification.Priority),
-		Title:          m.generateTitle(notification),
-		Message:        m.generateMessage(notification),
-		Timestamp:      notification.NotificationDeliveredTimestamp,
-	}
-
-	data, err := json.Marshal(sseMsg)
+		EventTimestamp: notification.EventTimestamp,
+		Payload:        string(payload),
+		Locale:         m.userLocale(notification.TenantID, userID),
+	}))
 	if err != nil {
 		m.logger.Error("failed to marshal SSE message", zap.Error(err))
 		return
 	}
 
-	// Format SSE message
-	sseData := fmt.Sprintf("event: notification\ndata: %s\n\n", data)
-
 	// Send to all user connections
 	for _, conn := range connections {
 		select {
-		case conn.ClientChan <- []byte(sseData):
+		case conn.ClientChan <- frame:
 			m.logger.Debug("notification sent to connection",
 				zap.String("user_id", userID),
 				zap.String("event_type", string(notification.EventType)))
 		default:
+			atomic.AddInt64(&conn.dropped, 1)
 			m.logger.Warn("connection buffer full, skipping",
 				zap.String("user_id", userID))
 		}
 	}
 }
 
-// Send sends a generic message to all connections of a user
-func (m *SSEManager) Send(userID string, data map[string]interface{}) error {
-	m.mu.RLock()
-	connections := m.connections[userID]
-	m.mu.RUnlock()
+// Send sends a notification message to all connections of a tenant's user
+func (m *SSEManager) Send(tenantID, userID string, msg *models.NotificationEvent) error {
+	return m.SendEvent(tenantID, userID, "notification", msg)
+}
 
-	if len(connections) == 0 {
-		return fmt.Errorf("no active connections for user: %s", userID)
+// SendEvent sends a message with the given SSE event name to all connections of a tenant's user
+func (m *SSEManager) SendEvent(tenantID, userID, event string, data interface{}) error {
+	connections, err := m.sendable(tenantID, userID)
+	if err != nil {
+		return err
 	}
 
 	jsonData, err := json.Marshal(data)
@@ -156,7 +635,7 @@ func (m *SSEManager) Send(userID string, data map[string]interface{}) error {
 	}
 
 	// Format SSE message
-	sseData := fmt.Sprintf("event: notification\ndata: %s\n\n", jsonData)
+	sseData := fmt.Sprintf("event: %s\ndata: %s\n\n", event, jsonData)
 
 	// Send to all user connections
 	for _, conn := range connections {
@@ -164,6 +643,7 @@ func (m *SSEManager) Send(userID string, data map[string]interface{}) error {
 		case conn.ClientChan <- []byte(sseData):
 			// Sent successfully
 		default:
+			atomic.AddInt64(&conn.dropped, 1)
 			m.logger.Warn("connection buffer full, skipping",
 				zap.String("user_id", userID))
 		}
@@ -172,88 +652,466 @@ func (m *SSEManager) Send(userID string, data map[string]interface{}) error {
 	return nil
 }
 
+// SendNotifications delivers notifications to every connection of a tenant's
+// user, applying each connection's filter. Unfiltered connections get one
+// "notification" frame, or one grouped "notifications" frame for several;
+// typed connections get one frame per notification named after its event
+// type. delivered[i] reports whether notifications[i] reached at least one
+// connection; throttled[i] whether a connection over its bandwidth cap held it
+// back.
+func (m *SSEManager) SendNotifications(tenantID, userID string, notifications []*NotificationBatch) (delivered, throttled []bool, err error) {
+	connections, err := m.sendable(tenantID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m.addUnread(tenantID, userID, len(notifications))
+	locale := m.userLocale(tenantID, userID)
+	for _, n := range notifications {
+		n.Locale = locale
+	}
+
+	delivered = make([]bool, len(notifications))
+	throttled = make([]bool, len(notifications))
+	var shared [][]byte // Frames for unfiltered connections, built once
+	now := time.Now()
+
+	for _, conn := range connections {
+		var frames [][]byte
+		var included []int
+
+		// Over its cap, a connection only takes what can't be deferred
+		capped := conn.bandwidth != nil && conn.bandwidth.Exhausted(now)
+		filter := conn.Filter() // One filter for the whole delivery, even if updated meanwhile
+
+		if filter.passesAll() && !capped {
+			if shared == nil {
+				if shared, err = m.genericFrames(userID, notifications); err != nil {
+					return nil, nil, err
+				}
+			}
+			frames = shared
+			for i := range notifications {
+				included = append(included, i)
+			}
+		} else {
+			var subset []*NotificationBatch
+			for i, n := range notifications {
+				if !filter.Matches(n.EventType, n.Priority, n.Topic) {
+					continue
+				}
+				if capped && deferrable(n) {
+					throttled[i] = true
+					atomic.AddInt64(&conn.deferred, 1)
+					atomic.AddInt64(&m.bandwidthDeferred, 1)
+					metrics.SSEBandwidthDeferred.Inc()
+					continue
+				}
+				subset = append(subset, n)
+				included = append(included, i)
+			}
+			if len(subset) == 0 {
+				continue
+			}
+			if filter.TypedEvents {
+				frames, err = m.typedFrames(subset)
+			} else {
+				frames, err = m.genericFrames(userID, subset)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		sent := true
+		for _, frame := range frames {
+			select {
+			case conn.ClientChan <- frame:
+			default:
+				sent = false
+				atomic.AddInt64(&conn.dropped, 1)
+				m.logger.Warn("connection buffer full, skipping",
+					zap.String("user_id", userID))
+			}
+		}
+		if sent {
+			for _, i := range included {
+				delivered[i] = true
+			}
+		}
+	}
+
+	return delivered, throttled, nil
+}
+
+// genericFrames renders one "notification" frame, or one grouped
+// "notifications" frame for several
+func (m *SSEManager) genericFrames(userID string, notifications []*NotificationBatch) ([][]byte, error) {
+	var event string
+	var data interface{}
+	if len(notifications) == 1 {
+		event, data = "notification", m.render(notifications[0])
+	} else {
+		items := make([]*models.NotificationEvent, len(notifications))
+		for i, n := range notifications {
+			items[i] = m.render(n)
+		}
+		event, data = "notifications", &models.NotificationGroup{
+			Version:       models.WireVersion,
+			UserID:        userID,
+			Count:         len(items),
+			Notifications: items,
+		}
+	}
+
+	frame, err := sseFrame(event, data)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{frame}, nil
+}
+
+// typedFrames renders one frame per notification, named after its event type
+func (m *SSEManager) typedFrames(notifications []*NotificationBatch) ([][]byte, error) {
+	frames := make([][]byte, len(notifications))
+	for i, n := range notifications {
+		frame, err := sseFrame(n.EventType, m.render(n))
+		if err != nil {
+			return nil, err
+		}
+		frames[i] = frame
+	}
+	return frames, nil
+}
+
+// userLocale is a user's preferred locale, empty without a preference cache
+func (m *SSEManager) userLocale(tenantID, userID string) string {
+	if m.preferences == nil {
+		return ""
+	}
+	return m.preferences.Locale(tenantID, userID)
+}
+
+// SetPreferences sets where users' locales come from when rendering titles
+// and messages; without it every user gets the default locale. Call it
+// before deliveries start.
+func (m *SSEManager) SetPreferences(cache *PreferenceCache) {
+	m.preferences = cache
+}
+
+// Preferences returns the user preference cache, nil when there is none
+func (m *SSEManager) Preferences() *PreferenceCache {
+	return m.preferences
+}
+
+// render builds a notification's frame data with its event type's handler
+func (m *SSEManager) render(n *NotificationBatch) *models.NotificationEvent {
+	start := time.Now()
+	event := m.handlers.Lookup(n.EventType).Render(n)
+	templated := m.expandEvent(event, n, start)
+	metrics.SSERenderSeconds.WithLabelValues(strconv.FormatBool(templated)).Observe(time.Since(start).Seconds())
+	event.InstanceID = m.instanceID
+	event.Topic = n.Topic
+	if m.stageTimestamps {
+		event.Stages = &models.StageTimestamps{
+			ProducedAt:  n.ProducedAt,
+			ConsumedAt:  n.NotificationReceivedTimestamp,
+			PersistedAt: n.PersistedAt,
+			ClaimedAt:   n.ClaimedAt,
+			DeliveredAt: time.Now(),
+		}
+	}
+	return event
+}
+
+// connectedFrame is the data of the first frame on every stream
+type connectedFrame struct {
+	Status          string `json:"status"`
+	InstanceID      string `json:"instance_id,omitempty"`
+	Region          string `json:"region,omitempty"`
+	ConnectionID    string `json:"connection_id,omitempty"`
+	LivenessTimeout string `json:"liveness_timeout,omitempty"`
+}
+
+// sseFrame formats a single SSE event
+func sseFrame(event string, data interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, jsonData)), nil
+}
+
 // StreamToClient handles the SSE streaming to a gin context
-func (m *SSEManager) StreamToClient(c *gin.Context, userID string) {
-	conn, err := m.AddConnection(userID)
+func (m *SSEManager) StreamToClient(c *gin.Context, tenantID, userID string) {
+	runInPool(c.Request.Context(), PoolSSEStream, func() { m.streamToClient(c, tenantID, userID) })
+}
+
+func (m *SSEManager) streamToClient(c *gin.Context, tenantID, userID string) {
+	// ?types=, ?priority=, ?topics= and ?event_names=typed narrow this stream
+	filter, err := ParseStreamFilter(c)
 	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := m.addConnection(tenantID, userID, filter)
+	if err != nil {
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			writeRejection(c, rejection)
+			return
+		}
 		c.JSON(503, gin.H{"error": err.Error()})
 		return
 	}
-	defer m.RemoveConnection(userID, conn)
+	defer m.RemoveConnection(conn)
 
 	// Set SSE headers
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
+	if m.instanceID != "" {
+		c.Header("X-Instance-ID", m.instanceID)
+	}
+
+	// An injected write failure drops the connection once the writer is
+	// closed, so clients see a broken stream rather than a clean end
+	var writeFailed bool
+	defer func() {
+		if writeFailed {
+			abortStream(c.Writer)
+		}
+	}()
+
+	// Compress if enabled and the client accepts it (flushed per frame)
+	w := newSSEWriter(c, m.compression)
+	defer w.Close()
+	userBytes := m.userByteCounter(tenantID, userID)
 
-	// Send initial connection message
-	c.Writer.Write([]byte("event: connected\ndata: {\"status\":\"connected\"}\n\n"))
-	c.Writer.Flush()
+	// Notification frames are buffered until flushMaxFrames are pending or
+	// the oldest has waited flushInterval; connected and heartbeat frames
+	// flush immediately, taking anything buffered with them
+	flushTimer := time.NewTimer(m.flushInterval)
+	flushTimer.Stop()
+	defer flushTimer.Stop()
+	var flushDue <-chan time.Time // Set while frames are buffered
+	var buffered int64            // Notification frames among them
+
+	flush := func() error {
+		frames := w.Pending()
+		if frames == 0 {
+			return nil
+		}
+		flushDue = nil
+		if err := m.chaos.FailWrite(); err != nil {
+			writeFailed = true
+			return err
+		}
+		before := w.Written()
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if n := w.Written() - before; n > 0 {
+			atomic.AddInt64(&conn.bytes, n)
+			atomic.AddInt64(userBytes, n)
+			atomic.AddInt64(&m.bytesWritten, n)
+			metrics.SSEBytesWritten.Add(float64(n))
+			if conn.bandwidth != nil {
+				conn.bandwidth.Charge(time.Now(), n)
+			}
+		}
+		atomic.AddInt64(&conn.sent, buffered)
+		buffered = 0
+		atomic.AddInt64(&m.framesWritten, int64(frames))
+		atomic.AddInt64(&m.flushes, 1)
+		metrics.SSEFramesPerFlush.Observe(float64(frames))
+		return nil
+	}
+
+	// Send initial connection message, naming the simulated region so
+	// clients can report latency per region, and the connection ID clients
+	// update their filter with (and echo heartbeats with when liveness is
+	// tracked)
+	connected := connectedFrame{Status: "connected", InstanceID: m.instanceID, ConnectionID: conn.ID}
+	if len(m.regions) > 0 {
+		connected.Region = regionName(conn.region)
+	}
+	if m.livenessTimeout > 0 {
+		connected.LivenessTimeout = m.livenessTimeout.String()
+	}
+	frame, err := sseFrame("connected", connected)
+	if err != nil {
+		m.logger.Error("failed to marshal SSE message", zap.Error(err))
+		return
+	}
+	if m.retry > 0 {
+		// Reconnect delay for EventSource clients when the stream drops
+		frame = append(fmt.Appendf(nil, "retry: %d\n", m.retry.Milliseconds()), frame...)
+	}
+	w.Buffer(frame)
+	if err := flush(); err != nil {
+		m.logger.Error("failed to write to client", zap.Error(err))
+		return
+	}
 
 	// Start heartbeat
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(m.heartbeatInterval)
 	defer ticker.Stop()
 
+	// Per-connection sequence number, sent as the SSE id of every
+	// notification frame so clients can detect reordering
+	var seq uint64
+
+	// Buffers a notification frame, flushing when the coalescing limits say so
+	send := func(msg []byte) error {
+		seq++
+		w.Buffer(fmt.Appendf(nil, "id: %d\n%s", seq, msg))
+		buffered++
+		m.wrote(conn)
+
+		if m.flushInterval <= 0 || w.Pending() >= m.flushMaxFrames {
+			return flush()
+		}
+		if flushDue == nil {
+			flushTimer.Reset(m.flushInterval)
+			flushDue = flushTimer.C
+		}
+		return nil
+	}
+
+	// Hands over what is already queued, then asks the client to reconnect
+	// (possibly to another instance) after the hint; the stream ends after
+	reconnect := func(req reconnectRequest) {
+		for len(conn.ClientChan) > 0 {
+			if err := send(<-conn.ClientChan); err != nil {
+				return
+			}
+		}
+		w.Buffer(reconnectFrame(req.reason, req.after))
+		if err := flush(); err != nil {
+			m.logger.Error("failed to send reconnect", zap.Error(err))
+			return
+		}
+		atomic.AddInt64(&m.reconnectsSent, 1)
+		metrics.SSEReconnectsSent.WithLabelValues(req.reason).Inc()
+	}
+
+	// Users in a simulated region get their frames through a WAN link
+	var link *wanLink
+	if conn.region != nil {
+		link = newWANLink(conn.region)
+		defer link.Stop()
+	}
+
 	for {
 		select {
 		case <-c.Request.Context().Done():
 			m.logger.Info("client disconnected", zap.String("user_id", userID))
 			return
-		case msg := <-conn.ClientChan:
-			_, err := c.Writer.Write(msg)
-			if err != nil {
+		case msg, ok := <-conn.ClientChan:
+			if !ok {
+				// Closed by stale connection cleanup
+				flush()
+				return
+			}
+			if link != nil {
+				link.Push(msg, time.Now())
+				continue
+			}
+			if err := send(msg); err != nil {
 				m.logger.Error("failed to write to client", zap.Error(err))
 				return
 			}
-			c.Writer.Flush()
-			conn.LastPing = time.Now()
+		case <-link.C():
+			for _, msg := range link.Release(time.Now()) {
+				if err := send(msg); err != nil {
+					m.logger.Error("failed to write to client", zap.Error(err))
+					return
+				}
+			}
+		case <-flushDue:
+			if err := flush(); err != nil {
+				m.logger.Error("failed to write to client", zap.Error(err))
+				return
+			}
+		case <-m.drainCh:
+			reconnect(reconnectRequest{reason: ReconnectDraining, after: m.reconnectHint()})
+			return
+		case req := <-conn.reconnect:
+			reconnect(req)
+			return
 		case <-ticker.C:
 			// Send heartbeat
 			heartbeat := fmt.Sprintf("event: heartbeat\ndata: {\"timestamp\":\"%s\"}\n\n",
 				time.Now().Format(time.RFC3339))
-			_, err := c.Writer.Write([]byte(heartbeat))
-			if err != nil {
+			w.Buffer([]byte(heartbeat))
+			if err := flush(); err != nil {
 				m.logger.Error("failed to send heartbeat", zap.Error(err))
 				return
 			}
-			c.Writer.Flush()
-			conn.LastPing = time.Now()
+			m.wrote(conn)
 		}
 	}
 }
 
-// cleanupStaleConnections removes stale connections
+// cleanupStaleConnections removes stale connections every cleanup interval
+// until the manager is stopped
 func (m *SSEManager) cleanupStaleConnections() {
-	ticker := time.NewTicker(1 * time.Minute)
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		m.mu.Lock()
-		staleTimeout := 5 * time.Minute
-		now := time.Now()
-
-		for userID, connections := range m.connections {
-			var activeConns []*SSEConnection
-			for _, conn := range connections {
-				if now.Sub(conn.LastPing) < staleTimeout {
-					activeConns = append(activeConns, conn)
-				} else {
-					close(conn.ClientChan)
-					m.logger.Info("removed stale connection",
-						zap.String("user_id", userID),
-						zap.Duration("idle_time", now.Sub(conn.LastPing)))
-				}
-			}
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.removeStaleConnections()
+		}
+	}
+}
+
+// removeStaleConnections closes connections idle longer than the stale timeout
+func (m *SSEManager) removeStaleConnections() {
+	start := time.Now()
 
-			if len(activeConns) > 0 {
-				m.connections[userID] = activeConns
+	m.mu.Lock()
+	removed := 0
+	for key, connections := range m.connections {
+		var activeConns []*SSEConnection
+		for _, conn := range connections {
+			if start.Sub(conn.LastPing()) < m.staleTimeout {
+				activeConns = append(activeConns, conn)
 			} else {
-				delete(m.connections, userID)
+				close(conn.ClientChan)
+				removed++
+				m.logger.Info("removed stale connection",
+					zap.String("tenant_id", conn.TenantID),
+					zap.String("user_id", conn.UserID),
+					zap.Duration("idle_time", start.Sub(conn.LastPing())))
 			}
 		}
-		m.mu.Unlock()
+
+		if len(activeConns) > 0 {
+			m.connections[key] = activeConns
+		} else {
+			delete(m.connections, key)
+		}
 	}
+
+	took := time.Since(start)
+	m.cleanupRuns++
+	m.staleRemoved += int64(removed)
+	m.lastCleanup = start
+	m.lastCleanupTook = took
+	m.mu.Unlock()
+
+	metrics.SSECleanupRuns.Inc()
+	metrics.SSEStaleRemoved.Add(float64(removed))
+	metrics.SSECleanupSeconds.Observe(took.Seconds())
 }
 
 // GetActiveConnections returns the count of active connections
@@ -268,38 +1126,144 @@ func (m *SSEManager) GetActiveConnections() int {
 	return total
 }
 
-// generateTitle generates a title for the notification
-func (m *SSEManager) generateTitle(notif *models.Notification) string {
-	switch notif.EventType {
-	case models.EventJobApplicationViewed:
-		return "Application Viewed"
-	case models.EventJobNew:
-		return "New Job Recommendation"
-	case models.EventConnectionRequest:
-		return "New Connection Request"
-	case models.EventFollowerNew:
-		return "New Follower"
-	default:
-		return "New Notification"
-	}
-}
-
-// generateMessage generates a message for the notification
-func (m *SSEManager) generateMessage(notif *models.Notification) string {
-	switch notif.EventType {
-	case models.EventJobApplicationViewed:
-		company := notif.Payload["company_name"]
-		return fmt.Sprintf("%s viewed your application", company)
-	case models.EventJobNew:
-		title := notif.Payload["job_title"]
-		return fmt.Sprintf("New job: %s", title)
-	case models.EventConnectionRequest:
-		name := notif.Payload["from"]
-		return fmt.Sprintf("%s sent you a connection request", name)
-	case models.EventFollowerNew:
-		name := notif.Payload["follower_name"]
-		return fmt.Sprintf("%s started following you", name)
-	default:
-		return "You have a new notification"
+// Connections lists open connections, oldest first, up to limit (0 = all)
+func (m *SSEManager) Connections(limit int) []ConnectionInfo {
+	now := time.Now()
+
+	m.mu.RLock()
+	infos := make([]ConnectionInfo, 0, len(m.connections))
+	for _, conns := range m.connections {
+		for _, conn := range conns {
+			info := conn.info(now)
+			info.Silent = m.silent(conn, now)
+			infos = append(infos, info)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+	})
+	if limit > 0 && len(infos) > limit {
+		infos = infos[:limit]
+	}
+	return infos
+}
+
+// UserConnections lists a tenant's user's open connections on this
+// instance, oldest first
+func (m *SSEManager) UserConnections(tenantID, userID string) []ConnectionInfo {
+	now := time.Now()
+
+	m.mu.RLock()
+	conns := m.connections[connectionKey(tenantID, userID)]
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, conn := range conns {
+		info := conn.info(now)
+		info.Silent = m.silent(conn, now)
+		infos = append(infos, info)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+	})
+	return infos
+}
+
+// userByteCounter returns the running byte total for a tenant's user
+func (m *SSEManager) userByteCounter(tenantID, userID string) *int64 {
+	counter, _ := m.userBytes.LoadOrStore(connectionKey(tenantID, userID), new(int64))
+	return counter.(*int64)
+}
+
+// UserBytesWritten returns the bytes written to a tenant's user on this
+// instance across all their connections, including closed ones
+func (m *SSEManager) UserBytesWritten(tenantID, userID string) int64 {
+	counter, ok := m.userBytes.Load(connectionKey(models.TenantOrDefault(tenantID), userID))
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(counter.(*int64))
+}
+
+// TenantConnections returns the number of open connections per tenant
+func (m *SSEManager) TenantConnections() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, conns := range m.connections {
+		for _, conn := range conns {
+			counts[conn.TenantID]++
+		}
+	}
+	return counts
+}
+
+// Stats returns the current SSE connection stats
+func (m *SSEManager) Stats() SSEStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	total, silent := 0, 0
+	for _, conns := range m.connections {
+		total += len(conns)
+		for _, conn := range conns {
+			if m.silent(conn, now) {
+				silent++
+			}
+		}
+	}
+
+	var livenessTimeout string
+	if m.livenessTimeout > 0 {
+		livenessTimeout = m.livenessTimeout.String()
+	}
+	var retry string
+	if m.retry > 0 {
+		retry = m.retry.String()
+	}
+
+	framesWritten := atomic.LoadInt64(&m.framesWritten)
+	flushes := atomic.LoadInt64(&m.flushes)
+	var framesPerFlush float64
+	if flushes > 0 {
+		framesPerFlush = float64(framesWritten) / float64(flushes)
+	}
+
+	return SSEStats{
+		ActiveConnections: total,
+		ConnectedUsers:    len(m.connections),
+		MaxConnections:    m.maxConns,
+		PeakConnections:   m.peakConns,
+		TotalAccepted:     m.totalAccepted,
+		TotalRejected:     m.totalRejected,
+		HeartbeatInterval: m.heartbeatInterval.String(),
+		StaleTimeout:      m.staleTimeout.String(),
+		LivenessTimeout:   livenessTimeout,
+		SilentConns:       silent,
+		ClientEchoes:      atomic.LoadInt64(&m.echoes),
+		FilterUpdates:     atomic.LoadInt64(&m.filterUpdates),
+		CleanupInterval:   m.cleanupInterval.String(),
+		CleanupRuns:       m.cleanupRuns,
+		StaleRemoved:      m.staleRemoved,
+		LastCleanup:       m.lastCleanup,
+		LastCleanupTookMs: float64(m.lastCleanupTook.Microseconds()) / 1000,
+		FlushInterval:     m.flushInterval.String(),
+		FlushMaxFrames:    m.flushMaxFrames,
+		FramesWritten:     framesWritten,
+		Flushes:           flushes,
+		FramesPerFlush:    framesPerFlush,
+		BytesWritten:      atomic.LoadInt64(&m.bytesWritten),
+		BandwidthLimit:    m.bandwidthLimit,
+		BandwidthDeferred: atomic.LoadInt64(&m.bandwidthDeferred),
+		Regions:           m.regionStats(),
+		Retry:             retry,
+		ReconnectAfter:    m.reconnectAfter.String(),
+		ReconnectSpread:   m.reconnectSpread.String(),
+		ReconnectsSent:    atomic.LoadInt64(&m.reconnectsSent),
+		Draining:          atomic.LoadInt32(&m.draining) == 1,
 	}
 }
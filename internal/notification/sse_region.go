@@ -0,0 +1,242 @@
+package notification
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// LocalRegion labels users outside every simulated region
+const LocalRegion = "local"
+
+// Region is a simulated client region: frames to its users pass through an
+// artificial WAN link that delays and drops them
+type Region struct {
+	Name     string
+	Fraction float64       // Share of users assigned to the region
+	Latency  time.Duration // Added one-way delay
+	Jitter   time.Duration // Delay varies uniformly by up to ± this
+	Loss     float64       // Probability a frame is dropped
+}
+
+// ParseRegions parses comma-separated name:fraction:latency[:jitter[:loss]]
+// entries, e.g. "eu-west:0.3:80ms:20ms:0.01,ap-south:0.2:180ms". Users
+// not covered by the fractions stay local.
+func ParseRegions(raw string) ([]Region, error) {
+	var regions []Region
+	seen := map[string]bool{LocalRegion: true}
+	total := 0.0
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || len(parts) > 5 {
+			return nil, fmt.Errorf("invalid region %q (want name:fraction:latency[:jitter[:loss]])", entry)
+		}
+
+		r := Region{Name: parts[0]}
+		if r.Name == "" || seen[r.Name] {
+			return nil, fmt.Errorf("invalid region %q: name must be unique and not %q", entry, LocalRegion)
+		}
+		seen[r.Name] = true
+
+		var err error
+		if r.Fraction, err = strconv.ParseFloat(parts[1], 64); err != nil || r.Fraction <= 0 {
+			return nil, fmt.Errorf("invalid region %q: fraction must be positive", entry)
+		}
+		if r.Latency, err = time.ParseDuration(parts[2]); err != nil || r.Latency < 0 {
+			return nil, fmt.Errorf("invalid region %q: bad latency", entry)
+		}
+		if len(parts) > 3 {
+			if r.Jitter, err = time.ParseDuration(parts[3]); err != nil || r.Jitter < 0 {
+				return nil, fmt.Errorf("invalid region %q: bad jitter", entry)
+			}
+		}
+		if len(parts) > 4 {
+			if r.Loss, err = strconv.ParseFloat(parts[4], 64); err != nil || r.Loss < 0 || r.Loss >= 1 {
+				return nil, fmt.Errorf("invalid region %q: loss must be in [0, 1)", entry)
+			}
+		}
+
+		total += r.Fraction
+		regions = append(regions, r)
+	}
+	if total > 1 {
+		return nil, fmt.Errorf("region fractions add up to %g, more than 1", total)
+	}
+	return regions, nil
+}
+
+// RegionStats reports one simulated region
+type RegionStats struct {
+	Name        string  `json:"name"`
+	Fraction    float64 `json:"fraction"`
+	Latency     string  `json:"latency"`
+	Jitter      string  `json:"jitter"`
+	Loss        float64 `json:"loss"`
+	Connections int     `json:"connections"`
+	Delayed     int64   `json:"frames_delayed"`
+	Lost        int64   `json:"frames_lost"`
+}
+
+// simRegion is a configured region with its counters (atomic)
+type simRegion struct {
+	Region
+	delayed int64
+	lost    int64
+}
+
+// regionFor assigns a user to a region by hashing their tenant and ID, so
+// the split is stable across connections and restarts. Returns nil for
+// local users.
+func (m *SSEManager) regionFor(tenantID, userID string) *simRegion {
+	if len(m.regions) == 0 {
+		return nil
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(connectionKey(tenantID, userID)))
+	point := float64(h.Sum64()%1_000_000) / 1_000_000
+
+	upper := 0.0
+	for _, r := range m.regions {
+		upper += r.Fraction
+		if point < upper {
+			return r
+		}
+	}
+	return nil
+}
+
+// regionName labels a connection's region
+func regionName(r *simRegion) string {
+	if r == nil {
+		return LocalRegion
+	}
+	return r.Name
+}
+
+// wanFrame is a frame held by a wanLink until it is due
+type wanFrame struct {
+	frame []byte
+	due   time.Time
+}
+
+// wanLink delays and drops one connection's frames as its region dictates.
+// Frames leave in order, as over TCP, so jitter never reorders them. Used
+// only by the connection's stream goroutine.
+type wanLink struct {
+	region  *simRegion
+	rng     *rand.Rand
+	queue   []wanFrame
+	lastDue time.Time
+	timer   *time.Timer
+}
+
+func newWANLink(region *simRegion) *wanLink {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return &wanLink{
+		region: region,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		timer:  timer,
+	}
+}
+
+// Push queues a frame to leave after the region's delay, or drops it
+func (l *wanLink) Push(frame []byte, now time.Time) {
+	if l.region.Loss > 0 && l.rng.Float64() < l.region.Loss {
+		atomic.AddInt64(&l.region.lost, 1)
+		metrics.SSERegionFramesLost.WithLabelValues(l.region.Name).Inc()
+		return
+	}
+
+	delay := l.region.Latency
+	if l.region.Jitter > 0 {
+		delay += time.Duration(l.rng.Int63n(int64(2*l.region.Jitter)+1)) - l.region.Jitter
+	}
+	due := now.Add(max(delay, 0))
+	if due.Before(l.lastDue) {
+		due = l.lastDue
+	}
+	l.lastDue = due
+
+	l.queue = append(l.queue, wanFrame{frame: frame, due: due})
+	atomic.AddInt64(&l.region.delayed, 1)
+	metrics.SSERegionDelay.WithLabelValues(l.region.Name).Observe(due.Sub(now).Seconds())
+	if len(l.queue) == 1 {
+		l.timer.Reset(due.Sub(now))
+	}
+}
+
+// C fires when the oldest queued frame is due; nil for a local connection
+// or an empty queue
+func (l *wanLink) C() <-chan time.Time {
+	if l == nil || len(l.queue) == 0 {
+		return nil
+	}
+	return l.timer.C
+}
+
+// Release returns the frames due by now and rearms the timer for the rest
+func (l *wanLink) Release(now time.Time) [][]byte {
+	n := 0
+	for n < len(l.queue) && !l.queue[n].due.After(now) {
+		n++
+	}
+	frames := make([][]byte, n)
+	for i := range frames {
+		frames[i] = l.queue[i].frame
+	}
+	l.queue = l.queue[n:]
+	if len(l.queue) > 0 {
+		l.timer.Reset(l.queue[0].due.Sub(now))
+	}
+	return frames
+}
+
+// Stop releases the timer
+func (l *wanLink) Stop() {
+	if l != nil {
+		l.timer.Stop()
+	}
+}
+
+// regionStats reports every simulated region. Caller holds m.mu.
+func (m *SSEManager) regionStats() []RegionStats {
+	if len(m.regions) == 0 {
+		return nil
+	}
+
+	connections := make(map[*simRegion]int)
+	for _, conns := range m.connections {
+		for _, conn := range conns {
+			if conn.region != nil {
+				connections[conn.region]++
+			}
+		}
+	}
+
+	stats := make([]RegionStats, len(m.regions))
+	for i, r := range m.regions {
+		stats[i] = RegionStats{
+			Name:        r.Name,
+			Fraction:    r.Fraction,
+			Latency:     r.Latency.String(),
+			Jitter:      r.Jitter.String(),
+			Loss:        r.Loss,
+			Connections: connections[r],
+			Delayed:     atomic.LoadInt64(&r.delayed),
+			Lost:        atomic.LoadInt64(&r.lost),
+		}
+	}
+	return stats
+}
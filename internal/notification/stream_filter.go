@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// StreamFilter narrows what a single SSE connection receives. The zero value
+// passes everything and uses the generic "notification"/"notifications"
+// event names.
+type StreamFilter struct {
+	Types       map[string]bool // Empty means all event types
+	Priorities  map[string]bool // Empty means all priorities
+	Topics      map[string]bool // Empty means all of the user's topics; notifications addressed to the user always pass
+	TypedEvents bool            // Name each frame after its event type (e.g. "event: job.new")
+}
+
+// ParseStreamFilter reads ?types=job.new,connection.request&priority=HIGH,MEDIUM,
+// ?topics=job-alerts:golang and ?event_names=typed from the request
+func ParseStreamFilter(c *gin.Context) (StreamFilter, error) {
+	return FilterUpdate{
+		Types:      splitList(c.Query("types")),
+		Priorities: splitList(c.Query("priority")),
+		Topics:     splitList(c.Query("topics")),
+		EventNames: c.Query("event_names"),
+	}.Filter()
+}
+
+// FilterUpdate is the body of PUT /notifications/stream/filter, the same
+// filter ParseStreamFilter reads from the stream's query string. It replaces
+// the connection's filter whole: omitted fields pass everything.
+type FilterUpdate struct {
+	Types      []string `json:"types"`
+	Priorities []string `json:"priority"`
+	Topics     []string `json:"topics"`      // Narrows which topics reach the connection; it doesn't subscribe to them
+	EventNames string   `json:"event_names"` // generic (default) or typed
+}
+
+// Filter validates the update and builds the filter it describes
+func (u FilterUpdate) Filter() (StreamFilter, error) {
+	var filter StreamFilter
+
+	for _, t := range u.Types {
+		if t = strings.TrimSpace(t); t != "" {
+			if filter.Types == nil {
+				filter.Types = make(map[string]bool)
+			}
+			filter.Types[t] = true
+		}
+	}
+
+	for _, p := range u.Priorities {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		switch models.Priority(p) {
+		case models.PriorityHigh, models.PriorityMedium, models.PriorityLow:
+			if filter.Priorities == nil {
+				filter.Priorities = make(map[string]bool)
+			}
+			filter.Priorities[p] = true
+		default:
+			return StreamFilter{}, fmt.Errorf("invalid priority %q (want HIGH, MEDIUM or LOW)", p)
+		}
+	}
+
+	topics, err := ParseTopics(u.Topics)
+	if err != nil {
+		return StreamFilter{}, err
+	}
+	for _, t := range topics {
+		if filter.Topics == nil {
+			filter.Topics = make(map[string]bool)
+		}
+		filter.Topics[t] = true
+	}
+
+	switch u.EventNames {
+	case "", "generic":
+	case "typed":
+		filter.TypedEvents = true
+	default:
+		return StreamFilter{}, fmt.Errorf("invalid event_names %q (want generic or typed)", u.EventNames)
+	}
+
+	return filter, nil
+}
+
+// splitList splits a comma-separated query value
+func splitList(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+// UpdateFilter replaces the filter of one of a tenant's user's connections,
+// or all of them when connectionID is empty, without reconnecting. The swap
+// is atomic: a delivery in flight uses the old filter or the new one, never
+// a mix. It returns the new filter.
+func (m *SSEManager) UpdateFilter(tenantID, userID, connectionID string, update FilterUpdate) (StreamFilter, error) {
+	filter, err := update.Filter()
+	if err != nil {
+		return StreamFilter{}, err
+	}
+
+	m.mu.RLock()
+	found := false
+	for _, conn := range m.connections[connectionKey(models.TenantOrDefault(tenantID), userID)] {
+		if connectionID == "" || conn.ID == connectionID {
+			conn.filter.Store(&filter)
+			found = true
+		}
+	}
+	m.mu.RUnlock()
+
+	if !found {
+		return StreamFilter{}, fmt.Errorf("%w %q for user: %s", ErrUnknownConnection, connectionID, userID)
+	}
+	atomic.AddInt64(&m.filterUpdates, 1)
+	metrics.SSEFilterUpdates.Inc()
+	return filter, nil
+}
+
+// Filter returns the connection's current filter
+func (conn *SSEConnection) Filter() StreamFilter {
+	return *conn.filter.Load()
+}
+
+// Matches reports whether a notification passes the filter; topic is empty
+// for notifications addressed to the user
+func (f StreamFilter) Matches(eventType, priority, topic string) bool {
+	if len(f.Types) > 0 && !f.Types[eventType] {
+		return false
+	}
+	if len(f.Priorities) > 0 && !f.Priorities[priority] {
+		return false
+	}
+	if topic != "" && len(f.Topics) > 0 && !f.Topics[topic] {
+		return false
+	}
+	return true
+}
+
+// passesAll reports whether the filter lets every notification through
+// unchanged, so frames can be shared with other unfiltered connections
+func (f StreamFilter) passesAll() bool {
+	return len(f.Types) == 0 && len(f.Priorities) == 0 && len(f.Topics) == 0 && !f.TypedEvents
+}
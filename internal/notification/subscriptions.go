@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxTopicLength matches the subscriptions.topic VARCHAR(128) column
+const MaxTopicLength = 128
+
+// topicPattern matches a topic: lower-case names joined by dots or colons
+// ("job-alerts:golang", "company.acme:hiring")
+var topicPattern = regexp.MustCompile(`^[a-z0-9_-]+([.:][a-z0-9_-]+)*$`)
+
+// ValidateTopic checks a topic name
+func ValidateTopic(topic string) error {
+	if len(topic) > MaxTopicLength {
+		return fmt.Errorf("topic exceeds %d bytes", MaxTopicLength)
+	}
+	if !topicPattern.MatchString(topic) {
+		return fmt.Errorf("invalid topic %q: expected lower-case names such as job-alerts:golang", topic)
+	}
+	return nil
+}
+
+// ParseTopics trims and validates topics, dropping empty and repeated ones
+func ParseTopics(topics []string) ([]string, error) {
+	var parsed []string
+	seen := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		topic = strings.TrimSpace(topic)
+		if topic == "" || seen[topic] {
+			continue
+		}
+		if err := ValidateTopic(topic); err != nil {
+			return nil, err
+		}
+		seen[topic] = true
+		parsed = append(parsed, topic)
+	}
+	return parsed, nil
+}
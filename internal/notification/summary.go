@@ -0,0 +1,146 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunSummary is the server side of a benchmark run's conclusion, emitted on
+// shutdown to line up with sse-bench's FINAL REPORT
+type RunSummary struct {
+	InstanceID    string    `json:"instance_id"`
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+
+	// Repository totals by status
+	Stats map[string]interface{} `json:"stats"`
+	// Per-priority event → delivery latency (p50/p95/p99) and SLO
+	// attainment over the run
+	Latency map[string]interface{} `json:"latency"`
+
+	PeakConnections int64         `json:"peak_connections"`
+	TotalAccepted   int64         `json:"total_connections_accepted"`
+	TotalRejected   int64         `json:"total_connections_rejected"`
+	PeakBacklog     int64         `json:"peak_backlog"` // Most pending notifications seen in a stats sample
+	PeakBacklogAt   time.Time     `json:"peak_backlog_at"`
+	Picker          PickerMetrics `json:"picker"`
+}
+
+// BuildRunSummary collects the run summary. Call it after the picker has
+// stopped so the final status updates are included.
+func BuildRunSummary(ctx context.Context, repo Repository, taskPicker *TaskPicker, sseManager *SSEManager, sloTargets SLOTargets, startedAt time.Time) (*RunSummary, error) {
+	stats, err := repo.GetStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	latency, err := repo.GetSLOAttainment(ctx, startedAt, sloTargets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slo attainment: %w", err)
+	}
+
+	// The closing sample counts towards the peak too
+	taskPicker.recordStats(stats)
+	peak, peakAt := taskPicker.PeakBacklog()
+
+	sse := sseManager.Stats()
+	now := time.Now()
+	return &RunSummary{
+		InstanceID:      taskPicker.instanceID,
+		StartedAt:       startedAt,
+		EndedAt:         now,
+		UptimeSeconds:   now.Sub(startedAt).Seconds(),
+		Stats:           stats,
+		Latency:         latency,
+		PeakConnections: sse.PeakConnections,
+		TotalAccepted:   sse.TotalAccepted,
+		TotalRejected:   sse.TotalRejected,
+		PeakBacklog:     peak,
+		PeakBacklogAt:   peakAt,
+		Picker:          taskPicker.Metrics(),
+	}, nil
+}
+
+// EmitRunSummary builds the run summary, logs it and, when path is set,
+// writes it there. Failures are logged; shutdown carries on.
+func EmitRunSummary(ctx context.Context, repo Repository, taskPicker *TaskPicker, sseManager *SSEManager, sloTargets SLOTargets, startedAt time.Time, path string, logger *zap.Logger) {
+	summary, err := BuildRunSummary(ctx, repo, taskPicker, sseManager, sloTargets, startedAt)
+	if err != nil {
+		logger.Error("failed to build run summary", zap.Error(err))
+		return
+	}
+
+	summary.Log(logger)
+	if path == "" {
+		return
+	}
+	if err := summary.WriteFile(path); err != nil {
+		logger.Error("failed to persist run summary", zap.Error(err))
+		return
+	}
+	logger.Info("run summary written", zap.String("path", path))
+}
+
+// Log prints the summary in the sections sse-bench uses for its report
+func (s *RunSummary) Log(logger *zap.Logger) {
+	logger.Info("=== FINAL REPORT ===",
+		zap.Float64("uptime_seconds", s.UptimeSeconds),
+		zap.Int64("peak_connections", s.PeakConnections),
+		zap.Int64("total_connections", s.TotalAccepted),
+		zap.Int64("rejected_connections", s.TotalRejected),
+		zap.Int64("peak_backlog", s.PeakBacklog),
+		zap.Time("peak_backlog_at", s.PeakBacklogAt))
+
+	statusFields := make([]zap.Field, 0, len(s.Stats))
+	for _, status := range sortedMapKeys(s.Stats) {
+		statusFields = append(statusFields, zap.Any(status, s.Stats[status]))
+	}
+	logger.Info("=== Totals by Status ===", statusFields...)
+
+	if len(s.Latency) > 0 {
+		logger.Info("=== Latency Statistics (Event → Delivery) ===")
+		for _, priority := range sortedMapKeys(s.Latency) {
+			digest, _ := s.Latency[priority].(map[string]interface{})
+			fields := []zap.Field{zap.String("priority", priority)}
+			for _, key := range sortedMapKeys(digest) {
+				fields = append(fields, zap.Any(key, digest[key]))
+			}
+			logger.Info("priority", fields...)
+		}
+	}
+
+	logger.Info("=== Task Picker ===",
+		zap.Int64("claimed", s.Picker.Claimed),
+		zap.Int64("delivered", s.Picker.Delivered),
+		zap.Int64("failed", s.Picker.Failed),
+		zap.Int64("parked", s.Picker.Parked),
+		zap.Int64("unparked", s.Picker.Unparked))
+}
+
+// WriteFile persists the summary as indented JSON
+func (s *RunSummary) WriteFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run summary: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write run summary: %w", err)
+	}
+	return nil
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
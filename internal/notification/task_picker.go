@@ -2,37 +2,139 @@ package notification
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/tracing"
 )
 
 // NotificationBatch represents a batch of notifications claimed from DB
 type NotificationBatch struct {
 	NotificationID                uuid.UUID
+	TenantID                      string
 	UserID                        string
+	Topic                         string // Topic it was published to; empty when addressed to the user
 	EventType                     string
 	Priority                      string
 	EventTimestamp                time.Time
 	NotificationReceivedTimestamp time.Time
 	Payload                       string
+	TraceID                       string
+	RetryCount                    int
+	Locale                        string    // Recipient's preferred locale, set at delivery (empty = default)
+	ExpiresAt                     time.Time // Not delivered after this; zero when it has no deadline
+
+	// Pipeline stage stamps; zero when unknown (NotificationReceivedTimestamp
+	// is when it was consumed)
+	ProducedAt  time.Time
+	PersistedAt time.Time
+	ClaimedAt   time.Time
+
+	enqueuedAt time.Time // When it was put on notificationChan
 }
 
-// StatusUpdate represents a status update to be batched
+// ChannelSSE is the delivery channel recorded for SSE attempts
+const ChannelSSE = "sse"
+
+// StatusUpdate represents a status update to be batched. Each update is also
+// one delivery attempt, appended to the attempt history.
 type StatusUpdate struct {
 	NotificationID uuid.UUID
 	Status         string
 	ErrorMsg       string
+
+	TenantID    string
+	UserID      string
+	InstanceID  string
+	WorkerID    int
+	Channel     string
+	GroupSize   int
+	Latency     time.Duration
+	AttemptedAt time.Time
+
+	// Failed attempts: not claimed again before this, zero clears it
+	NextAttemptAt time.Time
+
+	enqueuedAt time.Time // When it was put on statusUpdateChan
+	deferred   bool      // Parked by the user delivery cap, not by a missing connection
 }
 
+// ClaimPolicy controls the order in which pending notifications are claimed
+type ClaimPolicy string
+
+const (
+	// ClaimPolicyPriority claims by priority, earliest deadline first within a priority
+	ClaimPolicyPriority ClaimPolicy = "priority"
+	// ClaimPolicyDeadline claims by earliest deadline, priority breaks ties
+	ClaimPolicyDeadline ClaimPolicy = "deadline"
+)
+
+// StatsSnapshot is a point-in-time copy of repository stats
+type StatsSnapshot struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Stats     map[string]interface{} `json:"stats"`
+}
+
+// PickerMetrics is a point-in-time view of task picker state
+type PickerMetrics struct {
+	InstanceID              string `json:"instance_id"`
+	ClaimPolicy             string `json:"claim_policy"`
+	ClaimWeights            string `json:"claim_weights,omitempty"` // HIGH/MEDIUM/LOW split of each claim
+	ClaimConnectedOnly      bool   `json:"claim_connected_only"`
+	NotificationChannelSize int    `json:"notification_channel_size"`
+	NotificationChannelCap  int    `json:"notification_channel_cap"`
+	StatusUpdateChannelSize int    `json:"status_update_channel_size"`
+	StatusUpdateChannelCap  int    `json:"status_update_channel_cap"`
+	Inflight                int64  `json:"inflight"`
+	MaxInflight             int64  `json:"max_inflight"`
+	Claimed                 int64  `json:"claimed_total"`
+	Delivered               int64  `json:"delivered_total"`
+	Failed                  int64  `json:"failed_total"`
+	Parked                  int64  `json:"parked_total"`
+	Unparked                int64  `json:"unparked_total"`
+	Held                    int64  `json:"held_total"`    // Parked because their user is paused
+	Expired                 int64  `json:"expired_total"` // Claimed past their deadline or TTL, and expired instead of delivered
+	PausedUsers             int    `json:"paused_users"`
+	StoreUnavailable        int64  `json:"store_unavailable_total"` // Repository calls that failed with ErrUnavailable
+	Paused                  bool   `json:"paused"`
+
+	ClaimedByPriority map[string]int64 `json:"claimed_by_priority"`
+
+	Quotas   *QuotaStats   `json:"quotas,omitempty"`
+	Receipts *ReceiptStats `json:"receipts,omitempty"`
+}
+
+// maxStatsHistory bounds the stats history (~8h at the 30s report interval)
+const maxStatsHistory = 1000
+
+// Handling of transient repository errors (ErrUnavailable, ErrConflict)
+const (
+	minStoreBackoff  = 250 * time.Millisecond // First claim backoff while the store is unavailable
+	maxStoreBackoff  = 10 * time.Second
+	unparkRetryDelay = 5 * time.Second // Before retrying an unpark that hit an unavailable store
+
+	// Status updates kept for retry across failed flushes; beyond this the
+	// oldest are dropped and their notifications redelivered after the lease
+	maxRetainedStatusUpdates = 50000
+)
+
 // TaskPicker manages dual worker pools for maximum throughput
 // Pool 1: Picker workers claim from DB
 // Pool 2: Delivery workers send via SSE
 type TaskPicker struct {
 	instanceID string
-	repository *PostgresRepository
+	repository Repository
 	sseManager *SSEManager
 	logger     *zap.Logger
 
@@ -42,32 +144,123 @@ type TaskPicker struct {
 	batchSize          int
 	pollInterval       time.Duration
 	leaseDuration      time.Duration
+	maxInflight        int64
+	claimPolicy        ClaimPolicy
+	claimWeights       ClaimWeights
+	connectedOnly      bool
+	maxGroupSize       int
+	retryBackoff       time.Duration
+	retryBackoffMax    time.Duration
+	quotas             *DeliveryQuotas
+	consumerLag        *LagMonitor
+	pickerBeats        []int64 // Per picker worker: UnixNano of its last poll (atomic)
+
+	// Claimed-but-undelivered notifications held by this instance
+	// (in notificationChan or being delivered)
+	inflight int64
+
+	// Set by operators to stop claiming new work (in-flight work still drains)
+	paused int32
+
+	// Users whose deliveries operators paused; their notifications are parked
+	// until resumed
+	pausedMu    sync.RWMutex
+	pausedUsers map[string]PausedUser // connectionKey → pause
+
+	// Delivery outcomes for live tailing
+	audit *AuditLog
+
+	// Receipts of delivered notifications (nil = not published)
+	receipts *ReceiptPublisher
+
+	// Injected delivery errors (nil = none)
+	chaos *Chaos
+
+	// Callers waiting on a specific notification's delivery outcome
+	waitersMu   sync.Mutex
+	waiters     map[uuid.UUID]chan AuditEvent
+	waiterCount int64
+
+	// Lifetime counters
+	claimedTotal   int64
+	deliveredTotal int64
+	failedTotal    int64
+	parkedTotal    int64
+	unparkedTotal  int64
+	heldTotal      int64 // Parked because their user is paused
+	expiredTotal   int64 // Past their deadline or TTL when claimed
+
+	// Claimed by priorityRank, to show claim weights at work
+	claimedByPriority [3]int64
+
+	// Repository calls that failed with ErrUnavailable
+	storeUnavailable int64
+
+	// Users who reconnected with possibly parked notifications, drained by
+	// the unparker. A set, so a reconnect storm never blocks or drops.
+	unparkMu      sync.Mutex
+	unparkPending map[string][2]string // connectionKey → {tenantID, userID}
+	unparkSignal  chan struct{}
+
+	// Users with a scheduled unpark: parked by the delivery cap until their
+	// quota window rolls over, or retrying after an unavailable store
+	deferredUsers map[string]bool
+
+	// Repository stats sampled by the metrics reporter
+	historyMu     sync.RWMutex
+	statsHistory  []StatsSnapshot
+	peakBacklog   int64 // Most pending notifications in any sample
+	peakBacklogAt time.Time
 
 	// Channels for worker communication
-	notificationChan chan *NotificationBatch
+	notificationChan chan []*NotificationBatch // Per-user groups of claimed notifications
 	statusUpdateChan chan *StatusUpdate
 
-	// Lifecycle
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	// Lifecycle. Stop closes each channel only once its senders have
+	// exited: wg covers the pickers, unparker and background jobs (senders
+	// on notificationChan), deliveryWG the delivery workers (senders on
+	// statusUpdateChan), updaterWG the status updater.
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	deliveryWG sync.WaitGroup
+	updaterWG  sync.WaitGroup
+	stopOnce   sync.Once
 }
 
 // TaskPickerConfig holds configuration for the task picker
 type TaskPickerConfig struct {
 	InstanceID         string
-	NumPickerWorkers   int           // Number of workers claiming from DB
-	NumDeliveryWorkers int           // Number of workers delivering via SSE
-	BatchSize          int           // Notifications per claim (500)
-	PollInterval       time.Duration // How often pickers poll DB
-	LeaseDuration      time.Duration // Lease timeout (30s)
-	ChannelBufferSize  int           // Buffer between picker and delivery workers
+	NumPickerWorkers   int               // Number of workers claiming from DB
+	NumDeliveryWorkers int               // Number of workers delivering via SSE
+	BatchSize          int               // Notifications per claim (500)
+	PollInterval       time.Duration     // How often pickers poll DB
+	LeaseDuration      time.Duration     // Lease timeout (30s)
+	ChannelBufferSize  int               // Buffer (in per-user groups) between picker and delivery workers
+	MaxInflight        int               // Max claimed-but-undelivered notifications per instance
+	ClaimPolicy        ClaimPolicy       // Claim ordering (priority-first or deadline-first)
+	ClaimWeights       ClaimWeights      // Split each claim between priorities (zero value = strict policy order)
+	ClaimConnectedOnly bool              // Only claim notifications of users connected to this instance (claim weights are not applied)
+	MaxGroupSize       int               // Max notifications per user in one SSE frame (1 disables grouping)
+	RetryBackoff       time.Duration     // Backoff after a failed delivery, doubled per retry (default 1s)
+	RetryBackoffMax    time.Duration     // Backoff cap (default 5m)
+	Quotas             *DeliveryQuotas   // Delivery rate and per-user caps (nil = unlimited)
+	ConsumerLag        *LagMonitor       // Logged with the picker metrics (nil = not reported)
+	Receipts           *ReceiptPublisher // Receipts of delivered notifications (nil = not published)
+	Chaos              *Chaos            // Injected delivery errors (nil = none)
 }
 
 // NewTaskPicker creates a new task picker with dual worker pools
-func NewTaskPicker(cfg TaskPickerConfig, repo *PostgresRepository, sseManager *SSEManager, logger *zap.Logger) *TaskPicker {
+func NewTaskPicker(cfg TaskPickerConfig, repo Repository, sseManager *SSEManager, logger *zap.Logger) *TaskPicker {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.RetryBackoffMax < cfg.RetryBackoff {
+		cfg.RetryBackoffMax = max(5*time.Minute, cfg.RetryBackoff)
+	}
+
 	return &TaskPicker{
 		instanceID:         cfg.InstanceID,
 		repository:         repo,
@@ -78,7 +271,25 @@ func NewTaskPicker(cfg TaskPickerConfig, repo *PostgresRepository, sseManager *S
 		batchSize:          cfg.BatchSize,
 		pollInterval:       cfg.PollInterval,
 		leaseDuration:      cfg.LeaseDuration,
-		notificationChan:   make(chan *NotificationBatch, cfg.ChannelBufferSize),
+		maxInflight:        int64(cfg.MaxInflight),
+		claimPolicy:        cfg.ClaimPolicy,
+		claimWeights:       cfg.ClaimWeights,
+		connectedOnly:      cfg.ClaimConnectedOnly,
+		maxGroupSize:       cfg.MaxGroupSize,
+		retryBackoff:       cfg.RetryBackoff,
+		retryBackoffMax:    cfg.RetryBackoffMax,
+		quotas:             cfg.Quotas,
+		consumerLag:        cfg.ConsumerLag,
+		receipts:           cfg.Receipts,
+		chaos:              cfg.Chaos,
+		pickerBeats:        make([]int64, cfg.NumPickerWorkers),
+		audit:              NewAuditLog(),
+		waiters:            make(map[uuid.UUID]chan AuditEvent),
+		unparkPending:      make(map[string][2]string),
+		deferredUsers:      make(map[string]bool),
+		pausedUsers:        make(map[string]PausedUser),
+		unparkSignal:       make(chan struct{}, 1),
+		notificationChan:   make(chan []*NotificationBatch, cfg.ChannelBufferSize),
 		statusUpdateChan:   make(chan *StatusUpdate, cfg.ChannelBufferSize),
 		ctx:                ctx,
 		cancel:             cancel,
@@ -88,47 +299,62 @@ func NewTaskPicker(cfg TaskPickerConfig, repo *PostgresRepository, sseManager *S
 // Start starts all worker pools and background tasks
 func (tp *TaskPicker) Start() {
 	tp.logger.Info("starting task picker",
-		zap.String("instance_id", tp.instanceID),
 		zap.Int("picker_workers", tp.numPickerWorkers),
 		zap.Int("delivery_workers", tp.numDeliveryWorkers),
-		zap.Int("batch_size", tp.batchSize))
+		zap.Int("batch_size", tp.batchSize),
+		zap.Int64("max_inflight", tp.maxInflight),
+		zap.String("claim_policy", string(tp.claimPolicy)),
+		zap.Stringer("claim_weights", tp.claimWeights),
+		zap.Bool("claim_connected_only", tp.connectedOnly),
+		zap.Int("max_group_size", tp.maxGroupSize))
 
 	// Start picker workers (claim from DB)
 	for i := 0; i < tp.numPickerWorkers; i++ {
+		atomic.StoreInt64(&tp.pickerBeats[i], time.Now().UnixNano())
 		tp.wg.Add(1)
-		go tp.pickerWorker(i)
+		go runInPool(tp.ctx, PoolPickerWorker, func() { tp.pickerWorker(i) })
 	}
 
 	// Start delivery workers (send via SSE)
 	for i := 0; i < tp.numDeliveryWorkers; i++ {
-		tp.wg.Add(1)
-		go tp.deliveryWorker(i)
+		tp.deliveryWG.Add(1)
+		go runInPool(tp.ctx, PoolDeliveryWorker, func() { tp.deliveryWorker(i) })
 	}
 
 	// Start batch status updater (flushes every 1 second)
-	tp.wg.Add(1)
-	go tp.batchStatusUpdater()
+	tp.updaterWG.Add(1)
+	go runInPool(tp.ctx, PoolStatusUpdater, tp.batchStatusUpdater)
 
 	// Start lease cleanup background job
 	tp.wg.Add(1)
-	go tp.leaseCleanupWorker()
+	go runInPool(tp.ctx, PoolLeaseCleanup, tp.leaseCleanupWorker)
 
 	// Start metrics reporter
 	tp.wg.Add(1)
-	go tp.metricsReporter()
+	go runInPool(tp.ctx, PoolMetricsReporter, tp.metricsReporter)
+
+	// Un-park notifications of users as they reconnect
+	tp.sseManager.OnUserConnected(tp.requestUnpark)
+	tp.wg.Add(1)
+	go runInPool(tp.ctx, PoolUnparker, tp.unparker)
 }
 
-// Stop gracefully stops all workers
+// Stop gracefully stops all workers, flushing pending status updates.
+// Calls after the first are no-ops.
 func (tp *TaskPicker) Stop() {
-	tp.logger.Info("stopping task picker")
-	tp.cancel()
-
-	// Close channels to signal workers
-	close(tp.notificationChan)
-	close(tp.statusUpdateChan)
-
-	tp.wg.Wait()
-	tp.logger.Info("task picker stopped")
+	tp.stopOnce.Do(func() {
+		tp.logger.Info("stopping task picker")
+		tp.cancel()
+
+		// Close each channel after its senders exit, so none sends on a
+		// closed channel
+		tp.wg.Wait()
+		close(tp.notificationChan)
+		tp.deliveryWG.Wait()
+		close(tp.statusUpdateChan)
+		tp.updaterWG.Wait()
+		tp.logger.Info("task picker stopped")
+	})
 }
 
 // pickerWorker claims notifications from DB and sends to channel
@@ -140,23 +366,52 @@ func (tp *TaskPicker) pickerWorker(workerID int) {
 
 	tp.logger.Info("picker worker started", zap.Int("worker_id", workerID))
 
+	// Claims are skipped until retryAt while the store is unavailable
+	var backoff time.Duration
+	var retryAt time.Time
+
 	for {
 		select {
 		case <-ticker.C:
+			atomic.StoreInt64(&tp.pickerBeats[workerID], time.Now().UnixNano())
+			if tp.Paused() || time.Now().Before(retryAt) {
+				continue
+			}
+
+			// Reserve inflight capacity before claiming so a slow delivery
+			// pool doesn't make this instance hoard leased rows
+			reserved := tp.reserveInflight(tp.batchSize)
+			if reserved == 0 {
+				tp.logger.Debug("inflight cap reached, skipping claim",
+					zap.Int("worker_id", workerID),
+					zap.Int64("inflight", atomic.LoadInt64(&tp.inflight)))
+				continue
+			}
+
 			// Claim batch from DB
-			notifications, err := tp.repository.ClaimBatch(
-				tp.ctx,
-				tp.instanceID,
-				tp.batchSize,
-				tp.leaseDuration,
-			)
+			claimStart := time.Now()
+			notifications, err := tp.claim(reserved)
 
+			// Give back whatever we reserved but didn't claim
+			tp.releaseInflight(reserved - len(notifications))
+
+			if errors.Is(err, ErrUnavailable) {
+				backoff = min(max(2*backoff, minStoreBackoff), maxStoreBackoff)
+				retryAt = time.Now().Add(backoff)
+				tp.recordStoreUnavailable()
+				tp.logger.Warn("store unavailable, backing off claims",
+					zap.Int("worker_id", workerID),
+					zap.Duration("backoff", backoff),
+					zap.Error(err))
+				continue
+			}
 			if err != nil {
 				tp.logger.Error("failed to claim notifications",
 					zap.Int("worker_id", workerID),
 					zap.Error(err))
 				continue
 			}
+			backoff = 0
 
 			if len(notifications) == 0 {
 				// No work available
@@ -167,10 +422,21 @@ func (tp *TaskPicker) pickerWorker(workerID int) {
 				zap.Int("worker_id", workerID),
 				zap.Int("count", len(notifications)))
 
-			// Send to delivery workers via channel
-			for _, notif := range notifications {
+			tp.traceClaims(notifications, claimStart, time.Now())
+			atomic.AddInt64(&tp.claimedTotal, int64(len(notifications)))
+			for _, n := range notifications {
+				atomic.AddInt64(&tp.claimedByPriority[priorityRank(models.Priority(n.Priority))], 1)
+			}
+
+			// Group by user so a hot user's backlog goes out in one frame
+			for _, group := range groupByUser(notifications, tp.maxGroupSize, tp.sseManager.Handlers()) {
+				enqueuedAt := time.Now()
+				for _, notif := range group {
+					notif.enqueuedAt = enqueuedAt
+				}
+
 				select {
-				case tp.notificationChan <- notif:
+				case tp.notificationChan <- group:
 					// Sent successfully
 				case <-tp.ctx.Done():
 					return
@@ -184,23 +450,65 @@ func (tp *TaskPicker) pickerWorker(workerID int) {
 	}
 }
 
+// claim claims up to n notifications in policy order or, with claim
+// weights, split between priorities by weight; shares a priority can't fill
+// are topped up in policy order so weighting never leaves capacity idle.
+// In connected-only mode it claims just the work of users with a live
+// connection here, leaving offline users' notifications pending rather
+// than claiming and parking them.
+func (tp *TaskPicker) claim(n int) ([]*NotificationBatch, error) {
+	if tp.connectedOnly {
+		users := tp.sseManager.ConnectedUsers()
+		if len(users) == 0 {
+			return nil, nil
+		}
+		return tp.repository.ClaimForUsers(tp.ctx, tp.instanceID, users, n, tp.leaseDuration, tp.claimPolicy)
+	}
+	if !tp.claimWeights.Enabled() {
+		return tp.repository.ClaimBatch(tp.ctx, tp.instanceID, n, tp.leaseDuration, tp.claimPolicy)
+	}
+
+	quotas := tp.claimWeights.Quotas(n)
+	batch, err := tp.repository.ClaimWeighted(tp.ctx, tp.instanceID, quotas, tp.leaseDuration)
+	if err != nil || len(batch) >= n {
+		return batch, err
+	}
+	// Every priority was asked for some and none had any: the top-up would find nothing either
+	if len(batch) == 0 && quotas[models.PriorityHigh] > 0 && quotas[models.PriorityMedium] > 0 && quotas[models.PriorityLow] > 0 {
+		return batch, nil
+	}
+
+	rest, err := tp.repository.ClaimBatch(tp.ctx, tp.instanceID, n-len(batch), tp.leaseDuration, tp.claimPolicy)
+	if err != nil {
+		// Keep what the weighted claim leased; the next poll tops up
+		tp.logger.Warn("failed to top up weighted claim", zap.Int("claimed", len(batch)), zap.Error(err))
+		return batch, nil
+	}
+	return append(batch, rest...), nil
+}
+
 // deliveryWorker receives notifications from channel and delivers via SSE
 func (tp *TaskPicker) deliveryWorker(workerID int) {
-	defer tp.wg.Done()
+	defer tp.deliveryWG.Done()
 
 	tp.logger.Info("delivery worker started", zap.Int("worker_id", workerID))
 
 	for {
 		select {
-		case notif, ok := <-tp.notificationChan:
+		case group, ok := <-tp.notificationChan:
 			if !ok {
 				// Channel closed, shutdown
 				tp.logger.Info("delivery worker stopped", zap.Int("worker_id", workerID))
 				return
 			}
 
-			// Deliver notification
-			tp.deliverNotification(workerID, notif)
+			notificationQueueWait := metrics.QueueWaitSeconds.WithLabelValues("notification")
+			for _, notif := range group {
+				notificationQueueWait.Observe(time.Since(notif.enqueuedAt).Seconds())
+			}
+
+			// Deliver the user's notifications
+			tp.deliverGroup(workerID, group)
 
 		case <-tp.ctx.Done():
 			tp.logger.Info("delivery worker stopped", zap.Int("worker_id", workerID))
@@ -209,55 +517,341 @@ func (tp *TaskPicker) deliveryWorker(workerID int) {
 	}
 }
 
-// deliverNotification attempts to deliver a single notification
-func (tp *TaskPicker) deliverNotification(workerID int, notif *NotificationBatch) {
+// traceClaims records a claim span in each notification's trace covering
+// the batch claim query
+func (tp *TaskPicker) traceClaims(notifications []*NotificationBatch, start, end time.Time) {
+	for _, notif := range notifications {
+		_, span := tracing.Tracer().Start(tracing.WithTraceID(tp.ctx, notif.TraceID), "notification.claim",
+			trace.WithTimestamp(start),
+			trace.WithAttributes(
+				attribute.String("notification_id", notif.NotificationID.String()),
+				attribute.String("instance_id", tp.instanceID),
+				attribute.Int("batch_size", len(notifications))))
+		span.End(trace.WithTimestamp(end))
+	}
+}
+
+// groupByUser splits claimed notifications into per-user (per tenant) groups of at most
+// maxGroupSize, preserving claim order within each user. Event types whose
+// handler doesn't coalesce get a group of their own.
+func groupByUser(notifications []*NotificationBatch, maxGroupSize int, handlers *HandlerRegistry) [][]*NotificationBatch {
+	if maxGroupSize <= 1 {
+		groups := make([][]*NotificationBatch, len(notifications))
+		for i, notif := range notifications {
+			groups[i] = []*NotificationBatch{notif}
+		}
+		return groups
+	}
+
+	var groups [][]*NotificationBatch
+	open := make(map[string]int) // connection key → index of the user's open group
+
+	for _, notif := range notifications {
+		// Event types that opt out of coalescing always go alone
+		if !handlers.Lookup(notif.EventType).Coalesce() {
+			groups = append(groups, []*NotificationBatch{notif})
+			continue
+		}
+
+		key := connectionKey(notif.TenantID, notif.UserID)
+		idx, ok := open[key]
+		if !ok || len(groups[idx]) >= maxGroupSize {
+			groups = append(groups, make([]*NotificationBatch, 0, 1))
+			idx = len(groups) - 1
+			open[key] = idx
+		}
+		groups[idx] = append(groups[idx], notif)
+	}
+
+	return groups
+}
+
+// errFilteredOut marks a notification no open connection accepted, either
+// because every stream filters it out or every matching buffer was full
+var errFilteredOut = errors.New("not accepted by any connection (filtered out or buffer full)")
+
+// errExpired marks a notification past its deadline or older than its
+// event handler's TTL
+var errExpired = errors.New("expired before delivery")
+
+// errDeferred marks a notification held back because its user reached the
+// delivery cap; it is parked until the user's quota window rolls over
+var errDeferred = errors.New("deferred: user reached the delivery cap")
+
+// deliverGroup delivers a user's notifications in a single SSE frame per
+// connection (see SSEManager.SendNotifications for framing and filters).
+func (tp *TaskPicker) deliverGroup(workerID int, group []*NotificationBatch) {
+	tenantID, userID := group[0].TenantID, group[0].UserID
+
+	spans := make([]trace.Span, len(group))
+	for i, notif := range group {
+		_, spans[i] = tracing.Tracer().Start(tracing.WithTraceID(tp.ctx, notif.TraceID), "notification.deliver",
+			trace.WithAttributes(
+				attribute.String("notification_id", notif.NotificationID.String()),
+				attribute.String("tenant_id", notif.TenantID),
+				attribute.String("user_id", notif.UserID),
+				attribute.String("priority", notif.Priority),
+				attribute.Int("worker_id", workerID),
+				attribute.Int("group_size", len(group))))
+	}
+
 	startTime := time.Now()
 
-	// Attempt SSE delivery
-	err := tp.sseManager.Send(notif.UserID, map[string]interface{}{
-		"notification_id": notif.NotificationID.String(),
-		"event_type":      notif.EventType,
-		"priority":        notif.Priority,
-		"event_timestamp": notif.EventTimestamp,
-		"payload":         notif.Payload,
-	})
+	// Notifications past their deadline or their handler's TTL aren't worth
+	// sending: a backlog drained hours late shouldn't deliver stale alerts
+	handlers := make([]DeliveryHandler, len(group))
+	live := make([]*NotificationBatch, 0, len(group))
+	liveIdx := make([]int, len(group))      // Index into live, -1 when expired
+	expiredBy := make([]string, len(group)) // Expiry reason, empty when live
+	for i, notif := range group {
+		handlers[i] = tp.sseManager.Handlers().Lookup(notif.EventType)
+		if !notif.ExpiresAt.IsZero() && startTime.After(notif.ExpiresAt) {
+			expiredBy[i] = ExpiryReasonDeadline
+		} else if ttl := handlers[i].TTL(); ttl > 0 && startTime.Sub(notif.EventTimestamp) > ttl {
+			expiredBy[i] = ExpiryReasonTTL
+		}
+		if expiredBy[i] != "" {
+			liveIdx[i] = -1
+			continue
+		}
+		liveIdx[i] = len(live)
+		live = append(live, notif)
+	}
+
+	// Live notifications beyond the user's cap are deferred; the rest wait
+	// for room under the cluster-wide delivery rate. A paused user gets none.
+	paused := tp.UserPaused(tenantID, userID)
+	allowed := len(live)
+	if paused {
+		allowed = 0
+	} else if tp.quotas.Enabled() && allowed > 0 {
+		allowed = tp.quotas.AllowUser(tp.ctx, tenantID, userID, allowed)
+		tp.quotas.WaitGlobal(tp.ctx, allowed)
+	}
+
+	// Attempt SSE delivery (each connection's stream filter applies)
+	var delivered, throttled []bool
+	var sendErr error
+	if allowed > 0 {
+		if sendErr = tp.chaos.FailDelivery(); sendErr == nil {
+			delivered, throttled, sendErr = tp.sseManager.SendNotifications(tenantID, userID, live[:allowed])
+		}
+	}
 
 	deliveryLatency := time.Since(startTime)
 
-	// Queue status update (batched)
-	statusUpdate := &StatusUpdate{
-		NotificationID: notif.NotificationID,
-		Status:         "pushed",
-		ErrorMsg:       "",
+	for i, notif := range group {
+		span := spans[i]
+
+		var err error
+		switch {
+		case liveIdx[i] < 0:
+			err = errExpired
+		case paused:
+			err = errUserPaused
+		case liveIdx[i] >= allowed:
+			err = errDeferred
+		case sendErr != nil:
+			err = sendErr
+		case !delivered[liveIdx[i]] && throttled[liveIdx[i]]:
+			err = errThrottled
+		case !delivered[liveIdx[i]]:
+			err = errFilteredOut
+		}
+
+		// Queue status update (batched)
+		statusUpdate := &StatusUpdate{
+			NotificationID: notif.NotificationID,
+			Status:         "pushed",
+			ErrorMsg:       "",
+			TenantID:       notif.TenantID,
+			UserID:         notif.UserID,
+			InstanceID:     tp.instanceID,
+			WorkerID:       workerID,
+			Channel:        handlers[i].Channel(),
+			GroupSize:      len(group),
+			Latency:        deliveryLatency,
+			AttemptedAt:    startTime,
+		}
+
+		if errors.Is(err, errExpired) {
+			// Terminal, like the sweeper's expiries, and not an attempt
+			statusUpdate.Status = "expired"
+			statusUpdate.ErrorMsg = expiredBy[i]
+			statusUpdate.AttemptedAt = time.Time{}
+			span.SetAttributes(attribute.String("expired", expiredBy[i]))
+			atomic.AddInt64(&tp.expiredTotal, 1)
+			metrics.PickerExpired.WithLabelValues(notif.EventType, notif.Priority, expiredBy[i]).Inc()
+		} else if errors.Is(err, errUserPaused) {
+			// Held until an operator resumes the user, who then gets a
+			// catch-up unpark; reconnects don't release it
+			statusUpdate.Status = "parked"
+			statusUpdate.ErrorMsg = err.Error()
+			statusUpdate.AttemptedAt = time.Time{}
+			statusUpdate.deferred = true
+			span.SetAttributes(attribute.Bool("held", true))
+			atomic.AddInt64(&tp.heldTotal, 1)
+			metrics.NotificationsHeld.Inc()
+		} else if errors.Is(err, errDeferred) || errors.Is(err, errThrottled) {
+			// Not an attempt: parked rather than pending, so pickers don't
+			// re-claim it on every poll while the user is over the cap
+			statusUpdate.Status = "parked"
+			statusUpdate.ErrorMsg = err.Error()
+			statusUpdate.AttemptedAt = time.Time{}
+			statusUpdate.deferred = true
+			span.SetAttributes(attribute.Bool("deferred", true))
+			if errors.Is(err, errThrottled) {
+				tp.scheduleUnpark(tenantID, userID, time.Now().Add(bandwidthRetryDelay))
+			} else {
+				tp.scheduleUnpark(tenantID, userID, tp.quotas.UserWindowEnd())
+			}
+		} else if errors.Is(err, ErrNoConnection) {
+			// User is offline - park until they reconnect instead of
+			// failing and retrying against a missing connection
+			statusUpdate.Status = "parked"
+			statusUpdate.ErrorMsg = err.Error()
+			span.SetAttributes(attribute.Bool("parked", true))
+			atomic.AddInt64(&tp.parkedTotal, 1)
+			metrics.NotificationsParked.Inc()
+
+			tp.logger.Debug("parked notification",
+				zap.Int("worker_id", workerID),
+				zap.String("notification_id", notif.NotificationID.String()),
+				zap.String("tenant_id", notif.TenantID),
+				zap.String("user_id", notif.UserID),
+				zap.String("priority", notif.Priority))
+		} else if err != nil {
+			// Delivery failed - queue failed status, backing off before
+			// the notification can be claimed again
+			statusUpdate.Status = "failed"
+			statusUpdate.ErrorMsg = err.Error()
+			statusUpdate.NextAttemptAt = time.Now().Add(tp.backoff(notif.RetryCount))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "delivery failed")
+			atomic.AddInt64(&tp.failedTotal, 1)
+
+			tp.logger.Warn("delivery failed",
+				zap.Int("worker_id", workerID),
+				zap.String("notification_id", notif.NotificationID.String()),
+				zap.String("tenant_id", notif.TenantID),
+				zap.String("user_id", notif.UserID),
+				zap.String("priority", notif.Priority),
+				zap.Int("group_size", len(group)),
+				zap.Duration("latency", deliveryLatency),
+				zap.Error(err))
+		} else {
+			atomic.AddInt64(&tp.deliveredTotal, 1)
+			observeStages(notif, startTime, startTime.Add(deliveryLatency))
+			if tp.receipts != nil {
+				tp.receipts.Publish(newDeliveryReceipt(notif, statusUpdate, startTime, startTime.Add(deliveryLatency)))
+			}
+			tp.logger.Debug("delivered notification",
+				zap.Int("worker_id", workerID),
+				zap.String("notification_id", notif.NotificationID.String()),
+				zap.String("tenant_id", notif.TenantID),
+				zap.String("user_id", notif.UserID),
+				zap.String("priority", notif.Priority),
+				zap.Int("group_size", len(group)),
+				zap.Duration("delivery_latency", deliveryLatency))
+		}
+		span.End()
+
+		if tp.audit.HasSubscribers() || atomic.LoadInt64(&tp.waiterCount) > 0 {
+			event := AuditEvent{
+				NotificationID: notif.NotificationID.String(),
+				TenantID:       notif.TenantID,
+				UserID:         notif.UserID,
+				EventType:      notif.EventType,
+				Priority:       notif.Priority,
+				Status:         statusUpdate.Status,
+				Error:          statusUpdate.ErrorMsg,
+				GroupSize:      len(group),
+				LatencyMs:      float64(deliveryLatency.Microseconds()) / 1000,
+				InstanceID:     tp.instanceID,
+				Timestamp:      time.Now(),
+			}
+			tp.audit.Publish(event)
+			tp.notifyWaiter(notif.NotificationID, event)
+		}
+
+		// Notification is no longer held by this instance
+		tp.releaseInflight(1)
+
+		// Send to batch status updater
+		statusUpdate.enqueuedAt = time.Now()
+		select {
+		case tp.statusUpdateChan <- statusUpdate:
+			// Queued successfully
+		case <-tp.ctx.Done():
+			return
+		}
 	}
+}
 
-	if err != nil {
-		// Delivery failed - queue failed status
-		statusUpdate.Status = "failed"
-		statusUpdate.ErrorMsg = err.Error()
-
-		tp.logger.Warn("delivery failed",
-			zap.Int("worker_id", workerID),
-			zap.String("notification_id", notif.NotificationID.String()),
-			zap.String("user_id", notif.UserID),
-			zap.String("priority", notif.Priority),
-			zap.Duration("latency", deliveryLatency),
-			zap.Error(err))
-	} else {
-		tp.logger.Debug("delivered notification",
-			zap.Int("worker_id", workerID),
-			zap.String("notification_id", notif.NotificationID.String()),
-			zap.String("user_id", notif.UserID),
-			zap.String("priority", notif.Priority),
-			zap.Duration("delivery_latency", deliveryLatency))
+// Liveness returns how long ago the least recently active picker worker
+// polled; 0 before Start
+func (tp *TaskPicker) Liveness(now time.Time) time.Duration {
+	var stalest time.Duration
+	for i := range tp.pickerBeats {
+		if beat := atomic.LoadInt64(&tp.pickerBeats[i]); beat > 0 {
+			stalest = max(stalest, now.Sub(time.Unix(0, beat)))
+		}
 	}
+	return stalest
+}
 
-	// Send to batch status updater
-	select {
-	case tp.statusUpdateChan <- statusUpdate:
-		// Queued successfully
-	case <-tp.ctx.Done():
-		return
+// ChannelSaturation returns how full the picker → delivery and delivery →
+// status update channels are, from 0 to 1
+func (tp *TaskPicker) ChannelSaturation() (notifications, statusUpdates float64) {
+	if c := cap(tp.notificationChan); c > 0 {
+		notifications = float64(len(tp.notificationChan)) / float64(c)
+	}
+	if c := cap(tp.statusUpdateChan); c > 0 {
+		statusUpdates = float64(len(tp.statusUpdateChan)) / float64(c)
+	}
+	return notifications, statusUpdates
+}
+
+// reserveInflight reserves up to n slots under the inflight cap and returns
+// how many were reserved (0 when the cap is reached)
+func (tp *TaskPicker) reserveInflight(n int) int {
+	if tp.maxInflight <= 0 {
+		atomic.AddInt64(&tp.inflight, int64(n))
+		return n
+	}
+
+	for {
+		current := atomic.LoadInt64(&tp.inflight)
+		available := tp.maxInflight - current
+		if available <= 0 {
+			return 0
+		}
+
+		want := int64(n)
+		if want > available {
+			want = available
+		}
+
+		if atomic.CompareAndSwapInt64(&tp.inflight, current, current+want) {
+			return int(want)
+		}
+	}
+}
+
+// backoff is how long a notification that failed after retryCount retries
+// waits before it can be claimed again: RetryBackoff doubled per retry, capped
+func (tp *TaskPicker) backoff(retryCount int) time.Duration {
+	d := tp.retryBackoff
+	for i := 0; i < retryCount && d < tp.retryBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, tp.retryBackoffMax)
+}
+
+// releaseInflight returns n slots to the inflight budget
+func (tp *TaskPicker) releaseInflight(n int) {
+	if n > 0 {
+		atomic.AddInt64(&tp.inflight, -int64(n))
 	}
 }
 
@@ -308,14 +902,24 @@ func (tp *TaskPicker) metricsReporter() {
 				tp.logger.Error("failed to get metrics", zap.Error(err))
 				continue
 			}
+			tp.recordStats(metrics)
 
-			tp.logger.Info("task picker metrics",
-				zap.String("instance_id", tp.instanceID),
+			fields := []zap.Field{
 				zap.Int("notification_channel_size", len(tp.notificationChan)),
 				zap.Int("notification_channel_cap", cap(tp.notificationChan)),
 				zap.Int("status_update_channel_size", len(tp.statusUpdateChan)),
 				zap.Int("status_update_channel_cap", cap(tp.statusUpdateChan)),
-				zap.Any("pending_work", metrics))
+				zap.Int64("inflight", atomic.LoadInt64(&tp.inflight)),
+				zap.Int64("max_inflight", tp.maxInflight),
+				zap.Any("pending_work", metrics),
+			}
+			if tp.consumerLag != nil {
+				lag := tp.consumerLag.Report()
+				fields = append(fields,
+					zap.Int64("consumer_lag", lag.TotalLag),
+					zap.Int64("consumer_max_partition_lag", lag.MaxLag))
+			}
+			tp.logger.Info("task picker metrics", fields...)
 
 		case <-tp.ctx.Done():
 			tp.logger.Info("metrics reporter stopped")
@@ -324,9 +928,180 @@ func (tp *TaskPicker) metricsReporter() {
 	}
 }
 
+// recordStats appends a stats sample to the bounded history
+func (tp *TaskPicker) recordStats(stats map[string]interface{}) {
+	tp.historyMu.Lock()
+	defer tp.historyMu.Unlock()
+
+	now := time.Now()
+	tp.statsHistory = append(tp.statsHistory, StatsSnapshot{Timestamp: now, Stats: stats})
+	if len(tp.statsHistory) > maxStatsHistory {
+		tp.statsHistory = tp.statsHistory[len(tp.statsHistory)-maxStatsHistory:]
+	}
+
+	var pending int64
+	switch v := stats["pending"].(type) {
+	case int64:
+		pending = v
+	case float64: // Stats decoded from JSON
+		pending = int64(v)
+	}
+	if pending > tp.peakBacklog || tp.peakBacklogAt.IsZero() {
+		tp.peakBacklog, tp.peakBacklogAt = pending, now
+	}
+}
+
+// PeakBacklog returns the most pending notifications seen in a stats
+// sample and when
+func (tp *TaskPicker) PeakBacklog() (int64, time.Time) {
+	tp.historyMu.RLock()
+	defer tp.historyMu.RUnlock()
+	return tp.peakBacklog, tp.peakBacklogAt
+}
+
+// StatsHistory returns the stats samples taken at or after since
+func (tp *TaskPicker) StatsHistory(since time.Time) []StatsSnapshot {
+	tp.historyMu.RLock()
+	defer tp.historyMu.RUnlock()
+
+	history := make([]StatsSnapshot, 0, len(tp.statsHistory))
+	for _, snapshot := range tp.statsHistory {
+		if !snapshot.Timestamp.Before(since) {
+			history = append(history, snapshot)
+		}
+	}
+	return history
+}
+
+// Metrics returns the current task picker metrics
+func (tp *TaskPicker) Metrics() PickerMetrics {
+	m := PickerMetrics{
+		InstanceID:              tp.instanceID,
+		ClaimPolicy:             string(tp.claimPolicy),
+		ClaimWeights:            tp.claimWeights.String(),
+		ClaimConnectedOnly:      tp.connectedOnly,
+		NotificationChannelSize: len(tp.notificationChan),
+		NotificationChannelCap:  cap(tp.notificationChan),
+		StatusUpdateChannelSize: len(tp.statusUpdateChan),
+		StatusUpdateChannelCap:  cap(tp.statusUpdateChan),
+		Inflight:                atomic.LoadInt64(&tp.inflight),
+		MaxInflight:             tp.maxInflight,
+		Claimed:                 atomic.LoadInt64(&tp.claimedTotal),
+		ClaimedByPriority:       make(map[string]int64, len(claimPriorities)),
+		Delivered:               atomic.LoadInt64(&tp.deliveredTotal),
+		Failed:                  atomic.LoadInt64(&tp.failedTotal),
+		Parked:                  atomic.LoadInt64(&tp.parkedTotal),
+		Unparked:                atomic.LoadInt64(&tp.unparkedTotal),
+		Held:                    atomic.LoadInt64(&tp.heldTotal),
+		Expired:                 atomic.LoadInt64(&tp.expiredTotal),
+		PausedUsers:             tp.pausedUserCount(),
+		StoreUnavailable:        atomic.LoadInt64(&tp.storeUnavailable),
+		Paused:                  tp.Paused(),
+	}
+	for rank, p := range claimPriorities {
+		m.ClaimedByPriority[string(p)] = atomic.LoadInt64(&tp.claimedByPriority[rank])
+	}
+	if tp.quotas.Enabled() {
+		stats := tp.quotas.Stats()
+		m.Quotas = &stats
+	}
+	if tp.receipts != nil {
+		stats := tp.receipts.Stats()
+		m.Receipts = &stats
+	}
+	return m
+}
+
+// Pause stops picker workers from claiming new notifications. Work already
+// claimed is still delivered.
+func (tp *TaskPicker) Pause() {
+	if atomic.CompareAndSwapInt32(&tp.paused, 0, 1) {
+		tp.logger.Warn("delivery paused")
+	}
+}
+
+// Resume lets picker workers claim again
+func (tp *TaskPicker) Resume() {
+	if atomic.CompareAndSwapInt32(&tp.paused, 1, 0) {
+		tp.logger.Info("delivery resumed")
+	}
+}
+
+// Paused reports whether claiming is paused
+func (tp *TaskPicker) Paused() bool {
+	return atomic.LoadInt32(&tp.paused) == 1
+}
+
+// Drain pauses claiming and waits until every claimed notification has
+// been handed to delivery and its status update queued, or ctx ends. Call
+// it before Stop so a rolling deploy doesn't abandon claimed work to the
+// stale task reclaim.
+func (tp *TaskPicker) Drain(ctx context.Context) error {
+	tp.Pause()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		inflight := atomic.LoadInt64(&tp.inflight)
+		if inflight <= 0 && len(tp.notificationChan) == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("drain timed out with %d notifications in flight: %w", inflight, ctx.Err())
+		}
+	}
+}
+
+// WaitForDelivery registers interest in a notification's delivery outcome.
+// Register before the notification is persisted so a fast delivery isn't
+// missed; the returned func must be called to release the waiter. Only
+// deliveries made by this instance are observed.
+func (tp *TaskPicker) WaitForDelivery(id uuid.UUID) (<-chan AuditEvent, func()) {
+	ch := make(chan AuditEvent, 1)
+
+	tp.waitersMu.Lock()
+	tp.waiters[id] = ch
+	tp.waitersMu.Unlock()
+	atomic.AddInt64(&tp.waiterCount, 1)
+
+	return ch, func() {
+		tp.waitersMu.Lock()
+		if _, ok := tp.waiters[id]; ok {
+			delete(tp.waiters, id)
+			atomic.AddInt64(&tp.waiterCount, -1)
+		}
+		tp.waitersMu.Unlock()
+	}
+}
+
+// notifyWaiter hands the outcome to a registered waiter, if any
+func (tp *TaskPicker) notifyWaiter(id uuid.UUID, event AuditEvent) {
+	tp.waitersMu.Lock()
+	defer tp.waitersMu.Unlock()
+
+	if ch, ok := tp.waiters[id]; ok {
+		ch <- event
+		delete(tp.waiters, id)
+		atomic.AddInt64(&tp.waiterCount, -1)
+	}
+}
+
+// Audit returns the delivery audit log
+func (tp *TaskPicker) Audit() *AuditLog {
+	return tp.audit
+}
+
+// ReclaimNow resets expired leases immediately instead of waiting for the
+// lease cleanup worker
+func (tp *TaskPicker) ReclaimNow(ctx context.Context) (int, error) {
+	return tp.repository.ReclaimStaleTasks(ctx)
+}
+
 // batchStatusUpdater collects status updates and flushes every 1 second
 func (tp *TaskPicker) batchStatusUpdater() {
-	defer tp.wg.Done()
+	defer tp.updaterWG.Done()
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -347,14 +1122,21 @@ func (tp *TaskPicker) batchStatusUpdater() {
 				return
 			}
 
+			metrics.QueueWaitSeconds.WithLabelValues("status_update").Observe(time.Since(update.enqueuedAt).Seconds())
+
 			// Accumulate updates
 			statusBatch = append(statusBatch, update)
 
 		case <-ticker.C:
 			// Flush batch every 1 second
 			if len(statusBatch) > 0 {
-				tp.flushStatusBatch(statusBatch)
-				statusBatch = statusBatch[:0] // Reset slice
+				if tp.flushStatusBatch(statusBatch) {
+					statusBatch = statusBatch[:0] // Reset slice
+				} else if dropped := len(statusBatch) - maxRetainedStatusUpdates; dropped > 0 {
+					tp.logger.Error("dropping status updates retained for retry",
+						zap.Int("dropped", dropped))
+					statusBatch = append(statusBatch[:0], statusBatch[dropped:]...)
+				}
 			}
 
 		case <-tp.ctx.Done():
@@ -368,20 +1150,31 @@ func (tp *TaskPicker) batchStatusUpdater() {
 	}
 }
 
-// flushStatusBatch executes a batch status update to DB
-func (tp *TaskPicker) flushStatusBatch(batch []*StatusUpdate) {
+// flushStatusBatch executes a batch status update to DB. It returns false
+// if the batch failed transiently (store unavailable or a conflicting
+// transaction) and should be retried on the next flush.
+func (tp *TaskPicker) flushStatusBatch(batch []*StatusUpdate) bool {
 	if len(batch) == 0 {
-		return
+		return true
 	}
 
 	startTime := time.Now()
 
 	err := tp.repository.BatchUpdateStatus(tp.ctx, batch)
 	if err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			tp.recordStoreUnavailable()
+		}
+		if (errors.Is(err, ErrUnavailable) || errors.Is(err, ErrConflict)) && tp.ctx.Err() == nil {
+			tp.logger.Warn("status update failed transiently, retrying next flush",
+				zap.Int("batch_size", len(batch)),
+				zap.Error(err))
+			return false
+		}
 		tp.logger.Error("failed to batch update status",
 			zap.Int("batch_size", len(batch)),
 			zap.Error(err))
-		return
+		return true
 	}
 
 	flushDuration := time.Since(startTime)
@@ -389,6 +1182,152 @@ func (tp *TaskPicker) flushStatusBatch(batch []*StatusUpdate) {
 	tp.logger.Info("batch status update completed",
 		zap.Int("batch_size", len(batch)),
 		zap.Duration("duration", flushDuration))
+
+	// A user may have reconnected between the failed send and this flush,
+	// after their reconnect un-park already ran; catch those now
+	for _, update := range batch {
+		if update.Status == "parked" && !update.deferred && tp.sseManager.HasConnections(update.TenantID, update.UserID) {
+			tp.requestUnpark(update.TenantID, update.UserID)
+		}
+	}
+	return true
+}
+
+// recordStoreUnavailable counts a repository call that failed with ErrUnavailable
+func (tp *TaskPicker) recordStoreUnavailable() {
+	atomic.AddInt64(&tp.storeUnavailable, 1)
+	metrics.PickerStoreUnavailable.Inc()
+}
+
+// requestUnpark queues a user for the unparker without blocking
+func (tp *TaskPicker) requestUnpark(tenantID, userID string) {
+	tp.unparkMu.Lock()
+	tp.unparkPending[connectionKey(tenantID, userID)] = [2]string{tenantID, userID}
+	tp.unparkMu.Unlock()
+
+	select {
+	case tp.unparkSignal <- struct{}{}:
+	default:
+		// Already signaled
+	}
+}
+
+// scheduleUnpark un-parks a user's cap-deferred notifications at the given
+// time; later calls for the same user before then are no-ops
+func (tp *TaskPicker) scheduleUnpark(tenantID, userID string, at time.Time) {
+	key := connectionKey(tenantID, userID)
+
+	tp.unparkMu.Lock()
+	defer tp.unparkMu.Unlock()
+	if tp.deferredUsers[key] {
+		return
+	}
+	tp.deferredUsers[key] = true
+
+	time.AfterFunc(time.Until(at), func() {
+		tp.unparkMu.Lock()
+		delete(tp.deferredUsers, key)
+		tp.unparkMu.Unlock()
+		tp.requestUnpark(tenantID, userID)
+	})
+}
+
+// unparker claims reconnected users' parked notifications and hands them
+// straight to the delivery workers, ahead of the next poll
+func (tp *TaskPicker) unparker() {
+	defer tp.wg.Done()
+
+	tp.logger.Info("unparker started")
+
+	for {
+		select {
+		case <-tp.unparkSignal:
+			tp.unparkMu.Lock()
+			pending := tp.unparkPending
+			tp.unparkPending = make(map[string][2]string)
+			tp.unparkMu.Unlock()
+
+			for _, user := range pending {
+				if !tp.unparkUser(user[0], user[1]) {
+					return
+				}
+			}
+
+		case <-tp.ctx.Done():
+			tp.logger.Info("unparker stopped")
+			return
+		}
+	}
+}
+
+// unparkUser claims and enqueues all of a user's parked notifications. It
+// returns false if the picker is shutting down.
+func (tp *TaskPicker) unparkUser(tenantID, userID string) bool {
+	if tp.UserPaused(tenantID, userID) {
+		// Stays parked; ResumeUser unparks it
+		return true
+	}
+	start := time.Now()
+	total := 0
+
+	for {
+		notifications, err := tp.repository.ClaimParked(tp.ctx, tenantID, userID, tp.instanceID, tp.batchSize, tp.leaseDuration)
+		if errors.Is(err, ErrUnavailable) && tp.ctx.Err() == nil {
+			tp.recordStoreUnavailable()
+			tp.logger.Warn("store unavailable, retrying unpark",
+				zap.String("tenant_id", tenantID),
+				zap.String("user_id", userID),
+				zap.Duration("retry_in", unparkRetryDelay),
+				zap.Error(err))
+			tp.scheduleUnpark(tenantID, userID, time.Now().Add(unparkRetryDelay))
+			return true
+		}
+		if err != nil {
+			tp.logger.Error("failed to unpark notifications",
+				zap.String("tenant_id", tenantID),
+				zap.String("user_id", userID),
+				zap.Error(err))
+			return tp.ctx.Err() == nil
+		}
+		if len(notifications) == 0 {
+			break
+		}
+
+		// Fast-tracked work bypasses the inflight reservation but is still
+		// counted, so pickers back off while it drains
+		atomic.AddInt64(&tp.inflight, int64(len(notifications)))
+		atomic.AddInt64(&tp.claimedTotal, int64(len(notifications)))
+		atomic.AddInt64(&tp.unparkedTotal, int64(len(notifications)))
+		metrics.NotificationsUnparked.Add(float64(len(notifications)))
+		total += len(notifications)
+
+		for _, group := range groupByUser(notifications, tp.maxGroupSize, tp.sseManager.Handlers()) {
+			enqueuedAt := time.Now()
+			for _, notif := range group {
+				notif.enqueuedAt = enqueuedAt
+			}
+
+			select {
+			case tp.notificationChan <- group:
+			case <-tp.ctx.Done():
+				return false
+			}
+		}
+
+		if len(notifications) < tp.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		metrics.UnparkSeconds.Observe(time.Since(start).Seconds())
+		tp.logger.Info("unparked notifications",
+			zap.String("tenant_id", tenantID),
+			zap.String("user_id", userID),
+			zap.Int("count", total),
+			zap.Duration("duration", time.Since(start)))
+	}
+	return true
 }
 
 // metricsReporter periodically reports metrics
@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Delivery-time template variables. Payload values, titles and messages may
+// hold {{name}} placeholders, resolved when the notification is rendered for
+// delivery so they describe the moment it reaches the client rather than
+// when it was produced.
+const (
+	VarUnreadCount = "unread_count" // The user's notifications delivered by this instance, this batch included (nothing marks them read yet)
+	VarTimeAgo     = "time_ago"     // Event time relative to delivery, e.g. "2 minutes ago", in the recipient's locale
+	VarDeliveredAt = "delivered_at" // Delivery time, RFC 3339
+	VarUserID      = "user_id"
+)
+
+// hasVars reports whether s may hold a placeholder
+func hasVars(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// expandVars replaces {{name}} placeholders with resolve's value; names it
+// doesn't know are left as written
+func expandVars(s string, resolve func(name string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start+2:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 4 // Past the closing braces
+
+		b.WriteString(s[:start])
+		if value, ok := resolve(strings.TrimSpace(s[start+2 : end-2])); ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(s[start:end])
+		}
+		s = s[end:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// expandEvent resolves the placeholders of a rendered event as of now. It
+// reports whether the event had any, so plain events cost one scan.
+func (m *SSEManager) expandEvent(event *models.NotificationEvent, n *NotificationBatch, now time.Time) bool {
+	templated := hasVars(event.Title) || hasVars(event.Message)
+	for _, value := range event.Payload {
+		templated = templated || hasVars(value)
+	}
+	if !templated {
+		return false
+	}
+
+	resolve := func(name string) (string, bool) {
+		switch name {
+		case VarUnreadCount:
+			return strconv.FormatInt(m.UnreadCount(n.TenantID, n.UserID), 10), true
+		case VarTimeAgo:
+			return m.handlers.Catalog().TimeAgo(recipientLocale(n, event.Payload), now.Sub(n.EventTimestamp)), true
+		case VarDeliveredAt:
+			return now.UTC().Format(time.RFC3339), true
+		case VarUserID:
+			return n.UserID, true
+		}
+		return "", false
+	}
+	event.Title = expandVars(event.Title, resolve)
+	event.Message = expandVars(event.Message, resolve)
+	for key, value := range event.Payload {
+		if hasVars(value) {
+			event.Payload[key] = expandVars(value, resolve)
+		}
+	}
+	return true
+}
+
+// addUnread counts notifications being delivered to a tenant's user
+func (m *SSEManager) addUnread(tenantID, userID string, n int) {
+	counter, _ := m.userUnread.LoadOrStore(connectionKey(tenantID, userID), new(int64))
+	atomic.AddInt64(counter.(*int64), int64(n))
+}
+
+// UnreadCount returns the notifications this instance has delivered to a
+// tenant's user, the value of {{unread_count}}
+func (m *SSEManager) UnreadCount(tenantID, userID string) int64 {
+	counter, ok := m.userUnread.Load(connectionKey(models.TenantOrDefault(tenantID), userID))
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(counter.(*int64))
+}
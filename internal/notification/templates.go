@@ -0,0 +1,449 @@
+package notification
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// Notification templates. Titles and messages are text/template strings
+// kept in a YAML file per event type or family, so a new event type needs
+// a file entry rather than code:
+//
+//	default_locale: en
+//	templates:
+//	  job.new:
+//	    title: New Job Recommendation
+//	    message: "New job: {{.job_title}} at {{.company_name}}"
+//	    locales:
+//	      es:
+//	        title: Nueva oferta de empleo
+//	        message: "Nuevo empleo: {{.job_title}} en {{.company_name}}"
+//
+// Templates execute against the payload fields; a missing field renders
+// empty. {{t "key"}} looks a message up in the catalog in the template's
+// locale. The delivery-time variables (template_vars.go) are functions that
+// keep their placeholder, so {{time_ago}} works in a template as in a
+// payload and is resolved when the notification is delivered.
+//
+// Translations may also come from a directory of per-locale files (es.yaml,
+// pt-BR.yaml), each holding that locale's templates and messages; see
+// LoadLocaleDir.
+
+//go:embed default_templates.yaml
+var defaultTemplates []byte
+
+// LocaleField is the payload field naming the recipient's locale
+// ("es", "pt-BR"). It overrides the user's preferred locale for that
+// notification.
+const LocaleField = "locale"
+
+// TemplateConfig is a templates file
+type TemplateConfig struct {
+	DefaultLocale string                       `yaml:"default_locale"` // Locale of the base title and message (default "en")
+	Templates     map[string]TemplateSpec      `yaml:"templates"`      // Event type or family ("job.*") → templates
+	Messages      map[string]map[string]string `yaml:"messages"`       // Locale → catalog key → text
+}
+
+// TemplateSpec is the templates of one event type or family
+type TemplateSpec struct {
+	Title   string                   `yaml:"title"`
+	Message string                   `yaml:"message"`
+	Locales map[string]LocalizedText `yaml:"locales"` // Locale → translations; a blank one keeps the default locale's
+	MaxAge  time.Duration            `yaml:"max_age"` // TTL, see DeliveryHandler
+	NoGroup bool                     `yaml:"no_group"`
+}
+
+// LocalizedText is one locale's title and message
+type LocalizedText struct {
+	Title   string `yaml:"title" json:"title,omitempty"`
+	Message string `yaml:"message" json:"message,omitempty"`
+}
+
+// localeFile is one file of a locale directory
+type localeFile struct {
+	Templates map[string]TemplateSpec `yaml:"templates"`
+	Messages  map[string]string       `yaml:"messages"`
+}
+
+// LoadTemplates reads a YAML templates file; unknown keys are rejected so
+// typos don't silently fall back to the built-in text
+func LoadTemplates(path string) (*TemplateConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+	return parseTemplates(data, path)
+}
+
+func parseTemplates(data []byte, name string) (*TemplateConfig, error) {
+	cfg := newTemplateConfig("")
+	if err := decodeStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse templates %s: %w", name, err)
+	}
+	if cfg.DefaultLocale == "" {
+		cfg.DefaultLocale = FallbackLocale
+	}
+	cfg.DefaultLocale = normalizeLocale(cfg.DefaultLocale)
+	return cfg, nil
+}
+
+// LoadLocaleDir reads a directory of per-locale files, named after their
+// locale (es.yaml, pt-BR.yml), into one TemplateConfig. Each file holds
+// that locale's templates (title and message only) and catalog messages:
+//
+//	templates:
+//	  job.new:
+//	    title: Nueva oferta de empleo
+//	    message: "Nuevo empleo: {{.job_title}}"
+//	messages:
+//	  time_ago.just_now: ahora mismo
+//
+// The English file (en.yaml) holds the base text and may also set max_age
+// and no_group.
+func LoadLocaleDir(dir string) (*TemplateConfig, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locale directory: %w", err)
+	}
+
+	cfg := newTemplateConfig(FallbackLocale)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		locale := strings.TrimSuffix(entry.Name(), ext)
+		if err := ValidateLocale(locale); err != nil {
+			return nil, fmt.Errorf("locale file %s: %w", entry.Name(), err)
+		}
+		locale = normalizeLocale(locale)
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale file: %w", err)
+		}
+		var file localeFile
+		if err := decodeStrict(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse locale file %s: %w", path, err)
+		}
+
+		for pattern, spec := range file.Templates {
+			if len(spec.Locales) > 0 {
+				return nil, fmt.Errorf("locale file %s: %s: locales are set by file name", path, pattern)
+			}
+			if locale != FallbackLocale && (spec.MaxAge != 0 || spec.NoGroup) {
+				return nil, fmt.Errorf("locale file %s: %s: set max_age and no_group in %s.yaml", path, pattern, FallbackLocale)
+			}
+			if locale != FallbackLocale {
+				spec = TemplateSpec{Locales: map[string]LocalizedText{locale: {Title: spec.Title, Message: spec.Message}}}
+			}
+			cfg.merge(&TemplateConfig{DefaultLocale: FallbackLocale, Templates: map[string]TemplateSpec{pattern: spec}})
+		}
+		if len(file.Messages) > 0 {
+			cfg.merge(&TemplateConfig{DefaultLocale: FallbackLocale, Messages: map[string]map[string]string{locale: file.Messages}})
+		}
+	}
+	return cfg, nil
+}
+
+// decodeStrict decodes YAML, rejecting unknown keys
+func decodeStrict(data []byte, out interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(out)
+}
+
+func newTemplateConfig(defaultLocale string) *TemplateConfig {
+	return &TemplateConfig{
+		DefaultLocale: defaultLocale,
+		Templates:     make(map[string]TemplateSpec),
+		Messages:      make(map[string]map[string]string),
+	}
+}
+
+// builtinTemplates parses default_templates.yaml
+func builtinTemplates() *TemplateConfig {
+	cfg, err := parseTemplates(defaultTemplates, "default_templates.yaml")
+	if err != nil {
+		panic("invalid built-in templates: " + err.Error())
+	}
+	return cfg
+}
+
+// merge layers other over c: its non-empty text replaces c's per event
+// type, locale and field, and its catalog messages replace c's per key.
+// Base text written in another default locale becomes that locale's
+// translation.
+func (c *TemplateConfig) merge(other *TemplateConfig) {
+	setText := func(spec *TemplateSpec, locale string, text LocalizedText) {
+		locale = normalizeLocale(locale)
+		if locale == c.DefaultLocale {
+			if text.Title != "" {
+				spec.Title = text.Title
+			}
+			if text.Message != "" {
+				spec.Message = text.Message
+			}
+			return
+		}
+		if spec.Locales == nil {
+			spec.Locales = make(map[string]LocalizedText)
+		}
+		merged := spec.Locales[locale]
+		if text.Title != "" {
+			merged.Title = text.Title
+		}
+		if text.Message != "" {
+			merged.Message = text.Message
+		}
+		spec.Locales[locale] = merged
+	}
+
+	for pattern, spec := range other.Templates {
+		merged := c.Templates[pattern]
+		setText(&merged, other.DefaultLocale, LocalizedText{Title: spec.Title, Message: spec.Message})
+		for locale, text := range spec.Locales {
+			setText(&merged, locale, text)
+		}
+		if spec.MaxAge != 0 {
+			merged.MaxAge = spec.MaxAge
+		}
+		if spec.NoGroup {
+			merged.NoGroup = true
+		}
+		c.Templates[pattern] = merged
+	}
+
+	for locale, messages := range other.Messages {
+		locale = normalizeLocale(locale)
+		if c.Messages[locale] == nil {
+			c.Messages[locale] = make(map[string]string, len(messages))
+		}
+		for key, text := range messages {
+			c.Messages[locale][key] = text
+		}
+	}
+}
+
+// LoadHandlers returns the built-in handlers with the templates file at
+// path, then the locale directory dir, layered over them; either may be
+// empty
+func LoadHandlers(path, dir string) (*HandlerRegistry, error) {
+	cfg := builtinTemplates()
+	if path != "" {
+		file, err := LoadTemplates(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.merge(file)
+	}
+	if dir != "" {
+		locales, err := LoadLocaleDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		cfg.merge(locales)
+	}
+
+	handlers := NewHandlerRegistry()
+	if err := handlers.RegisterTemplates(cfg); err != nil {
+		return nil, fmt.Errorf("invalid templates: %w", err)
+	}
+	return handlers, nil
+}
+
+// RegisterTemplates adds cfg's messages to the catalog, then compiles every
+// template in it and registers a TemplateHandler per event type or family.
+// No handler is registered unless all of them compile.
+func (r *HandlerRegistry) RegisterTemplates(cfg *TemplateConfig) error {
+	for locale, messages := range cfg.Messages {
+		r.catalog.Add(locale, messages)
+	}
+
+	handlers := make(map[string]*TemplateHandler, len(cfg.Templates))
+	for pattern, spec := range cfg.Templates {
+		h, err := NewTemplateHandler(pattern, cfg.DefaultLocale, spec, r.catalog)
+		if err != nil {
+			return err
+		}
+		handlers[pattern] = h
+	}
+	for pattern, h := range handlers {
+		r.Register(pattern, h)
+	}
+	return nil
+}
+
+// templateFuncs are the functions of a locale's templates: t looks up the
+// catalog, and the delivery-time variables keep their placeholders for
+// expandEvent
+func templateFuncs(catalog *MessageCatalog, locale string) template.FuncMap {
+	return template.FuncMap{
+		"t":            func(key string) string { return catalog.Lookup(locale, key) },
+		VarUnreadCount: func() string { return "{{" + VarUnreadCount + "}}" },
+		VarTimeAgo:     func() string { return "{{" + VarTimeAgo + "}}" },
+		VarDeliveredAt: func() string { return "{{" + VarDeliveredAt + "}}" },
+		VarUserID:      func() string { return "{{" + VarUserID + "}}" },
+	}
+}
+
+// localizedTemplates is one locale's compiled title and message
+type localizedTemplates struct {
+	title, message *template.Template
+}
+
+// TemplateHandler adds a title and message rendered from templates in the
+// recipient's locale to the standard payload
+type TemplateHandler struct {
+	DefaultHandler
+	pattern       string
+	defaultLocale string
+	locales       map[string]*localizedTemplates
+	catalog       *MessageCatalog
+	maxAge        time.Duration
+	noGroup       bool
+}
+
+// NewTemplateHandler compiles spec's templates for an event type or family
+func NewTemplateHandler(pattern, defaultLocale string, spec TemplateSpec, catalog *MessageCatalog) (*TemplateHandler, error) {
+	defaultLocale = normalizeLocale(defaultLocale)
+	h := &TemplateHandler{
+		pattern:       pattern,
+		defaultLocale: defaultLocale,
+		locales:       make(map[string]*localizedTemplates, len(spec.Locales)+1),
+		catalog:       catalog,
+		maxAge:        spec.MaxAge,
+		noGroup:       spec.NoGroup,
+	}
+	if spec.MaxAge < 0 {
+		return nil, fmt.Errorf("%s: max_age %s must not be negative", pattern, spec.MaxAge)
+	}
+
+	base, err := h.compile(defaultLocale, LocalizedText{Title: spec.Title, Message: spec.Message})
+	if err != nil {
+		return nil, err
+	}
+	h.locales[defaultLocale] = base
+
+	for locale, text := range spec.Locales {
+		locale = normalizeLocale(locale)
+		compiled, err := h.compile(locale, text)
+		if err != nil {
+			return nil, err
+		}
+		if compiled.title == nil {
+			compiled.title = base.title
+		}
+		if compiled.message == nil {
+			compiled.message = base.message
+		}
+		h.locales[locale] = compiled
+	}
+	return h, nil
+}
+
+// compile parses one locale's templates; blank ones stay nil
+func (h *TemplateHandler) compile(locale string, text LocalizedText) (*localizedTemplates, error) {
+	funcs := templateFuncs(h.catalog, locale)
+	parse := func(field, src string) (*template.Template, error) {
+		if src == "" {
+			return nil, nil
+		}
+		name := h.pattern + "/" + locale + "/" + field
+		t, err := template.New(name).Option("missingkey=zero").Funcs(funcs).Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return t, nil
+	}
+
+	title, err := parse("title", text.Title)
+	if err != nil {
+		return nil, err
+	}
+	message, err := parse("message", text.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &localizedTemplates{title: title, message: message}, nil
+}
+
+// Render returns the standard wire event plus title and message
+func (h *TemplateHandler) Render(n *NotificationBatch) *models.NotificationEvent {
+	msg := NewNotificationEvent(n)
+	msg.Title, msg.Message = h.describe(n.EventType, recipientLocale(n, msg.Payload), msg.Payload)
+	return msg
+}
+
+// Describe returns the title and message in the recipient's locale
+func (h *TemplateHandler) Describe(n *NotificationBatch) (string, string) {
+	fields := n.Fields()
+	return h.describe(n.EventType, recipientLocale(n, fields), fields)
+}
+
+// describe executes the locale's templates. A blank template takes the
+// catalog's generic text; one that fails to execute falls back to the
+// generic title and message and is counted in
+// notification_sse_template_errors_total.
+func (h *TemplateHandler) describe(eventType, locale string, fields map[string]string) (string, string) {
+	locale = h.locale(locale)
+	texts := h.locales[locale]
+	title, titleErr := h.execute(texts.title, locale, MsgNotificationTitle, fields)
+	message, messageErr := h.execute(texts.message, locale, MsgNotificationMessage, fields)
+	if titleErr != nil || messageErr != nil {
+		metrics.SSETemplateErrors.WithLabelValues(eventType).Inc()
+		return h.catalog.Lookup(locale, MsgNotificationTitle), h.catalog.Lookup(locale, MsgNotificationMessage)
+	}
+	return title, message
+}
+
+// locale picks the handler's templates for a requested locale: the exact
+// one, its base language ("pt" for "pt-BR"), English, or the default
+func (h *TemplateHandler) locale(requested string) string {
+	for _, candidate := range localeChain(requested) {
+		if _, ok := h.locales[candidate]; ok {
+			return candidate
+		}
+	}
+	return h.defaultLocale
+}
+
+// execute renders t with the payload fields; a nil template is the
+// catalog's fallback message
+func (h *TemplateHandler) execute(t *template.Template, locale, fallback string, fields map[string]string) (string, error) {
+	if t == nil {
+		return h.catalog.Lookup(locale, fallback), nil
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Coalesce returns false when no_group is set
+func (h *TemplateHandler) Coalesce() bool { return !h.noGroup }
+
+// TTL returns max_age
+func (h *TemplateHandler) TTL() time.Duration { return h.maxAge }
+
+// recipientLocale is the locale a notification renders in: the payload's
+// locale field when set, otherwise the user's preferred locale
+func recipientLocale(n *NotificationBatch, fields map[string]string) string {
+	if locale := fields[LocaleField]; locale != "" {
+		return locale
+	}
+	return n.Locale
+}
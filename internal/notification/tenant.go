@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"notification-delivery-system/internal/auth"
+	"notification-delivery-system/internal/models"
+)
+
+// TenantHeader carries the tenant on API requests. EventSource clients,
+// which can't set headers, may use ?tenant_id= instead.
+const TenantHeader = "X-Tenant-ID"
+
+// TenantIDKey is the gin context key holding the resolved tenant ID
+const TenantIDKey = auth.TenantIDKey
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// TenantMiddleware resolves the request's tenant (header, then query, then
+// the default tenant) and stores it under TenantIDKey
+func TenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetHeader(TenantHeader)
+		if tenantID == "" {
+			tenantID = c.Query("tenant_id")
+		}
+		tenantID = models.TenantOrDefault(tenantID)
+
+		if !tenantIDPattern.MatchString(tenantID) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid tenant id"})
+			return
+		}
+
+		c.Set(TenantIDKey, tenantID)
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"crypto/tls"
+	"net/http"
+	"slices"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSConfig selects how the HTTP/SSE server terminates TLS. Either a static
+// cert/key pair or autocert domains may be set; neither means plain HTTP.
+type TLSConfig struct {
+	CertFile         string
+	KeyFile          string
+	AutocertDomains  []string
+	AutocertCacheDir string
+}
+
+// Enabled reports whether TLS is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
+}
+
+// ListenAndServe starts srv over HTTPS when TLS is configured, plain HTTP otherwise.
+// Autocert answers TLS-ALPN-01 challenges, so the server must be reachable on :443.
+func ListenAndServe(srv *http.Server, cfg TLSConfig, logger *zap.Logger) error {
+	switch {
+	case len(cfg.AutocertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		if http2Disabled(srv) {
+			// autocert offers h2 itself; clients must not negotiate it
+			srv.TLSConfig.NextProtos = slices.DeleteFunc(srv.TLSConfig.NextProtos, func(p string) bool { return p == "h2" })
+		}
+
+		logger.Info("serving HTTPS with autocert",
+			zap.String("addr", srv.Addr),
+			zap.Strings("domains", cfg.AutocertDomains))
+		return srv.ListenAndServeTLS("", "")
+
+	case cfg.CertFile != "":
+		if srv.TLSConfig == nil {
+			srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+
+		logger.Info("serving HTTPS",
+			zap.String("addr", srv.Addr),
+			zap.String("cert_file", cfg.CertFile))
+		return srv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+
+	default:
+		logger.Info("serving HTTP",
+			zap.String("addr", srv.Addr),
+			zap.Bool("h2c", srv.Protocols != nil && srv.Protocols.UnencryptedHTTP2()))
+		return srv.ListenAndServe()
+	}
+}
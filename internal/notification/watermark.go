@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LatePolicy decides what the consumer does with events whose event time is
+// too far from processing time (old replays, or clock-skewed producers)
+type LatePolicy string
+
+const (
+	// LatePolicyDeliver treats late events like any other
+	LatePolicyDeliver LatePolicy = "deliver"
+	// LatePolicyMark delivers late events but flags them (is_late) so they
+	// are left out of SLO/SLA latency statistics
+	LatePolicyMark LatePolicy = "mark"
+	// LatePolicyDrop discards late events without persisting them
+	LatePolicyDrop LatePolicy = "drop"
+)
+
+// ParseLatePolicy validates a late event policy name
+func ParseLatePolicy(raw string) (LatePolicy, error) {
+	switch policy := LatePolicy(raw); policy {
+	case LatePolicyDeliver, LatePolicyMark, LatePolicyDrop:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid late event policy %q (want deliver, mark or drop)", raw)
+	}
+}
+
+// LateEventConfig holds the consumer's late event handling
+type LateEventConfig struct {
+	Threshold time.Duration // Events further than this from processing time are late
+	Policy    LatePolicy
+}
+
+// Lateness classifies an event against the threshold: "late" when its event
+// time is older than the threshold, "future" when it is ahead of processing
+// time by more than the threshold, "" otherwise
+func (cfg LateEventConfig) Lateness(lag time.Duration) string {
+	switch {
+	case cfg.Threshold <= 0:
+		return ""
+	case lag > cfg.Threshold:
+		return "late"
+	case lag < -cfg.Threshold:
+		return "future"
+	default:
+		return ""
+	}
+}
+
+// Watermark tracks event-time progress: the highest event timestamp seen so
+// far and how far processing time runs ahead of it
+type Watermark struct {
+	mu        sync.Mutex
+	eventTime time.Time // Highest event timestamp observed
+}
+
+// Observe records an event and returns its lag (processing time minus event
+// time; negative when the event is stamped in the future)
+func (w *Watermark) Observe(eventTime, now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Future-stamped events would drag the watermark ahead of real time
+	if eventTime.After(w.eventTime) && !eventTime.After(now) {
+		w.eventTime = eventTime
+	}
+	return now.Sub(eventTime)
+}
+
+// Lag returns how far now is ahead of the watermark, or 0 before any event
+func (w *Watermark) Lag(now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.eventTime.IsZero() {
+		return 0
+	}
+	return now.Sub(w.eventTime)
+}
+
+// Time returns the current watermark
+func (w *Watermark) Time() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.eventTime
+}
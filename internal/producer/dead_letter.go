@@ -0,0 +1,22 @@
+package producer
+
+import (
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// NewDeadLetterWriter creates a writer for events the consumer refuses to
+// persist. Messages are forwarded raw, keeping their key and headers.
+func NewDeadLetterWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireOne,
+		MaxAttempts:            3,
+		BatchTimeout:           10 * time.Millisecond,
+		WriteTimeout:           10 * time.Second,
+		AllowAutoTopicCreation: true,
+	}
+}
@@ -0,0 +1,144 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+	"notification-delivery-system/internal/models"
+)
+
+// HTTPProducer publishes notification events through notification-service's
+// publish API (POST /notifications and /notifications/batch) instead of
+// Kafka, so ingestion protocols can be compared under the same load
+type HTTPProducer struct {
+	baseURL string
+	token   string // Publish-scoped bearer token; empty sends none
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// NewHTTPProducer creates a producer posting to the service at baseURL,
+// e.g. http://localhost:8080
+func NewHTTPProducer(baseURL string, logger *zap.Logger) *HTTPProducer {
+	logger.Info("http producer created", zap.String("url", baseURL))
+	return &HTTPProducer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: 64,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		logger: logger,
+	}
+}
+
+// SetToken sets the bearer token sent with every request, needed when the
+// service has auth enabled. Call before publishing.
+func (p *HTTPProducer) SetToken(token string) {
+	p.token = token
+}
+
+// PublishNotification posts one event to POST /notifications
+func (p *HTTPProducer) PublishNotification(ctx context.Context, msg *models.KafkaMessage) error {
+	stampProduced(msg)
+	err := p.post(ctx, "/notifications", msg.TenantID, msg, 1)
+	if err != nil {
+		p.logger.Error("delivery failed", zap.String("user_id", msg.UserID), zap.Error(err))
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
+}
+
+// PublishBatch posts events to POST /notifications/batch, one request per
+// tenant since the tenant travels in the X-Tenant-ID header
+func (p *HTTPProducer) PublishBatch(ctx context.Context, msgs []*models.KafkaMessage) error {
+	byTenant := make(map[string][]*models.KafkaMessage)
+	var tenants []string
+	for _, msg := range msgs {
+		stampProduced(msg)
+		if _, ok := byTenant[msg.TenantID]; !ok {
+			tenants = append(tenants, msg.TenantID)
+		}
+		byTenant[msg.TenantID] = append(byTenant[msg.TenantID], msg)
+	}
+
+	for _, tenantID := range tenants {
+		batch := byTenant[tenantID]
+		body := struct {
+			Notifications []*models.KafkaMessage `json:"notifications"`
+		}{batch}
+		if err := p.post(ctx, "/notifications/batch", tenantID, body, len(batch)); err != nil {
+			p.logger.Error("batch delivery failed", zap.Int("count", len(batch)), zap.Error(err))
+			return fmt.Errorf("failed to publish %d messages: %w", len(batch), err)
+		}
+	}
+	return nil
+}
+
+// stampProduced records when the event left the producer, unless the
+// caller already did
+func stampProduced(msg *models.KafkaMessage) {
+	if msg.ProducedAt == nil {
+		now := time.Now()
+		msg.ProducedAt = &now
+	}
+}
+
+// post sends one request carrying n events and records the outcome
+func (p *HTTPProducer) post(ctx context.Context, path, tenantID string, v any, n int) error {
+	metrics.ProducerBatchSize.Observe(float64(n))
+
+	err := func() error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if tenantID != "" {
+			req.Header.Set("X-Tenant-ID", tenantID)
+		}
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}()
+
+	result := "delivered"
+	if err != nil {
+		result = "failed"
+	}
+	metrics.ProducerMessages.WithLabelValues(result).Add(float64(n))
+	return err
+}
+
+// Close releases idle connections
+func (p *HTTPProducer) Close() {
+	p.logger.Info("closing producer")
+	p.client.CloseIdleConnections()
+}
@@ -4,19 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/compress"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"notification-delivery-system/internal/metrics"
 	"notification-delivery-system/internal/models"
+	"notification-delivery-system/internal/tracing"
 )
 
+// Publisher publishes notification events. *Producer writes to Kafka;
+// *HTTPProducer posts them to the publish API; *MemoryBus keeps them in
+// process for the all-in-one binary.
+type Publisher interface {
+	PublishNotification(ctx context.Context, msg *models.KafkaMessage) error
+}
+
+// BatchPublisher also publishes several events per call, saving a round trip
+// per event
+type BatchPublisher interface {
+	Publisher
+	PublishBatch(ctx context.Context, msgs []*models.KafkaMessage) error
+}
+
+// DeliveryFunc reports the outcome of one event published in async mode
+type DeliveryFunc func(msg *models.KafkaMessage, err error)
+
 type Producer struct {
-	writer *kafka.Writer
-	topic  string
-	logger *zap.Logger
+	writer     *kafka.Writer
+	topic      string
+	encoding   string // models.EncodingJSON or models.EncodingProtobuf
+	async      bool
+	onDelivery DeliveryFunc // Optional, async mode only
+	logger     *zap.Logger
 }
 
 func NewProducer(brokers []string, topic string, logger *zap.Logger) (*Producer, error) {
@@ -40,49 +66,196 @@ func NewProducer(brokers []string, topic string, logger *zap.Logger) (*Producer,
 		zap.String("topic", topic))
 
 	return &Producer{
-		writer: writer,
-		topic:  topic,
-		logger: logger,
+		writer:   writer,
+		topic:    topic,
+		encoding: models.EncodingJSON,
+		logger:   logger,
 	}, nil
 }
 
+// SetEncoding selects how notification events are encoded (see
+// models.ParseMessageEncoding). Consumers must be on schema 2 to read
+// protobuf. Call before publishing.
+func (p *Producer) SetEncoding(encoding string) {
+	p.encoding = encoding
+}
+
+// SetAsync makes publishing return once events are queued in the writer
+// instead of acknowledged; the writer batches them in the background and
+// reports each outcome to onDelivery (may be nil) and the producer metrics.
+// Call before publishing.
+func (p *Producer) SetAsync(onDelivery DeliveryFunc) {
+	p.async = true
+	p.onDelivery = onDelivery
+	p.writer.Async = true
+	p.writer.Completion = p.complete
+}
+
 // PublishNotification publishes a notification event to Kafka
 // Uses user_id as partition key to ensure all events for a user go to the same partition
 func (p *Producer) PublishNotification(ctx context.Context, msg *models.KafkaMessage) error {
-	data, err := json.Marshal(msg)
+	ctx, span := p.startSpan(ctx, msg)
+	defer span.End()
+
+	kafkaMsg, err := newKafkaMessage(ctx, msg, p.encoding)
+	if err != nil {
+		return err
+	}
+
+	err = p.write(ctx, kafkaMsg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "write failed")
+		p.logger.Error("delivery failed", zap.String("user_id", msg.UserID), zap.Error(err))
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	p.logger.Debug("message delivered", 
+		zap.String("user_id", msg.UserID), 
+		zap.String("event_type", msg.EventType))
+
+	return nil
+}
+
+// PublishBatch publishes several notification events in one write: in sync
+// mode it returns once all are acknowledged, in async mode once all are queued
+func (p *Producer) PublishBatch(ctx context.Context, msgs []*models.KafkaMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	kafkaMsgs := make([]kafka.Message, len(msgs))
+	spans := make([]trace.Span, 0, len(msgs))
+	defer func() {
+		for _, span := range spans {
+			span.End()
+		}
+	}()
+	for i, msg := range msgs {
+		msgCtx, span := p.startSpan(ctx, msg)
+		spans = append(spans, span)
+		kafkaMsg, err := newKafkaMessage(msgCtx, msg, p.encoding)
+		if err != nil {
+			return err
+		}
+		kafkaMsgs[i] = kafkaMsg
+	}
+
+	if err := p.write(ctx, kafkaMsgs...); err != nil {
+		for _, span := range spans {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "write failed")
+		}
+		p.logger.Error("batch delivery failed", zap.Int("count", len(msgs)), zap.Error(err))
+		return fmt.Errorf("failed to write %d messages: %w", len(msgs), err)
+	}
+	return nil
+}
+
+// startSpan starts the produce span for one event
+func (p *Producer) startSpan(ctx context.Context, msg *models.KafkaMessage) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(tracing.WithTraceID(ctx, msg.Metadata.TraceID), "kafka.produce",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", p.topic),
+			attribute.String("user_id", msg.UserID),
+			attribute.String("event_type", msg.EventType),
+			attribute.String("priority", msg.Priority)))
+}
+
+// write hands messages to the writer, recording the outcome now in sync mode
+// or leaving it to complete in async mode
+func (p *Producer) write(ctx context.Context, msgs ...kafka.Message) error {
+	metrics.ProducerBatchSize.Observe(float64(len(msgs)))
+	if p.async {
+		metrics.ProducerInflight.Add(float64(len(msgs)))
+	}
+
+	// Write with timeout
+	writeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := p.writer.WriteMessages(writeCtx, msgs...)
+	switch {
+	case err != nil:
+		// Never queued, so complete won't see them
+		if p.async {
+			metrics.ProducerInflight.Sub(float64(len(msgs)))
+		}
+		metrics.ProducerMessages.WithLabelValues("failed").Add(float64(len(msgs)))
+	case !p.async:
+		metrics.ProducerMessages.WithLabelValues("delivered").Add(float64(len(msgs)))
+	}
+	return err
+}
+
+// complete is the async writer's completion callback
+func (p *Producer) complete(messages []kafka.Message, err error) {
+	metrics.ProducerInflight.Sub(float64(len(messages)))
+	result := "delivered"
+	if err != nil {
+		result = "failed"
+		p.logger.Error("async delivery failed", zap.Int("count", len(messages)), zap.Error(err))
+	}
+	metrics.ProducerMessages.WithLabelValues(result).Add(float64(len(messages)))
+
+	if p.onDelivery == nil {
+		return
+	}
+	for _, m := range messages {
+		if msg, ok := m.WriterData.(*models.KafkaMessage); ok {
+			p.onDelivery(msg, err)
+		}
+	}
+}
+
+// PublishJSON publishes an arbitrary JSON document keyed by key, for
+// non-notification topics such as the metrics topic
+func (p *Producer) PublishJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := json.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	if err := p.write(ctx, kafka.Message{Key: []byte(key), Value: data, Time: time.Now()}); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// newKafkaMessage encodes a notification event with envelope and routing
+// headers and the current trace context
+func newKafkaMessage(ctx context.Context, msg *models.KafkaMessage, encoding string) (kafka.Message, error) {
+	stampProduced(msg)
+	data, err := models.EncodeKafkaMessage(msg, encoding)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
 	// IMPORTANT: Use user_id as partition key
 	// This ensures all notifications for the same user go to the same partition maintaining order for that user
+	// Broadcasts have no one user, so they spread by event ID instead
+	key := msg.UserID
+	if msg.IsBroadcast() {
+		key = msg.EventID
+	}
 	kafkaMsg := kafka.Message{
-		Key:   []byte(msg.UserID),
+		Key:   []byte(key),
 		Value: data,
 		Headers: []kafka.Header{
+			{Key: models.HeaderContentType, Value: []byte(encoding)},
+			{Key: models.HeaderSchemaVersion, Value: []byte(strconv.Itoa(msg.SchemaVersion))},
 			{Key: "event_type", Value: []byte(msg.EventType)},
 			{Key: "priority", Value: []byte(msg.Priority)},
 			{Key: "source_service", Value: []byte(msg.Metadata.SourceService)},
 			{Key: "trace_id", Value: []byte(msg.Metadata.TraceID)},
 		},
-		Time: time.Now(),
-	}
-
-	// Write with timeout
-	writeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	err = p.writer.WriteMessages(writeCtx, kafkaMsg)
-	if err != nil {
-		p.logger.Error("delivery failed", zap.String("user_id", msg.UserID), zap.Error(err))
-		return fmt.Errorf("failed to write message: %w", err)
+		Time:       time.Now(),
+		WriterData: msg,
 	}
+	tracing.InjectHeaders(ctx, &kafkaMsg.Headers)
 
-	p.logger.Debug("message delivered", 
-		zap.String("user_id", msg.UserID), 
-		zap.String("event_type", msg.EventType))
-
-	return nil
+	return kafkaMsg, nil
 }
 
 func (p *Producer) Close() {
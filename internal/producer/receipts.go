@@ -0,0 +1,24 @@
+package producer
+
+import (
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// NewReceiptWriter creates a writer for delivery receipts, keyed by user so a
+// user's receipts stay in order. Receipts are small and written in batches by
+// the caller; a lost receipt is not worth a long retry.
+func NewReceiptWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireOne,
+		MaxAttempts:            2,
+		BatchSize:              500,
+		BatchTimeout:           10 * time.Millisecond,
+		WriteTimeout:           5 * time.Second,
+		AllowAutoTopicCreation: true,
+	}
+}
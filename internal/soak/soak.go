@@ -0,0 +1,270 @@
+// Package soak watches a long-running binary for resource leaks: it samples
+// goroutines, live heap and open file descriptors, flags any that keep
+// growing over a window of samples and captures profiles when it does.
+package soak
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/metrics"
+)
+
+// Tracked resources, also the resource label of the metrics
+const (
+	ResourceGoroutines = "goroutines"
+	ResourceHeap       = "heap"
+	ResourceFDs        = "fds"
+)
+
+// Config tunes the detector
+type Config struct {
+	Interval        time.Duration // Sample period; 0 disables the detector
+	Window          int           // Samples a growth trend is judged over (default 20)
+	GoroutineGrowth int64         // Goroutines gained across the window that count as a leak (default 50)
+	HeapGrowth      int64         // Live heap bytes gained across the window (default 64 MiB)
+	FDGrowth        int64         // Open file descriptors gained across the window (default 50)
+	ProfileDir      string        // Where profiles are written when a leak is first suspected (default the temp dir)
+}
+
+// ConfigFromEnv reads SOAK_INTERVAL (unset disables), SOAK_WINDOW,
+// SOAK_GOROUTINE_GROWTH, SOAK_HEAP_GROWTH (bytes), SOAK_FD_GROWTH and
+// SOAK_PROFILE_DIR
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+	var err error
+	if v := os.Getenv("SOAK_INTERVAL"); v != "" {
+		if cfg.Interval, err = time.ParseDuration(v); err != nil {
+			return Config{}, fmt.Errorf("invalid SOAK_INTERVAL: %w", err)
+		}
+	}
+	if v := os.Getenv("SOAK_WINDOW"); v != "" {
+		if cfg.Window, err = strconv.Atoi(v); err != nil {
+			return Config{}, fmt.Errorf("invalid SOAK_WINDOW: %w", err)
+		}
+	}
+	for env, field := range map[string]*int64{
+		"SOAK_GOROUTINE_GROWTH": &cfg.GoroutineGrowth,
+		"SOAK_HEAP_GROWTH":      &cfg.HeapGrowth,
+		"SOAK_FD_GROWTH":        &cfg.FDGrowth,
+	} {
+		if v := os.Getenv(env); v != "" {
+			if *field, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %w", env, err)
+			}
+		}
+	}
+	cfg.ProfileDir = os.Getenv("SOAK_PROFILE_DIR")
+	return cfg, nil
+}
+
+// Sample is one reading; OpenFDs is -1 where /proc/self/fd is unavailable
+type Sample struct {
+	At         time.Time `json:"at"`
+	Goroutines int64     `json:"goroutines"`
+	HeapBytes  int64     `json:"heap_live_bytes"`
+	OpenFDs    int64     `json:"open_fds"`
+}
+
+// Detector samples resources every interval and flags steady growth
+type Detector struct {
+	cfg     Config
+	service string
+	logger  *zap.Logger
+
+	mu        sync.Mutex
+	samples   []Sample
+	suspected map[string]bool
+}
+
+// New creates a detector for service (used in profile file names), filling
+// in defaults
+func New(service string, cfg Config, logger *zap.Logger) *Detector {
+	if cfg.Window < 4 {
+		cfg.Window = 20
+	}
+	if cfg.GoroutineGrowth <= 0 {
+		cfg.GoroutineGrowth = 50
+	}
+	if cfg.HeapGrowth <= 0 {
+		cfg.HeapGrowth = 64 << 20
+	}
+	if cfg.FDGrowth <= 0 {
+		cfg.FDGrowth = 50
+	}
+	if cfg.ProfileDir == "" {
+		cfg.ProfileDir = os.TempDir()
+	}
+	return &Detector{
+		cfg:       cfg,
+		service:   service,
+		logger:    logger,
+		suspected: make(map[string]bool),
+	}
+}
+
+// Start samples every interval until ctx is done; it does nothing when the
+// interval is 0
+func (d *Detector) Start(ctx context.Context) {
+	if d.cfg.Interval <= 0 {
+		return
+	}
+	d.logger.Info("soak leak detector started",
+		zap.Duration("interval", d.cfg.Interval),
+		zap.Int("window", d.cfg.Window),
+		zap.Duration("window_span", d.cfg.Interval*time.Duration(d.cfg.Window)))
+
+	go func() {
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.Check()
+			}
+		}
+	}()
+}
+
+// Check takes a sample, updates the metrics and logs resources that start or
+// stop growing. It returns the resources currently suspected.
+func (d *Detector) Check() []string {
+	s := takeSample()
+	metrics.SoakGoroutines.Set(float64(s.Goroutines))
+	metrics.SoakHeapLiveBytes.Set(float64(s.HeapBytes))
+	if s.OpenFDs >= 0 {
+		metrics.SoakOpenFDs.Set(float64(s.OpenFDs))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.samples = append(d.samples, s)
+	if len(d.samples) > d.cfg.Window {
+		d.samples = d.samples[len(d.samples)-d.cfg.Window:]
+	}
+
+	d.judge(ResourceGoroutines, d.cfg.GoroutineGrowth, func(s Sample) int64 { return s.Goroutines })
+	d.judge(ResourceHeap, d.cfg.HeapGrowth, func(s Sample) int64 { return s.HeapBytes })
+	if s.OpenFDs >= 0 {
+		d.judge(ResourceFDs, d.cfg.FDGrowth, func(s Sample) int64 { return s.OpenFDs })
+	}
+
+	var suspected []string
+	for resource, on := range d.suspected {
+		if on {
+			suspected = append(suspected, resource)
+		}
+	}
+	sort.Strings(suspected)
+	return suspected
+}
+
+// judge updates one resource's state from the window. Caller holds d.mu.
+func (d *Detector) judge(resource string, threshold int64, value func(Sample) int64) {
+	series := make([]int64, len(d.samples))
+	for i, s := range d.samples {
+		series[i] = value(s)
+	}
+	growing := len(series) == d.cfg.Window && steadilyGrowing(series, threshold)
+	if growing == d.suspected[resource] {
+		return
+	}
+	d.suspected[resource] = growing
+
+	first, last := series[0], series[len(series)-1]
+	fields := []zap.Field{
+		zap.String("resource", resource),
+		zap.Int64("first", first),
+		zap.Int64("last", last),
+		zap.Int64("threshold", threshold),
+		zap.Duration("window", d.samples[len(d.samples)-1].At.Sub(d.samples[0].At)),
+	}
+	if !growing {
+		metrics.SoakLeakSuspected.WithLabelValues(resource).Set(0)
+		d.logger.Info("resource growth stopped", fields...)
+		return
+	}
+
+	metrics.SoakLeakSuspected.WithLabelValues(resource).Set(1)
+	profiles, err := d.writeProfiles(resource)
+	if err != nil {
+		fields = append(fields, zap.NamedError("profile_error", err))
+	}
+	d.logger.Warn("leak suspected", append(fields, zap.Strings("profiles", profiles))...)
+}
+
+// steadilyGrowing reports whether every reading in the later half of the
+// window exceeds every reading in the earlier half (so GC sawtooth and
+// short spikes don't count) and the window grew by at least threshold
+func steadilyGrowing(series []int64, threshold int64) bool {
+	half := len(series) / 2
+	var earlierMax, laterMin int64 = series[0], series[half]
+	for _, v := range series[:half] {
+		earlierMax = max(earlierMax, v)
+	}
+	for _, v := range series[half:] {
+		laterMin = min(laterMin, v)
+	}
+	return laterMin > earlierMax && series[len(series)-1]-series[0] >= threshold
+}
+
+// writeProfiles captures goroutine and heap profiles for resource
+func (d *Detector) writeProfiles(resource string) ([]string, error) {
+	if err := os.MkdirAll(d.cfg.ProfileDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profile dir: %w", err)
+	}
+
+	var paths []string
+	stamp := time.Now().UTC().Format("20060102T150405")
+	for _, name := range []string{"goroutine", "heap"} {
+		path := filepath.Join(d.cfg.ProfileDir, fmt.Sprintf("%s-%s-%s.%s.pprof", d.service, resource, stamp, name))
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create profile: %w", err)
+		}
+		err = pprof.Lookup(name).WriteTo(f, 0)
+		f.Close()
+		if err != nil {
+			return paths, fmt.Errorf("failed to write %s profile: %w", name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// liveHeapMetric is the heap marked live by the last GC, unaffected by
+// garbage waiting to be collected
+const liveHeapMetric = "/gc/heap/live:bytes"
+
+func takeSample() Sample {
+	heap := []rtmetrics.Sample{{Name: liveHeapMetric}}
+	rtmetrics.Read(heap)
+
+	s := Sample{
+		At:         time.Now(),
+		Goroutines: int64(runtime.NumGoroutine()),
+		OpenFDs:    -1,
+	}
+	if heap[0].Value.Kind() == rtmetrics.KindUint64 {
+		s.HeapBytes = int64(heap[0].Value.Uint64())
+	}
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		s.OpenFDs = int64(len(fds))
+	}
+	return s
+}
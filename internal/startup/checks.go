@@ -0,0 +1,121 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// ProducerUserPrefix is the user ID prefix every built-in producer emits (user_1..user_N)
+const ProducerUserPrefix = "user_"
+
+// Check is a single startup validation
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Run executes all checks and returns every failure joined, so operators
+// fix everything in one pass instead of one restart per problem
+func Run(ctx context.Context, logger *zap.Logger, checks ...Check) error {
+	var errs []error
+	for _, check := range checks {
+		start := time.Now()
+		if err := check.Run(ctx); err != nil {
+			logger.Error("startup check failed",
+				zap.String("check", check.Name),
+				zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, err))
+			continue
+		}
+		logger.Info("startup check passed",
+			zap.String("check", check.Name),
+			zap.Duration("took", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
+
+// KafkaTopicExists verifies the topic exists on the first reachable broker.
+// kafka-go readers don't create topics, so a missing topic means the
+// consumer would block forever without an error.
+func KafkaTopicExists(brokers []string, topic string) Check {
+	return Check{
+		Name: "kafka_topic",
+		Run: func(ctx context.Context) error {
+			var lastErr error
+			for _, broker := range brokers {
+				dialer := &kafka.Dialer{Timeout: 5 * time.Second}
+				conn, err := dialer.DialContext(ctx, "tcp", broker)
+				if err != nil {
+					lastErr = fmt.Errorf("cannot reach broker %s: %w (check KAFKA_BROKERS)", broker, err)
+					continue
+				}
+
+				partitions, err := conn.ReadPartitions(topic)
+				conn.Close()
+				if err != nil {
+					return fmt.Errorf("topic %q not found on %s: %w (create it with `make kafka-create-topic` or fix KAFKA_TOPIC)", topic, broker, err)
+				}
+				if len(partitions) == 0 {
+					return fmt.Errorf("topic %q has no partitions", topic)
+				}
+				return nil
+			}
+			return lastErr
+		},
+	}
+}
+
+// SchemaVerifier is implemented by repositories that can check their schema
+type SchemaVerifier interface {
+	VerifySchema(ctx context.Context) error
+}
+
+// RepositorySchema verifies the notifications table and required columns exist
+func RepositorySchema(repo SchemaVerifier) Check {
+	return Check{
+		Name: "repository_schema",
+		Run: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			return repo.VerifySchema(ctx)
+		},
+	}
+}
+
+// UserPopulation checks that the user IDs the bench will connect with can
+// actually receive events from the producers. Skipped when neither is known.
+func UserPopulation(expectedPrefix string, numUsers int) Check {
+	return Check{
+		Name: "user_population",
+		Run: func(ctx context.Context) error {
+			if expectedPrefix != "" && expectedPrefix != ProducerUserPrefix {
+				return fmt.Errorf("BENCH_USER_PREFIX=%q but producers emit %s1..%sN; bench clients would never receive notifications",
+					expectedPrefix, ProducerUserPrefix, ProducerUserPrefix)
+			}
+			if numUsers < 0 {
+				return fmt.Errorf("NUM_USERS must be positive, got %d", numUsers)
+			}
+			return nil
+		},
+	}
+}
+
+// PortAvailable verifies a TCP listen address is free before workers start
+func PortAvailable(name, addr string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("cannot listen on %s: %w", addr, err)
+			}
+			return ln.Close()
+		},
+	}
+}
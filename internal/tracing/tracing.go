@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const tracerName = "notification-delivery-system"
+
+// Config holds OpenTelemetry tracing configuration
+type Config struct {
+	Enabled      bool
+	OTLPEndpoint string // OTLP/HTTP collector: a base URL as in OTEL_EXPORTER_OTLP_ENDPOINT, or host:port
+	Insecure     bool   // Use plain HTTP to a host:port endpoint; a URL's scheme decides instead
+	ServiceName  string
+	SampleRatio  float64 // Fraction of traces sampled (0-1)
+}
+
+// Init installs the global tracer provider and W3C propagator.
+// When tracing is disabled the global no-op provider is kept.
+// The returned function flushes and shuts down the exporter.
+func Init(ctx context.Context, cfg Config, logger *zap.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpointURL, err := exporterURL(cfg.OTLPEndpoint, cfg.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithIDGenerator(&idGenerator{}),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	logger.Info("tracing initialized",
+		zap.String("otlp_endpoint", endpointURL),
+		zap.String("service_name", cfg.ServiceName),
+		zap.Float64("sample_ratio", cfg.SampleRatio))
+
+	return provider.Shutdown, nil
+}
+
+// exporterURL resolves the configured endpoint to the traces URL. A base URL
+// gets the signal path appended, as the OTLP spec has exporters do for
+// OTEL_EXPORTER_OTLP_ENDPOINT; a bare host:port takes its scheme from insecure.
+func exporterURL(endpoint string, insecure bool) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https"
+		if insecure {
+			scheme = "http"
+		}
+		endpoint = scheme + "://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid otlp endpoint %q", endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	return u.String(), nil
+}
+
+// Tracer returns the pipeline tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+type traceIDKey struct{}
+
+// WithTraceID makes root spans started from ctx use the given business
+// trace ID (a UUID generated by producers) as their OpenTelemetry trace ID,
+// so every pipeline stage lands in the same trace even across the database
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	id, err := uuid.Parse(traceID)
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, trace.TraceID(id))
+}
+
+// idGenerator honours trace IDs set via WithTraceID and falls back to
+// random IDs otherwise
+type idGenerator struct{}
+
+func (g *idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	traceID, ok := ctx.Value(traceIDKey{}).(trace.TraceID)
+	if !ok || !traceID.IsValid() {
+		_, _ = crand.Read(traceID[:])
+	}
+	return traceID, g.NewSpanID(ctx, traceID)
+}
+
+func (g *idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	_, _ = crand.Read(spanID[:])
+	return spanID
+}
+
+// HeaderCarrier adapts Kafka message headers to a propagation.TextMapCarrier
+type HeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+// Get returns the value of the first header with the given key
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces or appends a header
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if strings.EqualFold(h.Key, key) {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys lists all header keys
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// InjectHeaders writes the span context from ctx into Kafka headers
+func InjectHeaders(ctx context.Context, headers *[]kafka.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier{Headers: headers})
+}
+
+// ExtractHeaders returns ctx carrying the span context found in Kafka headers
+func ExtractHeaders(ctx context.Context, headers []kafka.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier{Headers: &headers})
+}
+
+// ConfigFromEnv builds a tracing config for binaries without a config file.
+// Tracing is enabled when OTEL_EXPORTER_OTLP_ENDPOINT is set.
+func ConfigFromEnv(serviceName string) Config {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	return Config{
+		Enabled:      endpoint != "",
+		OTLPEndpoint: endpoint,
+		Insecure:     os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") != "false",
+		ServiceName:  serviceName,
+		SampleRatio:  1.0,
+	}
+}
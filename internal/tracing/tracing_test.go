@@ -0,0 +1,30 @@
+package tracing
+
+import "testing"
+
+func TestExporterURL(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint string
+		insecure bool
+		want     string
+	}{
+		{"localhost:4318", true, "http://localhost:4318/v1/traces"},
+		{"collector:4318", false, "https://collector:4318/v1/traces"},
+		// OTEL_EXPORTER_OTLP_ENDPOINT is a base URL; its scheme wins
+		{"http://collector:4318", false, "http://collector:4318/v1/traces"},
+		{"https://collector:4318/", true, "https://collector:4318/v1/traces"},
+		{"https://gateway.example.com/otlp", false, "https://gateway.example.com/otlp/v1/traces"},
+	} {
+		got, err := exporterURL(tc.endpoint, tc.insecure)
+		if err != nil {
+			t.Fatalf("exporterURL(%q): %v", tc.endpoint, err)
+		}
+		if got != tc.want {
+			t.Errorf("exporterURL(%q, %v) = %q, want %q", tc.endpoint, tc.insecure, got, tc.want)
+		}
+	}
+
+	if _, err := exporterURL("http://", false); err == nil {
+		t.Error("exporterURL accepted an endpoint without a host")
+	}
+}
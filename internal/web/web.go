@@ -0,0 +1,19 @@
+package web
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed demo.html
+var demoPage []byte
+
+// RegisterRoutes mounts the demo UI at /demo. The page opens an EventSource
+// to /notifications/stream for the chosen user and renders what arrives.
+func RegisterRoutes(router gin.IRouter) {
+	router.GET("/demo", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", demoPage)
+	})
+}
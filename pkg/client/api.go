@@ -0,0 +1,159 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"time"
+)
+
+// ErrUnknownConnection is returned by Ack and UpdateFilter when the server
+// has no stream with the connection ID (it ended, or the server lost it)
+var ErrUnknownConnection = errors.New("unknown connection")
+
+// ReconnectError ends a stream the server closed after a reconnect event
+// (draining or shedding load)
+type ReconnectError struct {
+	After time.Duration
+}
+
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("server asked for a reconnect after %s", e.After)
+}
+
+// RejectedError ends a stream setup the server refused with a retry hint
+type RejectedError struct {
+	Status int
+	Reason string // capacity, draining, rate_limited; "unknown" when the body names none
+	After  time.Duration
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("rejected (%d %s), retry after %s", e.Status, e.Reason, e.After)
+}
+
+// StatusError is an unexpected response to Ack or UpdateFilter
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Status)
+}
+
+// parseRejection reads the retry hint of a 503 or 429 stream response,
+// preferring the body's retry_after_ms over the whole-second Retry-After
+// header; nil means the response carries no hint
+func parseRejection(resp *http.Response) *RejectedError {
+	if resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	var body struct {
+		Reason       string `json:"reason"`
+		RetryAfterMs int64  `json:"retry_after_ms"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body)
+
+	rejected := &RejectedError{Status: resp.StatusCode, Reason: body.Reason}
+	if rejected.Reason == "" {
+		rejected.Reason = "unknown"
+	}
+	switch header := resp.Header.Get("Retry-After"); {
+	case body.RetryAfterMs > 0:
+		rejected.After = time.Duration(body.RetryAfterMs) * time.Millisecond
+	case header == "":
+		return nil
+	default:
+		if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+			rejected.After = time.Duration(secs) * time.Second
+		} else if at, err := http.ParseTime(header); err == nil {
+			rejected.After = max(time.Until(at), 0)
+		} else {
+			return nil
+		}
+	}
+	return rejected
+}
+
+// newRequest builds a request carrying the client's tenant and token
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.TenantID != "" {
+		req.Header.Set("X-Tenant-ID", c.cfg.TenantID)
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	return req, nil
+}
+
+// connectionURL is a per-connection endpoint under /notifications/stream
+func (c *Client) connectionURL(path, connectionID string) string {
+	return fmt.Sprintf("%s/notifications/stream/%s?user_id=%s&connection_id=%s",
+		c.cfg.ServerURL, path, neturl.QueryEscape(c.cfg.UserID), neturl.QueryEscape(connectionID))
+}
+
+// do sends req, discarding the body, and maps 404 to ErrUnknownConnection
+// and any other status but want to a *StatusError
+func (c *Client) do(req *http.Request, want int) error {
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case want:
+		return nil
+	case http.StatusNotFound:
+		return ErrUnknownConnection
+	default:
+		return &StatusError{Status: resp.StatusCode}
+	}
+}
+
+// Ack echoes a heartbeat for the connection (POST
+// /notifications/stream/heartbeat), telling a server that tracks liveness
+// the client is still reading. The client acks every heartbeat itself
+// unless Silent; call it directly to ack from elsewhere.
+func (c *Client) Ack(ctx context.Context, connectionID string) error {
+	req, err := c.newRequest(ctx, http.MethodPost, c.connectionURL("heartbeat", connectionID), nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, http.StatusNoContent)
+}
+
+// FilterUpdate replaces what a stream receives; omitted fields pass
+// everything
+type FilterUpdate struct {
+	Types      []string `json:"types,omitempty"`
+	Priorities []string `json:"priority,omitempty"`
+	Topics     []string `json:"topics,omitempty"`      // Narrows the topics reaching the stream; doesn't subscribe
+	EventNames string   `json:"event_names,omitempty"` // generic (default) or typed
+}
+
+// UpdateFilter replaces the connection's filter without reconnecting (PUT
+// /notifications/stream/filter)
+func (c *Client) UpdateFilter(ctx context.Context, connectionID string, update FilterUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPut, c.connectionURL("filter", connectionID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, http.StatusOK)
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorMapping(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		call       func(c *Client) error
+		method     string
+		path       string
+		status     int
+		wantErr    error // Matched with errors.Is
+		wantStatus int   // Status of the expected *StatusError, 0 for none
+	}{
+		{name: "ack", call: ack, method: http.MethodPost, path: "/notifications/stream/heartbeat", status: http.StatusNoContent},
+		{name: "ack unknown connection", call: ack, method: http.MethodPost, path: "/notifications/stream/heartbeat", status: http.StatusNotFound, wantErr: ErrUnknownConnection},
+		{name: "ack unauthorized", call: ack, method: http.MethodPost, path: "/notifications/stream/heartbeat", status: http.StatusUnauthorized, wantStatus: http.StatusUnauthorized},
+		{name: "ack wrong success", call: ack, method: http.MethodPost, path: "/notifications/stream/heartbeat", status: http.StatusOK, wantStatus: http.StatusOK},
+		{name: "filter", call: updateFilter, method: http.MethodPut, path: "/notifications/stream/filter", status: http.StatusOK},
+		{name: "filter unknown connection", call: updateFilter, method: http.MethodPut, path: "/notifications/stream/filter", status: http.StatusNotFound, wantErr: ErrUnknownConnection},
+		{name: "filter bad request", call: updateFilter, method: http.MethodPut, path: "/notifications/stream/filter", status: http.StatusBadRequest, wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tc.method || r.URL.Path != tc.path {
+					t.Errorf("request %s %s, want %s %s", r.Method, r.URL.Path, tc.method, tc.path)
+				}
+				if got := r.URL.Query(); got.Get("user_id") != "user 1" || got.Get("connection_id") != "conn_1" {
+					t.Errorf("query = %v", got)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer token" {
+					t.Errorf("Authorization = %q", got)
+				}
+				if got := r.Header.Get("X-Tenant-ID"); got != "acme" {
+					t.Errorf("X-Tenant-ID = %q", got)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{"error":"body is discarded"}`))
+			}))
+			defer server.Close()
+
+			c := New(Config{ServerURL: server.URL, UserID: "user 1", TenantID: "acme", Token: "token"})
+			err := tc.call(c)
+
+			var statusErr *StatusError
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+			case tc.wantStatus != 0:
+				if !errors.As(err, &statusErr) || statusErr.Status != tc.wantStatus {
+					t.Fatalf("err = %v, want status %d", err, tc.wantStatus)
+				}
+			case err != nil:
+				t.Fatalf("err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func ack(c *Client) error {
+	return c.Ack(context.Background(), "conn_1")
+}
+
+func updateFilter(c *Client) error {
+	return c.UpdateFilter(context.Background(), "conn_1", FilterUpdate{Types: []string{"job.new"}, EventNames: "typed"})
+}
+
+func TestUpdateFilterBody(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	c := New(Config{ServerURL: server.URL, UserID: "user_1"})
+	if err := c.UpdateFilter(context.Background(), "conn_1", FilterUpdate{
+		Priorities: []string{"HIGH"},
+		Topics:     []string{"job-alerts:golang"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Omitted fields stay out of the body so they pass everything
+	body, _ := json.Marshal(got)
+	if want := `{"priority":["HIGH"],"topics":["job-alerts:golang"]}`; string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestAPIConnectError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	c := New(Config{ServerURL: server.URL, UserID: "user_1"})
+	err := c.Ack(context.Background(), "conn_1")
+	var statusErr *StatusError
+	if err == nil || errors.Is(err, ErrUnknownConnection) || errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want a transport error", err)
+	}
+}
+
+func TestParseRejection(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		want       *RejectedError // nil when the response carries no retry hint
+	}{
+		{
+			name:   "body hint",
+			status: http.StatusServiceUnavailable,
+			body:   `{"reason":"draining","retry_after_ms":1500}`,
+			want:   &RejectedError{Status: 503, Reason: "draining", After: 1500 * time.Millisecond},
+		},
+		{
+			name:       "body hint beats header",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "10",
+			body:       `{"reason":"rate_limited","retry_after_ms":250}`,
+			want:       &RejectedError{Status: 429, Reason: "rate_limited", After: 250 * time.Millisecond},
+		},
+		{
+			name:       "header seconds",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "3",
+			body:       `{"reason":"capacity"}`,
+			want:       &RejectedError{Status: 503, Reason: "capacity", After: 3 * time.Second},
+		},
+		{
+			name:       "no reason",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "2",
+			body:       `not json`,
+			want:       &RejectedError{Status: 503, Reason: "unknown", After: 2 * time.Second},
+		},
+		{name: "no hint", status: http.StatusServiceUnavailable, body: `{"reason":"capacity"}`},
+		{name: "bad header", status: http.StatusServiceUnavailable, retryAfter: "soon"},
+		{name: "not a rejection status", status: http.StatusInternalServerError, retryAfter: "3"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tc.status,
+				Header:     http.Header{},
+				Body:       http.NoBody,
+			}
+			if tc.retryAfter != "" {
+				resp.Header.Set("Retry-After", tc.retryAfter)
+			}
+			if tc.body != "" {
+				resp.Body = io.NopCloser(strings.NewReader(tc.body))
+			}
+
+			got := parseRejection(resp)
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Fatalf("parseRejection = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+// Package client is a Go client for the notification service's SSE stream.
+// It connects, reconnects with backoff (honouring the server's retry hints,
+// rejections and reconnect events, and resuming with Last-Event-ID), decodes
+// compressed streams, echoes heartbeats and hands notifications to typed
+// callbacks. sse-bench runs every one of its streams on it.
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Why a client reconnects, passed to Handlers.OnReconnect
+const (
+	ReconnectDropped   = "dropped"   // Drop ended the stream
+	ReconnectRequested = "requested" // The server sent a reconnect event (draining, shedding load)
+	ReconnectRejected  = "rejected"  // The server refused the stream with a retry hint
+	ReconnectBackoff   = "backoff"   // The stream failed; backing off exponentially
+)
+
+// NotificationFunc receives one notification and when it arrived
+type NotificationFunc func(event *models.NotificationEvent, receivedAt time.Time)
+
+// StreamInfo describes an established stream
+type StreamInfo struct {
+	Proto      string // HTTP/1.1 or HTTP/2.0
+	InstanceID string // Server instance (X-Instance-ID), empty if the server names none
+	Encoding   string // Content-Encoding of the stream, empty for identity
+}
+
+// Connected is the data of the server's "connected" frame
+type Connected struct {
+	Region          string `json:"region"`
+	ConnectionID    string `json:"connection_id"`
+	LivenessTimeout string `json:"liveness_timeout"` // Set when the server expects heartbeat echoes
+}
+
+// Handlers are the client's callbacks, all optional. They run on the
+// stream's goroutine, one at a time, so they should return quickly.
+type Handlers struct {
+	OnConnect      func(info StreamInfo)
+	OnConnected    func(ctx context.Context, connected Connected) // ctx ends with the stream
+	OnNotification NotificationFunc                               // Every notification, single, grouped or typed
+	ByType         map[string]NotificationFunc                    // Notifications of one event type, e.g. "job.new", after OnNotification
+	OnHeartbeat    func()
+	OnDisconnect   func(info StreamInfo)
+	OnReconnect    func(reason string, after time.Duration) // Before waiting to reconnect
+	OnRejected     func(rejected *RejectedError)
+	OnError        func(err error, retries int) // A stream failed; retries is how many in a row so far
+	OnGiveUp       func(err error)              // The client stopped after err (no reconnect, or out of retries)
+	OnViolation    func(kind string)            // Protocol violations, see the Violation constants
+	OnNewConn      func()                       // A new TCP connection was dialed; HTTP/2 streams share one
+}
+
+// Config configures one stream
+type Config struct {
+	ServerURL string // e.g. http://localhost:8080
+	UserID    string
+	TenantID  string // Sent as X-Tenant-ID, empty for the default tenant
+	Token     string // Bearer token, empty when auth is disabled
+
+	// What the stream receives (?types=, ?priority=, ?event_names=typed);
+	// empty passes everything. Typed streams name each frame after its event
+	// type; both kinds reach the same handlers.
+	Types       []string
+	Priorities  []string
+	TypedEvents bool
+
+	// Topics (?topics=job-alerts:golang) subscribe the user to them when the
+	// stream opens, and topic notifications reach this stream only for them
+	Topics []string
+
+	HTTPClient  *http.Client // Shared by many clients so they pool connections; nil = a client without timeout
+	Compression string       // Accept-Encoding to request (gzip, br), empty for identity
+
+	Reconnect        bool          // Reconnect after the stream fails or the server asks
+	MaxRetries       int           // Failed streams in a row before giving up (default 10)
+	RetryDelay       time.Duration // Backoff base, doubled per failure up to MaxBackoff (default 1s); the server's retry: hint replaces it
+	MaxBackoff       time.Duration // Default 30s
+	Jitter           time.Duration // Random extra wait, up to this, before every reconnect
+	IgnoreRetryAfter bool          // Back off exponentially from rejections instead of waiting their Retry-After
+	PingTimeout      time.Duration // A stream silent this long is dropped (default PingTimeoutFor(30s))
+	Silent           bool          // Never echo heartbeats, so the server treats the stream as offline
+
+	// Shared semaphore bounding concurrent reconnects (nil = unlimited); a
+	// slot is held from dialing until the stream is established
+	ReconnectSlots chan struct{}
+
+	// Bytes read off the wire and after decompression, added atomically
+	// (nil = not counted)
+	WireBytes, DecodedBytes *int64
+
+	Handlers Handlers
+	Logger   *zap.Logger
+}
+
+// PingTimeoutFor allows a heartbeat to arrive a little late (30s → 35s)
+// before the stream is considered silent
+func PingTimeoutFor(heartbeat time.Duration) time.Duration {
+	grace := heartbeat / 6
+	if grace < time.Second {
+		grace = time.Second
+	}
+	return heartbeat + grace
+}
+
+// Client holds one SSE stream open, reconnecting as configured
+type Client struct {
+	cfg      Config
+	logger   *zap.Logger
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	// Owned by the stream goroutine
+	echoID      string        // Server connection ID to echo heartbeats with; empty when the server doesn't track liveness
+	drainAfter  time.Duration // Set by a reconnect event: the server asked for a reconnect after this
+	serverRetry time.Duration // Last SSE retry: hint
+	lastEventID string        // Sent as Last-Event-ID on reconnect
+	holdingSlot bool
+
+	mu           sync.Mutex
+	cancelStream context.CancelFunc // Ends the current stream; nil between streams
+	dropped      atomic.Bool        // Stream ended by Drop
+}
+
+// New creates a client; Start or Run opens the stream
+func New(cfg Config) *Client {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{}
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 10
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.PingTimeout <= 0 {
+		cfg.PingTimeout = PingTimeoutFor(30 * time.Second)
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Client{cfg: cfg, logger: logger, stopChan: make(chan struct{})}
+}
+
+// Start runs the client in the background until ctx is done or Stop
+func (c *Client) Start(ctx context.Context) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.Run(ctx)
+	}()
+}
+
+// Stop ends the stream and waits for a Start-ed client to finish
+func (c *Client) Stop() {
+	c.stopOnce.Do(func() { close(c.stopChan) })
+	c.wg.Wait()
+}
+
+// Drop ends the current stream as if the network dropped it; the client
+// reconnects right away. False means no stream was open.
+func (c *Client) Drop() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelStream == nil {
+		return false
+	}
+	c.dropped.Store(true)
+	c.cancelStream()
+	return true
+}
+
+// Run streams until ctx is done, Stop, a clean end of stream, or a failure
+// it doesn't reconnect from, which it returns
+func (c *Client) Run(ctx context.Context) error {
+	h := &c.cfg.Handlers
+	retryCount := 0
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.stopChan:
+			return nil
+		default:
+		}
+
+		// Reconnects (not the first connect) queue for a shared slot
+		if attempt > 0 && !c.acquireSlot(ctx) {
+			return nil
+		}
+
+		err := c.stream(ctx)
+		c.releaseSlot()
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Dropped: reconnect at once, through jitter and the slots
+		if c.dropped.CompareAndSwap(true, false) {
+			if h.OnReconnect != nil {
+				h.OnReconnect(ReconnectDropped, 0)
+			}
+			retryCount = 0
+			if !c.wait(ctx, 0) {
+				return nil
+			}
+			continue
+		}
+
+		if err == nil {
+			// Clean disconnection
+			return nil
+		}
+
+		// The server asked for a reconnect (draining, shedding load): not a failure
+		var requested *ReconnectError
+		if errors.As(err, &requested) && c.cfg.Reconnect {
+			if h.OnReconnect != nil {
+				h.OnReconnect(ReconnectRequested, requested.After)
+			}
+			retryCount = 0
+			if !c.wait(ctx, requested.After) {
+				return nil
+			}
+			continue
+		}
+
+		// The server refused the stream with a retry hint (full, draining,
+		// rate limited): wait as long as it asked, so a herd paces itself
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			if h.OnRejected != nil {
+				h.OnRejected(rejected)
+			}
+			if c.cfg.Reconnect && !c.cfg.IgnoreRetryAfter {
+				if h.OnReconnect != nil {
+					h.OnReconnect(ReconnectRejected, rejected.After)
+				}
+				if !c.wait(ctx, rejected.After) {
+					return nil
+				}
+				continue
+			}
+		}
+
+		if h.OnError != nil {
+			h.OnError(err, retryCount)
+		}
+		c.logger.Warn("stream error",
+			zap.String("user_id", c.cfg.UserID),
+			zap.Error(err),
+			zap.Int("retry_count", retryCount),
+		)
+
+		if !c.cfg.Reconnect {
+			if h.OnGiveUp != nil {
+				h.OnGiveUp(err)
+			}
+			return err
+		}
+
+		retryCount++
+		if retryCount > c.cfg.MaxRetries {
+			c.logger.Error("max retries exceeded",
+				zap.String("user_id", c.cfg.UserID),
+				zap.Int("retries", retryCount),
+			)
+			if h.OnGiveUp != nil {
+				h.OnGiveUp(err)
+			}
+			return err
+		}
+
+		// Exponential backoff from the server's retry: hint when it sent one
+		base := c.cfg.RetryDelay
+		if c.serverRetry > 0 {
+			base = c.serverRetry
+		}
+		backoff := min(base*time.Duration(1<<uint(retryCount)), c.cfg.MaxBackoff)
+		if h.OnReconnect != nil {
+			h.OnReconnect(ReconnectBackoff, backoff)
+		}
+		if !c.wait(ctx, backoff) {
+			return nil
+		}
+	}
+}
+
+// wait sleeps d plus up to Jitter before a reconnect; false means the
+// client was stopped meanwhile
+func (c *Client) wait(ctx context.Context, d time.Duration) bool {
+	if c.cfg.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(c.cfg.Jitter)))
+	}
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.stopChan:
+		return false
+	}
+}
+
+// acquireSlot takes one of the reconnect slots, held until the stream is
+// established or fails; false means the client was stopped
+func (c *Client) acquireSlot(ctx context.Context) bool {
+	if c.cfg.ReconnectSlots == nil {
+		return true
+	}
+	select {
+	case c.cfg.ReconnectSlots <- struct{}{}:
+		c.holdingSlot = true
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.stopChan:
+		return false
+	}
+}
+
+// releaseSlot frees the reconnect slot, if held
+func (c *Client) releaseSlot() {
+	if c.holdingSlot {
+		c.holdingSlot = false
+		<-c.cfg.ReconnectSlots
+	}
+}
@@ -0,0 +1,455 @@
+package client
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"go.uber.org/zap"
+
+	"notification-delivery-system/internal/models"
+)
+
+// Protocol violations, passed to Handlers.OnViolation
+const (
+	ViolationMissedHeartbeat = "missed_heartbeat" // No heartbeat within the ping timeout
+	ViolationMalformedLine   = "malformed_line"   // Line that isn't an SSE field or comment
+	ViolationMalformedFrame  = "malformed_frame"  // Event data that doesn't parse or lacks required fields
+	ViolationMalformedID     = "malformed_id"     // Non-numeric SSE id
+	ViolationOutOfOrder      = "out_of_order"     // SSE id not greater than the previous one
+	ViolationUnexpectedEvent = "unexpected_event" // Event name outside the documented set
+	ViolationWireVersion     = "wire_version"     // Notification "v" newer than models.WireVersion
+	ViolationBadContentType  = "bad_content_type" // Stream not served as text/event-stream
+	ViolationHTTPStatus      = "http_status_%d"   // Non-200 response on stream setup, other than a rejection with a retry hint
+	ViolationEchoRejected    = "echo_rejected"    // Heartbeat echo refused: the server lost the connection
+)
+
+// frame is one dispatched SSE event
+type frame struct {
+	event string
+	data  string
+	id    string
+}
+
+func (c *Client) violation(kind string) {
+	if c.cfg.Handlers.OnViolation != nil {
+		c.cfg.Handlers.OnViolation(kind)
+	}
+}
+
+// streamURL is the stream endpoint with the user and filter in the query
+func (c *Client) streamURL() string {
+	query := neturl.Values{"user_id": {c.cfg.UserID}}
+	if len(c.cfg.Types) > 0 {
+		query.Set("types", strings.Join(c.cfg.Types, ","))
+	}
+	if len(c.cfg.Priorities) > 0 {
+		query.Set("priority", strings.Join(c.cfg.Priorities, ","))
+	}
+	if len(c.cfg.Topics) > 0 {
+		query.Set("topics", strings.Join(c.cfg.Topics, ","))
+	}
+	if c.cfg.TypedEvents {
+		query.Set("event_names", "typed")
+	}
+	return c.cfg.ServerURL + "/notifications/stream?" + query.Encode()
+}
+
+// stream opens one stream and reads it until it ends
+func (c *Client) stream(ctx context.Context) error {
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	c.mu.Lock()
+	c.cancelStream = cancelStream
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.cancelStream = nil
+		c.mu.Unlock()
+	}()
+
+	// Count new TCP connections; HTTP/2 streams reuse a shared one
+	traceCtx := streamCtx
+	if onNewConn := c.cfg.Handlers.OnNewConn; onNewConn != nil {
+		traceCtx = httptrace.WithClientTrace(streamCtx, &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if !info.Reused {
+					onNewConn()
+				}
+			},
+		})
+	}
+
+	req, err := c.newRequest(traceCtx, http.MethodGet, c.streamURL(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Connection", "keep-alive")
+	// Set explicitly so the transport never adds (and transparently strips) gzip
+	if c.cfg.Compression != "" {
+		req.Header.Set("Accept-Encoding", c.cfg.Compression)
+	} else {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+	if c.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", c.lastEventID)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if rejected := parseRejection(resp); rejected != nil {
+			return rejected
+		}
+		c.violation(fmt.Sprintf(ViolationHTTPStatus, resp.StatusCode))
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		c.violation(ViolationBadContentType)
+		return fmt.Errorf("unexpected content type: %q", contentType)
+	}
+
+	info := StreamInfo{
+		Proto:      resp.Proto,
+		InstanceID: resp.Header.Get("X-Instance-ID"),
+		Encoding:   resp.Header.Get("Content-Encoding"),
+	}
+	c.releaseSlot()
+	if c.cfg.Handlers.OnConnect != nil {
+		c.cfg.Handlers.OnConnect(info)
+	}
+	c.logger.Debug("connected", zap.String("user_id", c.cfg.UserID), zap.String("proto", resp.Proto))
+	defer func() {
+		if c.cfg.Handlers.OnDisconnect != nil {
+			c.cfg.Handlers.OnDisconnect(info)
+		}
+		c.logger.Debug("disconnected", zap.String("user_id", c.cfg.UserID))
+	}()
+
+	// Reads block, so timeouts are enforced by a watchdog that cancels the request
+	var lastActivity, lastHeartbeat, timedOut atomic.Int64
+	lastActivity.Store(time.Now().UnixNano())
+	lastHeartbeat.Store(time.Now().UnixNano())
+	go c.watchdog(streamCtx, cancelStream, &lastActivity, &lastHeartbeat, &timedOut)
+
+	body, err := c.decodeBody(resp)
+	if err != nil {
+		if streamCtx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	c.drainAfter = 0
+	reader := bufio.NewReader(body)
+	var f frame
+	var data []string
+	var lastSeq uint64
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if timedOut.Load() == 1 {
+				return fmt.Errorf("ping timeout: no activity for %v", c.cfg.PingTimeout)
+			}
+			if c.drainAfter > 0 && streamCtx.Err() == nil {
+				return &ReconnectError{After: c.drainAfter}
+			}
+			if streamCtx.Err() != nil || err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("read: %w", err)
+		}
+
+		lastActivity.Store(time.Now().UnixNano())
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// Blank line dispatches the event
+			if f.event != "" || len(data) > 0 || f.id != "" {
+				f.data = strings.Join(data, "\n")
+				lastSeq = c.checkSequence(f.id, lastSeq)
+				if f.event == "heartbeat" {
+					lastHeartbeat.Store(time.Now().UnixNano())
+				}
+				c.dispatch(streamCtx, f)
+			}
+			f, data = frame{}, data[:0]
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// Comment
+			continue
+		}
+
+		field, value, found := strings.Cut(line, ":")
+		if !found {
+			c.violation(ViolationMalformedLine)
+			continue
+		}
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			f.event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			f.id = value
+		case "retry":
+			// Reconnect hint in milliseconds
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || ms < 0 {
+				c.violation(ViolationMalformedLine)
+				continue
+			}
+			c.serverRetry = time.Duration(ms) * time.Millisecond
+		default:
+			c.violation(ViolationMalformedLine)
+		}
+	}
+}
+
+// countingReader adds the bytes read through it to n
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	atomic.AddInt64(cr.n, int64(n))
+	return n, err
+}
+
+// counted wraps r to count into n, if set
+func counted(r io.Reader, n *int64) io.Reader {
+	if n == nil {
+		return r
+	}
+	return &countingReader{r: r, n: n}
+}
+
+// decodeBody wraps the response body in the decompressor for its
+// Content-Encoding, counting bytes before and after decoding
+func (c *Client) decodeBody(resp *http.Response) (io.Reader, error) {
+	wire := counted(resp.Body, c.cfg.WireBytes)
+	var decoded io.Reader
+	switch encoding := resp.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+		decoded = wire
+	case "gzip":
+		// Blocks until the server flushes the gzip header with the first frame
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		decoded = gz
+	case "br":
+		decoded = brotli.NewReader(wire)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %q", encoding)
+	}
+	return counted(decoded, c.cfg.DecodedBytes), nil
+}
+
+// watchdog cancels the stream when it goes silent and flags missing heartbeats
+func (c *Client) watchdog(ctx context.Context, cancel context.CancelFunc, lastActivity, lastHeartbeat, timedOut *atomic.Int64) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var flaggedHeartbeat int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopChan:
+			cancel()
+			return
+		case <-ticker.C:
+			heartbeat := lastHeartbeat.Load()
+			if heartbeat != flaggedHeartbeat && time.Since(time.Unix(0, heartbeat)) > c.cfg.PingTimeout {
+				// Once per gap
+				flaggedHeartbeat = heartbeat
+				c.violation(ViolationMissedHeartbeat)
+			}
+			if time.Since(time.Unix(0, lastActivity.Load())) > c.cfg.PingTimeout {
+				timedOut.Store(1)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// checkSequence validates the frame's SSE id against the previous one,
+// remembers it for Last-Event-ID and returns the new high-water mark
+func (c *Client) checkSequence(id string, lastSeq uint64) uint64 {
+	if id == "" {
+		return lastSeq
+	}
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.violation(ViolationMalformedID)
+		return lastSeq
+	}
+	if seq <= lastSeq {
+		c.violation(ViolationOutOfOrder)
+		return lastSeq
+	}
+	c.lastEventID = id
+	return seq
+}
+
+// dispatch handles one complete SSE event
+func (c *Client) dispatch(ctx context.Context, f frame) {
+	h := &c.cfg.Handlers
+	switch f.event {
+	case "connected":
+		var connected Connected
+		if err := json.Unmarshal([]byte(f.data), &connected); err != nil {
+			c.violation(ViolationMalformedFrame)
+		}
+		c.echoID = ""
+		if connected.LivenessTimeout != "" {
+			c.echoID = connected.ConnectionID
+		}
+		if h.OnConnected != nil {
+			h.OnConnected(ctx, connected)
+		}
+
+	case "reconnect":
+		var reconnect struct {
+			RetryAfterMs int64 `json:"retry_after_ms"`
+		}
+		if err := json.Unmarshal([]byte(f.data), &reconnect); err != nil {
+			c.violation(ViolationMalformedFrame)
+		}
+		// Reconnect right away when the server gives no usable hint
+		c.drainAfter = max(time.Duration(reconnect.RetryAfterMs)*time.Millisecond, time.Millisecond)
+
+	case "heartbeat":
+		if !json.Valid([]byte(f.data)) {
+			c.violation(ViolationMalformedFrame)
+		}
+		if h.OnHeartbeat != nil {
+			h.OnHeartbeat()
+		}
+		if c.echoID != "" && !c.cfg.Silent {
+			go c.echoHeartbeat(c.echoID)
+		}
+
+	case "notifications":
+		// Grouped frame: several notifications for this user
+		var group models.NotificationGroup
+		if err := json.Unmarshal([]byte(f.data), &group); err != nil || len(group.Notifications) == 0 {
+			c.logger.Debug("malformed notification group",
+				zap.String("user_id", c.cfg.UserID),
+				zap.String("data", f.data),
+				zap.Error(err))
+			c.violation(ViolationMalformedFrame)
+			return
+		}
+		if !models.SupportedWireVersion(group.Version) {
+			c.violation(ViolationWireVersion)
+			return
+		}
+
+		receivedAt := time.Now()
+		for _, event := range group.Notifications {
+			if event == nil || !validNotification(event) {
+				c.violation(ViolationMalformedFrame)
+				continue
+			}
+			c.deliver(event, receivedAt)
+		}
+
+	case "notification":
+		c.single(f, "")
+
+	default:
+		// Typed streams name each notification frame after its event type
+		if strings.Contains(f.event, ".") {
+			c.single(f, f.event)
+			return
+		}
+		c.logger.Debug("unexpected event",
+			zap.String("user_id", c.cfg.UserID),
+			zap.String("event", f.event))
+		c.violation(ViolationUnexpectedEvent)
+	}
+}
+
+// single handles a frame carrying one notification; a typed frame's event
+// type must match its name
+func (c *Client) single(f frame, eventType string) {
+	receivedAt := time.Now()
+	var event models.NotificationEvent
+	err := json.Unmarshal([]byte(f.data), &event)
+	if err != nil || !validNotification(&event) || (eventType != "" && event.EventType != eventType) {
+		c.logger.Debug("malformed notification",
+			zap.String("user_id", c.cfg.UserID),
+			zap.String("event", f.event),
+			zap.String("data", f.data),
+			zap.Error(err))
+		c.violation(ViolationMalformedFrame)
+		return
+	}
+	if !models.SupportedWireVersion(event.Version) {
+		c.violation(ViolationWireVersion)
+		return
+	}
+
+	c.logger.Debug("notification received",
+		zap.String("user_id", c.cfg.UserID),
+		zap.String("notification_id", event.NotificationID),
+		zap.Duration("latency", receivedAt.Sub(event.EventTimestamp)),
+	)
+	c.deliver(&event, receivedAt)
+}
+
+// deliver hands a notification to OnNotification and its type's handler
+func (c *Client) deliver(event *models.NotificationEvent, receivedAt time.Time) {
+	h := &c.cfg.Handlers
+	if h.OnNotification != nil {
+		h.OnNotification(event, receivedAt)
+	}
+	if fn := h.ByType[event.EventType]; fn != nil {
+		fn(event, receivedAt)
+	}
+}
+
+// validNotification checks the fields latency accounting depends on
+func validNotification(event *models.NotificationEvent) bool {
+	return event.NotificationID != "" && !event.EventTimestamp.IsZero()
+}
+
+// echoHeartbeat acks a heartbeat, flagging an echo the server refused
+func (c *Client) echoHeartbeat(connectionID string) {
+	err := c.Ack(context.Background(), connectionID)
+	switch {
+	case errors.Is(err, ErrUnknownConnection):
+		c.violation(ViolationEchoRejected)
+	case err != nil:
+		c.logger.Debug("heartbeat echo failed", zap.String("user_id", c.cfg.UserID), zap.Error(err))
+	}
+}
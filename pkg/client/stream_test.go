@@ -0,0 +1,338 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"notification-delivery-system/internal/models"
+)
+
+// notificationJSON is a valid single-notification frame's data
+func notificationJSON(id, eventType string) string {
+	return fmt.Sprintf(`{"v":1,"notification_id":%q,"event_type":%q,"priority":"HIGH","event_timestamp":"2026-01-02T03:04:05Z","payload":{"job_title":"SRE"}}`, id, eventType)
+}
+
+// streamServer serves each stream request with the next of bodies as
+// text/event-stream. lastEventIDs returns every request's Last-Event-ID.
+func streamServer(t *testing.T, bodies ...string) (server *httptest.Server, lastEventIDs func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var ids []string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/notifications/stream" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		ids = append(ids, r.Header.Get("Last-Event-ID"))
+		n := len(ids)
+		mu.Unlock()
+		if n > len(bodies) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, bodies[n-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(ids)
+	}
+}
+
+// received is what a stream handed to its handlers
+type received struct {
+	notifications []string // Notification IDs, in order
+	typed         []string // Notification IDs seen by the ByType "job.new" handler
+	violations    []string
+	heartbeats    int
+	connected     []Connected
+}
+
+func (r *received) handlers() Handlers {
+	return Handlers{
+		OnConnected: func(ctx context.Context, connected Connected) {
+			r.connected = append(r.connected, connected)
+		},
+		OnNotification: func(event *models.NotificationEvent, receivedAt time.Time) {
+			r.notifications = append(r.notifications, event.NotificationID)
+		},
+		ByType: map[string]NotificationFunc{
+			"job.new": func(event *models.NotificationEvent, receivedAt time.Time) {
+				r.typed = append(r.typed, event.NotificationID)
+			},
+		},
+		OnHeartbeat: func() { r.heartbeats++ },
+		OnViolation: func(kind string) { r.violations = append(r.violations, kind) },
+	}
+}
+
+func TestStreamParsesFrames(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		body              string
+		wantNotifications []string
+		wantTyped         []string
+		wantViolations    []string
+	}{
+		{
+			name:              "single",
+			body:              "id: 1\nevent: notification\ndata: " + notificationJSON("n1", "job.new") + "\n\n",
+			wantNotifications: []string{"n1"},
+			wantTyped:         []string{"n1"},
+		},
+		{
+			name: "grouped",
+			body: "id: 1\nevent: notifications\ndata: {\"v\":1,\"user_id\":\"user_1\",\"count\":2,\"notifications\":[" +
+				notificationJSON("n1", "job.new") + "," + notificationJSON("n2", "job.applied") + "]}\n\n",
+			wantNotifications: []string{"n1", "n2"},
+			wantTyped:         []string{"n1"},
+		},
+		{
+			name:              "typed",
+			body:              "id: 1\nevent: job.applied\ndata: " + notificationJSON("n1", "job.applied") + "\n\n",
+			wantNotifications: []string{"n1"},
+		},
+		{
+			name:           "typed name mismatch",
+			body:           "id: 1\nevent: job.applied\ndata: " + notificationJSON("n1", "job.new") + "\n\n",
+			wantViolations: []string{ViolationMalformedFrame},
+		},
+		{
+			name: "multi-line data and CRLF",
+			body: "id: 1\r\nevent: notification\r\n" +
+				"data: {\"v\":1,\"notification_id\":\"n1\",\"event_type\":\"job.new\",\r\n" +
+				"data: \"event_timestamp\":\"2026-01-02T03:04:05Z\"}\r\n\r\n",
+			wantNotifications: []string{"n1"},
+			wantTyped:         []string{"n1"},
+		},
+		{
+			name:              "comments and blank lines are skipped",
+			body:              ": keep-alive\n\n\nevent: notification\ndata: " + notificationJSON("n1", "job.new") + "\n\n",
+			wantNotifications: []string{"n1"},
+			wantTyped:         []string{"n1"},
+		},
+		{
+			name:           "malformed data",
+			body:           "event: notification\ndata: {not json\n\nevent: notification\ndata: {\"v\":1,\"notification_id\":\"n1\"}\n\n",
+			wantViolations: []string{ViolationMalformedFrame, ViolationMalformedFrame},
+		},
+		{
+			name:           "newer wire version",
+			body:           "event: notification\ndata: {\"v\":99,\"notification_id\":\"n1\",\"event_timestamp\":\"2026-01-02T03:04:05Z\"}\n\n",
+			wantViolations: []string{ViolationWireVersion},
+		},
+		{
+			name: "ids out of order and malformed",
+			body: "id: 2\nevent: notification\ndata: " + notificationJSON("n1", "job.new") + "\n\n" +
+				"id: 1\nevent: notification\ndata: " + notificationJSON("n2", "job.new") + "\n\n" +
+				"id: x\nevent: notification\ndata: " + notificationJSON("n3", "job.new") + "\n\n",
+			wantNotifications: []string{"n1", "n2", "n3"},
+			wantTyped:         []string{"n1", "n2", "n3"},
+			wantViolations:    []string{ViolationOutOfOrder, ViolationMalformedID},
+		},
+		{
+			name:           "unknown event and field",
+			body:           "event: surprise\ndata: {}\n\nbogus: 1\nno colon\n\n",
+			wantViolations: []string{ViolationUnexpectedEvent, ViolationMalformedLine, ViolationMalformedLine},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _ := streamServer(t, tc.body)
+			var got received
+			c := New(Config{ServerURL: server.URL, UserID: "user_1", Handlers: got.handlers()})
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			if !slices.Equal(got.notifications, tc.wantNotifications) {
+				t.Errorf("notifications = %v, want %v", got.notifications, tc.wantNotifications)
+			}
+			if !slices.Equal(got.typed, tc.wantTyped) {
+				t.Errorf("job.new handler got %v, want %v", got.typed, tc.wantTyped)
+			}
+			if !slices.Equal(got.violations, tc.wantViolations) {
+				t.Errorf("violations = %v, want %v", got.violations, tc.wantViolations)
+			}
+		})
+	}
+}
+
+func TestStreamControlFrames(t *testing.T) {
+	server, _ := streamServer(t, "retry: 1500\n\n"+
+		"event: connected\ndata: {\"region\":\"eu\",\"connection_id\":\"conn_1\"}\n\n"+
+		"event: heartbeat\ndata: {\"ts\":1}\n\n")
+	var got received
+	c := New(Config{ServerURL: server.URL, UserID: "user_1", Handlers: got.handlers()})
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(got.connected) != 1 || got.connected[0] != (Connected{Region: "eu", ConnectionID: "conn_1"}) {
+		t.Errorf("connected = %+v", got.connected)
+	}
+	if got.heartbeats != 1 {
+		t.Errorf("heartbeats = %d, want 1", got.heartbeats)
+	}
+	if c.serverRetry != 1500*time.Millisecond {
+		t.Errorf("retry hint = %s, want 1.5s", c.serverRetry)
+	}
+	// Without a liveness timeout there's no connection to echo heartbeats for
+	if c.echoID != "" {
+		t.Errorf("echo ID = %q, want none", c.echoID)
+	}
+	if len(got.violations) != 0 {
+		t.Errorf("violations = %v", got.violations)
+	}
+}
+
+// TestReconnectResendsLastEventID follows a reconnect event and checks that
+// the new stream resumes from the last id the client saw
+func TestReconnectResendsLastEventID(t *testing.T) {
+	server, lastEventIDs := streamServer(t,
+		"id: 1\nevent: notification\ndata: "+notificationJSON("n1", "job.new")+"\n\n"+
+			"id: 2\nevent: notification\ndata: "+notificationJSON("n2", "job.new")+"\n\n"+
+			"event: reconnect\ndata: {\"reason\":\"draining\",\"retry_after_ms\":1}\n\n",
+		"id: 3\nevent: notification\ndata: "+notificationJSON("n3", "job.new")+"\n\n",
+	)
+	var got received
+	var reasons []string
+	handlers := got.handlers()
+	handlers.OnReconnect = func(reason string, after time.Duration) {
+		reasons = append(reasons, reason)
+	}
+	c := New(Config{ServerURL: server.URL, UserID: "user_1", Reconnect: true, Handlers: handlers})
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if want := []string{"", "2"}; !slices.Equal(lastEventIDs(), want) {
+		t.Errorf("Last-Event-ID per request = %q, want %q", lastEventIDs(), want)
+	}
+	if want := []string{ReconnectRequested}; !slices.Equal(reasons, want) {
+		t.Errorf("reconnect reasons = %v, want %v", reasons, want)
+	}
+	if want := []string{"n1", "n2", "n3"}; !slices.Equal(got.notifications, want) {
+		t.Errorf("notifications = %v, want %v", got.notifications, want)
+	}
+}
+
+// TestReconnectAfterFailureResendsLastEventID resumes after a failed stream
+// setup, backing off from the server's retry hint
+func TestReconnectAfterFailureResendsLastEventID(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastEventIDs)
+		mu.Unlock()
+		switch n {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "retry: 1\n\nid: 7\nevent: notification\ndata: "+notificationJSON("n7", "job.new")+"\n\n"+
+				"event: reconnect\ndata: {\"retry_after_ms\":1}\n\n")
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+		}
+	}))
+	defer server.Close()
+
+	var got received
+	var errs []error
+	handlers := got.handlers()
+	handlers.OnError = func(err error, retries int) { errs = append(errs, err) }
+	c := New(Config{ServerURL: server.URL, UserID: "user_1", Reconnect: true, Handlers: handlers})
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"", "7", "7"}; !slices.Equal(lastEventIDs, want) {
+		t.Errorf("Last-Event-ID per request = %q, want %q", lastEventIDs, want)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "502") {
+		t.Errorf("stream errors = %v, want one 502", errs)
+	}
+	if want := []string{fmt.Sprintf(ViolationHTTPStatus, http.StatusBadGateway)}; !slices.Equal(got.violations, want) {
+		t.Errorf("violations = %v, want %v", got.violations, want)
+	}
+}
+
+func TestStreamSetupErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		handler       http.HandlerFunc
+		wantRejected  *RejectedError
+		wantViolation string
+	}{
+		{
+			name: "rejected with retry hint",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, `{"reason":"capacity","retry_after_ms":250}`)
+			},
+			wantRejected: &RejectedError{Status: http.StatusServiceUnavailable, Reason: "capacity", After: 250 * time.Millisecond},
+		},
+		{
+			name: "unexpected status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantViolation: "http_status_500",
+		},
+		{
+			name: "not an event stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{}`)
+			},
+			wantViolation: ViolationBadContentType,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			var got received
+			var gaveUp error
+			handlers := got.handlers()
+			handlers.OnGiveUp = func(err error) { gaveUp = err }
+			c := New(Config{ServerURL: server.URL, UserID: "user_1", Handlers: handlers})
+			err := c.Run(context.Background())
+			if err == nil || gaveUp != err {
+				t.Fatalf("Run = %v, gave up with %v; want the same error", err, gaveUp)
+			}
+
+			var rejected *RejectedError
+			if errors.As(err, &rejected) != (tc.wantRejected != nil) {
+				t.Fatalf("Run = %v, want rejection %v", err, tc.wantRejected)
+			}
+			if tc.wantRejected != nil && *rejected != *tc.wantRejected {
+				t.Errorf("rejection = %+v, want %+v", rejected, tc.wantRejected)
+			}
+
+			var wantViolations []string
+			if tc.wantViolation != "" {
+				wantViolations = []string{tc.wantViolation}
+			}
+			if !slices.Equal(got.violations, wantViolations) {
+				t.Errorf("violations = %v, want %v", got.violations, wantViolations)
+			}
+		})
+	}
+}
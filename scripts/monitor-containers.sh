@@ -94,22 +94,15 @@ fi
 # Service Logs Summary
 echo ""
 echo -e "${BLUE}━━━ Recent Service Activity ━━━${NC}"
-echo "Producer Services (last 2 log lines each):"
-echo -e "${YELLOW}Job Service:${NC}"
-docker compose logs --tail=2 job-service 2>/dev/null | tail -2
-
-echo -e "${YELLOW}Connections Service:${NC}"
-docker compose logs --tail=2 connections-service 2>/dev/null | tail -2
-
-echo -e "${YELLOW}Followers Service:${NC}"
-docker compose logs --tail=2 followers-service 2>/dev/null | tail -2
+echo -e "${YELLOW}Event Generator (last 2 log lines):${NC}"
+docker compose logs --tail=2 event-generator 2>/dev/null | tail -2
 
 echo ""
 echo -e "${GREEN}════════════════════════════════════════════════════════════════${NC}"
 echo -e "${BLUE}Load Configuration:${NC}"
-echo "  • Job Service: 500 events/sec, 100,000 users"
-echo "  • Connections Service: 300 events/sec, 100,000 users"
-echo "  • Followers Service: 400 events/sec, 100,000 users"
+echo "  • Job profile: 500 events/sec, 100,000 users"
+echo "  • Connections profile: 300 events/sec, 100,000 users"
+echo "  • Followers profile: 400 events/sec, 100,000 users"
 echo "  • ${YELLOW}TOTAL: 1,200 events/sec${NC}"
 echo ""
 echo "Run: ${BLUE}watch -n 5 ./scripts/monitor-containers.sh${NC} for live updates"
@@ -7,6 +7,8 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 -- Create notifications table with optimized indexes
 CREATE TABLE IF NOT EXISTS notifications (
     notification_id UUID PRIMARY KEY,
+    event_id VARCHAR(64), -- Producer's event ID (cmd/backfill finds gaps by it)
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
     user_id VARCHAR(255) NOT NULL,
     event_type VARCHAR(50) NOT NULL,
     priority VARCHAR(10) NOT NULL,
@@ -20,7 +22,16 @@ CREATE TABLE IF NOT EXISTS notifications (
     retry_count INTEGER NOT NULL DEFAULT 0,
     error_message TEXT,
     lease_timeout TIMESTAMPTZ,
-    instance_id VARCHAR(255)
+    instance_id VARCHAR(255),
+    expires_at TIMESTAMPTZ,
+    trace_id VARCHAR(64),
+    is_late BOOLEAN NOT NULL DEFAULT FALSE, -- Event time beyond the consumer's late threshold
+    -- Pipeline stage stamps (notification_received_timestamp is when it was consumed)
+    produced_at TIMESTAMPTZ,  -- Producer sent the event
+    persisted_at TIMESTAMPTZ, -- Row written, by the database clock
+    claimed_at TIMESTAMPTZ,   -- Last claimed by a task picker, by the database clock
+    next_attempt_at TIMESTAMPTZ, -- Failed deliveries back off: not claimed again before this
+    topic VARCHAR(128) -- Topic it was published to; NULL when addressed to the user
 );
 
 -- Index for Task Picker: Find pending notifications by user, ordered by priority
@@ -28,10 +39,36 @@ CREATE TABLE IF NOT EXISTS notifications (
 CREATE INDEX idx_user_status_priority ON notifications (user_id, status, priority DESC, created_at)
 WHERE status IN ('not_pushed', 'claimed');
 
+-- Index for tenant-scoped user queries and per-tenant stats
+CREATE INDEX idx_tenant_user_created ON notifications (tenant_id, user_id, created_at DESC);
+
 -- Index for pending notifications (faster picker queries)
 CREATE INDEX idx_status_created ON notifications (status, created_at)
 WHERE status = 'not_pushed';
 
+-- Indexes for claim ordering policies (must match priorityRankSQL in the repository)
+-- priority-first: priority, then earliest deadline within priority
+CREATE INDEX idx_pending_priority_deadline ON notifications (
+    (CASE priority WHEN 'HIGH' THEN 0 WHEN 'MEDIUM' THEN 1 ELSE 2 END),
+    expires_at ASC NULLS LAST,
+    created_at
+) WHERE status = 'not_pushed';
+
+-- deadline-first: earliest deadline, priority breaks ties
+CREATE INDEX idx_pending_deadline_priority ON notifications (
+    expires_at ASC NULLS LAST,
+    (CASE priority WHEN 'HIGH' THEN 0 WHEN 'MEDIUM' THEN 1 ELSE 2 END),
+    created_at
+) WHERE status = 'not_pushed';
+
+-- Index for un-parking: a reconnecting user's parked notifications
+CREATE INDEX idx_parked_user ON notifications (tenant_id, user_id)
+WHERE status = 'parked';
+
+-- Index for backfill: which of a window of Kafka events were persisted
+CREATE INDEX idx_event_id ON notifications (event_id)
+WHERE event_id IS NOT NULL;
+
 -- Index for lease expiry (reclaiming stale tasks)
 CREATE INDEX idx_lease_timeout ON notifications (lease_timeout)
 WHERE status = 'claimed' AND lease_timeout IS NOT NULL;
@@ -47,6 +84,128 @@ CREATE INDEX idx_payload_gin ON notifications USING gin (payload);
 CREATE INDEX idx_error_tracking ON notifications (status, retry_count, created_at)
 WHERE status = 'failed';
 
+-- Delivery attempt history: one row per attempt, so retries and flaky users
+-- can be analyzed after a run (notifications.error_message only keeps the last)
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    attempt_id BIGSERIAL PRIMARY KEY,
+    notification_id UUID NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id VARCHAR(255) NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL,
+    instance_id VARCHAR(255),
+    worker_id INTEGER,
+    channel VARCHAR(20) NOT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    error_message TEXT,
+    latency_ms DOUBLE PRECISION NOT NULL,
+    group_size INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_attempts_notification ON delivery_attempts (notification_id, attempted_at);
+CREATE INDEX idx_attempts_user_time ON delivery_attempts (tenant_id, user_id, attempted_at DESC);
+CREATE INDEX idx_attempts_outcome_time ON delivery_attempts (outcome, attempted_at);
+
+-- Status counters behind GetStats, so stats don't COUNT(*) the whole table.
+-- Statement-level triggers append one row per status a statement changed,
+-- with how much it moved that status's count, in the same transaction; the
+-- repository periodically folds the rows back into one per status. Writers
+-- only insert here, so they never wait on each other for a counter row.
+CREATE TABLE IF NOT EXISTS notification_status_counts (
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    delta BIGINT NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION count_notification_statuses()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO notification_status_counts (status, delta)
+        SELECT status, COUNT(*) FROM new_rows GROUP BY status;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO notification_status_counts (status, delta)
+        SELECT status, SUM(delta)
+        FROM (
+            SELECT status, -1 AS delta FROM old_rows
+            UNION ALL
+            SELECT status, 1 AS delta FROM new_rows
+        ) moved
+        GROUP BY status
+        HAVING SUM(delta) <> 0;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO notification_status_counts (status, delta)
+        SELECT status, -COUNT(*) FROM old_rows GROUP BY status;
+    ELSE -- TRUNCATE
+        DELETE FROM notification_status_counts;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- A trigger with transition tables can only fire on one event
+CREATE TRIGGER notifications_count_insert AFTER INSERT ON notifications
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION count_notification_statuses();
+
+CREATE TRIGGER notifications_count_update AFTER UPDATE ON notifications
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION count_notification_statuses();
+
+CREATE TRIGGER notifications_count_delete AFTER DELETE ON notifications
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT EXECUTE FUNCTION count_notification_statuses();
+
+CREATE TRIGGER notifications_count_truncate AFTER TRUNCATE ON notifications
+FOR EACH STATEMENT EXECUTE FUNCTION count_notification_statuses();
+
+-- Per-user preferences, read when rendering deliveries (locale picks the
+-- language of titles and messages)
+CREATE TABLE IF NOT EXISTS user_preferences (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id VARCHAR(255) NOT NULL,
+    locale VARCHAR(35) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+-- Event types and families ("job.*") registered at runtime through
+-- /admin/event-types, overriding the built-in priority, templates,
+-- digestibility, channels and TTL; every instance reloads them periodically
+CREATE TABLE IF NOT EXISTS event_types (
+    event_type VARCHAR(50) PRIMARY KEY,
+    priority VARCHAR(10) NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    locales JSONB NOT NULL DEFAULT '{}',
+    digestible BOOLEAN NOT NULL DEFAULT TRUE,
+    channels TEXT[] NOT NULL DEFAULT '{sse}',
+    payload_schema JSONB, -- Subset of JSON Schema the consumer checks payloads against
+    ttl TEXT NOT NULL DEFAULT '', -- Go duration after which events expire undelivered
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Segments (named user lists) that broadcast events address by name,
+-- registered through /admin/segments; the consumer expands a broadcast into
+-- a notification per member
+CREATE TABLE IF NOT EXISTS segments (
+    segment VARCHAR(64) PRIMARY KEY,
+    user_ids TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Users' topic subscriptions ("job-alerts:golang"); the consumer expands an
+-- event published to a topic into a notification per subscriber
+CREATE TABLE IF NOT EXISTS subscriptions (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id VARCHAR(255) NOT NULL,
+    topic VARCHAR(128) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id, topic)
+);
+
+-- Index for expanding a topic into its subscribers
+CREATE INDEX idx_subscriptions_topic ON subscriptions (tenant_id, topic);
+
 -- Create table for performance metrics tracking
 CREATE TABLE IF NOT EXISTS notification_metrics (
     id SERIAL PRIMARY KEY,